@@ -0,0 +1,282 @@
+// cmd/announce_scanner/backfill.go
+// 历史公告回填：按数据源向后翻页直到截止日期，支持断点续传
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backfillItem 回填条目：去重键 + 发布时间 + 实际 ingest 的条目
+type backfillItem struct {
+	Key       string
+	ReleaseMS int64
+	Payload   any
+}
+
+// backfillPage 单页抓取结果；Next 为空表示没有更多页
+type backfillPage struct {
+	Items []backfillItem
+	Next  string
+}
+
+// backfillSource 可向后翻页的数据源
+type backfillSource struct {
+	Name       string
+	IngestPath string // 例如 /ingest/coincarp/announcements
+	Start      string // 初始游标
+	Fetch      func(ctx context.Context, cursor string) (backfillPage, error)
+}
+
+// backfillState 回填进度（按数据源记录下一页游标），用于失败后续传
+type backfillState struct {
+	Cutoff  string            `json:"cutoff"`
+	Cursors map[string]string `json:"cursors"`
+	Done    map[string]bool   `json:"done"`
+}
+
+type backfillOptions struct {
+	Delay      time.Duration // 页间隔（限速）
+	MaxRetries int           // 单页最大重试次数
+	StatePath  string        // 进度文件；为空则不持久化
+}
+
+type ingestFunc func(ctx context.Context, path string, items []any) error
+
+func loadBackfillState(path string, cutoff time.Time) *backfillState {
+	st := &backfillState{Cutoff: cutoff.Format("2006-01-02"), Cursors: map[string]string{}, Done: map[string]bool{}}
+	if path == "" {
+		return st
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return st
+	}
+	var old backfillState
+	if err := json.Unmarshal(b, &old); err != nil || old.Cutoff != st.Cutoff {
+		// 截止日期变化时从头开始
+		return st
+	}
+	if old.Cursors != nil {
+		st.Cursors = old.Cursors
+	}
+	if old.Done != nil {
+		st.Done = old.Done
+	}
+	return st
+}
+
+func (st *backfillState) save(path string) {
+	if path == "" {
+		return
+	}
+	b, _ := json.MarshalIndent(st, "", "  ")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		log.Printf("[backfill] save state %s err: %v", path, err)
+	}
+}
+
+// runBackfill 逐源向后翻页，直到遇到早于 cutoff 的条目或没有更多页。
+// 每页 ingest 成功后才推进游标，失败时按指数退避重试，最终失败则保存进度并返回错误。
+func runBackfill(ctx context.Context, sources []backfillSource, cutoff time.Time, st *backfillState, ingest ingestFunc, opt backfillOptions) (map[string]int, error) {
+	if opt.MaxRetries <= 0 {
+		opt.MaxRetries = 3
+	}
+	cutoffMS := cutoff.UTC().UnixMilli()
+	added := map[string]int{}
+	seen := map[string]struct{}{}
+
+	for _, src := range sources {
+		if st.Done[src.Name] {
+			log.Printf("[backfill] %s already done, skip", src.Name)
+			continue
+		}
+		cursor := st.Cursors[src.Name]
+		if cursor == "" {
+			cursor = src.Start
+		}
+		for cursor != "" {
+			var page backfillPage
+			var err error
+			for attempt := 0; attempt < opt.MaxRetries; attempt++ {
+				if attempt > 0 {
+					delay := opt.Delay * time.Duration(1<<uint(attempt))
+					if delay <= 0 {
+						delay = time.Duration(100*(1<<uint(attempt))) * time.Millisecond
+					}
+					select {
+					case <-ctx.Done():
+						return added, ctx.Err()
+					case <-time.After(delay):
+					}
+				}
+				if page, err = src.Fetch(ctx, cursor); err == nil {
+					break
+				}
+				log.Printf("[backfill] %s cursor=%s attempt %d/%d err: %v", src.Name, cursor, attempt+1, opt.MaxRetries, err)
+			}
+			if err != nil {
+				st.save(opt.StatePath)
+				return added, fmt.Errorf("backfill %s at cursor %s: %w", src.Name, cursor, err)
+			}
+
+			items := make([]any, 0, len(page.Items))
+			reachedCutoff := len(page.Items) == 0
+			for _, it := range page.Items {
+				if it.ReleaseMS < cutoffMS {
+					reachedCutoff = true
+					continue
+				}
+				if _, ok := seen[it.Key]; ok {
+					continue
+				}
+				seen[it.Key] = struct{}{}
+				items = append(items, it.Payload)
+			}
+			if len(items) > 0 {
+				if err := ingest(ctx, src.IngestPath, items); err != nil {
+					st.save(opt.StatePath)
+					return added, fmt.Errorf("backfill %s ingest at cursor %s: %w", src.Name, cursor, err)
+				}
+				added[src.Name] += len(items)
+			}
+			log.Printf("[backfill] %s cursor=%s fetched=%d ingested=%d", src.Name, cursor, len(page.Items), len(items))
+
+			if reachedCutoff || page.Next == "" {
+				break
+			}
+			cursor = page.Next
+			st.Cursors[src.Name] = cursor
+			st.save(opt.StatePath)
+
+			if opt.Delay > 0 {
+				select {
+				case <-ctx.Done():
+					return added, ctx.Err()
+				case <-time.After(opt.Delay):
+				}
+			}
+		}
+		st.Done[src.Name] = true
+		delete(st.Cursors, src.Name)
+		st.save(opt.StatePath)
+	}
+	return added, nil
+}
+
+// nextPageCursor 页码型游标：本页满页时返回下一页页码，否则为空
+func nextPageCursor(cursor string, got, pageSize int) string {
+	page, _ := strconv.Atoi(cursor)
+	if page <= 0 {
+		page = 1
+	}
+	if got < pageSize {
+		return ""
+	}
+	return strconv.Itoa(page + 1)
+}
+
+// buildBackfillSources 根据已启用的数据源构建回填列表
+func buildBackfillSources(client *http.Client, coincarp, binance, okx, bybit bool, cats []int, pageSize int) []backfillSource {
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 20
+	}
+	var out []backfillSource
+	if coincarp {
+		// CoinCarp 以 issuetime 为游标，每页以最早一条的时间继续向前翻
+		out = append(out, backfillSource{
+			Name:       "coincarp",
+			IngestPath: "/ingest/coincarp/announcements",
+			Start:      strconv.FormatInt(time.Now().Unix(), 10),
+			Fetch: func(ctx context.Context, cursor string) (backfillPage, error) {
+				issuetime, _ := strconv.ParseInt(cursor, 10, 64)
+				items, err := fetchCoinCarp(ctx, client, issuetime, 50)
+				if err != nil {
+					return backfillPage{}, err
+				}
+				var page backfillPage
+				oldest := issuetime
+				for _, it := range items {
+					it.URL = strings.TrimRight(strings.TrimSpace(it.URL), "/")
+					if it.URL == "" {
+						continue
+					}
+					page.Items = append(page.Items, backfillItem{Key: "coincarp|" + it.URL, ReleaseMS: it.ReleaseMS, Payload: coincarpGenericItem(it)})
+					if sec := it.ReleaseMS / 1000; sec < oldest {
+						oldest = sec
+					}
+				}
+				// 没有更早的数据则停止，避免原地打转
+				if oldest < issuetime {
+					page.Next = strconv.FormatInt(oldest, 10)
+				}
+				return page, nil
+			},
+		})
+	}
+	if binance {
+		for _, cat := range cats {
+			cat := cat
+			out = append(out, backfillSource{
+				Name:       fmt.Sprintf("binance-%d", cat),
+				IngestPath: "/ingest/binance/announcements",
+				Start:      "1",
+				Fetch: func(ctx context.Context, cursor string) (backfillPage, error) {
+					pageNo, _ := strconv.Atoi(cursor)
+					items, err := fetchBinancePage(ctx, client, cat, pageNo, pageSize)
+					if err != nil {
+						return backfillPage{}, err
+					}
+					var page backfillPage
+					page.Next = nextPageCursor(cursor, len(items), pageSize)
+					for _, it := range items {
+						page.Items = append(page.Items, backfillItem{Key: "binance|" + it.Code, ReleaseMS: it.ReleaseMS, Payload: it})
+					}
+					return page, nil
+				},
+			})
+		}
+	}
+	official := []struct {
+		name    string
+		enabled bool
+		fetch   func(ctx context.Context, client *http.Client, page, limit int) ([]binanceIngestItem, error)
+	}{
+		{"okx", okx, fetchOKXPage},
+		{"bybit", bybit, fetchBybitPage},
+	}
+	for _, o := range official {
+		if !o.enabled {
+			continue
+		}
+		o := o
+		out = append(out, backfillSource{
+			Name:       o.name,
+			IngestPath: "/ingest/" + o.name + "/announcements",
+			Start:      "1",
+			Fetch: func(ctx context.Context, cursor string) (backfillPage, error) {
+				pageNo, _ := strconv.Atoi(cursor)
+				items, err := o.fetch(ctx, client, pageNo, 20)
+				if err != nil {
+					return backfillPage{}, err
+				}
+				var page backfillPage
+				page.Next = nextPageCursor(cursor, len(items), 20)
+				for _, it := range items {
+					it.URL = strings.TrimRight(strings.TrimSpace(it.URL), "/")
+					page.Items = append(page.Items, backfillItem{Key: o.name + "|" + it.URL, ReleaseMS: it.ReleaseMS, Payload: officialGenericItem(o.name, it)})
+				}
+				return page, nil
+			},
+		})
+	}
+	return out
+}
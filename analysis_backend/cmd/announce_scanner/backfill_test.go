@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// mockDatedSource 每页 3 条，按天递减；page 1 从 2025-03-10 开始
+func mockDatedSource(failAt int, fails *int) backfillSource {
+	base := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	return backfillSource{
+		Name:       "mock",
+		IngestPath: "/ingest/mock/announcements",
+		Start:      "1",
+		Fetch: func(ctx context.Context, cursor string) (backfillPage, error) {
+			page, _ := strconv.Atoi(cursor)
+			if page == failAt && *fails > 0 {
+				*fails--
+				return backfillPage{}, errors.New("upstream 502")
+			}
+			var out backfillPage
+			for i := 0; i < 3; i++ {
+				day := (page-1)*3 + i
+				ts := base.AddDate(0, 0, -day)
+				out.Items = append(out.Items, backfillItem{
+					Key:       "mock|" + strconv.Itoa(day),
+					ReleaseMS: ts.UnixMilli(),
+					Payload:   day,
+				})
+			}
+			out.Next = strconv.Itoa(page + 1)
+			return out, nil
+		},
+	}
+}
+
+// TestRunBackfillStopsAtCutoff 遇到早于截止日期的条目即停止翻页
+func TestRunBackfillStopsAtCutoff(t *testing.T) {
+	fails := 0
+	src := mockDatedSource(0, &fails)
+	cutoff := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC) // 覆盖 day 0..7
+
+	var got []int
+	pages := 0
+	ingest := func(ctx context.Context, path string, items []any) error {
+		pages++
+		for _, it := range items {
+			got = append(got, it.(int))
+		}
+		return nil
+	}
+	st := loadBackfillState("", cutoff)
+	added, err := runBackfill(context.Background(), []backfillSource{src}, cutoff, st, ingest, backfillOptions{})
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if len(got) != 8 || got[len(got)-1] != 7 {
+		t.Fatalf("期望回填 day 0..7，实际 %v", got)
+	}
+	if pages != 3 || added["mock"] != 8 {
+		t.Fatalf("期望 3 页 8 条，实际 pages=%d added=%v", pages, added)
+	}
+	if !st.Done["mock"] {
+		t.Fatalf("回填完成后应标记 done")
+	}
+}
+
+// TestRunBackfillResume 连续失败时保存进度，重跑从失败页继续
+func TestRunBackfillResume(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	cutoff := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+	fails := 10
+	src := mockDatedSource(2, &fails)
+
+	var got []int
+	ingest := func(ctx context.Context, path string, items []any) error {
+		for _, it := range items {
+			got = append(got, it.(int))
+		}
+		return nil
+	}
+	opt := backfillOptions{Delay: time.Millisecond, MaxRetries: 2, StatePath: statePath}
+	if _, err := runBackfill(context.Background(), []backfillSource{src}, cutoff, loadBackfillState(statePath, cutoff), ingest, opt); err == nil {
+		t.Fatalf("期望第 2 页失败")
+	}
+	if len(got) != 3 {
+		t.Fatalf("失败前应只回填第 1 页，实际 %v", got)
+	}
+
+	fails = 0
+	st := loadBackfillState(statePath, cutoff)
+	if st.Cursors["mock"] != "2" {
+		t.Fatalf("进度应停在第 2 页，实际 %q", st.Cursors["mock"])
+	}
+	if _, err := runBackfill(context.Background(), []backfillSource{src}, cutoff, st, ingest, opt); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if len(got) != 8 || got[3] != 3 {
+		t.Fatalf("续传后应补齐 day 3..7，实际 %v", got)
+	}
+}
@@ -267,8 +267,19 @@ func fetchCoinMarketCal(ctx context.Context, client *http.Client, limit int) ([]
 
 // OKX 公告抓取
 func fetchOKX(ctx context.Context, client *http.Client, limit int) ([]binanceIngestItem, error) {
+	items, err := fetchOKXPage(ctx, client, 1, limit)
+	if err != nil {
+		// OKX API 可能偶尔失败，返回空列表而不是错误
+		log.Printf("[okx] fetch err (will retry next time): %v", err)
+		return nil, nil
+	}
+	return items, nil
+}
+
+// fetchOKXPage 抓取 OKX 公告第 page 页（回填时向后翻页使用）
+func fetchOKXPage(ctx context.Context, client *http.Client, page, limit int) ([]binanceIngestItem, error) {
 	// OKX 公告 API
-	url := "https://www.okx.com/api/v5/announcement/public?locale=zh_CN&limit=" + strconv.Itoa(limit)
+	url := fmt.Sprintf("https://www.okx.com/api/v5/announcement/public?locale=zh_CN&page=%d&limit=%d", page, limit)
 
 	var resp struct {
 		Code string `json:"code"`
@@ -277,14 +288,13 @@ func fetchOKX(ctx context.Context, client *http.Client, limit int) ([]binanceIng
 			Title     string `json:"title"`
 			Summary   string `json:"summary"`
 			URL       string `json:"url"`
+			AnnType   string `json:"annType"`
 			PublishTS int64  `json:"publishTime"`
 		} `json:"data"`
 	}
 
 	if err := httpGetJSON(ctx, client, url, &resp); err != nil {
-		// OKX API 可能偶尔失败，返回空列表而不是错误
-		log.Printf("[okx] fetch err (will retry next time): %v", err)
-		return nil, nil
+		return nil, err
 	}
 
 	if resp.Code != "0" {
@@ -300,6 +310,7 @@ func fetchOKX(ctx context.Context, client *http.Client, limit int) ([]binanceIng
 
 		items = append(items, binanceIngestItem{
 			Source:    "okx",
+			Category:  d.AnnType,
 			Code:      d.ID,
 			Title:     d.Title,
 			Summary:   d.Summary,
@@ -313,34 +324,41 @@ func fetchOKX(ctx context.Context, client *http.Client, limit int) ([]binanceIng
 
 // Bybit 公告抓取
 func fetchBybit(ctx context.Context, client *http.Client, limit int) ([]binanceIngestItem, error) {
+	items, err := fetchBybitPage(ctx, client, 1, limit)
+	if err != nil {
+		// Bybit API 可能偶尔失败（含 500），返回空列表而不是错误
+		log.Printf("[bybit] fetch err (will retry next time): %v", err)
+		return nil, nil
+	}
+	return items, nil
+}
+
+// fetchBybitPage 抓取 Bybit 公告第 page 页（回填时向后翻页使用）
+func fetchBybitPage(ctx context.Context, client *http.Client, page, limit int) ([]binanceIngestItem, error) {
 	// Bybit 公告页面
-	url := "https://api.bybit.com/v5/announcements/index?locale=zh-CN&limit=" + strconv.Itoa(limit)
+	url := fmt.Sprintf("https://api.bybit.com/v5/announcements/index?locale=zh-CN&page=%d&limit=%d", page, limit)
 
 	var resp struct {
 		RetCode int `json:"retCode"`
 		Result  struct {
 			List []struct {
-				ID        string `json:"id"`
-				Title     string `json:"title"`
-				Summary   string `json:"summary"`
-				URL       string `json:"url"`
-				CreatedAt int64  `json:"createdAt"`
+				ID      string `json:"id"`
+				Title   string `json:"title"`
+				Summary string `json:"summary"`
+				URL     string `json:"url"`
+				Type    struct {
+					Key string `json:"key"`
+				} `json:"type"`
+				CreatedAt int64 `json:"createdAt"`
 			} `json:"list"`
 		} `json:"result"`
 	}
 
 	if err := httpGetJSON(ctx, client, url, &resp); err != nil {
-		// Bybit API 可能偶尔失败，返回空列表而不是错误
-		log.Printf("[bybit] fetch err (will retry next time): %v", err)
-		return nil, nil
+		return nil, err
 	}
 
 	if resp.RetCode != 0 {
-		// 500 错误可能是临时问题，记录但不中断
-		if resp.RetCode == 500 {
-			log.Printf("[bybit] api error: retCode=%d (temporary server error, will retry next time)", resp.RetCode)
-			return nil, nil
-		}
 		return nil, fmt.Errorf("bybit api error: retCode=%d", resp.RetCode)
 	}
 
@@ -353,6 +371,7 @@ func fetchBybit(ctx context.Context, client *http.Client, limit int) ([]binanceI
 
 		items = append(items, binanceIngestItem{
 			Source:    "bybit",
+			Category:  d.Type.Key,
 			Code:      d.ID,
 			Title:     d.Title,
 			Summary:   d.Summary,
@@ -19,6 +19,8 @@ import (
 	"time"
 
 	"analysis/internal/netutil"
+	"analysis/internal/server"
+	"analysis/internal/util"
 
 	"gorm.io/gorm"
 )
@@ -31,6 +33,7 @@ import (
 type binanceIngestItem struct {
 	Source    string `json:"source"`     // 固定 "binance"
 	CatalogID int    `json:"catalog_id"` // 48/49/93...
+	Category  string `json:"category"`   // 非 Binance 官方源的原始类目（OKX annType / Bybit type.key）
 	Code      string `json:"code"`       // 唯一 code
 	Title     string `json:"title"`
 	Summary   string `json:"summary"`
@@ -79,59 +82,71 @@ func fetchBinance(ctx context.Context, client *http.Client, catalogs []int, page
 	items := make([]binanceIngestItem, 0, pageSize*len(catalogs))
 
 	for _, cat := range catalogs {
-		u := fmt.Sprintf(
-			"https://www.binance.com/bapi/composite/v1/public/cms/article/catalog/list/query?catalogId=%d&pageNo=1&pageSize=%d",
-			cat, pageSize,
-		)
-
-		var resp binanceCMSResp
-		if err := httpGetJSON(ctx, client, u, &resp); err != nil {
+		page, err := fetchBinancePage(ctx, client, cat, 1, pageSize)
+		if err != nil {
 			// Binance API 可能偶尔失败，记录错误但继续处理其他分类
 			log.Printf("[binance] fetch catalog %d err: %v", cat, err)
 			continue
 		}
+		items = append(items, page...)
+		time.Sleep(200 * time.Millisecond) // 轻微限速
+	}
+	return items, nil
+}
 
-		for _, a := range resp.Data.Articles {
-			// ---- 时间兜底（releaseDate / publishTime，且可能是“秒”也可能是“毫秒”）----
-			ms := a.ReleaseTS
-			if ms <= 0 {
-				ms = a.PublishTs2
-			}
-			// 如果是“秒”，转成“毫秒”
-			if ms > 0 && ms < 1e12 {
-				ms *= 1000
-			}
-
-			// 仍拿不到就用当前时间兜底（避免前端空白）
-			if ms <= 0 {
-				ms = time.Now().UTC().UnixMilli()
-			}
+// fetchBinancePage 抓取单个 catalog 的第 pageNo 页（回填时向后翻页使用）
+func fetchBinancePage(ctx context.Context, client *http.Client, cat, pageNo, pageSize int) ([]binanceIngestItem, error) {
+	if pageSize <= 0 || pageSize > 50 {
+		pageSize = 20
+	}
+	if pageNo <= 0 {
+		pageNo = 1
+	}
+	u := fmt.Sprintf(
+		"https://www.binance.com/bapi/composite/v1/public/cms/article/catalog/list/query?catalogId=%d&pageNo=%d&pageSize=%d",
+		cat, pageNo, pageSize,
+	)
+
+	var resp binanceCMSResp
+	if err := httpGetJSON(ctx, client, u, &resp); err != nil {
+		return nil, err
+	}
 
-			// 兜底：仍然拿不到就给当前时间，避免前端空白
-			if ms <= 0 {
-				ms = time.Now().UTC().UnixMilli()
-			}
+	items := make([]binanceIngestItem, 0, len(resp.Data.Articles))
+	for _, a := range resp.Data.Articles {
+		// ---- 时间兜底（releaseDate / publishTime，且可能是“秒”也可能是“毫秒”）----
+		ms := a.ReleaseTS
+		if ms <= 0 {
+			ms = a.PublishTs2
+		}
+		// 如果是“秒”，转成“毫秒”
+		if ms > 0 && ms < 1e12 {
+			ms *= 1000
+		}
 
-			// ---- 链接兜底（空/相对路径 -> 绝对路径；完全缺失时用 code 拼详情页）----
-			link := strings.TrimSpace(a.Link)
-			if link == "" {
-				// 你也可以改成 zh-CN
-				link = fmt.Sprintf("https://www.binance.com/en/support/announcement/%s", a.Code)
-			} else if strings.HasPrefix(link, "/") {
-				link = "https://www.binance.com" + link
-			}
+		// 仍拿不到就用当前时间兜底（避免前端空白）
+		if ms <= 0 {
+			ms = time.Now().UTC().UnixMilli()
+		}
 
-			items = append(items, binanceIngestItem{
-				Source:    "binance",
-				CatalogID: a.CatalogID,
-				Code:      a.Code,
-				Title:     a.Title,
-				Summary:   a.Summary,
-				URL:       link,
-				ReleaseMS: ms,
-			})
+		// ---- 链接兜底（空/相对路径 -> 绝对路径；完全缺失时用 code 拼详情页）----
+		link := strings.TrimSpace(a.Link)
+		if link == "" {
+			// 你也可以改成 zh-CN
+			link = fmt.Sprintf("https://www.binance.com/en/support/announcement/%s", a.Code)
+		} else if strings.HasPrefix(link, "/") {
+			link = "https://www.binance.com" + link
 		}
-		time.Sleep(200 * time.Millisecond) // 轻微限速
+
+		items = append(items, binanceIngestItem{
+			Source:    "binance",
+			CatalogID: a.CatalogID,
+			Code:      a.Code,
+			Title:     a.Title,
+			Summary:   a.Summary,
+			URL:       link,
+			ReleaseMS: ms,
+		})
 	}
 	return items, nil
 }
@@ -340,10 +355,11 @@ func httpGetJSON(ctx context.Context, client *http.Client, u string, out any) er
 
 func httpGetJSONWithRetry(ctx context.Context, client *http.Client, u string, out any, maxRetries int) error {
 	var lastErr error
+	// 指数退避：100ms, 200ms, 400ms ...
+	backoff := util.Backoff{Base: 100 * time.Millisecond, Factor: 2}
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			// 指数退避：100ms, 200ms, 400ms
-			delay := time.Duration(100*(1<<uint(attempt-1))) * time.Millisecond
+			delay, _ := backoff.Next()
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -515,6 +531,14 @@ func main() {
 	//proxyFlag := flag.String("proxy", "http://127.0.0.1:10808", "http(s) proxy, e.g. http://127.0.0.1:7890 (fallback to env HTTP_PROXY/HTTPS_PROXY)")
 	dnsFlag := flag.String("dns", "", "custom DNS servers, comma separated (e.g. 8.8.8.8,1.1.1.1)")
 	forceIPv4 := flag.Bool("force-ipv4", true, "force use IPv4 (tcp4)")
+	seenTTL := flag.Duration("seen-ttl", defaultSeenTTL, "how long an announcement key stays in the in-memory dedupe cache (0 = forever)")
+	seenPerSource := flag.Int("seen-max", defaultSeenPerSource, "max dedupe keys kept per source; least recently seen keys are evicted (0 = unlimited)")
+	fetchConcurrency := flag.Int("fetch-concurrency", defaultFetchConcurrency, "max sources fetched in parallel per poll (0 = unlimited)")
+
+	// 历史回填（一次性运行后退出）
+	backfillFrom := flag.String("backfill-from", "", "one-shot backfill: page each enabled source back to this date (YYYY-MM-DD), then exit")
+	backfillState := flag.String("backfill-state", "announce_backfill_state.json", "backfill progress file for resume")
+	backfillDelay := flag.Duration("backfill-delay", 500*time.Millisecond, "delay between backfill pages (rate limit)")
 
 	flag.Parse()
 
@@ -549,8 +573,8 @@ func main() {
 	// 连接数据库（用于读取最新公告时间）
 	var gdb *gorm.DB
 	if cfg.Database.DSN != "" {
-		var err error
-		gdb, err = pdb.OpenMySQL(pdb.Options{
+		database, err := pdb.OpenMySQL(pdb.Options{
+			Driver:       cfg.Database.Driver,
 			DSN:          cfg.Database.DSN,
 			Automigrate:  false, // scanner 不需要自动迁移
 			MaxOpenConns: 2,     // scanner 只需要少量连接
@@ -560,11 +584,8 @@ func main() {
 			log.Printf("[ann_scanner] failed to connect to database: %v, will use API fallback", err)
 		} else {
 			log.Printf("[ann_scanner] database connected successfully")
-			defer func() {
-				if sqlDB, err := gdb.DB(); err == nil {
-					sqlDB.Close()
-				}
-			}()
+			gdb = database.GormDB()
+			defer database.Close()
 		}
 	}
 
@@ -572,11 +593,35 @@ func main() {
 		*apiBase, interval.String(), cats, *upbitEnable, cfg.Proxy.HTTP != "", *forceIPv4, *dnsFlag != "")
 
 	ctx := context.Background()
+
+	if strings.TrimSpace(*backfillFrom) != "" {
+		cutoff, err := time.Parse("2006-01-02", strings.TrimSpace(*backfillFrom))
+		if err != nil {
+			log.Fatalf("[backfill] invalid -backfill-from %q: %v", *backfillFrom, err)
+		}
+		sources := buildBackfillSources(httpClient, *coincarpEnable, *binanceEnable, *okxEnable, *bybitEnable, cats, *pageSize)
+		st := loadBackfillState(*backfillState, cutoff)
+		ingest := func(ctx context.Context, path string, items []any) error {
+			var out map[string]any
+			return netutil.PostJSON(ctx, strings.TrimRight(*apiBase, "/")+path, map[string]any{"items": items}, &out)
+		}
+		added, err := runBackfill(ctx, sources, cutoff, st, ingest, backfillOptions{
+			Delay:      *backfillDelay,
+			MaxRetries: 5,
+			StatePath:  *backfillState,
+		})
+		if err != nil {
+			log.Fatalf("[backfill] stopped (rerun to resume): %v; added=%v", err, added)
+		}
+		log.Printf("[backfill] done cutoff=%s added=%v", cutoff.Format("2006-01-02"), added)
+		return
+	}
+
 	ticker := time.NewTicker(*interval)
 	defer ticker.Stop()
 
-	// 去重缓存（本进程生命周期内）
-	seen := make(map[string]struct{})
+	// 去重缓存：按数据源分片，LRU + TTL，避免长时间运行后无限增长
+	seen := newSeenCache(*seenTTL, *seenPerSource)
 
 	// 记录上次获取的最新公告时间戳（用于增量获取）
 	var lastFetchTime int64 = 0
@@ -600,122 +645,114 @@ func main() {
 		log.Printf("[ann_scanner] database not available, will start from 24h ago")
 	}
 
+	pool := server.NewWorkerPool(*fetchConcurrency)
+
 	runOnce := func() {
-		added := 0
+		var sources []pollSource
 
 		// ===== 第一层：CoinCarp（主数据源） =====
 		if *coincarpEnable {
-			// 使用上次获取时间作为起始点，只获取新公告
-			// 如果是第一次运行（lastFetchTime == 0），则获取最近 24 小时的公告
-			issuetime := lastFetchTime
-			if issuetime == 0 {
-				// 第一次运行：获取最近 24 小时的公告
-				issuetime = time.Now().Add(-24 * time.Hour).Unix()
-			}
+			sources = append(sources, pollSource{Name: "coincarp", Fetch: func(ctx context.Context) (func() int, error) {
+				// 使用上次获取时间作为起始点，只获取新公告
+				// 如果是第一次运行（lastFetchTime == 0），则获取最近 24 小时的公告
+				issuetime := lastFetchTime
+				if issuetime == 0 {
+					// 第一次运行：获取最近 24 小时的公告
+					issuetime = time.Now().Add(-24 * time.Hour).Unix()
+				}
 
-			if items, err := fetchCoinCarp(ctx, httpClient, issuetime, 50); err != nil {
-				log.Printf("[coincarp] fetch err: %v", err)
-			} else if len(items) > 0 {
-				// 从数据库查询已存在的 URL（用于去重，避免重启后重复同步）
-				existingURLs := make(map[string]struct{})
-				if gdb != nil {
-					var existing []pdb.Announcement
-					urls := make([]string, 0, len(items))
-					for _, it := range items {
-						normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
-						if normalizedURL != "" {
-							urls = append(urls, normalizedURL)
+				items, err := fetchCoinCarp(ctx, httpClient, issuetime, 50)
+				if err != nil || len(items) == 0 {
+					return nil, err
+				}
+				return func() (added int) {
+					// 从数据库查询已存在的 URL（用于去重，避免重启后重复同步）
+					existingURLs := make(map[string]struct{})
+					if gdb != nil {
+						var existing []pdb.Announcement
+						urls := make([]string, 0, len(items))
+						for _, it := range items {
+							normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
+							if normalizedURL != "" {
+								urls = append(urls, normalizedURL)
+							}
 						}
-					}
-					if len(urls) > 0 {
-						// 批量查询已存在的 URL
-						if err := gdb.Model(&pdb.Announcement{}).
-							Where("url IN ?", urls).
-							Select("url").
-							Find(&existing).Error; err == nil {
-							for _, e := range existing {
-								normalized := strings.TrimRight(strings.TrimSpace(e.URL), "/")
-								existingURLs[normalized] = struct{}{}
+						if len(urls) > 0 {
+							// 批量查询已存在的 URL
+							if err := gdb.Model(&pdb.Announcement{}).
+								Where("url IN ?", urls).
+								Select("url").
+								Find(&existing).Error; err == nil {
+								for _, e := range existing {
+									normalized := strings.TrimRight(strings.TrimSpace(e.URL), "/")
+									existingURLs[normalized] = struct{}{}
+								}
 							}
 						}
 					}
-				}
 
-				// 转换为通用格式，并去重（内存 + 数据库）
-				genericItems := make([]map[string]any, 0, len(items))
-				for _, it := range items {
-					// 标准化 URL（去除末尾斜杠和空格）
-					normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
-					if normalizedURL == "" {
-						continue
-					}
+					// 转换为通用格式，并去重（内存 + 数据库）
+					genericItems := make([]map[string]any, 0, len(items))
+					for _, it := range items {
+						// 标准化 URL（去除末尾斜杠和空格）
+						normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
+						if normalizedURL == "" {
+							continue
+						}
 
-					// 内存去重（本进程生命周期内）
-					key := "coincarp|" + normalizedURL
-					if _, ok := seen[key]; ok {
-						continue // 已处理过，跳过
-					}
+						// 内存去重（近期已处理）
+						if seen.Seen("coincarp", normalizedURL) {
+							continue // 已处理过，跳过
+						}
 
-					// 数据库去重（避免重启后重复）
-					if _, ok := existingURLs[normalizedURL]; ok {
-						seen[key] = struct{}{} // 标记为已处理，避免下次重复查询
-						continue               // 数据库中已存在，跳过
-					}
+						// 数据库去重（避免重启后重复）
+						if _, ok := existingURLs[normalizedURL]; ok {
+							seen.Mark("coincarp", normalizedURL) // 标记为已处理，避免下次重复查询
+							continue                             // 数据库中已存在，跳过
+						}
 
-					seen[key] = struct{}{}
-
-					// 使用标准化后的 URL
-					it.URL = normalizedURL
-
-					genericItems = append(genericItems, map[string]any{
-						"source":      it.Source,
-						"external_id": it.ExternalID,
-						"news_code":   it.NewsCode,
-						"title":       it.Title,
-						"summary":     it.Summary,
-						"url":         it.URL,
-						"tags":        it.Tags,
-						"release_ms":  it.ReleaseMS,
-						"exchange":    it.Exchange,
-						"is_event":    false,
-						"sentiment":   "",
-						"heat_score":  0,
-						"verified":    false,
-					})
-				}
-				if len(genericItems) > 0 {
-					payload := map[string]any{"items": genericItems}
-					postURL := strings.TrimRight(*apiBase, "/") + "/ingest/coincarp/announcements"
-					var out map[string]any
-					if err := netutil.PostJSON(ctx, postURL, payload, &out); err != nil {
-						log.Printf("[coincarp] ingest err: %v", err)
-					} else {
-						log.Printf("[coincarp] ingested: %v (count=%d, filtered=%d)", out, len(genericItems), len(items)-len(genericItems))
-						added += len(genericItems)
+						seen.Mark("coincarp", normalizedURL)
 
-						// 更新上次获取时间：使用本次获取的最新公告时间
-						maxTime := issuetime
-						for _, it := range items {
-							// releaseMS 是毫秒，转换为秒
-							itemTime := it.ReleaseMS / 1000
-							if itemTime > maxTime {
-								maxTime = itemTime
+						// 使用标准化后的 URL
+						it.URL = normalizedURL
+
+						genericItems = append(genericItems, coincarpGenericItem(it))
+					}
+					if len(genericItems) > 0 {
+						payload := map[string]any{"items": genericItems}
+						postURL := strings.TrimRight(*apiBase, "/") + "/ingest/coincarp/announcements"
+						var out map[string]any
+						if err := netutil.PostJSON(ctx, postURL, payload, &out); err != nil {
+							log.Printf("[coincarp] ingest err: %v", err)
+						} else {
+							log.Printf("[coincarp] ingested: %v (count=%d, filtered=%d)", out, len(genericItems), len(items)-len(genericItems))
+							added += len(genericItems)
+
+							// 更新上次获取时间：使用本次获取的最新公告时间
+							maxTime := issuetime
+							for _, it := range items {
+								// releaseMS 是毫秒，转换为秒
+								itemTime := it.ReleaseMS / 1000
+								if itemTime > maxTime {
+									maxTime = itemTime
+								}
+							}
+							if maxTime > lastFetchTime {
+								lastFetchTime = maxTime
+								log.Printf("[coincarp] updated last fetch time to %d (%s)", lastFetchTime, time.Unix(lastFetchTime, 0).UTC().Format(time.RFC3339))
 							}
 						}
-						if maxTime > lastFetchTime {
-							lastFetchTime = maxTime
-							log.Printf("[coincarp] updated last fetch time to %d (%s)", lastFetchTime, time.Unix(lastFetchTime, 0).UTC().Format(time.RFC3339))
+					} else {
+						log.Printf("[coincarp] all items already seen, skipped")
+						// 即使没有新数据，也更新时间为当前时间（避免重复获取旧数据）
+						currentTime := time.Now().Unix()
+						if currentTime > lastFetchTime {
+							lastFetchTime = currentTime
 						}
 					}
-				} else {
-					log.Printf("[coincarp] all items already seen, skipped")
-					// 即使没有新数据，也更新时间为当前时间（避免重复获取旧数据）
-					currentTime := time.Now().Unix()
-					if currentTime > lastFetchTime {
-						lastFetchTime = currentTime
-					}
-				}
-			}
+					return added
+				}, nil
+			}})
 		}
 
 		// ===== 第二层：CryptoPanic（验证和过滤） =====
@@ -789,150 +826,151 @@ func main() {
 		// ===== 第三层：Binance（校验和补齐） =====
 		// Binance 功能暂时关闭，保留代码以便将来恢复
 		if *binanceEnable {
-			if items, err := fetchBinance(ctx, httpClient, cats, *pageSize); err != nil {
-				log.Printf("[binance] fetch err: %v", err)
-			} else if len(items) > 0 {
-				payload := binanceIngestReq{Items: make([]binanceIngestItem, 0, len(items))}
-				for _, it := range items {
-					key := "binance|" + it.Code
-					if _, ok := seen[key]; ok {
-						continue
-					}
-					seen[key] = struct{}{}
-					payload.Items = append(payload.Items, it)
+			sources = append(sources, pollSource{Name: "binance", Fetch: func(ctx context.Context) (func() int, error) {
+				items, err := fetchBinance(ctx, httpClient, cats, *pageSize)
+				if err != nil || len(items) == 0 {
+					return nil, err
 				}
-				if len(payload.Items) > 0 {
-					postURL := strings.TrimRight(*apiBase, "/") + "/ingest/binance/announcements"
-					var out map[string]any
-					if err := netutil.PostJSON(ctx, postURL, &payload, &out); err != nil {
-						log.Printf("[binance] ingest err: %v", err)
-					} else {
-						log.Printf("[binance] ingested: %v (count=%d)", out, len(payload.Items))
-						added += len(payload.Items)
+				return func() (added int) {
+					payload := binanceIngestReq{Items: make([]binanceIngestItem, 0, len(items))}
+					for _, it := range items {
+						key := it.Code
+						if seen.Seen("binance", key) {
+							continue
+						}
+						seen.Mark("binance", key)
+						payload.Items = append(payload.Items, it)
 					}
-				}
-			}
+					if len(payload.Items) > 0 {
+						postURL := strings.TrimRight(*apiBase, "/") + "/ingest/binance/announcements"
+						var out map[string]any
+						if err := netutil.PostJSON(ctx, postURL, &payload, &out); err != nil {
+							log.Printf("[binance] ingest err: %v", err)
+						} else {
+							log.Printf("[binance] ingested: %v (count=%d)", out, len(payload.Items))
+							added += len(payload.Items)
+						}
+					}
+					return added
+				}, nil
+			}})
 		}
 
 		// ===== 第三层：OKX（校验和补齐） =====
 		if *okxEnable {
-			if items, err := fetchOKX(ctx, httpClient, 20); err != nil {
-				log.Printf("[okx] fetch err: %v", err)
-			} else if len(items) > 0 {
-				genericItems := make([]map[string]any, 0, len(items))
-				for _, it := range items {
-					// 标准化 URL
-					normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
-					// 使用 URL 作为去重键
-					key := "okx|" + normalizedURL
-					if _, ok := seen[key]; ok {
-						continue
-					}
-					seen[key] = struct{}{}
-
-					// 使用标准化后的 URL
-					it.URL = normalizedURL
-
-					genericItems = append(genericItems, map[string]any{
-						"source":      "okx",
-						"external_id": it.Code,
-						"title":       it.Title,
-						"summary":     it.Summary,
-						"url":         it.URL,
-						"tags":        []string{},
-						"release_ms":  it.ReleaseMS,
-						"verified":    true, // 第三层：官方源
-					})
+			sources = append(sources, pollSource{Name: "okx", Fetch: func(ctx context.Context) (func() int, error) {
+				items, err := fetchOKX(ctx, httpClient, 20)
+				if err != nil || len(items) == 0 {
+					return nil, err
 				}
-				if len(genericItems) > 0 {
-					payload := map[string]any{"items": genericItems}
-					postURL := strings.TrimRight(*apiBase, "/") + "/ingest/okx/announcements"
-					var out map[string]any
-					if err := netutil.PostJSON(ctx, postURL, payload, &out); err != nil {
-						log.Printf("[okx] ingest err: %v", err)
+				return func() (added int) {
+					genericItems := make([]map[string]any, 0, len(items))
+					for _, it := range items {
+						// 标准化 URL
+						normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
+						// 使用 URL 作为去重键
+						if seen.Seen("okx", normalizedURL) {
+							continue
+						}
+						seen.Mark("okx", normalizedURL)
+
+						// 使用标准化后的 URL
+						it.URL = normalizedURL
+
+						genericItems = append(genericItems, officialGenericItem("okx", it))
+					}
+					if len(genericItems) > 0 {
+						payload := map[string]any{"items": genericItems}
+						postURL := strings.TrimRight(*apiBase, "/") + "/ingest/okx/announcements"
+						var out map[string]any
+						if err := netutil.PostJSON(ctx, postURL, payload, &out); err != nil {
+							log.Printf("[okx] ingest err: %v", err)
+						} else {
+							log.Printf("[okx] ingested: %v (count=%d, filtered=%d)", out, len(genericItems), len(items)-len(genericItems))
+							added += len(genericItems)
+						}
 					} else {
-						log.Printf("[okx] ingested: %v (count=%d, filtered=%d)", out, len(genericItems), len(items)-len(genericItems))
-						added += len(genericItems)
+						log.Printf("[okx] all items already seen, skipped")
 					}
-				} else {
-					log.Printf("[okx] all items already seen, skipped")
-				}
-			}
+					return added
+				}, nil
+			}})
 		}
 
 		// ===== 第三层：Bybit（校验和补齐） =====
 		if *bybitEnable {
-			if items, err := fetchBybit(ctx, httpClient, 20); err != nil {
-				log.Printf("[bybit] fetch err: %v", err)
-			} else if len(items) > 0 {
-				genericItems := make([]map[string]any, 0, len(items))
-				for _, it := range items {
-					// 标准化 URL
-					normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
-					// 使用 URL 作为去重键
-					key := "bybit|" + normalizedURL
-					if _, ok := seen[key]; ok {
-						continue
-					}
-					seen[key] = struct{}{}
-
-					// 使用标准化后的 URL
-					it.URL = normalizedURL
-
-					genericItems = append(genericItems, map[string]any{
-						"source":      "bybit",
-						"external_id": it.Code,
-						"title":       it.Title,
-						"summary":     it.Summary,
-						"url":         it.URL,
-						"tags":        []string{},
-						"release_ms":  it.ReleaseMS,
-						"verified":    true, // 第三层：官方源
-					})
+			sources = append(sources, pollSource{Name: "bybit", Fetch: func(ctx context.Context) (func() int, error) {
+				items, err := fetchBybit(ctx, httpClient, 20)
+				if err != nil || len(items) == 0 {
+					return nil, err
 				}
-				if len(genericItems) > 0 {
-					payload := map[string]any{"items": genericItems}
-					postURL := strings.TrimRight(*apiBase, "/") + "/ingest/bybit/announcements"
-					var out map[string]any
-					if err := netutil.PostJSON(ctx, postURL, payload, &out); err != nil {
-						log.Printf("[bybit] ingest err: %v", err)
+				return func() (added int) {
+					genericItems := make([]map[string]any, 0, len(items))
+					for _, it := range items {
+						// 标准化 URL
+						normalizedURL := strings.TrimRight(strings.TrimSpace(it.URL), "/")
+						// 使用 URL 作为去重键
+						if seen.Seen("bybit", normalizedURL) {
+							continue
+						}
+						seen.Mark("bybit", normalizedURL)
+
+						// 使用标准化后的 URL
+						it.URL = normalizedURL
+
+						genericItems = append(genericItems, officialGenericItem("bybit", it))
+					}
+					if len(genericItems) > 0 {
+						payload := map[string]any{"items": genericItems}
+						postURL := strings.TrimRight(*apiBase, "/") + "/ingest/bybit/announcements"
+						var out map[string]any
+						if err := netutil.PostJSON(ctx, postURL, payload, &out); err != nil {
+							log.Printf("[bybit] ingest err: %v", err)
+						} else {
+							log.Printf("[bybit] ingested: %v (count=%d, filtered=%d)", out, len(genericItems), len(items)-len(genericItems))
+							added += len(genericItems)
+						}
 					} else {
-						log.Printf("[bybit] ingested: %v (count=%d, filtered=%d)", out, len(genericItems), len(items)-len(genericItems))
-						added += len(genericItems)
+						log.Printf("[bybit] all items already seen, skipped")
 					}
-				} else {
-					log.Printf("[bybit] all items already seen, skipped")
-				}
-			}
+					return added
+				}, nil
+			}})
 		}
 
 		// ===== 第三层：Upbit（校验和补齐） =====
 		if *upbitEnable {
-			if items, err := fetchUpbit(ctx, httpClient, *upbitPageSize); err != nil {
-				log.Printf("[upbit] fetch err: %v", err)
-			} else if len(items) > 0 {
-				payload := upbitIngestReq{Items: make([]upbitIngestItem, 0, len(items))}
-				for _, it := range items {
-					key := "upbit|" + strconv.FormatInt(it.ID, 10)
-					if _, ok := seen[key]; ok {
-						continue
-					}
-					seen[key] = struct{}{}
-					payload.Items = append(payload.Items, it)
+			sources = append(sources, pollSource{Name: "upbit", Fetch: func(ctx context.Context) (func() int, error) {
+				items, err := fetchUpbit(ctx, httpClient, *upbitPageSize)
+				if err != nil || len(items) == 0 {
+					return nil, err
 				}
-				if len(payload.Items) > 0 {
-					postURL := strings.TrimRight(*apiBase, "/") + "/ingest/upbit/announcements"
-					var out map[string]any
-					if err := netutil.PostJSON(ctx, postURL, &payload, &out); err != nil {
-						log.Printf("[upbit] ingest err: %v", err)
-					} else {
-						log.Printf("[upbit] ingested: %v (count=%d)", out, len(payload.Items))
-						added += len(payload.Items)
+				return func() (added int) {
+					payload := upbitIngestReq{Items: make([]upbitIngestItem, 0, len(items))}
+					for _, it := range items {
+						key := strconv.FormatInt(it.ID, 10)
+						if seen.Seen("upbit", key) {
+							continue
+						}
+						seen.Mark("upbit", key)
+						payload.Items = append(payload.Items, it)
 					}
-				}
-			}
+					if len(payload.Items) > 0 {
+						postURL := strings.TrimRight(*apiBase, "/") + "/ingest/upbit/announcements"
+						var out map[string]any
+						if err := netutil.PostJSON(ctx, postURL, &payload, &out); err != nil {
+							log.Printf("[upbit] ingest err: %v", err)
+						} else {
+							log.Printf("[upbit] ingested: %v (count=%d)", out, len(payload.Items))
+							added += len(payload.Items)
+						}
+					}
+					return added
+				}, nil
+			}})
 		}
 
+		added := runPollSources(ctx, pool, sources)
 		log.Printf("[ann_scanner] poll done; added=%d", added)
 	}
 
@@ -943,6 +981,40 @@ func main() {
 	}
 }
 
+// coincarpGenericItem 转换为 /ingest/:source/announcements 的通用条目
+func coincarpGenericItem(it coincarpItem) map[string]any {
+	return map[string]any{
+		"source":      it.Source,
+		"external_id": it.ExternalID,
+		"news_code":   it.NewsCode,
+		"title":       it.Title,
+		"summary":     it.Summary,
+		"url":         it.URL,
+		"tags":        it.Tags,
+		"release_ms":  it.ReleaseMS,
+		"exchange":    it.Exchange,
+		"is_event":    false,
+		"sentiment":   "",
+		"heat_score":  0,
+		"verified":    false,
+	}
+}
+
+// officialGenericItem 第三层官方源（okx/bybit）的通用条目
+func officialGenericItem(source string, it binanceIngestItem) map[string]any {
+	return map[string]any{
+		"source":      source,
+		"external_id": it.Code,
+		"title":       it.Title,
+		"summary":     it.Summary,
+		"url":         it.URL,
+		"tags":        []string{},
+		"category":    it.Category,
+		"release_ms":  it.ReleaseMS,
+		"verified":    true, // 第三层：官方源
+	}
+}
+
 // 解析 "48,49,93" -> []int
 func parseCatalogs(s string) []int {
 	parts := strings.Split(s, ",")
@@ -0,0 +1,48 @@
+// cmd/announce_scanner/poll.go
+// 常规轮询：各数据源并发拉取（受协程池限制），拉取完成后各自立即入库。
+// 入库阶段串行执行（共享去重缓存与增量时间戳），单个数据源的条目按原顺序整批入库，慢源不会拖住其它源。
+
+package main
+
+import (
+	"analysis/internal/server"
+	"context"
+	"log"
+	"sync"
+)
+
+// defaultFetchConcurrency 默认同时拉取的数据源数量
+const defaultFetchConcurrency = 4
+
+// pollSource 一个轮询数据源
+type pollSource struct {
+	Name string
+	// Fetch 拉取数据；返回的 ingest 在入库阶段执行，返回新增条数
+	Fetch func(ctx context.Context) (ingest func() int, err error)
+}
+
+// runPollSources 通过协程池并发拉取各数据源，返回本轮新增条数
+func runPollSources(ctx context.Context, pool *server.WorkerPool, sources []pollSource) int {
+	var (
+		ingestMu sync.Mutex
+		added    int
+	)
+	for _, src := range sources {
+		src := src
+		pool.Submit(func() {
+			ingest, err := src.Fetch(ctx)
+			if err != nil {
+				log.Printf("[%s] fetch err: %v", src.Name, err)
+				return
+			}
+			if ingest == nil {
+				return
+			}
+			ingestMu.Lock()
+			defer ingestMu.Unlock()
+			added += ingest()
+		})
+	}
+	pool.Wait()
+	return added
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"analysis/internal/server"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRunPollSourcesFetchesConcurrently 各源的拉取同时进行，且各自条目按原顺序整批入库
+func TestRunPollSourcesFetchesConcurrently(t *testing.T) {
+	names := []string{"coincarp", "okx", "bybit", "upbit"}
+	var started sync.WaitGroup
+	started.Add(len(names))
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+
+	var mu sync.Mutex
+	ingested := map[string][]int{}
+	var sources []pollSource
+	for _, name := range names {
+		name := name
+		sources = append(sources, pollSource{Name: name, Fetch: func(ctx context.Context) (func() int, error) {
+			started.Done()
+			// 所有源都进入拉取阶段后才返回；串行拉取会在此超时
+			select {
+			case <-allStarted:
+			case <-time.After(2 * time.Second):
+				return nil, errors.New("fetch not concurrent")
+			}
+			items := []int{1, 2, 3}
+			return func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				ingested[name] = append(ingested[name], items...)
+				return len(items)
+			}, nil
+		}})
+	}
+
+	added := runPollSources(context.Background(), server.NewWorkerPool(len(names)), sources)
+	if added != 12 {
+		t.Fatalf("期望新增 12 条，实际 %d", added)
+	}
+	for _, name := range names {
+		if got := ingested[name]; len(got) != 3 || got[0] != 1 || got[2] != 3 {
+			t.Errorf("%s 入库结果不符: %v", name, got)
+		}
+	}
+}
+
+// TestRunPollSourcesBounded 并发数受协程池限制，失败的源不影响其它源入库
+func TestRunPollSourcesBounded(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	var sources []pollSource
+	for i := 0; i < 6; i++ {
+		fail := i == 0
+		sources = append(sources, pollSource{Name: "src", Fetch: func(ctx context.Context) (func() int, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if fail {
+				return nil, errors.New("upstream 502")
+			}
+			return func() int { return 1 }, nil
+		}})
+	}
+
+	added := runPollSources(context.Background(), server.NewWorkerPool(2), sources)
+	if added != 5 {
+		t.Errorf("期望新增 5 条（1 个源失败），实际 %d", added)
+	}
+	if peak > 2 {
+		t.Errorf("并发上限为 2，实际峰值 %d", peak)
+	}
+}
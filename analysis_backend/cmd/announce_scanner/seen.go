@@ -0,0 +1,98 @@
+// cmd/announce_scanner/seen.go
+// 公告去重缓存：按数据源分片（各自加锁，拉取并发时互不阻塞），分片内 LRU + TTL。
+// 原先的 map 在进程生命周期内只增不减；现在超过容量淘汰最久未访问的键，超过 TTL 的键视为未见过，
+// 近期重复仍会被拦下（接口本身按 URL/编号幂等，过期后偶尔重复上报无害）。
+
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSeenTTL       = 7 * 24 * time.Hour // 交易所公告列表通常只保留最近几天的条目
+	defaultSeenPerSource = 5000
+)
+
+// seenCache 分数据源的去重缓存
+type seenCache struct {
+	ttl      time.Duration // <=0 表示不过期
+	capacity int           // 每个数据源的上限，<=0 表示不限
+	now      func() time.Time
+
+	mu     sync.Mutex
+	shards map[string]*seenShard
+}
+
+type seenShard struct {
+	mu    sync.Mutex
+	order *list.List // 前端为最近访问
+	items map[string]*list.Element
+}
+
+type seenEntry struct {
+	key  string
+	seen time.Time // 最近一次标记时间，TTL 从这里算
+}
+
+func newSeenCache(ttl time.Duration, perSource int) *seenCache {
+	return &seenCache{ttl: ttl, capacity: perSource, now: time.Now, shards: map[string]*seenShard{}}
+}
+
+func (c *seenCache) shard(source string) *seenShard {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.shards[source]
+	if s == nil {
+		s = &seenShard{order: list.New(), items: map[string]*list.Element{}}
+		c.shards[source] = s
+	}
+	return s
+}
+
+// Seen 键在 TTL 内标记过；命中时刷新 LRU 位置（不延长 TTL），过期的键顺便删除
+func (c *seenCache) Seen(source, key string) bool {
+	s := c.shard(source)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.items[key]
+	if !ok {
+		return false
+	}
+	if c.ttl > 0 && c.now().Sub(el.Value.(*seenEntry).seen) > c.ttl {
+		s.order.Remove(el)
+		delete(s.items, key)
+		return false
+	}
+	s.order.MoveToFront(el)
+	return true
+}
+
+// Mark 标记键；超过容量时淘汰最久未访问的键
+func (c *seenCache) Mark(source, key string) {
+	s := c.shard(source)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := c.now()
+	if el, ok := s.items[key]; ok {
+		el.Value.(*seenEntry).seen = now
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.order.PushFront(&seenEntry{key: key, seen: now})
+	for c.capacity > 0 && s.order.Len() > c.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*seenEntry).key)
+	}
+}
+
+// Len 数据源当前缓存的键数
+func (c *seenCache) Len(source string) int {
+	s := c.shard(source)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
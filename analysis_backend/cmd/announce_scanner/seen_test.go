@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSeenCacheEvictsPastCap 超过每源容量时淘汰最久未访问的键，近期访问过的键保留；各数据源独立计数
+func TestSeenCacheEvictsPastCap(t *testing.T) {
+	c := newSeenCache(0, 3)
+	for _, k := range []string{"a", "b", "c"} {
+		c.Mark("okx", k)
+	}
+	if !c.Seen("okx", "a") { // a 变为最近访问
+		t.Fatal("a 应已标记")
+	}
+	c.Mark("okx", "d")
+	if c.Seen("okx", "b") {
+		t.Error("超过容量后最久未访问的 b 应被淘汰")
+	}
+	for _, k := range []string{"a", "c", "d"} {
+		if !c.Seen("okx", k) {
+			t.Errorf("%s 不应被淘汰", k)
+		}
+	}
+	if n := c.Len("okx"); n != 3 {
+		t.Errorf("缓存大小应封顶 3，实际 %d", n)
+	}
+
+	c.Mark("bybit", "a")
+	if c.Seen("bybit", "b") || !c.Seen("bybit", "a") || c.Len("okx") != 3 {
+		t.Error("数据源之间应互不影响")
+	}
+}
+
+// TestSeenCacheTTL TTL 内的重复仍被拦下，过期后视为新键；Seen 不延长 TTL，重新 Mark 才延长
+func TestSeenCacheTTL(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := newSeenCache(time.Hour, 0)
+	c.now = func() time.Time { return now }
+
+	c.Mark("binance", "A1")
+	c.Mark("binance", "A2")
+	now = now.Add(50 * time.Minute)
+	if !c.Seen("binance", "A1") || !c.Seen("binance", "A2") {
+		t.Fatal("TTL 内应去重")
+	}
+	c.Mark("binance", "A2")
+	now = now.Add(20 * time.Minute)
+	if c.Seen("binance", "A1") {
+		t.Error("A1 标记已超过 TTL，应视为未见过")
+	}
+	if !c.Seen("binance", "A2") {
+		t.Error("A2 重新标记后 TTL 应顺延")
+	}
+	if n := c.Len("binance"); n != 1 {
+		t.Errorf("过期键应被删除，实际剩 %d", n)
+	}
+}
+
+// TestSeenCacheConcurrentSources 多个数据源并发读写（配合 -race）
+func TestSeenCacheConcurrentSources(t *testing.T) {
+	c := newSeenCache(time.Hour, 100)
+	var wg sync.WaitGroup
+	for _, src := range []string{"coincarp", "binance", "okx", "bybit", "upbit"} {
+		wg.Add(1)
+		go func(src string) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				k := fmt.Sprint(i)
+				if !c.Seen(src, k) {
+					c.Mark(src, k)
+				}
+			}
+		}(src)
+	}
+	wg.Wait()
+	for _, src := range []string{"coincarp", "binance", "okx", "bybit", "upbit"} {
+		if n := c.Len(src); n != 100 {
+			t.Errorf("%s 缓存大小应为 100，实际 %d", src, n)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -20,11 +21,14 @@ import (
 
 func main() {
 	// 命令行参数
-	action := flag.String("action", "auto-sync", "操作类型: sync(同步资产映射), market-data(同步市值数据), auto-sync(自动同步市值数据), validate(验证映射), search(搜索资产), stats(统计信息)")
+	action := flag.String("action", "auto-sync", "操作类型: sync(同步资产映射), market-data(同步市值数据), auto-sync(自动同步市值数据), validate(验证映射), search(搜索资产), stats(统计信息), coverage(交易对映射覆盖率)")
 	query := flag.String("query", "", "搜索关键词（用于search操作）")
 	limit := flag.Int("limit", 100, "搜索结果限制数量（用于search操作）")
 	interval := flag.Int("interval", 10080, "自动同步间隔（分钟，默认10080分钟，即7天）")
 	cfgPath := flag.String("config", "./config.yaml", "配置文件路径")
+	quote := flag.String("quote", "USDT", "计价资产过滤，为空统计全部（用于coverage操作）")
+	suggest := flag.Int("suggest", 3, "每个未映射资产给出的候选映射数量，0 表示不给出（用于coverage操作）")
+	workers := flag.Int("workers", 4, "市值数据分页并发拉取数（1 为串行，用于market-data/auto-sync操作）")
 	apiKey := flag.String("api-key", "292ca5251c7eab03e55f5f01f960dc635f00e2294e3963d0293764e36ff69080", "CoinCap API密钥（可选）")
 
 	flag.Parse()
@@ -47,6 +51,7 @@ func main() {
 
 	// 2. 初始化数据库连接
 	database, err := db.OpenMySQL(db.Options{
+		Driver:          cfg.Database.Driver,
 		DSN:             cfg.Database.DSN,
 		Automigrate:     true, // 确保表已创建
 		MaxOpenConns:    cfg.Database.MaxOpenConns,
@@ -75,15 +80,17 @@ func main() {
 	case "sync":
 		runSyncAction(ctx, mappingService, *apiKey)
 	case "market-data":
-		runMarketDataSyncAction(ctx, gormDB, *apiKey)
+		runMarketDataSyncAction(ctx, gormDB, *apiKey, *workers)
 	case "auto-sync":
-		runAutoSyncAction(ctx, gormDB, *apiKey, *interval)
+		runAutoSyncAction(ctx, gormDB, *apiKey, *interval, *workers)
 	case "validate":
 		runValidateAction(ctx, mappingService)
 	case "search":
 		runSearchAction(ctx, mappingService, *query, *limit)
 	case "stats":
 		runStatsAction(ctx, mappingService)
+	case "coverage":
+		runCoverageAction(ctx, mappingService, *quote, *suggest)
 	default:
 		fmt.Printf("[coincap_sync] unknown action: %s\n", *action)
 	}
@@ -115,7 +122,7 @@ func runSyncAction(ctx context.Context, mappingService *db.CoinCapMappingService
 }
 
 // runMarketDataSyncAction 执行市值数据同步操作
-func runMarketDataSyncAction(ctx context.Context, gormDB *gorm.DB, apiKey string) {
+func runMarketDataSyncAction(ctx context.Context, gormDB *gorm.DB, apiKey string, workers int) {
 	log.Printf("[coincap_sync] 正在同步CoinCap市值数据...")
 
 	// 如果没有提供API密钥，使用默认值
@@ -127,6 +134,7 @@ func runMarketDataSyncAction(ctx context.Context, gormDB *gorm.DB, apiKey string
 	// 创建市值数据同步服务
 	marketDataService := db.NewCoinCapMarketDataService(gormDB)
 	syncService := server.NewCoinCapMarketDataSyncService(marketDataService, apiKey)
+	syncService.SetIngestOptions(0, workers, -1)
 
 	// 执行市值数据同步
 	startTime := time.Now()
@@ -217,13 +225,40 @@ func runStatsAction(ctx context.Context, mappingService *db.CoinCapMappingServic
 	}
 }
 
+// runCoverageAction 执行映射覆盖率检查操作
+func runCoverageAction(ctx context.Context, mappingService *db.CoinCapMappingService, quote string, suggest int) {
+	log.Printf("[coincap_sync] 正在检查交易对的CoinCap映射覆盖率，计价资产: %q", quote)
+
+	syncService := server.NewCoinCapAssetSyncService(mappingService, "")
+
+	report, err := syncService.CoverageReport(ctx, quote, suggest)
+	if err != nil {
+		log.Fatalf("[coincap_sync] 覆盖率检查失败: %v", err)
+	}
+
+	log.Printf("[coincap_sync] 映射覆盖率: %d/%d (%.1f%%)，未映射 %d 个",
+		report.MappedSymbols, report.TotalSymbols, report.Coverage*100, len(report.Unmapped))
+	for _, item := range report.Unmapped {
+		if len(item.Suggestions) == 0 {
+			log.Printf("  %s", item.Symbol)
+			continue
+		}
+		candidates := make([]string, 0, len(item.Suggestions))
+		for _, m := range item.Suggestions {
+			candidates = append(candidates, fmt.Sprintf("%s(%s, 排名%s)", m.Symbol, m.AssetID, m.Rank))
+		}
+		log.Printf("  %s -> 候选: %s", item.Symbol, strings.Join(candidates, ", "))
+	}
+}
+
 // runAutoSyncAction 执行自动同步市值数据操作
-func runAutoSyncAction(ctx context.Context, gormDB *gorm.DB, apiKey string, intervalMinutes int) {
+func runAutoSyncAction(ctx context.Context, gormDB *gorm.DB, apiKey string, intervalMinutes, workers int) {
 	log.Printf("[coincap_sync] 开始自动同步市值数据，间隔: %d 分钟", intervalMinutes)
 
 	// 创建市值数据同步服务
 	marketDataService := db.NewCoinCapMarketDataService(gormDB)
 	syncService := server.NewCoinCapMarketDataSyncService(marketDataService, apiKey)
+	syncService.SetIngestOptions(0, workers, -1)
 
 	// 创建信号通道用于优雅退出
 	sigChan := make(chan os.Signal, 1)
@@ -302,6 +302,9 @@ type DepthSyncer struct {
 		successfulAPICalls int64
 		totalLatency       time.Duration
 	}
+
+	// 每轮结果上报给监控
+	cycleReporter
 }
 
 func NewDepthSyncer(db *gorm.DB, cfg *config.Config, config *DataSyncConfig, redisCache *RedisInvalidSymbolCache) *DepthSyncer {
@@ -442,7 +445,7 @@ func (s *DepthSyncer) Stop() {
 	log.Printf("[DepthSyncer] Stop signal received")
 }
 
-func (s *DepthSyncer) Sync(ctx context.Context) error {
+func (s *DepthSyncer) Sync(ctx context.Context) (err error) {
 	s.stats.mu.Lock()
 	s.stats.totalSyncs++
 	syncStartTime := time.Now()
@@ -456,6 +459,7 @@ func (s *DepthSyncer) Sync(ctx context.Context) error {
 
 	totalUpdates := 0
 	totalErrors := 0
+	defer func() { s.reportCycle(syncStartTime, totalUpdates, err) }()
 
 	// 同步现货市场深度
 	if len(syncerConfig.SpotSymbols) > 0 {
@@ -30,6 +30,9 @@ type ExchangeInfoSyncer struct {
 		lastSyncTime    time.Time
 		totalSymbols    int64
 	}
+
+	// 每轮结果上报给监控
+	cycleReporter
 }
 
 func NewExchangeInfoSyncer(db *gorm.DB, cfg *config.Config, config *DataSyncConfig) *ExchangeInfoSyncer {
@@ -67,13 +70,16 @@ func (s *ExchangeInfoSyncer) Stop() {
 	log.Printf("[ExchangeInfoSyncer] Exchange info syncer stopped")
 }
 
-func (s *ExchangeInfoSyncer) Sync(ctx context.Context) error {
+func (s *ExchangeInfoSyncer) Sync(ctx context.Context) (err error) {
 	s.stats.mu.Lock()
 	s.stats.totalSyncs++
 	syncStartTime := time.Now()
 	s.stats.lastSyncTime = syncStartTime
 	s.stats.mu.Unlock()
 
+	rows := 0
+	defer func() { s.reportCycle(syncStartTime, rows, err) }()
+
 	log.Printf("[ExchangeInfoSyncer] Starting exchange info sync with soft delete support...")
 
 	// 获取现货交易对信息
@@ -96,6 +102,7 @@ func (s *ExchangeInfoSyncer) Sync(ctx context.Context) error {
 
 	// 合并交易对信息
 	allSymbols := append(spotSymbols, futuresSymbols...)
+	rows = len(allSymbols)
 	log.Printf("[ExchangeInfoSyncer] Fetched %d total symbols from API (%d spot, %d futures)",
 		len(allSymbols), len(spotSymbols), len(futuresSymbols))
 
@@ -33,6 +33,9 @@ type FuturesSyncer struct {
 		lastSyncTime         time.Time
 		totalContractUpdates int64
 	}
+
+	// 每轮结果上报给监控
+	cycleReporter
 }
 
 func NewFuturesSyncer(db *gorm.DB, cfg *config.Config, config *DataSyncConfig) *FuturesSyncer {
@@ -70,12 +73,16 @@ func (s *FuturesSyncer) Stop() {
 	log.Printf("[FuturesSyncer] Stop signal received")
 }
 
-func (s *FuturesSyncer) Sync(ctx context.Context) error {
+func (s *FuturesSyncer) Sync(ctx context.Context) (err error) {
 	s.stats.mu.Lock()
 	s.stats.totalSyncs++
-	s.stats.lastSyncTime = time.Now()
+	syncStartTime := time.Now()
+	s.stats.lastSyncTime = syncStartTime
 	s.stats.mu.Unlock()
 
+	totalUpdates := 0
+	defer func() { s.reportCycle(syncStartTime, totalUpdates, err) }()
+
 	log.Printf("[FuturesSyncer] Starting futures info sync...")
 
 	// 同步合约信息
@@ -95,7 +102,7 @@ func (s *FuturesSyncer) Sync(ctx context.Context) error {
 		// 不返回错误，继续
 	}
 
-	totalUpdates := contractUpdates + fundingUpdates
+	totalUpdates = contractUpdates + fundingUpdates
 
 	s.stats.mu.Lock()
 	s.stats.successfulSyncs++
@@ -545,6 +545,9 @@ type KlineSyncer struct {
 		totalAPILatency    time.Duration
 		lastAPILatency     time.Duration
 	}
+
+	// 每轮结果上报给监控
+	cycleReporter
 }
 
 func NewKlineSyncer(db *gorm.DB, server interface{}, cfg *config.Config, config *DataSyncConfig, redisCache *RedisInvalidSymbolCache) *KlineSyncer {
@@ -780,7 +783,7 @@ func (s *KlineSyncer) Stop() {
 	log.Printf("[KlineSyncer] Stop signal received")
 }
 
-func (s *KlineSyncer) Sync(ctx context.Context) error {
+func (s *KlineSyncer) Sync(ctx context.Context) (err error) {
 	s.stats.mu.Lock()
 	syncStartTime := time.Now()
 	s.stats.totalSyncs++
@@ -797,6 +800,7 @@ func (s *KlineSyncer) Sync(ctx context.Context) error {
 
 	totalUpdates := 0
 	totalErrors := 0
+	defer func() { s.reportCycle(syncStartTime, totalUpdates, err) }()
 
 	// 同步现货市场
 	if len(syncerConfig.SpotSymbols) > 0 {
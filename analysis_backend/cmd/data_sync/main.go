@@ -7,10 +7,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
-	"sync"
 	"syscall"
 	"time"
 
@@ -36,7 +37,8 @@ type DataSyncService struct {
 	syncers map[string]DataSyncer
 
 	// 监控
-	monitor *DataSyncMonitor
+	monitor       *DataSyncMonitor
+	metricsServer *http.Server // /metrics 与 /errors
 
 	// 智能调度器
 	smartScheduler *SmartScheduler
@@ -112,9 +114,10 @@ type DataSyncConfig struct {
 	} `yaml:"initial_gainers_populator"`
 
 	// 数据源配置
-	Exchanges      []string `yaml:"exchanges"`
-	Symbols        []string `yaml:"symbols"`
-	KlineIntervals []string `yaml:"kline_intervals"`
+	Exchanges             []string `yaml:"exchanges"`
+	PriceOutlierThreshold float64  `yaml:"price_outlier_threshold"` // 多交易所聚合时的离群阈值（%），默认5
+	Symbols               []string `yaml:"symbols"`
+	KlineIntervals        []string `yaml:"kline_intervals"`
 
 	// 监控配置
 	EnableMetrics   bool `yaml:"enable_metrics"`
@@ -203,12 +206,6 @@ type DataSyncConfig struct {
 	} `yaml:"timeouts"`
 }
 
-type DataSyncMonitor struct {
-	mu        sync.RWMutex
-	stats     map[string]map[string]interface{}
-	startTime time.Time
-}
-
 func NewDataSyncService(db *gorm.DB, server interface{}, cfg *config.Config) *DataSyncService {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -220,10 +217,7 @@ func NewDataSyncService(db *gorm.DB, server interface{}, cfg *config.Config) *Da
 		cancel:  cancel,
 		config:  DataSyncConfig{}, // 使用零值，依赖配置文件提供所有配置
 		syncers: make(map[string]DataSyncer),
-		monitor: &DataSyncMonitor{
-			stats:     make(map[string]map[string]interface{}),
-			startTime: time.Now(),
-		},
+		monitor: NewDataSyncMonitor(defaultMonitorWindow),
 	}
 
 	// 如果数据库为nil，跳过初始化（将在后续设置数据库后重新初始化）
@@ -293,6 +287,8 @@ func (s *DataSyncService) registerConditionalSyncers() {
 			log.Printf("[DataSync] ❌ 期货市场实时涨幅榜同步器创建失败")
 		}
 
+		s.attachReporters()
+
 		log.Printf("[DataSync] 当前注册的同步器数量: %d", len(s.syncers))
 		log.Printf("[DataSync] 已注册的同步器: %v", getSyncerNames(s.syncers))
 	} else {
@@ -379,6 +375,17 @@ func (s *DataSyncService) initSyncers() {
 			log.Printf("[DataSync] Monitoring system initialized")
 		}
 	}
+
+	s.attachReporters()
+}
+
+// attachReporters 让支持上报的同步器把每轮结果上报给监控
+func (s *DataSyncService) attachReporters() {
+	for name, syncer := range s.syncers {
+		if r, ok := syncer.(reportableSyncer); ok {
+			r.SetReporter(name, s.monitor)
+		}
+	}
 }
 
 func (s *DataSyncService) Start(initialSyncMode string) error {
@@ -449,61 +456,12 @@ func (s *DataSyncService) Start(initialSyncMode string) error {
 	case "ordered":
 		log.Printf("[DataSync] Running initial sync test in ordered mode...")
 
-		// 定义首次同步的执行顺序：先同步交易对信息，再同步市场数据，最后同步涨幅榜相关数据
-		orderedSyncers := []string{"exchange_info", "market_stats", "initial_gainers", "realtime_gainers"}
-		executedSyncers := make(map[string]bool)
-
+		orderedSyncers := s.orderedSyncerNames()
 		log.Printf("[DataSync] Ordered syncers to test: %v", orderedSyncers)
-		log.Printf("[DataSync] Available syncers: %v", getSyncerNames(s.syncers))
-
-		// 按指定顺序执行关键同步器
-		for _, syncerName := range orderedSyncers {
-			if syncerName == "realtime_gainers" {
-				// 特殊处理实时涨幅榜：它被注册为两个同步器 (spot 和 futures)
-				realtimeSyncers := []string{"realtime_gainers_spot", "realtime_gainers_futures"}
-				allRealtimePassed := true
-
-				for _, rtSyncerName := range realtimeSyncers {
-					if syncer, exists := s.syncers[rtSyncerName]; exists {
-						log.Printf("[DataSync] Testing syncer: %s (part of %s)", rtSyncerName, syncerName)
-						if err := syncer.Sync(s.ctx); err != nil {
-							log.Printf("[DataSync] ❌ Initial sync test failed for %s: %v", rtSyncerName, err)
-							allRealtimePassed = false
-						} else {
-							log.Printf("[DataSync] ✅ Initial sync test passed for %s", rtSyncerName)
-						}
-						executedSyncers[rtSyncerName] = true
-					} else {
-						log.Printf("[DataSync] ⚠️  Realtime gainers syncer %s not found, skipping", rtSyncerName)
-					}
-				}
 
-				if allRealtimePassed {
-					log.Printf("[DataSync] ✅ Initial sync test passed for realtime_gainers")
-				} else {
-					log.Printf("[DataSync] ❌ Initial sync test failed for realtime_gainers")
-				}
-				executedSyncers[syncerName] = true
-			} else if syncer, exists := s.syncers[syncerName]; exists {
-				log.Printf("[DataSync] Testing syncer: %s (ordered)", syncerName)
-				if err := syncer.Sync(s.ctx); err != nil {
-					log.Printf("[DataSync] ❌ Initial sync test failed for %s: %v", syncerName, err)
-				} else {
-					log.Printf("[DataSync] ✅ Initial sync test passed for %s", syncerName)
-				}
-				executedSyncers[syncerName] = true
-			} else {
-				log.Printf("[DataSync] ⚠️  Syncer %s not found in syncers map, skipping", syncerName)
-			}
-		}
-
-		// 执行剩余的同步器（跳过已执行的）
-		for name, syncer := range s.syncers {
-			if executedSyncers[name] {
-				continue
-			}
-			log.Printf("[DataSync] Testing syncer: %s", name)
-			if err := syncer.Sync(s.ctx); err != nil {
+		for _, name := range orderedSyncers {
+			log.Printf("[DataSync] Testing syncer: %s (ordered)", name)
+			if err := s.syncers[name].Sync(s.ctx); err != nil {
 				log.Printf("[DataSync] ❌ Initial sync test failed for %s: %v", name, err)
 			} else {
 				log.Printf("[DataSync] ✅ Initial sync test passed for %s", name)
@@ -652,6 +610,8 @@ func (s *DataSyncService) Stop() {
 		syncer.Stop()
 	}
 
+	s.stopMetricsServer()
+
 	log.Printf("[DataSync] Data synchronization service stopped")
 }
 
@@ -772,10 +732,8 @@ func (s *DataSyncService) startMetricsReporter() {
 func (s *DataSyncService) reportMetrics() {
 	log.Printf("[DataSync] === Data Sync Metrics Report ===")
 
-	s.monitor.mu.RLock()
-	defer s.monitor.mu.RUnlock()
-
 	totalUptime := time.Since(s.monitor.startTime)
+	throughput := s.monitor.Snapshot()
 
 	for name, syncer := range s.syncers {
 		stats := syncer.GetStats()
@@ -783,6 +741,10 @@ func (s *DataSyncService) reportMetrics() {
 		for key, value := range stats {
 			log.Printf("[DataSync]   %s: %v", key, value)
 		}
+		if tp, ok := throughput[name].(map[string]interface{}); ok {
+			log.Printf("[DataSync]   throughput: %.1f rows/min, error rate %.1f%%, avg %vms",
+				tp["rows_per_minute"], tp["error_rate"].(float64)*100, tp["avg_duration_ms"])
+		}
 	}
 
 	log.Printf("[DataSync] Total Uptime: %v", totalUptime)
@@ -797,15 +759,71 @@ func (s *DataSyncService) SyncOnce(syncerName string) error {
 	return fmt.Errorf("syncer not found: %s", syncerName)
 }
 
-func (s *DataSyncService) GetStatus() map[string]interface{} {
-	s.monitor.mu.RLock()
-	defer s.monitor.mu.RUnlock()
+// 首次同步的执行顺序：先同步交易对信息，再同步市场数据，最后同步涨幅榜相关数据
+var initialSyncOrder = []string{"exchange_info", "market_stats", "initial_gainers", "realtime_gainers_spot", "realtime_gainers_futures"}
+
+// orderedSyncerNames 按依赖顺序返回已注册的同步器：先 initialSyncOrder，其余按名称排序
+func (s *DataSyncService) orderedSyncerNames() []string {
+	names := make([]string, 0, len(s.syncers))
+	seen := make(map[string]bool, len(s.syncers))
+	for _, name := range initialSyncOrder {
+		if _, exists := s.syncers[name]; exists {
+			names = append(names, name)
+			seen[name] = true
+		}
+	}
+	rest := make([]string, 0, len(s.syncers))
+	for name := range s.syncers {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
+}
 
+// RunOnceAll 按依赖顺序把每个同步器各执行一次，返回失败的同步器及其错误
+func (s *DataSyncService) RunOnceAll(ctx context.Context) map[string]error {
+	failures := make(map[string]error)
+	for _, name := range s.orderedSyncerNames() {
+		log.Printf("[DataSync] Running one-time sync for: %s", name)
+		startTime := time.Now()
+		if err := s.syncers[name].Sync(ctx); err != nil {
+			log.Printf("[DataSync] ❌ %s sync failed: %v", name, err)
+			failures[name] = err
+			continue
+		}
+		log.Printf("[DataSync] ✅ %s sync completed in %v", name, time.Since(startTime))
+	}
+	return failures
+}
+
+// runOnceAll 执行 run-once-all 操作并返回进程退出码：任一同步器失败时为 1，便于 cron 感知
+func runOnceAll(s *DataSyncService) int {
+	failures := s.RunOnceAll(s.ctx)
+	total := len(s.syncers)
+	fmt.Printf("[data_sync] Run-once-all completed: %d/%d syncers successful\n", total-len(failures), total)
+	if len(failures) == 0 {
+		return 0
+	}
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("[data_sync] ❌ %s: %v\n", name, failures[name])
+	}
+	return 1
+}
+
+func (s *DataSyncService) GetStatus() map[string]interface{} {
 	status := map[string]interface{}{
 		"service":    "data_sync",
 		"start_time": s.monitor.startTime,
 		"uptime":     time.Since(s.monitor.startTime).String(),
 		"syncers":    make(map[string]interface{}),
+		"throughput": s.monitor.Snapshot(),
 	}
 
 	for name, syncer := range s.syncers {
@@ -817,10 +835,12 @@ func (s *DataSyncService) GetStatus() map[string]interface{} {
 
 func main() {
 	// 命令行参数
-	action := flag.String("action", "start", "操作类型: start(启动服务), test-sync(测试所有同步器), sync-once(单次同步), status(状态查询)")
+	action := flag.String("action", "start", "操作类型: start(启动服务), test-sync(测试所有同步器), sync-once(单次同步), run-once-all(按顺序单次同步全部，适合cron), status(状态查询)")
 	syncerName := flag.String("syncer", "", "同步器名称 (用于sync-once操作)")
 	configPath := flag.String("config", "./config.yaml", "配置文件路径")
 	initialSyncMode := flag.String("initial-sync-mode", "ordered", "初始同步模式: skip(跳过), ordered(顺序执行), random(随机执行)")
+	metricsAddr := flag.String("metrics-addr", ":9108", "监控端点监听地址（/metrics、/errors），为空则不启动；仅 start 模式生效")
+	errorLogSize := flag.Int("error-log-size", defaultErrorLogSize, "每个同步器在 /errors 中保留的最近错误条数")
 
 	flag.Parse()
 
@@ -910,6 +930,7 @@ func main() {
 	// 配置加载完毕后，初始化数据库和服务
 	// 初始化数据库（优化连接池配置）
 	database, err := pdb.OpenMySQL(pdb.Options{
+		Driver:          cfg.Database.Driver,
 		DSN:             cfg.Database.DSN,
 		Automigrate:     true,
 		MaxOpenConns:    20, // 增加连接数以支持并发同步
@@ -987,6 +1008,8 @@ func main() {
 
 	case "start":
 		// 启动服务
+		syncService.monitor.SetErrorLogSize(*errorLogSize)
+		syncService.startMetricsServer(*metricsAddr)
 		if err := syncService.Start(*initialSyncMode); err != nil {
 			fmt.Printf("[data_sync] Failed to start service: %v\n", err)
 			return
@@ -1023,6 +1046,14 @@ func main() {
 			fmt.Printf("[data_sync] ✅ Sync completed successfully for %s in %v\n", *syncerName, duration)
 		}
 
+	case "run-once-all":
+		// 按依赖顺序把所有同步器各执行一次后退出，任一失败时返回非零退出码（适合cron）
+		fmt.Println("[data_sync] Running every syncer once in ordered mode...")
+		if code := runOnceAll(syncService); code != 0 {
+			database.Close()
+			os.Exit(code)
+		}
+
 	case "status":
 		// 查询状态
 		status := syncService.GetStatus()
@@ -1050,6 +1081,7 @@ func main() {
 		fmt.Println("[data_sync]   start     - 启动数据同步服务")
 		fmt.Println("[data_sync]   test-sync - 测试所有同步器功能")
 		fmt.Println("[data_sync]   sync-once - 单次同步指定同步器")
+		fmt.Println("[data_sync]   run-once-all - 按顺序单次同步全部同步器后退出（适合cron）")
 		fmt.Println("[data_sync]   status    - 查看服务状态")
 		fmt.Println("[data_sync] Examples:")
 		fmt.Println("[data_sync]   -action start")
@@ -1058,6 +1090,7 @@ func main() {
 		fmt.Println("[data_sync]   -action start -initial-sync-mode=ordered") // 顺序执行初始同步（默认）
 		fmt.Println("[data_sync]   -action test-sync")
 		fmt.Println("[data_sync]   -action sync-once -syncer price")
+		fmt.Println("[data_sync]   -action run-once-all")
 		os.Exit(1)
 	}
 }
@@ -1165,11 +1198,11 @@ func (s *DataSyncService) startStatsUpdater() {
 	}()
 }
 
-// updateGlobalStats 更新全局统计信息
+// updateGlobalStats 清理过期的滚动统计并输出窗口内的汇总
 func (s *DataSyncService) updateGlobalStats() {
-	// 由于DataSyncStats的字段是私有的，我们通过AddAlert等函数来间接更新
-	// 这里暂时不实现复杂的统计收集逻辑，后续可以扩展
-	log.Printf("[DataSync] Stats update triggered (placeholder implementation)")
+	s.monitor.Prune()
+	rows, errs := s.monitor.Totals()
+	log.Printf("[DataSync] Throughput (last %v): %d rows, %d failed cycles", s.monitor.window, rows, errs)
 }
 
 // getSyncerDisplayName 获取同步器显示名称
@@ -360,6 +360,9 @@ type MarketStatsSyncer struct {
 		lastSyncTime       time.Time
 		totalVolumeUpdates int64
 	}
+
+	// 每轮结果上报给监控
+	cycleReporter
 }
 
 func NewMarketStatsSyncer(db *gorm.DB, cfg *config.Config, config *DataSyncConfig, redisCache *RedisInvalidSymbolCache) *MarketStatsSyncer {
@@ -547,7 +550,7 @@ func (s *MarketStatsSyncer) Name() string {
 	return "MarketStatsSyncer"
 }
 
-func (s *MarketStatsSyncer) Sync(ctx context.Context) error {
+func (s *MarketStatsSyncer) Sync(ctx context.Context) (err error) {
 	s.stats.mu.Lock()
 	s.stats.totalSyncs++
 	syncStartTime := time.Now()
@@ -561,6 +564,7 @@ func (s *MarketStatsSyncer) Sync(ctx context.Context) error {
 
 	totalUpdates := 0
 	totalErrors := 0
+	defer func() { s.reportCycle(syncStartTime, totalUpdates, err) }()
 
 	// 同步现货市场统计
 	if len(syncerConfig.SpotSymbols) > 0 {
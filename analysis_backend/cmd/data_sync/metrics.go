@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ===== /metrics 与 /errors =====
+
+// WritePrometheus 以 Prometheus 文本格式输出各同步器的累计计数与最近一轮状态
+func (m *DataSyncMonitor) WritePrometheus(w io.Writer) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.stats))
+	for name := range m.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	metric := func(name, typ, help string, emit func()) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		emit()
+	}
+	metric("data_sync_cycles_total", "counter", "Sync cycles by syncer and result.", func() {
+		for _, n := range names {
+			st := m.stats[n]
+			fmt.Fprintf(&b, "data_sync_cycles_total{syncer=\"%s\",result=\"success\"} %d\n", promLabel(n), st.cycles-st.errors)
+			fmt.Fprintf(&b, "data_sync_cycles_total{syncer=\"%s\",result=\"failure\"} %d\n", promLabel(n), st.errors)
+		}
+	})
+	metric("data_sync_rows_total", "counter", "Rows written or updated by syncer.", func() {
+		for _, n := range names {
+			fmt.Fprintf(&b, "data_sync_rows_total{syncer=\"%s\"} %d\n", promLabel(n), m.stats[n].rows)
+		}
+	})
+	metric("data_sync_cycle_duration_seconds_total", "counter", "Total time spent in sync cycles.", func() {
+		for _, n := range names {
+			fmt.Fprintf(&b, "data_sync_cycle_duration_seconds_total{syncer=\"%s\"} %g\n", promLabel(n), m.stats[n].totalDuration.Seconds())
+		}
+	})
+	metric("data_sync_last_cycle_duration_seconds", "gauge", "Duration of the most recent sync cycle.", func() {
+		for _, n := range names {
+			fmt.Fprintf(&b, "data_sync_last_cycle_duration_seconds{syncer=\"%s\"} %g\n", promLabel(n), m.stats[n].last.Duration.Seconds())
+		}
+	})
+	metric("data_sync_last_cycle_timestamp_seconds", "gauge", "Start time of the most recent sync cycle.", func() {
+		for _, n := range names {
+			fmt.Fprintf(&b, "data_sync_last_cycle_timestamp_seconds{syncer=\"%s\"} %d\n", promLabel(n), m.stats[n].last.At.Unix())
+		}
+	})
+	metric("data_sync_uptime_seconds", "gauge", "Seconds since the monitor started.", func() {
+		fmt.Fprintf(&b, "data_sync_uptime_seconds %g\n", m.now().Sub(m.startTime).Seconds())
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// promLabel 转义 Prometheus 标签值
+func promLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// Handler 提供 /metrics（Prometheus）与 /errors（最近错误，可用 ?syncer= 过滤）
+func (m *DataSyncMonitor) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := m.WritePrometheus(w); err != nil {
+			log.Printf("[DataSync] write metrics failed: %v", err)
+		}
+	})
+	mux.HandleFunc("/errors", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.RLock()
+		limit := m.errorLog
+		m.mu.RUnlock()
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"limit":  limit,
+			"errors": m.RecentErrors(r.URL.Query().Get("syncer")),
+		})
+	})
+	return mux
+}
+
+// startMetricsServer 在 addr 上暴露监控端点；addr 为空时不启动
+func (s *DataSyncService) startMetricsServer(addr string) {
+	if addr == "" {
+		return
+	}
+	s.metricsServer = &http.Server{Addr: addr, Handler: s.monitor.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		log.Printf("[DataSync] Metrics server listening on %s (/metrics, /errors)", addr)
+		if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[DataSync] Metrics server error: %v", err)
+		}
+	}()
+}
+
+// stopMetricsServer 关闭监控端点
+func (s *DataSyncService) stopMetricsServer() {
+	if s.metricsServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.metricsServer.Shutdown(ctx); err != nil {
+		log.Printf("[DataSync] Metrics server shutdown error: %v", err)
+	}
+}
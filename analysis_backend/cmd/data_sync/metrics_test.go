@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDataSyncMetricsCounters /metrics 按同步器输出成功/失败计数
+func TestDataSyncMetricsCounters(t *testing.T) {
+	m := NewDataSyncMonitor(time.Hour)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		m.ReportCycle("price", SyncCycleReport{At: now, Rows: 10, Duration: time.Second})
+	}
+	m.ReportCycle("price", SyncCycleReport{At: now, Duration: time.Second, Err: errors.New("timeout")})
+	m.ReportCycle("kline", SyncCycleReport{At: now, Duration: 2 * time.Second, Err: errors.New("rate limit")})
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type 不符: %s", ct)
+	}
+
+	out := string(body)
+	for _, want := range []string{
+		"# TYPE data_sync_cycles_total counter",
+		`data_sync_cycles_total{syncer="price",result="success"} 5`,
+		`data_sync_cycles_total{syncer="price",result="failure"} 1`,
+		`data_sync_cycles_total{syncer="kline",result="success"} 0`,
+		`data_sync_cycles_total{syncer="kline",result="failure"} 1`,
+		`data_sync_rows_total{syncer="price"} 50`,
+		`data_sync_last_cycle_duration_seconds{syncer="kline"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics 缺少 %q\n%s", want, out)
+		}
+	}
+}
+
+// TestDataSyncErrorRingBufferCaps 错误环形缓冲最多保留 N 条，按从新到旧返回
+func TestDataSyncErrorRingBufferCaps(t *testing.T) {
+	m := NewDataSyncMonitor(time.Hour)
+	m.SetErrorLogSize(3)
+	base := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		m.ReportCycle("depth", SyncCycleReport{At: base.Add(time.Duration(i) * time.Minute), Err: fmt.Errorf("err-%d", i)})
+	}
+	m.ReportCycle("price", SyncCycleReport{At: base, Err: errors.New("only")})
+	m.ReportCycle("price", SyncCycleReport{At: base})
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL + "/errors?syncer=depth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		Limit  int                         `json:"limit"`
+		Errors map[string][]SyncErrorEntry `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Limit != 3 || len(got.Errors) != 1 {
+		t.Fatalf("响应不符: %+v", got)
+	}
+	depth := got.Errors["depth"]
+	if len(depth) != 3 {
+		t.Fatalf("缓冲应只保留 3 条，实际 %d", len(depth))
+	}
+	for i, want := range []string{"err-6", "err-5", "err-4"} {
+		if depth[i].Error != want {
+			t.Errorf("第%d条期望 %s，实际 %s", i, want, depth[i].Error)
+		}
+	}
+
+	if all := m.RecentErrors(""); len(all["price"]) != 1 || all["price"][0].Error != "only" {
+		t.Errorf("price 错误记录不符: %+v", all["price"])
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/netutil"
+)
+
+// ===== 多交易所价格聚合 =====
+
+// 默认离群阈值：偏离参考价超过 5% 的报价不参与 VWAP
+const defaultPriceOutlierThreshold = 5.0
+
+// exchangeQuote 某交易所的一条报价
+type exchangeQuote struct {
+	Exchange    string
+	Price       float64
+	QuoteVolume float64 // 24h 成交额（USDT）
+	At          time.Time
+}
+
+// priceAggregate 多交易所聚合结果
+type priceAggregate struct {
+	Price     float64 // 成交额加权均价（VWAP）
+	Reference float64 // 离群判定所用的参考价（成交额加权中位数）
+	Used      []exchangeQuote
+	Rejected  []exchangeQuote
+}
+
+// weightedMedianPrice 成交额加权中位数；成交额全为 0 时退化为普通中位数（偶数个取较低者）
+func weightedMedianPrice(quotes []exchangeQuote) float64 {
+	sorted := append([]exchangeQuote(nil), quotes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+
+	weight := func(q exchangeQuote) float64 { return q.QuoteVolume }
+	total := 0.0
+	for _, q := range sorted {
+		total += q.QuoteVolume
+	}
+	if total <= 0 {
+		weight = func(exchangeQuote) float64 { return 1 }
+		total = float64(len(sorted))
+	}
+
+	cum := 0.0
+	for _, q := range sorted {
+		cum += weight(q)
+		if cum >= total/2 {
+			return q.Price
+		}
+	}
+	return sorted[len(sorted)-1].Price
+}
+
+// aggregateQuotes 以成交额加权中位数为参考价，剔除偏离超过 maxDeviation（%）的报价后计算 VWAP。
+// 两个交易所时参考价即成交额较大一方的价格，因此偏离的小交易所会被剔除。
+func aggregateQuotes(quotes []exchangeQuote, maxDeviation float64) (priceAggregate, error) {
+	valid := make([]exchangeQuote, 0, len(quotes))
+	for _, q := range quotes {
+		if q.Price > 0 && !math.IsNaN(q.Price) && !math.IsInf(q.Price, 0) && q.QuoteVolume >= 0 {
+			valid = append(valid, q)
+		}
+	}
+	if len(valid) == 0 {
+		return priceAggregate{}, fmt.Errorf("no valid quotes")
+	}
+	if maxDeviation <= 0 {
+		maxDeviation = defaultPriceOutlierThreshold
+	}
+
+	agg := priceAggregate{Reference: weightedMedianPrice(valid)}
+	for _, q := range valid {
+		if math.Abs(q.Price-agg.Reference)/agg.Reference*100 > maxDeviation {
+			agg.Rejected = append(agg.Rejected, q)
+		} else {
+			agg.Used = append(agg.Used, q)
+		}
+	}
+
+	var pv, vol, sum float64
+	for _, q := range agg.Used {
+		pv += q.Price * q.QuoteVolume
+		vol += q.QuoteVolume
+		sum += q.Price
+	}
+	if vol > 0 {
+		agg.Price = pv / vol
+	} else {
+		agg.Price = sum / float64(len(agg.Used))
+	}
+	return agg, nil
+}
+
+// priceQuoteFetcher 获取某交易所的 24h 报价，symbol 为 Binance 格式（如 BTCUSDT）
+type priceQuoteFetcher func(ctx context.Context, symbol, kind string) (exchangeQuote, error)
+
+var priceQuoteFetchers = map[string]priceQuoteFetcher{
+	"binance": fetchBinanceQuote,
+	"okx":     fetchOKXQuote,
+	"huobi":   fetchHuobiQuote,
+}
+
+func fetchBinanceQuote(ctx context.Context, symbol, kind string) (exchangeQuote, error) {
+	url := fmt.Sprintf("https://api.binance.com/api/v3/ticker/24hr?symbol=%s", symbol)
+	if kind == "futures" {
+		url = fmt.Sprintf("https://fapi.binance.com/fapi/v1/ticker/24hr?symbol=%s", symbol)
+	}
+	var resp struct {
+		LastPrice   string `json:"lastPrice"`
+		QuoteVolume string `json:"quoteVolume"`
+	}
+	if err := netutil.GetJSON(ctx, url, &resp); err != nil {
+		return exchangeQuote{}, err
+	}
+	return exchangeQuote{Exchange: "binance", Price: parseFloat(resp.LastPrice), QuoteVolume: parseFloat(resp.QuoteVolume), At: time.Now()}, nil
+}
+
+func fetchOKXQuote(ctx context.Context, symbol, kind string) (exchangeQuote, error) {
+	instID := strings.TrimSuffix(symbol, "USDT") + "-USDT"
+	if kind == "futures" {
+		instID += "-SWAP"
+	}
+	var resp struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			Last      string `json:"last"`
+			VolCcy24h string `json:"volCcy24h"`
+		} `json:"data"`
+	}
+	if err := netutil.GetJSON(ctx, "https://www.okx.com/api/v5/market/ticker?instId="+instID, &resp); err != nil {
+		return exchangeQuote{}, err
+	}
+	if resp.Code != "0" || len(resp.Data) == 0 {
+		return exchangeQuote{}, fmt.Errorf("okx ticker %s: code=%s msg=%s", instID, resp.Code, resp.Msg)
+	}
+	price := parseFloat(resp.Data[0].Last)
+	volume := parseFloat(resp.Data[0].VolCcy24h)
+	if kind == "futures" {
+		// 永续合约的 volCcy24h 以币计价，换算成 USDT
+		volume *= price
+	}
+	return exchangeQuote{Exchange: "okx", Price: price, QuoteVolume: volume, At: time.Now()}, nil
+}
+
+func fetchHuobiQuote(ctx context.Context, symbol, kind string) (exchangeQuote, error) {
+	if kind != "spot" {
+		return exchangeQuote{}, fmt.Errorf("huobi %s quotes not supported", kind)
+	}
+	var resp struct {
+		Status string `json:"status"`
+		ErrMsg string `json:"err-msg"`
+		Tick   struct {
+			Close float64 `json:"close"`
+			Vol   float64 `json:"vol"` // 以计价币（USDT）计的成交额
+		} `json:"tick"`
+	}
+	url := "https://api.huobi.pro/market/detail/merged?symbol=" + strings.ToLower(symbol)
+	if err := netutil.GetJSON(ctx, url, &resp); err != nil {
+		return exchangeQuote{}, err
+	}
+	if resp.Status != "ok" {
+		return exchangeQuote{}, fmt.Errorf("huobi ticker %s: %s", symbol, resp.ErrMsg)
+	}
+	return exchangeQuote{Exchange: "huobi", Price: resp.Tick.Close, QuoteVolume: resp.Tick.Vol, At: time.Now()}, nil
+}
+
+// multiSourceEnabled 配置了多个交易所时，价格按多源 VWAP 聚合
+func (s *PriceSyncer) multiSourceEnabled() bool {
+	return len(s.config.Exchanges) > 1
+}
+
+// syncConsolidatedPrices 从所有配置的交易所获取报价，保存各交易所原始报价，并把 VWAP 作为权威价格写入价格缓存
+func (s *PriceSyncer) syncConsolidatedPrices(ctx context.Context, symbols []string, kind string) (int, int) {
+	if len(symbols) == 0 {
+		return 0, 0
+	}
+	log.Printf("[PriceSyncer] 🌐 Syncing %s prices for %d symbols from %v (VWAP)", kind, len(symbols), s.config.Exchanges)
+
+	updates := 0
+	errors := 0
+	for _, symbol := range symbols {
+		quotes := make([]exchangeQuote, 0, len(s.config.Exchanges))
+		for _, ex := range s.config.Exchanges {
+			fetch, ok := priceQuoteFetchers[strings.ToLower(ex)]
+			if !ok {
+				continue
+			}
+			if err := PriceAPIRateLimiter.WaitForToken(ctx); err != nil {
+				return updates, errors + 1
+			}
+			q, err := fetch(ctx, symbol, kind)
+			if err != nil {
+				log.Printf("[PriceSyncer] ⚠️ %s %s quote from %s failed: %v", kind, symbol, ex, err)
+				continue
+			}
+			quotes = append(quotes, q)
+		}
+
+		agg, err := aggregateQuotes(quotes, s.config.PriceOutlierThreshold)
+		if err != nil {
+			log.Printf("[PriceSyncer] ❌ No usable %s quotes for %s", kind, symbol)
+			errors++
+			continue
+		}
+		for _, q := range agg.Rejected {
+			log.Printf("[PriceSyncer] ⚠️ %s %s: rejected %s quote %.8g (reference %.8g)", kind, symbol, q.Exchange, q.Price, agg.Reference)
+		}
+
+		now := time.Now()
+		rows := make([]pdb.ExchangePriceQuote, 0, len(quotes))
+		for _, set := range []struct {
+			quotes  []exchangeQuote
+			outlier bool
+		}{{agg.Used, false}, {agg.Rejected, true}} {
+			for _, q := range set.quotes {
+				rows = append(rows, pdb.ExchangePriceQuote{
+					Symbol: symbol, Kind: kind, Exchange: q.Exchange,
+					Price: q.Price, QuoteVolume: q.QuoteVolume, Outlier: set.outlier, LastUpdated: q.At,
+				})
+			}
+		}
+		if err := pdb.SaveExchangePriceQuotes(s.db, rows); err != nil {
+			log.Printf("[PriceSyncer] ⚠️ Failed to save exchange quotes for %s: %v", symbol, err)
+		}
+
+		cache := &pdb.PriceCache{
+			Symbol:      symbol,
+			Kind:        kind,
+			Price:       strconv.FormatFloat(agg.Price, 'f', -1, 64),
+			LastUpdated: now,
+		}
+		if err := pdb.SavePriceCache(s.db, cache); err != nil {
+			log.Printf("[PriceSyncer] ❌ Failed to save %s price cache for %s: %v", kind, symbol, err)
+			errors++
+			continue
+		}
+		updates++
+	}
+
+	s.stats.mu.Lock()
+	s.stats.restAPICalls += int64(len(symbols) * len(s.config.Exchanges))
+	s.stats.mu.Unlock()
+
+	log.Printf("[PriceSyncer] 📊 %s VWAP price sync: %d updates, %d errors", kind, updates, errors)
+	return updates, errors
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// TestAggregateQuotesVWAP 两个交易所价格相近时按成交额加权
+func TestAggregateQuotesVWAP(t *testing.T) {
+	quotes := []exchangeQuote{
+		{Exchange: "binance", Price: 100, QuoteVolume: 3000},
+		{Exchange: "okx", Price: 102, QuoteVolume: 1000},
+	}
+	agg, err := aggregateQuotes(quotes, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (100*3000 + 102*1000) / 4000 = 100.5
+	if math.Abs(agg.Price-100.5) > 1e-9 {
+		t.Errorf("VWAP 期望 100.5，实际 %v", agg.Price)
+	}
+	if len(agg.Used) != 2 || len(agg.Rejected) != 0 {
+		t.Errorf("两个报价都应参与计算: used=%v rejected=%v", agg.Used, agg.Rejected)
+	}
+}
+
+// TestAggregateQuotesRejectsOutlier 偏离参考价过大的小交易所被剔除，权威价格只取主流报价
+func TestAggregateQuotesRejectsOutlier(t *testing.T) {
+	quotes := []exchangeQuote{
+		{Exchange: "binance", Price: 100, QuoteVolume: 5000},
+		{Exchange: "huobi", Price: 130, QuoteVolume: 800},
+	}
+	agg, err := aggregateQuotes(quotes, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agg.Price != 100 || agg.Reference != 100 {
+		t.Errorf("期望剔除离群报价后价格为 100，实际 price=%v reference=%v", agg.Price, agg.Reference)
+	}
+	if len(agg.Rejected) != 1 || agg.Rejected[0].Exchange != "huobi" {
+		t.Errorf("应剔除 huobi 报价: %+v", agg.Rejected)
+	}
+
+	// 三个交易所时中位数由多数决定，即使离群方成交额最大也会被剔除
+	quotes = []exchangeQuote{
+		{Exchange: "binance", Price: 100, QuoteVolume: 2000},
+		{Exchange: "okx", Price: 101, QuoteVolume: 2000},
+		{Exchange: "huobi", Price: 50, QuoteVolume: 3000},
+	}
+	agg, err = aggregateQuotes(quotes, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(agg.Rejected) != 1 || agg.Rejected[0].Exchange != "huobi" || math.Abs(agg.Price-100.5) > 1e-9 {
+		t.Errorf("三源离群剔除不符: price=%v rejected=%+v", agg.Price, agg.Rejected)
+	}
+}
+
+// TestAggregateQuotesEdgeCases 无成交额时退化为简单均值，无效报价被忽略
+func TestAggregateQuotesEdgeCases(t *testing.T) {
+	agg, err := aggregateQuotes([]exchangeQuote{
+		{Exchange: "binance", Price: 10},
+		{Exchange: "okx", Price: 10.2},
+		{Exchange: "huobi", Price: 0, QuoteVolume: 100},
+	}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(agg.Price-10.1) > 1e-9 || len(agg.Used) != 2 {
+		t.Errorf("期望简单均值 10.1，实际 %v (used=%d)", agg.Price, len(agg.Used))
+	}
+
+	if _, err := aggregateQuotes(nil, 5); err == nil {
+		t.Error("没有报价时应返回错误")
+	}
+}
@@ -122,6 +122,9 @@ type PriceSyncer struct {
 		websocketHits     int64 // 从WebSocket缓存命中的次数
 		restAPICalls      int64 // REST API调用的次数
 	}
+
+	// 每轮结果上报给监控
+	cycleReporter
 }
 
 func NewPriceSyncer(db *gorm.DB, cfg *config.Config, config *DataSyncConfig, redisCache *RedisInvalidSymbolCache) *PriceSyncer {
@@ -324,7 +327,7 @@ func (s *PriceSyncer) Stop() {
 	log.Printf("[PriceSyncer] Stop signal received")
 }
 
-func (s *PriceSyncer) Sync(ctx context.Context) error {
+func (s *PriceSyncer) Sync(ctx context.Context) (err error) {
 	s.stats.mu.Lock()
 	s.stats.totalSyncs++
 	syncStartTime := time.Now()
@@ -338,11 +341,17 @@ func (s *PriceSyncer) Sync(ctx context.Context) error {
 
 	totalUpdates := 0
 	totalErrors := 0
+	defer func() { s.reportCycle(syncStartTime, totalUpdates, err) }()
 
 	// 同步现货价格
 	if len(syncerConfig.SpotSymbols) > 0 {
 		log.Printf("[PriceSyncer] 📈 Starting spot market price sync for %d symbols", len(syncerConfig.SpotSymbols))
-		spotUpdates, spotErrors := s.syncSpotPricesForSymbols(ctx, syncerConfig.SpotSymbols)
+		var spotUpdates, spotErrors int
+		if s.multiSourceEnabled() {
+			spotUpdates, spotErrors = s.syncConsolidatedPrices(ctx, syncerConfig.SpotSymbols, "spot")
+		} else {
+			spotUpdates, spotErrors = s.syncSpotPricesForSymbols(ctx, syncerConfig.SpotSymbols)
+		}
 		totalUpdates += spotUpdates
 		totalErrors += spotErrors
 	} else {
@@ -352,7 +361,12 @@ func (s *PriceSyncer) Sync(ctx context.Context) error {
 	// 同步期货价格
 	if len(syncerConfig.FuturesSymbols) > 0 {
 		log.Printf("[PriceSyncer] 📈 Starting futures market price sync for %d symbols", len(syncerConfig.FuturesSymbols))
-		futuresUpdates, futuresErrors := s.syncFuturesPricesForSymbols(ctx, syncerConfig.FuturesSymbols)
+		var futuresUpdates, futuresErrors int
+		if s.multiSourceEnabled() {
+			futuresUpdates, futuresErrors = s.syncConsolidatedPrices(ctx, syncerConfig.FuturesSymbols, "futures")
+		} else {
+			futuresUpdates, futuresErrors = s.syncFuturesPricesForSymbols(ctx, syncerConfig.FuturesSymbols)
+		}
 		totalUpdates += futuresUpdates
 		totalErrors += futuresErrors
 	} else {
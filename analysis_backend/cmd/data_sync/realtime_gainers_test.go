@@ -1,13 +1,11 @@
 package main
 
 import (
-	"context"
 	"log"
 	"testing"
 	"time"
 
 	"analysis/internal/config"
-	pdb "analysis/internal/db"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -29,7 +27,7 @@ func TestRealtimeGainersSyncer(t *testing.T) {
 	}
 
 	// 创建同步器
-	syncer := NewRealtimeGainersSyncer(db, cfg, config)
+	syncer := NewRealtimeGainersSyncerWithKind(db, cfg, config, "spot")
 	if syncer == nil {
 		t.Fatal("创建RealtimeGainersSyncer失败")
 	}
@@ -176,20 +174,12 @@ func createTestDB() (*gorm.DB, error) {
 	return db, nil
 }
 
-// min 返回两个数中的较小值
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // BenchmarkRealtimeGainersSyncer 基准测试
 func BenchmarkRealtimeGainersSyncer(b *testing.B) {
 	// 创建同步器（使用nil数据库进行基准测试）
 	cfg := &config.Config{}
 	config := &DataSyncConfig{}
-	syncer := NewRealtimeGainersSyncer(nil, cfg, config)
+	syncer := NewRealtimeGainersSyncerWithKind(nil, cfg, config, "spot")
 
 	// 重置基准测试计时器
 	b.ResetTimer()
@@ -242,6 +232,8 @@ func TestChangeDetector(t *testing.T) {
 		{Symbol: "BNBUSDT", Rank: 3, ChangePercent: 0.5},
 	}
 
+	// 跳过最小保存间隔
+	detector.lastSaveTime = time.Now().Add(-detector.config.MinSaveInterval)
 	if !detector.HasSignificantChanges(changedGainers) {
 		t.Error("价格大幅变化应该检测到显著变化")
 	}
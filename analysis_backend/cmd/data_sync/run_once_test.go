@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeSyncer 记录 Sync 调用的测试同步器
+type fakeSyncer struct {
+	name  string
+	err   error
+	calls int
+	order *[]string
+}
+
+func (f *fakeSyncer) Name() string                                      { return f.name }
+func (f *fakeSyncer) Start(ctx context.Context, interval time.Duration) {}
+func (f *fakeSyncer) Stop()                                             {}
+func (f *fakeSyncer) GetStats() map[string]interface{}                  { return map[string]interface{}{} }
+func (f *fakeSyncer) Sync(ctx context.Context) error {
+	f.calls++
+	*f.order = append(*f.order, f.name)
+	return f.err
+}
+
+func newRunOnceService(errs map[string]error, names ...string) (*DataSyncService, map[string]*fakeSyncer, *[]string) {
+	order := &[]string{}
+	svc := &DataSyncService{
+		ctx:     context.Background(),
+		syncers: make(map[string]DataSyncer),
+		monitor: NewDataSyncMonitor(0),
+	}
+	fakes := make(map[string]*fakeSyncer)
+	for _, name := range names {
+		f := &fakeSyncer{name: name, err: errs[name], order: order}
+		fakes[name] = f
+		svc.syncers[name] = f
+	}
+	return svc, fakes, order
+}
+
+// TestRunOnceAllOrderedAndOnce 所有同步器各执行一次，且遵循 ordered 模式的依赖顺序
+func TestRunOnceAllOrderedAndOnce(t *testing.T) {
+	svc, fakes, order := newRunOnceService(nil,
+		"price", "realtime_gainers_futures", "futures", "exchange_info", "initial_gainers", "market_stats", "realtime_gainers_spot")
+
+	if code := runOnceAll(svc); code != 0 {
+		t.Fatalf("全部成功时退出码应为 0，实际 %d", code)
+	}
+	for name, f := range fakes {
+		if f.calls != 1 {
+			t.Errorf("%s 应执行 1 次，实际 %d 次", name, f.calls)
+		}
+	}
+	want := []string{"exchange_info", "market_stats", "initial_gainers", "realtime_gainers_spot", "realtime_gainers_futures", "futures", "price"}
+	if !reflect.DeepEqual(*order, want) {
+		t.Errorf("执行顺序不符:\n got  %v\n want %v", *order, want)
+	}
+}
+
+// TestRunOnceAllExitCodeOnFailure 任一同步器失败时退出码非零，且不影响后续同步器执行
+func TestRunOnceAllExitCodeOnFailure(t *testing.T) {
+	errs := map[string]error{"market_stats": errors.New("api down")}
+	svc, fakes, _ := newRunOnceService(errs, "exchange_info", "market_stats", "price")
+
+	failures := svc.RunOnceAll(svc.ctx)
+	if len(failures) != 1 || failures["market_stats"] == nil {
+		t.Fatalf("应只有 market_stats 失败，实际 %v", failures)
+	}
+	if fakes["price"].calls != 1 {
+		t.Error("失败后应继续执行剩余同步器")
+	}
+
+	svc, _, _ = newRunOnceService(errs, "exchange_info", "market_stats", "price")
+	if code := runOnceAll(svc); code != 1 {
+		t.Errorf("有失败时退出码应为 1，实际 %d", code)
+	}
+}
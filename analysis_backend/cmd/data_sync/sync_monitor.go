@@ -0,0 +1,263 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// ===== 同步吞吐统计 =====
+
+// 默认滚动统计窗口
+const defaultMonitorWindow = 15 * time.Minute
+
+// 每个同步器保留的最近错误条数
+const defaultErrorLogSize = 50
+
+// SyncCycleReport 同步器一轮同步的结果
+type SyncCycleReport struct {
+	At       time.Time     // 本轮开始时间
+	Rows     int64         // 写入/更新的行数
+	Duration time.Duration // 本轮耗时
+	Err      error
+}
+
+// SyncReporter 接收同步器每轮的结果
+type SyncReporter interface {
+	ReportCycle(syncer string, report SyncCycleReport)
+}
+
+// reportableSyncer 支持上报每轮结果的同步器
+type reportableSyncer interface {
+	SetReporter(name string, reporter SyncReporter)
+}
+
+// cycleReporter 嵌入到同步器中，负责把每轮结果上报给监控；未设置上报方时忽略
+type cycleReporter struct {
+	reportMu sync.RWMutex
+	name     string
+	reporter SyncReporter
+}
+
+// SetReporter 设置上报方，name 为同步器在服务中的注册名
+func (c *cycleReporter) SetReporter(name string, reporter SyncReporter) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+	c.name = name
+	c.reporter = reporter
+}
+
+// reportCycle 上报一轮同步结果
+func (c *cycleReporter) reportCycle(start time.Time, rows int, err error) {
+	c.reportMu.RLock()
+	name, reporter := c.name, c.reporter
+	c.reportMu.RUnlock()
+	if reporter == nil {
+		return
+	}
+	reporter.ReportCycle(name, SyncCycleReport{At: start, Rows: int64(rows), Duration: time.Since(start), Err: err})
+}
+
+// syncerThroughput 单个同步器的累计与滚动统计
+type syncerThroughput struct {
+	cycles        int64
+	errors        int64
+	rows          int64
+	totalDuration time.Duration
+	last          SyncCycleReport
+	lastError     string
+	lastErrorTime time.Time
+	recent        []SyncCycleReport // 窗口内的结果，按时间升序
+	errorLog      []SyncErrorEntry  // 最近的错误，环形缓冲，最多 errorLogSize 条
+	errorNext     int               // 缓冲写满后下一次覆盖的位置
+}
+
+// SyncErrorEntry 一条同步错误记录
+type SyncErrorEntry struct {
+	Time     time.Time `json:"time"`
+	Error    string    `json:"error"`
+	Duration int64     `json:"duration_ms"`
+}
+
+// addError 写入环形缓冲，超过 size 时覆盖最旧的一条
+func (st *syncerThroughput) addError(e SyncErrorEntry, size int) {
+	if len(st.errorLog) < size {
+		st.errorLog = append(st.errorLog, e)
+		return
+	}
+	st.errorLog[st.errorNext] = e
+	st.errorNext = (st.errorNext + 1) % size
+}
+
+// recentErrors 按时间从新到旧返回错误记录
+func (st *syncerThroughput) recentErrors() []SyncErrorEntry {
+	n := len(st.errorLog)
+	out := make([]SyncErrorEntry, 0, n)
+	for i := 0; i < n; i++ {
+		// errorNext 指向最旧的一条（缓冲未满时为 0），倒序遍历得到从新到旧
+		out = append(out, st.errorLog[(st.errorNext+n-1-i)%n])
+	}
+	return out
+}
+
+// DataSyncMonitor 汇总各同步器上报的吞吐、耗时与错误，所有访问都经过 mu
+type DataSyncMonitor struct {
+	mu        sync.RWMutex
+	stats     map[string]*syncerThroughput
+	window    time.Duration
+	errorLog  int // 每个同步器保留的最近错误条数
+	startTime time.Time
+	now       func() time.Time // 测试时可替换
+}
+
+// NewDataSyncMonitor 创建监控；window<=0 时使用默认窗口
+func NewDataSyncMonitor(window time.Duration) *DataSyncMonitor {
+	if window <= 0 {
+		window = defaultMonitorWindow
+	}
+	return &DataSyncMonitor{
+		stats:     make(map[string]*syncerThroughput),
+		window:    window,
+		errorLog:  defaultErrorLogSize,
+		startTime: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// ReportCycle 实现 SyncReporter
+func (m *DataSyncMonitor) ReportCycle(syncer string, report SyncCycleReport) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	st, ok := m.stats[syncer]
+	if !ok {
+		st = &syncerThroughput{}
+		m.stats[syncer] = st
+	}
+	st.cycles++
+	st.rows += report.Rows
+	st.totalDuration += report.Duration
+	st.last = report
+	if report.Err != nil {
+		st.errors++
+		st.lastError = report.Err.Error()
+		st.lastErrorTime = report.At
+		st.addError(SyncErrorEntry{Time: report.At, Error: report.Err.Error(), Duration: report.Duration.Milliseconds()}, m.errorLog)
+	}
+	st.recent = append(st.recent, report)
+	m.pruneLocked(st, m.now())
+}
+
+// SetErrorLogSize 设置每个同步器保留的最近错误条数；需在开始上报前调用，n<=0 时忽略
+func (m *DataSyncMonitor) SetErrorLogSize(n int) {
+	if n <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errorLog = n
+}
+
+// RecentErrors 各同步器最近的错误（从新到旧）；syncer 非空时只返回该同步器
+func (m *DataSyncMonitor) RecentErrors(syncer string) map[string][]SyncErrorEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make(map[string][]SyncErrorEntry)
+	for name, st := range m.stats {
+		if syncer != "" && name != syncer {
+			continue
+		}
+		if len(st.errorLog) > 0 {
+			out[name] = st.recentErrors()
+		}
+	}
+	return out
+}
+
+// pruneLocked 丢弃窗口外的结果，调用方需持有写锁
+func (m *DataSyncMonitor) pruneLocked(st *syncerThroughput, now time.Time) {
+	cutoff := now.Add(-m.window)
+	i := 0
+	for i < len(st.recent) && st.recent[i].At.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		st.recent = append(st.recent[:0], st.recent[i:]...)
+	}
+}
+
+// Prune 清理所有同步器窗口外的结果
+func (m *DataSyncMonitor) Prune() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for _, st := range m.stats {
+		m.pruneLocked(st, now)
+	}
+}
+
+// Snapshot 返回各同步器的累计与滚动统计（rows_per_minute / error_rate / avg_duration_ms 基于窗口）
+func (m *DataSyncMonitor) Snapshot() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cutoff := m.now().Add(-m.window)
+	out := make(map[string]interface{}, len(m.stats))
+	for name, st := range m.stats {
+		var rows, errs, cycles int64
+		var dur time.Duration
+		for _, r := range st.recent {
+			if r.At.Before(cutoff) {
+				continue
+			}
+			cycles++
+			rows += r.Rows
+			dur += r.Duration
+			if r.Err != nil {
+				errs++
+			}
+		}
+
+		entry := map[string]interface{}{
+			"total_cycles":     st.cycles,
+			"total_errors":     st.errors,
+			"total_rows":       st.rows,
+			"last_cycle_time":  st.last.At,
+			"last_cycle_rows":  st.last.Rows,
+			"last_duration_ms": st.last.Duration.Milliseconds(),
+			"window_cycles":    cycles,
+			"window_rows":      rows,
+			"rows_per_minute":  float64(rows) / m.window.Minutes(),
+			"error_rate":       0.0,
+			"avg_duration_ms":  int64(0),
+		}
+		if cycles > 0 {
+			entry["error_rate"] = float64(errs) / float64(cycles)
+			entry["avg_duration_ms"] = (dur / time.Duration(cycles)).Milliseconds()
+		}
+		if st.lastError != "" {
+			entry["last_error"] = st.lastError
+			entry["last_error_time"] = st.lastErrorTime
+		}
+		out[name] = entry
+	}
+	return out
+}
+
+// Totals 所有同步器窗口内的总行数与错误数
+func (m *DataSyncMonitor) Totals() (rows, errs int64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cutoff := m.now().Add(-m.window)
+	for _, st := range m.stats {
+		for _, r := range st.recent {
+			if r.At.Before(cutoff) {
+				continue
+			}
+			rows += r.Rows
+			if r.Err != nil {
+				errs++
+			}
+		}
+	}
+	return rows, errs
+}
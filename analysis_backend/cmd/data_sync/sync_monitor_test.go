@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDataSyncMonitorRollingStats 校验累计值、窗口内速率与过期清理
+func TestDataSyncMonitorRollingStats(t *testing.T) {
+	now := time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	m := NewDataSyncMonitor(10 * time.Minute)
+	m.now = func() time.Time { return now }
+
+	m.ReportCycle("price", SyncCycleReport{At: now.Add(-20 * time.Minute), Rows: 1000, Duration: time.Second})
+	m.ReportCycle("price", SyncCycleReport{At: now.Add(-5 * time.Minute), Rows: 100, Duration: 2 * time.Second})
+	m.ReportCycle("price", SyncCycleReport{At: now.Add(-time.Minute), Rows: 50, Duration: 4 * time.Second, Err: errors.New("timeout")})
+
+	st, ok := m.Snapshot()["price"].(map[string]interface{})
+	if !ok {
+		t.Fatal("缺少 price 统计")
+	}
+	if st["total_cycles"] != int64(3) || st["total_rows"] != int64(1150) || st["total_errors"] != int64(1) {
+		t.Errorf("累计值不符: %+v", st)
+	}
+	if st["window_cycles"] != int64(2) || st["window_rows"] != int64(150) {
+		t.Errorf("窗口外的结果应被排除: %+v", st)
+	}
+	if st["rows_per_minute"] != 15.0 || st["error_rate"] != 0.5 || st["avg_duration_ms"] != int64(3000) {
+		t.Errorf("滚动速率不符: %+v", st)
+	}
+	if st["last_error"] != "timeout" {
+		t.Errorf("应记录最近错误: %+v", st)
+	}
+
+	now = now.Add(30 * time.Minute)
+	m.Prune()
+	if rows, errs := m.Totals(); rows != 0 || errs != 0 {
+		t.Errorf("窗口过期后应为 0，实际 rows=%d errs=%d", rows, errs)
+	}
+}
+
+// TestDataSyncMonitorConcurrentReports 多个同步器并发上报的同时读取状态（配合 -race 运行）
+func TestDataSyncMonitorConcurrentReports(t *testing.T) {
+	svc := &DataSyncService{
+		syncers: make(map[string]DataSyncer),
+		monitor: NewDataSyncMonitor(time.Hour),
+	}
+
+	const syncers, cycles = 4, 200
+	reporters := make([]*cycleReporter, syncers)
+	for i := range reporters {
+		reporters[i] = &cycleReporter{}
+		reporters[i].SetReporter(fmt.Sprintf("syncer_%d", i), svc.monitor)
+	}
+
+	var wg sync.WaitGroup
+	for _, r := range reporters {
+		wg.Add(1)
+		go func(r *cycleReporter) {
+			defer wg.Done()
+			for c := 0; c < cycles; c++ {
+				var err error
+				if c%10 == 0 {
+					err = errors.New("boom")
+				}
+				r.reportCycle(time.Now(), 5, err)
+			}
+		}(r)
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					_ = svc.GetStatus()
+					svc.monitor.Prune()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	throughput := svc.GetStatus()["throughput"].(map[string]interface{})
+	if len(throughput) != syncers {
+		t.Fatalf("期望 %d 个同步器的统计，实际 %d", syncers, len(throughput))
+	}
+	for name, v := range throughput {
+		st := v.(map[string]interface{})
+		if st["total_cycles"] != int64(cycles) || st["total_rows"] != int64(cycles*5) || st["total_errors"] != int64(cycles/10) {
+			t.Errorf("%s 统计不符: %+v", name, st)
+		}
+	}
+}
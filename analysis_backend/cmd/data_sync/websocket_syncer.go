@@ -1209,7 +1209,7 @@ func (s *WebSocketSyncer) saveFuturesData(data map[string]FuturesData) {
 			continue
 		}
 
-		log.Printf("[WebSocketSyncer] ✅ Saved futures price: %s = %.4f", symbol, futuresData.Price)
+		log.Printf("[WebSocketSyncer] ✅ Saved futures price: %s = %s", symbol, futuresData.Price)
 	}
 }
 
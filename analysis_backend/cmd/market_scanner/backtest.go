@@ -0,0 +1,143 @@
+// cmd/market_scanner/backtest.go
+// 回调信号回测：回放已入库的时间槽快照，模拟在回调信号出现时买入、持有若干槽后卖出
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"analysis/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// slotSnapshot 一个时间槽的榜单快照
+type slotSnapshot struct {
+	Bucket time.Time
+	Tops   []db.BinanceMarketTop
+}
+
+// pullbackOptions 回调信号参数
+type pullbackOptions struct {
+	TopN      int     // 只看上一槽榜单前 N 名（<=0 不限）
+	MinGain   float64 // 上一槽 24h 涨幅下限（%），过滤掉本来就不强势的币
+	MinDrop   float64 // 本槽相对上一槽的价格回调幅度下限（%，正数）
+	HoldSlots int     // 信号后持有的槽数
+}
+
+// pullbackSignal 回调信号：强势币在相邻两个槽之间价格回落
+type pullbackSignal struct {
+	Symbol   string    `json:"symbol"`
+	Bucket   time.Time `json:"bucket"`
+	PrevRank int       `json:"prev_rank"`
+	Drop     float64   `json:"drop"` // 回调幅度（%，正数）
+	Price    float64   `json:"price"`
+}
+
+// pullbackTrade 单次模拟交易
+type pullbackTrade struct {
+	pullbackSignal
+	ExitBucket time.Time `json:"exit_bucket"`
+	ExitPrice  float64   `json:"exit_price"`
+	Return     float64   `json:"return"` // 前瞻收益（%）
+}
+
+// pullbackReport 回测汇总
+type pullbackReport struct {
+	Slots     int             `json:"slots"`
+	Signals   int             `json:"signals"`
+	Evaluated int             `json:"evaluated"` // 有退出价格的信号数
+	Hits      int             `json:"hits"`      // 前瞻收益 > 0
+	HitRate   float64         `json:"hit_rate"`
+	AvgReturn float64         `json:"avg_return"`
+	Trades    []pullbackTrade `json:"trades"`
+}
+
+func topPrices(tops []db.BinanceMarketTop) map[string]float64 {
+	out := make(map[string]float64, len(tops))
+	for _, t := range tops {
+		if p, err := strconv.ParseFloat(t.LastPrice, 64); err == nil && p > 0 {
+			out[t.Symbol] = p
+		}
+	}
+	return out
+}
+
+// detectPullbacks 比较相邻两个槽：上一槽榜单前 TopN 且涨幅 >= MinGain 的币，本槽价格回落 >= MinDrop 时产生信号
+func detectPullbacks(prev, cur slotSnapshot, opts pullbackOptions) []pullbackSignal {
+	curPrices := topPrices(cur.Tops)
+	var out []pullbackSignal
+	for _, p := range prev.Tops {
+		if opts.TopN > 0 && p.Rank > opts.TopN {
+			continue
+		}
+		if p.PctChange < opts.MinGain {
+			continue
+		}
+		prevPrice, err := strconv.ParseFloat(p.LastPrice, 64)
+		if err != nil || prevPrice <= 0 {
+			continue
+		}
+		price, ok := curPrices[p.Symbol]
+		if !ok {
+			continue
+		}
+		drop := (prevPrice - price) / prevPrice * 100
+		if drop >= opts.MinDrop {
+			out = append(out, pullbackSignal{Symbol: p.Symbol, Bucket: cur.Bucket, PrevRank: p.Rank, Drop: drop, Price: price})
+		}
+	}
+	return out
+}
+
+// runPullbackBacktest 按时间顺序回放快照；信号在第 i 槽以该槽价格买入，第 i+HoldSlots 槽价格卖出。
+// 退出槽缺失或该币不在退出槽榜单中时只计入信号数，不计入收益统计。
+func runPullbackBacktest(snaps []slotSnapshot, opts pullbackOptions) pullbackReport {
+	if opts.HoldSlots <= 0 {
+		opts.HoldSlots = 1
+	}
+	rep := pullbackReport{Slots: len(snaps), Trades: []pullbackTrade{}}
+	total := 0.0
+	for i := 1; i < len(snaps); i++ {
+		signals := detectPullbacks(snaps[i-1], snaps[i], opts)
+		rep.Signals += len(signals)
+		exit := i + opts.HoldSlots
+		if exit >= len(snaps) {
+			continue
+		}
+		exitPrices := topPrices(snaps[exit].Tops)
+		for _, sig := range signals {
+			px, ok := exitPrices[sig.Symbol]
+			if !ok {
+				continue
+			}
+			ret := (px - sig.Price) / sig.Price * 100
+			rep.Trades = append(rep.Trades, pullbackTrade{pullbackSignal: sig, ExitBucket: snaps[exit].Bucket, ExitPrice: px, Return: ret})
+			rep.Evaluated++
+			total += ret
+			if ret > 0 {
+				rep.Hits++
+			}
+		}
+	}
+	if rep.Evaluated > 0 {
+		rep.HitRate = float64(rep.Hits) / float64(rep.Evaluated)
+		rep.AvgReturn = total / float64(rep.Evaluated)
+	}
+	return rep
+}
+
+// loadSlotSnapshots 读取 [start, end] 内已入库的快照（按时间升序）
+func loadSlotSnapshots(gdb *gorm.DB, kind string, start, end time.Time) ([]slotSnapshot, error) {
+	snaps, tops, err := db.ListBinanceMarket(gdb, kind, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 快照失败: %w", kind, err)
+	}
+	out := make([]slotSnapshot, 0, len(snaps))
+	for _, s := range snaps {
+		out = append(out, slotSnapshot{Bucket: s.Bucket, Tops: tops[s.ID]})
+	}
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"analysis/internal/db"
+)
+
+func top(symbol, price string, pct float64, rank int) db.BinanceMarketTop {
+	return db.BinanceMarketTop{Symbol: symbol, LastPrice: price, PctChange: pct, Rank: rank}
+}
+
+// TestRunPullbackBacktest 构造快照序列，校验信号识别、前瞻收益与命中率
+func TestRunPullbackBacktest(t *testing.T) {
+	t0 := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	slot := func(i int, tops ...db.BinanceMarketTop) slotSnapshot {
+		return slotSnapshot{Bucket: t0.Add(time.Duration(i) * 2 * time.Hour), Tops: tops}
+	}
+	snaps := []slotSnapshot{
+		slot(0,
+			top("AAAUSDT", "100", 30, 1),
+			top("BBBUSDT", "50", 20, 2),
+			top("CCCUSDT", "10", 5, 3),   // 涨幅不足，不产生信号
+			top("DDDUSDT", "10", 15, 30), // 排名超出 TopN
+		),
+		slot(1,
+			top("AAAUSDT", "90", 25, 1), // 回调 10% -> 信号
+			top("BBBUSDT", "49", 19, 2), // 回调 2%，不足 3%
+			top("CCCUSDT", "8", 3, 3),   // 回调 20%，但上一槽涨幅不足
+			top("DDDUSDT", "5", 10, 30), // 排名超出
+		),
+		slot(2,
+			top("AAAUSDT", "99", 28, 1), // AAA 持有 1 槽后退出：+10%
+			top("BBBUSDT", "45", 9, 2),  // 相对 49 回调 8.16% -> 信号
+		),
+		slot(3,
+			top("BBBUSDT", "40.5", 10, 1), // BBB 退出：-10%；上一槽涨幅 9 不足，不产生新信号
+		),
+		slot(4,
+			top("BBBUSDT", "36", 5, 1), // 上一槽涨幅 10 >= 10，回调 11.1% -> 信号，但无退出槽
+		),
+	}
+	rep := runPullbackBacktest(snaps, pullbackOptions{TopN: 10, MinGain: 10, MinDrop: 3, HoldSlots: 1})
+
+	if rep.Slots != 5 || rep.Signals != 3 || rep.Evaluated != 2 || rep.Hits != 1 {
+		t.Fatalf("汇总不符: %+v", rep)
+	}
+	if math.Abs(rep.HitRate-0.5) > 1e-9 || math.Abs(rep.AvgReturn-0) > 1e-9 {
+		t.Errorf("期望命中率 0.5、平均收益 0，实际 %v / %v", rep.HitRate, rep.AvgReturn)
+	}
+	want := []struct {
+		symbol string
+		entry  float64
+		exit   float64
+		ret    float64
+	}{
+		{"AAAUSDT", 90, 99, 10},
+		{"BBBUSDT", 45, 40.5, -10},
+	}
+	for i, w := range want {
+		tr := rep.Trades[i]
+		if tr.Symbol != w.symbol || tr.Price != w.entry || tr.ExitPrice != w.exit || math.Abs(tr.Return-w.ret) > 1e-9 {
+			t.Errorf("第 %d 笔交易不符: %+v", i, tr)
+		}
+		if !tr.ExitBucket.Equal(tr.Bucket.Add(2 * time.Hour)) {
+			t.Errorf("第 %d 笔交易应在下一槽退出: %+v", i, tr)
+		}
+	}
+
+	// 持有 2 槽：AAA 在第 3 槽不在榜单，无法评估；BBB 在第 4 槽退出 -20%
+	rep = runPullbackBacktest(snaps, pullbackOptions{TopN: 10, MinGain: 10, MinDrop: 3, HoldSlots: 2})
+	if rep.Signals != 3 || rep.Evaluated != 1 || rep.Hits != 0 || math.Abs(rep.AvgReturn+20) > 1e-9 {
+		t.Errorf("持有 2 槽汇总不符: %+v", rep)
+	}
+}
@@ -0,0 +1,209 @@
+// cmd/market_scanner/breaker.go
+// 采集重试与熔断：单个槽失败时指数退避重试；连续失败达到阈值后熔断（只探测一次、不再密集重试）并发邮件告警。
+// 失败的槽记入待补队列：下一个槽仍落在同一小时桶时先补采；已过去的小时桶只能拿到当前行情，不补采，记为缺口。
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"analysis/internal/server"
+)
+
+// retryPolicy 单槽重试策略
+type retryPolicy struct {
+	Attempts  int           // 最大尝试次数（含首次）
+	BaseDelay time.Duration // 首次重试等待，之后翻倍
+	MaxDelay  time.Duration // 单次等待上限
+}
+
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// circuitBreaker 连续失败熔断器
+type circuitBreaker struct {
+	Threshold int           // 连续失败多少个槽后熔断
+	Cooldown  time.Duration // 熔断持续时间
+	Mailer    server.Mailer // 可选，熔断时发送告警
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	alerted   bool
+}
+
+// Open 当前是否处于熔断状态
+func (b *circuitBreaker) Open(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.openUntil)
+}
+
+// Success 成功一次即恢复
+func (b *circuitBreaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.alerted {
+		log.Printf("[breaker] 采集已恢复")
+	}
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.alerted = false
+}
+
+// Failure 记录一次槽失败；达到阈值时熔断并（每次故障只）告警一次
+func (b *circuitBreaker) Failure(now time.Time, err error) {
+	b.mu.Lock()
+	b.failures++
+	tripped := b.Threshold > 0 && b.failures >= b.Threshold
+	if tripped {
+		b.openUntil = now.Add(b.Cooldown)
+	}
+	sendAlert := tripped && !b.alerted && b.Mailer != nil
+	if tripped {
+		b.alerted = true
+	}
+	failures := b.failures
+	b.mu.Unlock()
+
+	if tripped {
+		log.Printf("[breaker] 连续 %d 个槽采集失败，熔断 %v: %v", failures, b.Cooldown, err)
+	}
+	if sendAlert {
+		subject := fmt.Sprintf("[market_scanner] 连续 %d 个槽采集失败", failures)
+		text := fmt.Sprintf("market_scanner 连续 %d 个槽采集失败，已熔断 %v，之后每个槽仍会尝试一次。\n最近错误: %v", failures, b.Cooldown, err)
+		if mailErr := b.Mailer.Send(subject, "<pre>"+text+"</pre>", text); mailErr != nil {
+			log.Printf("[breaker] 发送告警邮件失败: %v", mailErr)
+		}
+	}
+}
+
+// slotRunner 按槽执行采集，负责重试、熔断、补采与缺口记录
+type slotRunner struct {
+	Retry   retryPolicy
+	Breaker *circuitBreaker
+	MaxGaps int // 保留的缺口记录上限，超出时丢弃最旧的
+
+	Scan  func(ctx context.Context, bucket time.Time) error
+	Sleep func(ctx context.Context, d time.Duration) error // 测试时可替换
+	Now   func() time.Time
+
+	pending []time.Time
+	gaps    []time.Time
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (r *slotRunner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// attempt 采集单个槽；熔断期间只尝试一次
+func (r *slotRunner) attempt(ctx context.Context, bucket time.Time) error {
+	attempts := r.Retry.Attempts
+	if attempts < 1 || (r.Breaker != nil && r.Breaker.Open(r.now())) {
+		attempts = 1
+	}
+	sleep := r.Sleep
+	if sleep == nil {
+		sleep = sleepCtx
+	}
+	var err error
+	for i := 1; i <= attempts; i++ {
+		if err = r.Scan(ctx, bucket); err == nil {
+			return nil
+		}
+		if i == attempts {
+			break
+		}
+		d := r.Retry.delay(i)
+		log.Printf("[slot %s] 采集失败，%v 后重试 %d/%d: %v", bucket.Format(time.RFC3339), d, i, attempts-1, err)
+		if serr := sleep(ctx, d); serr != nil {
+			return serr
+		}
+	}
+	return err
+}
+
+// RunSlot 先补采之前失败且仍属当前小时桶的槽，再采集当前槽；失败的槽留在待补队列。
+// 待补槽的小时桶已过去时不再采集（接口只返回当前 24h 行情，写入过去的桶就是伪造历史），记为缺口
+func (r *slotRunner) RunSlot(ctx context.Context, bucket time.Time) error {
+	queue := append(r.pending, bucket)
+	r.pending = nil
+	cur := marketBucket(bucket)
+	var lastErr error
+	for _, b := range queue {
+		if !marketBucket(b).Equal(cur) {
+			r.markGap(b)
+			continue
+		}
+		if err := r.attempt(ctx, b); err != nil {
+			lastErr = err
+			r.pending = append(r.pending, b)
+			continue
+		}
+		if !b.Equal(bucket) {
+			log.Printf("[slot %s] 补采成功", b.Format(time.RFC3339))
+		}
+	}
+
+	if r.Breaker != nil {
+		if lastErr != nil {
+			r.Breaker.Failure(r.now(), lastErr)
+		} else {
+			r.Breaker.Success()
+		}
+	}
+	return lastErr
+}
+
+// markGap 记录无法补采的槽（同一小时桶只记一次）
+func (r *slotRunner) markGap(b time.Time) {
+	gb := marketBucket(b)
+	for _, g := range r.gaps {
+		if g.Equal(gb) {
+			return
+		}
+	}
+	log.Printf("[slot %s] 采集失败且小时桶 %s 已过去，无法补采，记为缺口", b.Format(time.RFC3339), gb.Format(time.RFC3339))
+	r.gaps = append(r.gaps, gb)
+	if r.MaxGaps > 0 && len(r.gaps) > r.MaxGaps {
+		r.gaps = r.gaps[len(r.gaps)-r.MaxGaps:]
+	}
+}
+
+// Pending 尚未补采成功、仍可补采的槽
+func (r *slotRunner) Pending() []time.Time {
+	return append([]time.Time(nil), r.pending...)
+}
+
+// Gaps 因小时桶已过去而缺失的快照（小时桶开始时间）
+func (r *slotRunner) Gaps() []time.Time {
+	return append([]time.Time(nil), r.gaps...)
+}
+
+// marketBucket 快照所属的小时桶（与 scanMarketInternal 写入的 bucket 一致）
+func marketBucket(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Hour)
+}
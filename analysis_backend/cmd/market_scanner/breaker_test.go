@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeMailer struct{ sent []string }
+
+func (m *fakeMailer) Send(subject, htmlBody, textBody string) error {
+	m.sent = append(m.sent, subject)
+	return nil
+}
+
+// fakeScan 按槽记录调用次数，failFor 中的槽前 n 次失败
+type fakeScan struct {
+	calls   map[time.Time]int
+	failFor map[time.Time]int
+	down    bool // 模拟整体不可用
+}
+
+func (f *fakeScan) scan(ctx context.Context, bucket time.Time) error {
+	f.calls[bucket]++
+	if f.down || f.calls[bucket] <= f.failFor[bucket] {
+		return errors.New("binance 503")
+	}
+	return nil
+}
+
+func newTestRunner(f *fakeScan, now *time.Time, mailer *fakeMailer) (*slotRunner, *[]time.Duration) {
+	var sleeps []time.Duration
+	r := &slotRunner{
+		Retry:   retryPolicy{Attempts: 3, BaseDelay: time.Second, MaxDelay: 3 * time.Second},
+		Breaker: &circuitBreaker{Threshold: 2, Cooldown: 3 * time.Hour},
+		MaxGaps: 6,
+		Scan:    f.scan,
+		Sleep: func(ctx context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		},
+		Now: func() time.Time { return *now },
+	}
+	if mailer != nil {
+		r.Breaker.Mailer = mailer
+	}
+	return r, &sleeps
+}
+
+// TestSlotRunnerRetry 瞬时失败在重试内恢复，按指数退避等待
+func TestSlotRunnerRetry(t *testing.T) {
+	slot := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	f := &fakeScan{calls: map[time.Time]int{}, failFor: map[time.Time]int{slot: 2}}
+	now := slot
+	r, sleeps := newTestRunner(f, &now, nil)
+
+	if err := r.RunSlot(context.Background(), slot); err != nil {
+		t.Fatalf("第 3 次应成功: %v", err)
+	}
+	if f.calls[slot] != 3 {
+		t.Errorf("期望尝试 3 次，实际 %d", f.calls[slot])
+	}
+	if len(*sleeps) != 2 || (*sleeps)[0] != time.Second || (*sleeps)[1] != 2*time.Second {
+		t.Errorf("退避间隔不符: %v", *sleeps)
+	}
+	if len(r.Pending()) != 0 {
+		t.Errorf("成功后不应有待补槽: %v", r.Pending())
+	}
+}
+
+// TestSlotRunnerBreakerAndGaps 连续失败熔断并告警一次；熔断期间仍尝试下一槽；
+// 已过去的小时桶不再用当前行情补采，记为缺口
+func TestSlotRunnerBreakerAndGaps(t *testing.T) {
+	s1 := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	s2, s3, s4 := s1.Add(2*time.Hour), s1.Add(4*time.Hour), s1.Add(6*time.Hour)
+	f := &fakeScan{calls: map[time.Time]int{}, failFor: map[time.Time]int{}, down: true}
+	mailer := &fakeMailer{}
+	now := s1
+	r, _ := newTestRunner(f, &now, mailer)
+	ctx := context.Background()
+
+	// 槽 1：重试 3 次仍失败，进入待补队列，未达熔断阈值
+	if err := r.RunSlot(ctx, s1); err == nil {
+		t.Fatal("槽 1 应失败")
+	}
+	if f.calls[s1] != 3 || len(r.Pending()) != 1 || r.Breaker.Open(now) || len(mailer.sent) != 0 {
+		t.Fatalf("槽 1 状态不符: calls=%d pending=%v", f.calls[s1], r.Pending())
+	}
+
+	// 槽 2：槽 1 的小时桶已过去，记为缺口不再采集；槽 2 也失败 -> 熔断并告警
+	now = s2
+	_ = r.RunSlot(ctx, s2)
+	if f.calls[s1] != 3 || f.calls[s2] != 3 {
+		t.Errorf("过去的槽不应再采集: calls=%v", f.calls)
+	}
+	if !r.Breaker.Open(now) || len(mailer.sent) != 1 {
+		t.Fatalf("连续 2 个槽失败应熔断并告警一次: open=%v mails=%v", r.Breaker.Open(now), mailer.sent)
+	}
+
+	// 槽 3：熔断期间每个槽仍尝试一次（不重试），且不重复告警
+	now = s3
+	_ = r.RunSlot(ctx, s3)
+	if f.calls[s3] != 1 || f.calls[s2] != 3 {
+		t.Errorf("熔断期间只应尝试当前槽一次: calls=%v", f.calls)
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("熔断期间不应重复告警: %v", mailer.sent)
+	}
+
+	// 槽 4：服务恢复（仍在熔断期内，探测一次即成功），熔断关闭；失败的三个槽都是缺口
+	f.down = false
+	now = s4
+	if err := r.RunSlot(ctx, s4); err != nil {
+		t.Fatalf("恢复后应成功: %v", err)
+	}
+	if len(r.Pending()) != 0 || r.Breaker.Open(now) {
+		t.Errorf("恢复后不应有待补槽且熔断关闭: pending=%v", r.Pending())
+	}
+	gaps := r.Gaps()
+	if len(gaps) != 3 || !gaps[0].Equal(s1) || !gaps[1].Equal(s2) || !gaps[2].Equal(s3) {
+		t.Errorf("期望缺口为槽 1-3，实际 %v", gaps)
+	}
+	if f.calls[s4] != 1 {
+		t.Errorf("槽 4 应采集一次: calls=%v", f.calls)
+	}
+}
+
+// TestSlotRunnerBackfillSameBucket 同一小时桶内的下一个槽补采失败的槽，不记缺口
+func TestSlotRunnerBackfillSameBucket(t *testing.T) {
+	s1 := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	s2 := s1.Add(30 * time.Minute)
+	f := &fakeScan{calls: map[time.Time]int{}, failFor: map[time.Time]int{s1: 3}}
+	now := s1
+	r, _ := newTestRunner(f, &now, nil)
+
+	if err := r.RunSlot(context.Background(), s1); err == nil {
+		t.Fatal("槽 1 应失败")
+	}
+	now = s2
+	if err := r.RunSlot(context.Background(), s2); err != nil {
+		t.Fatalf("同桶补采应成功: %v", err)
+	}
+	if f.calls[s1] != 4 || f.calls[s2] != 1 || len(r.Pending()) != 0 || len(r.Gaps()) != 0 {
+		t.Errorf("同桶应补采而非记缺口: calls=%v pending=%v gaps=%v", f.calls, r.Pending(), r.Gaps())
+	}
+}
+
+// TestSlotRunnerMaxGaps 缺口记录有上限，丢弃最旧的
+func TestSlotRunnerMaxGaps(t *testing.T) {
+	f := &fakeScan{calls: map[time.Time]int{}, failFor: map[time.Time]int{}, down: true}
+	now := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	r, _ := newTestRunner(f, &now, nil)
+	r.MaxGaps = 2
+	for i := 0; i < 5; i++ {
+		_ = r.RunSlot(context.Background(), now.Add(time.Duration(i)*time.Hour))
+	}
+	got := r.Gaps()
+	if len(got) != 2 || !got[0].Equal(now.Add(2*time.Hour)) || !got[1].Equal(now.Add(3*time.Hour)) {
+		t.Errorf("应只保留最近 2 个缺口: %v", got)
+	}
+	if p := r.Pending(); len(p) != 1 || !p[0].Equal(now.Add(4*time.Hour)) {
+		t.Errorf("只有当前槽待补: %v", p)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"analysis/internal/db"
+)
+
+// leaderboardSizes 单个市场各榜单的大小；Gainers<=0 表示不截断（全部交易对），Losers<=0 表示不采集跌幅榜
+type leaderboardSizes struct {
+	Gainers int
+	Losers  int
+}
+
+// leaderboard 一个时间槽内的一份榜单
+type leaderboard struct {
+	Board   string
+	Tickers []Binance24hrTicker
+}
+
+// buildLeaderboards 从按涨幅降序排好的行情中截取涨幅榜 / 跌幅榜（跌幅榜按跌幅从大到小）
+func buildLeaderboards(sorted []Binance24hrTicker, sizes leaderboardSizes) []leaderboard {
+	gainers := sorted
+	if sizes.Gainers > 0 && sizes.Gainers < len(sorted) {
+		gainers = sorted[:sizes.Gainers]
+	}
+	boards := []leaderboard{{Board: db.MarketBoardGainers, Tickers: gainers}}
+
+	if sizes.Losers > 0 {
+		n := min(sizes.Losers, len(sorted))
+		losers := make([]Binance24hrTicker, 0, n)
+		for i := len(sorted) - 1; i >= len(sorted)-n; i-- {
+			losers = append(losers, sorted[i])
+		}
+		boards = append(boards, leaderboard{Board: db.MarketBoardLosers, Tickers: losers})
+	}
+	return boards
+}
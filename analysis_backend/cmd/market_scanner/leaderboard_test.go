@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"analysis/internal/db"
+)
+
+// TestBuildLeaderboards 现货 / 期货各自的榜单大小互不影响，跌幅榜按跌幅从大到小
+func TestBuildLeaderboards(t *testing.T) {
+	var tickers []Binance24hrTicker
+	for i := 0; i < 10; i++ {
+		// 涨幅 9, 7, 5, ..., -9
+		tickers = append(tickers, Binance24hrTicker{
+			Symbol: fmt.Sprintf("S%dUSDT", i), LastPrice: "1", Volume: "1",
+			PriceChangePercent: fmt.Sprintf("%d", 9-2*i),
+		})
+	}
+	sorted := filterAndSortTickers(tickers, "spot")
+
+	spot := buildLeaderboards(sorted, leaderboardSizes{Gainers: 3, Losers: 2})
+	futures := buildLeaderboards(sorted, leaderboardSizes{Gainers: 5})
+
+	if len(spot) != 2 || spot[0].Board != db.MarketBoardGainers || spot[1].Board != db.MarketBoardLosers {
+		t.Fatalf("现货应有涨幅榜和跌幅榜: %+v", spot)
+	}
+	if got := symbolsOf(spot[0].Tickers); fmt.Sprint(got) != "[S0USDT S1USDT S2USDT]" {
+		t.Errorf("现货涨幅榜不符: %v", got)
+	}
+	if got := symbolsOf(spot[1].Tickers); fmt.Sprint(got) != "[S9USDT S8USDT]" {
+		t.Errorf("现货跌幅榜不符: %v", got)
+	}
+	if len(futures) != 1 || len(futures[0].Tickers) != 5 {
+		t.Errorf("期货只应有 5 条涨幅榜: %+v", futures)
+	}
+
+	// 未配置大小时涨幅榜保留全部交易对；榜单大小超过交易对数时不越界
+	all := buildLeaderboards(sorted, leaderboardSizes{Losers: 50})
+	if len(all[0].Tickers) != 10 || len(all[1].Tickers) != 10 {
+		t.Errorf("榜单大小应截断到交易对数: %d / %d", len(all[0].Tickers), len(all[1].Tickers))
+	}
+}
+
+func symbolsOf(tickers []Binance24hrTicker) []string {
+	out := make([]string, 0, len(tickers))
+	for _, tk := range tickers {
+		out = append(out, tk.Symbol)
+	}
+	return out
+}
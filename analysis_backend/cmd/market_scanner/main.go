@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -16,6 +17,8 @@ import (
 	"analysis/internal/config"
 	"analysis/internal/db"
 	"analysis/internal/netutil"
+	"analysis/internal/server"
+	"analysis/internal/util"
 )
 
 type Binance24hrTicker struct {
@@ -55,6 +58,7 @@ type MarketDataItem struct {
 
 type MarketDataRequest struct {
 	Kind      string           `json:"kind"`
+	Board     string           `json:"board,omitempty"` // gainers | losers
 	Bucket    string           `json:"bucket"`
 	FetchedAt string           `json:"fetched_at"`
 	Items     []MarketDataItem `json:"items"`
@@ -63,10 +67,40 @@ type MarketDataRequest struct {
 func main() {
 	configPath := flag.String("config", "config.yaml", "config file path")
 	apiBase := flag.String("api", "http://localhost:8010", "api base url")
-	interval := flag.Duration("interval", 1*time.Hour, "scan interval")
+	interval := flag.Duration("interval", 1*time.Hour, "scan interval (must divide 24h)")
+	offset := flag.Duration("offset", 0, "slot alignment offset within the interval, e.g. 30m for 00:30, 02:30 with -interval 2h")
+	tzName := flag.String("tz", "UTC", "timezone used for slot alignment, e.g. Asia/Taipei")
+	backtestFrom := flag.String("backtest-from", "", "one-shot pullback backtest over stored snapshots from this date (YYYY-MM-DD), then exit")
+	backtestTo := flag.String("backtest-to", "", "backtest end date (YYYY-MM-DD, inclusive); default now")
+	backtestKind := flag.String("backtest-kind", "futures", "backtest market kind: spot | futures")
+	pullbackTop := flag.Int("pullback-top", 20, "pullback signal: only symbols ranked within top N in the previous slot")
+	pullbackMinGain := flag.Float64("pullback-min-gain", 10, "pullback signal: minimum 24h change (%) in the previous slot")
+	pullbackDrop := flag.Float64("pullback-drop", 3, "pullback signal: minimum price drop (%) from the previous slot")
+	holdSlots := flag.Int("hold-slots", 1, "backtest: slots to hold after entering on a signal")
+	spotTop := flag.Int("spot-top", 0, "spot gainers leaderboard size (0 = all symbols)")
+	futuresTop := flag.Int("futures-top", 0, "futures gainers leaderboard size (0 = all symbols)")
+	spotLosers := flag.Int("spot-losers", 0, "spot losers leaderboard size (0 = disabled)")
+	futuresLosers := flag.Int("futures-losers", 0, "futures losers leaderboard size (0 = disabled)")
+	retryAttempts := flag.Int("retry-attempts", 3, "attempts per slot before it is queued for the next slot")
+	retryDelay := flag.Duration("retry-delay", 10*time.Second, "initial retry backoff (doubles each attempt)")
+	breakerThreshold := flag.Int("breaker-threshold", 3, "consecutive failed slots before the circuit breaker opens and alerts")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Minute, "how long the breaker stays open (one attempt per slot, no retries)")
+	pmServerToken := flag.String("pm-server-token", "", "Postmark Server Token (breaker alerts)")
+	pmFrom := flag.String("pm-from", "", "Postmark From email")
+	pmTo := flag.String("pm-to", "", "Comma-separated recipients")
+	pmStream := flag.String("pm-stream", "outbound", "Postmark message stream")
 	flag.Parse()
 
-	log.Printf("启动参数: config=%s, api=%s, interval=%v", *configPath, *apiBase, *interval)
+	log.Printf("启动参数: config=%s, api=%s, interval=%v, offset=%v, tz=%s", *configPath, *apiBase, *interval, *offset, *tzName)
+
+	loc, err := time.LoadLocation(*tzName)
+	if err != nil {
+		log.Fatalf("加载时区 %s 失败: %v", *tzName, err)
+	}
+	schedule, err := newSlotSchedule(*interval, *offset, loc)
+	if err != nil {
+		log.Fatalf("采集时间槽配置错误: %v", err)
+	}
 
 	// 加载配置
 	var cfg config.Config
@@ -77,6 +111,7 @@ func main() {
 
 	// 初始化数据库连接
 	database, err := db.OpenMySQL(db.Options{
+		Driver:          cfg.Database.Driver,
 		DSN:             cfg.Database.DSN,
 		Automigrate:     false, // 不自动迁移，只查询
 		MaxOpenConns:    cfg.Database.MaxOpenConns,
@@ -94,6 +129,37 @@ func main() {
 		log.Fatalf("获取GORM数据库实例失败: %v", err)
 	}
 
+	if strings.TrimSpace(*backtestFrom) != "" {
+		start, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(*backtestFrom), loc)
+		if err != nil {
+			log.Fatalf("[backtest] invalid -backtest-from %q: %v", *backtestFrom, err)
+		}
+		end := time.Now()
+		if v := strings.TrimSpace(*backtestTo); v != "" {
+			t, err := time.ParseInLocation("2006-01-02", v, loc)
+			if err != nil {
+				log.Fatalf("[backtest] invalid -backtest-to %q: %v", v, err)
+			}
+			end = t.AddDate(0, 0, 1).Add(-time.Second)
+		}
+		snaps, err := loadSlotSnapshots(gormDB, *backtestKind, start.UTC(), end.UTC())
+		if err != nil {
+			log.Fatalf("[backtest] %v", err)
+		}
+		rep := runPullbackBacktest(snaps, pullbackOptions{
+			TopN:      *pullbackTop,
+			MinGain:   *pullbackMinGain,
+			MinDrop:   *pullbackDrop,
+			HoldSlots: *holdSlots,
+		})
+		log.Printf("[backtest] %s %s ~ %s: slots=%d signals=%d evaluated=%d hit_rate=%.2f%% avg_return=%.2f%%",
+			*backtestKind, start.Format("2006-01-02"), end.Format("2006-01-02"),
+			rep.Slots, rep.Signals, rep.Evaluated, rep.HitRate*100, rep.AvgReturn)
+		out, _ := json.MarshalIndent(rep, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
 	// 创建CoinCap市值数据服务
 	marketDataService := db.NewCoinCapMarketDataService(gormDB)
 
@@ -113,55 +179,54 @@ func main() {
 
 	ctx := context.Background()
 	isFirstRun := true
+	spotSizes := leaderboardSizes{Gainers: *spotTop, Losers: *spotLosers}
+	futuresSizes := leaderboardSizes{Gainers: *futuresTop, Losers: *futuresLosers}
+	ingestURL := *apiBase + "/ingest/binance/market"
 
-	for {
-		startTime := time.Now()
+	breaker := &circuitBreaker{Threshold: *breakerThreshold, Cooldown: *breakerCooldown}
+	if *pmServerToken != "" && *pmFrom != "" && *pmTo != "" {
+		breaker.Mailer = server.NewPostmarkMailer(*pmServerToken, *pmFrom, strings.Split(*pmTo, ","), *pmStream)
+	}
+	runner := &slotRunner{
+		Retry:   retryPolicy{Attempts: *retryAttempts, BaseDelay: *retryDelay, MaxDelay: 5 * time.Minute},
+		Breaker: breaker,
+		MaxGaps: 48,
+		Scan: func(ctx context.Context, bucket time.Time) error {
+			return errors.Join(
+				scanMarketWithBucket(ctx, client, marketDataService, "spot", spotSizes, ingestURL, bucket),
+				scanMarketWithBucket(ctx, client, marketDataService, "futures", futuresSizes, ingestURL, bucket),
+			)
+		},
+	}
 
+	slotLoop(ctx, util.SystemClock, schedule, func(startTime time.Time) {
 		if isFirstRun {
 			// 第一次运行时，同时扫描前一个小时和当前小时的数据
 			log.Printf("首次运行，同时扫描前一个小时和当前小时的数据")
 
 			// 扫描前一个小时的数据
-			if err := scanMarketWithBucket(ctx, client, marketDataService, "spot", *apiBase+"/ingest/binance/market", startTime.Add(-1*time.Hour)); err != nil {
+			if err := scanMarketWithBucket(ctx, client, marketDataService, "spot", spotSizes, ingestURL, startTime.Add(-1*time.Hour)); err != nil {
 				log.Printf("扫描前一个小时现货市场失败: %v", err)
 			}
-			if err := scanMarketWithBucket(ctx, client, marketDataService, "futures", *apiBase+"/ingest/binance/market", startTime.Add(-1*time.Hour)); err != nil {
+			if err := scanMarketWithBucket(ctx, client, marketDataService, "futures", futuresSizes, ingestURL, startTime.Add(-1*time.Hour)); err != nil {
 				log.Printf("扫描前一个小时期货市场失败: %v", err)
 			}
 
 			isFirstRun = false
 		}
 
-		// 扫描当前小时的数据
-		if err := scanMarket(ctx, client, marketDataService, "spot", *apiBase+"/ingest/binance/market"); err != nil {
-			log.Printf("扫描现货市场失败: %v", err)
+		// 扫描当前槽（含之前失败待补的槽），失败时重试，连续失败熔断告警
+		if err := runner.RunSlot(ctx, startTime); err != nil {
+			log.Printf("扫描市场失败（待补 %d 个槽，缺口 %d 个）: %v", len(runner.Pending()), len(runner.Gaps()), err)
 		}
-
-		if err := scanMarket(ctx, client, marketDataService, "futures", *apiBase+"/ingest/binance/market"); err != nil {
-			log.Printf("扫描期货市场失败: %v", err)
-		}
-
-		// 计算下次执行时间（1小时对齐）
-		nextBucket := startTime.UTC().Truncate(1 * time.Hour).Add(1 * time.Hour)
-		sleepDuration := nextBucket.Sub(time.Now())
-		if sleepDuration <= 0 {
-			sleepDuration = 1 * time.Hour
-		}
-
-		log.Printf("扫描完成，下次执行时间: %s，等待 %v", nextBucket.Format(time.RFC3339), sleepDuration)
-		time.Sleep(sleepDuration)
-	}
-}
-
-func scanMarketWithBucket(ctx context.Context, client *http.Client, marketDataService *db.CoinCapMarketDataService, kind, apiURL string, bucketTime time.Time) error {
-	return scanMarketInternal(ctx, client, marketDataService, kind, apiURL, &bucketTime)
+	})
 }
 
-func scanMarket(ctx context.Context, client *http.Client, marketDataService *db.CoinCapMarketDataService, kind, apiURL string) error {
-	return scanMarketInternal(ctx, client, marketDataService, kind, apiURL, nil)
+func scanMarketWithBucket(ctx context.Context, client *http.Client, marketDataService *db.CoinCapMarketDataService, kind string, sizes leaderboardSizes, apiURL string, bucketTime time.Time) error {
+	return scanMarketInternal(ctx, client, marketDataService, kind, sizes, apiURL, &bucketTime)
 }
 
-func scanMarketInternal(ctx context.Context, client *http.Client, marketDataService *db.CoinCapMarketDataService, kind, apiURL string, bucketTimeOverride *time.Time) error {
+func scanMarketInternal(ctx context.Context, client *http.Client, marketDataService *db.CoinCapMarketDataService, kind string, sizes leaderboardSizes, apiURL string, bucketTimeOverride *time.Time) error {
 	log.Printf("开始扫描 %s 市场", kind)
 
 	// 获取Binance 24hr统计数据
@@ -183,15 +248,22 @@ func scanMarketInternal(ctx context.Context, client *http.Client, marketDataServ
 
 	// 如果指定了bucket时间，使用指定的时间
 	if bucketTimeOverride != nil {
-		bucket = bucketTimeOverride.UTC().Truncate(1 * time.Hour)
+		bucket = marketBucket(*bucketTimeOverride)
 	}
 
-	// 提取所有交易对的币种符号（去掉USDT后缀）
+	// 按配置截取涨幅榜 / 跌幅榜
+	boards := buildLeaderboards(filtered, sizes)
+
+	// 提取所有榜单交易对的币种符号（去掉USDT后缀）
 	symbols := make([]string, 0, len(filtered))
-	for _, ticker := range filtered {
-		symbol := strings.TrimSuffix(ticker.Symbol, "USDT")
-		if symbol != "" {
-			symbols = append(symbols, symbol)
+	seen := make(map[string]bool, len(filtered))
+	for _, b := range boards {
+		for _, ticker := range b.Tickers {
+			symbol := strings.TrimSuffix(ticker.Symbol, "USDT")
+			if symbol != "" && !seen[symbol] {
+				seen[symbol] = true
+				symbols = append(symbols, symbol)
+			}
 		}
 	}
 
@@ -202,8 +274,28 @@ func scanMarketInternal(ctx context.Context, client *http.Client, marketDataServ
 		marketDataMap = make(map[string]*db.CoinCapMarketData)
 	}
 
-	items := make([]MarketDataItem, 0, len(filtered))
-	for _, ticker := range filtered {
+	// 每份榜单单独发送，各自成为一个快照
+	for _, b := range boards {
+		req := MarketDataRequest{
+			Kind:      kind,
+			Board:     b.Board,
+			Bucket:    bucket.Format(time.RFC3339),
+			FetchedAt: now.Format(time.RFC3339),
+			Items:     buildMarketDataItems(b.Tickers, marketDataMap),
+		}
+
+		// 发送到API
+		if err := sendToAPI(ctx, client, apiURL, req); err != nil {
+			return fmt.Errorf("发送 %s %s 榜单失败: %w", kind, b.Board, err)
+		}
+	}
+	return nil
+}
+
+// buildMarketDataItems 将行情转换为上报条目并附加市值信息
+func buildMarketDataItems(tickers []Binance24hrTicker, marketDataMap map[string]*db.CoinCapMarketData) []MarketDataItem {
+	items := make([]MarketDataItem, 0, len(tickers))
+	for _, ticker := range tickers {
 		pctChange, _ := strconv.ParseFloat(ticker.PriceChangePercent, 64)
 
 		// 提取币种符号（去掉USDT后缀）
@@ -231,16 +323,7 @@ func scanMarketInternal(ctx context.Context, client *http.Client, marketDataServ
 
 		items = append(items, item)
 	}
-
-	req := MarketDataRequest{
-		Kind:      kind,
-		Bucket:    bucket.Format(time.RFC3339),
-		FetchedAt: now.Format(time.RFC3339),
-		Items:     items,
-	}
-
-	// 发送到API
-	return sendToAPI(ctx, client, apiURL, req)
+	return items
 }
 
 func getBinance24hrTickers(ctx context.Context, kind string) ([]Binance24hrTicker, error) {
@@ -318,6 +401,6 @@ func sendToAPI(ctx context.Context, client *http.Client, url string, req MarketD
 		return fmt.Errorf("API响应错误: %d", resp.StatusCode)
 	}
 
-	log.Printf("成功发送 %s 市场数据，交易对数量: %d", req.Kind, len(req.Items))
+	log.Printf("成功发送 %s 市场 %s 榜单，交易对数量: %d", req.Kind, req.Board, len(req.Items))
 	return nil
 }
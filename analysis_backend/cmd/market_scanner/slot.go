@@ -0,0 +1,148 @@
+package main
+
+import (
+	"analysis/internal/util"
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// slotSchedule 采集时间槽：按 Location 本地时间，每天从 00:00+Offset 起每 Period 一个槽
+// 例如 Period=2h、Offset=30m 时槽为 00:30, 02:30, ..., 22:30
+type slotSchedule struct {
+	Period   time.Duration
+	Offset   time.Duration
+	Location *time.Location
+}
+
+// newSlotSchedule 校验并构造时间槽；Period 需整除 24h，Offset 会归一到 [0, Period)
+func newSlotSchedule(period, offset time.Duration, loc *time.Location) (slotSchedule, error) {
+	if period <= 0 || period > 24*time.Hour || (24*time.Hour)%period != 0 {
+		return slotSchedule{}, fmt.Errorf("采集周期 %v 必须能整除 24h", period)
+	}
+	if offset%time.Minute != 0 {
+		return slotSchedule{}, fmt.Errorf("对齐偏移 %v 必须为整分钟", offset)
+	}
+	offset %= period
+	if offset < 0 {
+		offset += period
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	return slotSchedule{Period: period, Offset: offset, Location: loc}, nil
+}
+
+// slotsOfDay 本地日 (y, m, d) 内的全部槽开始时间（升序、去重）
+// 夏令时：重复的本地时间（回拨）只取第一次出现，同一本地槽不会出现两次；跳过的本地时间（前拨）取切换时刻，与已有槽重合时只保留一个
+func (s slotSchedule) slotsOfDay(y int, m time.Month, d int) []time.Time {
+	// d 可能越界（如 0 或 32），先归一成合法日期
+	y, m, d = time.Date(y, m, d, 12, 0, 0, 0, s.Location).Date()
+	out := make([]time.Time, 0, int(24*time.Hour/s.Period)+1)
+	for off := s.Offset; off < 24*time.Hour; off += s.Period {
+		h, mi := int(off/time.Hour), int(off%time.Hour/time.Minute)
+		out = append(out, resolveWallClock(y, m, d, h, mi, s.Location)[0])
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	uniq := out[:0]
+	for _, t := range out {
+		if len(uniq) == 0 || !t.Equal(uniq[len(uniq)-1]) {
+			uniq = append(uniq, t)
+		}
+	}
+	return uniq
+}
+
+// resolveWallClock 本地时间 y-m-d h:mi 对应的所有时刻（升序）
+// 正常情况 1 个；夏令时回拨的重复时段 2 个；前拨跳过的时段返回切换时刻
+func resolveWallClock(y int, m time.Month, d, h, mi int, loc *time.Location) []time.Time {
+	sameWall := func(t time.Time) bool {
+		lt := t.In(loc)
+		ly, lm, ld := lt.Date()
+		return ly == y && lm == m && ld == d && lt.Hour() == h && lt.Minute() == mi
+	}
+	t := time.Date(y, m, d, h, mi, 0, 0, loc)
+	if !sameWall(t) {
+		// 落在前拨跳过的时段：t 被归一到时段之前或之后，取相应一侧的切换时刻
+		start, end := t.ZoneBounds()
+		lt := t.In(loc)
+		wall := time.Date(lt.Year(), lt.Month(), lt.Day(), lt.Hour(), lt.Minute(), 0, 0, time.UTC)
+		if wall.Before(time.Date(y, m, d, h, mi, 0, 0, time.UTC)) {
+			return []time.Time{end}
+		}
+		return []time.Time{start}
+	}
+	out := []time.Time{t}
+	_, off := t.Zone()
+	start, end := t.ZoneBounds()
+	// 相邻时区（切换前/后）偏移不同时，同一本地时间可能再出现一次
+	var neighbours []time.Time
+	if !start.IsZero() {
+		neighbours = append(neighbours, start.Add(-time.Second))
+	}
+	if !end.IsZero() {
+		neighbours = append(neighbours, end)
+	}
+	for _, b := range neighbours {
+		_, o := b.Zone()
+		if alt := t.Add(time.Duration(off-o) * time.Second); o != off && sameWall(alt) {
+			out = append(out, alt)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// NextTimeLocal 严格晚于 now 的下一个槽开始时间（Location 时区）
+func (s slotSchedule) NextTimeLocal(now time.Time) time.Time {
+	local := now.In(s.Location)
+	y, m, d := local.Date()
+	for i := 0; i <= 1; i++ {
+		for _, t := range s.slotsOfDay(y, m, d+i) {
+			if t.After(now) {
+				return t
+			}
+		}
+	}
+	// Offset < Period <= 24h，次日必有槽，不会走到这里
+	return local.Add(s.Period)
+}
+
+// SlotStart now 所在槽的开始时间（不晚于 now 的最近一个槽）
+func (s slotSchedule) SlotStart(now time.Time) time.Time {
+	local := now.In(s.Location)
+	y, m, d := local.Date()
+	for i := 0; i >= -1; i-- {
+		slots := s.slotsOfDay(y, m, d+i)
+		for j := len(slots) - 1; j >= 0; j-- {
+			if !slots[j].After(now) {
+				return slots[j]
+			}
+		}
+	}
+	return local.Add(-s.Period)
+}
+
+// slotLoop 立即执行一次 run，之后每到下一个槽开始时执行；run 收到的是开始执行时的时间（槽开始时间或稍晚）。
+// 等待通过 clock.After 完成，ctx 取消后返回
+func slotLoop(ctx context.Context, clock util.Clock, s slotSchedule, run func(now time.Time)) {
+	for {
+		run(clock.Now())
+
+		// 计算下次执行时间（按 interval/offset/tz 对齐）
+		now := clock.Now()
+		next := s.NextTimeLocal(now)
+		wait := next.Sub(now)
+		if wait <= 0 {
+			wait = s.Period
+		}
+		log.Printf("扫描完成，下次执行时间: %s，等待 %v", next.Format(time.RFC3339), wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(wait):
+		}
+	}
+}
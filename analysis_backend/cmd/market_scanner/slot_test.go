@@ -0,0 +1,234 @@
+package main
+
+import (
+	"analysis/internal/util"
+	"context"
+	"testing"
+	"time"
+)
+
+func mustLoc(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("跳过：无法加载时区 %s: %v", name, err)
+	}
+	return loc
+}
+
+// TestSlotScheduleOffset 偏移 30m 的 2h 槽：跨日时落到次日 00:30
+func TestSlotScheduleOffset(t *testing.T) {
+	loc := mustLoc(t, "Asia/Taipei")
+	s, err := newSlotSchedule(2*time.Hour, 30*time.Minute, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		now, next, start time.Time
+	}{
+		{time.Date(2025, 3, 1, 0, 10, 0, 0, loc), time.Date(2025, 3, 1, 0, 30, 0, 0, loc), time.Date(2025, 2, 28, 22, 30, 0, 0, loc)},
+		{time.Date(2025, 3, 1, 0, 30, 0, 0, loc), time.Date(2025, 3, 1, 2, 30, 0, 0, loc), time.Date(2025, 3, 1, 0, 30, 0, 0, loc)},
+		{time.Date(2025, 3, 1, 13, 0, 0, 0, loc), time.Date(2025, 3, 1, 14, 30, 0, 0, loc), time.Date(2025, 3, 1, 12, 30, 0, 0, loc)},
+		{time.Date(2025, 3, 1, 23, 40, 0, 0, loc), time.Date(2025, 3, 2, 0, 30, 0, 0, loc), time.Date(2025, 3, 1, 22, 30, 0, 0, loc)},
+		// 月末跨日
+		{time.Date(2025, 3, 31, 22, 45, 0, 0, loc), time.Date(2025, 4, 1, 0, 30, 0, 0, loc), time.Date(2025, 3, 31, 22, 30, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		if got := s.NextTimeLocal(c.now); !got.Equal(c.next) {
+			t.Errorf("NextTimeLocal(%s) = %s，期望 %s", c.now, got, c.next)
+		}
+		if got := s.SlotStart(c.now); !got.Equal(c.start) {
+			t.Errorf("SlotStart(%s) = %s，期望 %s", c.now, got, c.start)
+		}
+	}
+
+	// 偏移按周期归一：2h30m 等价于 30m
+	if s2, _ := newSlotSchedule(2*time.Hour, 150*time.Minute, loc); s2.Offset != 30*time.Minute {
+		t.Errorf("偏移应归一为 30m，实际 %v", s2.Offset)
+	}
+	if _, err := newSlotSchedule(7*time.Hour, 0, loc); err == nil {
+		t.Error("不能整除 24h 的周期应报错")
+	}
+}
+
+// TestSlotScheduleOffsetAcrossDST 夏令时切换前后槽仍对齐到本地 HH:30
+func TestSlotScheduleOffsetAcrossDST(t *testing.T) {
+	loc := mustLoc(t, "America/New_York")
+	s, err := newSlotSchedule(2*time.Hour, 30*time.Minute, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		now, next time.Time
+	}{
+		// 2026-03-08 02:00 EST -> 03:00 EDT
+		{time.Date(2026, 3, 7, 23, 50, 0, 0, loc), time.Date(2026, 3, 8, 0, 30, 0, 0, loc)},
+		{time.Date(2026, 3, 8, 4, 0, 0, 0, loc), time.Date(2026, 3, 8, 4, 30, 0, 0, loc)},
+		{time.Date(2026, 3, 8, 23, 0, 0, 0, loc), time.Date(2026, 3, 9, 0, 30, 0, 0, loc)},
+		// 2026-11-01 02:00 EDT -> 01:00 EST
+		{time.Date(2026, 11, 1, 3, 0, 0, 0, loc), time.Date(2026, 11, 1, 4, 30, 0, 0, loc)},
+		{time.Date(2026, 11, 1, 23, 0, 0, 0, loc), time.Date(2026, 11, 2, 0, 30, 0, 0, loc)},
+	}
+	for _, c := range cases {
+		got := s.NextTimeLocal(c.now)
+		if !got.Equal(c.next) {
+			t.Errorf("NextTimeLocal(%s) = %s，期望 %s", c.now, got, c.next)
+		}
+		if got.In(loc).Minute() != 30 {
+			t.Errorf("槽 %s 未对齐到 :30", got.In(loc))
+		}
+	}
+}
+
+// TestSlotScheduleDSTNoDuplicateOrMissing 逐槽遍历夏令时切换日，槽严格递增且不遗漏任何本地槽时刻
+func TestSlotScheduleDSTNoDuplicateOrMissing(t *testing.T) {
+	loc := mustLoc(t, "America/New_York")
+	cases := []struct {
+		name           string
+		period, offset time.Duration
+		day            time.Time
+		wantCount      int
+		wantInclude    []time.Time
+	}{
+		// 前拨：02:00 不存在，映射到切换时刻 03:00 EDT，与 03:00 槽重合只保留一个
+		{"spring 1h", time.Hour, 0, time.Date(2026, 3, 8, 0, 0, 0, 0, loc), 23,
+			[]time.Time{time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC)}},
+		// 前拨：02:30 不存在，在切换时刻 03:00 EDT 补一个槽
+		{"spring 1h+30m", time.Hour, 30 * time.Minute, time.Date(2026, 3, 8, 0, 0, 0, 0, loc), 24,
+			[]time.Time{time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 7, 30, 0, 0, time.UTC)}},
+		{"spring 2h+30m", 2 * time.Hour, 30 * time.Minute, time.Date(2026, 3, 8, 0, 0, 0, 0, loc), 12,
+			[]time.Time{time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC), time.Date(2026, 3, 8, 8, 30, 0, 0, time.UTC)}},
+		// 回拨：01:00 / 01:30 重复出现，只在第一次（EDT）算槽
+		{"fall 1h", time.Hour, 0, time.Date(2026, 11, 1, 0, 0, 0, 0, loc), 24,
+			[]time.Time{time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC)}},
+		{"fall 1h+30m", time.Hour, 30 * time.Minute, time.Date(2026, 11, 1, 0, 0, 0, 0, loc), 24,
+			[]time.Time{time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), time.Date(2026, 11, 1, 7, 30, 0, 0, time.UTC)}},
+		{"fall 2h", 2 * time.Hour, 0, time.Date(2026, 11, 1, 0, 0, 0, 0, loc), 12,
+			[]time.Time{time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC)}},
+	}
+	for _, c := range cases {
+		s, err := newSlotSchedule(c.period, c.offset, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 从前一天最后一个槽开始，用 NextTimeLocal 逐槽推进到次日
+		end := c.day.AddDate(0, 0, 1)
+		cur := s.SlotStart(c.day.Add(-time.Nanosecond))
+		seen := map[int64]bool{}
+		seenWall := map[string]bool{}
+		count := 0
+		for {
+			next := s.NextTimeLocal(cur)
+			if !next.After(cur) {
+				t.Fatalf("%s: 槽未递增 %s -> %s", c.name, cur, next)
+			}
+			// 实际间隔不超过周期 + 1h 夏令时差
+			if gap := next.Sub(cur); gap > c.period+time.Hour {
+				t.Errorf("%s: %s -> %s 间隔 %v 过大", c.name, cur, next, gap)
+			}
+			if !next.Before(end) {
+				break
+			}
+			if seen[next.Unix()] {
+				t.Errorf("%s: 重复槽 %s", c.name, next)
+			}
+			seen[next.Unix()] = true
+			// 同一本地槽（如回拨时的 01:00 EDT / 01:00 EST）只能出现一次
+			if wall := next.In(loc).Format("2006-01-02 15:04"); seenWall[wall] {
+				t.Errorf("%s: 本地槽 %s 重复", c.name, wall)
+			} else {
+				seenWall[wall] = true
+			}
+			if got := s.SlotStart(next); !got.Equal(next) {
+				t.Errorf("%s: SlotStart(%s) = %s", c.name, next, got)
+			}
+			count++
+			cur = next
+		}
+		if count != c.wantCount {
+			t.Errorf("%s: 期望 %d 个槽，实际 %d", c.name, c.wantCount, count)
+		}
+		for _, w := range c.wantInclude {
+			if !seen[w.Unix()] {
+				t.Errorf("%s: 缺少槽 %s (%s)", c.name, w, w.In(loc))
+			}
+		}
+	}
+}
+
+// TestSlotLoopFakeClock 首次立即执行，之后只在推进时钟到达下一个槽时执行；ctx 取消后退出
+func TestSlotLoopFakeClock(t *testing.T) {
+	s, err := newSlotSchedule(time.Hour, 30*time.Minute, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := util.NewFakeClock(time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC))
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := make(chan time.Time, 4)
+	done := make(chan struct{})
+	go func() {
+		slotLoop(ctx, clk, s, func(now time.Time) { runs <- now })
+		close(done)
+	}()
+
+	waitRun := func(want time.Time) {
+		t.Helper()
+		select {
+		case got := <-runs:
+			if !got.Equal(want) {
+				t.Fatalf("期望 %v 执行，实际 %v", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("等待 %v 的执行超时", want)
+		}
+	}
+	waitIdle := func() {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for clk.Waiters() == 0 {
+			if time.Now().After(deadline) {
+				t.Fatal("slotLoop 未进入等待")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitRun(time.Date(2025, 3, 1, 10, 5, 0, 0, time.UTC))
+	waitIdle()
+	clk.Advance(24 * time.Minute) // 10:29，尚未到槽
+	select {
+	case got := <-runs:
+		t.Fatalf("未到槽不应执行，实际在 %v 执行", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+	clk.Advance(time.Minute)
+	waitRun(time.Date(2025, 3, 1, 10, 30, 0, 0, time.UTC))
+	waitIdle()
+	clk.Advance(time.Hour)
+	waitRun(time.Date(2025, 3, 1, 11, 30, 0, 0, time.UTC))
+	waitIdle()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ctx 取消后 slotLoop 未退出")
+	}
+}
+
+// TestSlotScheduleFallBackSingleLocalSlot 回拨当天落在重复时段内的时刻，SlotStart 仍归到第一次出现的本地槽
+func TestSlotScheduleFallBackSingleLocalSlot(t *testing.T) {
+	loc := mustLoc(t, "America/New_York")
+	s, err := newSlotSchedule(time.Hour, 0, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC)  // 01:00 EDT
+	second := time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC) // 01:00 EST
+	if got := s.SlotStart(second.Add(10 * time.Minute)); !got.Equal(first) {
+		t.Errorf("重复时段内应归到 01:00 EDT 槽，实际 %s", got)
+	}
+	if got := s.NextTimeLocal(first); !got.Equal(time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("01:00 EDT 之后的下一个槽应为 02:00 EST，实际 %s", got.In(loc))
+	}
+}
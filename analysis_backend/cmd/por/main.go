@@ -2,7 +2,6 @@ package main
 
 import (
 	"analysis/internal/addr"
-	"analysis/internal/chains"
 	"analysis/internal/collector"
 	"analysis/internal/config"
 	"analysis/internal/db"
@@ -15,6 +14,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,6 +29,8 @@ func main() {
 	zipBinance := flag.String("zip-binance", "wallet_address_20250901.zip", "Binance PoR zip file")
 	binanceEntity := flag.String("binance-entity", "binance", "entity name")
 	binanceIncludeDeposit := flag.Bool("binance-include-deposit", false, "include deposit addresses")
+	binancePORURL := flag.String("binance-por-url", "", "download Binance PoR zip from this URL instead of -zip-binance (cached by filename, resumes interrupted downloads)")
+	binancePORCache := flag.String("binance-por-cache", "data/por", "cache dir for downloaded Binance PoR zips")
 
 	// OKX PoR
 	okxPOR := flag.String("okx-por", "", "path to OKX PoR zip/csv (may contain multiple csv files)")
@@ -66,6 +68,10 @@ func main() {
 	config.MustLoad(*cfgPath, &cfg)
 	config.ApplyProxy(&cfg)
 	chainsCfg := config.BuildChainCfg(&cfg)
+	for ent, coins := range cfg.EntityCoinScopes() {
+		util.SetEntityAllowed(ent, coins)
+		log.Printf("[por] entity=%s only symbols=%s", ent, coins)
+	}
 
 	if cfg.Proxy.Enable {
 		log.Printf("[proxy] enabled: all=%s http=%s https=%s no=%s", cfg.Proxy.All, cfg.Proxy.HTTP, cfg.Proxy.HTTPS, cfg.Proxy.No)
@@ -85,15 +91,18 @@ func main() {
 			"BTC": "bitcoin", "ETH": "ethereum", "SOL": "solana", "USDT": "tether", "USDC": "usd-coin",
 		}
 	}
-	px, _ := price.FetchPrices(context.Background(), cfg, []string{"BTC", "ETH", "SOL", "USDT", "USDC"})
-	if bs, err := json.Marshal(px); err == nil {
-		log.Printf("[price] fetched: %s", string(bs))
-	}
 
 	// ---------- Address sources ----------
 	rows := addr.RowsFromConfig(cfg)
 	log.Printf("[addr] from config: %d rows", len(rows))
 
+	if *binancePORURL != "" {
+		p, err := addr.FetchBinancePORZip(context.Background(), *binancePORURL, *binancePORCache, nil)
+		if err != nil {
+			panic(err)
+		}
+		*zipBinance = p
+	}
 	if *zipBinance != "" {
 		rs, err := addr.RowsFromBinancePORZip(*zipBinance, *binanceEntity, *binanceIncludeDeposit)
 		if err != nil {
@@ -113,19 +122,27 @@ func main() {
 	}
 
 	// ---------- Group by entity ----------
-	group := map[string][]models.AddressRow{}
-	for _, r := range rows {
-		ent := r.Entity
-		if ent == "" {
-			ent = "unknown"
-		}
-		group[ent] = append(group[ent], r)
-	}
+	group := collector.GroupByEntity(rows)
 	log.Printf("[addr] grouped entities: %d", len(group))
 	for k, v := range group {
 		log.Printf("[addr] entity=%s addrs=%d", k, len(v))
 	}
 
+	// ---------- Prices: 预扫描各实体实际会出现的币种，一次批量取价 ----------
+	priceSet, err := collector.PrefetchPrices(context.Background(), func(ctx context.Context, syms []string) (map[string]float64, error) {
+		return price.FetchPrices(ctx, cfg, syms)
+	}, group, chainsCfg)
+	if err != nil {
+		log.Printf("[price] fetch failed: %v", err)
+	}
+	px := priceSet.Prices
+	if bs, err := json.Marshal(px); err == nil {
+		log.Printf("[price] fetched: %s", string(bs))
+	}
+	if len(priceSet.Unpriced) > 0 {
+		log.Printf("[price] unpriced (value_usd=0, add to pricing.map): %s", strings.Join(priceSet.Unpriced, ","))
+	}
+
 	// ---------- Time windows ----------
 	weeklyEnd := time.Now().UTC()
 	weeklyStart := weeklyEnd.AddDate(0, 0, -7*(*weeks))
@@ -161,6 +178,7 @@ func main() {
 
 	// ---------- DB ----------
 	gdb, err := db.OpenMySQL(db.Options{
+		Driver:       cfg.Database.Driver,
 		DSN:          cfg.Database.DSN,
 		Automigrate:  cfg.Database.Automigrate,
 		MaxOpenConns: cfg.Database.MaxOpenConns,
@@ -187,116 +205,52 @@ func main() {
 	for ent, rs := range group {
 		log.Printf("processing entity=%s addrs=%d ...", ent, len(rs))
 
+		// 同一实体的余额/周度/日度结果一次事务写入，失败整体回滚，不留半截数据
+		var (
+			portfolios []models.Portfolio
+			weekly     []models.WeeklyResult
+			daily      []models.DailyResult
+		)
+
 		// 1) Portfolio snapshot
 		if p, err := collector.ComputePortfolio(context.Background(), ent, rs, chainsCfg, px); err != nil {
 			log.Printf("compute portfolio %s: %v", ent, err)
 		} else {
-			if err := db.SaveAll(gdb, runID, asOf, []models.Portfolio{p}, nil, nil); err != nil {
-				log.Printf("     (portfolio, entity=%s) error: %v", ent, err)
-			} else {
-				log.Printf("✔ flushed portfolio entity=%s", ent)
-				sum.Portfolios = append(sum.Portfolios, p)
+			if miss := collector.UnpricedHoldings(p, px); len(miss) > 0 {
+				log.Printf("[price] entity=%s unpriced holdings: %s", ent, strings.Join(miss, ","))
 			}
+			portfolios = append(portfolios, p)
 		}
 
 		// 2) Weekly flows  —— 注意：WeeklyBucket 是 map，值传递
 		if *withWeekly {
 			wb := models.WeeklyBucket{}
-			for i, r := range rs {
-				fmt.Printf("b%v", i)
-				switch r.Chain {
-				case "bitcoin":
-					if util.IsAllowed("BTC") && chainsCfg["bitcoin"].Esplora != "" {
-						_ = chains.BTCFlows(context.Background(), chainsCfg["bitcoin"].Esplora, r.Address, weeklyStart, weeklyEnd, wb, nil)
-					}
-				case "solana":
-					if util.IsAllowed("SOL") && chainsCfg["solana"].RPC != "" {
-						_ = chains.SolFlowsSOL(context.Background(), chainsCfg["solana"].RPC, r.Address, weeklyStart, weeklyEnd, wb, nil)
-					}
-					for _, t := range chainsCfg["solana"].SPL {
-						if util.IsAllowed(t.Symbol) {
-							_ = chains.SolFlowsSPL(context.Background(), chainsCfg["solana"].RPC, r.Address, t.Mint, t.Symbol, weeklyStart, weeklyEnd, wb, nil)
-						}
-					}
-				case "tron":
-					for _, t := range chainsCfg["tron"].TRC20 {
-						if util.IsAllowed(t.Symbol) {
-							_ = chains.TronTRC20Flows(context.Background(), r.Address, t.Contract, weeklyStart, weeklyEnd, t.Symbol, wb, nil)
-						}
-					}
-				default: // EVM-like
-					cc := chainsCfg[r.Chain]
-					owner := r.EVM()
-					for _, tok := range cc.ERC20 {
-						if util.IsAllowed(tok.Symbol) && (tok.Symbol == "USDT" || tok.Symbol == "USDC") {
-							_ = chains.EVMERC20Flows(context.Background(), cc.RPC, tok, owner, weeklyStart, weeklyEnd, wb, nil)
-						}
-					}
-					if r.Chain == "ethereum" && *etherscanKey != "" && util.IsAllowed("ETH") {
-						_ = chains.ETHNativeFlowsEtherscan(context.Background(), *etherscanKey, cc.RPC, r.Address, weeklyStart, weeklyEnd, wb, nil)
-					}
-				}
-			}
-
+			collector.CollectFlows(context.Background(), ent, rs, chainsCfg, weeklyStart, weeklyEnd, wb, nil, *etherscanKey)
 			if len(wb) > 0 {
-				wres := models.WeeklyResult{Entity: ent, Data: wb}
-				if err := db.SaveAll(gdb, runID, asOf, nil, []models.WeeklyResult{wres}, nil); err != nil {
-					log.Printf("     (weekly, entity=%s) error: %v", ent, err)
-				} else {
-					log.Printf("✔ flushed weekly entity=%s", ent)
-					sum.WeeklyResults = append(sum.WeeklyResults, wres)
-				}
+				weekly = append(weekly, models.WeeklyResult{Entity: ent, Data: wb})
 			}
 		}
 
 		// 3) Daily flows —— 注意：DailyBucket 是 map，值传递
 		if *withDaily {
 			dbkt := models.DailyBucket{}
-			for i, r := range rs {
-				fmt.Printf("c%v", i)
-				switch r.Chain {
-				case "bitcoin":
-					if util.IsAllowed("BTC") && chainsCfg["bitcoin"].Esplora != "" {
-						_ = chains.BTCFlows(context.Background(), chainsCfg["bitcoin"].Esplora, r.Address, dailyStart, dailyEnd, nil, dbkt)
-					}
-				case "solana":
-					if util.IsAllowed("SOL") && chainsCfg["solana"].RPC != "" {
-						_ = chains.SolFlowsSOL(context.Background(), chainsCfg["solana"].RPC, r.Address, dailyStart, dailyEnd, nil, dbkt)
-					}
-					for _, t := range chainsCfg["solana"].SPL {
-						if util.IsAllowed(t.Symbol) {
-							_ = chains.SolFlowsSPL(context.Background(), chainsCfg["solana"].RPC, r.Address, t.Mint, t.Symbol, dailyStart, dailyEnd, nil, dbkt)
-						}
-					}
-				case "tron":
-					for _, t := range chainsCfg["tron"].TRC20 {
-						if util.IsAllowed(t.Symbol) {
-							_ = chains.TronTRC20Flows(context.Background(), r.Address, t.Contract, dailyStart, dailyEnd, t.Symbol, nil, dbkt)
-						}
-					}
-				default: // EVM-like
-					cc := chainsCfg[r.Chain]
-					owner := r.EVM()
-					for _, tok := range cc.ERC20 {
-						if util.IsAllowed(tok.Symbol) && (tok.Symbol == "USDT" || tok.Symbol == "USDC") {
-							_ = chains.EVMERC20Flows(context.Background(), cc.RPC, tok, owner, dailyStart, dailyEnd, nil, dbkt)
-						}
-					}
-					if r.Chain == "ethereum" && *etherscanKey != "" && util.IsAllowed("ETH") {
-						_ = chains.ETHNativeFlowsEtherscan(context.Background(), *etherscanKey, cc.RPC, r.Address, dailyStart, dailyEnd, nil, dbkt)
-					}
-				}
-			}
+			collector.CollectFlows(context.Background(), ent, rs, chainsCfg, dailyStart, dailyEnd, nil, dbkt, *etherscanKey)
 			if len(dbkt) > 0 {
-				dres := models.DailyResult{Entity: ent, Data: dbkt}
-				if err := db.SaveAll(gdb, runID, asOf, nil, nil, []models.DailyResult{dres}); err != nil {
-					log.Printf("     (daily, entity=%s) error: %v", ent, err)
-				} else {
-					log.Printf("✔ flushed daily entity=%s", ent)
-					sum.DailyResults = append(sum.DailyResults, dres)
-				}
+				daily = append(daily, models.DailyResult{Entity: ent, Data: dbkt})
 			}
 		}
+
+		if len(portfolios)+len(weekly)+len(daily) == 0 {
+			continue
+		}
+		if err := db.SaveAll(gdb.GormDB(), runID, asOf, portfolios, weekly, daily); err != nil {
+			log.Printf("     (entity=%s) rolled back: %v", ent, err)
+			continue
+		}
+		log.Printf("✔ flushed entity=%s portfolio=%d weekly=%d daily=%d", ent, len(portfolios), len(weekly), len(daily))
+		sum.Portfolios = append(sum.Portfolios, portfolios...)
+		sum.WeeklyResults = append(sum.WeeklyResults, weekly...)
+		sum.DailyResults = append(sum.DailyResults, daily...)
 	}
 
 	// ---------- Print summary ----------
@@ -305,6 +259,6 @@ func main() {
 	log.Printf("[por] finished compute. duration=%s", time.Since(startTs))
 
 	abs, _ := filepath.Abs(*cfgPath)
-	fmt.Println("✔ 增量写入完成（余额/周度/日度分开保存），run_id =", runID)
+	fmt.Println("✔ 增量写入完成（按实体事务写入余额/周度/日度），run_id =", runID)
 	fmt.Println("✔ 使用配置：", abs)
 }
@@ -14,6 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"analysis/internal/netutil"
+	"analysis/internal/util"
 )
 
 // =============================
@@ -30,6 +31,7 @@ type RecommendationScanner struct {
 	nextRun    *time.Time
 	totalRuns  int64
 	httpServer *http.Server
+	output     *recommendationOutput // 可选：每次运行的结果落盘
 }
 
 // NewRecommendationScanner 创建推荐扫描器
@@ -52,6 +54,10 @@ func main() {
 	limit := flag.Int("limit", 5, "推荐数量限制")
 	forceRefresh := flag.Bool("force-refresh", false, "强制刷新推荐（忽略缓存）")
 	port := flag.String("port", "8011", "HTTP服务器端口（仅server模式）")
+	outputDir := flag.String("output", "", "每次运行把完整推荐列表写入该目录（带时间戳的文件，为空不写）")
+	outputFormat := flag.String("output-format", "json", "输出文件格式: json, csv")
+	outputMaxAge := flag.Duration("output-max-age", 0, "结果文件最长保留时间，超过的在每次写入后删除（0 不限）")
+	outputMaxBytes := flag.Int64("output-max-bytes", 0, "结果目录中结果文件的总大小上限（字节），超出时从最旧的开始删除（0 不限）")
 
 	flag.Parse()
 
@@ -62,9 +68,18 @@ func main() {
 	var cfg config.Config
 	config.MustLoad(*configPath, &cfg)
 	config.ApplyProxy(&cfg)
+	if maxLimit := cfg.RecommendationMaxLimit(); *limit > maxLimit {
+		log.Printf("[recommendation_scanner] limit=%d 超过上限 %d，按上限生成", *limit, maxLimit)
+		*limit = maxLimit
+	}
 
 	// 创建扫描器
 	scanner := NewRecommendationScanner(*apiBase, &cfg, *generationMode)
+	output, err := newRecommendationOutput(*outputDir, *outputFormat, util.RetentionPolicy{MaxAge: *outputMaxAge, MaxBytes: *outputMaxBytes})
+	if err != nil {
+		log.Fatalf("[recommendation_scanner] %v", err)
+	}
+	scanner.output = output
 
 	// 启动HTTP控制服务器
 	go scanner.startHTTPServer(*port)
@@ -187,6 +202,7 @@ func (rs *RecommendationScanner) warmupRecommendations(ctx context.Context, kind
 	// 解析响应
 	if recommendations, ok := resp["recommendations"].([]interface{}); ok {
 		log.Printf("[recommendation_scanner] 预热成功，缓存推荐数量: %d", len(recommendations))
+		rs.writeOutput(kind, recommendations)
 
 		// 输出预热结果
 		for i, rec := range recommendations {
@@ -248,6 +264,7 @@ func (rs *RecommendationScanner) generateHistoricalRecommendations(ctx context.C
 		if data, ok := resp["data"].(map[string]interface{}); ok {
 			if recommendations, ok := data["recommendations"].([]interface{}); ok {
 				log.Printf("[recommendation_scanner] 生成推荐数量: %d", len(recommendations))
+				rs.writeOutput(kind, recommendations)
 
 				// 简单输出前几个推荐
 				for i, rec := range recommendations {
@@ -275,6 +292,26 @@ func (rs *RecommendationScanner) generateHistoricalRecommendations(ctx context.C
 	return nil
 }
 
+// writeOutput 结果落盘；失败只记录日志，不影响本次运行
+func (rs *RecommendationScanner) writeOutput(kind string, recommendations []interface{}) {
+	if rs.output == nil {
+		return
+	}
+	path, err := rs.output.Write(kind, recommendations)
+	if err != nil {
+		log.Printf("[recommendation_scanner] 写入结果文件失败: %v", err)
+		return
+	}
+	log.Printf("[recommendation_scanner] 结果已写入: %s", path)
+	removed, err := rs.output.Prune()
+	if err != nil {
+		log.Printf("[recommendation_scanner] 清理旧结果文件失败: %v", err)
+	}
+	if len(removed) > 0 {
+		log.Printf("[recommendation_scanner] 已清理 %d 个旧结果文件", len(removed))
+	}
+}
+
 // makeAPIRequest 发送API请求的辅助方法
 func (rs *RecommendationScanner) makeAPIRequest(ctx context.Context, method, url string, body interface{}) (map[string]interface{}, error) {
 	log.Printf("[recommendation_scanner] 发送%s请求到: %s", method, url)
@@ -430,10 +467,11 @@ func (rs *RecommendationScanner) handleGenerate(c *gin.Context) {
 	genMode := c.DefaultQuery("gen_mode", rs.mode) // 支持动态指定生成模式
 
 	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 || limit > 50 {
+	maxLimit := rs.config.RecommendationMaxLimit()
+	if err != nil || limit <= 0 || limit > maxLimit {
 		c.JSON(400, gin.H{
 			"status":  "error",
-			"message": "无效的limit参数",
+			"message": fmt.Sprintf("无效的limit参数（1-%d）", maxLimit),
 		})
 		return
 	}
@@ -0,0 +1,139 @@
+// cmd/recommendation_scanner/output.go
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"analysis/internal/util"
+)
+
+// =============================
+//         结果落盘
+// =============================
+
+// recommendationOutput 每次运行把完整推荐列表写入带时间戳的本地文件，便于离线复核；
+// 设置了保留策略时每次写入后清理目录中过期 / 超出总大小的旧结果文件
+type recommendationOutput struct {
+	dir       string
+	format    string // json / csv
+	retention util.RetentionPolicy
+	now       func() time.Time
+}
+
+// recommendationFilePattern 结果文件名（各类型、各格式），清理时只匹配这些文件
+const recommendationFilePattern = "recommendations_*"
+
+// recommendationCSVColumns CSV 列（得分拆解 + 价格 + 理由）
+var recommendationCSVColumns = []string{
+	"rank", "symbol", "total_score", "market_score", "flow_score", "heat_score",
+	"event_score", "sentiment_score", "current_price", "reasons", "generated_at",
+}
+
+// newRecommendationOutput dir 为空时返回 nil（不落盘）
+func newRecommendationOutput(dir, format string, retention util.RetentionPolicy) (*recommendationOutput, error) {
+	if strings.TrimSpace(dir) == "" {
+		return nil, nil
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		return nil, fmt.Errorf("不支持的输出格式: %s（可选 json/csv）", format)
+	}
+	return &recommendationOutput{dir: dir, format: format, retention: retention, now: time.Now}, nil
+}
+
+// Write 写入一次运行的推荐列表，返回文件路径
+func (o *recommendationOutput) Write(kind string, recommendations []interface{}) (string, error) {
+	if err := os.MkdirAll(o.dir, 0o755); err != nil {
+		return "", err
+	}
+	ts := o.now().UTC()
+	path := filepath.Join(o.dir, fmt.Sprintf("recommendations_%s_%s.%s", kind, ts.Format("20060102T150405Z"), o.format))
+
+	// 先写临时文件再改名，避免读到半个文件
+	tmp, err := os.CreateTemp(o.dir, ".recommendations-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	switch o.format {
+	case "csv":
+		err = writeRecommendationsCSV(tmp, recommendations)
+	default:
+		enc := json.NewEncoder(tmp)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(map[string]interface{}{
+			"kind":            kind,
+			"generated_at":    ts,
+			"count":           len(recommendations),
+			"recommendations": recommendations,
+		})
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Prune 按保留策略清理旧结果文件（最旧的先删），返回删除的路径
+func (o *recommendationOutput) Prune() ([]string, error) {
+	return util.PruneFiles(o.dir, recommendationFilePattern, o.retention, o.now())
+}
+
+func writeRecommendationsCSV(f *os.File, recommendations []interface{}) error {
+	w := csv.NewWriter(f)
+	if err := w.Write(recommendationCSVColumns); err != nil {
+		return err
+	}
+	for _, rec := range recommendations {
+		recMap, ok := rec.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		row := make([]string, len(recommendationCSVColumns))
+		for i, col := range recommendationCSVColumns {
+			row[i] = csvCell(recMap[col])
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// csvCell 数字按最短形式输出，理由列表用 "; " 连接
+func csvCell(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(x))
+		for _, it := range x {
+			parts = append(parts, csvCell(it))
+		}
+		return strings.Join(parts, "; ")
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"analysis/internal/util"
+)
+
+func stubGenerateServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recommendations/generate" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":true,"message":"ok","data":{"recommendations":[
+			{"symbol":"BTC","rank":1,"total_score":87.5,"market_score":30,"flow_score":20,"heat_score":15,"event_score":12.5,"sentiment_score":10,"current_price":65000.12,"reasons":["放量突破","资金净流入"]},
+			{"symbol":"ETH","rank":2,"total_score":80,"market_score":28,"flow_score":18,"heat_score":14,"event_score":10,"sentiment_score":10,"current_price":3200,"reasons":[]}
+		]}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestScanner(t *testing.T, apiBase, format string) (*RecommendationScanner, string) {
+	t.Helper()
+	dir := t.TempDir()
+	out, err := newRecommendationOutput(dir, format, util.RetentionPolicy{})
+	if err != nil {
+		t.Fatalf("newRecommendationOutput: %v", err)
+	}
+	out.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	rs := NewRecommendationScanner(apiBase, nil, "historical")
+	rs.output = out
+	return rs, dir
+}
+
+func TestGenerateWritesJSONOutput(t *testing.T) {
+	srv := stubGenerateServer(t)
+	rs, dir := newTestScanner(t, srv.URL, "json")
+
+	if err := rs.generateHistoricalRecommendations(context.Background(), "spot", 5, false); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(dir, "recommendations_spot_20260102T030405Z.json"))
+	if err != nil {
+		t.Fatalf("output file not written: %v", err)
+	}
+	var got struct {
+		Kind            string                   `json:"kind"`
+		Count           int                      `json:"count"`
+		Recommendations []map[string]interface{} `json:"recommendations"`
+	}
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Kind != "spot" || got.Count != 2 || len(got.Recommendations) != 2 {
+		t.Fatalf("unexpected output: %+v", got)
+	}
+	if got.Recommendations[0]["symbol"] != "BTC" || got.Recommendations[0]["flow_score"] != float64(20) {
+		t.Fatalf("factor breakdown missing: %+v", got.Recommendations[0])
+	}
+}
+
+func TestGenerateWritesCSVOutput(t *testing.T) {
+	srv := stubGenerateServer(t)
+	rs, dir := newTestScanner(t, srv.URL, "csv")
+
+	if err := rs.generateHistoricalRecommendations(context.Background(), "futures", 5, false); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "recommendations_futures_20260102T030405Z.csv"))
+	if err != nil {
+		t.Fatalf("output file not written: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("want header + 2 rows, got %d", len(rows))
+	}
+	want := []string{"1", "BTC", "87.5", "30", "20", "15", "12.5", "10", "65000.12", "放量突破; 资金净流入", ""}
+	for i, v := range want {
+		if rows[1][i] != v {
+			t.Fatalf("col %s: want %q, got %q", rows[0][i], v, rows[1][i])
+		}
+	}
+}
+
+func TestNewRecommendationOutput(t *testing.T) {
+	if out, err := newRecommendationOutput("", "json", util.RetentionPolicy{}); out != nil || err != nil {
+		t.Fatalf("empty dir should disable output, got %v %v", out, err)
+	}
+	if _, err := newRecommendationOutput(t.TempDir(), "xml", util.RetentionPolicy{}); err == nil {
+		t.Fatal("unsupported format should fail")
+	}
+}
+
+// TestOutputRetentionPrunesOldest 每次写入后按总大小清理最旧的结果文件，目录中的其它文件不动
+func TestOutputRetentionPrunesOldest(t *testing.T) {
+	srv := stubGenerateServer(t)
+	rs, dir := newTestScanner(t, srv.URL, "json")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		rs.output.now = func() time.Time { return at.Add(time.Duration(i) * time.Hour) }
+		if err := rs.generateHistoricalRecommendations(context.Background(), "spot", 5, false); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if i == 0 {
+			st, err := os.Stat(filepath.Join(dir, "recommendations_spot_20260102T030405Z.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rs.output.retention = util.RetentionPolicy{MaxBytes: 2 * st.Size()}
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	sort.Strings(files)
+	want := []string{"notes.txt", "recommendations_spot_20260102T040405Z.json", "recommendations_spot_20260102T050405Z.json"}
+	if len(files) != len(want) {
+		t.Fatalf("want %v, got %v", want, files)
+	}
+	for i, f := range files {
+		if filepath.Base(f) != want[i] {
+			t.Errorf("want %v, got %v", want, files)
+			break
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// cmd/scanner/btc.go
+// BTC 交易解析：把 Esplora 交易拆成监控地址的入/出事件。
+// 没有标准地址的输出（OP_RETURN、裸多签、P2PK 等）用 "<类型>:<脚本hex>" 标记，避免对手方被误判为找零地址。
+
+package main
+
+import (
+	"analysis/internal/models"
+	"strings"
+	"time"
+)
+
+// Esplora scriptpubkey_type 中没有标准地址的输出类型
+const (
+	btcScriptOpReturn = "op_return"
+	btcScriptMultisig = "multisig"
+)
+
+// btcOutputLabel 输出的对手方标识：有地址用地址，否则用 "<类型>:<脚本hex>"
+func btcOutputLabel(v *btcVout) string {
+	if v == nil {
+		return ""
+	}
+	if a := strings.TrimSpace(v.ScriptPubKeyAddress); a != "" {
+		return a
+	}
+	typ := strings.ToLower(strings.TrimSpace(v.ScriptPubKeyType))
+	if typ == "" {
+		typ = "nonstandard"
+	}
+	if v.ScriptPubKey == "" {
+		return typ
+	}
+	return typ + ":" + strings.ToLower(v.ScriptPubKey)
+}
+
+// isBTCDataOutput OP_RETURN 数据输出（不可花费，不是资金去向）
+func isBTCDataOutput(v btcVout) bool {
+	return strings.EqualFold(v.ScriptPubKeyType, btcScriptOpReturn) ||
+		strings.HasPrefix(strings.ToLower(v.ScriptPubKey), "6a")
+}
+
+// firstVoutAddr 第一个承载金额的输出；跳过 OP_RETURN 与零值输出，多签等无地址输出返回其标记
+func firstVoutAddr(vouts []btcVout) string {
+	for i := range vouts {
+		if vouts[i].Value <= 0 || isBTCDataOutput(vouts[i]) {
+			continue
+		}
+		if a := btcOutputLabel(&vouts[i]); a != "" {
+			return a
+		}
+	}
+	return ""
+}
+
+// firstVinAddr 第一个输入花费的输出标识（coinbase 等无 prevout 的输入跳过）
+func firstVinAddr(vins []btcVin) string {
+	for _, vin := range vins {
+		if vin.Prevout == nil {
+			continue
+		}
+		if a := btcOutputLabel(vin.Prevout); a != "" {
+			return a
+		}
+	}
+	return ""
+}
+
+// btcTxEvents 交易中命中监控地址的入/出事件；LogIndex 出为 -(vin序号+1)，入为 vout 序号
+func btcTxEvents(entity string, tx btcTx, watched func(addr string) bool) []models.Event {
+	var events []models.Event
+	ts := time.Unix(tx.Status.BlockTime, 0).UTC()
+	for i, vin := range tx.Vin {
+		if vin.Prevout == nil {
+			continue
+		}
+		addr := strings.TrimSpace(vin.Prevout.ScriptPubKeyAddress)
+		if addr == "" || vin.Prevout.Value <= 0 || !watched(addr) {
+			continue
+		}
+		events = append(events, models.Event{
+			Entity: entity, Chain: "bitcoin", Coin: "BTC", AssetID: models.AssetIDFor("bitcoin", ""), Direction: "out", Amount: satsToDecimal(vin.Prevout.Value),
+			TS: ts, TxID: tx.Txid, From: addr, To: firstVoutAddr(tx.Vout), Address: addr, LogIndex: -(i + 1),
+		})
+	}
+	for i, vout := range tx.Vout {
+		addr := strings.TrimSpace(vout.ScriptPubKeyAddress)
+		if addr == "" || vout.Value <= 0 || !watched(addr) {
+			continue
+		}
+		events = append(events, models.Event{
+			Entity: entity, Chain: "bitcoin", Coin: "BTC", AssetID: models.AssetIDFor("bitcoin", ""), Direction: "in", Amount: satsToDecimal(vout.Value),
+			TS: ts, TxID: tx.Txid, From: firstVinAddr(tx.Vin), To: addr, Address: addr, LogIndex: i,
+		})
+	}
+	return events
+}
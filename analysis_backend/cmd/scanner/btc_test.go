@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// Esplora 返回的交易：输出依次为 OP_RETURN、裸 1-of-2 多签、找零到监控地址
+const btcOpReturnMultisigTx = `{
+  "txid": "f00d",
+  "status": {"block_time": 1725148800},
+  "vin": [
+    {"prevout": {"value": 150000, "scriptpubkey": "0014aa", "scriptpubkey_type": "v0_p2wpkh", "scriptpubkey_address": "bc1qwatched"}},
+    {"prevout": {"value": 70000, "scriptpubkey": "512102aa2103bb52ae", "scriptpubkey_type": "multisig"}}
+  ],
+  "vout": [
+    {"value": 0, "scriptpubkey": "6a0b68656c6c6f20776f726c64", "scriptpubkey_type": "op_return"},
+    {"value": 100000, "scriptpubkey": "5121021111210322225221ae", "scriptpubkey_type": "multisig"},
+    {"value": 115000, "scriptpubkey": "0014aa", "scriptpubkey_type": "v0_p2wpkh", "scriptpubkey_address": "bc1qwatched"}
+  ]
+}`
+
+// TestBTCTxEventsOpReturnAndMultisig 对手方不应被 OP_RETURN 或找零地址占据：转出的去向是多签输出，转入的来源按第一个输入记录
+func TestBTCTxEventsOpReturnAndMultisig(t *testing.T) {
+	var tx btcTx
+	if err := json.Unmarshal([]byte(btcOpReturnMultisigTx), &tx); err != nil {
+		t.Fatal(err)
+	}
+	events := btcTxEvents("binance", tx, func(a string) bool { return a == "bc1qwatched" })
+	if len(events) != 2 {
+		t.Fatalf("期望 1 笔转出 + 1 笔找零转入，实际 %d: %+v", len(events), events)
+	}
+
+	out := events[0]
+	if out.Direction != "out" || out.Amount != "0.00150000" || out.LogIndex != -1 {
+		t.Errorf("转出事件不符: %+v", out)
+	}
+	if out.To != "multisig:5121021111210322225221ae" {
+		t.Errorf("转出对手方应为多签输出，实际 %q", out.To)
+	}
+
+	in := events[1]
+	if in.Direction != "in" || in.Amount != "0.00115000" || in.LogIndex != 2 || in.From != "bc1qwatched" {
+		t.Errorf("转入事件不符: %+v", in)
+	}
+}
+
+// TestBTCOutputLabels 无地址输出按类型区分标记；只有 OP_RETURN 与多签输出时去向取多签
+func TestBTCOutputLabels(t *testing.T) {
+	vouts := []btcVout{
+		{Value: 0, ScriptPubKey: "6a04deadbeef", ScriptPubKeyType: "op_return"},
+		{Value: 546, ScriptPubKey: "4104AB", ScriptPubKeyType: "p2pk"},
+	}
+	if got := btcOutputLabel(&vouts[0]); got != "op_return:6a04deadbeef" {
+		t.Errorf("OP_RETURN 标记不符: %q", got)
+	}
+	if got := firstVoutAddr(vouts); got != "p2pk:4104ab" {
+		t.Errorf("应跳过 OP_RETURN 取 P2PK 输出，实际 %q", got)
+	}
+	if got := firstVoutAddr(vouts[:1]); got != "" {
+		t.Errorf("只有数据输出时没有资金去向，实际 %q", got)
+	}
+
+	vins := []btcVin{{}, {Prevout: &btcVout{Value: 70000, ScriptPubKey: "5121", ScriptPubKeyType: "multisig"}}}
+	if got := firstVinAddr(vins); got != "multisig:5121" {
+		t.Errorf("花费多签输出的来源标记不符: %q", got)
+	}
+}
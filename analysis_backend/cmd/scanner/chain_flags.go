@@ -0,0 +1,105 @@
+// cmd/scanner/chain_flags.go
+// 链开关：扫描循环每轮开始前检查一个 JSON 文件，运行中即可停用/恢复某条链，无需重启。
+// 文件格式：{"bsc": false, "solana": true}；未列出的链默认启用，文件不存在时全部启用。
+// -exclude-chains 仍在启动时生效（被排除的链不会加载地址，无法在运行中开启）。
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// chainFlags 文件驱动的链开关
+type chainFlags struct {
+	path     string
+	interval time.Duration // 检查文件的最小间隔
+
+	lastCheck time.Time
+	modTime   time.Time
+	disabled  map[string]bool
+}
+
+func newChainFlags(path string, interval time.Duration) *chainFlags {
+	return &chainFlags{path: strings.TrimSpace(path), interval: interval, disabled: map[string]bool{}}
+}
+
+// canonicalChain 链名归一（btc -> bitcoin，sol -> solana）
+func canonicalChain(ch string) string {
+	switch ch = strings.ToLower(strings.TrimSpace(ch)); ch {
+	case "btc":
+		return "bitcoin"
+	case "sol":
+		return "solana"
+	}
+	return ch
+}
+
+// Enabled 链当前是否启用
+func (f *chainFlags) Enabled(chain string) bool {
+	return !f.disabled[canonicalChain(chain)]
+}
+
+// Refresh 距上次检查超过 interval 且文件有变化时重新加载；解析失败保留上一次的开关
+func (f *chainFlags) Refresh(now time.Time) {
+	if f.path == "" || (!f.lastCheck.IsZero() && now.Sub(f.lastCheck) < f.interval) {
+		return
+	}
+	f.lastCheck = now
+
+	st, err := os.Stat(f.path)
+	if err != nil {
+		if os.IsNotExist(err) && len(f.disabled) > 0 {
+			log.Printf("[chain-flags] %s removed, all chains enabled", f.path)
+			f.disabled = map[string]bool{}
+			f.modTime = time.Time{}
+		}
+		return
+	}
+	if st.ModTime().Equal(f.modTime) {
+		return
+	}
+	disabled, err := loadChainFlags(f.path)
+	if err != nil {
+		log.Printf("[chain-flags] keep previous flags: %v", err)
+		return
+	}
+	f.modTime = st.ModTime()
+	if fmt.Sprint(disabled) != fmt.Sprint(f.disabled) {
+		log.Printf("[chain-flags] disabled chains: %v", sortedKeys(disabled))
+	}
+	f.disabled = disabled
+}
+
+// loadChainFlags 读取开关文件，返回被停用的链
+func loadChainFlags(path string) (map[string]bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]bool
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	disabled := map[string]bool{}
+	for ch, on := range raw {
+		if !on {
+			disabled[canonicalChain(ch)] = true
+		}
+	}
+	return disabled, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
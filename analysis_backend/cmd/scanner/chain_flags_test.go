@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeChainFlags(t *testing.T, path, body string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestChainFlagsToggleAtRuntime 修改开关文件后，下一轮即跳过/恢复对应链
+func TestChainFlagsToggleAtRuntime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chains.json")
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	f := newChainFlags(path, 10*time.Second)
+
+	// 文件不存在：全部启用
+	f.Refresh(now)
+	if !f.Enabled("ethereum") || !f.Enabled("solana") {
+		t.Fatal("无开关文件时应全部启用")
+	}
+
+	writeChainFlags(t, path, `{"sol": false, "ethereum": true}`, now)
+	f.Refresh(now.Add(5 * time.Second))
+	if !f.Enabled("solana") {
+		t.Fatal("未到检查间隔不应重新加载")
+	}
+	f.Refresh(now.Add(10 * time.Second))
+	if f.Enabled("solana") || f.Enabled("SOL") {
+		t.Fatal("solana 应被停用（sol 为别名）")
+	}
+	if !f.Enabled("ethereum") || !f.Enabled("bsc") {
+		t.Fatal("其它链应保持启用")
+	}
+
+	// 解析失败保留上一次开关
+	writeChainFlags(t, path, `{not json`, now.Add(time.Minute))
+	f.Refresh(now.Add(20 * time.Second))
+	if f.Enabled("solana") {
+		t.Fatal("开关文件损坏时应保留之前的状态")
+	}
+
+	writeChainFlags(t, path, `{"solana": true}`, now.Add(2*time.Minute))
+	f.Refresh(now.Add(30 * time.Second))
+	if !f.Enabled("solana") {
+		t.Fatal("solana 应恢复扫描")
+	}
+
+	writeChainFlags(t, path, `{"bitcoin": false}`, now.Add(3*time.Minute))
+	f.Refresh(now.Add(40 * time.Second))
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if f.Enabled("btc") {
+		t.Fatal("bitcoin 应被停用")
+	}
+	f.Refresh(now.Add(50 * time.Second))
+	if !f.Enabled("btc") {
+		t.Fatal("删除开关文件后应全部恢复")
+	}
+}
@@ -0,0 +1,113 @@
+// cmd/scanner/chain_poll.go
+// 按链轮询间隔：各链出块速度差别很大（BTC ~10 分钟、Solana ~400ms、EVM 各不相同），统一用 -poll 会让慢链白白请求 RPC。
+// 每条链追平最新高度后按自己的间隔等待再扫；还有积压（本轮有进展）的链下一轮立即继续。
+// 间隔优先级：-poll-chains 覆盖 > 内置默认 > -poll。
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// builtinChainPoll 常见链的默认轮询间隔（约等于出块时间，Solana 一轮扫多个 slot，取 1s）
+var builtinChainPoll = map[string]time.Duration{
+	"bitcoin":  time.Minute,
+	"solana":   time.Second,
+	"tron":     3 * time.Second,
+	"ethereum": 12 * time.Second,
+	"bsc":      3 * time.Second,
+	"polygon":  2 * time.Second,
+	"arbitrum": time.Second,
+	"optimism": 2 * time.Second,
+	"base":     2 * time.Second,
+}
+
+// chainPoller 记录每条链下次扫描的时间；只在扫描主协程使用，不加锁
+type chainPoller struct {
+	def       time.Duration
+	overrides map[string]time.Duration
+	next      map[string]time.Time
+}
+
+func newChainPoller(def time.Duration, overrides map[string]time.Duration) *chainPoller {
+	return &chainPoller{def: def, overrides: overrides, next: map[string]time.Time{}}
+}
+
+// Interval 链的轮询间隔
+func (p *chainPoller) Interval(chain string) time.Duration {
+	chain = strings.ToLower(chain)
+	if d, ok := p.overrides[chain]; ok {
+		return d
+	}
+	if d, ok := builtinChainPoll[chain]; ok {
+		return d
+	}
+	return p.def
+}
+
+// Due 链是否到了扫描时间（从未扫描过的链立即扫描）
+func (p *chainPoller) Due(chain string, now time.Time) bool {
+	t, ok := p.next[strings.ToLower(chain)]
+	return !ok || !now.Before(t)
+}
+
+// Done 记录一轮扫描结束：有进展（还有积压）时立即可再扫，否则等待该链的间隔
+func (p *chainPoller) Done(chain string, now time.Time, progressed bool) {
+	next := now
+	if !progressed {
+		next = now.Add(p.Interval(chain))
+	}
+	p.next[strings.ToLower(chain)] = next
+}
+
+// Wait 距最近一条启用中的链到期还要等多久；没有记录过的链时按 -poll 等待
+func (p *chainPoller) Wait(now time.Time, enabled func(string) bool) time.Duration {
+	wait, found := time.Duration(0), false
+	for chain, t := range p.next {
+		if !enabled(chain) {
+			continue
+		}
+		if d := t.Sub(now); !found || d < wait {
+			wait, found = d, true
+		}
+	}
+	if !found {
+		return p.def
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// Summary 日志用："bitcoin=1m0s solana=1s ..."
+func (p *chainPoller) Summary(chains []string) string {
+	sorted := append([]string(nil), chains...)
+	sort.Strings(sorted)
+	parts := make([]string, 0, len(sorted))
+	for _, c := range sorted {
+		parts = append(parts, fmt.Sprintf("%s=%s", c, p.Interval(c)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseChainPoll 解析 "bitcoin=1m,solana=500ms" 形式的按链轮询间隔（链名不区分大小写）
+func parseChainPoll(s string) (map[string]time.Duration, error) {
+	out := map[string]time.Duration{}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		k, v, ok := strings.Cut(part, "=")
+		k = strings.ToLower(strings.TrimSpace(k))
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid chain poll interval %q (want chain=duration)", part)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(v))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid chain poll interval %q: must be a positive duration", part)
+		}
+		out[k] = d
+	}
+	return out, nil
+}
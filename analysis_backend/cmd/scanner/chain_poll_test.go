@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestChainPollerSchedules 各链按自己的间隔扫描：模拟 2 分钟，BTC 约每分钟一次、Solana 约每秒一次、ethereum 每 12 秒一次
+func TestChainPollerSchedules(t *testing.T) {
+	p := newChainPoller(4*time.Second, map[string]time.Duration{"ethereum": 12 * time.Second})
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	scans := map[string]int{}
+	chains := []string{"bitcoin", "solana", "ethereum", "linea"}
+	all := func(string) bool { return true }
+	for now.Before(start.Add(2 * time.Minute)) {
+		for _, c := range chains {
+			if p.Due(c, now) {
+				scans[c]++
+				p.Done(c, now, false) // 已追平，没有积压
+			}
+		}
+		now = now.Add(p.Wait(now, all))
+	}
+	want := map[string]int{"bitcoin": 2, "solana": 120, "ethereum": 10, "linea": 30}
+	for c, n := range want {
+		if scans[c] != n {
+			t.Errorf("%s 两分钟内应扫描 %d 次，实际 %d（全部：%v）", c, n, scans[c], scans)
+		}
+	}
+}
+
+// TestChainPollerBacklogAndWait 有积压的链立即再扫；停用的链不参与等待时间计算；没有记录时按 -poll 等待
+func TestChainPollerBacklogAndWait(t *testing.T) {
+	p := newChainPoller(4*time.Second, nil)
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	all := func(string) bool { return true }
+	if w := p.Wait(now, all); w != 4*time.Second {
+		t.Errorf("没有扫描记录时应按 -poll 等待，实际 %s", w)
+	}
+
+	p.Done("bitcoin", now, true)
+	if !p.Due("bitcoin", now) || p.Wait(now, all) != 0 {
+		t.Error("有进展的链应立即可再扫")
+	}
+	p.Done("bitcoin", now, false)
+	p.Done("solana", now, false)
+	if p.Due("bitcoin", now.Add(59*time.Second)) || !p.Due("bitcoin", now.Add(time.Minute)) {
+		t.Error("BTC 追平后应等待 1 分钟")
+	}
+	if w := p.Wait(now, all); w != time.Second {
+		t.Errorf("应等到最近的 Solana，实际 %s", w)
+	}
+	noSol := func(c string) bool { return c != "solana" }
+	if w := p.Wait(now, noSol); w != time.Minute {
+		t.Errorf("停用的 Solana 不应参与等待计算，实际 %s", w)
+	}
+	if s := p.Summary([]string{"solana", "bitcoin", "linea"}); s != "bitcoin=1m0s linea=4s solana=1s" {
+		t.Errorf("Summary 不符: %s", s)
+	}
+}
+
+// TestParseChainPoll 解析按链轮询间隔
+func TestParseChainPoll(t *testing.T) {
+	m, err := parseChainPoll("Bitcoin=2m, solana=500ms")
+	if err != nil || m["bitcoin"] != 2*time.Minute || m["solana"] != 500*time.Millisecond {
+		t.Fatalf("解析结果不符: %v %v", m, err)
+	}
+	for _, bad := range []string{"bitcoin", "=1s", "solana=fast", "tron=0s"} {
+		if _, err := parseChainPoll(bad); err == nil || !strings.Contains(err.Error(), "poll interval") {
+			t.Errorf("%q 应报错，实际 %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// cmd/scanner/confirmations.go
+// 确认深度：每轮扫描窗口的右端点不超过 latest - confirmations，离链头太近、可能被重组掉的区块留到下一轮。
+// 默认 bitcoin=2、solana=32、EVM 链=12，tron 不留余量；-confirmations 按链覆盖。
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBTCConfirmations    = 2
+	defaultSolanaConfirmations = 32
+	defaultEVMConfirmations    = 12
+)
+
+// parseConfirmations 解析 "bitcoin=3,polygon=128" 形式的按链确认深度（链名不区分大小写，0 表示扫到链头）
+func parseConfirmations(s string) (map[string]uint64, error) {
+	out := map[string]uint64{}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		k, v, ok := strings.Cut(part, "=")
+		k = canonicalChain(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid confirmations %q (want chain=blocks)", part)
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid confirmations %q: must be a non-negative integer", part)
+		}
+		out[k] = n
+	}
+	return out, nil
+}
+
+// confirmationsFor 链的确认深度：按链覆盖优先，否则用该链默认值
+func confirmationsFor(chain string, perChain map[string]uint64) uint64 {
+	chain = canonicalChain(chain)
+	if n, ok := perChain[chain]; ok {
+		return n
+	}
+	switch chain {
+	case "bitcoin":
+		return defaultBTCConfirmations
+	case "solana":
+		return defaultSolanaConfirmations
+	case "tron":
+		return 0
+	default:
+		return defaultEVMConfirmations
+	}
+}
+
+// confirmedTip 可扫描的最高区块 latest - confirmations；latest 不足确认深度时 ok=false，本轮不扫描
+func confirmedTip(latest, confirmations uint64) (tip uint64, ok bool) {
+	if latest < confirmations {
+		return 0, false
+	}
+	return latest - confirmations, true
+}
+
+// scanWindow 本轮扫描窗口 [cur, to]：游标 cur 是下一个待扫区块，to = cur+span 且不超过 tip；
+// cur 已越过 tip（tip 之前都已扫完）时 ok=false。各链统一按此规则，窗口提交后游标推进到 to+1
+func scanWindow(cur, span, tip uint64) (to uint64, ok bool) {
+	if cur > tip {
+		return 0, false
+	}
+	return min(cur+span, tip), true
+}
+
+// startCursor 没有已提交游标时的起点：在链头之前回退 back 个区块，且不超过确认深度允许的高度；
+// 链高不足回退深度时从 latest 起（不从创世块整链回扫），等链头推进到确认深度之后再开始扫描
+func startCursor(latest, back, confirmations uint64) uint64 {
+	back = max(back, confirmations)
+	if latest > back {
+		return latest - back
+	}
+	return latest
+}
@@ -0,0 +1,116 @@
+package main
+
+import "testing"
+
+func TestConfirmationsDefaultsAndOverrides(t *testing.T) {
+	over, err := parseConfirmations("BTC=3, polygon=128;tron=19 ethereum=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]uint64{
+		"bitcoin": 3, "polygon": 128, "tron": 19, "ethereum": 0,
+		"solana": defaultSolanaConfirmations, "bsc": defaultEVMConfirmations,
+	}
+	for chain, want := range cases {
+		if got := confirmationsFor(chain, over); got != want {
+			t.Errorf("%s: got %d, want %d", chain, got, want)
+		}
+	}
+	defaults := map[string]uint64{"btc": 2, "sol": 32, "ethereum": 12, "arbitrum": 12, "tron": 0}
+	for chain, want := range defaults {
+		if got := confirmationsFor(chain, nil); got != want {
+			t.Errorf("default %s: got %d, want %d", chain, got, want)
+		}
+	}
+	for _, bad := range []string{"bitcoin", "bitcoin=-1", "=3", "solana=x"} {
+		if _, err := parseConfirmations(bad); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestConfirmedTipWindowNeverPassesConfirmationDepth(t *testing.T) {
+	for _, conf := range []uint64{0, 2, 12, 32} {
+		for latest := uint64(0); latest < 80; latest++ {
+			tip, ok := confirmedTip(latest, conf)
+			if latest < conf {
+				if ok {
+					t.Fatalf("latest=%d conf=%d: want nothing to scan", latest, conf)
+				}
+				continue
+			}
+			if !ok || tip != latest-conf {
+				t.Fatalf("latest=%d conf=%d: tip=%d ok=%v", latest, conf, tip, ok)
+			}
+			for cur := uint64(0); cur <= latest; cur++ {
+				if to, ok := scanWindow(cur, 10, tip); ok && (to > latest-conf || to < cur) {
+					t.Fatalf("latest=%d conf=%d cur=%d: window end %d exceeds %d", latest, conf, cur, to, latest-conf)
+				}
+			}
+		}
+	}
+}
+
+// TestScanWindowConfirmedTipBoundary 游标是下一个待扫区块：cur == tip 时仍扫 tip 本身，cur > tip 才停；
+// 逐窗推进时 tip 之前的区块恰好各扫一次，且从不越过确认深度
+func TestScanWindowConfirmedTipBoundary(t *testing.T) {
+	tip, ok := confirmedTip(100, 12)
+	if !ok || tip != 88 {
+		t.Fatalf("tip=%d ok=%v, want 88", tip, ok)
+	}
+	if to, ok := scanWindow(88, 500, tip); !ok || to != 88 {
+		t.Fatalf("cursor at tip should scan the tip block, got to=%d ok=%v", to, ok)
+	}
+	if _, ok := scanWindow(89, 500, tip); ok {
+		t.Fatal("cursor past tip should not scan")
+	}
+	// latest 恰好等于确认深度：tip=0，创世块可扫
+	if tip, ok := confirmedTip(12, 12); !ok || tip != 0 {
+		t.Fatalf("tip=%d ok=%v, want 0", tip, ok)
+	}
+	if to, ok := scanWindow(0, 6, 0); !ok || to != 0 {
+		t.Fatalf("cursor 0 with tip 0 should scan block 0, got to=%d ok=%v", to, ok)
+	}
+
+	for _, span := range []uint64{0, 6, 19, 500} {
+		scanned := map[uint64]int{}
+		cur := uint64(50)
+		for {
+			to, ok := scanWindow(cur, span, tip)
+			if !ok {
+				break
+			}
+			if to > tip || to < cur {
+				t.Fatalf("span=%d cur=%d: window end %d outside [cur, %d]", span, cur, to, tip)
+			}
+			for n := cur; n <= to; n++ {
+				scanned[n]++
+			}
+			cur = to + 1
+		}
+		if cur != tip+1 {
+			t.Fatalf("span=%d: cursor should stop at tip+1=%d, got %d", span, tip+1, cur)
+		}
+		for n := uint64(50); n <= tip; n++ {
+			if scanned[n] != 1 {
+				t.Fatalf("span=%d: block %d scanned %d times", span, n, scanned[n])
+			}
+		}
+	}
+}
+
+func TestStartCursorRespectsConfirmations(t *testing.T) {
+	cases := []struct{ latest, back, conf, want uint64 }{
+		{1000, 4, 12, 988},
+		{1000, 200, 32, 800},
+		{1000, 1, 2, 998},
+		{1000, 4, 0, 996},
+		{10, 4, 12, 10},
+		{12, 12, 0, 12},
+	}
+	for _, c := range cases {
+		if got := startCursor(c.latest, c.back, c.conf); got != c.want {
+			t.Errorf("startCursor(%d, %d, %d) = %d, want %d", c.latest, c.back, c.conf, got, c.want)
+		}
+	}
+}
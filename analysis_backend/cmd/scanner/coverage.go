@@ -0,0 +1,138 @@
+// cmd/scanner/coverage.go
+// 启动时的地址覆盖报告：按链汇总已加载的地址，对照链配置与 -exclude-chains，
+// 列出“有地址但无法扫描”的链，避免这些地址被静默跳过。
+
+package main
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// 覆盖状态
+const (
+	coverageOK          = "ok"
+	coverageExcluded    = "excluded"    // 被 -exclude-chains 排除
+	coverageNoConfig    = "no-config"   // 配置中没有这条链
+	coverageNoEndpoint  = "no-endpoint" // 有配置但 rpc/esplora 为空
+	coverageUnsupported = "unsupported" // 扫描器不支持的链类型（如 ton）
+)
+
+// chainCoverage 单条链的覆盖情况
+type chainCoverage struct {
+	Chain     string
+	Addresses int
+	Entities  int
+	Status    string
+}
+
+// Covered 该链的地址是否会被扫描
+func (c chainCoverage) Covered() bool {
+	return c.Status == coverageOK
+}
+
+// buildCoverageReport 按链名排序返回每条有地址的链的覆盖状态
+func buildCoverageReport(rows []models.AddressRow, chains map[string]config.ChainCfg, exclude map[string]bool) []chainCoverage {
+	type agg struct {
+		addrs    int
+		entities map[string]bool
+	}
+	byChain := map[string]*agg{}
+	for _, r := range rows {
+		ch := strings.ToLower(strings.TrimSpace(r.Chain))
+		if ch == "" || strings.TrimSpace(r.Address) == "" {
+			continue
+		}
+		a := byChain[ch]
+		if a == nil {
+			a = &agg{entities: map[string]bool{}}
+			byChain[ch] = a
+		}
+		a.addrs++
+		a.entities[r.Entity] = true
+	}
+
+	out := make([]chainCoverage, 0, len(byChain))
+	for ch, a := range byChain {
+		out = append(out, chainCoverage{Chain: ch, Addresses: a.addrs, Entities: len(a.entities), Status: chainCoverageStatus(ch, chains, exclude)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Chain < out[j].Chain })
+	return out
+}
+
+// chainCoverageStatus 与扫描器初始化时的判断保持一致
+func chainCoverageStatus(ch string, chains map[string]config.ChainCfg, exclude map[string]bool) string {
+	if exclude[ch] {
+		return coverageExcluded
+	}
+	switch ch {
+	case "bitcoin", "btc":
+		if exclude["bitcoin"] || exclude["btc"] {
+			return coverageExcluded
+		}
+		cc, ok := chains["bitcoin"]
+		if !ok {
+			return coverageNoConfig
+		}
+		if len(parseEsploraEndpoints(cc.Esplora)) == 0 {
+			return coverageNoEndpoint
+		}
+	case "solana", "sol":
+		if exclude["solana"] || exclude["sol"] {
+			return coverageExcluded
+		}
+		cc, ok := chains["solana"]
+		if !ok {
+			return coverageNoConfig
+		}
+		if len(parseRPCList(cc.RPC)) == 0 {
+			return coverageNoEndpoint
+		}
+	case "tron", "trx":
+		if exclude["tron"] || exclude["trx"] {
+			return coverageExcluded
+		}
+		cc, ok := chains["tron"]
+		if !ok {
+			return coverageNoConfig
+		}
+		if len(parseRPCList(cc.RPC)) == 0 {
+			return coverageNoEndpoint
+		}
+	default:
+		cc, ok := chains[ch]
+		if !ok {
+			return coverageNoConfig
+		}
+		if t := strings.ToLower(strings.TrimSpace(cc.Type)); t != "" && t != "evm" {
+			return coverageUnsupported
+		}
+		if len(parseRPCList(cc.RPC)) == 0 {
+			return coverageNoEndpoint
+		}
+	}
+	return coverageOK
+}
+
+// logCoverageReport 打印覆盖报告，返回未覆盖的链
+func logCoverageReport(report []chainCoverage) []chainCoverage {
+	var uncovered []chainCoverage
+	for _, c := range report {
+		log.Printf("[coverage] chain=%-10s addrs=%-6d entities=%-3d status=%s", c.Chain, c.Addresses, c.Entities, c.Status)
+		if !c.Covered() && c.Status != coverageExcluded {
+			uncovered = append(uncovered, c)
+		}
+	}
+	if len(uncovered) > 0 {
+		parts := make([]string, 0, len(uncovered))
+		for _, c := range uncovered {
+			parts = append(parts, fmt.Sprintf("%s(%s, %d addrs)", c.Chain, c.Status, c.Addresses))
+		}
+		log.Printf("[coverage] WARNING: addresses on these chains will NOT be scanned: %s", strings.Join(parts, ", "))
+	}
+	return uncovered
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"testing"
+)
+
+// TestCoverageReportFlagsUncoveredChains 有地址但没有可用配置的链会被标记出来
+func TestCoverageReportFlagsUncoveredChains(t *testing.T) {
+	rows := []models.AddressRow{
+		{Entity: "binance", Chain: "ethereum", Address: "0x1"},
+		{Entity: "okx", Chain: "ethereum", Address: "0x2"},
+		{Entity: "binance", Chain: "Solana", Address: "So1"},
+		{Entity: "binance", Chain: "bitcoin", Address: "bc1"},
+		{Entity: "binance", Chain: "linea", Address: "0x3"},
+		{Entity: "binance", Chain: "tron", Address: "T1"},
+		{Entity: "binance", Chain: "ton", Address: "EQ1"},
+		{Entity: "binance", Chain: "bsc", Address: "0x4"},
+	}
+	chains := map[string]config.ChainCfg{
+		"ethereum": {Name: "ethereum", Type: "evm", RPC: "https://eth.example"},
+		"solana":   {Name: "solana", Type: "solana", RPC: " , "},
+		"bitcoin":  {Name: "bitcoin", Type: "bitcoin", Esplora: "https://mempool.space/api"},
+		"tron":     {Name: "tron", Type: "tron", RPC: "https://tron.example"},
+		"ton":      {Name: "ton", Type: "ton", RPC: "https://ton.example"},
+	}
+	report := buildCoverageReport(rows, chains, map[string]bool{"bsc": true})
+
+	want := map[string]string{
+		"bitcoin":  coverageOK,
+		"bsc":      coverageExcluded,
+		"ethereum": coverageOK,
+		"linea":    coverageNoConfig,
+		"solana":   coverageNoEndpoint,
+		"ton":      coverageUnsupported,
+		"tron":     coverageOK,
+	}
+	if len(report) != len(want) {
+		t.Fatalf("期望 %d 条链，实际 %+v", len(want), report)
+	}
+	for _, c := range report {
+		if c.Status != want[c.Chain] {
+			t.Errorf("%s 状态期望 %s，实际 %s", c.Chain, want[c.Chain], c.Status)
+		}
+		if c.Chain == "ethereum" && (c.Addresses != 2 || c.Entities != 2) {
+			t.Errorf("ethereum 汇总不符: %+v", c)
+		}
+	}
+
+	uncovered := logCoverageReport(report)
+	if len(uncovered) != 3 {
+		t.Fatalf("linea/solana/ton 应被列为未覆盖（排除的 bsc 不算），实际 %+v", uncovered)
+	}
+}
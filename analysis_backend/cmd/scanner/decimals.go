@@ -0,0 +1,48 @@
+// cmd/scanner/decimals.go
+// ERC20 精度：配置覆盖优先，其次缓存，最后才走 eth_call decimals() 自动探测。
+
+package main
+
+import (
+	"log"
+	"strings"
+
+	"analysis/internal/config"
+)
+
+// defaultERC20Decimals 探测失败或结果异常时的兜底精度
+const defaultERC20Decimals = 18
+
+// erc20DecimalsOverrides 收集配置里的精度覆盖（lowerAddr -> decimals），越界值告警后忽略
+func erc20DecimalsOverrides(chain string, tokens []config.TokenERC20) map[string]int {
+	out := map[string]int{}
+	for _, t := range tokens {
+		addr := strings.ToLower(strings.TrimSpace(t.Address))
+		if addr == "" || t.Decimals == nil {
+			continue
+		}
+		d, ok := config.DecimalsOverride(t.Decimals)
+		if !ok {
+			log.Printf("[warn] chain %s token %s: invalid decimals override %d, auto-detect instead", chain, t.Symbol, *t.Decimals)
+			continue
+		}
+		out[addr] = d
+	}
+	return out
+}
+
+// erc20Decimals 查缓存（已预填配置覆盖），未命中才调用 detect；探测失败返回兜底精度且不缓存
+func erc20Decimals(cache map[string]int, contract string, detect func() (int, error)) (int, error) {
+	if v, ok := cache[contract]; ok {
+		return v, nil
+	}
+	d, err := detect()
+	if err != nil {
+		return defaultERC20Decimals, err
+	}
+	if d <= 0 || d > config.MaxTokenDecimals {
+		d = defaultERC20Decimals
+	}
+	cache[contract] = d
+	return d, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"analysis/internal/config"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestERC20DecimalsOverrideBypassesDetection(t *testing.T) {
+	cache := erc20DecimalsOverrides("ethereum", []config.TokenERC20{
+		{Symbol: "PROXY", Address: " 0xAbC0000000000000000000000000000000000001 ", Decimals: intPtr(6)},
+		{Symbol: "ZERO", Address: "0xabc0000000000000000000000000000000000002", Decimals: intPtr(0)},
+		{Symbol: "AUTO", Address: "0xabc0000000000000000000000000000000000003"},
+		{Symbol: "BAD", Address: "0xabc0000000000000000000000000000000000004", Decimals: intPtr(99)},
+	})
+	if len(cache) != 2 {
+		t.Fatalf("want 2 overrides, got %v", cache)
+	}
+
+	detect := func() (int, error) {
+		t.Fatal("auto-detection must be bypassed when an override is set")
+		return 0, nil
+	}
+	if d, err := erc20Decimals(cache, "0xabc0000000000000000000000000000000000001", detect); err != nil || d != 6 {
+		t.Fatalf("want override 6, got %d %v", d, err)
+	}
+	if d, err := erc20Decimals(cache, "0xabc0000000000000000000000000000000000002", detect); err != nil || d != 0 {
+		t.Fatalf("want override 0, got %d %v", d, err)
+	}
+}
+
+func TestERC20DecimalsAutoDetect(t *testing.T) {
+	cache := map[string]int{}
+	calls := 0
+	detect := func() (int, error) { calls++; return 8, nil }
+	for i := 0; i < 2; i++ {
+		if d, err := erc20Decimals(cache, "0xa", detect); err != nil || d != 8 {
+			t.Fatalf("want detected 8, got %d %v", d, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("detected decimals should be cached, calls=%d", calls)
+	}
+
+	// 探测失败：兜底 18 且不缓存
+	d, err := erc20Decimals(cache, "0xb", func() (int, error) { return 0, errors.New("revert") })
+	if err == nil || d != defaultERC20Decimals {
+		t.Fatalf("want fallback %d with error, got %d %v", defaultERC20Decimals, d, err)
+	}
+	if _, ok := cache["0xb"]; ok {
+		t.Fatal("failed detection must not be cached")
+	}
+
+	// 异常值：兜底 18
+	if d, _ := erc20Decimals(cache, "0xc", func() (int, error) { return 77, nil }); d != defaultERC20Decimals {
+		t.Fatalf("want fallback for out-of-range detection, got %d", d)
+	}
+}
@@ -0,0 +1,189 @@
+// cmd/scanner/evm_block_times.go
+// ERC20 日志的出块时间：每个窗口内按区块高度缓存，同一区块最多拉取一次；
+// 一批 getLogs 结果里缺时间的区块先用 JSON-RPC 批量请求一次取回全部区块头，批量失败时退回逐个 eth_getBlockByNumber。
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// evmBlockHeaderBatchMax 单次批量请求的区块头数上限，超出分多批
+const evmBlockHeaderBatchMax = 100
+
+// errRPCBatchUnsupported 节点明确不支持 JSON-RPC 批量请求（返回非数组、拒绝批量或方法不存在）；
+// 网络错误、超时、5xx 等临时失败不包装此错误
+var errRPCBatchUnsupported = errors.New("rpc batch not supported")
+
+// disableRPCBatch 批量请求失败后的处理：节点不支持批量时关闭该链的批量（各扫描任务共享，返回 true），
+// 临时失败只让本次退回逐个拉取，批量保持开启
+func disableRPCBatch(chain, endpoint string, off *atomic.Bool, err error) bool {
+	if err == nil {
+		return false
+	}
+	if !errors.Is(err, errRPCBatchUnsupported) {
+		log.Printf("[%s] batch getBlockByNumber by %s failed, single requests for this window: %v", chain, endpoint, err)
+		return false
+	}
+	if !off.Swap(true) {
+		log.Printf("[%s] batch getBlockByNumber not supported by %s, falling back to single requests: %v", chain, endpoint, err)
+	}
+	return true
+}
+
+// evmBlockTimes 单个扫描窗口的出块时间查询
+type evmBlockTimes struct {
+	blocks  *evmWindowBlocks
+	tried   map[uint64]bool // 本窗口已拉取过（含失败）的区块，不再重复请求
+	batch   func(nums []uint64) (map[uint64]map[string]any, error)
+	single  func(num uint64) (map[string]any, error)
+	observe func(num uint64, blk map[string]any) // 拉到区块头后调用（记录区块哈希等），可为空
+}
+
+// newEVMBlockTimes batch 为空表示不支持批量请求，只逐个拉取
+func newEVMBlockTimes(blocks *evmWindowBlocks, batch func(nums []uint64) (map[uint64]map[string]any, error),
+	single func(num uint64) (map[string]any, error), observe func(num uint64, blk map[string]any)) *evmBlockTimes {
+	return &evmBlockTimes{blocks: blocks, tried: map[uint64]bool{}, batch: batch, single: single, observe: observe}
+}
+
+// Prefetch 批量拉取 logs 所在区块中尚未缓存的区块头；批量请求失败时不做处理，由 Time 逐个拉取
+func (t *evmBlockTimes) Prefetch(logs []map[string]any) {
+	if t.batch == nil {
+		return
+	}
+	need := map[uint64]bool{}
+	for _, lg := range logs {
+		n := hexToUint64(str(lg["blockNumber"]))
+		if n == 0 || t.tried[n] {
+			continue
+		}
+		if _, ok := t.blocks.Time(n); ok {
+			continue
+		}
+		need[n] = true
+	}
+	if len(need) < 2 {
+		return
+	}
+	nums := make([]uint64, 0, len(need))
+	for n := range need {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(i, j int) bool { return nums[i] < nums[j] })
+
+	for len(nums) > 0 {
+		part := nums
+		if len(part) > evmBlockHeaderBatchMax {
+			part = part[:evmBlockHeaderBatchMax]
+		}
+		nums = nums[len(part):]
+		got, err := t.batch(part)
+		if err != nil {
+			return
+		}
+		for _, n := range part {
+			t.tried[n] = true
+			if blk, ok := got[n]; ok {
+				t.record(n, blk)
+			}
+		}
+	}
+}
+
+// Time 区块的出块时间：先查缓存，未拉取过的逐个拉区块头；拉取失败时返回当前时间
+func (t *evmBlockTimes) Time(n uint64) time.Time {
+	if ts, ok := t.blocks.Time(n); ok {
+		return ts
+	}
+	if t.tried[n] {
+		return time.Now().UTC()
+	}
+	t.tried[n] = true
+	blk, err := t.single(n)
+	if err != nil {
+		return time.Now().UTC()
+	}
+	t.record(n, blk)
+	return parseBlockTime(blk)
+}
+
+func (t *evmBlockTimes) record(n uint64, blk map[string]any) {
+	t.blocks.Record(n, blk)
+	if t.observe != nil {
+		t.observe(n, blk)
+	}
+}
+
+// postRPCBatch 一次 HTTP 请求发送多个 eth_getBlockByNumber(num, false)，按 id 对应结果；
+// 节点不支持批量（返回单个对象、HTTP 错误等）或任一结果带 error 时返回错误，null 结果跳过
+func postRPCBatch(ctx context.Context, url string, nums []uint64) (map[uint64]map[string]any, error) {
+	reqs := make([]rpcReq, len(nums))
+	for i, n := range nums {
+		reqs[i] = rpcReq{Jsonrpc: "2.0", ID: i + 1, Method: "eth_getBlockByNumber", Params: []interface{}{fmt.Sprintf("0x%x", n), false}}
+	}
+	body, _ := json.Marshal(reqs)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new batch request %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "scanner/1.0")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do batch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err := fmt.Errorf("rpc batch => %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+		if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests && strings.Contains(strings.ToLower(string(b)), "batch") {
+			err = fmt.Errorf("%w: %v", errRPCBatchUnsupported, err)
+		}
+		return nil, err
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read batch %s: %w", url, err)
+	}
+	var outs []rpcResp
+	if err := json.Unmarshal(raw, &outs); err != nil {
+		// 完整读到的响应不是数组（单个错误对象等）：节点不支持批量
+		if trimmed := bytes.TrimSpace(raw); len(trimmed) > 0 && trimmed[0] != '[' {
+			return nil, fmt.Errorf("%w: %s", errRPCBatchUnsupported, strings.TrimSpace(string(raw[:min(len(raw), 512)])))
+		}
+		return nil, fmt.Errorf("rpc batch decode error: %w", err)
+	}
+
+	got := make(map[uint64]map[string]any, len(outs))
+	for _, out := range outs {
+		if out.Error != nil {
+			err := fmt.Errorf("rpc batch error [%d]: %s", out.Error.Code, out.Error.Message)
+			msg := strings.ToLower(out.Error.Message)
+			if out.Error.Code == -32600 || out.Error.Code == -32601 || strings.Contains(msg, "batch") || strings.Contains(msg, "method not found") {
+				err = fmt.Errorf("%w: %v", errRPCBatchUnsupported, err)
+			}
+			return nil, err
+		}
+		if out.ID < 1 || out.ID > len(nums) || isEmptyResult(out.Result) {
+			continue
+		}
+		var blk map[string]any
+		if err := json.Unmarshal(out.Result, &blk); err != nil {
+			return nil, fmt.Errorf("rpc batch decode result: %w", err)
+		}
+		got[nums[out.ID-1]] = blk
+	}
+	return got, nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func logAt(n uint64) map[string]any {
+	return map[string]any{"blockNumber": fmt.Sprintf("0x%x", n)}
+}
+
+func headerAt(n uint64) map[string]any {
+	return map[string]any{"timestamp": fmt.Sprintf("0x%x", 1700000000+n), "hash": fmt.Sprintf("0xh%d", n)}
+}
+
+// blockTimesCounter 统计每个区块被拉取（批量或逐个）的次数
+type blockTimesCounter struct {
+	fetched  map[uint64]int
+	batches  int
+	singles  int
+	batchErr error
+	missing  map[uint64]bool // 批量结果中缺失（null）的区块
+}
+
+func (c *blockTimesCounter) batch(nums []uint64) (map[uint64]map[string]any, error) {
+	c.batches++
+	if c.batchErr != nil {
+		return nil, c.batchErr
+	}
+	out := map[uint64]map[string]any{}
+	for _, n := range nums {
+		c.fetched[n]++
+		if !c.missing[n] {
+			out[n] = headerAt(n)
+		}
+	}
+	return out, nil
+}
+
+func (c *blockTimesCounter) single(n uint64) (map[string]any, error) {
+	c.singles++
+	c.fetched[n]++
+	return headerAt(n), nil
+}
+
+func TestEVMBlockTimesFetchesEachBlockOncePerWindow(t *testing.T) {
+	c := &blockTimesCounter{fetched: map[uint64]int{}, missing: map[uint64]bool{12: true}}
+	observed := map[uint64]string{}
+	bt := newEVMBlockTimes(newEVMWindowBlocks(false), c.batch, c.single,
+		func(n uint64, blk map[string]any) { observed[n] = str(blk["hash"]) })
+
+	// 两批日志有重叠区块；11 已由原生扫描缓存
+	bt.blocks.Record(11, headerAt(11))
+	first := []map[string]any{logAt(10), logAt(10), logAt(11), logAt(12), logAt(13)}
+	second := []map[string]any{logAt(13), logAt(14), logAt(15)}
+	for _, logs := range [][]map[string]any{first, second} {
+		bt.Prefetch(logs)
+		for _, lg := range logs {
+			n := hexToUint64(str(lg["blockNumber"]))
+			if got, want := bt.Time(n).Unix(), int64(1700000000+n); n != 12 && got != want {
+				t.Errorf("block %d time = %d, want %d", n, got, want)
+			}
+		}
+	}
+	// 批量结果缺失的 12 不再逐个重拉
+	bt.Time(12)
+
+	for n, cnt := range c.fetched {
+		if cnt > 1 {
+			t.Errorf("block %d fetched %d times", n, cnt)
+		}
+	}
+	if c.fetched[11] != 0 {
+		t.Errorf("cached block 11 should not be fetched")
+	}
+	if c.batches != 2 || c.singles != 0 {
+		t.Errorf("want 2 batches and no single calls, got batches=%d singles=%d", c.batches, c.singles)
+	}
+	if observed[10] != "0xh10" || observed[15] != "0xh15" {
+		t.Errorf("observe not called for fetched headers: %v", observed)
+	}
+}
+
+func TestEVMBlockTimesFallsBackToSingleOnBatchError(t *testing.T) {
+	c := &blockTimesCounter{fetched: map[uint64]int{}, batchErr: errors.New("batch not supported")}
+	bt := newEVMBlockTimes(newEVMWindowBlocks(false), c.batch, c.single, nil)
+
+	logs := []map[string]any{logAt(20), logAt(21), logAt(21), logAt(22)}
+	bt.Prefetch(logs)
+	for _, lg := range logs {
+		n := hexToUint64(str(lg["blockNumber"]))
+		if got := bt.Time(n).Unix(); got != int64(1700000000+n) {
+			t.Errorf("block %d time = %d", n, got)
+		}
+	}
+	if c.batches != 1 || c.singles != 3 {
+		t.Errorf("want 1 failed batch then 3 single calls, got batches=%d singles=%d", c.batches, c.singles)
+	}
+	for n, cnt := range c.fetched {
+		if cnt > 1 {
+			t.Errorf("block %d fetched %d times", n, cnt)
+		}
+	}
+}
+
+func TestPostRPCBatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reqs []rpcReq
+		if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// 乱序返回，最后一个为 null
+		out := make([]map[string]any, 0, len(reqs))
+		for i := len(reqs) - 1; i >= 0; i-- {
+			var res any = map[string]any{"number": reqs[i].Params[0], "timestamp": "0x10"}
+			if i == len(reqs)-1 {
+				res = nil
+			}
+			out = append(out, map[string]any{"jsonrpc": "2.0", "id": reqs[i].ID, "result": res})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	got, err := postRPCBatch(context.Background(), srv.URL, []uint64{0x64, 0x65, 0x66})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || str(got[0x64]["number"]) != "0x64" || str(got[0x65]["number"]) != "0x65" {
+		t.Errorf("unexpected batch result: %v", got)
+	}
+
+	// 不支持批量的节点返回单个错误对象
+	single := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"batch not supported"}}`))
+	}))
+	defer single.Close()
+	_, err = postRPCBatch(context.Background(), single.URL, []uint64{1, 2})
+	if !errors.Is(err, errRPCBatchUnsupported) {
+		t.Errorf("non-batch response should be reported as unsupported, got %v", err)
+	}
+}
+
+// TestDisableRPCBatchOnlyWhenUnsupported 超时、5xx 等临时失败不关闭批量；节点返回非数组时才关闭
+func TestDisableRPCBatchOnlyWhenUnsupported(t *testing.T) {
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream timeout", http.StatusBadGateway)
+	}))
+	defer flaky.Close()
+	off := &atomic.Bool{}
+	_, err := postRPCBatch(context.Background(), flaky.URL, []uint64{1, 2})
+	if err == nil || disableRPCBatch("ethereum", flaky.URL, off, err) || off.Load() {
+		t.Fatalf("5xx should not disable batching: err=%v off=%v", err, off.Load())
+	}
+	if disableRPCBatch("ethereum", flaky.URL, off, context.DeadlineExceeded) || off.Load() {
+		t.Fatal("timeout should not disable batching")
+	}
+
+	single := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"invalid request"}}`))
+	}))
+	defer single.Close()
+	_, err = postRPCBatch(context.Background(), single.URL, []uint64{1, 2})
+	if !disableRPCBatch("ethereum", single.URL, off, err) || !off.Load() {
+		t.Errorf("non-array response should disable batching: err=%v off=%v", err, off.Load())
+	}
+}
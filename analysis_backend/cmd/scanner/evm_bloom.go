@@ -0,0 +1,133 @@
+// cmd/scanner/evm_bloom.go
+// EVM 扫描窗口内的区块缓存：记录已拉取区块的 logsBloom 与出块时间。
+// 原生 ETH 转账不产生日志，bloom 无法判断，原生路径仍需逐块拉完整交易；
+// 但原生路径拉到的区块自带 logsBloom，ERC20 路径可借此跳过整段无关区块、收窄 getLogs 范围，
+// 日志的出块时间也优先走缓存，未命中时只拉区块头（不带交易）。
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"golang.org/x/crypto/sha3"
+)
+
+// evmBloom 区块头的 2048 位 logsBloom
+type evmBloom [256]byte
+
+// bloomBits 与 go-ethereum 的 bloom9 一致：keccak256 前 6 字节，每 2 字节取低 11 位作为位下标
+func bloomBits(data []byte) [3]uint {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	sum := h.Sum(nil)
+	var bits [3]uint
+	for i := 0; i < 3; i++ {
+		bits[i] = (uint(sum[2*i])<<8 | uint(sum[2*i+1])) & 2047
+	}
+	return bits
+}
+
+// Add 加入一个地址/主题
+func (b *evmBloom) Add(data []byte) {
+	for _, bit := range bloomBits(data) {
+		b[len(b)-1-int(bit/8)] |= 1 << (bit % 8)
+	}
+}
+
+// Test 可能包含 data（存在误判，不会漏判）
+func (b evmBloom) Test(data []byte) bool {
+	for _, bit := range bloomBits(data) {
+		if b[len(b)-1-int(bit/8)]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// evmWindowBlocks 单个扫描窗口内的区块信息
+type evmWindowBlocks struct {
+	useBloom bool
+	blooms   map[uint64]evmBloom
+	times    map[uint64]time.Time
+}
+
+// newEVMWindowBlocks useBloom=false 时只缓存出块时间，CandidateRange 始终返回整段
+func newEVMWindowBlocks(useBloom bool) *evmWindowBlocks {
+	return &evmWindowBlocks{
+		useBloom: useBloom,
+		blooms:   map[uint64]evmBloom{},
+		times:    map[uint64]time.Time{},
+	}
+}
+
+// Record 记录 eth_getBlockByNumber 的结果（完整区块或区块头均可）
+func (w *evmWindowBlocks) Record(num uint64, blk map[string]any) {
+	if blk == nil {
+		return
+	}
+	if blk["timestamp"] != nil {
+		w.times[num] = parseBlockTime(blk)
+	}
+	if !w.useBloom {
+		return
+	}
+	raw, err := hexutil.Decode(str(blk["logsBloom"]))
+	if err != nil || len(raw) != len(evmBloom{}) {
+		return
+	}
+	w.blooms[num] = evmBloom(raw)
+}
+
+// Time 缓存的出块时间
+func (w *evmWindowBlocks) Time(num uint64) (time.Time, bool) {
+	t, ok := w.times[num]
+	return t, ok
+}
+
+// CandidateRange 返回 [from, to] 内可能含有 contract 与 addrs 相关 Transfer 日志的最小区间。
+// 只要有一个区块缺少 bloom 就不下结论，返回整段；ok=false 表示整段都可跳过。
+func (w *evmWindowBlocks) CandidateRange(from, to uint64, contract string, addrs []string) (lo, hi uint64, ok bool) {
+	if !w.useBloom || to < from {
+		return from, to, true
+	}
+	for b := from; b <= to; b++ {
+		if _, has := w.blooms[b]; !has {
+			return from, to, true
+		}
+	}
+	contractKey := common.HexToAddress(contract).Bytes()
+	addrTopics := make([][]byte, 0, len(addrs))
+	for _, a := range addrs {
+		if !common.IsHexAddress(strings.TrimSpace(a)) {
+			continue
+		}
+		addrTopics = append(addrTopics, common.BytesToHash(common.HexToAddress(a).Bytes()).Bytes())
+	}
+	found := false
+	for b := from; b <= to; b++ {
+		if !erc20BloomMatch(w.blooms[b], contractKey, addrTopics) {
+			continue
+		}
+		if !found {
+			lo, found = b, true
+		}
+		hi = b
+	}
+	return lo, hi, found
+}
+
+// erc20BloomMatch 区块 bloom 同时命中 Transfer 主题、合约地址与任一监控地址（32 字节左补零的 topic）
+func erc20BloomMatch(bloom evmBloom, contract []byte, addrTopics [][]byte) bool {
+	if !bloom.Test(transferTopic.Bytes()) || !bloom.Test(contract) {
+		return false
+	}
+	for _, t := range addrTopics {
+		if bloom.Test(t) {
+			return true
+		}
+	}
+	return false
+}
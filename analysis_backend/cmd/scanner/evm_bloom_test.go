@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	bloomUSDT    = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	bloomWatched = "0x28c6c06298d514db089934071355e5743bf21d60"
+	bloomOther   = "0x1111111111111111111111111111111111111111"
+)
+
+// transferBloom 一条 Transfer(from, to) 日志对应的 bloom
+func transferBloom(contract, from, to string) evmBloom {
+	var b evmBloom
+	b.Add(common.HexToAddress(contract).Bytes())
+	b.Add(transferTopic.Bytes())
+	b.Add(common.BytesToHash(common.HexToAddress(from).Bytes()).Bytes())
+	b.Add(common.BytesToHash(common.HexToAddress(to).Bytes()).Bytes())
+	return b
+}
+
+func blockWithBloom(ts string, b evmBloom) map[string]any {
+	return map[string]any{"timestamp": ts, "logsBloom": hexutil.Encode(b[:])}
+}
+
+func TestCandidateRangeSkipsIrrelevantBlocks(t *testing.T) {
+	w := newEVMWindowBlocks(true)
+	w.Record(100, blockWithBloom("0x1", evmBloom{}))
+	w.Record(101, blockWithBloom("0x2", transferBloom(bloomUSDT, bloomOther, bloomOther)))
+	w.Record(102, blockWithBloom("0x3", transferBloom(bloomUSDT, bloomOther, bloomWatched)))
+	w.Record(103, blockWithBloom("0x4", evmBloom{}))
+
+	lo, hi, ok := w.CandidateRange(100, 103, bloomUSDT, []string{bloomWatched})
+	if !ok || lo != 102 || hi != 102 {
+		t.Fatalf("want narrowed to 102-102, got %d-%d ok=%v", lo, hi, ok)
+	}
+
+	// 其他合约在整段都不出现 => 整段跳过
+	if _, _, ok := w.CandidateRange(100, 103, "0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48", []string{bloomWatched}); ok {
+		t.Fatal("unrelated contract should skip the whole window")
+	}
+	// 监控地址不在任何 bloom 中 => 整段跳过
+	if _, _, ok := w.CandidateRange(100, 101, bloomUSDT, []string{bloomWatched}); ok {
+		t.Fatal("blocks without watched address should be skipped")
+	}
+}
+
+func TestCandidateRangeFallsBackWhenInconclusive(t *testing.T) {
+	w := newEVMWindowBlocks(true)
+	w.Record(100, blockWithBloom("0x1", evmBloom{}))
+	// 101 未拉取（原生扫描关闭或失败）=> 不下结论
+	lo, hi, ok := w.CandidateRange(100, 101, bloomUSDT, []string{bloomWatched})
+	if !ok || lo != 100 || hi != 101 {
+		t.Fatalf("missing bloom should fall back to full range, got %d-%d ok=%v", lo, hi, ok)
+	}
+
+	// bloom 非法也视为缺失
+	w.Record(101, map[string]any{"timestamp": "0x2", "logsBloom": "0x00"})
+	if lo, hi, ok := w.CandidateRange(100, 101, bloomUSDT, []string{bloomWatched}); !ok || lo != 100 || hi != 101 {
+		t.Fatalf("invalid bloom should fall back to full range, got %d-%d ok=%v", lo, hi, ok)
+	}
+
+	// 关闭 bloom 预筛 => 始终整段
+	off := newEVMWindowBlocks(false)
+	off.Record(100, blockWithBloom("0x1", evmBloom{}))
+	if lo, hi, ok := off.CandidateRange(100, 100, bloomUSDT, []string{bloomWatched}); !ok || lo != 100 || hi != 100 {
+		t.Fatalf("disabled filter should return full range, got %d-%d ok=%v", lo, hi, ok)
+	}
+}
+
+func TestWindowBlocksCachesBlockTime(t *testing.T) {
+	w := newEVMWindowBlocks(false)
+	if _, ok := w.Time(7); ok {
+		t.Fatal("unexpected cached time")
+	}
+	w.Record(7, map[string]any{"timestamp": "0x5f5e100"})
+	ts, ok := w.Time(7)
+	if !ok || ts.Unix() != 100000000 {
+		t.Fatalf("want cached ts 100000000, got %v ok=%v", ts, ok)
+	}
+}
@@ -0,0 +1,121 @@
+// cmd/scanner/evm_logs.go
+// eth_getLogs 区块跨度自适应：服务商对单次结果数/区块跨度有限制（Infura/Alchemy/QuickNode 等报错文案各不相同），
+// 命中限制时把 [from,to] 二分重试（最小 1 个区块），并按端点记住成功的跨度，避免每轮重新试探；
+// 连续成功一段时间后跨度翻倍恢复，直到不再限制。
+
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+const (
+	evmLogWindowRestoreAfter = 100 // 按上限连续成功多少次后尝试把跨度翻倍
+	evmLogWindowUnlimited    = 500 // 跨度恢复到该值（扫描窗口大小）即视为不再限制
+)
+
+// evmLogRangeErrPatterns 各服务商“结果过多/跨度过大”的报错片段（小写）
+var evmLogRangeErrPatterns = []string{
+	"query returned more than",            // Infura / geth: query returned more than 10000 results
+	"response size exceeded",              // Alchemy: Log response size exceeded
+	"eth_getlogs is limited to",           // QuickNode: eth_getLogs is limited to a 10,000 range
+	"range is too large",                  // QuickNode / Ankr: block range is too large
+	"block range too large",               // 部分自建节点网关
+	"exceed maximum block range",          // BSC / Polygon 节点
+	"logs matched by query exceeds limit", // Erigon
+	"too many logs",
+}
+
+// isLogRangeTooLarge eth_getLogs 因结果过多/跨度过大被拒绝
+func isLogRangeTooLarge(err error) bool {
+	if err == nil {
+		return false
+	}
+	s := strings.ToLower(err.Error())
+	for _, p := range evmLogRangeErrPatterns {
+		if strings.Contains(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// evmLogWindows 各端点 eth_getLogs 的可用区块跨度（0 表示未发现限制），并发扫描任务共享
+type evmLogWindows struct {
+	mu     sync.Mutex
+	limit  map[string]uint64
+	streak map[string]int
+}
+
+func newEVMLogWindows() *evmLogWindows {
+	return &evmLogWindows{limit: map[string]uint64{}, streak: map[string]int{}}
+}
+
+// Limit 端点当前的跨度上限，0 表示不限
+func (w *evmLogWindows) Limit(endpoint string) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.limit[endpoint]
+}
+
+// Shrink span 个区块被拒绝后把上限降为 span/2（最小 1）
+func (w *evmLogWindows) Shrink(endpoint string, span uint64) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	next := span / 2
+	if next < 1 {
+		next = 1
+	}
+	if cur := w.limit[endpoint]; cur == 0 || next < cur {
+		w.limit[endpoint] = next
+	}
+	w.streak[endpoint] = 0
+	return w.limit[endpoint]
+}
+
+// Success 按上限查询成功；连续成功 evmLogWindowRestoreAfter 次后上限翻倍，恢复到扫描窗口大小时取消限制
+func (w *evmLogWindows) Success(endpoint string, span uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lim := w.limit[endpoint]
+	if lim == 0 || span < lim {
+		return
+	}
+	w.streak[endpoint]++
+	if w.streak[endpoint] < evmLogWindowRestoreAfter {
+		return
+	}
+	w.streak[endpoint] = 0
+	if lim*2 >= evmLogWindowUnlimited {
+		delete(w.limit, endpoint)
+		return
+	}
+	w.limit[endpoint] = lim * 2
+}
+
+// getLogsAdaptive 按端点已知上限把 [from,to] 切段查询；返回“结果过多”时二分重试，单个区块仍失败才返回错误。
+// endpoint 返回当前（或刚报错的）端点，get 查询一段区块
+func getLogsAdaptive(from, to uint64, windows *evmLogWindows, endpoint func() string,
+	get func(from, to uint64) ([]map[string]any, error)) ([]map[string]any, error) {
+	var out []map[string]any
+	for cur := from; cur <= to; {
+		ep := endpoint()
+		span := to - cur + 1
+		if lim := windows.Limit(ep); lim > 0 && span > lim {
+			span = lim
+		}
+		logs, err := get(cur, cur+span-1)
+		if err != nil {
+			if isLogRangeTooLarge(err) && span > 1 {
+				windows.Shrink(endpoint(), span)
+				continue
+			}
+			return out, err
+		}
+		windows.Success(ep, span)
+		out = append(out, logs...)
+		cur += span
+	}
+	return out, nil
+}
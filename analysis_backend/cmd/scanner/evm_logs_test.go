@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// mockLogsProvider 跨度超过 maxSpan 时返回服务商的“结果过多”错误，否则每个区块返回一条日志
+type mockLogsProvider struct {
+	maxSpan uint64
+	errMsg  string
+	calls   int
+	errors  int
+}
+
+func (m *mockLogsProvider) get(from, to uint64) ([]map[string]any, error) {
+	m.calls++
+	if to-from+1 > m.maxSpan {
+		m.errors++
+		return nil, fmt.Errorf("rpc eth_getLogs by https://rpc.example => rpc eth_getLogs error [-32005]: %s", m.errMsg)
+	}
+	var logs []map[string]any
+	for b := from; b <= to; b++ {
+		logs = append(logs, map[string]any{"blockNumber": fmt.Sprintf("0x%x", b)})
+	}
+	return logs, nil
+}
+
+// TestGetLogsAdaptiveConvergesTo256 超过 256 个区块报错时二分收敛到 256，并按端点记住，下一轮不再试探
+func TestGetLogsAdaptiveConvergesTo256(t *testing.T) {
+	m := &mockLogsProvider{maxSpan: 256, errMsg: "query returned more than 10000 results"}
+	w := newEVMLogWindows()
+	ep := func() string { return "https://rpc.example" }
+
+	logs, err := getLogsAdaptive(0, 1023, w, ep, m.get)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1024 {
+		t.Fatalf("期望 1024 条日志，实际 %d", len(logs))
+	}
+	if got := w.Limit("https://rpc.example"); got != 256 {
+		t.Fatalf("跨度应收敛到 256，实际 %d", got)
+	}
+	if m.errors != 2 {
+		t.Errorf("1024 -> 512 -> 256 期望报错 2 次，实际 %d", m.errors)
+	}
+	for i := 1; i < len(logs); i++ {
+		if hexToUint64(str(logs[i]["blockNumber"])) != uint64(i) {
+			t.Fatalf("日志顺序错乱: 第 %d 条为 %v", i, logs[i]["blockNumber"])
+		}
+	}
+
+	m.calls, m.errors = 0, 0
+	if _, err := getLogsAdaptive(1024, 2047, w, ep, m.get); err != nil {
+		t.Fatal(err)
+	}
+	if m.errors != 0 || m.calls != 4 {
+		t.Errorf("已知上限后应直接按 256 切段：调用 %d 次、报错 %d 次", m.calls, m.errors)
+	}
+	if w.Limit("https://other.example") != 0 {
+		t.Error("其它端点不受影响")
+	}
+}
+
+// TestGetLogsAdaptiveMinimumOneBlock 单个区块仍超限时返回错误；其它错误不重试
+func TestGetLogsAdaptiveMinimumOneBlock(t *testing.T) {
+	m := &mockLogsProvider{maxSpan: 0, errMsg: "Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"}
+	w := newEVMLogWindows()
+	if _, err := getLogsAdaptive(10, 17, w, func() string { return "a" }, m.get); !isLogRangeTooLarge(err) {
+		t.Fatalf("单块仍超限应返回原错误，实际 %v", err)
+	}
+	if w.Limit("a") != 1 || m.calls != 4 {
+		t.Errorf("8 -> 4 -> 2 -> 1 期望调用 4 次、上限 1，实际调用 %d 次、上限 %d", m.calls, w.Limit("a"))
+	}
+
+	calls := 0
+	_, err := getLogsAdaptive(0, 99, newEVMLogWindows(), func() string { return "a" }, func(from, to uint64) ([]map[string]any, error) {
+		calls++
+		return nil, errors.New("connection refused")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("非跨度错误应直接返回，调用 %d 次: %v", calls, err)
+	}
+}
+
+// TestEVMLogWindowRestore 连续成功后跨度翻倍，恢复到扫描窗口大小时取消限制
+func TestEVMLogWindowRestore(t *testing.T) {
+	w := newEVMLogWindows()
+	w.Shrink("a", 256) // -> 128
+	for i := 0; i < evmLogWindowRestoreAfter; i++ {
+		w.Success("a", 128)
+	}
+	if w.Limit("a") != 256 {
+		t.Fatalf("连续成功后应翻倍到 256，实际 %d", w.Limit("a"))
+	}
+	w.Success("a", 10) // 小于上限的查询不计入
+	for i := 0; i < evmLogWindowRestoreAfter-1; i++ {
+		w.Success("a", 256)
+	}
+	if w.Limit("a") != 256 {
+		t.Fatalf("未达到连续次数不应恢复，实际 %d", w.Limit("a"))
+	}
+	w.Success("a", 256)
+	if w.Limit("a") != 0 {
+		t.Errorf("恢复到扫描窗口大小后应取消限制，实际 %d", w.Limit("a"))
+	}
+}
+
+// TestIsLogRangeTooLarge 常见服务商报错文案
+func TestIsLogRangeTooLarge(t *testing.T) {
+	for _, msg := range []string{
+		"query returned more than 10000 results",
+		"Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range and no limit on the response size",
+		"eth_getLogs is limited to a 10,000 range",
+		"block range is too large",
+		"exceed maximum block range: 5000",
+	} {
+		if !isLogRangeTooLarge(errors.New(msg)) {
+			t.Errorf("应识别: %q", msg)
+		}
+	}
+	if isLogRangeTooLarge(errors.New("execution reverted")) || isLogRangeTooLarge(nil) {
+		t.Error("其它错误不应识别为跨度过大")
+	}
+}
@@ -0,0 +1,121 @@
+// cmd/scanner/evm_native.go
+// EVM 原生币转账事件与跨窗口去重。
+// 原生币事件没有链上 logIndex（顶层为 -1，内部转账为 -(2+序号)），窗口因下发失败等原因重扫时，
+// 用每个实体已成功下发的 txHash#logIndex|方向|区块哈希 跳过重复事件；区块哈希变化（重组）的事件照常重新下发。
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"analysis/internal/models"
+)
+
+// evmNativeSeenKeep 去重记录保留的区块数（远大于重组回退与断线回补的范围）
+const evmNativeSeenKeep = 5000
+
+// evmTransferLeg 一笔转账命中监控地址的一侧
+type evmTransferLeg struct {
+	dir, address string
+}
+
+// evmTransferLegs from/to 分别命中时各记一侧；两端都是监控地址（自有地址互转）时同时记 out 与 in
+func evmTransferLegs(from, to string, addrSet map[string]bool) []evmTransferLeg {
+	var legs []evmTransferLeg
+	if from != "" && addrSet[from] {
+		legs = append(legs, evmTransferLeg{dir: "out", address: from})
+	}
+	if to != "" && addrSet[to] {
+		legs = append(legs, evmTransferLeg{dir: "in", address: to})
+	}
+	return legs
+}
+
+// evmNativeTxEvents 区块内顶层交易的原生币转账（tx.value）转换为事件
+func evmNativeTxEvents(txs []any, entity, chain, symbol string, addrSet map[string]bool, ts time.Time, blockNum uint64, blockHash string) []models.Event {
+	var out []models.Event
+	for _, it := range txs {
+		tx, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		valHex := str(tx["value"])
+		if valHex == "" {
+			continue
+		}
+		wei := new(big.Int)
+		_, _ = wei.SetString(strings.TrimPrefix(valHex, "0x"), 16)
+		if txFilter.skipZero(wei.Sign()) {
+			continue
+		}
+		from := strings.ToLower(str(tx["from"]))
+		toA := strings.ToLower(str(tx["to"]))
+		amt := toDecimal(wei, 18)
+		for _, leg := range evmTransferLegs(from, toA, addrSet) {
+			out = append(out, models.Event{
+				Entity: entity, Chain: chain, Coin: symbol, AssetID: models.AssetIDFor(chain, ""), Direction: leg.dir, Amount: amt,
+				TS: ts, TxID: str(tx["hash"]), From: from, To: toA, Address: leg.address, LogIndex: -1,
+				BlockNumber: blockNum, BlockHash: blockHash,
+			})
+		}
+	}
+	return out
+}
+
+// evmNativeSeen 单个实体已成功下发的原生币事件（键 -> 区块高度）
+type evmNativeSeen struct {
+	keys map[string]uint64
+}
+
+func newEVMNativeSeen() *evmNativeSeen {
+	return &evmNativeSeen{keys: map[string]uint64{}}
+}
+
+// evmNativeKey 原生币事件的去重键；ERC20 事件（logIndex>=0）不参与
+func evmNativeKey(e models.Event) (string, bool) {
+	if e.LogIndex >= 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%s#%d|%s|%s|%s", strings.ToLower(e.TxID), e.LogIndex, e.Direction, strings.ToLower(e.Address), e.BlockHash), true
+}
+
+// Filter 去掉已下发过的原生币事件以及本批内的重复事件
+func (s *evmNativeSeen) Filter(events []models.Event) []models.Event {
+	out := events[:0:0]
+	batch := map[string]bool{}
+	for _, e := range events {
+		if k, ok := evmNativeKey(e); ok {
+			if _, dup := s.keys[k]; dup || batch[k] {
+				continue
+			}
+			batch[k] = true
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Commit 下发成功后记录本批原生币事件
+func (s *evmNativeSeen) Commit(events []models.Event) {
+	for _, e := range events {
+		if k, ok := evmNativeKey(e); ok {
+			s.keys[k] = e.BlockNumber
+		}
+	}
+}
+
+// Prune 丢弃 next 之前 evmNativeSeenKeep 个区块以外的记录
+func (s *evmNativeSeen) Prune(next uint64) {
+	if next <= evmNativeSeenKeep {
+		return
+	}
+	floor := next - evmNativeSeenKeep
+	for k, n := range s.keys {
+		if n < floor {
+			delete(s.keys, k)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+const (
+	nativeHot  = "0x00000000000000000000000000000000000000a1"
+	nativeCold = "0x00000000000000000000000000000000000000a2"
+	nativeUser = "0x00000000000000000000000000000000000000e1"
+)
+
+func nativeTestBlock() []any {
+	return []any{
+		map[string]any{"hash": "0xT1", "from": nativeUser, "to": nativeHot, "value": "0xde0b6b3a7640000"},  // 外部转入 1 ETH
+		map[string]any{"hash": "0xT2", "from": nativeHot, "to": nativeCold, "value": "0x1bc16d674ec80000"}, // 热钱包转冷钱包 2 ETH
+		map[string]any{"hash": "0xT3", "from": nativeHot, "to": nativeUser, "value": "0x0"},
+	}
+}
+
+// TestEVMNativeSelfTransferLegs 两端都是监控地址时同时记 out 与 in
+func TestEVMNativeSelfTransferLegs(t *testing.T) {
+	addrSet := map[string]bool{nativeHot: true, nativeCold: true}
+	events := evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100")
+	if len(events) != 3 {
+		t.Fatalf("期望 3 条事件（T1 in，T2 out+in），实际 %+v", events)
+	}
+	if e := events[0]; e.TxID != "0xT1" || e.Direction != "in" || e.Address != nativeHot || e.LogIndex != -1 {
+		t.Errorf("T1 不符: %+v", e)
+	}
+	if e := events[1]; e.TxID != "0xT2" || e.Direction != "out" || e.Address != nativeHot || e.Amount != "2.00000000" {
+		t.Errorf("T2 转出侧不符: %+v", e)
+	}
+	if e := events[2]; e.TxID != "0xT2" || e.Direction != "in" || e.Address != nativeCold {
+		t.Errorf("T2 转入侧不符: %+v", e)
+	}
+
+	internal := evmInternalTransferEvents([]evmInternalTransfer{{TxHash: "0xt4", From: nativeCold, To: nativeHot, Value: hexBig("0x1")}},
+		"binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100")
+	if len(internal) != 2 || internal[0].Direction != "out" || internal[1].Direction != "in" {
+		t.Errorf("内部自有地址互转也应记两侧: %+v", internal)
+	}
+}
+
+// TestEVMNativeSeenReplay 同一区块扫描两次，每笔转账只下发一次；下发失败未记录时重扫照常下发
+func TestEVMNativeSeenReplay(t *testing.T) {
+	addrSet := map[string]bool{nativeHot: true, nativeCold: true}
+	seen := newEVMNativeSeen()
+
+	first := seen.Filter(evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100"))
+	if len(first) != 3 {
+		t.Fatalf("首次扫描应下发 3 条，实际 %d", len(first))
+	}
+	// 下发失败：不记录，重扫仍应下发
+	retry := seen.Filter(evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100"))
+	if len(retry) != 3 {
+		t.Fatalf("未提交时重扫应再次下发，实际 %d", len(retry))
+	}
+	seen.Commit(retry)
+
+	// 窗口重叠：同一区块再次扫描，同一批内的重复也只保留一条
+	replay := evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100")
+	if got := seen.Filter(replay); len(got) != 0 {
+		t.Fatalf("已下发的事件不应重复，实际 %+v", got)
+	}
+	dup := append(evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 101, "0xb101"),
+		evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 101, "0xb101")...)
+	if got := seen.Filter(dup); len(got) != 3 {
+		t.Fatalf("批内重复应只保留一条/笔，实际 %d", len(got))
+	}
+
+	// 重组后同一笔交易进了新区块（哈希不同），照常下发
+	reorged := evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100x")
+	if got := seen.Filter(reorged); len(got) != 3 {
+		t.Fatalf("重组后的新区块事件应下发，实际 %d", len(got))
+	}
+
+	seen.Prune(100 + evmNativeSeenKeep + 1)
+	if got := seen.Filter(replay); len(got) != 3 {
+		t.Errorf("超出保留范围的记录应清理，实际 %d", len(got))
+	}
+}
@@ -0,0 +1,130 @@
+// cmd/scanner/evm_pool.go
+// EVM 按实体并发扫描：有界协程池、按 (链, 实体) 的游标与 RPC 端点轮换位置、跨协程共享的每端点限速。
+// 同一 (链, 实体) 每轮只提交一个任务，窗口内事件仍按原顺序生成与下发，游标写入按 (链, 实体) 串行。
+
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WorkerPool 协程池，用于限制并发数量（与 cmd/investment 相同的用法）
+type WorkerPool struct {
+	maxWorkers int
+	workers    chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewWorkerPool 创建协程池
+// maxWorkers: 最大并发数，<=0 时按 1 处理（串行）
+func NewWorkerPool(maxWorkers int) *WorkerPool {
+	if maxWorkers <= 0 {
+		maxWorkers = 1
+	}
+	return &WorkerPool{maxWorkers: maxWorkers, workers: make(chan struct{}, maxWorkers)}
+}
+
+// Submit 提交任务，无空闲槽位时阻塞等待
+func (wp *WorkerPool) Submit(task func()) {
+	wp.workers <- struct{}{}
+	wp.wg.Add(1)
+	go func() {
+		defer wp.wg.Done()
+		defer func() { <-wp.workers }()
+		task()
+	}()
+}
+
+// Wait 等待所有任务完成
+func (wp *WorkerPool) Wait() {
+	wp.wg.Wait()
+}
+
+// evmScanState 各 (链, 实体) 的扫描游标与 RPC 端点轮换位置，供并发的扫描任务读写
+type evmScanState struct {
+	mu     sync.Mutex
+	cursor map[string]map[string]uint64 // chain->entity->block
+	rpcIdx map[string]map[string]int    // chain->entity->上次成功的端点下标
+}
+
+func newEVMScanState() *evmScanState {
+	return &evmScanState{cursor: map[string]map[string]uint64{}, rpcIdx: map[string]map[string]int{}}
+}
+
+func (s *evmScanState) Cursor(chain, entity string) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cursor[chain][entity]
+}
+
+func (s *evmScanState) SetCursor(chain, entity string, block uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cursor[chain] == nil {
+		s.cursor[chain] = map[string]uint64{}
+	}
+	s.cursor[chain][entity] = block
+}
+
+func (s *evmScanState) RPCIdx(chain, entity string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rpcIdx[chain][entity]
+}
+
+func (s *evmScanState) SetRPCIdx(chain, entity string, idx int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rpcIdx[chain] == nil {
+		s.rpcIdx[chain] = map[string]int{}
+	}
+	s.rpcIdx[chain][entity] = idx
+}
+
+// evmEndpointPacer 每端点限速（请求间隔），所有扫描任务共享，并发不会放大单个端点的请求频率
+type evmEndpointPacer struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     map[string]time.Time // endpoint -> 下一个可用时刻
+}
+
+// newEVMEndpointPacer rps<=0 不限速
+func newEVMEndpointPacer(rps float64) *evmEndpointPacer {
+	p := &evmEndpointPacer{next: map[string]time.Time{}}
+	if rps > 0 {
+		p.interval = time.Duration(float64(time.Second) / rps)
+	}
+	return p
+}
+
+// Wait 预占端点的下一个请求时刻并等待到该时刻；ctx 取消时提前返回错误
+func (p *evmEndpointPacer) Wait(ctx context.Context, endpoint string) error {
+	if p == nil || p.interval <= 0 {
+		return nil
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next[endpoint]
+	if at.Before(now) {
+		at = now
+	}
+	p.next[endpoint] = at.Add(p.interval)
+	p.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return nil
+	}
+	t := time.NewTimer(wait)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newMockEVMRPC 每次调用固定延迟的模拟 RPC，返回 eth_blockNumber 风格的结果
+func newMockEVMRPC(t testing.TB, latency time.Duration) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(latency)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x64"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// scanMockEntities 与扫描循环相同的提交方式：每个 (链, 实体) 一个任务，窗口内按顺序发 rpcPerWindow 次调用后推进游标
+func scanMockEntities(t testing.TB, url string, concurrency, rpcPerWindow int, entities []string, state *evmScanState) {
+	pool := NewWorkerPool(concurrency)
+	for _, entity := range entities {
+		pool.Submit(func() {
+			cur := state.Cursor("ethereum", entity)
+			for i := 0; i < rpcPerWindow; i++ {
+				var out rpcResp
+				if err := postRPC(context.Background(), url, "eth_getBlockByNumber", []interface{}{"0x64", false}, &out); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+			state.SetCursor("ethereum", entity, cur+uint64(rpcPerWindow))
+		})
+	}
+	pool.Wait()
+}
+
+var benchEntities = []string{"binance", "okx", "bybit"}
+
+func benchmarkEVMEntityScan(b *testing.B, concurrency int) {
+	srv, _ := newMockEVMRPC(b, 2*time.Millisecond)
+	state := newEVMScanState()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanMockEntities(b, srv.URL, concurrency, 10, benchEntities, state)
+	}
+}
+
+// BenchmarkEVMEntityScanSequential 3 个实体串行扫描（-evm-concurrency=1）
+func BenchmarkEVMEntityScanSequential(b *testing.B) { benchmarkEVMEntityScan(b, 1) }
+
+// BenchmarkEVMEntityScanConcurrent 3 个实体并发扫描（默认 -evm-concurrency=4），约为串行耗时的 1/3
+func BenchmarkEVMEntityScanConcurrent(b *testing.B) { benchmarkEVMEntityScan(b, 4) }
+
+// TestEVMEntityScanConcurrentSpeedup 3 个实体并发扫描明显快于串行，且每个实体的游标只推进自己的窗口
+func TestEVMEntityScanConcurrentSpeedup(t *testing.T) {
+	srv, calls := newMockEVMRPC(t, 20*time.Millisecond)
+
+	run := func(concurrency int) (time.Duration, *evmScanState) {
+		state := newEVMScanState()
+		start := time.Now()
+		scanMockEntities(t, srv.URL, concurrency, 3, benchEntities, state)
+		return time.Since(start), state
+	}
+	seq, _ := run(1)
+	par, state := run(4)
+	if par*2 > seq {
+		t.Errorf("并发扫描应明显快于串行: sequential=%s concurrent=%s", seq, par)
+	}
+	for _, e := range benchEntities {
+		if got := state.Cursor("ethereum", e); got != 3 {
+			t.Errorf("entity=%s 游标应推进到 3，实际 %d", e, got)
+		}
+	}
+	if calls.Load() != 18 {
+		t.Errorf("期望 18 次 RPC 调用，实际 %d", calls.Load())
+	}
+}
+
+// TestWorkerPoolBounded 同时运行的任务数不超过上限
+func TestWorkerPoolBounded(t *testing.T) {
+	pool := NewWorkerPool(2)
+	var running, peak atomic.Int32
+	for i := 0; i < 8; i++ {
+		pool.Submit(func() {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		})
+	}
+	pool.Wait()
+	if peak.Load() != 2 {
+		t.Errorf("期望最多 2 个任务同时运行，实际 %d", peak.Load())
+	}
+}
+
+// TestEVMEndpointPacerShared 并发任务共享同一端点的限速，不因并发放大请求频率
+func TestEVMEndpointPacerShared(t *testing.T) {
+	p := newEVMEndpointPacer(50) // 每 20ms 一次
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Wait(context.Background(), "http://rpc.example/"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if d := time.Since(start); d < 55*time.Millisecond {
+		t.Errorf("4 次调用同一端点至少间隔 3 个周期，实际 %s", d)
+	}
+	// 不同端点互不影响
+	start = time.Now()
+	_ = p.Wait(context.Background(), "http://other.example")
+	if d := time.Since(start); d > 10*time.Millisecond {
+		t.Errorf("其它端点不应等待，实际 %s", d)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = p.Wait(context.Background(), "http://rpc.example")
+	if err := p.Wait(ctx, "http://rpc.example"); err == nil {
+		t.Error("ctx 取消时应返回错误")
+	}
+}
+
+// TestEVMScanStatePerEntity 游标与端点下标按 (链, 实体) 独立保存
+func TestEVMScanStatePerEntity(t *testing.T) {
+	s := newEVMScanState()
+	s.SetCursor("ethereum", "binance", 100)
+	s.SetRPCIdx("ethereum", "binance", 2)
+	s.SetRPCIdx("ethereum", "okx", 1)
+	if s.Cursor("ethereum", "binance") != 100 || s.Cursor("ethereum", "okx") != 0 || s.Cursor("bsc", "binance") != 0 {
+		t.Error("游标应按 (链, 实体) 独立")
+	}
+	if s.RPCIdx("ethereum", "binance") != 2 || s.RPCIdx("ethereum", "okx") != 1 {
+		t.Error("端点下标应按 (链, 实体) 独立")
+	}
+}
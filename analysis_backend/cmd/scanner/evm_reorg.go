@@ -0,0 +1,167 @@
+// cmd/scanner/evm_reorg.go
+// EVM 重组保护：记录已扫描高度的规范区块哈希，新窗口开始前校验 cur 的 parentHash 是否仍等于 cur-1 的记录；
+// 不一致说明链已重组，游标回退 reorg-depth 个区块重扫，重新下发的事件带新的区块哈希，由 API 替换孤块事件。
+// 窗口内同一高度出现两个不同哈希（扫描途中发生重组）时整窗不提交，下一轮重扫。
+// 回退前已扫描过的区间重扫时，连同每个高度的规范哈希上报 API（/ingest/canonical），
+// 孤块上的交易没有被重新打包、重扫不产生事件时，旧事件也会被删除。
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultEVMReorgDepth 默认回退深度
+const defaultEVMReorgDepth = 12
+
+// evmReorgGuard 单条链、单个实体的区块哈希记录（游标按实体推进，各自校验）
+type evmReorgGuard struct {
+	depth  uint64            // 回退深度，0 表示不做重组检查
+	hashes map[uint64]string // 高度 -> 规范区块哈希（小写）
+
+	// 回退后待向 API 校正的已扫描区间 [staleFrom, staleTo]，这些高度上可能留有孤块事件
+	stale              bool
+	staleFrom, staleTo uint64
+}
+
+func newEVMReorgGuard(depth uint64) *evmReorgGuard {
+	return &evmReorgGuard{depth: depth, hashes: map[uint64]string{}}
+}
+
+// Observe 记录某高度的区块哈希；与已记录的哈希冲突时返回错误（不覆盖）
+func (g *evmReorgGuard) Observe(height uint64, hash string) error {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if g.depth == 0 || hash == "" {
+		return nil
+	}
+	if old, ok := g.hashes[height]; ok && old != hash {
+		return fmt.Errorf("block %d hash changed %s -> %s", height, old, hash)
+	}
+	g.hashes[height] = hash
+	return nil
+}
+
+// Check 新窗口开始前校验 cur 的父哈希。返回实际应扫描的起点：
+// 未重组或无记录时为 cur；父哈希不一致时回退 depth 个区块，并丢弃回退点及之后的记录
+func (g *evmReorgGuard) Check(cur uint64, parentHash string) (uint64, bool) {
+	if g.depth == 0 || cur == 0 {
+		return cur, false
+	}
+	want, ok := g.hashes[cur-1]
+	if !ok || strings.EqualFold(want, strings.TrimSpace(parentHash)) {
+		return cur, false
+	}
+	rewind := uint64(0)
+	if cur > g.depth {
+		rewind = cur - g.depth
+	}
+	g.Forget(rewind)
+	if g.stale {
+		g.staleFrom, g.staleTo = min(g.staleFrom, rewind), max(g.staleTo, cur-1)
+	} else {
+		g.stale, g.staleFrom, g.staleTo = true, rewind, cur-1
+	}
+	return rewind, true
+}
+
+// StaleRange 窗口 [from, to] 与待校正区间的交集
+func (g *evmReorgGuard) StaleRange(from, to uint64) (uint64, uint64, bool) {
+	if !g.stale || to < g.staleFrom || from > g.staleTo {
+		return 0, 0, false
+	}
+	return max(from, g.staleFrom), min(to, g.staleTo), true
+}
+
+// ClearStale 校正已提交到 upTo（含），待校正区间从 upTo+1 开始
+func (g *evmReorgGuard) ClearStale(upTo uint64) {
+	if !g.stale || upTo < g.staleFrom {
+		return
+	}
+	if upTo >= g.staleTo {
+		g.stale = false
+		return
+	}
+	g.staleFrom = upTo + 1
+}
+
+// CanonicalHashes [from, to] 每个高度的规范区块哈希：已记录的直接使用，缺的用 fetch 拉取并记录
+func (g *evmReorgGuard) CanonicalHashes(from, to uint64, fetch func(height uint64) (string, error)) (map[uint64]string, error) {
+	out := make(map[uint64]string, to-from+1)
+	for n := from; n <= to; n++ {
+		if h, ok := g.hashes[n]; ok {
+			out[n] = h
+			continue
+		}
+		h, err := fetch(n)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", n, err)
+		}
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "" {
+			return nil, fmt.Errorf("block %d: empty hash", n)
+		}
+		g.hashes[n] = h
+		out[n] = h
+	}
+	return out, nil
+}
+
+// Enabled 是否做重组检查
+func (g *evmReorgGuard) Enabled() bool { return g.depth > 0 }
+
+// Has 是否已记录该高度的哈希
+func (g *evmReorgGuard) Has(height uint64) bool {
+	_, ok := g.hashes[height]
+	return ok
+}
+
+// NeedsParentCheck cur-1 有记录时才需要拉 cur 的区块头做校验
+func (g *evmReorgGuard) NeedsParentCheck(cur uint64) bool {
+	return g.Enabled() && cur > 0 && g.Has(cur-1)
+}
+
+// Forget 丢弃 from 及之后高度的记录
+func (g *evmReorgGuard) Forget(from uint64) {
+	for h := range g.hashes {
+		if h >= from {
+			delete(g.hashes, h)
+		}
+	}
+}
+
+// Prune 只保留 next 之前 depth 个高度的记录（更早的区块不会再被回退）
+func (g *evmReorgGuard) Prune(next uint64) {
+	for h := range g.hashes {
+		if h+g.depth < next {
+			delete(g.hashes, h)
+		}
+	}
+}
+
+// parseReorgDepths 解析按链覆盖的回退深度，如 "polygon=128,arbitrum=0"（0 表示该链不检查）
+func parseReorgDepths(s string) (map[string]uint64, error) {
+	out := map[string]uint64{}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		k, v, ok := strings.Cut(part, "=")
+		k = strings.ToLower(strings.TrimSpace(k))
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid reorg depth %q (want chain=depth)", part)
+		}
+		d, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reorg depth %q: must be a non-negative integer", part)
+		}
+		out[k] = d
+	}
+	return out, nil
+}
+
+// reorgDepthFor 链的回退深度：按链覆盖优先，否则用默认值
+func reorgDepthFor(chain string, perChain map[string]uint64, def uint64) uint64 {
+	if d, ok := perChain[strings.ToLower(chain)]; ok {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"analysis/internal/models"
+)
+
+// reorgChain 模拟链：每个高度一个区块哈希，fork 后从某高度起换成新分叉的哈希
+type reorgChain struct {
+	fork   string
+	forkAt uint64
+	tip    uint64
+	txAt   map[uint64]string // 高度 -> 该高度上监控地址的交易
+}
+
+func (c *reorgChain) hash(n uint64) string {
+	if c.fork != "" && n >= c.forkAt {
+		return fmt.Sprintf("0x%s%d", c.fork, n)
+	}
+	return fmt.Sprintf("0xa%d", n)
+}
+
+func (c *reorgChain) parentHash(n uint64) string { return c.hash(n - 1) }
+
+// scanEVMWindow 与主循环相同的步骤：校验父哈希（必要时回退）→ 扫描窗口并记录哈希 → 提交并推进游标
+func scanEVMWindow(g *evmReorgGuard, c *reorgChain, cur uint64, step uint64) (next uint64, events []models.Event, rewound bool) {
+	if g.NeedsParentCheck(cur) {
+		cur, rewound = g.Check(cur, c.parentHash(cur))
+	}
+	to := cur + step - 1
+	if to > c.tip {
+		to = c.tip
+	}
+	for n := cur; n <= to; n++ {
+		h := c.hash(n)
+		if err := g.Observe(n, h); err != nil {
+			panic(err)
+		}
+		if tx, ok := c.txAt[n]; ok {
+			events = append(events, models.Event{TxID: tx, BlockNumber: n, BlockHash: h, LogIndex: -1})
+		}
+	}
+	g.Prune(to + 1)
+	return to + 1, events, rewound
+}
+
+// TestEVMReorgRewindsAndReemits 已扫描区块被重组后，下一窗口发现父哈希不符，回退 depth 个区块并带新哈希重新下发
+func TestEVMReorgRewindsAndReemits(t *testing.T) {
+	chain := &reorgChain{tip: 20, txAt: map[uint64]string{8: "0xt8", 12: "0xt12", 19: "0xt19"}}
+	g := newEVMReorgGuard(6)
+
+	cur, first, _ := scanEVMWindow(g, chain, 1, 10)
+	if cur != 11 || len(first) != 1 || first[0].BlockHash != "0xa8" {
+		t.Fatalf("首个窗口不符: next=%d events=%+v", cur, first)
+	}
+
+	// 9 号及之后的区块被替换
+	chain.fork, chain.forkAt = "b", 9
+	cur, again, rewound := scanEVMWindow(g, chain, cur, 10)
+	if !rewound {
+		t.Fatal("期望检测到重组并回退")
+	}
+	if cur != 15 {
+		t.Fatalf("期望从 11-6=5 重扫到 14，下一游标 15，实际 %d", cur)
+	}
+	got := map[string]string{}
+	for _, e := range again {
+		got[e.TxID] = e.BlockHash
+	}
+	if got["0xt8"] != "0xa8" {
+		t.Errorf("回退区间内未重组的事件应原样重新下发，实际 %v", got)
+	}
+	if got["0xt12"] != "0xb12" {
+		t.Errorf("重组后的事件应带新分叉哈希，实际 %v", got)
+	}
+
+	// 重扫后哈希已更新，继续扫描不再回退
+	if _, rest, rewound := scanEVMWindow(g, chain, cur, 10); rewound || len(rest) != 1 || rest[0].BlockHash != "0xb19" {
+		t.Fatalf("重扫后应正常推进: rewound=%v events=%+v", rewound, rest)
+	}
+}
+
+// TestEVMReorgStaleRangeCanonicalHashes 回退后重扫的旧区间即使没有事件，也要取到每个高度的规范哈希并上报，分窗口提交时逐段清除
+func TestEVMReorgStaleRangeCanonicalHashes(t *testing.T) {
+	chain := &reorgChain{tip: 30, txAt: map[uint64]string{18: "0xt18"}}
+	g := newEVMReorgGuard(6)
+	cur, _, _ := scanEVMWindow(g, chain, 1, 10)
+	cur, events, _ := scanEVMWindow(g, chain, cur, 10)
+	if cur != 21 || len(events) != 1 || events[0].BlockHash != "0xa18" {
+		t.Fatalf("首轮扫描不符: next=%d events=%+v", cur, events)
+	}
+	if _, _, ok := g.StaleRange(1, 30); ok {
+		t.Fatal("未发生重组时不应有待校正区间")
+	}
+
+	// 17 号起被替换，0xt18 没有被重新打包：重扫 15..20 不会产生任何事件
+	chain.fork, chain.forkAt = "b", 17
+	delete(chain.txAt, 18)
+	rewind, reorged := g.Check(cur, chain.parentHash(cur))
+	if !reorged || rewind != 15 {
+		t.Fatalf("期望回退到 15，实际 %d %v", rewind, reorged)
+	}
+
+	// 第一个重扫窗口只覆盖 15..17
+	from, to, ok := g.StaleRange(rewind, 17)
+	if !ok || from != 15 || to != 17 {
+		t.Fatalf("待校正区间期望 15..17，实际 %d..%d %v", from, to, ok)
+	}
+	fetched := 0
+	fetch := func(n uint64) (string, error) {
+		fetched++
+		return chain.hash(n), nil
+	}
+	hashes, err := g.CanonicalHashes(from, to, fetch)
+	if err != nil || len(hashes) != 3 || hashes[16] != "0xa16" || hashes[17] != "0xb17" || fetched != 3 {
+		t.Fatalf("规范哈希不符: %v fetched=%d err=%v", hashes, fetched, err)
+	}
+	g.ClearStale(to)
+
+	// 第二个窗口 18..27：待校正区间只剩 18..20，已记录的高度不再重复拉取
+	if err := g.Observe(19, chain.hash(19)); err != nil {
+		t.Fatal(err)
+	}
+	from, to, ok = g.StaleRange(18, 27)
+	if !ok || from != 18 || to != 20 {
+		t.Fatalf("待校正区间期望 18..20，实际 %d..%d %v", from, to, ok)
+	}
+	fetched = 0
+	hashes, err = g.CanonicalHashes(from, to, fetch)
+	if err != nil || hashes[18] != "0xb18" || hashes[19] != "0xb19" || fetched != 2 {
+		t.Fatalf("规范哈希不符: %v fetched=%d err=%v", hashes, fetched, err)
+	}
+	g.ClearStale(to)
+	if _, _, ok := g.StaleRange(1, 30); ok {
+		t.Error("全部校正后不应再有待校正区间")
+	}
+	// 拉取的哈希已记录，窗口内再观察到不同哈希视为扫描途中重组
+	if err := g.Observe(18, "0xc18"); err == nil {
+		t.Error("拉取的哈希应被记录")
+	}
+}
+
+// TestPostCanonicalBlocks 上报的区间与每个高度的哈希；dry-run 不上报
+func TestPostCanonicalBlocks(t *testing.T) {
+	var got struct {
+		From   uint64            `json:"from"`
+		To     uint64            `json:"to"`
+		Hashes map[uint64]string `json:"hashes"`
+	}
+	var query string
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		query = r.URL.Path + "?" + r.URL.RawQuery
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"ok":true,"deleted":1}`))
+	}))
+	defer srv.Close()
+
+	hashes := map[uint64]string{15: "0xa15", 16: "0xb16"}
+	if err := canonicalPoster(srv.URL, true)(context.Background(), "acme", "ethereum", 15, 16, hashes); err != nil || calls != 0 {
+		t.Fatalf("dry-run 不应上报: calls=%d err=%v", calls, err)
+	}
+	if err := canonicalPoster(srv.URL+"/", false)(context.Background(), "acme", "ethereum", 15, 16, hashes); err != nil {
+		t.Fatal(err)
+	}
+	if query != "/ingest/canonical?entity=acme&chain=ethereum" || got.From != 15 || got.To != 16 || got.Hashes[16] != "0xb16" || len(got.Hashes) != 2 {
+		t.Errorf("上报内容不符: %s %+v", query, got)
+	}
+}
+
+// TestEVMReorgGuardObserveConflict 同一高度观察到不同哈希时报错，不覆盖原记录
+func TestEVMReorgGuardObserveConflict(t *testing.T) {
+	g := newEVMReorgGuard(12)
+	if err := g.Observe(5, "0xAA"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Observe(5, "0xaa"); err != nil {
+		t.Fatalf("大小写不同不算冲突: %v", err)
+	}
+	if err := g.Observe(5, "0xbb"); err == nil {
+		t.Fatal("期望哈希冲突报错")
+	}
+
+	off := newEVMReorgGuard(0)
+	off.Observe(5, "0xaa")
+	if off.NeedsParentCheck(6) {
+		t.Error("depth=0 不应做重组检查")
+	}
+	if next, rewound := off.Check(6, "0xother"); rewound || next != 6 {
+		t.Errorf("depth=0 不应回退，实际 %d %v", next, rewound)
+	}
+}
+
+// TestParseReorgDepths 按链覆盖回退深度，未覆盖的链用默认值
+func TestParseReorgDepths(t *testing.T) {
+	m, err := parseReorgDepths("Polygon=128, arbitrum=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reorgDepthFor("polygon", m, 12) != 128 || reorgDepthFor("arbitrum", m, 12) != 0 || reorgDepthFor("ethereum", m, 12) != 12 {
+		t.Fatalf("回退深度不符: %v", m)
+	}
+	for _, bad := range []string{"polygon", "polygon=-1", "=3"} {
+		if _, err := parseReorgDepths(bad); err == nil {
+			t.Errorf("%q 应报错", bad)
+		}
+	}
+}
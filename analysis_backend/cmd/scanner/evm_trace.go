@@ -0,0 +1,260 @@
+// cmd/scanner/evm_trace.go
+// EVM 原生币内部转账（合约内部调用转账，如经代理合约/多签的提现）。按链配置 trace_internal 开启：
+//   - debug：debug_traceBlockByNumber + callTracer（geth/erigon/多数托管节点）
+//   - parity：trace_block（erigon/nethermind/openethereum）
+//
+// 只取带 value 的 CALL/CREATE/CREATE2/SELFDESTRUCT；DELEGATECALL/STATICCALL/CALLCODE 不转移余额，跳过（其子调用照常处理）。
+// 出错回滚的调用连同其子调用一并跳过；顶层调用已由 tx.value 路径处理，这里不重复。
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"analysis/internal/models"
+)
+
+const (
+	evmTraceDebug  = "debug"
+	evmTraceParity = "parity"
+)
+
+// evmTraceMode 规范化 trace_internal 配置；空串表示不扫描内部转账
+func evmTraceMode(s string) (string, error) {
+	switch m := strings.ToLower(strings.TrimSpace(s)); m {
+	case "", "off", "false":
+		return "", nil
+	case evmTraceDebug, "debug_traceblockbynumber":
+		return evmTraceDebug, nil
+	case evmTraceParity, "trace", "trace_block":
+		return evmTraceParity, nil
+	default:
+		return "", fmt.Errorf("unknown trace_internal %q (want debug or parity)", s)
+	}
+}
+
+// evmTraceRequest 按模式返回拉取整块 trace 的 RPC 方法与参数
+func evmTraceRequest(mode string, block uint64) (string, []interface{}) {
+	num := fmt.Sprintf("0x%x", block)
+	if mode == evmTraceParity {
+		return "trace_block", []interface{}{num}
+	}
+	return "debug_traceBlockByNumber", []interface{}{num, map[string]any{"tracer": "callTracer"}}
+}
+
+// isTraceUnsupported 节点不提供 trace 方法（方法不存在/未开放命名空间）
+func isTraceUnsupported(err error) bool {
+	if err == nil {
+		return false
+	}
+	s := strings.ToLower(err.Error())
+	return strings.Contains(s, "-32601") || strings.Contains(s, "method not found") ||
+		strings.Contains(s, "does not exist") || strings.Contains(s, "not available") ||
+		strings.Contains(s, "not supported") || strings.Contains(s, "unsupported method")
+}
+
+// traceBlockTransfers 拉取一块的内部转账：未配置 trace 或已因节点不支持而关闭时不发请求；
+// 首次遇到不支持时关闭该链的内部转账扫描（各扫描任务共享），返回空结果不报错
+func traceBlockTransfers(chain, mode string, off *atomic.Bool, block uint64, trace func(block uint64) ([]evmInternalTransfer, error)) ([]evmInternalTransfer, error) {
+	if mode == "" || off.Load() {
+		return nil, nil
+	}
+	transfers, err := trace(block)
+	if isTraceUnsupported(err) {
+		if !off.Swap(true) {
+			log.Printf("[%s] %s trace not supported by rpc, internal transfers disabled: %v", chain, mode, err)
+		}
+		return nil, nil
+	}
+	return transfers, err
+}
+
+// evmInternalTransfer 一笔内部转账
+type evmInternalTransfer struct {
+	TxHash   string
+	Index    int // 在该交易的内部转账中的序号（深度优先），用于区分同一交易的多笔
+	From, To string
+	Value    *big.Int
+}
+
+// parseBlockTraces 按模式解析整块 trace 结果
+func parseBlockTraces(mode string, raw json.RawMessage, txHashes []string) ([]evmInternalTransfer, error) {
+	if mode == evmTraceParity {
+		return parseParityBlockTraces(raw)
+	}
+	return parseDebugBlockTraces(raw, txHashes)
+}
+
+// callFrame callTracer 的调用帧
+type callFrame struct {
+	Type  string      `json:"type"`
+	From  string      `json:"from"`
+	To    string      `json:"to"`
+	Value string      `json:"value"`
+	Error string      `json:"error"`
+	Calls []callFrame `json:"calls"`
+}
+
+// parseDebugBlockTraces 解析 debug_traceBlockByNumber(callTracer)；旧版节点不返回 txHash 时按顺序对应区块交易
+func parseDebugBlockTraces(raw json.RawMessage, txHashes []string) ([]evmInternalTransfer, error) {
+	var items []struct {
+		TxHash string    `json:"txHash"`
+		Result callFrame `json:"result"`
+		Error  string    `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("decode callTracer result: %w", err)
+	}
+	var out []evmInternalTransfer
+	for i, it := range items {
+		txHash := strings.ToLower(it.TxHash)
+		if txHash == "" && i < len(txHashes) {
+			txHash = strings.ToLower(txHashes[i])
+		}
+		if txHash == "" {
+			return nil, fmt.Errorf("trace %d: no tx hash (block has %d txs)", i, len(txHashes))
+		}
+		if it.Error != "" || it.Result.Error != "" {
+			continue // 追踪失败或整笔交易回滚
+		}
+		idx := 0
+		var walk func(calls []callFrame)
+		walk = func(calls []callFrame) {
+			for _, c := range calls {
+				if c.Error != "" {
+					continue
+				}
+				if isValueTransferCall(c.Type) {
+					if v := hexBig(c.Value); v.Sign() > 0 {
+						out = append(out, evmInternalTransfer{TxHash: txHash, Index: idx,
+							From: strings.ToLower(c.From), To: strings.ToLower(c.To), Value: v})
+						idx++
+					}
+				}
+				walk(c.Calls)
+			}
+		}
+		walk(it.Result.Calls) // 顶层帧即交易本身，由 tx.value 路径处理
+	}
+	return out, nil
+}
+
+// isValueTransferCall 会转移原生币余额的调用类型
+func isValueTransferCall(typ string) bool {
+	switch strings.ToUpper(typ) {
+	case "CALL", "CREATE", "CREATE2", "SELFDESTRUCT":
+		return true
+	}
+	return false
+}
+
+// parseParityBlockTraces 解析 trace_block：traceAddress 为空的是顶层调用；出错的调用其子树整体回滚
+func parseParityBlockTraces(raw json.RawMessage) ([]evmInternalTransfer, error) {
+	var items []struct {
+		Type   string `json:"type"`
+		Action struct {
+			CallType      string `json:"callType"`
+			From          string `json:"from"`
+			To            string `json:"to"`
+			Value         string `json:"value"`
+			Address       string `json:"address"`       // suicide
+			RefundAddress string `json:"refundAddress"` // suicide
+			Balance       string `json:"balance"`       // suicide
+		} `json:"action"`
+		Result *struct {
+			Address string `json:"address"` // create
+		} `json:"result"`
+		Error        string `json:"error"`
+		TraceAddress []int  `json:"traceAddress"`
+		TxHash       string `json:"transactionHash"`
+	}
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, fmt.Errorf("decode trace_block result: %w", err)
+	}
+
+	var out []evmInternalTransfer
+	failed := map[string]bool{} // tx|traceAddress 前缀 -> 已回滚
+	idx := map[string]int{}
+	for _, it := range items {
+		txHash := strings.ToLower(it.TxHash)
+		if txHash == "" {
+			continue // 区块奖励等
+		}
+		path := make([]string, 0, len(it.TraceAddress)+1)
+		path = append(path, txHash)
+		reverted := false
+		for _, n := range it.TraceAddress {
+			if failed[strings.Join(path, "/")] {
+				reverted = true
+				break
+			}
+			path = append(path, fmt.Sprint(n))
+		}
+		key := strings.Join(path, "/")
+		if reverted || it.Error != "" || failed[key] {
+			failed[key] = true
+			continue
+		}
+		if len(it.TraceAddress) == 0 {
+			continue
+		}
+
+		var from, to, value string
+		switch it.Type {
+		case "call":
+			if !strings.EqualFold(it.Action.CallType, "call") {
+				continue
+			}
+			from, to, value = it.Action.From, it.Action.To, it.Action.Value
+		case "create":
+			if it.Result == nil {
+				continue
+			}
+			from, to, value = it.Action.From, it.Result.Address, it.Action.Value
+		case "suicide":
+			from, to, value = it.Action.Address, it.Action.RefundAddress, it.Action.Balance
+		default:
+			continue
+		}
+		v := hexBig(value)
+		if v.Sign() == 0 {
+			continue
+		}
+		out = append(out, evmInternalTransfer{TxHash: txHash, Index: idx[txHash],
+			From: strings.ToLower(from), To: strings.ToLower(to), Value: v})
+		idx[txHash]++
+	}
+	return out, nil
+}
+
+// evmInternalTransferEvents 命中监控地址的内部转账转换为事件，方向规则与顶层 tx.value 相同；
+// LogIndex 用 -(2+序号)，与顶层的 -1、ERC20 的非负 logIndex 区分
+func evmInternalTransferEvents(transfers []evmInternalTransfer, entity, chain, symbol string, addrSet map[string]bool,
+	ts time.Time, blockNum uint64, blockHash string) []models.Event {
+	var out []models.Event
+	for _, t := range transfers {
+		for _, leg := range evmTransferLegs(t.From, t.To, addrSet) {
+			out = append(out, models.Event{
+				Entity: entity, Chain: chain, Coin: symbol, AssetID: models.AssetIDFor(chain, ""), Direction: leg.dir, Amount: toDecimal(t.Value, 18),
+				TS: ts, TxID: t.TxHash, From: t.From, To: t.To, Address: leg.address, LogIndex: -(2 + t.Index),
+				BlockNumber: blockNum, BlockHash: blockHash,
+			})
+		}
+	}
+	return out
+}
+
+// hexBig 解析十六进制数值，空串或非法时为 0
+func hexBig(h string) *big.Int {
+	v, ok := new(big.Int).SetString(strings.TrimPrefix(strings.TrimSpace(h), "0x"), 16)
+	if !ok {
+		return new(big.Int)
+	}
+	return v
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	traceWatched = "0x00000000000000000000000000000000000000aa"
+	traceProxy   = "0x00000000000000000000000000000000000000cc"
+	traceImpl    = "0x00000000000000000000000000000000000000dd"
+	traceUser    = "0x00000000000000000000000000000000000000ee"
+)
+
+// 用户调用多签代理提现：代理 DELEGATECALL 实现合约（带继承的 value，不是转账），实现合约再 CALL 给监控地址 1 ETH；
+// 另有一笔零值 CALL、一笔回滚的 CALL（其子调用也不算）
+const debugTraceBlock = `[
+  {"txHash": "0xT1", "result": {"type": "CALL", "from": "` + traceUser + `", "to": "` + traceProxy + `", "value": "0x0", "calls": [
+    {"type": "DELEGATECALL", "from": "` + traceProxy + `", "to": "` + traceImpl + `", "value": "0x5", "calls": [
+      {"type": "CALL", "from": "` + traceProxy + `", "to": "` + traceWatched + `", "value": "0xde0b6b3a7640000"},
+      {"type": "CALL", "from": "` + traceProxy + `", "to": "` + traceWatched + `", "value": "0x0"},
+      {"type": "CALL", "from": "` + traceProxy + `", "to": "` + traceUser + `", "value": "0x1", "error": "execution reverted", "calls": [
+        {"type": "CALL", "from": "` + traceUser + `", "to": "` + traceWatched + `", "value": "0x1"}
+      ]},
+      {"type": "STATICCALL", "from": "` + traceProxy + `", "to": "` + traceWatched + `"}
+    ]}
+  ]}},
+  {"txHash": "0xT2", "result": {"type": "CALL", "from": "` + traceUser + `", "to": "` + traceProxy + `", "value": "0x0", "error": "out of gas", "calls": [
+    {"type": "CALL", "from": "` + traceProxy + `", "to": "` + traceWatched + `", "value": "0x1"}
+  ]}}
+]`
+
+// TestDebugTraceInternalTransferIn 代理合约内部转给监控地址的 ETH 生成一条 in 事件
+func TestDebugTraceInternalTransferIn(t *testing.T) {
+	transfers, err := parseBlockTraces(evmTraceDebug, json.RawMessage(debugTraceBlock), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	events := evmInternalTransferEvents(transfers, "binance", "ethereum", "ETH", map[string]bool{traceWatched: true}, ts, 100, "0xb100")
+	if len(events) != 1 {
+		t.Fatalf("期望 1 条内部转入事件，实际 %+v", events)
+	}
+	e := events[0]
+	if e.Direction != "in" || e.Amount != "1.00000000" || e.Coin != "ETH" || e.TxID != "0xt1" ||
+		e.From != traceProxy || e.Address != traceWatched || e.LogIndex != -2 || e.BlockNumber != 100 || !e.TS.Equal(ts) {
+		t.Errorf("内部转入事件不符: %+v", e)
+	}
+}
+
+// TestDebugTraceTxHashFallback 旧版 callTracer 不返回 txHash 时按区块交易顺序对应
+func TestDebugTraceTxHashFallback(t *testing.T) {
+	raw := `[{"result": {"type": "CALL", "from": "` + traceUser + `", "to": "` + traceProxy + `", "calls": [
+	  {"type": "CALL", "from": "` + traceWatched + `", "to": "` + traceUser + `", "value": "0x2"}]}}]`
+	transfers, err := parseDebugBlockTraces(json.RawMessage(raw), []string{"0xAB"})
+	if err != nil || len(transfers) != 1 || transfers[0].TxHash != "0xab" {
+		t.Fatalf("应按顺序取区块交易哈希: %+v %v", transfers, err)
+	}
+	events := evmInternalTransferEvents(transfers, "binance", "ethereum", "ETH", map[string]bool{traceWatched: true}, time.Now(), 1, "")
+	if len(events) != 1 || events[0].Direction != "out" {
+		t.Fatalf("监控地址转出应记 out: %+v", events)
+	}
+	if _, err := parseDebugBlockTraces(json.RawMessage(raw), nil); err == nil {
+		t.Error("无法对应交易哈希时应报错")
+	}
+}
+
+// TestParityTraceInternalTransfers trace_block：跳过顶层调用、delegatecall、零值与回滚子树
+func TestParityTraceInternalTransfers(t *testing.T) {
+	raw := `[
+	  {"type": "call", "action": {"callType": "call", "from": "` + traceUser + `", "to": "` + traceProxy + `", "value": "0x7"}, "traceAddress": [], "transactionHash": "0xT1"},
+	  {"type": "call", "action": {"callType": "delegatecall", "from": "` + traceProxy + `", "to": "` + traceImpl + `", "value": "0x7"}, "traceAddress": [0], "transactionHash": "0xT1"},
+	  {"type": "call", "action": {"callType": "call", "from": "` + traceProxy + `", "to": "` + traceWatched + `", "value": "0x3"}, "traceAddress": [0, 0], "transactionHash": "0xT1"},
+	  {"type": "call", "action": {"callType": "call", "from": "` + traceProxy + `", "to": "` + traceWatched + `", "value": "0x0"}, "traceAddress": [0, 1], "transactionHash": "0xT1"},
+	  {"type": "call", "action": {"callType": "call", "from": "` + traceProxy + `", "to": "` + traceUser + `", "value": "0x1"}, "error": "Reverted", "traceAddress": [1], "transactionHash": "0xT1"},
+	  {"type": "call", "action": {"callType": "call", "from": "` + traceUser + `", "to": "` + traceWatched + `", "value": "0x1"}, "traceAddress": [1, 0], "transactionHash": "0xT1"},
+	  {"type": "suicide", "action": {"address": "` + traceImpl + `", "refundAddress": "` + traceWatched + `", "balance": "0x9"}, "traceAddress": [2], "transactionHash": "0xT1"},
+	  {"type": "reward", "action": {"author": "` + traceWatched + `", "value": "0x1"}, "traceAddress": []}
+	]`
+	transfers, err := parseBlockTraces(evmTraceParity, json.RawMessage(raw), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 2 || transfers[0].Value.Int64() != 3 || transfers[1].Value.Int64() != 9 || transfers[1].Index != 1 {
+		t.Fatalf("期望 call 0x3 与 suicide 0x9 两笔，实际 %+v", transfers)
+	}
+}
+
+// TestEVMTraceMode 配置取值
+func TestEVMTraceMode(t *testing.T) {
+	for in, want := range map[string]string{"": "", "off": "", "Debug": evmTraceDebug, "trace_block": evmTraceParity, "parity": evmTraceParity} {
+		if got, err := evmTraceMode(in); err != nil || got != want {
+			t.Errorf("%q -> %q %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := evmTraceMode("geth"); err == nil {
+		t.Error("未知取值应报错")
+	}
+	if !isTraceUnsupported(errors.New("rpc debug_traceBlockByNumber error [-32601]: the method debug_traceBlockByNumber does not exist/is not available")) {
+		t.Error("-32601 应识别为不支持")
+	}
+}
+
+// TestTraceBlockTransfersDisabledAfterUnsupported 节点不支持 trace 时首块关闭该链的内部转账扫描，之后的区块不再请求；其它错误照常返回
+func TestTraceBlockTransfersDisabledAfterUnsupported(t *testing.T) {
+	off := &atomic.Bool{}
+	var traced []uint64
+	trace := func(n uint64) ([]evmInternalTransfer, error) {
+		traced = append(traced, n)
+		return nil, errors.New("rpc debug_traceBlockByNumber error [-32601]: method not found")
+	}
+	for _, b := range []uint64{100, 101} {
+		if transfers, err := traceBlockTransfers("ethereum", evmTraceDebug, off, b, trace); err != nil || transfers != nil {
+			t.Fatalf("block %d: 不支持 trace 不应报错: %v %v", b, transfers, err)
+		}
+	}
+	if len(traced) != 1 || traced[0] != 100 || !off.Load() {
+		t.Errorf("只应 trace 首块并关闭该链，实际 traced=%v off=%v", traced, off.Load())
+	}
+
+	off = &atomic.Bool{}
+	if _, err := traceBlockTransfers("ethereum", evmTraceParity, off, 100, func(uint64) ([]evmInternalTransfer, error) { return nil, errors.New("timeout") }); err == nil || off.Load() {
+		t.Errorf("普通错误应返回且不关闭 trace: %v off=%v", err, off.Load())
+	}
+}
@@ -0,0 +1,529 @@
+// cmd/scanner/evm_ws.go
+// EVM WebSocket 订阅模式（-evm-ws）：RPC 列表里有 ws(s):// 端点时，用 eth_subscribe("logs") 实时接收 ERC20 Transfer 日志，
+// 过滤条件与轮询相同（Transfer 主题 + orTopic(from/to 地址)，address 为该链配置的合约），事件照常下发到 ingest。
+//
+// 与轮询循环的分工：
+//   - 订阅建立后记录 head，head 之后的区块由订阅负责，轮询窗口只对 head 及之前的区块做 getLogs（即重连后的缺口回补）；
+//   - 原生币转账、游标推进与重组检查仍由轮询负责；
+//   - 连接断开后轮询游标回退到最后收到的区块头，恢复 getLogs，直到重新订阅成功。
+//
+// 与轮询一样遵守确认深度：事件按区块头暂存，区块进入 head - confirmations 之后才下发；暂存期间被重组撤销的日志直接丢弃。
+// 重复下发的事件由 API 按唯一键去重。
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"analysis/internal/models"
+	"analysis/internal/sink"
+	"analysis/internal/util"
+
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// evmWSTopicChunk 单个订阅的 from/to 地址数，与轮询 getLogs 的分块一致
+const evmWSTopicChunk = 100
+
+// evmWSMaxPending 单个实体待下发事件的上限，下发持续失败时丢弃最旧的事件
+const evmWSMaxPending = 10000
+
+// isWSURL 是否为 WebSocket 端点
+func isWSURL(u string) bool {
+	u = strings.ToLower(strings.TrimSpace(u))
+	return strings.HasPrefix(u, "ws://") || strings.HasPrefix(u, "wss://")
+}
+
+// splitWSEndpoints 拆分出 WebSocket 端点（取第一个）与 HTTP 端点；
+// 只配置了 ws 端点时按 wss->https / ws->http 推导轮询用的 HTTP 端点
+func splitWSEndpoints(rpcs []string) (ws string, httpRPCs []string) {
+	for _, u := range rpcs {
+		if isWSURL(u) {
+			if ws == "" {
+				ws = u
+			}
+			continue
+		}
+		httpRPCs = append(httpRPCs, u)
+	}
+	if ws != "" && len(httpRPCs) == 0 {
+		httpRPCs = []string{"http" + strings.TrimPrefix(ws, "ws")}
+	}
+	return ws, httpRPCs
+}
+
+// evmWSState 一条链上订阅的状态，供轮询循环裁剪 getLogs 范围、断线后回退游标
+type evmWSState struct {
+	mu      sync.Mutex
+	live    bool
+	from    uint64         // 当前订阅负责的起始区块（订阅建立时的 head+1）
+	epoch   int            // 每次断线 +1
+	resume  uint64         // 最近一次断线时最后收到的区块头，轮询从这里恢复 getLogs
+	resumed map[string]int // entity -> 已应用的断线 epoch
+}
+
+func newEVMWSState() *evmWSState {
+	return &evmWSState{resumed: map[string]int{}}
+}
+
+// Live 订阅建立，from 之后的区块由订阅负责
+func (s *evmWSState) Live(from uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.live, s.from = true, from
+}
+
+// Down 订阅断开，lastHead 为断线前最后收到的区块头（0 表示未收到，回退到订阅起点）
+func (s *evmWSState) Down(lastHead uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.live {
+		return
+	}
+	s.live = false
+	s.epoch++
+	s.resume = s.from
+	if lastHead > 0 {
+		s.resume = lastHead
+	}
+}
+
+// PollLogRange 把轮询窗口 [cur,to] 的 getLogs 范围裁剪到订阅起点之前；ok=false 表示整段由订阅覆盖
+func (s *evmWSState) PollLogRange(cur, to uint64) (uint64, uint64, bool) {
+	if s == nil {
+		return cur, to, true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.live {
+		return cur, to, true
+	}
+	if s.from <= cur {
+		return 0, 0, false
+	}
+	if to >= s.from {
+		to = s.from - 1
+	}
+	return cur, to, true
+}
+
+// ResumeCursor 断线后每个实体只回退一次：游标越过断线位置时退回到断线位置
+func (s *evmWSState) ResumeCursor(entity string, cur uint64) (uint64, bool) {
+	if s == nil {
+		return cur, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.resumed[entity] == s.epoch {
+		return cur, false
+	}
+	s.resumed[entity] = s.epoch
+	if s.resume > 0 && s.resume < cur {
+		return s.resume, true
+	}
+	return cur, false
+}
+
+// evmSubscription 订阅句柄（go-ethereum 的 *rpc.ClientSubscription 满足该接口）
+type evmSubscription interface {
+	Err() <-chan error
+	Unsubscribe()
+}
+
+// evmLogSource WebSocket 连接；测试中注入内存实现
+type evmLogSource interface {
+	SubscribeLogs(ctx context.Context, filter map[string]any, ch chan<- map[string]any) (evmSubscription, error)
+	SubscribeHeads(ctx context.Context, ch chan<- map[string]any) (evmSubscription, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	Decimals(ctx context.Context, contract string) (int, error)
+	Close()
+}
+
+// gethLogSource 基于 go-ethereum rpc 客户端的 WebSocket 连接
+type gethLogSource struct {
+	c *gethrpc.Client
+}
+
+func dialGethLogSource(ctx context.Context, url string) (evmLogSource, error) {
+	c, err := gethrpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return &gethLogSource{c: c}, nil
+}
+
+func (g *gethLogSource) SubscribeLogs(ctx context.Context, filter map[string]any, ch chan<- map[string]any) (evmSubscription, error) {
+	return g.c.EthSubscribe(ctx, ch, "logs", filter)
+}
+
+func (g *gethLogSource) SubscribeHeads(ctx context.Context, ch chan<- map[string]any) (evmSubscription, error) {
+	return g.c.EthSubscribe(ctx, ch, "newHeads")
+}
+
+func (g *gethLogSource) BlockNumber(ctx context.Context) (uint64, error) {
+	var x string
+	if err := g.c.CallContext(ctx, &x, "eth_blockNumber"); err != nil {
+		return 0, err
+	}
+	return hexToUint64(x), nil
+}
+
+func (g *gethLogSource) Decimals(ctx context.Context, contract string) (int, error) {
+	var x string
+	if err := g.c.CallContext(ctx, &x, "eth_call", map[string]any{"to": contract, "data": "0x313ce567"}, "latest"); err != nil {
+		return 0, err
+	}
+	n, ok := new(big.Int).SetString(strings.TrimPrefix(x, "0x"), 16)
+	if !ok {
+		return 0, fmt.Errorf("decimals %s: bad result %q", contract, x)
+	}
+	return int(n.Int64()), nil
+}
+
+func (g *gethLogSource) Close() { g.c.Close() }
+
+// evmTransferEvent 把一条 Transfer 日志转换为实体事件：to 命中记 in，否则 from 命中记 out；都未命中返回 false
+func evmTransferEvent(lg map[string]any, entity, chain, symbol string, decimals int, addrSet map[string]bool, ts time.Time) (models.Event, bool) {
+	topics, _ := lg["topics"].([]any)
+	if len(topics) < 3 {
+		return models.Event{}, false
+	}
+	from := topicAddr(topics[1])
+	toA := topicAddr(topics[2])
+	dir, target := "", ""
+	switch {
+	case addrSet[toA]:
+		dir, target = "in", toA
+	case addrSet[from]:
+		dir, target = "out", from
+	default:
+		return models.Event{}, false
+	}
+
+	val := new(big.Int)
+	_, _ = val.SetString(strings.TrimPrefix(str(lg["data"]), "0x"), 16)
+	if txFilter.skipZero(val.Sign()) {
+		return models.Event{}, false
+	}
+	amt := toDecimal(val, decimals)
+	if minAmounts.below(chain, symbol, amt) {
+		return models.Event{}, false
+	}
+	return models.Event{
+		Entity: entity, Chain: chain, Coin: symbol, AssetID: models.AssetIDFor(chain, str(lg["address"])), Direction: dir, Amount: amt,
+		TS: ts, TxID: str(lg["transactionHash"]), From: from, To: toA, Address: target,
+		LogIndex:    int(hexToUint64(str(lg["logIndex"]))),
+		BlockNumber: hexToUint64(str(lg["blockNumber"])), BlockHash: strings.ToLower(str(lg["blockHash"])),
+	}, true
+}
+
+// evmWSSubscriber 一条链的日志订阅：一个连接，按实体与地址分块建立 from/to 两组订阅
+type evmWSSubscriber struct {
+	chain     string
+	url       string
+	contracts map[string]string   // lowerAddr -> SYMBOL（只读，与轮询共用）
+	entities  map[string][]string // entity -> 小写地址
+	addrSets  map[string]map[string]bool
+	// decimals 查共享精度缓存，未命中时调用 detect
+	decimals   func(contract string, detect func() (int, error)) (int, error)
+	publish    func(ctx context.Context, entity string, events []models.Event) error
+	state      *evmWSState
+	dial       func(ctx context.Context, url string) (evmLogSource, error)
+	flushEvery time.Duration
+	retryDelay time.Duration
+	// confirmations 确认深度，区块不超过 lastHead - confirmations 的事件才下发；0 表示收到即下发
+	confirmations uint64
+
+	pending  map[string][]models.Event
+	seen     map[string]uint64    // entity|tx#logIndex -> 区块，同一日志可能同时命中 from/to 订阅
+	headTime map[uint64]time.Time // 区块头时间，日志本身不带时间戳
+	lastHead uint64
+}
+
+func newEVMWSSubscriber(chain, url string, contracts map[string]string, entities map[string][]string, state *evmWSState,
+	decimals func(string, func() (int, error)) (int, error), publish func(context.Context, string, []models.Event) error) *evmWSSubscriber {
+	ents := make(map[string][]string, len(entities))
+	sets := make(map[string]map[string]bool, len(entities))
+	for ent, addrs := range entities {
+		ents[ent] = uniqueLower(addrs)
+		sets[ent] = toSetLower(ents[ent])
+	}
+	return &evmWSSubscriber{
+		chain: chain, url: url, contracts: contracts, entities: ents, addrSets: sets, state: state,
+		decimals: decimals, publish: publish, dial: dialGethLogSource,
+		flushEvery: 2 * time.Second, retryDelay: 5 * time.Second,
+		pending: map[string][]models.Event{}, seen: map[string]uint64{}, headTime: map[uint64]time.Time{},
+	}
+}
+
+// Run 订阅直到 ctx 取消；断线后轮询接管并定期重连。退出前下发所有待发事件
+func (s *evmWSSubscriber) Run(ctx context.Context) {
+	defer func() {
+		// ctx 已取消，用独立超时下发剩余事件
+		flushCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		s.flush(flushCtx)
+		if n := s.pendingCount(); n > 0 {
+			log.Printf("[%s-ws] shutdown with %d undelivered events", s.chain, n)
+		}
+	}()
+
+	for ctx.Err() == nil {
+		err := s.session(ctx)
+		s.state.Down(s.resumeHead())
+		s.flush(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[%s-ws] subscription dropped, falling back to polling (resume from %d): %v", s.chain, s.resumeHead(), err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// session 一次连接：先订阅再取 head，保证 head 之后的区块不会漏掉
+func (s *evmWSSubscriber) session(ctx context.Context) error {
+	src, err := s.dial(ctx, s.url)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer src.Close()
+
+	logsCh := make(chan map[string]any, 1024)
+	headsCh := make(chan map[string]any, 64)
+	var subs []evmSubscription
+	defer func() {
+		for _, sub := range subs {
+			sub.Unsubscribe()
+		}
+	}()
+
+	headSub, err := src.SubscribeHeads(ctx, headsCh)
+	if err != nil {
+		return fmt.Errorf("subscribe newHeads: %w", err)
+	}
+	subs = append(subs, headSub)
+	for _, filter := range s.filters() {
+		sub, err := src.SubscribeLogs(ctx, filter, logsCh)
+		if err != nil {
+			return fmt.Errorf("subscribe logs: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+	head, err := src.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("blockNumber: %w", err)
+	}
+	s.lastHead = head
+	s.state.Live(head + 1)
+	log.Printf("[%s-ws] subscribed %d log filters, live from block %d", s.chain, len(subs)-1, head+1)
+
+	errCh := make(chan error, len(subs))
+	for _, sub := range subs {
+		go func(sub evmSubscription) {
+			if err, ok := <-sub.Err(); ok {
+				errCh <- err
+			} else {
+				errCh <- fmt.Errorf("subscription closed")
+			}
+		}(sub)
+	}
+
+	ticker := time.NewTicker(s.flushEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			return err
+		case h := <-headsCh:
+			n := hexToUint64(str(h["number"]))
+			if n > s.lastHead {
+				s.lastHead = n
+			}
+			s.headTime[n] = parseBlockTime(h)
+		case lg := <-logsCh:
+			s.handleLog(ctx, src, lg)
+		case <-ticker.C:
+			s.flush(ctx)
+			s.prune()
+		}
+	}
+}
+
+// filters 与轮询相同的过滤条件：address=全部合约，topics=[Transfer, OR(from), nil] / [Transfer, nil, OR(to)]
+func (s *evmWSSubscriber) filters() []map[string]any {
+	contracts := keys(s.contracts)
+	var out []map[string]any
+	for _, addrs := range s.entities {
+		for i := 0; i < len(addrs); i += evmWSTopicChunk {
+			end := i + evmWSTopicChunk
+			if end > len(addrs) {
+				end = len(addrs)
+			}
+			chunk := addrs[i:end]
+			out = append(out,
+				map[string]any{"address": contracts, "topics": []any{transferTopic.Hex(), orTopic(chunk), nil}},
+				map[string]any{"address": contracts, "topics": []any{transferTopic.Hex(), nil, orTopic(chunk)}},
+			)
+		}
+	}
+	return out
+}
+
+func (s *evmWSSubscriber) handleLog(ctx context.Context, src evmLogSource, lg map[string]any) {
+	if removed, _ := lg["removed"].(bool); removed {
+		// 重组撤销的日志：尚未下发的直接丢弃；新分叉上的日志会重新推送，已下发的旧事件由 API 按区块哈希替换
+		log.Printf("[%s-ws] log removed by reorg: tx=%s block=%s", s.chain, str(lg["transactionHash"]), str(lg["blockNumber"]))
+		s.dropRemoved(lg)
+		return
+	}
+	contract := strings.ToLower(str(lg["address"]))
+	symbol, ok := s.contracts[contract]
+	if !ok {
+		return
+	}
+	decimals, err := s.decimals(contract, func() (int, error) { return src.Decimals(ctx, contract) })
+	if err != nil {
+		log.Printf("[%s-ws] decimals %s: %v (use %d)", s.chain, contract, err, decimals)
+	}
+	blockNum := hexToUint64(str(lg["blockNumber"]))
+	ts, ok := s.headTime[blockNum]
+	if !ok {
+		ts = time.Now().UTC()
+	}
+
+	for entity, addrSet := range s.addrSets {
+		if !util.IsAllowedFor(entity, symbol) {
+			continue
+		}
+		ev, ok := evmTransferEvent(lg, entity, s.chain, symbol, decimals, addrSet, ts)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s#%d", entity, ev.TxID, ev.LogIndex)
+		if _, dup := s.seen[key]; dup {
+			continue
+		}
+		s.seen[key] = blockNum
+		q := append(s.pending[entity], ev)
+		if len(q) > evmWSMaxPending {
+			q = q[len(q)-evmWSMaxPending:]
+		}
+		s.pending[entity] = q
+	}
+}
+
+// dropRemoved 从待发队列中移除被重组撤销的日志，并清掉去重键，新分叉上同一交易的日志可以重新入队
+func (s *evmWSSubscriber) dropRemoved(lg map[string]any) {
+	tx := str(lg["transactionHash"])
+	logIndex := int(hexToUint64(str(lg["logIndex"])))
+	blockHash := strings.ToLower(str(lg["blockHash"]))
+	for entity, evs := range s.pending {
+		kept := evs[:0]
+		for _, e := range evs {
+			if e.TxID == tx && e.LogIndex == logIndex && e.BlockHash == blockHash {
+				delete(s.seen, fmt.Sprintf("%s|%s#%d", entity, e.TxID, e.LogIndex))
+				continue
+			}
+			kept = append(kept, e)
+		}
+		s.pending[entity] = kept
+	}
+}
+
+// confirmed 拆分出已达到确认深度、可以下发的事件
+func (s *evmWSSubscriber) confirmed(evs []models.Event) (ready, held []models.Event) {
+	if s.confirmations == 0 {
+		return evs, nil
+	}
+	tip, ok := confirmedTip(s.lastHead, s.confirmations)
+	for _, e := range evs {
+		if ok && e.BlockNumber <= tip {
+			ready = append(ready, e)
+		} else {
+			held = append(held, e)
+		}
+	}
+	return ready, held
+}
+
+// resumeHead 断线后轮询恢复 getLogs 的位置：未达到确认深度的区块没有下发，从已确认的最高区块恢复
+func (s *evmWSSubscriber) resumeHead() uint64 {
+	if s.confirmations == 0 {
+		return s.lastHead
+	}
+	tip, _ := confirmedTip(s.lastHead, s.confirmations)
+	return tip
+}
+
+// flush 逐实体下发已确认的待发事件，失败的与未确认的保留到下次
+func (s *evmWSSubscriber) flush(ctx context.Context) {
+	for entity, evs := range s.pending {
+		ready, held := s.confirmed(evs)
+		if len(ready) == 0 {
+			if len(held) == 0 {
+				delete(s.pending, entity)
+			}
+			continue
+		}
+		if err := s.publish(ctx, entity, ready); err != nil {
+			log.Printf("[%s-ws] entity=%s publish %d events: %v (retry later)", s.chain, entity, len(ready), err)
+			continue
+		}
+		if len(held) == 0 {
+			delete(s.pending, entity)
+		} else {
+			s.pending[entity] = held
+		}
+	}
+}
+
+func (s *evmWSSubscriber) pendingCount() int {
+	n := 0
+	for _, evs := range s.pending {
+		n += len(evs)
+	}
+	return n
+}
+
+// prune 丢弃远早于当前区块头的去重键与区块时间
+func (s *evmWSSubscriber) prune() {
+	const keep = 1000
+	if s.lastHead < keep {
+		return
+	}
+	floor := s.lastHead - keep
+	for k, n := range s.seen {
+		if n < floor {
+			delete(s.seen, k)
+		}
+	}
+	for n := range s.headTime {
+		if n < floor {
+			delete(s.headTime, n)
+		}
+	}
+}
+
+// syncSink 串行化下发：订阅协程与轮询循环共用同一个事件下发目标
+type syncSink struct {
+	mu sync.Mutex
+	sink.EventSink
+}
+
+func (s *syncSink) Publish(ctx context.Context, entity string, events []models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.EventSink.Publish(ctx, entity, events)
+}
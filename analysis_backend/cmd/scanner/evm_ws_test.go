@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"analysis/internal/models"
+	"analysis/internal/util"
+)
+
+// fakeWSSub 可手动触发断线的订阅句柄
+type fakeWSSub struct {
+	err  chan error
+	once sync.Once
+}
+
+func (s *fakeWSSub) Err() <-chan error { return s.err }
+func (s *fakeWSSub) Unsubscribe()      { s.once.Do(func() { close(s.err) }) }
+
+// fakeLogSource 内存 WebSocket 连接：记录订阅的过滤条件与通道
+type fakeLogSource struct {
+	mu      sync.Mutex
+	head    uint64
+	filters []map[string]any
+	logs    chan<- map[string]any
+	subs    []*fakeWSSub
+}
+
+func (f *fakeLogSource) newSub() *fakeWSSub {
+	sub := &fakeWSSub{err: make(chan error, 1)}
+	f.subs = append(f.subs, sub)
+	return sub
+}
+
+func (f *fakeLogSource) SubscribeLogs(_ context.Context, filter map[string]any, ch chan<- map[string]any) (evmSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.filters = append(f.filters, filter)
+	f.logs = ch
+	return f.newSub(), nil
+}
+
+func (f *fakeLogSource) SubscribeHeads(_ context.Context, _ chan<- map[string]any) (evmSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.newSub(), nil
+}
+
+func (f *fakeLogSource) BlockNumber(context.Context) (uint64, error) { return f.head, nil }
+func (f *fakeLogSource) Decimals(context.Context, string) (int, error) {
+	return 0, errors.New("should use cache")
+}
+func (f *fakeLogSource) Close() {}
+
+// drop 模拟连接断开
+func (f *fakeLogSource) drop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.subs[0].err <- errors.New("websocket: close 1006")
+}
+
+func (f *fakeLogSource) send(lg map[string]any) {
+	f.mu.Lock()
+	ch := f.logs
+	f.mu.Unlock()
+	ch <- lg
+}
+
+const (
+	wsTestContract = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	wsTestWatched  = "0x00000000000000000000000000000000000000aa"
+	wsTestOther    = "0x00000000000000000000000000000000000000bb"
+)
+
+func wsTransferLog(tx string, block uint64, from, to string) map[string]any {
+	pad := func(a string) string { return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(a, "0x") }
+	return map[string]any{
+		"address":         wsTestContract,
+		"topics":          []any{transferTopic.Hex(), pad(from), pad(to)},
+		"data":            "0x1e8480", // 2000000
+		"transactionHash": tx,
+		"logIndex":        "0x3",
+		"blockNumber":     fmt.Sprintf("0x%x", block),
+		"blockHash":       fmt.Sprintf("0xAB%d", block),
+	}
+}
+
+type wsPublished struct {
+	mu       sync.Mutex
+	events   []models.Event
+	fail     bool // 模拟下游不可用
+	attempts int
+}
+
+func (p *wsPublished) publish(_ context.Context, _ string, evs []models.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts++
+	if p.fail {
+		return errors.New("api unavailable")
+	}
+	p.events = append(p.events, evs...)
+	return nil
+}
+
+func (p *wsPublished) setFail(fail bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fail, p.attempts = fail, 0
+}
+
+func (p *wsPublished) tried() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.attempts
+}
+
+func (p *wsPublished) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.events)
+}
+
+func newTestWSSubscriber(t *testing.T, state *evmWSState, pub *wsPublished, src *fakeLogSource) *evmWSSubscriber {
+	util.SetEntityAllowed("binance", "USDT")
+	t.Cleanup(util.ResetEntityAllowed)
+	s := newEVMWSSubscriber("ethereum", "wss://node", map[string]string{wsTestContract: "USDT"},
+		map[string][]string{"binance": {strings.ToUpper(wsTestWatched)}}, state,
+		func(string, func() (int, error)) (int, error) { return 6, nil }, pub.publish)
+	s.dial = func(context.Context, string) (evmLogSource, error) { return src, nil }
+	s.flushEvery = 10 * time.Millisecond
+	s.retryDelay = time.Hour
+	return s
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestEVMWSHandleLog 日志转换为事件：方向、精度缓存、区块哈希与区块头时间；from/to 两组订阅重复推送只记一次
+func TestEVMWSHandleLog(t *testing.T) {
+	pub := &wsPublished{}
+	s := newTestWSSubscriber(t, newEVMWSState(), pub, &fakeLogSource{})
+	at := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	s.headTime[101] = at
+
+	in := wsTransferLog("0xin", 101, wsTestOther, wsTestWatched)
+	s.handleLog(context.Background(), &fakeLogSource{}, in)
+	s.handleLog(context.Background(), &fakeLogSource{}, in)
+	s.handleLog(context.Background(), &fakeLogSource{}, wsTransferLog("0xout", 102, wsTestWatched, wsTestOther))
+	s.handleLog(context.Background(), &fakeLogSource{}, wsTransferLog("0xnone", 102, wsTestOther, wsTestOther))
+	removed := wsTransferLog("0xgone", 102, wsTestOther, wsTestWatched)
+	removed["removed"] = true
+	s.handleLog(context.Background(), &fakeLogSource{}, removed)
+
+	evs := s.pending["binance"]
+	if len(evs) != 2 {
+		t.Fatalf("期望 2 条事件，实际 %+v", evs)
+	}
+	if e := evs[0]; e.Direction != "in" || e.Amount != "2.00000000" || e.Coin != "USDT" || e.BlockNumber != 101 ||
+		e.BlockHash != "0xab101" || !e.TS.Equal(at) || e.Address != wsTestWatched || e.LogIndex != 3 {
+		t.Errorf("转入事件不符: %+v", e)
+	}
+	if e := evs[1]; e.Direction != "out" || e.Address != wsTestWatched {
+		t.Errorf("转出事件不符: %+v", e)
+	}
+
+	if got := s.filters(); len(got) != 2 {
+		t.Errorf("单实体单分块应有 from/to 两组过滤，实际 %d", len(got))
+	}
+}
+
+// TestEVMWSSubscriberLiveDropAndShutdown 订阅建立后轮询只回补 head 及之前；断线后游标按实体回退一次；退出前下发待发事件
+func TestEVMWSSubscriberLiveDropAndShutdown(t *testing.T) {
+	state := newEVMWSState()
+	pub := &wsPublished{}
+	src := &fakeLogSource{head: 100}
+	s := newTestWSSubscriber(t, state, pub, src)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { s.Run(ctx); close(done) }()
+
+	waitFor(t, "订阅建立", func() bool { _, _, ok := state.PollLogRange(101, 110); return !ok })
+	if from, to, ok := state.PollLogRange(95, 110); !ok || from != 95 || to != 100 {
+		t.Fatalf("getLogs 应裁剪到 [95,100]，实际 [%d,%d] %v", from, to, ok)
+	}
+
+	src.send(wsTransferLog("0xlive", 101, wsTestOther, wsTestWatched))
+	waitFor(t, "实时事件下发", func() bool { return pub.count() == 1 })
+
+	// 下游不可用期间收到的事件留在待发队列
+	pub.setFail(true)
+	src.send(wsTransferLog("0xheld", 102, wsTestWatched, wsTestOther))
+	waitFor(t, "下发失败后保留", func() bool { return pub.tried() > 0 })
+
+	src.drop()
+	waitFor(t, "断线后恢复轮询", func() bool { _, to, ok := state.PollLogRange(95, 110); return ok && to == 110 })
+	if cur, ok := state.ResumeCursor("binance", 111); !ok || cur != 100 {
+		t.Fatalf("断线后游标应回退到最后区块头 100，实际 %d %v", cur, ok)
+	}
+	if cur, ok := state.ResumeCursor("binance", 111); ok || cur != 111 {
+		t.Fatalf("同一次断线只回退一次，实际 %d %v", cur, ok)
+	}
+
+	// 下游恢复后退出：退出前下发剩余事件
+	pub.setFail(false)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run 未在 ctx 取消后退出")
+	}
+	if pub.count() != 2 {
+		t.Fatalf("退出前应下发剩余事件，实际 %d", pub.count())
+	}
+}
+
+// TestEVMWSHoldsUnconfirmedLogs 事件在区块达到确认深度后才下发；暂存期间被重组撤销的日志丢弃，新分叉上的同一交易重新入队；
+// 断线后从已确认的最高区块恢复轮询
+func TestEVMWSHoldsUnconfirmedLogs(t *testing.T) {
+	pub := &wsPublished{}
+	src := &fakeLogSource{}
+	s := newTestWSSubscriber(t, newEVMWSState(), pub, src)
+	s.confirmations = 2
+	s.lastHead = 101
+	ctx := context.Background()
+
+	s.handleLog(ctx, src, wsTransferLog("0xa", 100, wsTestOther, wsTestWatched))
+	orphan := wsTransferLog("0xb", 101, wsTestOther, wsTestWatched)
+	s.handleLog(ctx, src, orphan)
+	s.flush(ctx)
+	if pub.count() != 0 || len(s.pending["binance"]) != 2 {
+		t.Fatalf("head=101 时 100、101 均未确认，不应下发: published=%d pending=%d", pub.count(), len(s.pending["binance"]))
+	}
+
+	s.lastHead = 102
+	s.flush(ctx)
+	if pub.count() != 1 || pub.events[0].TxID != "0xa" || len(s.pending["binance"]) != 1 {
+		t.Fatalf("head=102 时只应下发区块 100: published=%+v", pub.events)
+	}
+
+	// 区块 101 被重组：撤销的日志从队列中丢弃，新分叉上的同一交易重新入队
+	orphan["removed"] = true
+	s.handleLog(ctx, src, orphan)
+	if len(s.pending["binance"]) != 0 {
+		t.Fatalf("被撤销的日志应丢弃: %+v", s.pending["binance"])
+	}
+	reorged := wsTransferLog("0xb", 101, wsTestOther, wsTestWatched)
+	reorged["blockHash"] = "0xCD101"
+	s.handleLog(ctx, src, reorged)
+	s.lastHead = 103
+	s.flush(ctx)
+	if pub.count() != 2 || pub.events[1].BlockHash != "0xcd101" {
+		t.Fatalf("新分叉上的事件应在确认后下发: %+v", pub.events)
+	}
+	if got := s.resumeHead(); got != 101 {
+		t.Errorf("断线后应从已确认的 101 恢复轮询，实际 %d", got)
+	}
+}
+
+// TestSplitWSEndpoints 拆分 ws 端点；只有 ws 端点时推导 HTTP 端点
+func TestSplitWSEndpoints(t *testing.T) {
+	ws, rpcs := splitWSEndpoints([]string{"https://a", "wss://b", "wss://c"})
+	if ws != "wss://b" || len(rpcs) != 1 || rpcs[0] != "https://a" {
+		t.Errorf("混合配置拆分不符: %q %v", ws, rpcs)
+	}
+	ws, rpcs = splitWSEndpoints([]string{"wss://node/v3/key"})
+	if ws != "wss://node/v3/key" || len(rpcs) != 1 || rpcs[0] != "https://node/v3/key" {
+		t.Errorf("应推导出 https 端点: %q %v", ws, rpcs)
+	}
+	if ws, rpcs = splitWSEndpoints([]string{"https://a"}); ws != "" || len(rpcs) != 1 {
+		t.Errorf("无 ws 端点应原样返回: %q %v", ws, rpcs)
+	}
+}
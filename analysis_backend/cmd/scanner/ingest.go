@@ -0,0 +1,72 @@
+// cmd/scanner/ingest.go
+// 窗口提交：事件（按 event_sink.batch_size 分块）全部下发成功后才推进游标，失败时下一轮重扫同一窗口。
+// -dry-run 时事件写 NDJSON（-out），游标不上报。
+
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/netutil"
+	"analysis/internal/sink"
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ingestWindow 下发一个扫描窗口的事件，成功后调用 advance 推进游标
+func ingestWindow(ctx context.Context, s sink.EventSink, entity string, events []models.Event, advance func() error) error {
+	if len(events) > 0 {
+		start := time.Now()
+		if err := s.Publish(ctx, entity, events); err != nil {
+			return fmt.Errorf("ingest: %w", err)
+		}
+		metrics.ObserveIngest(time.Since(start))
+	}
+	if err := advance(); err != nil {
+		return fmt.Errorf("set cursor: %w", err)
+	}
+	return nil
+}
+
+// cursorPoster 返回窗口提交时上报游标的函数；dryRun 时不上报（API 游标不动，同一窗口可反复重扫）
+func cursorPoster(apiBase string, dryRun bool) func(ctx context.Context, entity, chain string, next uint64) error {
+	if dryRun {
+		return func(context.Context, string, string, uint64) error { return nil }
+	}
+	return func(ctx context.Context, entity, chain string, next uint64) error {
+		return postCursor(ctx, apiBase, entity, chain, next)
+	}
+}
+
+// postCursor 上报实体在某条链上的下一个待扫描区块/slot
+func postCursor(ctx context.Context, apiBase, entity, chain string, next uint64) error {
+	u := fmt.Sprintf("%s/sync/cursor?entity=%s&chain=%s", strings.TrimRight(apiBase, "/"), url.QueryEscape(entity), url.QueryEscape(chain))
+	return netutil.PostJSON(ctx, u, map[string]uint64{"block": next}, &struct {
+		OK bool `json:"ok"`
+	}{})
+}
+
+// canonicalPoster 返回 EVM 重组后上报规范区块哈希的函数；dryRun 时不上报（API 中的事件不动）
+func canonicalPoster(apiBase string, dryRun bool) func(ctx context.Context, entity, chain string, from, to uint64, hashes map[uint64]string) error {
+	if dryRun {
+		return func(context.Context, string, string, uint64, uint64, map[uint64]string) error { return nil }
+	}
+	return func(ctx context.Context, entity, chain string, from, to uint64, hashes map[uint64]string) error {
+		return postCanonicalBlocks(ctx, apiBase, entity, chain, from, to, hashes)
+	}
+}
+
+// postCanonicalBlocks 上报重扫区间 [from, to] 每个高度的规范区块哈希，API 删除区间内哈希不符的孤块事件
+func postCanonicalBlocks(ctx context.Context, apiBase, entity, chain string, from, to uint64, hashes map[uint64]string) error {
+	u := fmt.Sprintf("%s/ingest/canonical?entity=%s&chain=%s", strings.TrimRight(apiBase, "/"), url.QueryEscape(entity), url.QueryEscape(chain))
+	body := struct {
+		From   uint64            `json:"from"`
+		To     uint64            `json:"to"`
+		Hashes map[uint64]string `json:"hashes"`
+	}{from, to, hashes}
+	return netutil.PostJSON(ctx, u, body, &struct {
+		OK bool `json:"ok"`
+	}{})
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/sink"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ingestAPI 模拟 /ingest/events 与 /sync/cursor；ingest 请求超过 maxBatch 条或命中 failCall 时返回 413/500
+type ingestAPI struct {
+	mu       sync.Mutex
+	maxBatch int
+	failCall int
+	batches  []int
+	cursors  []uint64
+}
+
+func (a *ingestAPI) handler(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/ingest/events"):
+		var evs []models.Event
+		_ = json.NewDecoder(r.Body).Decode(&evs)
+		if len(evs) > a.maxBatch {
+			http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if a.failCall > 0 && len(a.batches)+1 == a.failCall {
+			a.failCall = 0
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		a.batches = append(a.batches, len(evs))
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	case r.URL.Path == "/sync/cursor":
+		var body map[string]uint64
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		a.cursors = append(a.cursors, body["block"])
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// TestIngestWindowChunksAndAdvancesCursorOnSuccess 大窗口按批次大小拆分，全部成功后才推进游标；中途失败游标不动，重试后推进
+func TestIngestWindowChunksAndAdvancesCursorOnSuccess(t *testing.T) {
+	api := &ingestAPI{maxBatch: 500, failCall: 3}
+	srv := httptest.NewServer(http.HandlerFunc(api.handler))
+	defer srv.Close()
+
+	s := sink.NewChunkedSink(sink.NewHTTPSink(srv.URL), 500)
+	events := make([]models.Event, 1800)
+	for i := range events {
+		events[i] = models.Event{Entity: "binance", Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "1", LogIndex: i}
+	}
+	advance := func() error { return postCursor(context.Background(), srv.URL, "binance", "ethereum", 1001) }
+
+	// 第 3 块失败：前两块已送达，但游标不推进
+	if err := ingestWindow(context.Background(), s, "binance", events, advance); err == nil {
+		t.Fatal("有批次失败时应返回错误")
+	}
+	api.mu.Lock()
+	if len(api.cursors) != 0 {
+		t.Fatalf("批次失败时不应推进游标，实际 %v", api.cursors)
+	}
+	if len(api.batches) != 2 {
+		t.Fatalf("失败前应已送达 2 块，实际 %v", api.batches)
+	}
+	api.batches = nil
+	api.mu.Unlock()
+
+	// 下一轮重扫同一窗口：全部成功后推进游标
+	if err := ingestWindow(context.Background(), s, "binance", events, advance); err != nil {
+		t.Fatal(err)
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if want := []int{500, 500, 500, 300}; len(api.batches) != len(want) {
+		t.Fatalf("期望批次 %v，实际 %v", want, api.batches)
+	} else {
+		for i := range want {
+			if api.batches[i] != want[i] {
+				t.Errorf("第%d块期望 %d 条，实际 %d", i, want[i], api.batches[i])
+			}
+		}
+	}
+	if len(api.cursors) != 1 || api.cursors[0] != 1001 {
+		t.Errorf("全部成功后应推进游标一次到 1001，实际 %v", api.cursors)
+	}
+}
+
+// TestIngestWindowDryRunMakesNoAPICalls dry-run：事件写成 NDJSON，ingest 与游标接口都不被调用；同一窗口可反复提交
+func TestIngestWindowDryRunMakesNoAPICalls(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		http.Error(w, "unexpected", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	var out strings.Builder
+	s := sink.NewChunkedSink(sink.NewNDJSONWriter(&out), 2)
+	commit := cursorPoster(srv.URL, true)
+	events := []models.Event{
+		{Entity: "binance", Chain: "tron", Coin: "USDT", Direction: "in", Amount: "1", TxID: "t1"},
+		{Entity: "binance", Chain: "tron", Coin: "USDT", Direction: "out", Amount: "2", TxID: "t2"},
+		{Entity: "binance", Chain: "tron", Coin: "USDT", Direction: "in", Amount: "3", TxID: "t3"},
+	}
+	for i := 0; i < 2; i++ {
+		if err := ingestWindow(context.Background(), s, "binance", events, func() error {
+			return commit(context.Background(), "binance", "tron", 101)
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	n := calls
+	mu.Unlock()
+	if n != 0 {
+		t.Fatalf("dry-run 不应调用 API，实际 %d 次", n)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 6 {
+		t.Fatalf("两次提交应写 6 行 NDJSON，实际 %d 行: %q", lines, out.String())
+	}
+
+	// 非 dry-run 时照常上报游标
+	err := cursorPoster(srv.URL, false)(context.Background(), "binance", "tron", 101)
+	mu.Lock()
+	defer mu.Unlock()
+	if err == nil || calls != 1 {
+		t.Fatalf("非 dry-run 应请求游标接口，calls=%d err=%v", calls, err)
+	}
+}
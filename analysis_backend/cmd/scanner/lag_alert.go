@@ -0,0 +1,174 @@
+// cmd/scanner/lag_alert.go
+// 游标滞后告警（-lag-alert）：每个窗口开始前按 链/实体 计算 latest - cursor，
+// 超过该链阈值且持续超过宽限期（-lag-alert-grace）时告警一次：写日志，配置了 -lag-alert-webhook 时以 JSON POST 推送。
+// 滞后回到阈值以内后记录恢复并重新布防。端点退化时游标会悄悄落后于链头，这里在数据变旧前发现。
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lagAlertTimeout 单次 Webhook 推送超时
+const lagAlertTimeout = 10 * time.Second
+
+// cursorLagAlert 一次游标滞后告警
+type cursorLagAlert struct {
+	Chain     string    `json:"chain"`
+	Entity    string    `json:"entity"`
+	Latest    uint64    `json:"latest"`
+	Cursor    uint64    `json:"cursor"`
+	Lag       uint64    `json:"lag"`
+	Threshold uint64    `json:"threshold"`
+	Since     time.Time `json:"since"` // 滞后首次超过阈值的时间
+	At        time.Time `json:"at"`
+}
+
+// lagState 单个 链/实体 的滞后状态
+type lagState struct {
+	since   time.Time // 超过阈值的起始时间，零值表示当前未超过
+	alerted bool
+}
+
+// cursorLagMonitor 按链阈值与宽限期判断游标滞后；EVM 扫描任务并发调用，加锁
+type cursorLagMonitor struct {
+	thresholds map[string]uint64 // 链 -> 区块/slot 数
+	grace      time.Duration
+	notify     func(cursorLagAlert) // 告警发出时调用（日志之外），可为空
+
+	mu     sync.Mutex
+	states map[[2]string]*lagState
+}
+
+func newCursorLagMonitor(thresholds map[string]uint64, grace time.Duration, notify func(cursorLagAlert)) *cursorLagMonitor {
+	return &cursorLagMonitor{thresholds: thresholds, grace: grace, notify: notify, states: map[[2]string]*lagState{}}
+}
+
+// Observe 记录一次 latest/cursor；滞后持续超过阈值达到宽限期时返回告警（同一次滞后只返回一次）。
+// 未配置阈值的链或 m 为 nil 时不做任何事
+func (m *cursorLagMonitor) Observe(chain, entity string, latest, cursor uint64, now time.Time) (cursorLagAlert, bool) {
+	if m == nil {
+		return cursorLagAlert{}, false
+	}
+	chain = canonicalChain(chain)
+	threshold, ok := m.thresholds[chain]
+	if !ok {
+		return cursorLagAlert{}, false
+	}
+	lag := uint64(0)
+	if latest > cursor {
+		lag = latest - cursor
+	}
+
+	m.mu.Lock()
+	key := [2]string{chain, entity}
+	st := m.states[key]
+	if st == nil {
+		st = &lagState{}
+		m.states[key] = st
+	}
+	if lag <= threshold {
+		if st.alerted {
+			log.Printf("[lag-alert] %s entity=%s recovered: lag=%d <= %d", chain, entity, lag, threshold)
+		}
+		*st = lagState{}
+		m.mu.Unlock()
+		return cursorLagAlert{}, false
+	}
+	if st.since.IsZero() {
+		st.since = now
+	}
+	if st.alerted || now.Sub(st.since) < m.grace {
+		m.mu.Unlock()
+		return cursorLagAlert{}, false
+	}
+	st.alerted = true
+	alert := cursorLagAlert{
+		Chain: chain, Entity: entity, Latest: latest, Cursor: cursor, Lag: lag,
+		Threshold: threshold, Since: st.since, At: now,
+	}
+	m.mu.Unlock()
+
+	log.Printf("[lag-alert] %s entity=%s cursor lagging: lag=%d > %d for %s (cursor=%d latest=%d)",
+		chain, entity, lag, threshold, now.Sub(alert.Since).Round(time.Second), cursor, latest)
+	if m.notify != nil {
+		m.notify(alert)
+	}
+	return alert, true
+}
+
+// Summary 阈值配置，供启动日志
+func (m *cursorLagMonitor) Summary() string {
+	chains := make([]string, 0, len(m.thresholds))
+	for c := range m.thresholds {
+		chains = append(chains, c)
+	}
+	sort.Strings(chains)
+	parts := make([]string, 0, len(chains))
+	for _, c := range chains {
+		parts = append(parts, fmt.Sprintf("%s=%d", c, m.thresholds[c]))
+	}
+	return strings.Join(parts, " ") + " grace=" + m.grace.String()
+}
+
+// parseLagThresholds 解析 "bitcoin=6,ethereum=300" 形式的按链滞后阈值（区块/slot 数，链名不区分大小写）
+func parseLagThresholds(s string) (map[string]uint64, error) {
+	out := map[string]uint64{}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		k, v, ok := strings.Cut(part, "=")
+		k = canonicalChain(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid lag threshold %q (want chain=blocks)", part)
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid lag threshold %q: must be a positive integer", part)
+		}
+		out[k] = n
+	}
+	return out, nil
+}
+
+// lagWebhookNotifier 告警以 JSON POST 推送到 url；后台发送，不阻塞扫描，失败只记日志
+func lagWebhookNotifier(url string) func(cursorLagAlert) {
+	return func(a cursorLagAlert) {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), lagAlertTimeout)
+			defer cancel()
+			if err := postLagAlert(ctx, url, a); err != nil {
+				log.Printf("[lag-alert] webhook %s: %v", metricsEndpoint(url), err)
+			}
+		}()
+	}
+}
+
+func postLagAlert(ctx context.Context, url string, a cursorLagAlert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestCursorLagMonitorFiresAfterGrace 滞后持续增长：超过阈值后满宽限期才告警，且同一次滞后只告警一次；恢复后重新布防
+func TestCursorLagMonitorFiresAfterGrace(t *testing.T) {
+	var fired []cursorLagAlert
+	m := newCursorLagMonitor(map[string]uint64{"bitcoin": 6}, 5*time.Minute, func(a cursorLagAlert) { fired = append(fired, a) })
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	// 游标停在 100，链头每分钟涨 1 块：第 7 分钟滞后 7 > 6 开始计时，第 12 分钟满 5 分钟宽限期
+	firedAt := -1
+	for minute := 0; minute <= 20; minute++ {
+		if _, ok := m.Observe("btc", "binance", 100+uint64(minute), 100, start.Add(time.Duration(minute)*time.Minute)); ok {
+			if firedAt >= 0 {
+				t.Fatalf("同一次滞后只应告警一次，第 %d 分钟再次告警", minute)
+			}
+			firedAt = minute
+		}
+	}
+	if firedAt != 12 || len(fired) != 1 {
+		t.Fatalf("期望第 12 分钟告警一次，实际第 %d 分钟，告警 %+v", firedAt, fired)
+	}
+	a := fired[0]
+	if a.Chain != "bitcoin" || a.Entity != "binance" || a.Lag != 12 || a.Threshold != 6 || !a.Since.Equal(start.Add(7*time.Minute)) {
+		t.Errorf("告警内容不符: %+v", a)
+	}
+
+	// 追平后恢复，再次滞后重新计时
+	now := start.Add(30 * time.Minute)
+	if _, ok := m.Observe("bitcoin", "binance", 130, 128, now); ok {
+		t.Fatal("滞后回到阈值以内不应告警")
+	}
+	if _, ok := m.Observe("bitcoin", "binance", 140, 128, now.Add(time.Minute)); ok {
+		t.Fatal("重新超过阈值后未满宽限期不应告警")
+	}
+	if _, ok := m.Observe("bitcoin", "binance", 145, 128, now.Add(6*time.Minute)); !ok {
+		t.Fatal("恢复后再次滞后满宽限期应重新告警")
+	}
+
+	// 未配置阈值的链与其它实体互不影响；nil 监控不告警
+	if _, ok := m.Observe("ethereum", "binance", 1_000_000, 0, now.Add(time.Hour)); ok {
+		t.Error("未配置阈值的链不应告警")
+	}
+	var off *cursorLagMonitor
+	if _, ok := off.Observe("bitcoin", "binance", 1000, 0, now); ok {
+		t.Error("未开启时不应告警")
+	}
+}
+
+// TestLagWebhookNotifier 告警以 JSON POST 推送
+func TestLagWebhookNotifier(t *testing.T) {
+	got := make(chan cursorLagAlert, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a cursorLagAlert
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&a) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		got <- a
+	}))
+	defer srv.Close()
+
+	lagWebhookNotifier(srv.URL)(cursorLagAlert{Chain: "solana", Entity: "okx", Lag: 5000, Threshold: 3000})
+	select {
+	case a := <-got:
+		if a.Chain != "solana" || a.Entity != "okx" || a.Lag != 5000 {
+			t.Errorf("推送内容不符: %+v", a)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到 Webhook 推送")
+	}
+}
+
+// TestParseLagThresholds 链名归一，非正整数报错
+func TestParseLagThresholds(t *testing.T) {
+	m, err := parseLagThresholds("btc=6, Ethereum=300;sol=3000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["bitcoin"] != 6 || m["ethereum"] != 300 || m["solana"] != 3000 || len(m) != 3 {
+		t.Errorf("解析结果不符: %v", m)
+	}
+	for _, bad := range []string{"bitcoin", "bitcoin=0", "bitcoin=-1", "=5"} {
+		if _, err := parseLagThresholds(bad); err == nil {
+			t.Errorf("%q 应报错", bad)
+		}
+	}
+}
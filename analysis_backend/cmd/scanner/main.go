@@ -2,9 +2,10 @@ package main
 
 import (
 	"analysis/internal/addr"
+	"analysis/internal/chains"
 	"analysis/internal/config"
 	"analysis/internal/models"
-	"analysis/internal/netutil"
+	"analysis/internal/sink"
 	"analysis/internal/util"
 	"bytes"
 	"context"
@@ -15,8 +16,13 @@ import (
 	"log"
 	"math/big"
 	"net/http"
+	"os"
+	"os/signal"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -54,6 +60,8 @@ type btcVin struct {
 }
 type btcVout struct {
 	Value               int64  `json:"value"`
+	ScriptPubKey        string `json:"scriptpubkey"`
+	ScriptPubKeyType    string `json:"scriptpubkey_type"` // op_return / multisig / p2pk / v0_p2wpkh ...
 	ScriptPubKeyAddress string `json:"scriptpubkey_address"`
 }
 
@@ -63,6 +71,7 @@ type solTransfer struct {
 	mint        string
 	decimals    int
 	amountDec   string
+	rawAmount   string // 指令未带精度（transfer）时的原始数量，amountDec 按默认精度换算
 	source      string
 	destination string
 }
@@ -89,12 +98,19 @@ func main() {
 	//only := flag.String("only", "BTC,ETH,SOL,USDC,USDT,BNB,XRP,ADA,DOGE,TON", "symbols to include")
 	//only := flag.String("only", "BNB,XRP,ADA,DOGE,TON", "symbols to include")
 	apiBase := flag.String("api", "http://localhost:8010", "api base for ingest")
+	dryRun := flag.Bool("dry-run", false, "write events as NDJSON to -out instead of ingesting, and never advance API cursors (start cursors are still read)")
+	dryRunOut := flag.String("out", "-", "dry-run: NDJSON output file, appended across restarts ('-' for stdout)")
+	outMaxBytes := flag.Int64("out-max-bytes", 0, "dry-run: rotate the -out file to <out>.<timestamp> once it reaches this size (0 = no rotation)")
+	outKeepBytes := flag.Int64("out-keep-bytes", 0, "dry-run: total size cap of rotated -out archives, oldest removed first, applied at startup and after each rotation (0 = unlimited)")
+	outKeepAge := flag.Duration("out-keep-age", 0, "dry-run: remove rotated -out archives older than this, applied at startup and after each rotation (0 = keep all)")
 	entityArg := flag.String("entity", "", "only this entity (optional)")
 
 	// PoR
 	zipBinance := flag.String("zip-binance", "wallet_address_20250801.zip", "Binance PoR zip file")
 	binanceEntity := flag.String("binance-entity", "binance", "entity tag for binance")
 	binanceIncludeDeposit := flag.Bool("binance-include-deposit", false, "include deposit addresses")
+	binancePORURL := flag.String("binance-por-url", "", "download Binance PoR zip from this URL instead of -zip-binance (cached by filename, resumes interrupted downloads)")
+	binancePORCache := flag.String("binance-por-cache", "data/por", "cache dir for downloaded Binance PoR zips")
 	okxPOR := flag.String("okx-por", "okx_por_202507042112.csv.zip", "OKX PoR zip/csv")
 	okxEntity := flag.String("okx-entity", "okx", "entity tag for okx")
 	okxIncludeDeposit := flag.Bool("okx-include-deposit", true, "include OKX deposit addresses (if any)")
@@ -102,21 +118,62 @@ func main() {
 
 	// 起始/轮询
 	startFrom := flag.Int64("start-block", -5, "start block if no cursor (EVM: latest-4, BTC: latest-1, Solana: latest-200)")
-	poll := flag.Duration("poll", 4*time.Second, "poll interval")
+	poll := flag.Duration("poll", 4*time.Second, "poll interval for chains without a built-in or -poll-chains interval")
+	pollChains := flag.String("poll-chains", "", "per-chain poll intervals overriding the built-in ones (bitcoin=1m solana=1s tron=3s ethereum=12s ...), e.g. 'bitcoin=2m,solana=500ms'")
+
+	// 实体调度
+	entitiesPerLoop := flag.Int("entities-per-loop", 0, "max entities scanned per loop (weighted round-robin; 0 = all entities every loop)")
+	entityWeightsFlag := flag.String("entity-weights", "", "per-entity scan weights, e.g. 'binance=5,okx=2' (default weight 1)")
+	entityMaxInterval := flag.Duration("entity-max-interval", 5*time.Minute, "every entity is scanned at least once within this interval")
+
+	// EVM
+	evmBloomFilter := flag.Bool("evm-bloom-filter", true, "use logsBloom of blocks fetched by the native scan to skip/narrow ERC20 getLogs ranges")
+	reorgDepth := flag.Uint64("reorg-depth", defaultEVMReorgDepth, "EVM: blocks to rewind when the parent hash of the next window no longer matches (0 = no reorg check)")
+	reorgDepthChains := flag.String("reorg-depth-chains", "", "per-chain reorg depth overrides, e.g. 'polygon=128,arbitrum=0'")
+	confirmationsFlag := flag.String("confirmations", "", "per-chain confirmation depth overrides; windows never scan past latest-confirmations (defaults: bitcoin=2, solana=32, EVM chains=12, tron=0), e.g. 'bitcoin=3,polygon=128'")
+	evmConcurrency := flag.Int("evm-concurrency", 4, "EVM: number of (chain, entity) windows scanned concurrently (1 = sequential)")
+	evmRPS := flag.Float64("evm-rps", 0, "EVM: per-endpoint target requests per second shared by all scan workers (<=0 to disable pacing)")
+	evmWS := flag.Bool("evm-ws", false, "EVM: subscribe to ERC20 Transfer logs over the chain's ws(s):// RPC endpoint (eth_subscribe), polling getLogs only to backfill gaps")
 
 	// 过滤链
 	excludeChainsFlag := flag.String("exclude-chains", "bsc,arbitrum,polygon,base", "comma/space separated chains to exclude, e.g. 'bsc, arbitrum'")
+	strictCoverage := flag.Bool("strict-coverage", false, "exit at startup if any chain with monitored addresses has no usable config")
+	chainFlagsPath := flag.String("chain-flags", "", "JSON file toggling chains at runtime, e.g. {\"bsc\": false}; re-read while running")
+	chainFlagsInterval := flag.Duration("chain-flags-interval", 10*time.Second, "how often to check the -chain-flags file")
+	includeFailedTx := flag.Bool("include-failed-tx", false, "emit events of failed transactions (Solana meta.err, EVM receipt status 0x0); skipped by default")
+	includeZeroValue := flag.Bool("include-zero-value", false, "emit zero-amount transfers (address-poisoning dust); skipped by default")
 
 	// Solana 限速/退避
 	solRPS := flag.Float64("sol-rps", 8, "Solana per-endpoint target requests per second (approx; <=0 to disable pacing)")
 	sol429Cooldown := flag.Duration("sol-429-cooldown", 8*time.Second, "initial cooldown for HTTP 429 backoff (exponential)")
+	solStep := flag.Int("sol-step", 200, "initial Solana scan window per entity (slots); adapted per entity at runtime")
+	solStepMin := flag.Int("sol-step-min", 20, "minimum Solana scan window (slots)")
+	solStepMax := flag.Int("sol-step-max", 1000, "maximum Solana scan window (slots)")
+	solWindowTarget := flag.Duration("sol-window-target", time.Minute, "target duration of one Solana scan window; the step grows or shrinks towards it")
+	solModeFlag := flag.String("sol-mode", solModeSlots, "Solana scan mode: slots (getBlock every slot) | signatures (getSignaturesForAddress per monitored address, cheaper for entities with few addresses)")
+	solSigLimit := flag.Int("sol-sig-limit", 100, "signatures mode: getSignaturesForAddress page size (max 1000)")
+	solSigCursorFile := flag.String("sol-sig-cursor-file", "data/sol_sig_cursor.json", "signatures mode: file mirroring per-address last-signature cursors (empty to keep them in memory only)")
+	solHealthFile := flag.String("sol-health-file", "data/sol_health.json", "persist Solana endpoint ban/cooldown state to this JSON file across restarts (empty to disable)")
+	solUnknownMintsFlag := flag.Bool("sol-unknown-mints", false, "emit SPL transfers of mints not in chains.solana.spl, labelled with the symbol from getTokenSupply/Metaplex metadata (cached; truncated mint when unresolved)")
+	solFinalizedOnly := flag.Bool("sol-finalized-only", false, "only scan Solana slots up to the finalized tip (block contents are still fetched at confirmed)")
+
+	// Tron
+	tronAPIKey := flag.String("tron-api-key", os.Getenv("TRON_PRO_API_KEY"), "TronGrid API key sent as TRON-PRO-API-KEY (default $TRON_PRO_API_KEY)")
+	tronStep := flag.Uint64("tron-step", 20, "Tron scan window per entity (blocks, ~3s each)")
 
 	// 日志
 	verbose := flag.Bool("v", true, "verbose logging")
 	logEvery := flag.Int("log-every", 200, "log progress every N blocks/slots")
+	lagAlertFlag := flag.String("lag-alert", "", "per-chain cursor lag thresholds in blocks/slots that raise an alert, e.g. 'bitcoin=6,ethereum=300,solana=3000' (empty to disable)")
+	lagAlertGrace := flag.Duration("lag-alert-grace", 5*time.Minute, "alert only when a chain/entity cursor stays over its -lag-alert threshold for this long")
+	lagAlertWebhook := flag.String("lag-alert-webhook", "", "POST cursor lag alerts as JSON to this URL (alerts are always logged)")
+	metricsAddr := flag.String("metrics-addr", "", "serve Prometheus metrics on this address (e.g. :9109, path /metrics); empty to disable")
+	shutdownGrace := flag.Duration("shutdown-grace", 2*time.Minute, "on SIGINT/SIGTERM, let in-flight windows finish and flush cursors for up to this long before aborting requests (0 = until a second signal)")
 
 	flag.Parse()
 	util.SetAllowed(*only)
+	txFilter = txFilterCfg{IncludeFailed: *includeFailedTx, IncludeZero: *includeZeroValue}
+	metricsServer := startMetricsServer(*metricsAddr, metrics)
 
 	logv := func(format string, args ...any) {
 		if *verbose {
@@ -142,6 +199,10 @@ func main() {
 	var cfg config.Config
 	config.MustLoad(*cfgPath, &cfg)
 	config.ApplyProxy(&cfg)
+	for ent, coins := range cfg.EntityCoinScopes() {
+		util.SetEntityAllowed(ent, coins)
+		log.Printf("[scope] entity=%s only=%s", ent, coins)
+	}
 
 	excludeSet := map[string]bool{}
 	if s := strings.TrimSpace(*excludeChainsFlag); s != "" {
@@ -161,7 +222,14 @@ func main() {
 	}
 
 	// 地址来源
-	rows := addr.RowsFromConfig(cfg)
+	rows, btcXPubs := addr.RowsAndXPubsFromConfig(cfg)
+	if *binancePORURL != "" {
+		p, err := addr.FetchBinancePORZip(context.Background(), *binancePORURL, *binancePORCache, nil)
+		if err != nil {
+			log.Fatalf("download binance por: %v", err)
+		}
+		*zipBinance = p
+	}
 	if *zipBinance != "" {
 		rs, err := addr.RowsFromBinancePORZip(*zipBinance, *binanceEntity, *binanceIncludeDeposit)
 		if err != nil {
@@ -179,13 +247,54 @@ func main() {
 	if len(rows) == 0 {
 		log.Fatal("no addresses from config/zip")
 	}
+	// 地址类型索引：上报前给事件打上 hot/cold/deposit 等标签
+	addrTypes := addr.NewTypeIndex(rows)
 
 	chainCfg := config.BuildChainCfg(&cfg)
+	mins, err := newMinAmounts(chainCfg)
+	if err != nil {
+		log.Fatalf("[config] %v", err)
+	}
+	minAmounts = mins
+
+	// 覆盖报告：有地址但无法扫描的链
+	if uncovered := logCoverageReport(buildCoverageReport(rows, chainCfg, excludeSet)); len(uncovered) > 0 && *strictCoverage {
+		log.Fatalf("[coverage] %d chain(s) with addresses are not covered (-strict-coverage)", len(uncovered))
+	}
+
+	// 事件下发目标（http / kafka / nats）
+	var evSink sink.EventSink
+	if *dryRun {
+		nd, err := sink.NewRotatingNDJSONSink(*dryRunOut, sink.NDJSONRotation{
+			MaxBytes:  *outMaxBytes,
+			Retention: util.RetentionPolicy{MaxAge: *outKeepAge, MaxBytes: *outKeepBytes},
+		})
+		if err != nil {
+			log.Fatalf("init dry-run output: %v", err)
+		}
+		evSink = sink.NewChunkedSink(nd, cfg.EventSink.BatchSize)
+		log.Printf("[dry-run] events -> %s, cursors are not advanced", *dryRunOut)
+	} else {
+		s, err := sink.New(&cfg, *apiBase)
+		if err != nil {
+			log.Fatalf("init event sink: %v", err)
+		}
+		evSink = s
+	}
+	commitCursor := cursorPoster(*apiBase, *dryRun)
+	commitCanonical := canonicalPoster(*apiBase, *dryRun)
+	defer evSink.Close()
+	logv("[init] event sink=%s", evSink.Name())
+	if *evmWS || *evmConcurrency > 1 {
+		// 订阅协程、并发扫描任务共用下发目标
+		evSink = &syncSink{EventSink: evSink}
+	}
 
-	// 分组：EVM/Bitcoin/Solana
+	// 分组：EVM/Bitcoin/Solana/Tron
 	addressesEVM := map[string]map[string][]string{} // chain -> entity -> addrs
 	addressesBTC := map[string][]string{}
 	addressesSOL := map[string][]string{}
+	addressesTRON := map[string][]string{} // entity -> base58 addrs
 	for _, r := range rows {
 		ent := r.Entity
 		if ent == "" {
@@ -200,6 +309,8 @@ func main() {
 			addressesBTC[ent] = append(addressesBTC[ent], strings.TrimSpace(r.Address))
 		case "solana", "sol":
 			addressesSOL[ent] = append(addressesSOL[ent], strings.TrimSpace(r.Address))
+		case "tron", "trx":
+			addressesTRON[ent] = append(addressesTRON[ent], tronNormalizeAddress(r.Address))
 		default:
 			if _, ok := addressesEVM[ch]; !ok {
 				addressesEVM[ch] = map[string][]string{}
@@ -207,7 +318,61 @@ func main() {
 			addressesEVM[ch][ent] = append(addressesEVM[ch][ent], strings.ToLower(strings.TrimSpace(r.Address)))
 		}
 	}
-	logv("[init] entities evm=%d chains, btc=%d entities, sol=%d entities", len(addressesEVM), len(addressesBTC), len(addressesSOL))
+	logv("[init] entities evm=%d chains, btc=%d entities, sol=%d entities, tron=%d entities", len(addressesEVM), len(addressesBTC), len(addressesSOL), len(addressesTRON))
+
+	entityWeights, err := parseEntityWeights(*entityWeightsFlag)
+	if err != nil {
+		log.Fatalf("-entity-weights: %v", err)
+	}
+	reorgDepths, err := parseReorgDepths(*reorgDepthChains)
+	if err != nil {
+		log.Fatalf("-reorg-depth-chains: %v", err)
+	}
+	confirmations, err := parseConfirmations(*confirmationsFlag)
+	if err != nil {
+		log.Fatalf("-confirmations: %v", err)
+	}
+	chainPolls, err := parseChainPoll(*pollChains)
+	if err != nil {
+		log.Fatalf("-poll-chains: %v", err)
+	}
+	lagThresholds, err := parseLagThresholds(*lagAlertFlag)
+	if err != nil {
+		log.Fatalf("-lag-alert: %v", err)
+	}
+	var lagAlerts *cursorLagMonitor
+	if len(lagThresholds) > 0 {
+		var notify func(cursorLagAlert)
+		if u := strings.TrimSpace(*lagAlertWebhook); u != "" {
+			notify = lagWebhookNotifier(u)
+		}
+		lagAlerts = newCursorLagMonitor(lagThresholds, *lagAlertGrace, notify)
+		logv("[init] cursor lag alerts: %s", lagAlerts.Summary())
+	}
+	solMode, err := parseSolMode(*solModeFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var allEntities []string
+	for _, ents := range addressesEVM {
+		for ent := range ents {
+			allEntities = append(allEntities, ent)
+		}
+	}
+	for ent := range addressesBTC {
+		allEntities = append(allEntities, ent)
+	}
+	for ent := range addressesSOL {
+		allEntities = append(allEntities, ent)
+	}
+	for ent := range addressesTRON {
+		allEntities = append(allEntities, ent)
+	}
+	scheduler := newEntityScheduler(allEntities, entityWeights, *entitiesPerLoop, *entityMaxInterval, time.Now())
+	if *entitiesPerLoop > 0 {
+		logv("[init] scan schedule: %d entities/loop of %d, weights=%v, max-interval=%s",
+			*entitiesPerLoop, len(scheduler.names), entityWeights, *entityMaxInterval)
+	}
 
 	/*************** EVM 初始化（支持多 RPC + fallback） ***************/
 	type evmChain struct {
@@ -219,6 +384,11 @@ func main() {
 		addressesByEnt   map[string][]string
 		includeNativeETH bool // 仅以太坊主网
 		nativeSymbol     string
+		wsURL            string       // -evm-ws 时的订阅端点
+		decMu            *sync.Mutex  // decimalsCache 在轮询与订阅协程间共享
+		traceMode        string       // 内部转账 trace 方式（debug/parity），为空不扫描
+		traceOff         *atomic.Bool // 节点不支持 trace 时置位，各扫描任务共享
+		batchOff         *atomic.Bool // 节点不支持 JSON-RPC 批量请求时置位，各扫描任务共享
 	}
 	evmChains := []evmChain{}
 
@@ -233,6 +403,18 @@ func main() {
 			log.Printf("[warn] chain %s rpc list is empty after parsing", ch)
 			continue
 		}
+		wsURL := ""
+		if *evmWS {
+			if ws, httpRPCs := splitWSEndpoints(rpcs); ws != "" {
+				wsURL, rpcs = ws, httpRPCs
+			} else {
+				log.Printf("[warn] chain %s has no ws(s):// rpc, -evm-ws falls back to polling", ch)
+			}
+		}
+		traceMode, err := evmTraceMode(cc.TraceInternal)
+		if err != nil {
+			log.Fatalf("chains.%s.trace_internal: %v", ch, err)
+		}
 		contractToSymbol := map[string]string{}
 		for _, t := range cc.ERC20 {
 			addr := strings.ToLower(strings.TrimSpace(t.Address))
@@ -246,19 +428,25 @@ func main() {
 			rpcList:          rpcs,
 			rpcIdx:           0,
 			contractToSym:    contractToSymbol,
-			decimalsCache:    map[string]int{},
+			decimalsCache:    erc20DecimalsOverrides(ch, cc.ERC20),
 			addressesByEnt:   ents,
 			includeNativeETH: ch == "ethereum",
-			nativeSymbol:     evmNativeSymbol(ch),
+			nativeSymbol:     evmNativeSymbol(cc),
+			wsURL:            wsURL,
+			decMu:            &sync.Mutex{},
+			traceMode:        traceMode,
+			traceOff:         &atomic.Bool{},
+			batchOff:         &atomic.Bool{},
 		})
 	}
 	for _, ec := range evmChains {
-		logv("[init] evm %s rpc=%v tokens=%v", ec.name, ec.rpcList, keys(ec.contractToSym))
+		logv("[init] evm %s rpc=%v tokens=%v trace=%q", ec.name, ec.rpcList, keys(ec.contractToSym), ec.traceMode)
 	}
 
 	/*************** BTC 初始化 ***************/
 	var btcAPIs []string
 	var btcAPIIdx int
+	var btcChain config.ChainCfg
 	if len(addressesBTC) > 0 && !excludeSet["bitcoin"] && !excludeSet["btc"] {
 		btc, ok := chainCfg["bitcoin"]
 		if !ok || strings.TrimSpace(btc.Esplora) == "" {
@@ -268,13 +456,15 @@ func main() {
 		if len(btcAPIs) == 0 {
 			log.Fatal("chains.bitcoin.esplora resolved empty endpoints")
 		}
+		btcChain = btc
 		logv("[init] bitcoin esplora=%v", btcAPIs)
 	}
 
 	/*************** Solana 初始化 ***************/
 	var solRPCs []string
-	var solRPCIdx int
 	var mintToSymbol = map[string]string{}
+	// 每个实体独立的扫描步长，慢实体不拖累其它实体
+	solSteps := newSolStepController(*solStep, *solStepMin, *solStepMax, *solWindowTarget)
 	if len(addressesSOL) > 0 && !excludeSet["solana"] && !excludeSet["sol"] {
 		sol, ok := chainCfg["solana"]
 		if !ok || strings.TrimSpace(sol.RPC) == "" {
@@ -290,11 +480,43 @@ func main() {
 				mintToSymbol[m] = strings.ToUpper(strings.TrimSpace(sp.Symbol))
 			}
 		}
-		logv("[init] solana rpc=%v spl=%v", solRPCs, keys(mintToSymbol))
+		logv("[init] solana rpc=%v spl=%v mode=%s", solRPCs, keys(mintToSymbol), solMode)
+	}
+
+	/*************** Tron 初始化 ***************/
+	var tronAPIs []string
+	var tronAPIIdx int
+	tronTokens := map[string]tronToken{} // 20 字节 hex 合约（小写，不带 41） -> 代币
+	if len(addressesTRON) > 0 && !excludeSet["tron"] && !excludeSet["trx"] {
+		tron, ok := chainCfg["tron"]
+		if !ok || strings.TrimSpace(tron.RPC) == "" {
+			log.Fatal("chains.tron.rpc not configured")
+		}
+		tronAPIs = parseRPCList(tron.RPC)
+		if len(tronAPIs) == 0 {
+			log.Fatal("chains.tron.rpc empty after parsing")
+		}
+		for _, t := range tron.TRC20 {
+			h, err := tronBase58ToHex(t.Contract)
+			if err != nil {
+				log.Printf("[warn] chain tron token %s: %v, skip", t.Symbol, err)
+				continue
+			}
+			dec, ok := config.DecimalsOverride(t.Decimals)
+			if !ok {
+				if dec, err = tronDecimals(context.Background(), tronAPIs[0], *tronAPIKey, strings.TrimSpace(t.Contract)); err != nil || dec <= 0 || dec > config.MaxTokenDecimals {
+					log.Printf("[warn] chain tron token %s decimals() failed (%v), using %d", t.Symbol, err, tronDefaultDecimals)
+					dec = tronDefaultDecimals
+				}
+			}
+			tronTokens[h[2:]] = tronToken{Symbol: strings.ToUpper(strings.TrimSpace(t.Symbol)), Decimals: dec}
+		}
+		logv("[init] tron api=%v trc20=%v", tronAPIs, tronTokens)
 	}
 
 	/*************** RPC helpers ***************/
-	// —— EVM：多端点轮询封装（带重试和指数退避）
+	// —— EVM：多端点轮询封装（带重试和指数退避）；端点限速由所有扫描任务共享
+	evmPacer := newEVMEndpointPacer(*evmRPS)
 	evmPost := func(ctx context.Context, ec *evmChain, method string, params []interface{}, out *rpcResp) error {
 		var lastErr error
 		maxRetries := len(ec.rpcList) * 2 // 每个端点最多重试 2 次
@@ -305,6 +527,9 @@ func main() {
 			idx := (ec.rpcIdx + attempt) % len(ec.rpcList)
 			base := strings.TrimRight(ec.rpcList[idx], "/")
 
+			if err := evmPacer.Wait(ctx, base); err != nil {
+				return err
+			}
 			// 创建带超时的 context（每次重试都重新创建）
 			rpcCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
 			err := postRPC(rpcCtx, base, method, params, out)
@@ -316,7 +541,13 @@ func main() {
 			}
 
 			lastErr = fmt.Errorf("rpc %s by %s => %w", method, base, err)
+			if isLogRangeTooLarge(err) {
+				// 结果过多由调用方缩小区块跨度重试；记下报错端点，跨度上限按端点记录
+				ec.rpcIdx = idx
+				return lastErr
+			}
 
+			metrics.RPCError(ec.name, base)
 			// 判断错误类型
 			errStr := strings.ToLower(err.Error())
 			isEOF := strings.Contains(errStr, "eof") || strings.Contains(errStr, "connection reset")
@@ -360,9 +591,10 @@ func main() {
 		n, _ := new(big.Int).SetString(strings.TrimPrefix(x, "0x"), 16)
 		return n.Uint64(), nil
 	}
-	evmGetBlock := func(ctx context.Context, ec *evmChain, num uint64) (map[string]any, error) {
+	// fullTx=false 时只返回区块头与交易哈希
+	evmGetBlock := func(ctx context.Context, ec *evmChain, num uint64, fullTx bool) (map[string]any, error) {
 		var out rpcResp
-		if err := evmPost(ctx, ec, "eth_getBlockByNumber", []interface{}{fmt.Sprintf("0x%x", num), true}, &out); err != nil {
+		if err := evmPost(ctx, ec, "eth_getBlockByNumber", []interface{}{fmt.Sprintf("0x%x", num), fullTx}, &out); err != nil {
 			return nil, err
 		}
 		var m map[string]any
@@ -371,7 +603,44 @@ func main() {
 		}
 		return m, nil
 	}
-	evmGetLogs := func(ctx context.Context, ec *evmChain, from, to uint64, contract string, fromAddrs, toAddrs []string) ([]map[string]any, error) {
+	// 批量拉取区块头，只发往当前端点不重试；失败时本次退回逐个拉取，节点明确不支持批量时此后该链一直逐个拉取
+	evmGetBlockHeaders := func(ctx context.Context, ec *evmChain, nums []uint64) (map[uint64]map[string]any, error) {
+		if ec.batchOff.Load() {
+			return nil, fmt.Errorf("rpc batch disabled")
+		}
+		base := strings.TrimRight(ec.rpcList[ec.rpcIdx%len(ec.rpcList)], "/")
+		if err := evmPacer.Wait(ctx, base); err != nil {
+			return nil, err
+		}
+		rpcCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
+		got, err := postRPCBatch(rpcCtx, base, nums)
+		cancel()
+		if ctx.Err() == nil {
+			disableRPCBatch(ec.name, base, ec.batchOff, err)
+		}
+		return got, err
+	}
+	evmGetReceipt := func(ctx context.Context, ec *evmChain, hash string) (map[string]any, error) {
+		var out rpcResp
+		if err := evmPost(ctx, ec, "eth_getTransactionReceipt", []interface{}{hash}, &out); err != nil {
+			return nil, err
+		}
+		var m map[string]any
+		if err := json.Unmarshal(out.Result, &m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	// 整块内部转账；txHashes 用于旧版 callTracer 结果不带 txHash 时按顺序对应
+	evmTraceBlock := func(ctx context.Context, ec *evmChain, num uint64, txHashes []string) ([]evmInternalTransfer, error) {
+		method, params := evmTraceRequest(ec.traceMode, num)
+		var out rpcResp
+		if err := evmPost(ctx, ec, method, params, &out); err != nil {
+			return nil, err
+		}
+		return parseBlockTraces(ec.traceMode, out.Result, txHashes)
+	}
+	evmGetLogsRange := func(ctx context.Context, ec *evmChain, from, to uint64, contract string, fromAddrs, toAddrs []string) ([]map[string]any, error) {
 		p := map[string]any{
 			"fromBlock": fmt.Sprintf("0x%x", from),
 			"toBlock":   fmt.Sprintf("0x%x", to),
@@ -386,35 +655,35 @@ func main() {
 		if err := evmPost(ctx, ec, "eth_getLogs", []interface{}{p}, &out); err != nil {
 			return nil, err
 		}
-		if len(out.Result) == 0 || string(out.Result) == "null" {
-			return nil, fmt.Errorf("eth_getLogs via %s: empty result", strings.Join(ec.rpcList, ","))
-		}
 		var arr []map[string]any
-		if err := json.Unmarshal(out.Result, &arr); err != nil {
+		if err := decodeResult("eth_getLogs via "+strings.Join(ec.rpcList, ","), &out, &arr); err != nil {
 			return nil, err
 		}
 		return arr, nil
 	}
+	// 区块跨度按端点自适应：结果过多时二分重试
+	evmLogWin := newEVMLogWindows()
+	evmGetLogs := func(ctx context.Context, ec *evmChain, from, to uint64, contract string, fromAddrs, toAddrs []string) ([]map[string]any, error) {
+		return getLogsAdaptive(from, to, evmLogWin, func() string { return ec.rpcList[ec.rpcIdx] }, func(from, to uint64) ([]map[string]any, error) {
+			return evmGetLogsRange(ctx, ec, from, to, contract, fromAddrs, toAddrs)
+		})
+	}
 	evmDecimals := func(ctx context.Context, ec *evmChain, contract string) (int, error) {
-		if v, ok := ec.decimalsCache[contract]; ok {
-			return v, nil
-		}
-		call := map[string]any{"to": contract, "data": "0x313ce567"}
-		var out rpcResp
-		if err := evmPost(ctx, ec, "eth_call", []interface{}{call, "latest"}, &out); err != nil {
-			return 18, err
-		}
-		var x string
-		if err := json.Unmarshal(out.Result, &x); err != nil {
-			return 18, err
-		}
-		n, _ := new(big.Int).SetString(strings.TrimPrefix(x, "0x"), 16)
-		d := int(n.Int64())
-		if d <= 0 || d > 36 {
-			d = 18
-		}
-		ec.decimalsCache[contract] = d
-		return d, nil
+		ec.decMu.Lock()
+		defer ec.decMu.Unlock()
+		return erc20Decimals(ec.decimalsCache, contract, func() (int, error) {
+			call := map[string]any{"to": contract, "data": "0x313ce567"}
+			var out rpcResp
+			if err := evmPost(ctx, ec, "eth_call", []interface{}{call, "latest"}, &out); err != nil {
+				return 0, err
+			}
+			var x string
+			if err := json.Unmarshal(out.Result, &x); err != nil {
+				return 0, err
+			}
+			n, _ := new(big.Int).SetString(strings.TrimPrefix(x, "0x"), 16)
+			return int(n.Int64()), nil
+		})
 	}
 
 	// —— BTC（带 fallback）
@@ -430,26 +699,11 @@ func main() {
 				return txt, nil
 			}
 			lastErr = err
+			metrics.RPCError("bitcoin", base)
 			log.Printf("[btc] fallback %s: %v", url, err)
 		}
 		return "", lastErr
 	}
-	btcGetJSON := func(ctx context.Context, path string, out any) error {
-		var lastErr error
-		for i := 0; i < len(btcAPIs); i++ {
-			idx := (btcAPIIdx + i) % len(btcAPIs)
-			base := strings.TrimRight(btcAPIs[idx], "/")
-			url := base + path
-			if err := getJSON(ctx, url, out); err == nil {
-				btcAPIIdx = idx
-				return nil
-			} else {
-				lastErr = err
-				log.Printf("[btc] fallback %s: %v", url, err)
-			}
-		}
-		return lastErr
-	}
 	btcTipHeight := func(ctx context.Context) (uint64, error) {
 		txt, err := btcGetText(ctx, "/blocks/tip/height")
 		if err != nil {
@@ -462,47 +716,61 @@ func main() {
 	btcBlockHash := func(ctx context.Context, height uint64) (string, error) {
 		return btcGetText(ctx, fmt.Sprintf("/block-height/%d", height))
 	}
+	// 区块交易分页：整块在同一端点内翻页（各端点分页参数可能不同），首页失败才切换端点
 	btcBlockTxs := func(ctx context.Context, blockHash string) ([]btcTx, error) {
-		const pageSize = 25
-		var all []btcTx
-		offset := 0
-		for {
-			path := fmt.Sprintf("/block/%s/txs", blockHash)
-			if offset > 0 {
-				path = fmt.Sprintf("/block/%s/txs/%d", blockHash, offset)
-			}
-			var arr []btcTx
-			if err := btcGetJSON(ctx, path, &arr); err != nil {
-				if offset == 0 {
-					return all, err
+		var lastErr error
+		for i := 0; i < len(btcAPIs); i++ {
+			idx := (btcAPIIdx + i) % len(btcAPIs)
+			paging := btcChain.EsploraPagingFor(btcAPIs[idx])
+			txs, truncated, err := chains.EsploraBlockTxs[btcTx](ctx, btcAPIs[idx], blockHash, paging, getJSON)
+			if err == nil {
+				btcAPIIdx = idx
+				if truncated {
+					log.Printf("[btc] block %s 只读到前 %d 笔交易（%s 的 max_offset 限制），其余交易本轮不计入", blockHash, len(txs), btcAPIs[idx])
 				}
-				break
-			}
-			if len(arr) == 0 {
-				break
+				return txs, nil
 			}
-			all = append(all, arr...)
-			if len(arr) < pageSize {
-				break
-			}
-			offset += pageSize
-			if offset > 20000 {
-				break
+			lastErr = err
+			metrics.RPCError("bitcoin", btcAPIs[idx])
+			log.Printf("[btc] fallback %s block %s: %v", btcAPIs[idx], blockHash, err)
+		}
+		return nil, lastErr
+	}
+
+	// —— Tron（TronGrid，多端点 fallback）
+	tronCall := func(what string, call func(base string) error) error {
+		var lastErr error
+		for i := 0; i < len(tronAPIs); i++ {
+			idx := (tronAPIIdx + i) % len(tronAPIs)
+			err := call(tronAPIs[idx])
+			if err == nil {
+				tronAPIIdx = idx
+				return nil
 			}
+			lastErr = err
+			metrics.RPCError("tron", tronAPIs[idx])
+			log.Printf("[tron] fallback %s %s: %v", tronAPIs[idx], what, err)
 		}
-		return all, nil
+		return lastErr
+	}
+	tronLatest := func(ctx context.Context) (uint64, error) {
+		var n uint64
+		err := tronCall("latest", func(base string) (err error) {
+			n, err = tronLatestBlock(ctx, base, *tronAPIKey)
+			return err
+		})
+		return n, err
+	}
+	tronBlock := func(ctx context.Context, num uint64) ([]tronTxInfo, error) {
+		var infos []tronTxInfo
+		err := tronCall(fmt.Sprintf("block %d", num), func(base string) (err error) {
+			infos, err = tronBlockTxInfos(ctx, base, *tronAPIKey, num)
+			return err
+		})
+		return infos, err
 	}
 
 	/*************** Solana（多端点 fallback + 限速 + 封禁/冷却 + 降级/退避） ***************/
-	var (
-		// 端点健康状态
-		solBan                = map[string]time.Time{}     // endpoint -> unbanTime (403/-32052)
-		solCooldown           = map[string]time.Time{}     // endpoint -> coolUntil (429)
-		solCooldownDur        = map[string]time.Duration{} // endpoint -> 当前退避时长（指数退避）
-		solLastCall           = map[string]time.Time{}     // endpoint -> 上次调用时间（限速）
-		solLastDegradeAttempt = map[string]time.Time{}     // endpoint -> 最近一次降级尝试时间
-	)
-
 	// 限速：每端点目标 RPS（<=0 表示不节流）
 	var solMinInterval time.Duration
 	if *solRPS > 0 {
@@ -510,37 +778,11 @@ func main() {
 		if solMinInterval < 10*time.Millisecond {
 			solMinInterval = 10 * time.Millisecond
 		}
-	} else {
-		solMinInterval = 0
-	}
-	// 429 初始冷却
-	baseCooldown := *sol429Cooldown
-	if baseCooldown <= 0 {
-		baseCooldown = 8 * time.Second
 	}
-	// 降级策略：只在冷却即将结束时（<=1s）尝试一次；同端点降级尝试最小间隔 6s
-	degradeHeadstart := 1 * time.Second
-	degradeMinGap := 6 * time.Second
-	maxBackoff := 60 * time.Second
+	solEPs := newSolEndpoints(solRPCs, solMinInterval, *sol429Cooldown, util.SystemClock)
 
 	errNoSolEndpoint := fmt.Errorf("no solana endpoint available (banned/cooling)")
 
-	solHealth := func(now time.Time) string {
-		var okList, coolList, banList []string
-		for _, ep := range solRPCs {
-			ep = strings.TrimRight(ep, "/")
-			if until, banned := solBan[ep]; banned && now.Before(until) {
-				banList = append(banList, fmt.Sprintf("%s(until=%s)", ep, until.UTC().Format(time.RFC3339)))
-				continue
-			}
-			if until, cooling := solCooldown[ep]; cooling && now.Before(until) {
-				coolList = append(coolList, fmt.Sprintf("%s(until=%s)", ep, until.UTC().Format(time.RFC3339)))
-				continue
-			}
-			okList = append(okList, ep)
-		}
-		return fmt.Sprintf("healthy=%v cooling=%v banned=%v", okList, coolList, banList)
-	}
 	shouldBanSol := func(err error) (bool, time.Duration, string) {
 		if err == nil {
 			return false, 0, ""
@@ -563,129 +805,60 @@ func main() {
 		s := strings.ToLower(err.Error())
 		return strings.Contains(s, "429") || strings.Contains(s, "too many requests")
 	}
-	isBanned := func(ep string, now time.Time) (bool, time.Time) {
-		ep = strings.TrimRight(ep, "/")
-		if until, ok := solBan[ep]; ok && now.Before(until) {
-			return true, until
-		}
-		return false, time.Time{}
-	}
-	isCooling := func(ep string, now time.Time) (bool, time.Time) {
-		ep = strings.TrimRight(ep, "/")
-		if until, ok := solCooldown[ep]; ok && now.Before(until) {
-			return true, until
-		}
-		return false, time.Time{}
-	}
-	waitRate := func(ep string) {
-		ep = strings.TrimRight(ep, "/")
-		if solMinInterval <= 0 {
-			return
-		}
-		if last, ok := solLastCall[ep]; ok {
-			sleep := last.Add(solMinInterval).Sub(time.Now())
-			if sleep > 0 && sleep < 5*time.Second {
-				time.Sleep(sleep)
-			}
-		}
-	}
-
-	// 端点选择：优先健康端点；否则仅在冷却即将结束（<=1s）且距上次降级>=6s 的端点上进行一次“降级尝试”
-	chooseSolEndpoint := func(now time.Time) (base string, degraded bool) {
-		// 健康优先
-		for i := 0; i < len(solRPCs); i++ {
-			idx := (solRPCIdx + i) % len(solRPCs)
-			cand := strings.TrimRight(solRPCs[idx], "/")
-			if banned, _ := isBanned(cand, now); banned {
-				continue
-			}
-			if cooling, _ := isCooling(cand, now); cooling {
-				continue
-			}
-			solRPCIdx = idx
-			return cand, false
-		}
-		// 降级：找最早解冻且满足“即将结束冷却 + 降级间隔”的端点
-		type cand struct {
-			ep    string
-			until time.Time
-		}
-		var cds []cand
-		for _, ep := range solRPCs {
-			base := strings.TrimRight(ep, "/")
-			if banned, _ := isBanned(base, now); banned {
-				continue
-			}
-			if until, cooling := solCooldown[base]; cooling && now.Before(until) {
-				remain := until.Sub(now)
-				if remain <= degradeHeadstart {
-					if last, ok := solLastDegradeAttempt[base]; !ok || now.Sub(last) >= degradeMinGap {
-						cds = append(cds, cand{ep: base, until: until})
-					}
-				}
-			}
-		}
-		if len(cds) == 0 {
-			return "", false
+	// 封禁/冷却状态持久化（-sol-health-file）：启动时恢复未到期的记录，变化后防抖写盘
+	solHealthStore := newSolHealthStore(*solHealthFile, solHealthSaveDebounce, solEPs.Snapshot)
+	if len(solRPCs) > 0 {
+		if solEPs.Restore(loadSolHealth(*solHealthFile, time.Now())) > 0 {
+			log.Printf("[solana] restored endpoint health from %s: %s", *solHealthFile, solEPs.Health())
 		}
-		sort.Slice(cds, func(i, j int) bool { return cds[i].until.Before(cds[j].until) })
-		return cds[0].ep, true
 	}
 
 	solPost := func(ctx context.Context, method string, params []any, out *rpcResp) error {
 		if len(solRPCs) == 0 {
 			return fmt.Errorf("no solana rpc configured")
 		}
-		now := time.Now()
+		defer func() { solHealthStore.Maybe(time.Now()) }()
 		var tried bool
 		var lastErr error
 
 		for attempt := 0; attempt < len(solRPCs); attempt++ {
-			base, degraded := chooseSolEndpoint(now)
+			base, degraded := solEPs.Choose()
 			if base == "" {
 				break
 			}
 			tried = true
 			if degraded {
 				log.Printf("[solana] DEGRADE try cooldown endpoint %s", base)
-				solLastDegradeAttempt[base] = now
+				solEPs.MarkDegrade(base)
 			}
 
-			waitRate(base)
+			solEPs.WaitRate(base)
 			cctx, cancel := context.WithTimeout(ctx, 20*time.Second)
 			err := postRPC(cctx, base, method, params, out)
 			cancel()
-			solLastCall[base] = time.Now()
+			solEPs.Called(base)
 
 			if err == nil {
 				// 成功：清理冷却记录
-				delete(solCooldown, base)
-				delete(solCooldownDur, base)
+				if solEPs.Succeeded(base) {
+					solHealthStore.Changed()
+				}
 				return nil
 			}
 
 			// 分类处理错误
 			lastErr = fmt.Errorf("rpc %s by %s => %w", method, base, err)
+			metrics.RPCError("solana", base)
 
 			// 403/权限问题：长时间封禁
 			if ban, dur, why := shouldBanSol(err); ban {
-				until := time.Now().Add(dur)
-				solBan[base] = until
+				solEPs.Ban(base, dur)
+				solHealthStore.Changed()
 				log.Printf("[solana] BAN %s for %s reason=%s err=%v", base, dur, why, err)
 			} else if is429(err) {
 				// 429：指数退避
-				cur := solCooldownDur[base]
-				if cur <= 0 {
-					cur = baseCooldown
-				} else {
-					cur = cur * 2
-					if cur > maxBackoff {
-						cur = maxBackoff
-					}
-				}
-				solCooldownDur[base] = cur
-				until := time.Now().Add(cur)
-				solCooldown[base] = until
+				cur := solEPs.RateLimited(base)
+				solHealthStore.Changed()
 				log.Printf("[solana] COOL %s for %s reason=429 err=%v", base, cur, err)
 
 				// 若本次是降级尝试，避免在同一次调用里继续循环降级；交给上层下一轮再来
@@ -698,31 +871,23 @@ func main() {
 			}
 
 			// 下一轮尝试其它端点
-			time.Sleep(200 * time.Millisecond)
-			now = time.Now()
+			solEPs.clock.Sleep(200 * time.Millisecond)
 		}
 
 		if !tried {
-			log.Printf("[solana] all endpoints skipped (%s)", solHealth(time.Now()))
+			log.Printf("[solana] all endpoints skipped (%s)", solEPs.Health())
 			return errNoSolEndpoint
 		}
 		log.Printf("[solana] all endpoints failed: %v", lastErr)
-		log.Printf("[solana] health: %s", solHealth(time.Now()))
+		log.Printf("[solana] health: %s", solEPs.Health())
 		return lastErr
 	}
+	if *solUnknownMintsFlag && len(solRPCs) > 0 {
+		solUnknownMints = newSolMintResolver(solPost, mintToSymbol)
+		logv("[init] solana unknown mints: resolving symbols via getTokenSupply/Metaplex metadata")
+	}
 	solLatestSlot := func(ctx context.Context) (uint64, error) {
-		var out rpcResp
-		if err := solPost(ctx, "getSlot", []any{}, &out); err != nil {
-			return 0, err
-		}
-		if len(out.Result) == 0 || string(out.Result) == "null" {
-			return 0, fmt.Errorf("getSlot empty result")
-		}
-		var n uint64
-		if err := json.Unmarshal(out.Result, &n); err != nil {
-			return 0, err
-		}
-		return n, nil
+		return solTipSlot(ctx, solPost, *solFinalizedOnly)
 	}
 	solGetBlock := func(ctx context.Context, slot uint64) (map[string]any, error) {
 		opts := map[string]any{
@@ -736,21 +901,26 @@ func main() {
 		if err := solPost(ctx, "getBlock", []any{slot, opts}, &out); err != nil {
 			return nil, err
 		}
-		if len(out.Result) == 0 || string(out.Result) == "null" {
-			return nil, fmt.Errorf("block %d not available", slot)
-		}
 		var blk map[string]any
-		if err := json.Unmarshal(out.Result, &blk); err != nil {
+		if err := decodeResult(fmt.Sprintf("getBlock slot %d", slot), &out, &blk); err != nil {
 			return nil, err
 		}
 		return blk, nil
 	}
 
 	/*************** 读取游标 ***************/
-	ctx := context.Background()
+	// SIGINT/SIGTERM：shutdown 取消后不再开始新窗口，订阅协程关闭连接并下发剩余事件；
+	// ctx（RPC、上报、游标提交）在宽限期内保持可用，进行中的窗口跑完并提交游标
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	shutdown, ctx, stop := shutdownContexts(sigs, *shutdownGrace)
+	defer stop()
 
 	// EVM
-	cursorEVM := map[string]map[string]uint64{} // chain->entity->block
+	evmState := newEVMScanState()                           // chain->entity->block / rpc 端点下标
+	reorgEVM := map[string]map[string]*evmReorgGuard{}      // chain->entity->已扫描区块哈希
+	nativeSeenEVM := map[string]map[string]*evmNativeSeen{} // chain->entity->已下发的原生币事件
 	for i := range evmChains {
 		ec := &evmChains[i]
 		latest, err := evmLatestBlock(ctx, ec)
@@ -758,9 +928,11 @@ func main() {
 			log.Printf("[cursor] %s latest error: %v", ec.name, err)
 			continue
 		}
-		if cursorEVM[ec.name] == nil {
-			cursorEVM[ec.name] = map[string]uint64{}
+		if reorgEVM[ec.name] == nil {
+			reorgEVM[ec.name] = map[string]*evmReorgGuard{}
+			nativeSeenEVM[ec.name] = map[string]*evmNativeSeen{}
 		}
+		depth := reorgDepthFor(ec.name, reorgDepths, *reorgDepth)
 		for entity := range ec.addressesByEnt {
 			if *entityArg != "" && !strings.EqualFold(*entityArg, entity) {
 				continue
@@ -771,16 +943,17 @@ func main() {
 			url := fmt.Sprintf("%s/sync/cursor?entity=%s&chain=%s", strings.TrimRight(*apiBase, "/"), entity, ec.name)
 			if err := getJSON(ctx, url, &curResp); err != nil || curResp.Block == 0 {
 				if *startFrom >= 0 {
-					cursorEVM[ec.name][entity] = uint64(*startFrom)
-				} else if latest > 4 {
-					cursorEVM[ec.name][entity] = latest - 4
+					evmState.SetCursor(ec.name, entity, uint64(*startFrom))
 				} else {
-					cursorEVM[ec.name][entity] = latest
+					evmState.SetCursor(ec.name, entity, startCursor(latest, 4, confirmationsFor(ec.name, confirmations)))
 				}
 			} else {
-				cursorEVM[ec.name][entity] = curResp.Block
+				evmState.SetCursor(ec.name, entity, curResp.Block)
 			}
-			log.Printf("[cursor] %s entity=%s start=%d (latest=%d)", ec.name, entity, cursorEVM[ec.name][entity], latest)
+			reorgEVM[ec.name][entity] = newEVMReorgGuard(depth)
+			nativeSeenEVM[ec.name][entity] = newEVMNativeSeen()
+			log.Printf("[cursor] %s entity=%s start=%d (latest=%d, reorg-depth=%d)", ec.name, entity, evmState.Cursor(ec.name, entity), latest, depth)
+			metrics.SetCursor(ec.name, entity, evmState.Cursor(ec.name, entity))
 		}
 	}
 
@@ -802,15 +975,14 @@ func main() {
 				if err := getJSON(ctx, url, &curResp); err != nil || curResp.Block == 0 {
 					if *startFrom >= 0 {
 						cursorBTC[entity] = uint64(*startFrom)
-					} else if latest > 1 {
-						cursorBTC[entity] = latest - 1
 					} else {
-						cursorBTC[entity] = latest
+						cursorBTC[entity] = startCursor(latest, 1, confirmationsFor("bitcoin", confirmations))
 					}
 				} else {
 					cursorBTC[entity] = curResp.Block
 				}
 				log.Printf("[cursor] btc entity=%s start=%d (latest=%d)", entity, cursorBTC[entity], latest)
+				metrics.SetCursor("bitcoin", entity, cursorBTC[entity])
 			}
 		}
 	}
@@ -833,358 +1005,588 @@ func main() {
 				if err := getJSON(ctx, url, &curResp); err != nil || curResp.Block == 0 {
 					if *startFrom >= 0 {
 						cursorSOL[entity] = uint64(*startFrom)
-					} else if latest > 200 {
-						cursorSOL[entity] = latest - 200
 					} else {
-						cursorSOL[entity] = latest
+						cursorSOL[entity] = startCursor(latest, 200, confirmationsFor("solana", confirmations))
 					}
 				} else {
 					cursorSOL[entity] = curResp.Block
 				}
 				log.Printf("[cursor] sol entity=%s start=%d (latest=%d)", entity, cursorSOL[entity], latest)
+				metrics.SetCursor("solana", entity, cursorSOL[entity])
 			}
 		}
 	}
 
-	/*************** 扫描循环 ***************/
-	for {
-		progressed := false
-
-		// —— EVM 各链
-		for i := range evmChains {
-			ec := &evmChains[i]
-			for entity, addrs := range ec.addressesByEnt {
+	// TRON
+	cursorTRON := map[string]uint64{}
+	if len(tronAPIs) > 0 {
+		latest, err := tronLatest(ctx)
+		if err != nil {
+			log.Printf("[cursor] tron latest error: %v", err)
+		} else {
+			for entity := range addressesTRON {
 				if *entityArg != "" && !strings.EqualFold(*entityArg, entity) {
 					continue
 				}
-				latest, err := evmLatestBlock(ctx, ec)
+				var curResp struct {
+					Block uint64 `json:"block"`
+				}
+				url := fmt.Sprintf("%s/sync/cursor?entity=%s&chain=tron", strings.TrimRight(*apiBase, "/"), entity)
+				if err := getJSON(ctx, url, &curResp); err != nil || curResp.Block == 0 {
+					if *startFrom >= 0 {
+						cursorTRON[entity] = uint64(*startFrom)
+					} else {
+						cursorTRON[entity] = startCursor(latest, *tronStep, confirmationsFor("tron", confirmations))
+					}
+				} else {
+					cursorTRON[entity] = curResp.Block
+				}
+				log.Printf("[cursor] tron entity=%s start=%d (latest=%d)", entity, cursorTRON[entity], latest)
+				metrics.SetCursor("tron", entity, cursorTRON[entity])
+			}
+		}
+	}
+
+	// SOL 签名游标（-sol-mode=signatures）
+	solSigCursors := loadSolSigCursors("")
+	if solMode == solModeSignatures {
+		solSigCursors = loadSolSigCursors(*solSigCursorFile)
+	}
+
+	/*************** EVM 日志订阅（-evm-ws） ***************/
+	wsStates := map[string]*evmWSState{} // chain -> 订阅状态；无订阅的链为 nil
+	var wsWG sync.WaitGroup
+	for i := range evmChains {
+		ec := &evmChains[i]
+		if ec.wsURL == "" || len(ec.contractToSym) == 0 {
+			continue
+		}
+		ents := map[string][]string{}
+		for entity, addrs := range ec.addressesByEnt {
+			if *entityArg == "" || strings.EqualFold(*entityArg, entity) {
+				ents[entity] = addrs
+			}
+		}
+		state := newEVMWSState()
+		wsStates[ec.name] = state
+		sub := newEVMWSSubscriber(ec.name, ec.wsURL, ec.contractToSym, ents, state,
+			func(contract string, detect func() (int, error)) (int, error) {
+				ec.decMu.Lock()
+				defer ec.decMu.Unlock()
+				return erc20Decimals(ec.decimalsCache, contract, detect)
+			},
+			func(ctx context.Context, entity string, events []models.Event) error {
+				addrTypes.Tag(events)
+				if err := evSink.Publish(ctx, entity, events); err != nil {
+					return err
+				}
+				metrics.EventsEmitted(ec.name, events)
+				return nil
+			})
+		sub.confirmations = confirmationsFor(ec.name, confirmations)
+		wsWG.Add(1)
+		go func() {
+			defer wsWG.Done()
+			sub.Run(shutdown)
+		}()
+		logv("[init] evm %s log subscription via %s", ec.name, ec.wsURL)
+	}
+
+	// 单个实体扫描一个窗口，窗口提交成功返回 true；ec 为任务独立的副本（各自的 rpcIdx 轮换）
+	scanEVMEntity := func(ctx context.Context, ec *evmChain, entity string, addrs []string) bool {
+		latest, err := evmLatestBlock(ctx, ec)
+		if err != nil {
+			log.Printf("[latest] %s error: %v", ec.name, err)
+			return false
+		}
+		cur := evmState.Cursor(ec.name, entity)
+		if resumed, ok := wsStates[ec.name].ResumeCursor(entity, cur); ok {
+			log.Printf("[%s] entity=%s log subscription dropped, rewind cursor %d -> %d for getLogs backfill", ec.name, entity, cur, resumed)
+			cur = resumed
+			evmState.SetCursor(ec.name, entity, cur)
+		}
+		lagAlerts.Observe(ec.name, entity, latest, cur, time.Now())
+		tip, ok := confirmedTip(latest, confirmationsFor(ec.name, confirmations))
+		if !ok {
+			return false
+		}
+		to, ok := scanWindow(cur, 500, tip)
+		if !ok {
+			return false
+		}
+		guard := reorgEVM[ec.name][entity]
+		if guard.NeedsParentCheck(cur) {
+			head, err := evmGetBlock(ctx, ec, cur, false)
+			if err != nil {
+				log.Printf("[%s] entity=%s parent check %d: %v", ec.name, entity, cur, err)
+				return false
+			}
+			if rewind, reorged := guard.Check(cur, str(head["parentHash"])); reorged {
+				log.Printf("[%s] entity=%s reorg detected at %d (parent %s), rewind cursor to %d",
+					ec.name, entity, cur, str(head["parentHash"]), rewind)
+				cur = rewind
+				evmState.SetCursor(ec.name, entity, cur)
+				to, _ = scanWindow(cur, 500, tip)
+			}
+		}
+		// 窗口内观察到的区块哈希，同一高度出现不同哈希说明扫描途中发生重组
+		var reorgErr, traceErr, receiptErr error
+		observeHash := func(n uint64, hash string) {
+			if err := guard.Observe(n, hash); err != nil && reorgErr == nil {
+				reorgErr = err
+			}
+		}
+		addrSet := toSetLower(addrs)
+		events := make([]models.Event, 0, 256)
+		scanStart := time.Now()
+		logv("[%s] entity=%s window=%s latest=%d addrs=%d", ec.name, entity, rangeStr(cur, to), latest, len(addrs))
+		winBlocks := newEVMWindowBlocks(*evmBloomFilter)
+		// 日志出块时间：优先用窗口缓存，每批日志缺的区块头先批量拉取，同一区块本窗口最多拉一次
+		logBlockTimes := newEVMBlockTimes(winBlocks,
+			func(nums []uint64) (map[uint64]map[string]any, error) { return evmGetBlockHeaders(ctx, ec, nums) },
+			func(n uint64) (map[string]any, error) { return evmGetBlock(ctx, ec, n, false) },
+			func(n uint64, blk map[string]any) { observeHash(n, str(blk["hash"])) })
+
+		// ETH 原生（仅以太坊主网）
+		//if ec.includeNativeETH && util.IsAllowed("ETH") {
+		if ec.nativeSymbol != "" && util.IsAllowedFor(entity, ec.nativeSymbol) {
+			for b := cur; b <= to; b++ {
+				if (b-cur)%uint64(*logEvery) == 0 {
+					logv("[%s] block %d/%d (+%d)", ec.name, b, to, b-cur)
+				}
+				blk, err := evmGetBlock(ctx, ec, b, true)
 				if err != nil {
-					log.Printf("[latest] %s error: %v", ec.name, err)
+					log.Printf("[%s] getBlock %d: %v", ec.name, b, err)
 					continue
 				}
-				cur := cursorEVM[ec.name][entity]
-				if cur >= latest {
+				winBlocks.Record(b, blk)
+				blockHash := strings.ToLower(str(blk["hash"]))
+				observeHash(b, blockHash)
+				txs, _ := blk["transactions"].([]any)
+				ts := parseBlockTime(blk)
+				txHashes := make([]string, 0, len(txs))
+				for _, it := range txs {
+					if tx, ok := it.(map[string]any); ok {
+						txHashes = append(txHashes, str(tx["hash"]))
+					}
+				}
+				// 回滚交易的 tx.value 没有转出：只对命中监控地址的交易查回执
+				native := evmNativeTxEvents(txs, entity, ec.name, ec.nativeSymbol, addrSet, ts, b, blockHash)
+				if receiptErr == nil {
+					native, err = dropFailedEVMTxs(ctx, native, func(ctx context.Context, hash string) (map[string]any, error) {
+						return evmGetReceipt(ctx, ec, hash)
+					})
+					if err != nil {
+						receiptErr = fmt.Errorf("block %d: %w", b, err)
+					}
+				}
+				events = append(events, native...)
+
+				// 内部转账（合约内部 CALL 转账）：节点不支持 trace 时关闭该链的内部转账扫描，其它失败整窗不提交
+				if ec.traceMode != "" && !ec.traceOff.Load() && traceErr == nil {
+					transfers, err := traceBlockTransfers(ec.name, ec.traceMode, ec.traceOff, b, func(n uint64) ([]evmInternalTransfer, error) {
+						return evmTraceBlock(ctx, ec, n, txHashes)
+					})
+					if err != nil {
+						traceErr = fmt.Errorf("trace block %d: %w", b, err)
+					} else {
+						events = append(events, evmInternalTransferEvents(transfers, entity, ec.name, ec.nativeSymbol, addrSet, ts, b, blockHash)...)
+					}
+				}
+			}
+		}
+
+		// ERC20（按配置）；订阅生效时只回补订阅起点之前的区块
+		pollFrom, pollTo, pollLogs := wsStates[ec.name].PollLogRange(cur, to)
+		if len(ec.contractToSym) > 0 && !pollLogs {
+			logv("[%s] entity=%s window=%s erc20 covered by log subscription", ec.name, entity, rangeStr(cur, to))
+		}
+		if len(ec.contractToSym) > 0 && pollLogs {
+			const chunk = 100 // 可按 RPC 限制调整
+			// 地址唯一化
+			addrList := uniqueLower(addrs)
+			addrSet := toSetLower(addrList)
+
+			// 记录去重：txHash#logIndex
+			seen := map[string]struct{}{}
+
+			for contract, symbol := range ec.contractToSym {
+				if !util.IsAllowedFor(entity, symbol) {
 					continue
 				}
-				to := cur + 500
-				if to > latest {
-					to = latest
+				// bloom 预筛：整段无关则跳过，否则收窄到候选区块
+				logFrom, logTo, hit := winBlocks.CandidateRange(pollFrom, pollTo, contract, addrList)
+				if !hit {
+					logv("[%s] bloom skip %s %s %s", ec.name, symbol, contract, rangeStr(pollFrom, pollTo))
+					continue
 				}
-				addrSet := toSetLower(addrs)
-				events := make([]models.Event, 0, 256)
-				scanStart := time.Now()
-				logv("[%s] entity=%s window=%s latest=%d addrs=%d", ec.name, entity, rangeStr(cur, to), latest, len(addrs))
-
-				// ETH 原生（仅以太坊主网）
-				//if ec.includeNativeETH && util.IsAllowed("ETH") {
-				if ec.nativeSymbol != "" && util.IsAllowed(ec.nativeSymbol) {
-					for b := cur; b <= to; b++ {
-						if (b-cur)%uint64(*logEvery) == 0 {
-							logv("[%s] block %d/%d (+%d)", ec.name, b, to, b-cur)
+				if logFrom != pollFrom || logTo != pollTo {
+					logv("[%s] bloom narrow %s %s %s -> %s", ec.name, symbol, contract, rangeStr(pollFrom, pollTo), rangeStr(logFrom, logTo))
+				}
+				decimals, derr := evmDecimals(ctx, ec, contract)
+				if derr != nil {
+					log.Printf("[%s] decimals %s: %v (use 18)", ec.name, contract, derr)
+					decimals = 18
+				}
+
+				// 1) fromChunk：topics = [Transfer, OR(from), nil]
+				for i := 0; i < len(addrList); i += chunk {
+					end := i + chunk
+					if end > len(addrList) {
+						end = len(addrList)
+					}
+					fc := addrList[i:end]
+
+					if *verbose {
+						log.Printf("[%s] getLogs %s %s %s fromChunk %d/%d size=%d",
+							ec.name, symbol, contract, rangeStr(logFrom, logTo),
+							(i/chunk)+1, (len(addrList)+chunk-1)/chunk, len(fc))
+					}
+
+					logsArr, err := evmGetLogs(ctx, ec, logFrom, logTo, contract, fc, nil)
+					if err != nil {
+						log.Printf("[%s] getLogs(from) %s %s %s: %v", ec.name, symbol, contract, rangeStr(logFrom, logTo), err)
+						continue
+					}
+					logBlockTimes.Prefetch(logsArr)
+					for _, lg := range logsArr {
+						topics, _ := lg["topics"].([]any)
+						if len(topics) < 3 {
+							// 容错：部分节点会返回异常日志
+							continue
 						}
-						blk, err := evmGetBlock(ctx, ec, b)
-						if err != nil {
-							log.Printf("[%s] getBlock %d: %v", ec.name, b, err)
+						from := topicAddr(topics[1])
+						toA := topicAddr(topics[2])
+
+						// 只要 from 在监控集即可
+						if !addrSet[from] {
 							continue
 						}
-						txs, _ := blk["transactions"].([]any)
-						ts := parseBlockTime(blk)
-						for _, it := range txs {
-							tx := it.(map[string]any)
-							from := strings.ToLower(str(tx["from"]))
-							toA := strings.ToLower(str(tx["to"]))
-							valHex := str(tx["value"])
-							if valHex == "" {
-								continue
-							}
-							wei := new(big.Int)
-							_, _ = wei.SetString(strings.TrimPrefix(valHex, "0x"), 16)
-							if wei.Sign() == 0 {
-								continue
-							}
-							if addrSet[from] || (toA != "" && addrSet[toA]) {
-								amt := toDecimal(wei, 18)
-								dir := "in"
-								target := toA
-								if addrSet[from] && !addrSet[toA] {
-									dir = "out"
-									target = from
-								}
-								events = append(events, models.Event{
-									Entity: entity, Chain: ec.name, Coin: ec.nativeSymbol, Direction: dir, Amount: amt,
-									TS: ts, TxID: str(tx["hash"]), From: from, To: toA, Address: target, LogIndex: -1,
-								})
-							}
+
+						val := new(big.Int)
+						_, _ = val.SetString(strings.TrimPrefix(str(lg["data"]), "0x"), 16)
+						if txFilter.skipZero(val.Sign()) {
+							continue
+						}
+						amt := toDecimal(val, decimals)
+						if minAmounts.below(ec.name, symbol, amt) {
+							continue
+						}
+						hash := str(lg["transactionHash"])
+						lidx := int(hexToUint64(str(lg["logIndex"])))
+						key := hash + "#" + fmt.Sprint(lidx)
+						if _, ok := seen[key]; ok {
+							continue
+						}
+						seen[key] = struct{}{}
+
+						blkTs := time.Now().UTC()
+						blkNum := hexToUint64(str(lg["blockNumber"]))
+						blkHash := strings.ToLower(str(lg["blockHash"]))
+						if blkNum > 0 {
+							observeHash(blkNum, blkHash)
+							blkTs = logBlockTimes.Time(blkNum)
+						}
+
+						// 如果 to 不在集，就判定为 out；否则记为 in
+						dir := "in"
+						target := toA
+						if !addrSet[toA] {
+							dir = "out"
+							target = from
 						}
+
+						events = append(events, models.Event{
+							Entity: entity, Chain: ec.name, Coin: symbol, AssetID: models.AssetIDFor(ec.name, contract), Direction: dir, Amount: amt,
+							TS: blkTs, TxID: hash, From: from, To: toA, Address: target, LogIndex: lidx,
+							BlockNumber: blkNum, BlockHash: blkHash,
+						})
 					}
 				}
 
-				// ERC20（按配置）
-				if len(ec.contractToSym) > 0 {
-					const chunk = 100 // 可按 RPC 限制调整
-					// 地址唯一化
-					addrList := uniqueLower(addrs)
-					addrSet := toSetLower(addrList)
+				// 2) toChunk：topics = [Transfer, nil, OR(to)]
+				for i := 0; i < len(addrList); i += chunk {
+					end := i + chunk
+					if end > len(addrList) {
+						end = len(addrList)
+					}
+					tc := addrList[i:end]
 
-					// 记录去重：txHash#logIndex
-					seen := map[string]struct{}{}
+					if *verbose {
+						log.Printf("[%s] getLogs %s %s %s toChunk %d/%d size=%d",
+							ec.name, symbol, contract, rangeStr(logFrom, logTo),
+							(i/chunk)+1, (len(addrList)+chunk-1)/chunk, len(tc))
+					}
 
-					for contract, symbol := range ec.contractToSym {
-						if !util.IsAllowed(symbol) {
+					logsArr, err := evmGetLogs(ctx, ec, logFrom, logTo, contract, nil, tc)
+					if err != nil {
+						log.Printf("[%s] getLogs(to) %s %s %s: %v", ec.name, symbol, contract, rangeStr(logFrom, logTo), err)
+						continue
+					}
+					logBlockTimes.Prefetch(logsArr)
+					for _, lg := range logsArr {
+						topics, _ := lg["topics"].([]any)
+						if len(topics) < 3 {
 							continue
 						}
-						decimals, derr := evmDecimals(ctx, ec, contract)
-						if derr != nil {
-							log.Printf("[%s] decimals %s: %v (use 18)", ec.name, contract, derr)
-							decimals = 18
-						}
+						from := topicAddr(topics[1])
+						toA := topicAddr(topics[2])
 
-						// 1) fromChunk：topics = [Transfer, OR(from), nil]
-						for i := 0; i < len(addrList); i += chunk {
-							end := i + chunk
-							if end > len(addrList) {
-								end = len(addrList)
-							}
-							fc := addrList[i:end]
+						// 只要 to 在监控集即可
+						if !addrSet[toA] {
+							continue
+						}
 
-							if *verbose {
-								log.Printf("[%s] getLogs %s %s %s fromChunk %d/%d size=%d",
-									ec.name, symbol, contract, rangeStr(cur, to),
-									(i/chunk)+1, (len(addrList)+chunk-1)/chunk, len(fc))
-							}
+						val := new(big.Int)
+						_, _ = val.SetString(strings.TrimPrefix(str(lg["data"]), "0x"), 16)
+						if txFilter.skipZero(val.Sign()) {
+							continue
+						}
+						amt := toDecimal(val, decimals)
+						if minAmounts.below(ec.name, symbol, amt) {
+							continue
+						}
+						hash := str(lg["transactionHash"])
+						lidx := int(hexToUint64(str(lg["logIndex"])))
+						key := hash + "#" + fmt.Sprint(lidx)
+						if _, ok := seen[key]; ok {
+							continue
+						} // 避免与 fromChunk 重复
+						seen[key] = struct{}{}
+
+						blkTs := time.Now().UTC()
+						blkNum := hexToUint64(str(lg["blockNumber"]))
+						blkHash := strings.ToLower(str(lg["blockHash"]))
+						if blkNum > 0 {
+							observeHash(blkNum, blkHash)
+							blkTs = logBlockTimes.Time(blkNum)
+						}
 
-							logsArr, err := evmGetLogs(ctx, ec, cur, to, contract, fc, nil)
-							if err != nil {
-								log.Printf("[%s] getLogs(from) %s %s %s: %v", ec.name, symbol, contract, rangeStr(cur, to), err)
-								continue
-							}
-							for _, lg := range logsArr {
-								topics, _ := lg["topics"].([]any)
-								if len(topics) < 3 {
-									// 容错：部分节点会返回异常日志
-									continue
-								}
-								from := topicAddr(topics[1])
-								toA := topicAddr(topics[2])
-
-								// 只要 from 在监控集即可
-								if !addrSet[from] {
-									continue
-								}
-
-								val := new(big.Int)
-								_, _ = val.SetString(strings.TrimPrefix(str(lg["data"]), "0x"), 16)
-								if val.Sign() == 0 {
-									continue
-								}
-								amt := toDecimal(val, decimals)
-								hash := str(lg["transactionHash"])
-								lidx := int(hexToUint64(str(lg["logIndex"])))
-								key := hash + "#" + fmt.Sprint(lidx)
-								if _, ok := seen[key]; ok {
-									continue
-								}
-								seen[key] = struct{}{}
-
-								blkTs := time.Now().UTC()
-								if n := hexToUint64(str(lg["blockNumber"])); n > 0 {
-									if blk, err := evmGetBlock(ctx, ec, n); err == nil {
-										blkTs = parseBlockTime(blk)
-									}
-								}
-
-								// 如果 to 不在集，就判定为 out；否则记为 in
-								dir := "in"
-								target := toA
-								if !addrSet[toA] {
-									dir = "out"
-									target = from
-								}
-
-								events = append(events, models.Event{
-									Entity: entity, Chain: ec.name, Coin: symbol, Direction: dir, Amount: amt,
-									TS: blkTs, TxID: hash, From: from, To: toA, Address: target, LogIndex: lidx,
-								})
-							}
+						// to 命中 => in（from 也在集的情况前面已去重）
+						dir := "in"
+						target := toA
+						if addrSet[from] && !addrSet[toA] {
+							dir = "out"
+							target = from
 						}
+						events = append(events, models.Event{
+							Entity: entity, Chain: ec.name, Coin: symbol, AssetID: models.AssetIDFor(ec.name, contract), Direction: dir, Amount: amt,
+							TS: blkTs, TxID: hash, From: from, To: toA, Address: target, LogIndex: lidx,
+							BlockNumber: blkNum, BlockHash: blkHash,
+						})
+					}
+				}
+			}
+		}
 
-						// 2) toChunk：topics = [Transfer, nil, OR(to)]
-						for i := 0; i < len(addrList); i += chunk {
-							end := i + chunk
-							if end > len(addrList) {
-								end = len(addrList)
-							}
-							tc := addrList[i:end]
+		minT, maxT, byCoin := summarize(events)
+		if len(events) == 0 {
+			logv("[%s] entity=%s no-events window=%s duration=%s", ec.name, entity, rangeStr(cur, to), time.Since(scanStart))
+		} else {
+			logv("[%s] entity=%s events=%d window=%s ts=[%s .. %s] byCoin=%v duration=%s",
+				ec.name, entity, len(events), rangeStr(cur, to),
+				minT.UTC().Format(time.RFC3339), maxT.UTC().Format(time.RFC3339), byCoin, time.Since(scanStart))
+		}
+		// 窗口末块的哈希供下一窗口校验父哈希（原生路径已记录时不再拉取）
+		if guard.Enabled() && reorgErr == nil && !guard.Has(to) {
+			if blk, err := evmGetBlock(ctx, ec, to, false); err == nil {
+				observeHash(to, str(blk["hash"]))
+			} else {
+				log.Printf("[%s] getBlock %d for reorg check: %v", ec.name, to, err)
+			}
+		}
+		if reorgErr != nil {
+			guard.Forget(cur)
+			log.Printf("[%s] entity=%s window=%s reorg during scan, not committed: %v", ec.name, entity, rangeStr(cur, to), reorgErr)
+			return false
+		}
+		if traceErr != nil {
+			log.Printf("[%s] entity=%s window=%s internal transfers incomplete, not committed: %v", ec.name, entity, rangeStr(cur, to), traceErr)
+			return false
+		}
+		if receiptErr != nil {
+			log.Printf("[%s] entity=%s window=%s tx status unknown, not committed: %v", ec.name, entity, rangeStr(cur, to), receiptErr)
+			return false
+		}
+		if ctx.Err() != nil {
+			// 退出途中窗口内的 RPC 调用可能被取消，不提交，下次启动重扫
+			log.Printf("[%s] entity=%s window=%s interrupted by shutdown, not committed", ec.name, entity, rangeStr(cur, to))
+			return false
+		}
 
-							if *verbose {
-								log.Printf("[%s] getLogs %s %s %s toChunk %d/%d size=%d",
-									ec.name, symbol, contract, rangeStr(cur, to),
-									(i/chunk)+1, (len(addrList)+chunk-1)/chunk, len(tc))
-							}
+		// 重组回退后重扫到回退前已扫描的高度：取每个高度的规范哈希，提交时由 API 删除哈希不符的孤块事件
+		staleFrom, staleTo, stale := guard.StaleRange(cur, to)
+		var canonical map[uint64]string
+		if stale {
+			canonical, err = guard.CanonicalHashes(staleFrom, staleTo, func(n uint64) (string, error) {
+				blk, err := evmGetBlock(ctx, ec, n, false)
+				if err != nil {
+					return "", err
+				}
+				return str(blk["hash"]), nil
+			})
+			if err != nil {
+				log.Printf("[%s] entity=%s window=%s canonical hashes: %v, not committed", ec.name, entity, rangeStr(cur, to), err)
+				return false
+			}
+		}
 
-							logsArr, err := evmGetLogs(ctx, ec, cur, to, contract, nil, tc)
-							if err != nil {
-								log.Printf("[%s] getLogs(to) %s %s %s: %v", ec.name, symbol, contract, rangeStr(cur, to), err)
-								continue
-							}
-							for _, lg := range logsArr {
-								topics, _ := lg["topics"].([]any)
-								if len(topics) < 3 {
-									continue
-								}
-								from := topicAddr(topics[1])
-								toA := topicAddr(topics[2])
-
-								// 只要 to 在监控集即可
-								if !addrSet[toA] {
-									continue
-								}
-
-								val := new(big.Int)
-								_, _ = val.SetString(strings.TrimPrefix(str(lg["data"]), "0x"), 16)
-								if val.Sign() == 0 {
-									continue
-								}
-								amt := toDecimal(val, decimals)
-								hash := str(lg["transactionHash"])
-								lidx := int(hexToUint64(str(lg["logIndex"])))
-								key := hash + "#" + fmt.Sprint(lidx)
-								if _, ok := seen[key]; ok {
-									continue
-								} // 避免与 fromChunk 重复
-								seen[key] = struct{}{}
-
-								blkTs := time.Now().UTC()
-								if n := hexToUint64(str(lg["blockNumber"])); n > 0 {
-									if blk, err := evmGetBlock(ctx, ec, n); err == nil {
-										blkTs = parseBlockTime(blk)
-									}
-								}
-
-								// to 命中 => in（from 也在集的情况前面已去重）
-								dir := "in"
-								target := toA
-								if addrSet[from] && !addrSet[toA] {
-									dir = "out"
-									target = from
-								}
-								events = append(events, models.Event{
-									Entity: entity, Chain: ec.name, Coin: symbol, Direction: dir, Amount: amt,
-									TS: blkTs, TxID: hash, From: from, To: toA, Address: target, LogIndex: lidx,
-								})
-							}
-						}
-					}
+		// 重扫窗口时跳过已成功下发过的原生币事件
+		seen := nativeSeenEVM[ec.name][entity]
+		events = seen.Filter(events)
+		addrTypes.Tag(events)
+		next := to + 1
+		if err := ingestWindow(ctx, evSink, entity, events, func() error {
+			if stale {
+				if err := commitCanonical(ctx, entity, ec.name, staleFrom, staleTo, canonical); err != nil {
+					return fmt.Errorf("canonical blocks %s: %w", rangeStr(staleFrom, staleTo), err)
 				}
+			}
+			return commitCursor(ctx, entity, ec.name, next)
+		}); err != nil {
+			log.Printf("[%s] entity=%s window=%s not committed, cursor stays at %d: %v", ec.name, entity, rangeStr(cur, to), cur, err)
+			return false
+		}
+		evmState.SetCursor(ec.name, entity, next)
+		metrics.WindowCommitted(ec.name, entity, next-cur, next, events)
+		guard.Prune(next)
+		if stale {
+			guard.ClearStale(staleTo)
+		}
+		seen.Commit(events)
+		seen.Prune(next)
+		return true
+	}
 
-				minT, maxT, byCoin := summarize(events)
-				if len(events) == 0 {
-					logv("[%s] entity=%s no-events window=%s duration=%s", ec.name, entity, rangeStr(cur, to), time.Since(scanStart))
-				} else {
-					logv("[%s] entity=%s events=%d window=%s ts=[%s .. %s] byCoin=%v duration=%s",
-						ec.name, entity, len(events), rangeStr(cur, to),
-						minT.UTC().Format(time.RFC3339), maxT.UTC().Format(time.RFC3339), byCoin, time.Since(scanStart))
+	/*************** 扫描循环 ***************/
+	chainSwitch := newChainFlags(*chainFlagsPath, *chainFlagsInterval)
+	poller := newChainPoller(*poll, chainPolls)
+	{
+		var scanned []string
+		for i := range evmChains {
+			scanned = append(scanned, evmChains[i].name)
+		}
+		if len(addressesBTC) > 0 {
+			scanned = append(scanned, "bitcoin")
+		}
+		if len(addressesSOL) > 0 {
+			scanned = append(scanned, "solana")
+		}
+		if len(tronAPIs) > 0 {
+			scanned = append(scanned, "tron")
+		}
+		log.Printf("[init] poll intervals: %s", poller.Summary(scanned))
+	}
+	for shutdown.Err() == nil {
+		progressed := false
+		chainSwitch.Refresh(time.Now())
+		due := scheduler.Next(time.Now())
+		loopStart := time.Now()
+
+		// —— EVM 各链：按 (链, 实体) 提交到协程池并发扫描
+		evmPool := NewWorkerPool(*evmConcurrency)
+		evmProgressed := make([]atomic.Bool, len(evmChains))
+		evmScanned := make([]bool, len(evmChains))
+		for i := range evmChains {
+			ec := &evmChains[i]
+			if !chainSwitch.Enabled(ec.name) || !poller.Due(ec.name, loopStart) {
+				continue
+			}
+			evmScanned[i] = true
+			for entity, addrs := range ec.addressesByEnt {
+				if (*entityArg != "" && !strings.EqualFold(*entityArg, entity)) || !due[entity] {
+					continue
 				}
-				if len(events) > 0 {
-					u := fmt.Sprintf("%s/ingest/events?entity=%s", strings.TrimRight(*apiBase, "/"), entity)
-					var resp struct {
-						OK    bool   `json:"ok"`
-						Saved int    `json:"saved"`
-						RunID string `json:"run_id"`
+				task := *ec
+				task.rpcIdx = evmState.RPCIdx(ec.name, entity)
+				evmPool.Submit(func() {
+					if shutdown.Err() != nil {
+						return
 					}
-					if err := netutil.PostJSON(context.Background(), u, events, &resp); err != nil {
-						log.Printf("ingest error (%s): %v", ec.name, err)
-					} else {
-						log.Printf("ingest ok (%s): entity=%s saved=%d run_id=%s", ec.name, entity, resp.Saved, resp.RunID)
+					if scanEVMEntity(ctx, &task, entity, addrs) {
+						evmProgressed[i].Store(true)
 					}
-				}
-				next := to + 1
-				if err := netutil.PostJSON(context.Background(),
-					fmt.Sprintf("%s/sync/cursor?entity=%s&chain=%s", strings.TrimRight(*apiBase, "/"), entity, ec.name),
-					map[string]uint64{"block": next}, &struct {
-						OK bool `json:"ok"`
-					}{},
-				); err != nil {
-					log.Printf("[cursor] set %s %s -> %d error: %v", ec.name, entity, next, err)
-				} else {
-					cursorEVM[ec.name][entity] = next
-					progressed = true
-				}
+					evmState.SetRPCIdx(task.name, entity, task.rpcIdx)
+				})
+			}
+		}
+		evmPool.Wait()
+		for i := range evmChains {
+			if evmScanned[i] {
+				poller.Done(evmChains[i].name, time.Now(), evmProgressed[i].Load())
+				progressed = progressed || evmProgressed[i].Load()
 			}
 		}
 
 		// —— BTC
-		if len(addressesBTC) > 0 {
+		if len(addressesBTC) > 0 && chainSwitch.Enabled("bitcoin") && poller.Due("bitcoin", loopStart) {
+			chainProgressed := false
 			latest, err := btcTipHeight(ctx)
 			if err != nil {
 				log.Printf("[latest] btc error: %v", err)
 			} else {
 				for entity, addrs := range addressesBTC {
-					if *entityArg != "" && !strings.EqualFold(*entityArg, entity) {
+					if shutdown.Err() != nil {
+						break
+					}
+					if (*entityArg != "" && !strings.EqualFold(*entityArg, entity)) || !due[entity] || !util.IsAllowedFor(entity, "BTC") {
 						continue
 					}
 					cur := cursorBTC[entity]
-					if cur >= latest {
+					lagAlerts.Observe("bitcoin", entity, latest, cur, time.Now())
+					tip, ok := confirmedTip(latest, confirmationsFor("bitcoin", confirmations))
+					if !ok {
 						continue
 					}
-					to := cur + 6
-					if to > latest {
-						to = latest
+					to, ok := scanWindow(cur, 6, tip)
+					if !ok {
+						continue
 					}
 					addrSetExact := toSetExact(addrs)
 					addrSetLower := toSetLower(addrs)
+					watched := func(a string) bool { return addrSetExact[a] || addrSetLower[strings.ToLower(a)] }
 					events := make([]models.Event, 0, 512)
 					scanStart := time.Now()
 					logv("[bitcoin] entity=%s window=%s latest=%d addrs=%d", entity, rangeStr(cur, to), latest, len(addrs))
+					// 任一区块读取失败整窗不提交，下轮从原游标重扫，避免跳过该区块的交易
+					failed := false
 					for h := cur; h <= to; h++ {
 						if (h-cur)%uint64(*logEvery) == 0 {
 							logv("[bitcoin] height %d/%d (+%d)", h, to, h-cur)
 						}
 						bh, err := btcBlockHash(ctx, h)
-						if err != nil || strings.TrimSpace(bh) == "" {
-							log.Printf("[bitcoin] block hash %d: %v", h, err)
-							continue
+						if err == nil && strings.TrimSpace(bh) == "" {
+							err = fmt.Errorf("empty block hash")
+						}
+						if err != nil {
+							log.Printf("[bitcoin] entity=%s block hash %d: %v, cursor stays at %d", entity, h, err, cur)
+							failed = true
+							break
 						}
 						txs, err := btcBlockTxs(ctx, strings.TrimSpace(bh))
 						if err != nil {
-							log.Printf("[bitcoin] block txs %d: %v", h, err)
-							continue
+							log.Printf("[bitcoin] entity=%s block txs %d: %v, cursor stays at %d", entity, h, err, cur)
+							failed = true
+							break
 						}
 						for _, tx := range txs {
-							ts := time.Unix(tx.Status.BlockTime, 0).UTC()
-							for i, vin := range tx.Vin {
-								if vin.Prevout == nil {
-									continue
-								}
-								addr := strings.TrimSpace(vin.Prevout.ScriptPubKeyAddress)
-								if addr == "" || vin.Prevout.Value <= 0 {
-									continue
-								}
-								if !(addrSetExact[addr] || addrSetLower[strings.ToLower(addr)]) {
-									continue
-								}
-								amt := satsToDecimal(vin.Prevout.Value)
-								toAddr := firstVoutAddr(tx.Vout)
-								events = append(events, models.Event{
-									Entity: entity, Chain: "bitcoin", Coin: "BTC", Direction: "out", Amount: amt,
-									TS: ts, TxID: tx.Txid, From: addr, To: toAddr, Address: addr, LogIndex: -(i + 1),
-								})
-							}
-							for i, vout := range tx.Vout {
-								addr := strings.TrimSpace(vout.ScriptPubKeyAddress)
-								if addr == "" || vout.Value <= 0 {
-									continue
-								}
-								if !(addrSetExact[addr] || addrSetLower[strings.ToLower(addr)]) {
-									continue
-								}
-								amt := satsToDecimal(vout.Value)
-								fromAddr := firstVinAddr(tx.Vin)
-								events = append(events, models.Event{
-									Entity: entity, Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: amt,
-									TS: ts, TxID: tx.Txid, From: fromAddr, To: addr, Address: addr, LogIndex: i,
-								})
-							}
+							events = append(events, btcTxEvents(entity, tx, watched)...)
+						}
+					}
+					if failed {
+						continue
+					}
+					// 扩展公钥：用到了靠近末尾的派生地址时继续派生，本窗口不提交，立即带上新地址重扫
+					if set := btcXPubs[entity]; set != nil {
+						var more []string
+						for _, e := range events {
+							more = append(more, set.MarkUsed(e.Address)...)
+						}
+						if len(more) > 0 {
+							addressesBTC[entity] = append(addressesBTC[entity], more...)
+							log.Printf("[bitcoin] entity=%s derived %d more xpub addresses (total=%d), rescanning window=%s", entity, len(more), len(addressesBTC[entity]), rangeStr(cur, to))
+							chainProgressed = true
+							continue
 						}
 					}
 					minT, maxT, byCoin := summarize(events)
@@ -1195,62 +1597,126 @@ func main() {
 							entity, len(events), rangeStr(cur, to),
 							minT.UTC().Format(time.RFC3339), maxT.UTC().Format(time.RFC3339), byCoin, time.Since(scanStart))
 					}
-					if len(events) > 0 {
-						u := fmt.Sprintf("%s/ingest/events?entity=%s", strings.TrimRight(*apiBase, "/"), entity)
-						var resp struct {
-							OK    bool   `json:"ok"`
-							Saved int    `json:"saved"`
-							RunID string `json:"run_id"`
-						}
-						if err := netutil.PostJSON(context.Background(), u, events, &resp); err != nil {
-							log.Printf("ingest error (btc): %v", err)
-						} else {
-							log.Printf("ingest ok (btc): entity=%s saved=%d run_id=%s", entity, resp.Saved, resp.RunID)
-						}
-					}
+					addrTypes.Tag(events)
 					next := to + 1
-					if err := netutil.PostJSON(context.Background(),
-						fmt.Sprintf("%s/sync/cursor?entity=%s&chain=bitcoin", strings.TrimRight(*apiBase, "/"), entity),
-						map[string]uint64{"block": next}, &struct {
-							OK bool `json:"ok"`
-						}{},
-					); err != nil {
-						log.Printf("[cursor] set BTC %s -> %d error: %v", entity, next, err)
+					if err := ingestWindow(ctx, evSink, entity, events, func() error {
+						return commitCursor(ctx, entity, "bitcoin", next)
+					}); err != nil {
+						log.Printf("[bitcoin] entity=%s window=%s not committed, cursor stays at %d: %v", entity, rangeStr(cur, to), cur, err)
 					} else {
 						cursorBTC[entity] = next
-						progressed = true
+						metrics.WindowCommitted("bitcoin", entity, next-cur, next, events)
+						chainProgressed = true
 					}
 				}
 			}
+			poller.Done("bitcoin", time.Now(), chainProgressed)
+			progressed = progressed || chainProgressed
 		}
 
-		// —— Solana
-		if len(addressesSOL) > 0 {
+		// —— Solana（按地址签名）
+		if len(addressesSOL) > 0 && chainSwitch.Enabled("solana") && solMode == solModeSignatures && poller.Due("solana", loopStart) {
+			chainProgressed := false
+			// 签名与 slot 游标都不越过确认深度允许的最高 slot
+			latest, err := solLatestSlot(ctx)
+			tip, tipOK := confirmedTip(latest, confirmationsFor("solana", confirmations))
+			if err != nil {
+				log.Printf("[latest] solana error: %v; %s", err, solEPs.Health())
+				tipOK = false
+			}
+			for entity, addrs := range addressesSOL {
+				if shutdown.Err() != nil || !tipOK {
+					break
+				}
+				if (*entityArg != "" && !strings.EqualFold(*entityArg, entity)) || !due[entity] {
+					continue
+				}
+				cur, ok := cursorSOL[entity]
+				if !ok {
+					continue
+				}
+				lagAlerts.Observe("solana", entity, latest, cur, time.Now())
+				addrSet := toSetExact(addrs)
+				addrLower := toSetLower(addrs)
+				watched := func(a string) bool { return addrSet[a] || addrLower[strings.ToLower(a)] }
+				scanStart := time.Now()
+				win, err := scanSolSignatures(ctx, solPost, entity, addrs, solSigCursors, cur, tip, *solSigLimit, watched, mintToSymbol)
+				if err != nil {
+					log.Printf("[solana] entity=%s signatures scan failed, cursors stay: %v; %s", entity, err, solEPs.Health())
+					continue
+				}
+				if len(win.Next) == 0 {
+					continue
+				}
+				events := win.Events
+				minT, maxT, byCoin := summarize(events)
+				if len(events) == 0 {
+					logv("[solana] entity=%s no-events %s", entity, solSigWindowSummary(win, time.Since(scanStart)))
+				} else {
+					logv("[solana] entity=%s events=%d ts=[%s .. %s] byCoin=%v %s",
+						entity, len(events), minT.UTC().Format(time.RFC3339), maxT.UTC().Format(time.RFC3339), byCoin,
+						solSigWindowSummary(win, time.Since(scanStart)))
+				}
+				addrTypes.Tag(events)
+				// slot 游标同步推进，切回 slot 模式或签名游标丢失时从这里接上
+				next := cur
+				if win.MaxSlot+1 > next {
+					next = win.MaxSlot + 1
+				}
+				if err := ingestWindow(ctx, evSink, entity, events, func() error {
+					if next == cur {
+						return nil
+					}
+					return commitCursor(ctx, entity, "solana", next)
+				}); err != nil {
+					log.Printf("[solana] entity=%s signatures window not committed, cursors stay: %v", entity, err)
+				} else {
+					solSigCursors.Commit(entity, win.Next)
+					cursorSOL[entity] = next
+					metrics.WindowCommitted("solana", entity, next-cur, next, events)
+					chainProgressed = true
+				}
+			}
+			poller.Done("solana", time.Now(), chainProgressed)
+			progressed = progressed || chainProgressed
+		}
+
+		// —— Solana（逐 slot）
+		if len(addressesSOL) > 0 && chainSwitch.Enabled("solana") && solMode == solModeSlots && poller.Due("solana", loopStart) {
+			chainProgressed := false
 			latest, err := solLatestSlot(ctx)
 			if err != nil {
-				log.Printf("[latest] solana error: %v; %s", err, solHealth(time.Now()))
+				log.Printf("[latest] solana error: %v; %s", err, solEPs.Health())
 			} else {
-				const step = 200
 				for entity, addrs := range addressesSOL {
-					if *entityArg != "" && !strings.EqualFold(*entityArg, entity) {
+					if shutdown.Err() != nil {
+						break
+					}
+					if (*entityArg != "" && !strings.EqualFold(*entityArg, entity)) || !due[entity] {
 						continue
 					}
 					cur := cursorSOL[entity]
-					if cur >= latest {
+					lagAlerts.Observe("solana", entity, latest, cur, time.Now())
+					tip, ok := confirmedTip(latest, confirmationsFor("solana", confirmations))
+					if !ok {
 						continue
 					}
-					to := cur + step
-					if to > latest {
-						to = latest
+					step := solSteps.Step(entity)
+					to, ok := scanWindow(cur, uint64(step), tip)
+					if !ok {
+						continue
 					}
 					addrSet := toSetExact(addrs)
 					addrLower := toSetLower(addrs)
+					watched := func(a string) bool { return addrSet[a] || addrLower[strings.ToLower(a)] }
 					events := make([]models.Event, 0, 256)
 					logIndex := 0
 					scanStart := time.Now()
+					stats := solWindowStats{Slots: int(to - cur + 1)}
+					rateLimitBefore := solEPs.rateLimitHits
 					rpcInUse := ""
 					if len(solRPCs) > 0 {
-						rpcInUse = strings.TrimRight(solRPCs[solRPCIdx], "/")
+						rpcInUse = solEPs.Current()
 					}
 					logv("[solana] entity=%s window=%s latest=%d addrs=%d rpc=%s", entity, rangeStr(cur, to), latest, len(addrs), rpcInUse)
 
@@ -1261,166 +1727,20 @@ func main() {
 						blk, err := solGetBlock(ctx, slot)
 						if err != nil {
 							log.Printf("[solana] getBlock slot=%d rpc=%s err=%v", slot, rpcInUse, err)
-							continue
-						}
-						blkt := time.Now().UTC()
-						if v := blk["blockTime"]; v != nil {
-							switch vv := v.(type) {
-							case float64:
-								blkt = time.Unix(int64(vv), 0).UTC()
-							case int64:
-								blkt = time.Unix(vv, 0).UTC()
-							}
-						}
-						txs, _ := blk["transactions"].([]any)
-						for _, ti := range txs {
-							tx := ti.(map[string]any)
-							sigs, _ := tx["transaction"].(map[string]any)["signatures"].([]any)
-							var txid string
-							if len(sigs) > 0 {
-								txid = str(sigs[0])
-							}
-
-							// 指令解析
-							trs := parseSolanaTransfers(tx)
-							for _, tr := range trs {
-								symbol := "SOL"
-								if !tr.isSOL {
-									symbol = mintToSymbol[strings.ToLower(tr.mint)]
-									if symbol == "" {
-										continue
-									}
-								}
-								if !util.IsAllowed(symbol) {
-									continue
-								}
-								hitOut := addrSet[tr.source] || addrLower[strings.ToLower(tr.source)]
-								hitIn := addrSet[tr.destination] || addrLower[strings.ToLower(tr.destination)]
-								if !(hitOut || hitIn) {
-									continue
-								}
-								dir := "in"
-								addr := tr.destination
-								if hitOut && !hitIn {
-									dir = "out"
-									addr = tr.source
-								}
-								events = append(events, models.Event{
-									Entity: entity, Chain: "solana", Coin: symbol, Direction: dir, Amount: tr.amountDec,
-									TS: blkt, TxID: txid, From: tr.source, To: tr.destination, Address: addr, LogIndex: logIndex,
-								})
-								logIndex++
-							}
-
-							// 余额差兜底
-							meta, _ := tx["meta"].(map[string]any)
-							if meta == nil {
-								continue
-							}
-							if util.IsAllowed("SOL") {
-								if preB, ok := toInt64Slice(meta["preBalances"]); ok {
-									if postB, ok2 := toInt64Slice(meta["postBalances"]); ok2 {
-										msg := tx["transaction"].(map[string]any)["message"]
-										var accountKeys []string
-										switch ak := msg.(map[string]any)["accountKeys"].(type) {
-										case []any:
-											for _, k := range ak {
-												switch kv := k.(type) {
-												case string:
-													accountKeys = append(accountKeys, kv)
-												case map[string]any:
-													accountKeys = append(accountKeys, str(kv["pubkey"]))
-												}
-											}
-										}
-										for i := 0; i < len(preB) && i < len(postB) && i < len(accountKeys); i++ {
-											a := accountKeys[i]
-											if !(addrSet[a] || addrLower[strings.ToLower(a)]) {
-												continue
-											}
-											diff := postB[i] - preB[i]
-											if diff == 0 {
-												continue
-											}
-											amt := lamportsToSOL(diff)
-											dir := "in"
-											if diff < 0 {
-												dir = "out"
-											}
-											events = append(events, models.Event{
-												Entity: entity, Chain: "solana", Coin: "SOL", Direction: dir, Amount: amt,
-												TS: blkt, TxID: txid, From: "", To: "", Address: a, LogIndex: logIndex,
-											})
-											logIndex++
-										}
-									}
-								}
-							}
-							// SPL 余额差
-							preTB, _ := meta["preTokenBalances"].([]any)
-							postTB, _ := meta["postTokenBalances"].([]any)
-							type tokenState struct {
-								owner, mint, amount string
-								decimals            int
-							}
-							preMap := map[int]tokenState{}
-							postMap := map[int]tokenState{}
-							for _, it := range preTB {
-								m := it.(map[string]any)
-								idx := intFromAny(m["accountIndex"])
-								mint := strings.ToLower(str(m["mint"]))
-								owner := str(m["owner"])
-								ui, _ := m["uiTokenAmount"].(map[string]any)
-								amt := str(ui["amount"])
-								dec := intFromAny(ui["decimals"])
-								preMap[idx] = tokenState{owner: owner, mint: mint, amount: amt, decimals: dec}
-							}
-							for _, it := range postTB {
-								m := it.(map[string]any)
-								idx := intFromAny(m["accountIndex"])
-								mint := strings.ToLower(str(m["mint"]))
-								owner := str(m["owner"])
-								ui, _ := m["uiTokenAmount"].(map[string]any)
-								amt := str(ui["amount"])
-								dec := intFromAny(ui["decimals"])
-								postMap[idx] = tokenState{owner: owner, mint: mint, amount: amt, decimals: dec}
-							}
-							for idx, pre := range preMap {
-								post, ok := postMap[idx]
-								if !ok || pre.mint != post.mint {
-									continue
-								}
-								owner := post.owner
-								if owner == "" {
-									owner = pre.owner
-								}
-								if !(addrSet[owner] || addrLower[strings.ToLower(owner)]) {
-									continue
-								}
-								dec := post.decimals
-								if dec <= 0 {
-									dec = pre.decimals
-								}
-								diff := bigIntSub(post.amount, pre.amount)
-								if diff.Sign() == 0 {
-									continue
-								}
-								sym := mintToSymbol[strings.ToLower(pre.mint)]
-								if sym == "" || !util.IsAllowed(sym) {
-									continue
-								}
-								amount := toDecimal(new(big.Int).Abs(diff), dec)
-								dir := "in"
-								if diff.Sign() < 0 {
-									dir = "out"
-								}
-								events = append(events, models.Event{
-									Entity: entity, Chain: "solana", Coin: sym, Direction: dir, Amount: amount,
-									TS: blkt, TxID: txid, From: "", To: "", Address: owner, LogIndex: logIndex,
-								})
-								logIndex++
+							if !strings.Contains(strings.ToLower(err.Error()), "skipped") {
+								stats.Failed++
 							}
+							continue
 						}
+						blkEvents, txs := solBlockEvents(entity, blk, watched, mintToSymbol, &logIndex)
+						stats.Txs += txs
+						events = append(events, blkEvents...)
+					}
+					stats.Elapsed = time.Since(scanStart)
+					stats.RateLimited = solEPs.rateLimitHits - rateLimitBefore
+					if next := solSteps.Observe(entity, stats); next != step {
+						logv("[solana] entity=%s step %d -> %d (slots=%d txs=%d 429=%d failed=%d duration=%s)",
+							entity, step, next, stats.Slots, stats.Txs, stats.RateLimited, stats.Failed, stats.Elapsed)
 					}
 
 					minT, maxT, byCoin := summarize(events)
@@ -1431,40 +1751,118 @@ func main() {
 							entity, len(events), rangeStr(cur, to),
 							minT.UTC().Format(time.RFC3339), maxT.UTC().Format(time.RFC3339), byCoin, time.Since(scanStart))
 					}
-					if len(events) > 0 {
-						u := fmt.Sprintf("%s/ingest/events?entity=%s", strings.TrimRight(*apiBase, "/"), entity)
-						var resp struct {
-							OK    bool   `json:"ok"`
-							Saved int    `json:"saved"`
-							RunID string `json:"run_id"`
+					addrTypes.Tag(events)
+					next := to + 1
+					if err := ingestWindow(ctx, evSink, entity, events, func() error {
+						return commitCursor(ctx, entity, "solana", next)
+					}); err != nil {
+						log.Printf("[solana] entity=%s window=%s not committed, cursor stays at %d: %v", entity, rangeStr(cur, to), cur, err)
+					} else {
+						cursorSOL[entity] = next
+						metrics.WindowCommitted("solana", entity, next-cur, next, events)
+						chainProgressed = true
+					}
+				}
+			}
+			poller.Done("solana", time.Now(), chainProgressed)
+			progressed = progressed || chainProgressed
+		}
+
+		// —— Tron（TRC20）
+		if len(tronAPIs) > 0 && chainSwitch.Enabled("tron") && poller.Due("tron", loopStart) {
+			chainProgressed := false
+			latest, err := tronLatest(ctx)
+			if err != nil {
+				log.Printf("[latest] tron error: %v", err)
+			} else {
+				for entity, addrs := range addressesTRON {
+					if shutdown.Err() != nil {
+						break
+					}
+					if (*entityArg != "" && !strings.EqualFold(*entityArg, entity)) || !due[entity] {
+						continue
+					}
+					cur, ok := cursorTRON[entity]
+					if !ok || cur > latest {
+						continue
+					}
+					lagAlerts.Observe("tron", entity, latest, cur, time.Now())
+					tip, ok := confirmedTip(latest, confirmationsFor("tron", confirmations))
+					if !ok {
+						continue
+					}
+					to, ok := scanWindow(cur, *tronStep-1, tip)
+					if !ok {
+						continue
+					}
+					// 实体级币种范围
+					tokens := map[string]tronToken{}
+					for c, tok := range tronTokens {
+						if util.IsAllowedFor(entity, tok.Symbol) {
+							tokens[c] = tok
 						}
-						if err := netutil.PostJSON(context.Background(), u, events, &resp); err != nil {
-							log.Printf("ingest error (sol): %v", err)
-						} else {
-							log.Printf("ingest ok (sol): entity=%s saved=%d run_id=%s", entity, resp.Saved, resp.RunID)
+					}
+					addrSet := toSetExact(addrs)
+					events := make([]models.Event, 0, 256)
+					scanStart := time.Now()
+					logv("[tron] entity=%s window=%s latest=%d addrs=%d", entity, rangeStr(cur, to), latest, len(addrs))
+					failed := false
+					for h := cur; h <= to && len(tokens) > 0; h++ {
+						if (h-cur)%uint64(*logEvery) == 0 {
+							logv("[tron] block %d/%d (+%d)", h, to, h-cur)
 						}
+						infos, err := tronBlock(ctx, h)
+						if err != nil {
+							log.Printf("[tron] entity=%s block %d: %v, cursor stays at %d", entity, h, err, cur)
+							failed = true
+							break
+						}
+						for _, info := range infos {
+							events = append(events, tronTxEvents(entity, info, tokens, addrSet)...)
+						}
+					}
+					if failed {
+						continue
+					}
+					minT, maxT, byCoin := summarize(events)
+					if len(events) == 0 {
+						logv("[tron] entity=%s no-events window=%s duration=%s", entity, rangeStr(cur, to), time.Since(scanStart))
+					} else {
+						logv("[tron] entity=%s events=%d window=%s ts=[%s .. %s] byCoin=%v duration=%s",
+							entity, len(events), rangeStr(cur, to),
+							minT.UTC().Format(time.RFC3339), maxT.UTC().Format(time.RFC3339), byCoin, time.Since(scanStart))
 					}
+					addrTypes.Tag(events)
 					next := to + 1
-					if err := netutil.PostJSON(context.Background(),
-						fmt.Sprintf("%s/sync/cursor?entity=%s&chain=solana", strings.TrimRight(*apiBase, "/"), entity),
-						map[string]uint64{"block": next}, &struct {
-							OK bool `json:"ok"`
-						}{},
-					); err != nil {
-						log.Printf("[cursor] set SOL %s -> %d error: %v", entity, next, err)
+					if err := ingestWindow(ctx, evSink, entity, events, func() error {
+						return commitCursor(ctx, entity, "tron", next)
+					}); err != nil {
+						log.Printf("[tron] entity=%s window=%s not committed, cursor stays at %d: %v", entity, rangeStr(cur, to), cur, err)
 					} else {
-						cursorSOL[entity] = next
-						progressed = true
+						cursorTRON[entity] = next
+						metrics.WindowCommitted("tron", entity, next-cur, next, events)
+						chainProgressed = true
 					}
 				}
 			}
+			poller.Done("tron", time.Now(), chainProgressed)
+			progressed = progressed || chainProgressed
 		}
 
-		if !progressed {
-			logv("[idle] no chain progressed; sleep=%s", *poll)
-			time.Sleep(*poll)
+		// 有进展的链立即再扫；否则睡到最近一条链的下次扫描时间
+		if wait := poller.Wait(time.Now(), chainSwitch.Enabled); !progressed && wait > 0 {
+			logv("[idle] no chain progressed; sleep=%s", wait)
+			select {
+			case <-shutdown.Done():
+			case <-time.After(wait):
+			}
 		}
 	}
+
+	log.Printf("[shutdown] scan loop stopped, waiting for log subscriptions to flush")
+	wsWG.Wait()
+	solHealthStore.Flush(time.Now())
+	stopMetricsServer(metricsServer)
 }
 
 /*************** 工具函数 ***************/
@@ -1655,27 +2053,6 @@ func hexToUint64(h string) uint64 {
 	n, _ := new(big.Int).SetString(strings.TrimPrefix(h, "0x"), 16)
 	return n.Uint64()
 }
-func firstVoutAddr(vouts []btcVout) string {
-	for _, v := range vouts {
-		a := strings.TrimSpace(v.ScriptPubKeyAddress)
-		if a != "" {
-			return a
-		}
-	}
-	return ""
-}
-func firstVinAddr(vins []btcVin) string {
-	for _, vin := range vins {
-		if vin.Prevout == nil {
-			continue
-		}
-		a := strings.TrimSpace(vin.Prevout.ScriptPubKeyAddress)
-		if a != "" {
-			return a
-		}
-	}
-	return ""
-}
 func parseEsploraEndpoints(s string) []string {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -1830,6 +2207,7 @@ func parseSolanaTransfers(tx map[string]any) []solTransfer {
 						dec = intFromAny(ta["decimals"])
 					}
 				}
+				rawAmount := ""
 				if amountDec == "" {
 					raw := str(info["amount"])
 					if n, ok := new(big.Int).SetString(raw, 10); ok {
@@ -1837,10 +2215,11 @@ func parseSolanaTransfers(tx map[string]any) []solTransfer {
 							dec = 6
 						}
 						amountDec = toDecimal(n, dec)
+						rawAmount = raw
 					}
 				}
 				out = append(out, solTransfer{
-					isSOL: false, mint: mint, decimals: dec, amountDec: amountDec,
+					isSOL: false, mint: mint, decimals: dec, amountDec: amountDec, rawAmount: rawAmount,
 					source: src, destination: dst,
 				})
 			}
@@ -1886,26 +2265,9 @@ func uniqueLower(ss []string) []string {
 	return out
 }
 
-func evmNativeSymbol(chain string) string {
-	c := strings.ToLower(strings.TrimSpace(chain))
-	switch c {
-	case "ethereum", "eth":
-		return "ETH"
-	case "bsc", "bnb", "bnbchain", "bnbsmartchain":
-		return "BNB"
-	case "polygon", "matic":
-		return "MATIC"
-	case "avalanche", "avax", "avaxc", "avalanchec":
-		return "AVAX"
-	case "fantom", "ftm":
-		return "FTM"
-	case "op", "optimism":
-		return "ETH"
-	case "arbitrum", "arb", "arbitrumone":
-		return "ETH"
-	case "base":
-		return "ETH"
-	default:
-		return ""
+func evmNativeSymbol(cc config.ChainCfg) string {
+	if cc.NativeSymbol == "" {
+		log.Printf("[warn] chain %s has no native_symbol (not in built-in map), native transfers skipped", cc.Name)
 	}
+	return cc.NativeSymbol
 }
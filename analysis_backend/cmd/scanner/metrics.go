@@ -0,0 +1,192 @@
+// cmd/scanner/metrics.go
+// Prometheus 指标（-metrics-addr）：扫描区块数、下发事件数、RPC 错误数、游标高度与下发耗时直方图，
+// 用于“游标停滞”“端点切换频繁”之类的告警。文本格式手写输出，与 data_sync 的 /metrics 一致，不引入客户端库。
+// endpoint 标签只保留主机名，RPC URL 路径里常带 API key。
+
+package main
+
+import (
+	"analysis/internal/models"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ingestLatencyBuckets 下发耗时直方图的上界（秒）
+var ingestLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// scanMetrics 进程内指标；EVM 扫描任务并发更新，加锁
+type scanMetrics struct {
+	mu            sync.Mutex
+	blocks        map[[2]string]uint64 // chain, entity
+	events        map[[2]string]uint64 // chain, coin
+	rpcErrors     map[[2]string]uint64 // chain, endpoint
+	cursor        map[[2]string]uint64 // chain, entity
+	ingestCounts  []uint64             // 与 ingestLatencyBuckets 对应（非累计），末尾为 +Inf
+	ingestSum     float64
+	ingestSamples uint64
+}
+
+// metrics 全局指标；未开启 -metrics-addr 时照常计数，只是不暴露
+var metrics = newScanMetrics()
+
+func newScanMetrics() *scanMetrics {
+	return &scanMetrics{
+		blocks: map[[2]string]uint64{}, events: map[[2]string]uint64{},
+		rpcErrors: map[[2]string]uint64{}, cursor: map[[2]string]uint64{},
+		ingestCounts: make([]uint64, len(ingestLatencyBuckets)+1),
+	}
+}
+
+// WindowCommitted 窗口提交成功：累计扫描的区块（slot）数与事件数，更新游标高度
+func (m *scanMetrics) WindowCommitted(chain, entity string, blocks uint64, next uint64, events []models.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocks[[2]string{chain, entity}] += blocks
+	m.addEvents(chain, events)
+	m.cursor[[2]string{chain, entity}] = next
+}
+
+// EventsEmitted 窗口之外下发的事件（日志订阅）
+func (m *scanMetrics) EventsEmitted(chain string, events []models.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addEvents(chain, events)
+}
+
+func (m *scanMetrics) addEvents(chain string, events []models.Event) {
+	for _, e := range events {
+		m.events[[2]string{chain, e.Coin}]++
+	}
+}
+
+// SetCursor 启动时加载的游标高度
+func (m *scanMetrics) SetCursor(chain, entity string, height uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cursor[[2]string{chain, entity}] = height
+}
+
+// RPCError 端点一次调用失败
+func (m *scanMetrics) RPCError(chain, endpoint string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rpcErrors[[2]string{chain, metricsEndpoint(endpoint)}]++
+}
+
+// ObserveIngest 一次事件下发的耗时
+func (m *scanMetrics) ObserveIngest(d time.Duration) {
+	s := d.Seconds()
+	i := sort.SearchFloat64s(ingestLatencyBuckets, s)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ingestCounts[i]++
+	m.ingestSum += s
+	m.ingestSamples++
+}
+
+// metricsEndpoint URL -> 主机名（去掉路径与查询串里的 API key）
+func metricsEndpoint(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return endpoint
+}
+
+// WritePrometheus 以 Prometheus 文本格式输出
+func (m *scanMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	metric := func(name, typ, help string, emit func()) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		emit()
+	}
+	series := func(name, l1, l2 string, values map[[2]string]uint64) func() {
+		return func() {
+			keys := make([][2]string, 0, len(values))
+			for k := range values {
+				keys = append(keys, k)
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				if keys[i][0] != keys[j][0] {
+					return keys[i][0] < keys[j][0]
+				}
+				return keys[i][1] < keys[j][1]
+			})
+			for _, k := range keys {
+				fmt.Fprintf(&b, "%s{%s=\"%s\",%s=\"%s\"} %d\n", name, l1, promLabel(k[0]), l2, promLabel(k[1]), values[k])
+			}
+		}
+	}
+	metric("blocks_scanned_total", "counter", "Blocks (slots) in committed scan windows.", series("blocks_scanned_total", "chain", "entity", m.blocks))
+	metric("events_emitted_total", "counter", "Events delivered to the event sink.", series("events_emitted_total", "chain", "coin", m.events))
+	metric("rpc_errors_total", "counter", "Failed RPC calls by endpoint host.", series("rpc_errors_total", "chain", "endpoint", m.rpcErrors))
+	metric("cursor_height", "gauge", "Next block (slot) to scan.", series("cursor_height", "chain", "entity", m.cursor))
+	metric("ingest_latency_seconds", "histogram", "Time to publish one scan window's events.", func() {
+		var cum uint64
+		for i, le := range ingestLatencyBuckets {
+			cum += m.ingestCounts[i]
+			fmt.Fprintf(&b, "ingest_latency_seconds_bucket{le=\"%g\"} %d\n", le, cum)
+		}
+		cum += m.ingestCounts[len(ingestLatencyBuckets)]
+		fmt.Fprintf(&b, "ingest_latency_seconds_bucket{le=\"+Inf\"} %d\n", cum)
+		fmt.Fprintf(&b, "ingest_latency_seconds_sum %g\n", m.ingestSum)
+		fmt.Fprintf(&b, "ingest_latency_seconds_count %d\n", m.ingestSamples)
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// promLabel 转义 Prometheus 标签值
+func promLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+// Handler 提供 /metrics
+func (m *scanMetrics) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := m.WritePrometheus(w); err != nil {
+			log.Printf("[metrics] write failed: %v", err)
+		}
+	})
+	return mux
+}
+
+// startMetricsServer 在 addr 上暴露 /metrics（独立协程，不阻塞扫描）；addr 为空时不启动，返回 nil
+func startMetricsServer(addr string, m *scanMetrics) *http.Server {
+	if addr == "" {
+		return nil
+	}
+	srv := &http.Server{Addr: addr, Handler: m.Handler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		log.Printf("[metrics] listening on %s (/metrics)", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("[metrics] server error: %v", err)
+		}
+	}()
+	return srv
+}
+
+// stopMetricsServer 关闭监控端点
+func stopMetricsServer(srv *http.Server) {
+	if srv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("[metrics] shutdown error: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/sink"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrapeMetrics(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type 不符: %s", ct)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+func assertMetrics(t *testing.T, out string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(out, w+"\n") {
+			t.Errorf("缺少 %q，实际:\n%s", w, out)
+		}
+	}
+}
+
+// TestScanMetricsDuringMockScan 模拟两个窗口的扫描：/metrics 的区块数、事件数、游标高度与下发耗时随之递增；endpoint 标签不含 URL 路径
+func TestScanMetricsDuringMockScan(t *testing.T) {
+	saved := metrics
+	metrics = newScanMetrics()
+	defer func() { metrics = saved }()
+
+	api := &ingestAPI{maxBatch: 500}
+	ingest := httptest.NewServer(http.HandlerFunc(api.handler))
+	defer ingest.Close()
+	s := sink.NewHTTPSink(ingest.URL)
+
+	exp := httptest.NewServer(metrics.Handler())
+	defer exp.Close()
+
+	metrics.SetCursor("ethereum", "binance", 100)
+	scanWindow := func(cur, to uint64, coins ...string) {
+		events := make([]models.Event, len(coins))
+		for i, c := range coins {
+			events[i] = models.Event{Entity: "binance", Chain: "ethereum", Coin: c, Direction: "in", Amount: "1", LogIndex: i}
+		}
+		next := to + 1
+		if err := ingestWindow(context.Background(), s, "binance", events, func() error {
+			return postCursor(context.Background(), ingest.URL, "binance", "ethereum", next)
+		}); err != nil {
+			t.Fatal(err)
+		}
+		metrics.WindowCommitted("ethereum", "binance", next-cur, next, events)
+	}
+
+	assertMetrics(t, scrapeMetrics(t, exp.URL), `cursor_height{chain="ethereum",entity="binance"} 100`, "ingest_latency_seconds_count 0")
+
+	scanWindow(100, 149, "USDT", "USDT", "ETH")
+	metrics.RPCError("ethereum", "https://eth-mainnet.example.com/v2/SECRETKEY")
+	assertMetrics(t, scrapeMetrics(t, exp.URL),
+		"# TYPE blocks_scanned_total counter",
+		`blocks_scanned_total{chain="ethereum",entity="binance"} 50`,
+		`events_emitted_total{chain="ethereum",coin="ETH"} 1`,
+		`events_emitted_total{chain="ethereum",coin="USDT"} 2`,
+		`rpc_errors_total{chain="ethereum",endpoint="eth-mainnet.example.com"} 1`,
+		`cursor_height{chain="ethereum",entity="binance"} 150`,
+		`ingest_latency_seconds_bucket{le="+Inf"} 1`,
+		"ingest_latency_seconds_count 1",
+	)
+
+	scanWindow(150, 159) // 空窗口不下发，不计下发耗时
+	scanWindow(160, 169, "USDT")
+	metrics.RPCError("ethereum", "https://eth-mainnet.example.com/v2/SECRETKEY")
+	out := scrapeMetrics(t, exp.URL)
+	assertMetrics(t, out,
+		`blocks_scanned_total{chain="ethereum",entity="binance"} 70`,
+		`events_emitted_total{chain="ethereum",coin="USDT"} 3`,
+		`rpc_errors_total{chain="ethereum",endpoint="eth-mainnet.example.com"} 2`,
+		`cursor_height{chain="ethereum",entity="binance"} 170`,
+		"ingest_latency_seconds_count 2",
+	)
+	if strings.Contains(out, "SECRETKEY") {
+		t.Error("endpoint 标签不应包含 URL 路径")
+	}
+}
+
+// TestScanMetricsIngestBuckets 直方图按上界累计
+func TestScanMetricsIngestBuckets(t *testing.T) {
+	m := newScanMetrics()
+	m.ObserveIngest(30 * time.Millisecond)
+	m.ObserveIngest(300 * time.Millisecond)
+	m.ObserveIngest(time.Minute)
+	var b strings.Builder
+	if err := m.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	assertMetrics(t, b.String(),
+		"# TYPE ingest_latency_seconds histogram",
+		`ingest_latency_seconds_bucket{le="0.05"} 1`,
+		`ingest_latency_seconds_bucket{le="0.25"} 1`,
+		`ingest_latency_seconds_bucket{le="0.5"} 2`,
+		`ingest_latency_seconds_bucket{le="30"} 2`,
+		`ingest_latency_seconds_bucket{le="+Inf"} 3`,
+		"ingest_latency_seconds_count 3",
+	)
+}
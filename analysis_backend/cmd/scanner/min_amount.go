@@ -0,0 +1,68 @@
+// cmd/scanner/min_amount.go
+// 按币种的最小金额过滤：交易所地址会收到大量 ERC20/SPL 粉尘转账，chains.*.erc20/spl 的 min_amount
+// 配置阈值，金额换算精度后低于阈值的事件在上报前丢弃。比较用 big.Rat，18 位精度的代币不丢精度。
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"analysis/internal/config"
+)
+
+// minAmounts 由配置初始化，只读
+var minAmounts minAmountCfg
+
+// minAmountCfg 链|币种（大写） -> 阈值；没有条目表示不过滤
+type minAmountCfg map[string]*big.Rat
+
+func minAmountKey(chain, symbol string) string {
+	return strings.ToLower(chain) + "|" + strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// newMinAmounts 汇总各链 erc20/spl 的 min_amount；为空或 0 的不记录，非法或负数报错
+func newMinAmounts(chains map[string]config.ChainCfg) (minAmountCfg, error) {
+	out := minAmountCfg{}
+	add := func(chain, symbol, raw string) error {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			return nil
+		}
+		r, ok := new(big.Rat).SetString(raw)
+		if !ok || r.Sign() < 0 {
+			return fmt.Errorf("chains.%s token %s: bad min_amount %q", chain, symbol, raw)
+		}
+		if r.Sign() > 0 {
+			out[minAmountKey(chain, symbol)] = r
+		}
+		return nil
+	}
+	for name, cc := range chains {
+		for _, t := range cc.ERC20 {
+			if err := add(name, t.Symbol, t.MinAmount); err != nil {
+				return nil, err
+			}
+		}
+		for _, t := range cc.SPL {
+			if err := add(name, t.Symbol, t.MinAmount); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// below 金额（toDecimal 的十进制字符串）低于该币种阈值；未配置阈值或金额无法解析时返回 false
+func (m minAmountCfg) below(chain, symbol, amount string) bool {
+	min := m[minAmountKey(chain, symbol)]
+	if min == nil {
+		return false
+	}
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return false
+	}
+	return r.Cmp(min) < 0
+}
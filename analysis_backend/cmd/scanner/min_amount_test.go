@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"analysis/internal/config"
+	"analysis/internal/models"
+)
+
+func setTestMinAmounts(t *testing.T, chains map[string]config.ChainCfg) {
+	t.Helper()
+	m, err := newMinAmounts(chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	minAmounts = m
+	t.Cleanup(func() { minAmounts = nil })
+}
+
+// TestMinAmountsBelow 阈值按链+币种生效，big.Rat 比较不丢 18 位精度；为空或 0 不过滤，非法值报错
+func TestMinAmountsBelow(t *testing.T) {
+	m, err := newMinAmounts(map[string]config.ChainCfg{
+		"ethereum": {ERC20: []config.TokenERC20{
+			{Symbol: "usdt", MinAmount: "0.0001"},
+			{Symbol: "USDC", MinAmount: "0"},
+			{Symbol: "DAI"},
+		}},
+		"solana": {SPL: []config.TokenSPL{{Symbol: "USDT", MinAmount: "0.0001"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		chain, coin, amount string
+		want                bool
+	}{
+		{"ethereum", "USDT", "0.00005000", true},
+		{"ethereum", "USDT", "0.000099999999999999999", true}, // float64 会舍入成 0.0001
+		{"ethereum", "USDT", "0.0001", false},
+		{"ethereum", "USDT", "1.00000000", false},
+		{"ethereum", "USDC", "0.00000001", false},
+		{"ethereum", "DAI", "0.00000001", false},
+		{"bsc", "USDT", "0.00000001", false},
+		{"solana", "USDT", "0.00005000", true},
+		{"solana", "SOL", "0.00005000", false},
+	}
+	for _, c := range cases {
+		if got := m.below(c.chain, c.coin, c.amount); got != c.want {
+			t.Errorf("below(%s,%s,%s)=%v，期望 %v", c.chain, c.coin, c.amount, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"abc", "-1"} {
+		if _, err := newMinAmounts(map[string]config.ChainCfg{"ethereum": {ERC20: []config.TokenERC20{{Symbol: "USDT", MinAmount: bad}}}}); err == nil {
+			t.Errorf("min_amount=%q 应报错", bad)
+		}
+	}
+}
+
+// TestMinAmountsERC20 0.0001 阈值丢弃粉尘 Transfer，保留 1.0 的转账
+func TestMinAmountsERC20(t *testing.T) {
+	setTestMinAmounts(t, map[string]config.ChainCfg{
+		"ethereum": {ERC20: []config.TokenERC20{{Symbol: "USDT", MinAmount: "0.0001"}}},
+	})
+	addrSet := map[string]bool{wsTestWatched: true}
+
+	dust := wsTransferLog("0xdust", 101, wsTestOther, wsTestWatched)
+	dust["data"] = "0x32" // 50 / 1e6
+	if ev, ok := evmTransferEvent(dust, "binance", "ethereum", "USDT", 6, addrSet, time.Time{}); ok {
+		t.Errorf("低于阈值的转账应被丢弃: %+v", ev)
+	}
+	one := wsTransferLog("0xone", 101, wsTestOther, wsTestWatched)
+	one["data"] = "0xf4240" // 1000000 / 1e6
+	if ev, ok := evmTransferEvent(one, "binance", "ethereum", "USDT", 6, addrSet, time.Time{}); !ok || ev.Amount != "1.00000000" {
+		t.Errorf("1.0 的转账应保留: ok=%v %+v", ok, ev)
+	}
+}
+
+// solUSDTTransferTx 热钱包转出 raw 个最小单位 USDT（6 位精度）；withIx=false 时去掉指令，只能靠余额差兜底
+func solUSDTTransferTx(t *testing.T, raw int64, withIx bool) map[string]any {
+	t.Helper()
+	tx := decodeSolTx(t, solDoubleCoveredTx)
+	msg := tx["transaction"].(map[string]any)["message"].(map[string]any)
+	if withIx {
+		ixs := msg["instructions"].([]any)
+		ixs[1].(map[string]any)["parsed"].(map[string]any)["info"].(map[string]any)["amount"] = fmt.Sprint(raw)
+	} else {
+		msg["instructions"] = []any{}
+	}
+	post := tx["meta"].(map[string]any)["postTokenBalances"].([]any)
+	post[0].(map[string]any)["uiTokenAmount"].(map[string]any)["amount"] = fmt.Sprint(1000000000 - raw)
+	post[1].(map[string]any)["uiTokenAmount"].(map[string]any)["amount"] = fmt.Sprint(raw)
+	return tx
+}
+
+// TestMinAmountsSolana 指令路径与余额差兜底都按阈值过滤；被丢弃的指令转账不会被余额差补回
+func TestMinAmountsSolana(t *testing.T) {
+	setTestMinAmounts(t, map[string]config.ChainCfg{
+		"solana": {SPL: []config.TokenSPL{{Symbol: "USDT", Mint: testUSDTMint, MinAmount: "0.0001"}}},
+	})
+	usdt := func(events []models.Event) []models.Event {
+		var out []models.Event
+		for _, e := range events {
+			if e.Coin == "USDT" {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+
+	for _, withIx := range []bool{true, false} {
+		if evs := usdt(solTestEvents(t, solUSDTTransferTx(t, 50, withIx))); len(evs) != 0 {
+			t.Errorf("withIx=%v: 0.00005 USDT 应被丢弃，实际 %+v", withIx, evs)
+		}
+		evs := usdt(solTestEvents(t, solUSDTTransferTx(t, 1000000, withIx)))
+		if len(evs) != 1 || evs[0].Amount != "1.00000000" {
+			t.Errorf("withIx=%v: 1.0 USDT 应保留，实际 %+v", withIx, evs)
+		}
+	}
+}
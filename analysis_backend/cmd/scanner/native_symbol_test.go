@@ -0,0 +1,54 @@
+package main
+
+import (
+	"analysis/internal/config"
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestEvmNativeSymbolFromConfig 配置的 native_symbol 优先，已知链回退内置映射，未知链告警并跳过原生币
+func TestEvmNativeSymbolFromConfig(t *testing.T) {
+	const raw = `
+chains:
+  - name: linea
+    type: evm
+    rpc: https://rpc.linea.build
+    native_symbol: eth
+  - name: polygon
+    type: evm
+    rpc: https://polygon-rpc.com
+  - name: mystery
+    type: evm
+    rpc: https://rpc.mystery.example
+`
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	chains := config.BuildChainCfg(&cfg)
+
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(prev)
+
+	if got := evmNativeSymbol(chains["linea"]); got != "ETH" {
+		t.Errorf("linea 应使用配置的 ETH，实际 %q", got)
+	}
+	if got := evmNativeSymbol(chains["polygon"]); got != "MATIC" {
+		t.Errorf("polygon 应回退内置映射 MATIC，实际 %q", got)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("已知链不应告警: %s", buf.String())
+	}
+	if got := evmNativeSymbol(chains["mystery"]); got != "" {
+		t.Errorf("未知链不应有原生币符号，实际 %q", got)
+	}
+	if !strings.Contains(buf.String(), "chain mystery has no native_symbol") {
+		t.Errorf("未知链应告警，日志: %q", buf.String())
+	}
+}
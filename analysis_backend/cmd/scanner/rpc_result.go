@@ -0,0 +1,32 @@
+// cmd/scanner/rpc_result.go
+// JSON-RPC result 解码：调用成功但 result 为空/null（区块未产出或被跳过、节点未索引等）返回 ErrEmptyResult，
+// 调用方用 errors.Is 与真正的调用失败区分，不再比较 "null" 字符串。
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrEmptyResult RPC 调用成功但 result 为空或 null
+var ErrEmptyResult = errors.New("rpc empty result")
+
+// isEmptyResult result 缺省、为空或为 JSON null
+func isEmptyResult(raw json.RawMessage) bool {
+	t := bytes.TrimSpace(raw)
+	return len(t) == 0 || bytes.Equal(t, []byte("null"))
+}
+
+// decodeResult 把 out.Result 解码到 v；result 为空/null 时返回包装了 ErrEmptyResult 的错误（what 用于错误信息）
+func decodeResult(what string, out *rpcResp, v any) error {
+	if isEmptyResult(out.Result) {
+		return fmt.Errorf("%s: %w", what, ErrEmptyResult)
+	}
+	if err := json.Unmarshal(out.Result, v); err != nil {
+		return fmt.Errorf("%s decode result: %w", what, err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newMockRPCBody 固定返回 body 的模拟 JSON-RPC 端点
+func newMockRPCBody(t *testing.T, body string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// TestDecodeResultNullIsEmptyResult result 为 null/缺省时返回 ErrEmptyResult，RPC 错误与解码错误不是
+func TestDecodeResultNullIsEmptyResult(t *testing.T) {
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","id":1,"result":null}`,
+		`{"jsonrpc":"2.0","id":1}`,
+	} {
+		var out rpcResp
+		if err := postRPC(context.Background(), newMockRPCBody(t, body), "getBlock", nil, &out); err != nil {
+			t.Fatalf("null result 不应是调用错误: %v", err)
+		}
+		var blk map[string]any
+		if err := decodeResult("getBlock slot 1", &out, &blk); !errors.Is(err, ErrEmptyResult) {
+			t.Errorf("%s 期望 ErrEmptyResult，实际 %v", body, err)
+		}
+	}
+
+	var out rpcResp
+	err := postRPC(context.Background(), newMockRPCBody(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32007,"message":"Slot 1 was skipped"}}`), "getBlock", nil, &out)
+	if err == nil || errors.Is(err, ErrEmptyResult) {
+		t.Errorf("RPC 错误不应是 ErrEmptyResult: %v", err)
+	}
+
+	out = rpcResp{Result: []byte(`"0x1"`)}
+	var logs []map[string]any
+	if err := decodeResult("eth_getLogs", &out, &logs); err == nil || errors.Is(err, ErrEmptyResult) {
+		t.Errorf("类型不符应是解码错误: %v", err)
+	}
+	out = rpcResp{Result: []byte(`[]`)}
+	if err := decodeResult("eth_getLogs", &out, &logs); err != nil || len(logs) != 0 {
+		t.Errorf("空数组是有效结果: %v %v", logs, err)
+	}
+}
+
+// TestSolTipSlotNullResult getSlot 返回 null 时返回 ErrEmptyResult
+func TestSolTipSlotNullResult(t *testing.T) {
+	post := func(ctx context.Context, method string, params []any, out *rpcResp) error {
+		out.Result = []byte("null")
+		return nil
+	}
+	if _, err := solTipSlot(context.Background(), post, false); !errors.Is(err, ErrEmptyResult) {
+		t.Errorf("期望 ErrEmptyResult，实际 %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+// cmd/scanner/schedule.go
+// 实体扫描调度：实体很多时每轮只扫描一部分。按权重做平滑加权轮询，权重高（活跃）的实体扫描更频繁；
+// 超过 maxInterval 未扫描的实体下一轮强制入选，保证每个实体最终都会被扫描到。
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// entityScheduler 每轮挑选要扫描的实体
+type entityScheduler struct {
+	perRound    int           // 每轮最多扫描的实体数（不含超时强制入选的），<=0 表示全部
+	maxInterval time.Duration // 单个实体两次扫描的最大间隔，<=0 不限制
+
+	names   []string
+	weights map[string]float64
+	total   float64
+	credit  map[string]float64
+	last    map[string]time.Time
+}
+
+// newEntityScheduler 创建调度器；未配置权重的实体权重为 1，start 视为所有实体的上次扫描时间
+func newEntityScheduler(entities []string, weights map[string]float64, perRound int, maxInterval time.Duration, start time.Time) *entityScheduler {
+	s := &entityScheduler{
+		perRound:    perRound,
+		maxInterval: maxInterval,
+		weights:     map[string]float64{},
+		credit:      map[string]float64{},
+		last:        map[string]time.Time{},
+	}
+	seen := map[string]bool{}
+	for _, e := range entities {
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		w := 1.0
+		if v, ok := weights[strings.ToLower(e)]; ok && v > 0 {
+			w = v
+		}
+		s.names = append(s.names, e)
+		s.weights[e] = w
+		s.total += w
+		s.last[e] = start
+	}
+	sort.Strings(s.names)
+	return s
+}
+
+// Next 返回本轮要扫描的实体集合，并记录扫描时间
+func (s *entityScheduler) Next(now time.Time) map[string]bool {
+	due := make(map[string]bool, len(s.names))
+	if s.perRound <= 0 || s.perRound >= len(s.names) {
+		for _, e := range s.names {
+			due[e] = true
+			s.last[e] = now
+		}
+		return due
+	}
+
+	for _, e := range s.names {
+		s.credit[e] += s.weights[e]
+		// 防止单个高权重实体的积分无限累积
+		if s.credit[e] > s.total {
+			s.credit[e] = s.total
+		}
+	}
+
+	// 超时的实体强制入选
+	for _, e := range s.names {
+		if s.maxInterval > 0 && now.Sub(s.last[e]) >= s.maxInterval {
+			due[e] = true
+		}
+	}
+
+	// 其余名额按积分从高到低分配
+	ranked := make([]string, 0, len(s.names))
+	for _, e := range s.names {
+		if !due[e] {
+			ranked = append(ranked, e)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return s.credit[ranked[i]] > s.credit[ranked[j]] })
+	for _, e := range ranked {
+		if len(due) >= s.perRound {
+			break
+		}
+		due[e] = true
+	}
+
+	share := s.total / float64(s.perRound)
+	for e := range due {
+		s.credit[e] -= share
+		s.last[e] = now
+	}
+	return due
+}
+
+// parseEntityWeights 解析 "binance=5,okx=2" 形式的实体权重（实体名不区分大小写）
+func parseEntityWeights(s string) (map[string]float64, error) {
+	out := map[string]float64{}
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		k, v, ok := strings.Cut(part, "=")
+		k = strings.ToLower(strings.TrimSpace(k))
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid entity weight %q (want name=weight)", part)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || w <= 0 {
+			return nil, fmt.Errorf("invalid entity weight %q: must be a positive number", part)
+		}
+		out[k] = w
+	}
+	return out, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEntitySchedulerRespectsWeights 每轮扫描 1 个实体时，扫描次数与权重成正比
+func TestEntitySchedulerRespectsWeights(t *testing.T) {
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	weights, err := parseEntityWeights("Binance=4, okx=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newEntityScheduler([]string{"binance", "okx", "bybit", "kraken"}, weights, 1, 0, start)
+
+	counts := map[string]int{}
+	for i := 0; i < 80; i++ {
+		due := s.Next(start.Add(time.Duration(i) * time.Second))
+		if len(due) != 1 {
+			t.Fatalf("第%d轮应只扫描 1 个实体，实际 %v", i, due)
+		}
+		for e := range due {
+			counts[e]++
+		}
+	}
+	want := map[string]int{"binance": 40, "okx": 20, "bybit": 10, "kraken": 10}
+	for e, n := range want {
+		if counts[e] != n {
+			t.Errorf("%s 扫描次数期望 %d，实际 %d（全部 %v）", e, n, counts[e], counts)
+		}
+	}
+}
+
+// TestEntitySchedulerMaxInterval 低权重实体被挤占时，超过最大间隔也会被强制扫描
+func TestEntitySchedulerMaxInterval(t *testing.T) {
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	entities := []string{"hot1", "hot2", "cold1", "cold2", "cold3"}
+	weights := map[string]float64{"hot1": 100, "hot2": 100}
+	s := newEntityScheduler(entities, weights, 2, 5*time.Second, start)
+
+	last := map[string]int{}
+	for _, e := range entities {
+		last[e] = 0
+	}
+	hot := 0
+	for i := 1; i <= 60; i++ {
+		due := s.Next(start.Add(time.Duration(i) * time.Second))
+		for e := range due {
+			last[e] = i
+			if e == "hot1" || e == "hot2" {
+				hot++
+			}
+		}
+		for _, e := range entities {
+			if gap := i - last[e]; gap >= 5 {
+				t.Fatalf("第%d轮: %s 已 %d 轮未扫描，超过最大间隔", i, e, gap)
+			}
+		}
+	}
+	if hot < 60 {
+		t.Errorf("高权重实体应占大部分名额，60 轮内只扫描了 %d 次", hot)
+	}
+}
+
+// TestEntitySchedulerAllWhenUnlimited 未限制每轮数量时每轮扫描全部实体
+func TestEntitySchedulerAllWhenUnlimited(t *testing.T) {
+	s := newEntityScheduler([]string{"a", "b", "c", "a"}, nil, 0, time.Minute, time.Now())
+	if due := s.Next(time.Now()); len(due) != 3 {
+		t.Errorf("期望扫描全部 3 个实体，实际 %v", due)
+	}
+	if _, err := parseEntityWeights("binance=abc"); err == nil {
+		t.Error("非法权重应报错")
+	}
+}
@@ -0,0 +1,50 @@
+// cmd/scanner/shutdown.go
+// 优雅退出：第一次 SIGINT/SIGTERM 只停止开始新窗口，进行中的窗口继续跑完并提交游标；
+// 超过 -shutdown-grace 或再次收到信号时才取消进行中的 RPC/上报（窗口不提交，下次启动从原游标重扫）。
+// 原先信号直接取消 RPC 使用的 ctx，窗口做到一半被丢弃；上报与游标用的又是 context.Background()，无法强制退出。
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// shutdownContexts 返回两个 ctx：stop 在第一次信号时取消（主循环与订阅据此退出），
+// work 在宽限期结束或第二次信号时取消（RPC、上报、游标提交使用）；grace<=0 表示只等第二次信号。
+// cancel 释放两者并结束后台协程
+func shutdownContexts(sigs <-chan os.Signal, grace time.Duration) (stop, work context.Context, cancel func()) {
+	stop, stopCancel := context.WithCancel(context.Background())
+	work, workCancel := context.WithCancel(context.Background())
+	go func() {
+		defer workCancel()
+		var sig os.Signal
+		select {
+		case sig = <-sigs:
+		case <-work.Done():
+			return
+		}
+		log.Printf("[shutdown] %v received, shutting down: finishing in-flight windows and flushing cursors (grace=%s, send again to abort)", sig, grace)
+		stopCancel()
+
+		var timeout <-chan time.Time
+		if grace > 0 {
+			t := time.NewTimer(grace)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case sig = <-sigs:
+			log.Printf("[shutdown] %v received again, aborting in-flight requests", sig)
+		case <-timeout:
+			log.Printf("[shutdown] grace %s elapsed, aborting in-flight requests", grace)
+		case <-work.Done():
+		}
+	}()
+	return stop, work, func() {
+		workCancel()
+		stopCancel()
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestShutdownContextsGrace 第一次信号只取消 stop，work 保持可用直到宽限期结束
+func TestShutdownContextsGrace(t *testing.T) {
+	sigs := make(chan os.Signal, 2)
+	stop, work, cancel := shutdownContexts(sigs, 100*time.Millisecond)
+	defer cancel()
+
+	if stop.Err() != nil || work.Err() != nil {
+		t.Fatal("收到信号前两个 ctx 都不应取消")
+	}
+	sigs <- os.Interrupt
+	select {
+	case <-stop.Done():
+	case <-time.After(time.Second):
+		t.Fatal("第一次信号后 stop 应取消")
+	}
+	if work.Err() != nil {
+		t.Fatal("宽限期内 work 应保持可用，进行中的窗口才能提交游标")
+	}
+	select {
+	case <-work.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("宽限期结束后 work 应取消")
+	}
+}
+
+// TestShutdownContextsSecondSignal 宽限期内再次收到信号立即取消 work；grace=0 时只等第二次信号
+func TestShutdownContextsSecondSignal(t *testing.T) {
+	sigs := make(chan os.Signal, 2)
+	stop, work, cancel := shutdownContexts(sigs, 0)
+	defer cancel()
+
+	sigs <- os.Interrupt
+	<-stop.Done()
+	time.Sleep(50 * time.Millisecond)
+	if work.Err() != nil {
+		t.Fatal("grace=0 时第一次信号不应取消 work")
+	}
+	sigs <- os.Interrupt
+	select {
+	case <-work.Done():
+	case <-time.After(time.Second):
+		t.Fatal("第二次信号后 work 应取消")
+	}
+}
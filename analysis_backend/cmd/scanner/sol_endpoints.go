@@ -0,0 +1,229 @@
+// cmd/scanner/sol_endpoints.go
+// Solana 多端点状态：403/-32052 封禁、429 冷却（指数退避）、每端点限速，以及冷却即将结束时的降级尝试。
+// 时间统一取自注入的 util.Clock，测试用 FakeClock 推进时间验证冷却到期与降级节奏。
+
+package main
+
+import (
+	"analysis/internal/util"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	solDegradeHeadstart = 1 * time.Second  // 冷却剩余不超过该值时允许降级尝试
+	solDegradeMinGap    = 6 * time.Second  // 同一端点两次降级尝试的最小间隔
+	solMaxBackoff       = 60 * time.Second // 429 冷却上限
+	solRateMaxWait      = 5 * time.Second  // 限速等待超过该值时不等（时钟跳变等异常）
+)
+
+// solEndpoints 端点健康状态；只在扫描主协程使用，不加锁
+type solEndpoints struct {
+	clock        util.Clock
+	rpcs         []string // 已去掉末尾 "/"
+	idx          int      // 最近一次选中的健康端点
+	minInterval  time.Duration
+	baseCooldown time.Duration
+
+	ban           map[string]time.Time     // endpoint -> unbanTime (403/-32052)
+	cooldown      map[string]time.Time     // endpoint -> coolUntil (429)
+	backoff       map[string]*util.Backoff // endpoint -> 429 退避状态
+	lastCall      map[string]time.Time     // endpoint -> 上次调用时间（限速）
+	lastDegrade   map[string]time.Time     // endpoint -> 最近一次降级尝试时间
+	rateLimitHits int                      // 累计 429 次数，用于扫描步长自适应
+}
+
+// newSolEndpoints minInterval<=0 表示不节流；baseCooldown<=0 时按 8s
+func newSolEndpoints(rpcs []string, minInterval, baseCooldown time.Duration, clock util.Clock) *solEndpoints {
+	if baseCooldown <= 0 {
+		baseCooldown = 8 * time.Second
+	}
+	if clock == nil {
+		clock = util.SystemClock
+	}
+	e := &solEndpoints{
+		clock: clock, minInterval: minInterval, baseCooldown: baseCooldown,
+		ban: map[string]time.Time{}, cooldown: map[string]time.Time{}, backoff: map[string]*util.Backoff{},
+		lastCall: map[string]time.Time{}, lastDegrade: map[string]time.Time{},
+	}
+	for _, ep := range rpcs {
+		e.rpcs = append(e.rpcs, strings.TrimRight(ep, "/"))
+	}
+	return e
+}
+
+// Current 最近一次选中的端点（日志用）
+func (e *solEndpoints) Current() string {
+	if len(e.rpcs) == 0 {
+		return ""
+	}
+	return e.rpcs[e.idx]
+}
+
+func (e *solEndpoints) isBanned(ep string, now time.Time) bool {
+	until, ok := e.ban[ep]
+	return ok && now.Before(until)
+}
+
+func (e *solEndpoints) isCooling(ep string, now time.Time) bool {
+	until, ok := e.cooldown[ep]
+	return ok && now.Before(until)
+}
+
+// Health 健康/冷却/封禁端点列表（日志用）
+func (e *solEndpoints) Health() string {
+	now := e.clock.Now()
+	var okList, coolList, banList []string
+	for _, ep := range e.rpcs {
+		if e.isBanned(ep, now) {
+			banList = append(banList, fmt.Sprintf("%s(until=%s)", ep, e.ban[ep].UTC().Format(time.RFC3339)))
+			continue
+		}
+		if e.isCooling(ep, now) {
+			coolList = append(coolList, fmt.Sprintf("%s(until=%s)", ep, e.cooldown[ep].UTC().Format(time.RFC3339)))
+			continue
+		}
+		okList = append(okList, ep)
+	}
+	return fmt.Sprintf("healthy=%v cooling=%v banned=%v", okList, coolList, banList)
+}
+
+// Choose 端点选择：优先健康端点（从上次选中的开始轮询）；否则仅在冷却即将结束（<=1s）且距上次降级>=6s 的端点上进行一次“降级尝试”。
+// 没有可用端点时返回空串
+func (e *solEndpoints) Choose() (base string, degraded bool) {
+	now := e.clock.Now()
+	for i := 0; i < len(e.rpcs); i++ {
+		idx := (e.idx + i) % len(e.rpcs)
+		cand := e.rpcs[idx]
+		if e.isBanned(cand, now) || e.isCooling(cand, now) {
+			continue
+		}
+		e.idx = idx
+		return cand, false
+	}
+	// 降级：找最早解冻且满足“即将结束冷却 + 降级间隔”的端点
+	type cand struct {
+		ep    string
+		until time.Time
+	}
+	var cds []cand
+	for _, ep := range e.rpcs {
+		if e.isBanned(ep, now) || !e.isCooling(ep, now) {
+			continue
+		}
+		until := e.cooldown[ep]
+		if until.Sub(now) > solDegradeHeadstart {
+			continue
+		}
+		if last, ok := e.lastDegrade[ep]; ok && now.Sub(last) < solDegradeMinGap {
+			continue
+		}
+		cds = append(cds, cand{ep: ep, until: until})
+	}
+	if len(cds) == 0 {
+		return "", false
+	}
+	sort.Slice(cds, func(i, j int) bool { return cds[i].until.Before(cds[j].until) })
+	return cds[0].ep, true
+}
+
+// MarkDegrade 记录一次降级尝试
+func (e *solEndpoints) MarkDegrade(ep string) {
+	e.lastDegrade[ep] = e.clock.Now()
+}
+
+// WaitRate 按每端点最小调用间隔等待
+func (e *solEndpoints) WaitRate(ep string) {
+	if e.minInterval <= 0 {
+		return
+	}
+	if last, ok := e.lastCall[ep]; ok {
+		sleep := last.Add(e.minInterval).Sub(e.clock.Now())
+		if sleep > 0 && sleep < solRateMaxWait {
+			e.clock.Sleep(sleep)
+		}
+	}
+}
+
+// Called 记录调用完成时间（限速基准）
+func (e *solEndpoints) Called(ep string) {
+	e.lastCall[ep] = e.clock.Now()
+}
+
+// Succeeded 成功后清理冷却与退避进度；返回状态是否变化（需要写盘）
+func (e *solEndpoints) Succeeded(ep string) bool {
+	_, cooling := e.cooldown[ep]
+	delete(e.cooldown, ep)
+	delete(e.backoff, ep)
+	return cooling
+}
+
+// Ban 封禁端点 dur
+func (e *solEndpoints) Ban(ep string, dur time.Duration) {
+	e.ban[ep] = e.clock.Now().Add(dur)
+}
+
+// RateLimited 429：按指数退避冷却端点，返回本次冷却时长
+func (e *solEndpoints) RateLimited(ep string) time.Duration {
+	bo := e.backoff[ep]
+	if bo == nil {
+		bo = e.newBackoff()
+		e.backoff[ep] = bo
+	}
+	cur, _ := bo.Next()
+	e.rateLimitHits++
+	e.cooldown[ep] = e.clock.Now().Add(cur)
+	return cur
+}
+
+func (e *solEndpoints) newBackoff() *util.Backoff {
+	return &util.Backoff{Base: e.baseCooldown, Factor: 2, Max: solMaxBackoff}
+}
+
+// Snapshot 持久化用的封禁/冷却状态
+func (e *solEndpoints) Snapshot() map[string]solHealthEntry {
+	out := map[string]solHealthEntry{}
+	for ep, until := range e.ban {
+		s := out[ep]
+		s.BanUntil = until
+		out[ep] = s
+	}
+	for ep, until := range e.cooldown {
+		s := out[ep]
+		s.CooldownUntil = until
+		if bo := e.backoff[ep]; bo != nil {
+			s.Backoffs = bo.Attempt()
+		}
+		out[ep] = s
+	}
+	return out
+}
+
+// Restore 恢复已配置端点未到期的封禁/冷却；冷却的退避进度一并恢复，下次 429 从上次的冷却时长继续翻倍。
+// 返回恢复的记录数
+func (e *solEndpoints) Restore(saved map[string]solHealthEntry) int {
+	now := e.clock.Now()
+	n := 0
+	for _, ep := range e.rpcs {
+		s, ok := saved[ep]
+		if !ok {
+			continue
+		}
+		if now.Before(s.BanUntil) {
+			e.ban[ep] = s.BanUntil
+			n++
+		}
+		if now.Before(s.CooldownUntil) {
+			e.cooldown[ep] = s.CooldownUntil
+			bo := e.newBackoff()
+			for i := 0; i < s.Backoffs; i++ {
+				bo.Next()
+			}
+			e.backoff[ep] = bo
+			n++
+		}
+	}
+	return n
+}
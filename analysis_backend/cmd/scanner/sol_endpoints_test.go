@@ -0,0 +1,112 @@
+package main
+
+import (
+	"analysis/internal/util"
+	"testing"
+	"time"
+)
+
+// TestSolEndpointsCooldownExpiry 429 冷却期间切到其它端点；推进时钟到期后恢复，退避按倍数增长，成功后重置
+func TestSolEndpointsCooldownExpiry(t *testing.T) {
+	clk := util.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	e := newSolEndpoints([]string{"https://a.example/", "https://b.example"}, 0, 8*time.Second, clk)
+
+	if ep, deg := e.Choose(); ep != "https://a.example" || deg {
+		t.Fatalf("期望健康端点 a，实际 %s degraded=%v", ep, deg)
+	}
+	if d := e.RateLimited("https://a.example"); d != 8*time.Second {
+		t.Fatalf("首次冷却期望 8s，实际 %v", d)
+	}
+	if ep, _ := e.Choose(); ep != "https://b.example" {
+		t.Fatalf("a 冷却中应选 b，实际 %s", ep)
+	}
+
+	// 两个端点都在冷却：冷却剩余 >1s 时不降级
+	e.RateLimited("https://b.example")
+	clk.Advance(6 * time.Second)
+	if ep, _ := e.Choose(); ep != "" {
+		t.Fatalf("冷却剩余 2s 不应选端点，实际 %s", ep)
+	}
+	// 剩余 <=1s：对 a 进行一次降级尝试，6s 内不再重复
+	clk.Advance(time.Second)
+	ep, deg := e.Choose()
+	if ep != "https://a.example" || !deg {
+		t.Fatalf("期望降级尝试 a，实际 %s degraded=%v", ep, deg)
+	}
+	e.MarkDegrade(ep)
+	if d := e.RateLimited(ep); d != 16*time.Second {
+		t.Fatalf("第二次冷却期望 16s，实际 %v", d)
+	}
+	clk.Advance(500 * time.Millisecond)
+	if ep, deg := e.Choose(); ep != "https://b.example" || !deg {
+		t.Fatalf("期望降级尝试 b，实际 %s degraded=%v", ep, deg)
+	}
+
+	// b 冷却到期后恢复健康
+	clk.Advance(time.Second)
+	if ep, deg := e.Choose(); ep != "https://b.example" || deg {
+		t.Fatalf("b 冷却到期应为健康端点，实际 %s degraded=%v", ep, deg)
+	}
+	if e.rateLimitHits != 3 {
+		t.Errorf("期望累计 3 次 429，实际 %d", e.rateLimitHits)
+	}
+
+	// a 冷却到期、成功后退避从头开始
+	clk.Advance(16 * time.Second)
+	if !e.Succeeded("https://a.example") {
+		t.Error("清理冷却记录应返回 true")
+	}
+	if d := e.RateLimited("https://a.example"); d != 8*time.Second {
+		t.Fatalf("成功后退避应重置为 8s，实际 %v", d)
+	}
+}
+
+// TestSolEndpointsBanAndRate 封禁期间跳过端点；限速按最小间隔等待（FakeClock.Sleep 推进时间）
+func TestSolEndpointsBanAndRate(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := util.NewFakeClock(start)
+	e := newSolEndpoints([]string{"https://a.example", "https://b.example"}, 100*time.Millisecond, 0, clk)
+
+	e.Ban("https://a.example", 30*time.Minute)
+	if ep, _ := e.Choose(); ep != "https://b.example" {
+		t.Fatalf("a 封禁中应选 b，实际 %s", ep)
+	}
+	e.Ban("https://b.example", time.Minute)
+	if ep, _ := e.Choose(); ep != "" {
+		t.Fatalf("全部封禁时不应选端点（封禁不参与降级），实际 %s", ep)
+	}
+	clk.Advance(time.Minute)
+	if ep, _ := e.Choose(); ep != "https://b.example" {
+		t.Fatalf("b 解封后应可用，实际 %s", ep)
+	}
+
+	e.Called("https://b.example")
+	clk.Advance(30 * time.Millisecond)
+	e.WaitRate("https://b.example")
+	if got := clk.Now().Sub(start); got != time.Minute+100*time.Millisecond {
+		t.Fatalf("限速应等到上次调用后 100ms，实际经过 %v", got)
+	}
+}
+
+// TestSolEndpointsSnapshotRestore 快照恢复后冷却与退避进度保持，只恢复已配置的端点
+func TestSolEndpointsSnapshotRestore(t *testing.T) {
+	clk := util.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	e := newSolEndpoints([]string{"https://a.example", "https://b.example"}, 0, 8*time.Second, clk)
+	e.RateLimited("https://a.example")
+	e.RateLimited("https://a.example")
+	e.Ban("https://b.example", time.Hour)
+	snap := e.Snapshot()
+	snap["https://other.example"] = solHealthEntry{BanUntil: clk.Now().Add(time.Hour)}
+
+	r := newSolEndpoints([]string{"https://a.example", "https://b.example"}, 0, 8*time.Second, clk)
+	if n := r.Restore(snap); n != 2 {
+		t.Fatalf("期望恢复 2 条记录，实际 %d", n)
+	}
+	if ep, _ := r.Choose(); ep != "" {
+		t.Fatalf("恢复后两个端点都不可用，实际选中 %s", ep)
+	}
+	clk.Advance(16 * time.Second)
+	if d := r.RateLimited("https://a.example"); d != 32*time.Second {
+		t.Fatalf("恢复退避进度后下一次冷却期望 32s，实际 %v", d)
+	}
+}
@@ -0,0 +1,127 @@
+// cmd/scanner/sol_health.go
+// Solana 端点健康状态持久化：403 封禁与 429 冷却写入 -sol-health-file，重启后加载，
+// 避免重启后立刻重复请求已知被封禁/冷却中的端点（可能导致 API key 被标记）。
+// 内存中的 map 仍是唯一数据源，这里只做防抖后的镜像；到期的记录加载时丢弃，文件损坏时告警后从空状态开始。
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// solHealthSaveDebounce 两次写盘的最小间隔
+const solHealthSaveDebounce = 5 * time.Second
+
+// solHealthEntry 单个端点的持久化状态
+type solHealthEntry struct {
+	BanUntil      time.Time `json:"ban_until,omitempty"`
+	CooldownUntil time.Time `json:"cooldown_until,omitempty"`
+	Backoffs      int       `json:"cooldown_backoffs,omitempty"` // 连续 429 退避次数，恢复退避进度
+}
+
+// active 封禁或冷却尚未到期
+func (e solHealthEntry) active(now time.Time) bool {
+	return now.Before(e.BanUntil) || now.Before(e.CooldownUntil)
+}
+
+type solHealthFile struct {
+	SavedAt   time.Time                 `json:"saved_at"`
+	Endpoints map[string]solHealthEntry `json:"endpoints"`
+}
+
+// loadSolHealth 读取健康状态并丢弃已到期的记录；文件不存在返回空状态，文件损坏告警后返回空状态
+func loadSolHealth(path string, now time.Time) map[string]solHealthEntry {
+	out := map[string]solHealthEntry{}
+	if path == "" {
+		return out
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[solana] read health file %s: %v (starting fresh)", path, err)
+		}
+		return out
+	}
+	var f solHealthFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		log.Printf("[solana] corrupted health file %s: %v (starting fresh)", path, err)
+		return out
+	}
+	for ep, e := range f.Endpoints {
+		if ep = strings.TrimRight(strings.TrimSpace(ep), "/"); ep != "" && e.active(now) {
+			out[ep] = e
+		}
+	}
+	return out
+}
+
+// saveSolHealth 写入健康状态（先写临时文件再改名，避免中途退出留下半个文件）；只保存未到期的记录
+func saveSolHealth(path string, endpoints map[string]solHealthEntry, now time.Time) error {
+	f := solHealthFile{SavedAt: now.UTC(), Endpoints: map[string]solHealthEntry{}}
+	for ep, e := range endpoints {
+		if e.active(now) {
+			f.Endpoints[ep] = e
+		}
+	}
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("mkdir %s: %w", dir, err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// solHealthStore 防抖写盘：状态变化时 Changed，调用方在合适的时机 Maybe（距上次写盘不足 debounce 时跳过），退出前 Flush。
+// 只在扫描主协程使用，不加锁
+type solHealthStore struct {
+	path     string
+	debounce time.Duration
+	snapshot func() map[string]solHealthEntry
+	dirty    bool
+	lastSave time.Time
+}
+
+func newSolHealthStore(path string, debounce time.Duration, snapshot func() map[string]solHealthEntry) *solHealthStore {
+	return &solHealthStore{path: path, debounce: debounce, snapshot: snapshot}
+}
+
+// Changed 标记内存状态已变化
+func (s *solHealthStore) Changed() {
+	s.dirty = s.path != ""
+}
+
+// Maybe 有变化且距上次写盘超过 debounce 时写盘
+func (s *solHealthStore) Maybe(now time.Time) {
+	if !s.dirty || now.Sub(s.lastSave) < s.debounce {
+		return
+	}
+	s.Flush(now)
+}
+
+// Flush 有变化时立即写盘；失败只记日志，保留变化标记稍后重试
+func (s *solHealthStore) Flush(now time.Time) {
+	if !s.dirty {
+		return
+	}
+	s.lastSave = now // 失败也按 debounce 间隔重试，避免每次调用都刷日志
+	if err := saveSolHealth(s.path, s.snapshot(), now); err != nil {
+		log.Printf("[solana] save health file %s: %v", s.path, err)
+		return
+	}
+	s.dirty = false
+}
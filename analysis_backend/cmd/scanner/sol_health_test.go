@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestSolHealthRoundTrip 封禁/冷却状态写盘后可原样加载，已到期的记录被丢弃
+func TestSolHealthRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "sol_health.json")
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	in := map[string]solHealthEntry{
+		"https://banned.example":  {BanUntil: now.Add(30 * time.Minute)},
+		"https://cooling.example": {CooldownUntil: now.Add(16 * time.Second), Backoffs: 2},
+		"https://expired.example": {BanUntil: now.Add(-time.Minute), CooldownUntil: now.Add(-time.Second)},
+	}
+	if err := saveSolHealth(path, in, now); err != nil {
+		t.Fatal(err)
+	}
+
+	got := loadSolHealth(path, now)
+	if len(got) != 2 {
+		t.Fatalf("期望 2 个未到期端点，实际 %+v", got)
+	}
+	if e := got["https://banned.example"]; !e.BanUntil.Equal(in["https://banned.example"].BanUntil) {
+		t.Errorf("封禁到期时间不符: %+v", e)
+	}
+	if e := got["https://cooling.example"]; !e.CooldownUntil.Equal(in["https://cooling.example"].CooldownUntil) || e.Backoffs != 2 {
+		t.Errorf("冷却状态不符: %+v", e)
+	}
+
+	// 重启时冷却已过期，只剩封禁
+	later := loadSolHealth(path, now.Add(time.Minute))
+	if _, ok := later["https://cooling.example"]; ok || len(later) != 1 {
+		t.Errorf("过期的冷却应被丢弃: %+v", later)
+	}
+}
+
+// TestSolHealthCorruptedFile 文件损坏或不存在时从空状态开始
+func TestSolHealthCorruptedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sol_health.json")
+	if err := os.WriteFile(path, []byte(`{"endpoints": {"https://a": {"ban_until": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadSolHealth(path, time.Now()); len(got) != 0 {
+		t.Errorf("损坏的文件应返回空状态: %+v", got)
+	}
+	if got := loadSolHealth(filepath.Join(dir, "missing.json"), time.Now()); len(got) != 0 {
+		t.Errorf("不存在的文件应返回空状态: %+v", got)
+	}
+}
+
+// TestSolHealthStoreDebounce 变化后按 debounce 间隔写盘，Flush 立即写盘；路径为空时不写
+func TestSolHealthStoreDebounce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sol_health.json")
+	now := time.Now()
+	state := map[string]solHealthEntry{"https://a": {BanUntil: now.Add(time.Hour)}}
+	saves := 0
+	st := newSolHealthStore(path, 5*time.Second, func() map[string]solHealthEntry { saves++; return state })
+
+	st.Maybe(now)
+	if saves != 0 {
+		t.Fatal("没有变化不应写盘")
+	}
+	st.Changed()
+	st.Maybe(now)
+	st.Changed()
+	st.Maybe(now.Add(time.Second))
+	if saves != 1 {
+		t.Fatalf("debounce 内只应写盘 1 次，实际 %d", saves)
+	}
+	st.Maybe(now.Add(6 * time.Second))
+	if saves != 2 {
+		t.Fatalf("超过 debounce 后应写盘，实际 %d", saves)
+	}
+	state["https://b"] = solHealthEntry{CooldownUntil: now.Add(time.Minute)}
+	st.Changed()
+	st.Flush(now.Add(7 * time.Second))
+	if got := loadSolHealth(path, now); len(got) != 2 {
+		t.Errorf("Flush 后文件应包含最新状态: %+v", got)
+	}
+
+	off := newSolHealthStore("", time.Second, func() map[string]solHealthEntry { t.Fatal("路径为空不应写盘"); return nil })
+	off.Changed()
+	off.Flush(now)
+}
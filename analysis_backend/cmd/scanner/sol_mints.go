@@ -0,0 +1,230 @@
+// cmd/scanner/sol_mints.go
+// 未配置 SPL mint 的发现（-sol-unknown-mints）：默认只输出 chains.solana.spl 中配置的代币，其它 mint 的转账直接丢弃；
+// 开启后改为通过 getTokenSupply 取精度、Metaplex 元数据账户取符号，带解析出的符号输出事件，便于发现空投/新上币。
+// 解析结果在进程内缓存；没有元数据或解析失败时 Coin 为截断的 mint 地址。
+// 与已配置代币同名的符号（冒充 USDT 的空投很常见）加 mint 前缀区分，不会混入真实币种的流量。
+
+package main
+
+import (
+	"analysis/internal/util"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"filippo.io/edwards25519"
+)
+
+const (
+	metaplexMetadataProgram = "metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s"
+
+	solMintSymbolMax  = 10               // 解析出的符号最长保留的字符数（Coin 列 16 字符，需留出区分后缀）
+	solMintRetryAfter = 10 * time.Minute // 解析失败（RPC 错误）后多久重试，期间使用截断地址
+	solMintTimeout    = 10 * time.Second
+)
+
+// solUnknownMints 非 nil 时 solTxEvents 对未配置的 mint 解析符号并输出事件（-sol-unknown-mints）
+var solUnknownMints *solMintResolver
+
+// solMintInfo 解析结果
+type solMintInfo struct {
+	Symbol   string // 事件使用的 Coin
+	Decimals int    // 0 表示未知
+}
+
+type solMintEntry struct {
+	info    solMintInfo
+	retryAt time.Time // 非零表示解析失败，到期后重试
+}
+
+// solMintResolver 未配置 mint 的符号/精度解析与缓存；只在扫描主协程使用，不加锁
+type solMintResolver struct {
+	post       solPostFunc
+	configured map[string]bool // 已配置代币的符号（大写）
+	cache      map[string]solMintEntry
+	now        func() time.Time
+}
+
+func newSolMintResolver(post solPostFunc, mintToSymbol map[string]string) *solMintResolver {
+	r := &solMintResolver{post: post, configured: map[string]bool{"SOL": true}, cache: map[string]solMintEntry{}, now: time.Now}
+	for _, sym := range mintToSymbol {
+		r.configured[strings.ToUpper(sym)] = true
+	}
+	return r
+}
+
+// Resolve 返回 mint 的符号与精度（mint 需为原始大小写的 base58）
+func (r *solMintResolver) Resolve(mint string) solMintInfo {
+	if e, ok := r.cache[mint]; ok && (e.retryAt.IsZero() || r.now().Before(e.retryAt)) {
+		return e.info
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), solMintTimeout)
+	defer cancel()
+
+	info := solMintInfo{Symbol: solMintLabel(mint)}
+	dec, err := solTokenDecimals(ctx, r.post, mint)
+	if err != nil {
+		log.Printf("[solana] resolve mint %s: %v (labelled %s, retry in %s)", mint, err, info.Symbol, solMintRetryAfter)
+		r.cache[mint] = solMintEntry{info: info, retryAt: r.now().Add(solMintRetryAfter)}
+		return info
+	}
+	info.Decimals = dec
+	sym, err := solMetaplexSymbol(ctx, r.post, mint)
+	if err != nil {
+		log.Printf("[solana] metadata for mint %s: %v (labelled %s, retry in %s)", mint, err, info.Symbol, solMintRetryAfter)
+		r.cache[mint] = solMintEntry{info: info, retryAt: r.now().Add(solMintRetryAfter)}
+		return info
+	}
+	if sym != "" {
+		info.Symbol = sym
+		if r.configured[sym] {
+			info.Symbol = sym + "_" + mint[:4]
+		}
+	}
+	r.cache[mint] = solMintEntry{info: info}
+	log.Printf("[solana] discovered mint %s symbol=%s decimals=%d", mint, info.Symbol, info.Decimals)
+	return info
+}
+
+// solMintLabel 没有符号时用截断的 mint 地址作为 Coin
+func solMintLabel(mint string) string {
+	if len(mint) <= 10 {
+		return mint
+	}
+	return mint[:4] + ".." + mint[len(mint)-4:]
+}
+
+// solTokenDecimals getTokenSupply 取 mint 精度
+func solTokenDecimals(ctx context.Context, post solPostFunc, mint string) (int, error) {
+	var out rpcResp
+	if err := post(ctx, "getTokenSupply", []any{mint}, &out); err != nil {
+		return 0, err
+	}
+	var res struct {
+		Value struct {
+			Decimals int `json:"decimals"`
+		} `json:"value"`
+	}
+	if err := decodeResult("getTokenSupply "+mint, &out, &res); err != nil {
+		return 0, err
+	}
+	return res.Value.Decimals, nil
+}
+
+// solMetaplexSymbol 读取 Metaplex 元数据账户中的符号；没有元数据账户返回空串
+func solMetaplexSymbol(ctx context.Context, post solPostFunc, mint string) (string, error) {
+	mintKey, ok := util.Base58Decode(mint)
+	if !ok || len(mintKey) != 32 {
+		return "", fmt.Errorf("invalid mint %q", mint)
+	}
+	program, _ := util.Base58Decode(metaplexMetadataProgram)
+	pda, ok := solFindProgramAddress([][]byte{[]byte("metadata"), program, mintKey}, program)
+	if !ok {
+		return "", fmt.Errorf("no metadata address for mint %s", mint)
+	}
+	var out rpcResp
+	if err := post(ctx, "getAccountInfo", []any{util.Base58Encode(pda), map[string]any{"encoding": "base64", "commitment": "confirmed"}}, &out); err != nil {
+		return "", err
+	}
+	var res struct {
+		Value *struct {
+			Data  []string `json:"data"`
+			Owner string   `json:"owner"`
+		} `json:"value"`
+	}
+	if err := decodeResult("getAccountInfo metadata "+mint, &out, &res); err != nil {
+		return "", err
+	}
+	if res.Value == nil || res.Value.Owner != metaplexMetadataProgram || len(res.Value.Data) == 0 {
+		return "", nil
+	}
+	data, err := base64.StdEncoding.DecodeString(res.Value.Data[0])
+	if err != nil {
+		return "", fmt.Errorf("metadata %s: %w", mint, err)
+	}
+	sym, ok := parseMetaplexSymbol(data, mintKey)
+	if !ok {
+		return "", fmt.Errorf("metadata %s: unexpected layout", mint)
+	}
+	return sym, nil
+}
+
+// parseMetaplexSymbol 解析元数据账户（borsh）：key(1) update_authority(32) mint(32) name(u32+bytes) symbol(u32+bytes)
+func parseMetaplexSymbol(data, mint []byte) (string, bool) {
+	if len(data) < 65 || !bytes.Equal(data[33:65], mint) {
+		return "", false
+	}
+	off := 65
+	readString := func() (string, bool) {
+		if off+4 > len(data) {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(data[off:]))
+		off += 4
+		if n < 0 || off+n > len(data) {
+			return "", false
+		}
+		s := string(data[off : off+n])
+		off += n
+		return s, true
+	}
+	if _, ok := readString(); !ok { // name
+		return "", false
+	}
+	sym, ok := readString()
+	if !ok {
+		return "", false
+	}
+	return sanitizeMintSymbol(sym), true
+}
+
+// sanitizeMintSymbol 去掉填充的 0 字节与不可打印字符，转大写并截断
+func sanitizeMintSymbol(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(s) {
+		if c > ' ' && c < 0x7f && b.Len() < solMintSymbolMax {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// solFindProgramAddress Solana PDA 推导：bump 从 255 递减，取第一个不在 ed25519 曲线上的 sha256(seeds|bump|program|"ProgramDerivedAddress")
+func solFindProgramAddress(seeds [][]byte, program []byte) ([]byte, bool) {
+	for bump := 255; bump >= 0; bump-- {
+		h := sha256.New()
+		for _, s := range seeds {
+			h.Write(s)
+		}
+		h.Write([]byte{byte(bump)})
+		h.Write(program)
+		h.Write([]byte("ProgramDerivedAddress"))
+		sum := h.Sum(nil)
+		if _, err := new(edwards25519.Point).SetBytes(sum); err != nil {
+			return sum, true
+		}
+	}
+	return nil, false
+}
+
+// solOriginalMints 小写 mint -> 原始大小写（交易里的 mint 比较时统一小写，RPC 解析需要原始地址）
+func solOriginalMints(meta map[string]any) map[string]string {
+	out := map[string]string{}
+	for _, field := range []string{"preTokenBalances", "postTokenBalances"} {
+		list, _ := meta[field].([]any)
+		for _, it := range list {
+			if m, ok := it.(map[string]any); ok {
+				if mint := str(m["mint"]); mint != "" {
+					out[strings.ToLower(mint)] = mint
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"analysis/internal/util"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/edwards25519"
+)
+
+// 热钱包转出 2.5 个未配置代币（精度 9，transfer 指令不带精度）
+const solUnknownMintTx = `{
+  "transaction": {
+    "signatures": ["sigAirdrop"],
+    "message": {
+      "accountKeys": [{"pubkey": "` + testHotWallet + `"}, {"pubkey": "` + testUser + `"}, {"pubkey": "` + testHotUSDTAcc + `"}, {"pubkey": "` + testUserUSDT + `"}],
+      "instructions": [
+        {"program": "spl-token", "parsed": {"type": "transfer", "info": {"source": "` + testHotUSDTAcc + `", "destination": "` + testUserUSDT + `", "amount": "2500000000", "authority": "` + testHotWallet + `"}}}
+      ]
+    }
+  },
+  "meta": {
+    "fee": 5000,
+    "preBalances": [5000000000, 0, 2039280, 2039280],
+    "postBalances": [4999995000, 0, 2039280, 2039280],
+    "preTokenBalances": [
+      {"accountIndex": 2, "mint": "` + testUSDTMint + `", "owner": "` + testHotWallet + `", "uiTokenAmount": {"amount": "10000000000", "decimals": 9}},
+      {"accountIndex": 3, "mint": "` + testUSDTMint + `", "owner": "` + testUser + `", "uiTokenAmount": {"amount": "0", "decimals": 9}}
+    ],
+    "postTokenBalances": [
+      {"accountIndex": 2, "mint": "` + testUSDTMint + `", "owner": "` + testHotWallet + `", "uiTokenAmount": {"amount": "7500000000", "decimals": 9}},
+      {"accountIndex": 3, "mint": "` + testUSDTMint + `", "owner": "` + testUser + `", "uiTokenAmount": {"amount": "2500000000", "decimals": 9}}
+    ]
+  }
+}`
+
+// metaplexAccount 构造元数据账户数据（名称/符号按 Metaplex 的固定长度补 0）
+func metaplexAccount(mint []byte, name, symbol string) string {
+	pad := func(s string, n int) []byte {
+		b := make([]byte, 4+n)
+		binary.LittleEndian.PutUint32(b, uint32(n))
+		copy(b[4:], s)
+		return b
+	}
+	data := append([]byte{4}, make([]byte, 32)...)
+	data = append(data, mint...)
+	data = append(data, pad(name, 32)...)
+	data = append(data, pad(symbol, 10)...)
+	data = append(data, pad("https://example.com/meta.json", 200)...)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// fakeMintRPC 模拟 getTokenSupply/getAccountInfo；symbol 为空表示没有元数据账户
+func fakeMintRPC(t *testing.T, mint, symbol string, decimals int, calls map[string]int) solPostFunc {
+	mintKey, _ := util.Base58Decode(mint)
+	program, _ := util.Base58Decode(metaplexMetadataProgram)
+	pda, ok := solFindProgramAddress([][]byte{[]byte("metadata"), program, mintKey}, program)
+	if !ok {
+		t.Fatal("PDA 推导失败")
+	}
+	return func(ctx context.Context, method string, params []any, out *rpcResp) error {
+		calls[method]++
+		var result any
+		switch method {
+		case "getTokenSupply":
+			if params[0] != mint {
+				return fmt.Errorf("invalid param: not a token mint %v", params[0])
+			}
+			result = map[string]any{"value": map[string]any{"amount": "1", "decimals": decimals}}
+		case "getAccountInfo":
+			result = map[string]any{"value": nil}
+			if symbol != "" && params[0] == util.Base58Encode(pda) {
+				result = map[string]any{"value": map[string]any{
+					"owner": metaplexMetadataProgram,
+					"data":  []string{metaplexAccount(mintKey, "Airdrop Token", symbol), "base64"},
+				}}
+			}
+		default:
+			return fmt.Errorf("unexpected method %s", method)
+		}
+		out.Result, _ = json.Marshal(result)
+		return nil
+	}
+}
+
+// TestSolUnknownMintLabelled 未配置的 mint 有元数据时按解析出的符号输出事件，指令未带精度时按链上精度换算；解析结果缓存
+func TestSolUnknownMintLabelled(t *testing.T) {
+	util.SetAllowed("USDC") // 只看代币事件；发现的代币不受币种范围限制
+	defer func() { solUnknownMints = nil }()
+	watched := func(a string) bool { return a == testHotWallet || a == testHotUSDTAcc }
+	ts := time.Unix(1725148800, 0).UTC()
+	tx := decodeSolTx(t, solUnknownMintTx)
+
+	solUnknownMints = nil
+	if evs := solTxEvents("binance", "sigAirdrop", tx, ts, watched, map[string]string{}); len(evs) != 0 {
+		t.Fatalf("未开启 -sol-unknown-mints 时未配置的 mint 应丢弃: %+v", evs)
+	}
+
+	calls := map[string]int{}
+	solUnknownMints = newSolMintResolver(fakeMintRPC(t, testUSDTMint, "drop", 9, calls), map[string]string{})
+	for i := 0; i < 2; i++ {
+		evs := solTxEvents("binance", "sigAirdrop", tx, ts, watched, map[string]string{})
+		if len(evs) != 1 {
+			t.Fatalf("指令与余额差应去重为 1 个事件，实际 %+v", evs)
+		}
+		if e := evs[0]; e.Coin != "DROP" || e.Amount != "2.50000000" || e.Direction != "out" || e.Address != testHotUSDTAcc {
+			t.Errorf("事件不符: %+v", e)
+		}
+	}
+	if calls["getTokenSupply"] != 1 || calls["getAccountInfo"] != 1 {
+		t.Errorf("解析结果应缓存，实际调用 %v", calls)
+	}
+}
+
+// TestSolMintResolverFallbacks 没有元数据用截断的 mint；与已配置代币同名加 mint 前缀；RPC 失败用截断 mint 并在到期后重试
+func TestSolMintResolverFallbacks(t *testing.T) {
+	calls := map[string]int{}
+	r := newSolMintResolver(fakeMintRPC(t, testUSDTMint, "", 6, calls), nil)
+	if got := r.Resolve(testUSDTMint); got.Symbol != "Es9v..wNYB" || got.Decimals != 6 {
+		t.Errorf("没有元数据应使用截断的 mint: %+v", got)
+	}
+
+	r = newSolMintResolver(fakeMintRPC(t, testUSDTMint, "usdt\x00\x00", 6, map[string]int{}), map[string]string{"other": "USDT"})
+	if got := r.Resolve(testUSDTMint); got.Symbol != "USDT_Es9v" {
+		t.Errorf("冒充已配置代币的符号应加 mint 前缀: %+v", got)
+	}
+	if len("USDT_Es9v") > 16 || len(solMintLabel(testUSDTMint)) > 16 {
+		t.Error("Coin 不应超过 16 字符")
+	}
+
+	calls = map[string]int{}
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	r = newSolMintResolver(fakeMintRPC(t, "SomeOtherMint1111111111111111111111111111111", "", 6, calls), nil)
+	r.now = func() time.Time { return now }
+	if got := r.Resolve(testUSDTMint); got.Symbol != "Es9v..wNYB" || got.Decimals != 0 {
+		t.Errorf("解析失败应使用截断的 mint: %+v", got)
+	}
+	r.Resolve(testUSDTMint)
+	if calls["getTokenSupply"] != 1 {
+		t.Errorf("失败在重试间隔内应使用缓存，实际 %d 次", calls["getTokenSupply"])
+	}
+	now = now.Add(solMintRetryAfter)
+	r.Resolve(testUSDTMint)
+	if calls["getTokenSupply"] != 2 {
+		t.Errorf("到期后应重试，实际 %d 次", calls["getTokenSupply"])
+	}
+}
+
+// TestSolFindProgramAddress PDA 不在 ed25519 曲线上（普通公钥在曲线上），结果确定；解析元数据符号
+func TestSolFindProgramAddress(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := new(edwards25519.Point).SetBytes(pub); err != nil {
+		t.Fatalf("普通公钥应在曲线上: %v", err)
+	}
+	program, _ := util.Base58Decode(metaplexMetadataProgram)
+	a, ok := solFindProgramAddress([][]byte{[]byte("metadata"), program, pub}, program)
+	b, _ := solFindProgramAddress([][]byte{[]byte("metadata"), program, pub}, program)
+	if !ok || len(a) != 32 || util.Base58Encode(a) != util.Base58Encode(b) {
+		t.Fatalf("PDA 推导不符: %x %x", a, b)
+	}
+	if _, err := new(edwards25519.Point).SetBytes(a); err == nil {
+		t.Error("PDA 不应在曲线上")
+	}
+
+	raw, _ := base64.StdEncoding.DecodeString(metaplexAccount(pub, "Name", " bonk\x00"))
+	if sym, ok := parseMetaplexSymbol(raw, pub); !ok || sym != "BONK" {
+		t.Errorf("符号解析不符: %q %v", sym, ok)
+	}
+	if _, ok := parseMetaplexSymbol(raw, program); ok {
+		t.Error("mint 不匹配的元数据应拒绝")
+	}
+	if got := util.Base58Encode(mustBase58(t, testUSDTMint)); got != testUSDTMint || !strings.HasPrefix(solMintLabel(got), "Es9v") {
+		t.Errorf("base58 往返不符: %s", got)
+	}
+}
+
+func mustBase58(t *testing.T, s string) []byte {
+	t.Helper()
+	b, ok := util.Base58Decode(s)
+	if !ok {
+		t.Fatalf("base58 解码失败: %s", s)
+	}
+	return b
+}
@@ -0,0 +1,250 @@
+// cmd/scanner/sol_sigs.go
+// Solana 按地址扫描（-sol-mode=signatures）：每个监控地址调用 getSignaturesForAddress 取上次处理之后的新签名，
+// 再对每个新签名 getTransaction，复用 solTxEvents 解析转账。地址少、交易稀疏的实体比逐 slot getBlock 省得多。
+// 游标按 (实体, 地址) 记录最后处理的签名，镜像到 -sol-sig-cursor-file；同时照常上报 slot 游标（最大 slot+1），
+// 切回 slot 模式或丢失签名游标时可以从 slot 游标接上（首次运行按 slot 游标截断历史）。
+
+package main
+
+import (
+	"analysis/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	solModeSlots      = "slots"
+	solModeSignatures = "signatures"
+
+	solSigPageMax        = 1000 // getSignaturesForAddress 单页上限
+	solSigBootstrapPages = 10   // 没有签名游标时最多向前翻的页数
+	solSigMaxPerWindow   = 500  // 单个地址每轮最多处理的签名数，积压时分多轮追上
+)
+
+// solSignature getSignaturesForAddress 的单条结果
+type solSignature struct {
+	Signature string `json:"signature"`
+	Slot      uint64 `json:"slot"`
+	BlockTime *int64 `json:"blockTime"`
+}
+
+// solAddressSignatures 按从新到旧分页（before）取 until 之后的签名，返回从旧到新的列表。
+// until 为空（首次扫描）时只取 slot >= minSlot 的签名，且最多翻 solSigBootstrapPages 页；地址没有历史时返回空列表
+func solAddressSignatures(ctx context.Context, post solPostFunc, address, until string, minSlot uint64, limit int) ([]solSignature, error) {
+	if limit <= 0 || limit > solSigPageMax {
+		limit = solSigPageMax
+	}
+	var all []solSignature
+	before := ""
+	for page := 0; ; page++ {
+		opts := map[string]any{"limit": limit, "commitment": "confirmed"}
+		if before != "" {
+			opts["before"] = before
+		}
+		if until != "" {
+			opts["until"] = until
+		}
+		var out rpcResp
+		if err := post(ctx, "getSignaturesForAddress", []any{address, opts}, &out); err != nil {
+			return nil, err
+		}
+		var sigs []solSignature
+		if err := decodeResult("getSignaturesForAddress "+address, &out, &sigs); err != nil {
+			if errors.Is(err, ErrEmptyResult) {
+				break // 没有历史
+			}
+			return nil, err
+		}
+		reachedMin := false
+		for _, s := range sigs {
+			if until == "" && s.Slot < minSlot {
+				reachedMin = true
+				break
+			}
+			all = append(all, s)
+		}
+		if len(sigs) < limit || reachedMin {
+			break
+		}
+		if until == "" && page+1 >= solSigBootstrapPages {
+			log.Printf("[solana] address %s: history truncated to %d signatures on first scan", address, len(all))
+			break
+		}
+		before = sigs[len(sigs)-1].Signature
+	}
+	for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+		all[i], all[j] = all[j], all[i]
+	}
+	return all, nil
+}
+
+// solGetTransaction getTransaction（jsonParsed，与 slot 模式的 getBlock 同样的编码与 commitment）
+func solGetTransaction(ctx context.Context, post solPostFunc, signature string) (map[string]any, error) {
+	opts := map[string]any{
+		"encoding":                       "jsonParsed",
+		"maxSupportedTransactionVersion": 0,
+		"commitment":                     "confirmed",
+	}
+	var out rpcResp
+	if err := post(ctx, "getTransaction", []any{signature, opts}, &out); err != nil {
+		return nil, err
+	}
+	var tx map[string]any
+	if err := decodeResult("getTransaction "+signature, &out, &tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// solSigWindow 一个实体一轮签名扫描的结果
+type solSigWindow struct {
+	Events     []models.Event
+	Signatures int               // 本轮处理的签名数（去重后）
+	Next       map[string]string // 地址 -> 本轮处理到的最新签名；提交成功后写入游标
+	MaxSlot    uint64
+}
+
+// scanSolSignatures 取实体各地址的新签名并解析交易。同一交易涉及多个监控地址时只解析一次；
+// slot 超过 maxSlot（确认深度允许的最高 slot）的签名留到下一轮，签名游标不越过它们。
+// 任一请求失败返回错误，本轮不提交，下轮从原游标重试。LogIndex 为事件在交易内的序号（与窗口划分无关）
+func scanSolSignatures(ctx context.Context, post solPostFunc, entity string, addrs []string, cursors *solSigCursors,
+	minSlot, maxSlot uint64, limit int, watched func(string) bool, mintToSymbol map[string]string) (solSigWindow, error) {
+	win := solSigWindow{Next: map[string]string{}}
+	bySig := map[string]solSignature{}
+	for _, a := range addrs {
+		sigs, err := solAddressSignatures(ctx, post, a, cursors.Get(entity, a), minSlot, limit)
+		if err != nil {
+			return win, fmt.Errorf("signatures %s: %w", a, err)
+		}
+		for i, sig := range sigs {
+			if sig.Slot > maxSlot {
+				sigs = sigs[:i]
+				break
+			}
+		}
+		if len(sigs) > solSigMaxPerWindow {
+			sigs = sigs[:solSigMaxPerWindow]
+		}
+		for _, s := range sigs {
+			bySig[s.Signature] = s
+		}
+		if len(sigs) > 0 {
+			win.Next[a] = sigs[len(sigs)-1].Signature
+		}
+	}
+
+	// 按 slot 从旧到新解析，同 slot 内按签名排序保证结果稳定
+	ordered := make([]solSignature, 0, len(bySig))
+	for _, s := range bySig {
+		ordered = append(ordered, s)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].Slot != ordered[j].Slot {
+			return ordered[i].Slot < ordered[j].Slot
+		}
+		return ordered[i].Signature < ordered[j].Signature
+	})
+	for _, s := range ordered {
+		tx, err := solGetTransaction(ctx, post, s.Signature)
+		if err != nil {
+			return win, err
+		}
+		for i, e := range solTxEvents(entity, s.Signature, tx, solBlockTime(tx), watched, mintToSymbol) {
+			e.LogIndex = i
+			win.Events = append(win.Events, e)
+		}
+		if s.Slot > win.MaxSlot {
+			win.MaxSlot = s.Slot
+		}
+	}
+	win.Signatures = len(ordered)
+	return win, nil
+}
+
+// solSigCursors (实体, 地址) -> 最后处理的签名；内存为准，提交后整体写入文件（path 为空不落盘）
+type solSigCursors struct {
+	path string
+	last map[string]map[string]string // entity -> address -> signature
+}
+
+// loadSolSigCursors 读取签名游标；文件不存在或损坏时从空游标开始（首次扫描按 slot 游标截断）
+func loadSolSigCursors(path string) *solSigCursors {
+	c := &solSigCursors{path: path, last: map[string]map[string]string{}}
+	if path == "" {
+		return c
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("[solana] read signature cursors %s: %v (starting fresh)", path, err)
+		}
+		return c
+	}
+	if err := json.Unmarshal(b, &c.last); err != nil {
+		log.Printf("[solana] corrupted signature cursors %s: %v (starting fresh)", path, err)
+		c.last = map[string]map[string]string{}
+	}
+	return c
+}
+
+// Get 地址最后处理的签名，没有返回空串
+func (c *solSigCursors) Get(entity, address string) string {
+	return c.last[entity][address]
+}
+
+// Commit 写入本轮推进后的签名并落盘；落盘失败只记日志（重启后按 slot 游标重新截断）
+func (c *solSigCursors) Commit(entity string, next map[string]string) {
+	if len(next) == 0 {
+		return
+	}
+	if c.last[entity] == nil {
+		c.last[entity] = map[string]string{}
+	}
+	for a, sig := range next {
+		c.last[entity][a] = sig
+	}
+	if c.path == "" {
+		return
+	}
+	if err := c.save(); err != nil {
+		log.Printf("[solana] save signature cursors %s: %v", c.path, err)
+	}
+}
+
+func (c *solSigCursors) save() error {
+	b, err := json.MarshalIndent(c.last, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// parseSolMode 校验 -sol-mode
+func parseSolMode(s string) (string, error) {
+	switch s {
+	case "", solModeSlots:
+		return solModeSlots, nil
+	case solModeSignatures:
+		return solModeSignatures, nil
+	}
+	return "", fmt.Errorf("unknown -sol-mode %q (want %s|%s)", s, solModeSignatures, solModeSlots)
+}
+
+// solSigWindowSummary 日志用：签名数与耗时
+func solSigWindowSummary(win solSigWindow, elapsed time.Duration) string {
+	return fmt.Sprintf("signatures=%d addrs-advanced=%d max-slot=%d duration=%s", win.Signatures, len(win.Next), win.MaxSlot, elapsed)
+}
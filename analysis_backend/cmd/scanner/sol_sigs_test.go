@@ -0,0 +1,251 @@
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// 用户向热钱包转入 2 SOL（手续费由用户支付）
+const solInboundTx = `{
+  "transaction": {
+    "signatures": ["sigB"],
+    "message": {
+      "accountKeys": [{"pubkey": "` + testUser + `"}, {"pubkey": "` + testHotWallet + `"}],
+      "instructions": [
+        {"program": "system", "parsed": {"type": "transfer", "info": {"source": "` + testUser + `", "destination": "` + testHotWallet + `", "lamports": 2000000000}}}
+      ]
+    }
+  },
+  "meta": {"fee": 5000, "preBalances": [5000000000, 3999995000], "postBalances": [2999995000, 5999995000]}
+}`
+
+// 与监控地址无关的交易
+const solUnrelatedTx = `{
+  "transaction": {"signatures": ["sigX"], "message": {"accountKeys": [{"pubkey": "` + testUser + `"}], "instructions": []}},
+  "meta": {"fee": 5000, "preBalances": [100], "postBalances": [95]}
+}`
+
+// solFixture 两个 slot 的区块，以及按地址索引的签名（从新到旧，与 getSignaturesForAddress 顺序一致）
+type solFixture struct {
+	blocks map[uint64]map[string]any
+	txs    map[string]map[string]any
+	sigs   map[string][]solSignature
+	calls  map[string]int
+}
+
+func newSolFixture(t *testing.T) *solFixture {
+	t.Helper()
+	withSig := func(raw, sig string) map[string]any {
+		tx := decodeSolTx(t, raw)
+		tx["transaction"].(map[string]any)["signatures"] = []any{sig}
+		return tx
+	}
+	a, b, x := withSig(solDoubleCoveredTx, "sigA"), withSig(solInboundTx, "sigB"), withSig(solUnrelatedTx, "sigX")
+	f := &solFixture{
+		blocks: map[uint64]map[string]any{
+			100: {"blockTime": float64(1725148800), "transactions": []any{x, a}},
+			101: {"blockTime": float64(1725148801), "transactions": []any{b}},
+		},
+		txs: map[string]map[string]any{},
+		sigs: map[string][]solSignature{
+			testHotWallet:  {{Signature: "sigB", Slot: 101}, {Signature: "sigA", Slot: 100}},
+			testHotUSDTAcc: {{Signature: "sigA", Slot: 100}},
+		},
+		calls: map[string]int{},
+	}
+	for slot, blk := range f.blocks {
+		for _, it := range blk["transactions"].([]any) {
+			tx := it.(map[string]any)
+			f.txs[solTxSignature(tx)] = map[string]any{
+				"slot": float64(slot), "blockTime": blk["blockTime"],
+				"transaction": tx["transaction"], "meta": tx["meta"],
+			}
+		}
+	}
+	return f
+}
+
+// post 模拟 getSignaturesForAddress（limit/before/until）与 getTransaction
+func (f *solFixture) post(ctx context.Context, method string, params []any, out *rpcResp) error {
+	f.calls[method]++
+	var result any
+	switch method {
+	case "getSignaturesForAddress":
+		opts := params[1].(map[string]any)
+		limit := opts["limit"].(int)
+		before, _ := opts["before"].(string)
+		until, _ := opts["until"].(string)
+		page := []solSignature{}
+		started := before == ""
+		for _, s := range f.sigs[params[0].(string)] {
+			if !started {
+				started = s.Signature == before
+				continue
+			}
+			if s.Signature == until || len(page) == limit {
+				break
+			}
+			page = append(page, s)
+		}
+		result = page
+	case "getTransaction":
+		if tx, ok := f.txs[params[0].(string)]; ok {
+			result = tx
+		}
+	default:
+		return fmt.Errorf("unexpected method %s", method)
+	}
+	out.Result, _ = json.Marshal(result)
+	return nil
+}
+
+// eventKeys 事件比较键（LogIndex 两种模式分配方式不同，不参与比较）
+func eventKeys(events []models.Event) []string {
+	var keys []string
+	for _, e := range events {
+		keys = append(keys, strings.Join([]string{e.TxID, e.Coin, e.Direction, e.Address, e.Amount, e.From, e.To, e.TS.String()}, "|"))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TestSolSignaturesModeMatchesSlots 同一份数据，签名模式与逐 slot 模式产生相同的事件，且只请求涉及监控地址的交易
+func TestSolSignaturesModeMatchesSlots(t *testing.T) {
+	util.SetAllowed("SOL,USDT")
+	f := newSolFixture(t)
+	addrs := []string{testHotWallet, testHotUSDTAcc}
+	set := toSetExact(addrs)
+	watched := func(a string) bool { return set[a] }
+	mints := map[string]string{strings.ToLower(testUSDTMint): "USDT"}
+
+	var slotEvents []models.Event
+	logIndex := 0
+	for _, slot := range []uint64{100, 101} {
+		evs, _ := solBlockEvents("binance", f.blocks[slot], watched, mints, &logIndex)
+		slotEvents = append(slotEvents, evs...)
+	}
+
+	cursors := loadSolSigCursors("")
+	win, err := scanSolSignatures(context.Background(), f.post, "binance", addrs, cursors, 100, 200, 100, watched, mints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slotEvents) != 3 {
+		t.Fatalf("fixture 期望 3 个事件（SOL 出、USDT 出、SOL 入），实际 %+v", slotEvents)
+	}
+	got, want := eventKeys(win.Events), eventKeys(slotEvents)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("签名模式事件与 slot 模式不一致:\n got=%v\nwant=%v", got, want)
+	}
+	if f.calls["getTransaction"] != 2 || win.Signatures != 2 {
+		t.Errorf("两个地址共享的交易只应请求一次: getTransaction=%d signatures=%d", f.calls["getTransaction"], win.Signatures)
+	}
+	if win.MaxSlot != 101 || win.Next[testHotWallet] != "sigB" || win.Next[testHotUSDTAcc] != "sigA" {
+		t.Errorf("游标推进不符: %+v", win)
+	}
+
+	// 提交后下一轮没有新签名
+	cursors.Commit("binance", win.Next)
+	win, err = scanSolSignatures(context.Background(), f.post, "binance", addrs, cursors, 102, 200, 100, watched, mints)
+	if err != nil || len(win.Next) != 0 || len(win.Events) != 0 {
+		t.Errorf("无新签名时不应推进: %+v %v", win, err)
+	}
+}
+
+// TestSolSignaturesRespectConfirmedTip 超过确认深度的签名留到下一轮：游标停在其之前，链头推进后再处理
+func TestSolSignaturesRespectConfirmedTip(t *testing.T) {
+	util.SetAllowed("SOL,USDT")
+	f := newSolFixture(t)
+	addrs := []string{testHotWallet, testHotUSDTAcc}
+	set := toSetExact(addrs)
+	watched := func(a string) bool { return set[a] }
+	mints := map[string]string{strings.ToLower(testUSDTMint): "USDT"}
+
+	cursors := loadSolSigCursors("")
+	win, err := scanSolSignatures(context.Background(), f.post, "binance", addrs, cursors, 100, 100, 100, watched, mints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if win.MaxSlot != 100 || win.Signatures != 1 || win.Next[testHotWallet] != "sigA" || f.calls["getTransaction"] != 1 {
+		t.Fatalf("slot 101 未确认，只应处理 sigA: %+v getTransaction=%d", win, f.calls["getTransaction"])
+	}
+	for _, e := range win.Events {
+		if e.TxID == "sigB" {
+			t.Errorf("未确认的 sigB 不应产生事件: %+v", e)
+		}
+	}
+
+	cursors.Commit("binance", win.Next)
+	win, err = scanSolSignatures(context.Background(), f.post, "binance", addrs, cursors, 100, 101, 100, watched, mints)
+	if err != nil || win.MaxSlot != 101 || win.Signatures != 1 || win.Next[testHotWallet] != "sigB" {
+		t.Errorf("链头推进后应处理 sigB: %+v %v", win, err)
+	}
+}
+
+// TestSolAddressSignaturesPagination 按 before 翻页直到 until，结果从旧到新；首次扫描按 minSlot 截断；无历史返回空
+func TestSolAddressSignaturesPagination(t *testing.T) {
+	f := &solFixture{sigs: map[string][]solSignature{}, calls: map[string]int{}}
+	for i := 10; i >= 1; i-- {
+		f.sigs["addr"] = append(f.sigs["addr"], solSignature{Signature: fmt.Sprintf("s%d", i), Slot: uint64(100 + i)})
+	}
+	ctx := context.Background()
+
+	sigs, err := solAddressSignatures(ctx, f.post, "addr", "s3", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range sigs {
+		names = append(names, s.Signature)
+	}
+	if strings.Join(names, ",") != "s4,s5,s6,s7,s8,s9,s10" {
+		t.Errorf("until=s3 期望 s4..s10（从旧到新），实际 %v", names)
+	}
+	if f.calls["getSignaturesForAddress"] != 4 {
+		t.Errorf("7 条签名按每页 2 条应请求 4 次，实际 %d", f.calls["getSignaturesForAddress"])
+	}
+
+	sigs, _ = solAddressSignatures(ctx, f.post, "addr", "", 108, 2)
+	if len(sigs) != 3 || sigs[0].Signature != "s8" {
+		t.Errorf("首次扫描应只取 slot>=108 的签名: %+v", sigs)
+	}
+
+	if sigs, err := solAddressSignatures(ctx, f.post, "empty", "", 0, 2); err != nil || len(sigs) != 0 {
+		t.Errorf("无历史的地址应返回空: %+v %v", sigs, err)
+	}
+	nullPost := func(ctx context.Context, method string, params []any, out *rpcResp) error {
+		out.Result = []byte("null")
+		return nil
+	}
+	if sigs, err := solAddressSignatures(ctx, nullPost, "addr", "", 0, 2); err != nil || len(sigs) != 0 {
+		t.Errorf("null 结果视为无历史: %+v %v", sigs, err)
+	}
+}
+
+// TestSolSigCursorsPersist 签名游标落盘后可重新加载；损坏的文件从空游标开始
+func TestSolSigCursorsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sol_sig_cursor.json")
+	c := loadSolSigCursors(path)
+	c.Commit("binance", map[string]string{"addr1": "sigA"})
+	c.Commit("binance", map[string]string{"addr2": "sigB"})
+	if got := loadSolSigCursors(path); got.Get("binance", "addr1") != "sigA" || got.Get("binance", "addr2") != "sigB" {
+		t.Errorf("重新加载后游标不符: %+v", got.last)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadSolSigCursors(path); got.Get("binance", "addr1") != "" {
+		t.Errorf("损坏的文件应从空游标开始: %+v", got.last)
+	}
+	if _, err := parseSolMode("blocks"); err == nil {
+		t.Error("未知模式应报错")
+	}
+}
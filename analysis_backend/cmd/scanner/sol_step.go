@@ -0,0 +1,70 @@
+// cmd/scanner/sol_step.go
+// Solana 扫描窗口自适应：每个实体独立维护步长（一次扫描的 slot 数）。
+// 窗口内出现限流/失败时步长减半；健康时按单 slot 耗时（随区块交易密度变化）估算目标耗时内能扫的 slot 数，逐步放大。
+
+package main
+
+import (
+	"time"
+)
+
+// solWindowStats 一个扫描窗口的统计
+type solWindowStats struct {
+	Slots       int           // 扫描的 slot 数
+	Txs         int           // 处理的交易数（区块密度）
+	RateLimited int           // 窗口内 429 次数
+	Failed      int           // getBlock 失败的 slot 数
+	Elapsed     time.Duration // 窗口耗时
+}
+
+// solStepController 按实体自适应 Solana 扫描步长
+type solStepController struct {
+	initial, min, max int
+	target            time.Duration // 单个窗口的目标耗时
+
+	steps map[string]int
+}
+
+// newSolStepController initial/lo/hi 为 slot 数；lo 非法时取 hi，initial 夹在 [lo, hi] 内
+func newSolStepController(initial, lo, hi int, target time.Duration) *solStepController {
+	if hi <= 0 {
+		hi = initial
+	}
+	if lo <= 0 || lo > hi {
+		lo = hi
+	}
+	initial = max(lo, min(hi, initial))
+	return &solStepController{initial: initial, min: lo, max: hi, target: target, steps: map[string]int{}}
+}
+
+// Step 实体当前的扫描步长
+func (c *solStepController) Step(entity string) int {
+	if s, ok := c.steps[entity]; ok {
+		return s
+	}
+	return c.initial
+}
+
+// Observe 根据一个窗口的统计调整该实体的步长，返回新步长
+func (c *solStepController) Observe(entity string, w solWindowStats) int {
+	step := c.Step(entity)
+	switch {
+	case w.RateLimited > 0 || (w.Slots > 0 && w.Failed*10 > w.Slots):
+		// 限流或失败率超过 10%：减半
+		step /= 2
+	case w.Slots > 0 && w.Elapsed > 0 && c.target > 0:
+		// 按单 slot 耗时估算目标耗时内可扫的 slot 数；放大每次最多翻倍，缩小直接到位
+		ideal := c.max
+		if perSlot := w.Elapsed / time.Duration(w.Slots); perSlot > 0 {
+			ideal = int(c.target / perSlot)
+		}
+		if ideal > step {
+			step = min(ideal, step*2)
+		} else {
+			step = ideal
+		}
+	}
+	step = max(c.min, min(c.max, step))
+	c.steps[entity] = step
+	return step
+}
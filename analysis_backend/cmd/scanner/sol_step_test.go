@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSolStepShrinksUnderRateLimit 限流时步长逐次减半直到下限，且只影响该实体
+func TestSolStepShrinksUnderRateLimit(t *testing.T) {
+	c := newSolStepController(200, 20, 1000, time.Minute)
+	want := []int{100, 50, 25, 20, 20}
+	for i, w := range want {
+		if got := c.Observe("binance", solWindowStats{Slots: c.Step("binance"), RateLimited: 3, Elapsed: 30 * time.Second}); got != w {
+			t.Fatalf("第%d次限流后步长期望 %d，实际 %d", i, w, got)
+		}
+	}
+	if got := c.Step("okx"); got != 200 {
+		t.Errorf("其它实体步长不应受影响，实际 %d", got)
+	}
+
+	// 失败率超过 10% 同样收缩
+	if got := c.Observe("okx", solWindowStats{Slots: 200, Failed: 30, Elapsed: 10 * time.Second}); got != 100 {
+		t.Errorf("高失败率时步长期望 100，实际 %d", got)
+	}
+}
+
+// TestSolStepGrowsWhenHealthy 健康且远快于目标耗时时逐步翻倍到上限；区块变密导致单 slot 变慢时收缩到目标耗时对应的步长
+func TestSolStepGrowsWhenHealthy(t *testing.T) {
+	c := newSolStepController(100, 20, 1000, time.Minute)
+	want := []int{200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		step := c.Step("binance")
+		// 每 slot 10ms，目标 1 分钟可扫 6000 slot
+		stats := solWindowStats{Slots: step, Txs: step * 50, Elapsed: time.Duration(step) * 10 * time.Millisecond}
+		if got := c.Observe("binance", stats); got != w {
+			t.Fatalf("第%d个健康窗口后步长期望 %d，实际 %d", i, w, got)
+		}
+	}
+
+	// 区块密度上升：每 slot 耗时 200ms，1 分钟只能扫 300 slot
+	stats := solWindowStats{Slots: 1000, Txs: 1000 * 2000, Elapsed: 200 * time.Second}
+	if got := c.Observe("binance", stats); got != 300 {
+		t.Errorf("单 slot 变慢后步长期望 300，实际 %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+// cmd/scanner/sol_tip.go
+// Solana 扫描上界：默认扫到 confirmed 最新 slot；开启 -sol-finalized-only 时只扫到 finalized 的 slot，
+// 未最终确认（可能被跳过/回滚）的 slot 留到下一轮。区块内容仍按 confirmed 拉取。
+
+package main
+
+import (
+	"context"
+)
+
+// solPostFunc 与 main 中的 solPost 一致（带端点轮换/退避）
+type solPostFunc func(ctx context.Context, method string, params []any, out *rpcResp) error
+
+// solTipCommitment 取扫描上界时使用的 commitment
+func solTipCommitment(finalizedOnly bool) string {
+	if finalizedOnly {
+		return "finalized"
+	}
+	return "confirmed"
+}
+
+// solTipSlot 查询扫描上界 slot
+func solTipSlot(ctx context.Context, post solPostFunc, finalizedOnly bool) (uint64, error) {
+	var out rpcResp
+	params := []any{map[string]any{"commitment": solTipCommitment(finalizedOnly)}}
+	if err := post(ctx, "getSlot", params, &out); err != nil {
+		return 0, err
+	}
+	var n uint64
+	if err := decodeResult("getSlot", &out, &n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// fakeSolTips 按 getSlot 的 commitment 返回不同的 tip
+func fakeSolTips(tips map[string]uint64, seen *[]string) solPostFunc {
+	return func(ctx context.Context, method string, params []any, out *rpcResp) error {
+		commitment := ""
+		if len(params) > 0 {
+			if m, ok := params[0].(map[string]any); ok {
+				commitment, _ = m["commitment"].(string)
+			}
+		}
+		*seen = append(*seen, method+":"+commitment)
+		out.Result, _ = json.Marshal(tips[commitment])
+		return nil
+	}
+}
+
+func TestSolScanWindowClampedToFinalizedTip(t *testing.T) {
+	var seen []string
+	post := fakeSolTips(map[string]uint64{"confirmed": 1_000_050, "finalized": 1_000_010}, &seen)
+
+	tip, err := solTipSlot(context.Background(), post, true)
+	if err != nil || tip != 1_000_010 {
+		t.Fatalf("want finalized tip 1000010, got %d %v", tip, err)
+	}
+	if len(seen) != 1 || seen[0] != "getSlot:finalized" {
+		t.Fatalf("tip must be queried at finalized commitment, got %v", seen)
+	}
+
+	// 步长足够覆盖到 confirmed tip，但窗口只到 finalized tip
+	to, ok := scanWindow(1_000_000, 200, tip)
+	if !ok || to != 1_000_010 {
+		t.Fatalf("window should be clamped to finalized tip, got %d ok=%v", to, ok)
+	}
+	// 游标已越过 finalized tip（tip 已扫完）：本轮不扫
+	if _, ok := scanWindow(1_000_011, 200, tip); ok {
+		t.Fatal("cursor past finalized tip should not scan unfinalized slots")
+	}
+}
+
+func TestSolTipDefaultsToConfirmed(t *testing.T) {
+	var seen []string
+	post := fakeSolTips(map[string]uint64{"confirmed": 1_000_050, "finalized": 1_000_010}, &seen)
+
+	tip, err := solTipSlot(context.Background(), post, false)
+	if err != nil || tip != 1_000_050 {
+		t.Fatalf("want confirmed tip 1000050, got %d %v", tip, err)
+	}
+	if seen[0] != "getSlot:confirmed" {
+		t.Fatalf("default tip should be confirmed, got %v", seen)
+	}
+	if to, ok := scanWindow(1_000_000, 20, tip); !ok || to != 1_000_020 {
+		t.Fatalf("window should follow the step below the tip, got %d ok=%v", to, ok)
+	}
+}
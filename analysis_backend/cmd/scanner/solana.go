@@ -0,0 +1,335 @@
+// cmd/scanner/solana.go
+// Solana 交易解析：先按指令解析转账，再用 pre/post 余额差兜底。
+// 同一笔转账两条路径都能看到，余额差只在 (txid, owner, mint, 金额) 未被指令覆盖时才产生事件，避免重复计数。
+
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// solTokenAccount SPL token 账户的持有人与币种（来自 pre/postTokenBalances）
+type solTokenAccount struct {
+	owner, mint string
+}
+
+// solAccountKeys 交易涉及的账户列表（兼容 json 与 jsonParsed 两种编码）
+func solAccountKeys(tx map[string]any) []string {
+	txObj, _ := tx["transaction"].(map[string]any)
+	msg, _ := txObj["message"].(map[string]any)
+	var keys []string
+	if ak, ok := msg["accountKeys"].([]any); ok {
+		for _, k := range ak {
+			switch kv := k.(type) {
+			case string:
+				keys = append(keys, kv)
+			case map[string]any:
+				keys = append(keys, str(kv["pubkey"]))
+			}
+		}
+	}
+	return keys
+}
+
+// solTokenAccounts token 账户地址 -> 持有人/币种
+func solTokenAccounts(meta map[string]any, keys []string) map[string]solTokenAccount {
+	out := map[string]solTokenAccount{}
+	for _, field := range []string{"preTokenBalances", "postTokenBalances"} {
+		list, _ := meta[field].([]any)
+		for _, it := range list {
+			m, ok := it.(map[string]any)
+			if !ok {
+				continue
+			}
+			idx := intFromAny(m["accountIndex"])
+			if idx < 0 || idx >= len(keys) {
+				continue
+			}
+			out[keys[idx]] = solTokenAccount{owner: str(m["owner"]), mint: strings.ToLower(str(m["mint"]))}
+		}
+	}
+	return out
+}
+
+// solCoverKey 去重键：持有人 + 币种（SOL 为空串）
+func solCoverKey(owner, mint string) string {
+	return owner + "|" + strings.ToLower(mint)
+}
+
+// solAmount 统一到 8 位小数后比较，与 toDecimal 的输出精度一致
+func solAmount(r *big.Rat) string {
+	return r.FloatString(8)
+}
+
+// solBlockTime 区块（或 getTransaction 结果）的 blockTime，缺失时用当前时间
+func solBlockTime(blk map[string]any) time.Time {
+	switch v := blk["blockTime"].(type) {
+	case float64:
+		return time.Unix(int64(v), 0).UTC()
+	case int64:
+		return time.Unix(v, 0).UTC()
+	}
+	return time.Now().UTC()
+}
+
+// solTxSignature 交易的第一个签名（即 txid）
+func solTxSignature(tx map[string]any) string {
+	txObj, _ := tx["transaction"].(map[string]any)
+	if sigs, _ := txObj["signatures"].([]any); len(sigs) > 0 {
+		return str(sigs[0])
+	}
+	return ""
+}
+
+// solBlockEvents 区块内所有交易的事件（slot 模式），LogIndex 从 *logIndex 起按窗口顺序分配；返回事件与交易数
+func solBlockEvents(entity string, blk map[string]any, watched func(string) bool, mintToSymbol map[string]string, logIndex *int) ([]models.Event, int) {
+	ts := solBlockTime(blk)
+	txs, _ := blk["transactions"].([]any)
+	var events []models.Event
+	for _, ti := range txs {
+		tx, ok := ti.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, e := range solTxEvents(entity, solTxSignature(tx), tx, ts, watched, mintToSymbol) {
+			e.LogIndex = *logIndex
+			*logIndex++
+			events = append(events, e)
+		}
+	}
+	return events, len(txs)
+}
+
+// solTxEvents 单笔交易中命中监控地址的事件（LogIndex 由调用方按窗口顺序分配）；
+// 失败交易（meta.err 非空）的转账已回滚，未开启 -include-failed-tx 时不产生事件
+func solTxEvents(entity, txid string, tx map[string]any, ts time.Time, watched func(string) bool, mintToSymbol map[string]string) []models.Event {
+	var events []models.Event
+	meta, _ := tx["meta"].(map[string]any)
+	if solTxFailed(meta) && !txFilter.IncludeFailed {
+		return nil
+	}
+	keys := solAccountKeys(tx)
+	tokenAccounts := solTokenAccounts(meta, keys)
+	ownerOf := func(account string) string {
+		if ta, ok := tokenAccounts[account]; ok && ta.owner != "" {
+			return ta.owner
+		}
+		return account
+	}
+
+	// 指令已覆盖的净额：持有人|币种 -> 有符号金额
+	covered := map[string]*big.Rat{}
+	cover := func(owner, mint, amount string, sign int) {
+		r, ok := new(big.Rat).SetString(amount)
+		if !ok {
+			return
+		}
+		if sign < 0 {
+			r.Neg(r)
+		}
+		k := solCoverKey(owner, mint)
+		if covered[k] == nil {
+			covered[k] = new(big.Rat)
+		}
+		covered[k].Add(covered[k], r)
+	}
+	// isCovered 余额差（已剔除手续费）与指令净额一致时视为已覆盖
+	isCovered := func(owner, mint string, diff *big.Rat) bool {
+		c, ok := covered[solCoverKey(owner, mint)]
+		return ok && solAmount(c) == solAmount(diff)
+	}
+
+	// 未配置的 mint：开启 -sol-unknown-mints 时解析符号，否则丢弃；发现的代币不受币种范围限制
+	var originalMints map[string]string
+	unknownMint := func(mint string) (solMintInfo, bool) {
+		if solUnknownMints == nil || mint == "" {
+			return solMintInfo{}, false
+		}
+		if originalMints == nil {
+			originalMints = solOriginalMints(meta)
+		}
+		raw := originalMints[strings.ToLower(mint)]
+		if raw == "" {
+			return solMintInfo{}, false
+		}
+		return solUnknownMints.Resolve(raw), true
+	}
+
+	// 指令解析
+	for _, tr := range parseSolanaTransfers(tx) {
+		symbol := "SOL"
+		mint := tr.mint
+		discovered := false
+		if !tr.isSOL {
+			// transfer（非 checked）指令不带 mint，从 token 账户补齐
+			if mint == "" {
+				mint = tokenAccounts[tr.source].mint
+				if mint == "" {
+					mint = tokenAccounts[tr.destination].mint
+				}
+			}
+			symbol = mintToSymbol[strings.ToLower(mint)]
+			if symbol == "" {
+				info, ok := unknownMint(mint)
+				if !ok {
+					continue
+				}
+				symbol, discovered = info.Symbol, true
+				// 指令没带精度时按解析出的精度重新换算
+				if tr.rawAmount != "" && info.Decimals > 0 {
+					if n, ok := new(big.Int).SetString(tr.rawAmount, 10); ok {
+						tr.amountDec = toDecimal(n, info.Decimals)
+					}
+				}
+			}
+		}
+		if !discovered && !util.IsAllowedFor(entity, symbol) {
+			continue
+		}
+		if amt, ok := new(big.Rat).SetString(tr.amountDec); ok && txFilter.skipZero(amt.Sign()) {
+			continue
+		}
+		hitOut := watched(tr.source)
+		hitIn := watched(tr.destination)
+		if !(hitOut || hitIn) {
+			continue
+		}
+		dir := "in"
+		addr := tr.destination
+		if hitOut && !hitIn {
+			dir = "out"
+			addr = tr.source
+		}
+		if tr.isSOL {
+			mint = ""
+		}
+		if dir == "out" {
+			cover(ownerOf(addr), mint, tr.amountDec, -1)
+		} else {
+			cover(ownerOf(addr), mint, tr.amountDec, 1)
+		}
+		// 粉尘仍计入覆盖额，避免余额差兜底把它补回来
+		if minAmounts.below("solana", symbol, tr.amountDec) {
+			continue
+		}
+		events = append(events, models.Event{
+			Entity: entity, Chain: "solana", Coin: symbol, AssetID: models.AssetIDFor("solana", mint), Direction: dir, Amount: tr.amountDec,
+			TS: ts, TxID: txid, From: tr.source, To: tr.destination, Address: addr,
+		})
+	}
+
+	// 余额差兜底
+	if meta == nil {
+		return events
+	}
+	if util.IsAllowedFor(entity, "SOL") {
+		preB, ok := toInt64Slice(meta["preBalances"])
+		postB, ok2 := toInt64Slice(meta["postBalances"])
+		if ok && ok2 {
+			fee := int64(intFromAny(meta["fee"]))
+			for i := 0; i < len(preB) && i < len(postB) && i < len(keys); i++ {
+				a := keys[i]
+				if !watched(a) {
+					continue
+				}
+				diff := postB[i] - preB[i]
+				if diff == 0 {
+					continue
+				}
+				// 首个账户是手续费支付方，比较时剔除手续费
+				transfer := diff
+				if i == 0 {
+					transfer += fee
+				}
+				if isCovered(a, "", new(big.Rat).SetFrac64(transfer, 1_000_000_000)) {
+					continue
+				}
+				dir := "in"
+				if diff < 0 {
+					dir = "out"
+				}
+				events = append(events, models.Event{
+					Entity: entity, Chain: "solana", Coin: "SOL", AssetID: models.AssetIDFor("solana", ""), Direction: dir, Amount: lamportsToSOL(diff),
+					TS: ts, TxID: txid, Address: a,
+				})
+			}
+		}
+	}
+
+	// SPL 余额差
+	type tokenState struct {
+		owner, mint, amount string
+		decimals            int
+	}
+	readStates := func(field string) map[int]tokenState {
+		out := map[int]tokenState{}
+		list, _ := meta[field].([]any)
+		for _, it := range list {
+			m, ok := it.(map[string]any)
+			if !ok {
+				continue
+			}
+			ui, _ := m["uiTokenAmount"].(map[string]any)
+			out[intFromAny(m["accountIndex"])] = tokenState{
+				owner:    str(m["owner"]),
+				mint:     strings.ToLower(str(m["mint"])),
+				amount:   str(ui["amount"]),
+				decimals: intFromAny(ui["decimals"]),
+			}
+		}
+		return out
+	}
+	preMap := readStates("preTokenBalances")
+	postMap := readStates("postTokenBalances")
+	for idx, pre := range preMap {
+		post, ok := postMap[idx]
+		if !ok || pre.mint != post.mint {
+			continue
+		}
+		owner := post.owner
+		if owner == "" {
+			owner = pre.owner
+		}
+		if !watched(owner) {
+			continue
+		}
+		dec := post.decimals
+		if dec <= 0 {
+			dec = pre.decimals
+		}
+		diff := bigIntSub(post.amount, pre.amount)
+		if diff.Sign() == 0 {
+			continue
+		}
+		sym := mintToSymbol[strings.ToLower(pre.mint)]
+		if sym == "" {
+			info, ok := unknownMint(pre.mint)
+			if !ok {
+				continue
+			}
+			sym = info.Symbol
+		} else if !util.IsAllowedFor(entity, sym) {
+			continue
+		}
+		if dec > 0 && isCovered(owner, pre.mint, new(big.Rat).SetFrac(diff, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(dec)), nil))) {
+			continue
+		}
+		amt := toDecimal(new(big.Int).Abs(diff), dec)
+		if minAmounts.below("solana", sym, amt) {
+			continue
+		}
+		dir := "in"
+		if diff.Sign() < 0 {
+			dir = "out"
+		}
+		events = append(events, models.Event{
+			Entity: entity, Chain: "solana", Coin: sym, AssetID: models.AssetIDFor("solana", pre.mint), Direction: dir, Amount: amt,
+			TS: ts, TxID: txid, Address: owner,
+		})
+	}
+	return events
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testUSDTMint   = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+	testHotWallet  = "HotWa11et1111111111111111111111111111111111"
+	testHotUSDTAcc = "HotUsdtAcc111111111111111111111111111111111"
+	testUser       = "User111111111111111111111111111111111111111"
+	testUserUSDT   = "UserUsdtAcc11111111111111111111111111111111"
+)
+
+// jsonParsed 编码的交易：热钱包（手续费支付方）转出 1 SOL，并从其 USDT token 账户转出 250 USDT
+const solDoubleCoveredTx = `{
+  "transaction": {
+    "signatures": ["sig1"],
+    "message": {
+      "accountKeys": [
+        {"pubkey": "` + testHotWallet + `"},
+        {"pubkey": "` + testUser + `"},
+        {"pubkey": "` + testHotUSDTAcc + `"},
+        {"pubkey": "` + testUserUSDT + `"}
+      ],
+      "instructions": [
+        {"program": "system", "parsed": {"type": "transfer", "info": {"source": "` + testHotWallet + `", "destination": "` + testUser + `", "lamports": 1000000000}}},
+        {"program": "spl-token", "parsed": {"type": "transfer", "info": {"source": "` + testHotUSDTAcc + `", "destination": "` + testUserUSDT + `", "amount": "250000000", "authority": "` + testHotWallet + `"}}}
+      ]
+    }
+  },
+  "meta": {
+    "fee": 5000,
+    "preBalances": [5000000000, 0, 2039280, 2039280],
+    "postBalances": [3999995000, 1000000000, 2039280, 2039280],
+    "preTokenBalances": [
+      {"accountIndex": 2, "mint": "` + testUSDTMint + `", "owner": "` + testHotWallet + `", "uiTokenAmount": {"amount": "1000000000", "decimals": 6}},
+      {"accountIndex": 3, "mint": "` + testUSDTMint + `", "owner": "` + testUser + `", "uiTokenAmount": {"amount": "0", "decimals": 6}}
+    ],
+    "postTokenBalances": [
+      {"accountIndex": 2, "mint": "` + testUSDTMint + `", "owner": "` + testHotWallet + `", "uiTokenAmount": {"amount": "750000000", "decimals": 6}},
+      {"accountIndex": 3, "mint": "` + testUSDTMint + `", "owner": "` + testUser + `", "uiTokenAmount": {"amount": "250000000", "decimals": 6}}
+    ]
+  }
+}`
+
+func decodeSolTx(t *testing.T, raw string) map[string]any {
+	t.Helper()
+	var tx map[string]any
+	if err := json.Unmarshal([]byte(raw), &tx); err != nil {
+		t.Fatal(err)
+	}
+	return tx
+}
+
+func solTestEvents(t *testing.T, tx map[string]any) []models.Event {
+	t.Helper()
+	util.SetAllowed("SOL,USDT")
+	watched := toSetExact([]string{testHotWallet, testHotUSDTAcc})
+	mints := map[string]string{strings.ToLower(testUSDTMint): "USDT"}
+	return solTxEvents("binance", "sig1", tx, time.Unix(1725148800, 0).UTC(), func(a string) bool { return watched[a] }, mints)
+}
+
+// TestSolTxEventsDedupesBalanceDiff 指令与余额差覆盖同一笔转账时每笔只产生一个事件（SOL 比较时剔除手续费）
+func TestSolTxEventsDedupesBalanceDiff(t *testing.T) {
+	events := solTestEvents(t, decodeSolTx(t, solDoubleCoveredTx))
+	if len(events) != 2 {
+		t.Fatalf("期望 SOL、USDT 各一个事件，实际 %d: %+v", len(events), events)
+	}
+	byCoin := map[string]models.Event{}
+	for _, e := range events {
+		byCoin[e.Coin] = e
+	}
+	if e := byCoin["SOL"]; e.Direction != "out" || e.Amount != "1.00000000" || e.To != testUser {
+		t.Errorf("SOL 事件应来自指令解析: %+v", e)
+	}
+	if e := byCoin["USDT"]; e.Direction != "out" || e.Amount != "250.00000000" || e.From != testHotUSDTAcc {
+		t.Errorf("USDT 事件应来自指令解析（mint 由 token 账户补齐）: %+v", e)
+	}
+}
+
+// TestSolTxEventsBalanceDiffFallback 指令解析不到的转账仍由余额差兜底
+func TestSolTxEventsBalanceDiffFallback(t *testing.T) {
+	tx := decodeSolTx(t, solDoubleCoveredTx)
+	// 去掉指令，模拟未知程序发起的转账
+	tx["transaction"].(map[string]any)["message"].(map[string]any)["instructions"] = []any{}
+
+	events := solTestEvents(t, tx)
+	if len(events) != 2 {
+		t.Fatalf("期望余额差兜底出 SOL、USDT 各一个事件，实际 %d: %+v", len(events), events)
+	}
+	for _, e := range events {
+		switch e.Coin {
+		case "SOL":
+			if e.Direction != "out" || e.Amount != "-1.00000500" {
+				t.Errorf("SOL 余额差事件不符（含手续费）: %+v", e)
+			}
+		case "USDT":
+			if e.Direction != "out" || e.Amount != "250.00000000" || e.Address != testHotWallet {
+				t.Errorf("USDT 余额差事件不符: %+v", e)
+			}
+		default:
+			t.Errorf("意外事件: %+v", e)
+		}
+	}
+}
+
+// TestSolTxEventsEntityScope 实体级币种范围独立于全局 -only 过滤事件
+func TestSolTxEventsEntityScope(t *testing.T) {
+	util.SetEntityAllowed("binance", "USDT")
+	t.Cleanup(util.ResetEntityAllowed)
+
+	events := solTestEvents(t, decodeSolTx(t, solDoubleCoveredTx))
+	if len(events) != 1 || events[0].Coin != "USDT" {
+		t.Fatalf("binance 仅允许 USDT，实际 %+v", events)
+	}
+}
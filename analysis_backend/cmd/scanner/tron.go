@@ -0,0 +1,234 @@
+// cmd/scanner/tron.go
+// Tron TRC20 转账解析：TronGrid gettransactioninfobyblocknum 返回的日志地址为 20 字节 hex（不带 41 前缀），
+// 监控地址与配置的合约为 base58check（T 开头），两者在这里互转；金额按合约 decimals 换算。
+
+package main
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	tronAddrPrefix      = 0x41 // Tron 主网地址版本字节
+	tronDefaultDecimals = 6    // decimals() 探测失败时的兜底精度（USDT/USDC 均为 6）
+	tronAPIKeyHeader    = "TRON-PRO-API-KEY"
+)
+
+var errTronAddress = errors.New("invalid tron address")
+
+// tronBase58ToHex base58check 地址 -> 21 字节 hex（41 开头，小写）
+func tronBase58ToHex(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+	raw, ok := util.Base58Decode(addr)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", errTronAddress, addr)
+	}
+	if len(raw) != 25 || raw[0] != tronAddrPrefix {
+		return "", fmt.Errorf("%w: %q", errTronAddress, addr)
+	}
+	payload, sum := raw[:21], raw[21:]
+	if !bytes.Equal(tronChecksum(payload), sum) {
+		return "", fmt.Errorf("%w: bad checksum %q", errTronAddress, addr)
+	}
+	return hex.EncodeToString(payload), nil
+}
+
+// tronHexToBase58 hex 地址（41+20 字节，或不带前缀的 20 字节，可带 0x）-> base58check
+func tronHexToBase58(h string) (string, error) {
+	h = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(h)), "0x")
+	if len(h) == 40 {
+		h = "41" + h
+	}
+	payload, err := hex.DecodeString(h)
+	if err != nil || len(payload) != 21 || payload[0] != tronAddrPrefix {
+		return "", fmt.Errorf("%w: %q", errTronAddress, h)
+	}
+	raw := append(append([]byte{}, payload...), tronChecksum(payload)...)
+	return util.Base58Encode(raw), nil
+}
+
+// tronChecksum 两次 sha256 的前 4 字节
+func tronChecksum(payload []byte) []byte {
+	a := sha256.Sum256(payload)
+	b := sha256.Sum256(a[:])
+	return b[:4]
+}
+
+// tronNormalizeAddress 监控地址统一为 base58；配置里写成 hex 的地址也能命中
+func tronNormalizeAddress(a string) string {
+	a = strings.TrimSpace(a)
+	if strings.HasPrefix(a, "T") {
+		return a
+	}
+	if b, err := tronHexToBase58(a); err == nil {
+		return b
+	}
+	return a
+}
+
+// tronToken 扫描的 TRC20 代币；按 20 字节 hex 合约地址（小写、不带 41）索引
+type tronToken struct {
+	Symbol   string
+	Decimals int
+}
+
+// tronTxInfo TronGrid gettransactioninfobyblocknum 的单笔交易
+type tronTxInfo struct {
+	ID             string `json:"id"`
+	BlockNumber    uint64 `json:"blockNumber"`
+	BlockTimeStamp int64  `json:"blockTimeStamp"` // 毫秒
+	Receipt        struct {
+		Result string `json:"result"`
+	} `json:"receipt"`
+	Log []struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+		Data    string   `json:"data"`
+	} `json:"log"`
+}
+
+// tronTopicAddress topic 末尾 20 字节 -> base58
+func tronTopicAddress(topic string) string {
+	t := strings.TrimPrefix(strings.ToLower(topic), "0x")
+	if len(t) < 40 {
+		return ""
+	}
+	a, err := tronHexToBase58(t[len(t)-40:])
+	if err != nil {
+		return ""
+	}
+	return a
+}
+
+// tronTxEvents 交易中命中监控地址的 TRC20 Transfer 转换为事件；LogIndex 为日志在交易内的序号。
+// 失败交易（receipt.result 非 SUCCESS）与未配置的合约跳过
+func tronTxEvents(entity string, info tronTxInfo, tokens map[string]tronToken, addrSet map[string]bool) []models.Event {
+	if r := info.Receipt.Result; r != "" && r != "SUCCESS" {
+		return nil
+	}
+	wantTopic := strings.TrimPrefix(transferTopic.Hex(), "0x")
+	ts := time.UnixMilli(info.BlockTimeStamp).UTC()
+	var out []models.Event
+	for i, lg := range info.Log {
+		if len(lg.Topics) < 3 || strings.TrimPrefix(strings.ToLower(lg.Topics[0]), "0x") != wantTopic {
+			continue
+		}
+		contract := strings.TrimPrefix(strings.ToLower(lg.Address), "0x")
+		if len(contract) == 42 && strings.HasPrefix(contract, "41") {
+			contract = contract[2:]
+		}
+		tok, ok := tokens[contract]
+		if !ok {
+			continue
+		}
+		from, to := tronTopicAddress(lg.Topics[1]), tronTopicAddress(lg.Topics[2])
+		val, ok := new(big.Int).SetString(strings.TrimPrefix(lg.Data, "0x"), 16)
+		if !ok || val.Sign() == 0 {
+			continue
+		}
+		amt := toDecimal(val, tok.Decimals)
+		for _, leg := range evmTransferLegs(from, to, addrSet) {
+			out = append(out, models.Event{
+				Entity: entity, Chain: "tron", Coin: tok.Symbol, AssetID: models.AssetIDFor("tron", contract), Direction: leg.dir, Amount: amt,
+				TS: ts, TxID: info.ID, From: from, To: to, Address: leg.address, LogIndex: i,
+				BlockNumber: info.BlockNumber,
+			})
+		}
+	}
+	return out
+}
+
+// tronPost TronGrid HTTP API（POST JSON）；apiKey 非空时带 TRON-PRO-API-KEY 头
+func tronPost(ctx context.Context, base, path, apiKey string, body, out any) error {
+	b, _ := json.Marshal(body)
+	url := strings.TrimRight(base, "/") + path
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("new request %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set(tronAPIKeyHeader, apiKey)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("do post %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("post %s => %d: %s", url, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// tronLatestBlock 已固化（solidified）的最新区块高度，不会再被回滚
+func tronLatestBlock(ctx context.Context, base, apiKey string) (uint64, error) {
+	var r struct {
+		BlockHeader struct {
+			RawData struct {
+				Number uint64 `json:"number"`
+			} `json:"raw_data"`
+		} `json:"block_header"`
+	}
+	if err := tronPost(ctx, base, "/walletsolidity/getnowblock", apiKey, map[string]any{}, &r); err != nil {
+		return 0, err
+	}
+	if r.BlockHeader.RawData.Number == 0 {
+		return 0, fmt.Errorf("tron getnowblock via %s: empty block header", base)
+	}
+	return r.BlockHeader.RawData.Number, nil
+}
+
+// tronBlockTxInfos 区块内所有交易的执行信息（含日志）；空块返回 {} 或 []
+func tronBlockTxInfos(ctx context.Context, base, apiKey string, num uint64) ([]tronTxInfo, error) {
+	var raw json.RawMessage
+	if err := tronPost(ctx, base, "/wallet/gettransactioninfobyblocknum", apiKey, map[string]any{"num": num}, &raw); err != nil {
+		return nil, err
+	}
+	if t := bytes.TrimSpace(raw); len(t) == 0 || t[0] != '[' {
+		return nil, nil
+	}
+	var infos []tronTxInfo
+	if err := json.Unmarshal(raw, &infos); err != nil {
+		return nil, fmt.Errorf("tron block %d txinfo decode: %w", num, err)
+	}
+	return infos, nil
+}
+
+// tronDecimals 通过 triggerconstantcontract 调用 decimals()
+func tronDecimals(ctx context.Context, base, apiKey, contract string) (int, error) {
+	var r struct {
+		ConstantResult []string `json:"constant_result"`
+	}
+	body := map[string]any{
+		"owner_address":     contract,
+		"contract_address":  contract,
+		"function_selector": "decimals()",
+		"visible":           true,
+	}
+	if err := tronPost(ctx, base, "/wallet/triggerconstantcontract", apiKey, body, &r); err != nil {
+		return 0, err
+	}
+	if len(r.ConstantResult) == 0 {
+		return 0, fmt.Errorf("tron decimals %s: empty constant_result", contract)
+	}
+	n, ok := new(big.Int).SetString(r.ConstantResult[0], 16)
+	if !ok {
+		return 0, fmt.Errorf("tron decimals %s: bad result %q", contract, r.ConstantResult[0])
+	}
+	return int(n.Int64()), nil
+}
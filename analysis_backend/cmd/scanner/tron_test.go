@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	tronUSDT    = "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"
+	tronUSDTHex = "41a614f803b6fd780986a42c78ec9c7f77e6ded13c"
+)
+
+// TestTronAddressRoundTrip base58check <-> hex 互转（USDT 合约）
+func TestTronAddressRoundTrip(t *testing.T) {
+	h, err := tronBase58ToHex(tronUSDT)
+	if err != nil || h != tronUSDTHex {
+		t.Fatalf("base58 -> hex 期望 %s，实际 %s (%v)", tronUSDTHex, h, err)
+	}
+	for _, in := range []string{tronUSDTHex, "0x" + strings.ToUpper(tronUSDTHex), tronUSDTHex[2:]} {
+		if b, err := tronHexToBase58(in); err != nil || b != tronUSDT {
+			t.Errorf("hex %s -> base58 期望 %s，实际 %s (%v)", in, tronUSDT, b, err)
+		}
+	}
+	if _, err := tronBase58ToHex("TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6u"); err == nil {
+		t.Error("校验和错误的地址应报错")
+	}
+	if _, err := tronBase58ToHex("0OIl"); err == nil {
+		t.Error("非 base58 字符应报错")
+	}
+	if got := tronNormalizeAddress(tronUSDTHex); got != tronUSDT {
+		t.Errorf("hex 监控地址应归一为 base58，实际 %s", got)
+	}
+}
+
+// tronTestAddr 由 20 字节尾号构造测试地址，返回 base58 与 topic
+func tronTestAddr(t *testing.T, last byte) (string, string) {
+	t.Helper()
+	h := strings.Repeat("0", 38) + string("0123456789abcdef"[last>>4]) + string("0123456789abcdef"[last&0xf])
+	b, err := tronHexToBase58(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, strings.Repeat("0", 24) + h
+}
+
+// TestTronTxEventsUSDT 配置的 TRC20 合约 Transfer 按 decimals 换算，监控地址的入/出与自转两侧都生成事件
+func TestTronTxEventsUSDT(t *testing.T) {
+	hot, hotTopic := tronTestAddr(t, 1)
+	cold, coldTopic := tronTestAddr(t, 2)
+	_, extTopic := tronTestAddr(t, 3)
+	tokens := map[string]tronToken{tronUSDTHex[2:]: {Symbol: "USDT", Decimals: 6}}
+	watched := toSetExact([]string{hot, cold})
+
+	info := tronTxInfo{ID: "abc", BlockNumber: 100, BlockTimeStamp: 1700000000000}
+	info.Receipt.Result = "SUCCESS"
+	type lg = struct {
+		Address string   `json:"address"`
+		Topics  []string `json:"topics"`
+		Data    string   `json:"data"`
+	}
+	transfer := strings.TrimPrefix(transferTopic.Hex(), "0x")
+	info.Log = []lg{
+		{Address: tronUSDTHex[2:], Topics: []string{transfer, extTopic, hotTopic}, Data: "0000000000000000000000000000000000000000000000000000000005f5e100"},  // 100 in
+		{Address: tronUSDTHex[2:], Topics: []string{transfer, hotTopic, coldTopic}, Data: "00000000000000000000000000000000000000000000000000000000000f4240"}, // 1 自转
+		{Address: strings.Repeat("ab", 20), Topics: []string{transfer, hotTopic, extTopic}, Data: "01"},                                                       // 未配置合约
+	}
+	evs := tronTxEvents("binance", info, tokens, watched)
+	if len(evs) != 3 {
+		t.Fatalf("期望 3 个事件，实际 %+v", evs)
+	}
+	want := []struct{ dir, addr, amount string }{{"in", hot, "100.00000000"}, {"out", hot, "1.00000000"}, {"in", cold, "1.00000000"}}
+	for i, w := range want {
+		e := evs[i]
+		if e.Direction != w.dir || e.Address != w.addr || e.Amount != w.amount || e.Coin != "USDT" || e.Chain != "tron" {
+			t.Errorf("事件 %d 期望 %+v，实际 %+v", i, w, e)
+		}
+	}
+	if want := "tron:" + tronUSDTHex[2:]; evs[0].AssetID != want {
+		t.Errorf("asset_id 期望 %s，实际 %s", want, evs[0].AssetID)
+	}
+	if evs[1].LogIndex != 1 || evs[0].TxID != "abc" || evs[0].BlockNumber != 100 || evs[0].TS.Unix() != 1700000000 {
+		t.Errorf("事件元数据不符: %+v", evs[0])
+	}
+
+	info.Receipt.Result = "REVERT"
+	if evs := tronTxEvents("binance", info, tokens, watched); len(evs) != 0 {
+		t.Errorf("失败交易不应生成事件: %+v", evs)
+	}
+}
+
+// TestTronGridCalls 空块返回 {} 视为无交易；API key 通过请求头传递
+func TestTronGridCalls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(tronAPIKeyHeader) != "k" {
+			http.Error(w, "missing key", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/walletsolidity/getnowblock":
+			_, _ = w.Write([]byte(`{"block_header":{"raw_data":{"number":123}}}`))
+		case "/wallet/gettransactioninfobyblocknum":
+			_, _ = w.Write([]byte(`{}`))
+		case "/wallet/triggerconstantcontract":
+			_, _ = w.Write([]byte(`{"constant_result":["0000000000000000000000000000000000000000000000000000000000000006"]}`))
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if n, err := tronLatestBlock(ctx, srv.URL, "k"); err != nil || n != 123 {
+		t.Errorf("latest 期望 123，实际 %d (%v)", n, err)
+	}
+	if infos, err := tronBlockTxInfos(ctx, srv.URL, "k", 1); err != nil || len(infos) != 0 {
+		t.Errorf("空块应返回空列表，实际 %v (%v)", infos, err)
+	}
+	if d, err := tronDecimals(ctx, srv.URL, "k", tronUSDT); err != nil || d != 6 {
+		t.Errorf("decimals 期望 6，实际 %d (%v)", d, err)
+	}
+	if _, err := tronLatestBlock(ctx, srv.URL, ""); err == nil {
+		t.Error("缺少 API key 时应返回错误")
+	}
+}
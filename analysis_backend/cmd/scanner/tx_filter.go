@@ -0,0 +1,80 @@
+// cmd/scanner/tx_filter.go
+// 失败交易与零金额转账的过滤：
+//   - Solana meta.err 非空的交易已回滚，余额差只剩手续费，指令里的转账并未生效，默认整笔跳过；
+//   - EVM 回滚交易（receipt.status=0x0）的 tx.value 没有转出，默认不输出原生币事件；
+//   - 零金额转账（地址投毒常见手法）默认丢弃。
+// -include-failed-tx / -include-zero-value 恢复输出，便于排查。
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"analysis/internal/models"
+)
+
+// txFilter 由命令行初始化，只读
+var txFilter txFilterCfg
+
+type txFilterCfg struct {
+	IncludeFailed bool // 输出失败/回滚交易的事件
+	IncludeZero   bool // 输出零金额转账
+}
+
+// skipZero 金额为 0（sign==0）且未开启 -include-zero-value 时跳过
+func (f txFilterCfg) skipZero(sign int) bool {
+	return sign == 0 && !f.IncludeZero
+}
+
+// solTxFailed Solana 交易执行失败（meta.err 非空）
+func solTxFailed(meta map[string]any) bool {
+	return meta != nil && meta["err"] != nil
+}
+
+// evmReceiptFailed 回执 status 为 0x0（回滚）；没有 status 字段（拜占庭分叉前）视为成功
+func evmReceiptFailed(receipt map[string]any) bool {
+	s := strings.ToLower(str(receipt["status"]))
+	return s == "0x0" || s == "0x00"
+}
+
+// evmTxHashes 事件涉及的交易哈希（去重，保持顺序）
+func evmTxHashes(events []models.Event) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, e := range events {
+		if e.TxID != "" && !seen[e.TxID] {
+			seen[e.TxID] = true
+			out = append(out, e.TxID)
+		}
+	}
+	return out
+}
+
+// dropFailedEVMTxs 去掉回滚交易的事件；receipt 拉取失败时返回错误，由调用方决定整窗不提交
+func dropFailedEVMTxs(ctx context.Context, events []models.Event, receipt func(ctx context.Context, hash string) (map[string]any, error)) ([]models.Event, error) {
+	if txFilter.IncludeFailed || len(events) == 0 {
+		return events, nil
+	}
+	failed := map[string]bool{}
+	for _, h := range evmTxHashes(events) {
+		r, err := receipt(ctx, h)
+		if err != nil {
+			return events, fmt.Errorf("receipt %s: %w", h, err)
+		}
+		if evmReceiptFailed(r) {
+			failed[h] = true
+		}
+	}
+	if len(failed) == 0 {
+		return events, nil
+	}
+	out := events[:0]
+	for _, e := range events {
+		if !failed[e.TxID] {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestSolTxEventsSkipsFailedTx meta.err 非空的交易不产生事件（余额差只剩手续费也不计）；开启 -include-failed-tx 后恢复
+func TestSolTxEventsSkipsFailedTx(t *testing.T) {
+	tx := decodeSolTx(t, solDoubleCoveredTx)
+	tx["meta"].(map[string]any)["err"] = map[string]any{"InstructionError": []any{1, "Custom"}}
+	if evs := solTestEvents(t, tx); len(evs) != 0 {
+		t.Fatalf("失败交易不应产生事件: %+v", evs)
+	}
+
+	txFilter = txFilterCfg{IncludeFailed: true}
+	defer func() { txFilter = txFilterCfg{} }()
+	if evs := solTestEvents(t, tx); len(evs) == 0 {
+		t.Fatal("-include-failed-tx 时失败交易应照常输出")
+	}
+}
+
+// TestDropFailedEVMTxs 回执 status=0x0 的交易的原生币事件被去掉；每笔交易只查一次回执，查询失败返回错误
+func TestDropFailedEVMTxs(t *testing.T) {
+	addrSet := map[string]bool{nativeHot: true, nativeCold: true}
+	events := evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100")
+	calls := map[string]int{}
+	receipt := func(_ context.Context, hash string) (map[string]any, error) {
+		calls[hash]++
+		if hash == "0xT2" {
+			return map[string]any{"status": "0x0"}, nil
+		}
+		return map[string]any{"status": "0x1"}, nil
+	}
+	got, err := dropFailedEVMTxs(context.Background(), events, receipt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].TxID != "0xT1" {
+		t.Fatalf("回滚的 T2 应被去掉，实际 %+v", got)
+	}
+	if calls["0xT1"] != 1 || calls["0xT2"] != 1 || calls["0xT3"] != 0 {
+		t.Errorf("回执查询次数不符: %v", calls)
+	}
+
+	events = evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100")
+	if _, err := dropFailedEVMTxs(context.Background(), events, func(context.Context, string) (map[string]any, error) {
+		return nil, errors.New("timeout")
+	}); err == nil {
+		t.Error("回执查询失败应返回错误")
+	}
+}
+
+// TestTxFilterZeroValue 零金额转账默认丢弃；-include-zero-value 时输出
+func TestTxFilterZeroValue(t *testing.T) {
+	addrSet := map[string]bool{nativeHot: true}
+	if evs := evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100"); len(evs) != 2 {
+		t.Fatalf("默认应跳过零金额的 T3，实际 %+v", evs)
+	}
+	txFilter = txFilterCfg{IncludeZero: true}
+	defer func() { txFilter = txFilterCfg{} }()
+	evs := evmNativeTxEvents(nativeTestBlock(), "binance", "ethereum", "ETH", addrSet, time.Now(), 100, "0xb100")
+	if len(evs) != 3 || evs[2].TxID != "0xT3" || evs[2].Direction != "out" {
+		t.Fatalf("-include-zero-value 时应输出 T3，实际 %+v", evs)
+	}
+}
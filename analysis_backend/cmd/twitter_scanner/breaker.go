@@ -0,0 +1,68 @@
+// cmd/twitter_scanner/breaker.go
+// 熔断：某个用户（或全局）连续失败达到阈值后，冷却期内不再请求 /twitter/fetch；
+// 冷却结束后放行一次探测，探测成功即恢复，失败则再次熔断。
+
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// globalBreakerKey 全局熔断使用的键（bearer 失效/整体限流时所有用户一起停）
+const globalBreakerKey = "*"
+
+// fetchBreaker 按键（用户名）独立计数的连续失败熔断器
+type fetchBreaker struct {
+	Threshold int           // 连续失败多少次后熔断，<=0 表示不熔断
+	Cooldown  time.Duration // 熔断持续时间
+
+	mu     sync.Mutex
+	states map[string]*breakerState
+}
+
+type breakerState struct {
+	failures  int
+	openUntil time.Time
+}
+
+func newFetchBreaker(threshold int, cooldown time.Duration) *fetchBreaker {
+	return &fetchBreaker{Threshold: threshold, Cooldown: cooldown, states: map[string]*breakerState{}}
+}
+
+// Allow 是否允许请求；熔断期间返回 false
+func (b *fetchBreaker) Allow(key string, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.states[key]
+	return st == nil || !now.Before(st.openUntil)
+}
+
+// Success 成功一次即恢复
+func (b *fetchBreaker) Success(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if st := b.states[key]; st != nil {
+		if !st.openUntil.IsZero() {
+			log.Printf("[breaker] %s 已恢复", key)
+		}
+		delete(b.states, key)
+	}
+}
+
+// Failure 记录一次失败；达到阈值时熔断（冷却后的探测失败会立即再次熔断）
+func (b *fetchBreaker) Failure(key string, now time.Time, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.states[key]
+	if st == nil {
+		st = &breakerState{}
+		b.states[key] = st
+	}
+	st.failures++
+	if b.Threshold > 0 && st.failures >= b.Threshold {
+		st.openUntil = now.Add(b.Cooldown)
+		log.Printf("[breaker] %s 连续失败 %d 次，熔断至 %s: %v", key, st.failures, st.openUntil.UTC().Format(time.RFC3339), err)
+	}
+}
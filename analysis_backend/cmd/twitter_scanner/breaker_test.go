@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestFetchBreakerOpensAndCloses 连续失败 N 次后熔断，冷却后放行探测；探测失败再次熔断，成功则恢复
+func TestFetchBreakerOpensAndCloses(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	b := newFetchBreaker(3, 10*time.Minute)
+	errLimited := errors.New("429 too many requests")
+
+	for i := 0; i < 2; i++ {
+		b.Failure("alice", now, errLimited)
+	}
+	if !b.Allow("alice", now) {
+		t.Fatal("未达到阈值不应熔断")
+	}
+	b.Failure("alice", now, errLimited)
+	if b.Allow("alice", now.Add(time.Minute)) {
+		t.Fatal("连续 3 次失败后应熔断")
+	}
+	if !b.Allow("bob", now) {
+		t.Fatal("其它用户不受影响")
+	}
+
+	probe := now.Add(10 * time.Minute)
+	if !b.Allow("alice", probe) {
+		t.Fatal("冷却结束后应放行探测")
+	}
+	b.Failure("alice", probe, errLimited)
+	if b.Allow("alice", probe.Add(time.Second)) {
+		t.Fatal("探测失败应立即再次熔断")
+	}
+
+	b.Success("alice")
+	if !b.Allow("alice", probe.Add(time.Second)) {
+		t.Fatal("成功后应恢复")
+	}
+}
+
+// TestTwitterPollerSkipsTrippedUser 某用户熔断后跳过它，其它用户照常拉取；全局熔断时整轮跳过
+func TestTwitterPollerSkipsTrippedUser(t *testing.T) {
+	var calls []string
+	getJSON := func(ctx context.Context, u string, out any) error {
+		calls = append(calls, u)
+		if strings.Contains(u, "username=bad") {
+			return errors.New("GET => 401")
+		}
+		return nil
+	}
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	p := newTwitterPoller("http://api", []string{"bad", "good"}, 5, time.Minute, 0, getJSON)
+	p.userBreaker = newFetchBreaker(2, 10*time.Minute)
+	p.globalBreaker = newFetchBreaker(10, 10*time.Minute)
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		p.pollOnce(context.Background())
+	}
+	bad, good := 0, 0
+	for _, u := range calls {
+		if strings.Contains(u, "username=bad") {
+			bad++
+		} else {
+			good++
+		}
+	}
+	if bad != 2 || good != 4 {
+		t.Fatalf("bad 应在 2 次失败后被跳过、good 每轮都拉取: bad=%d good=%d", bad, good)
+	}
+
+	// 冷却结束后 bad 再被探测一次
+	now = now.Add(10 * time.Minute)
+	calls = nil
+	p.pollOnce(context.Background())
+	if len(calls) != 2 {
+		t.Fatalf("冷却后应探测 bad 并拉取 good，实际 %v", calls)
+	}
+
+	// 全局熔断：所有用户都失败达到阈值后整轮跳过
+	p.globalBreaker = newFetchBreaker(2, 10*time.Minute)
+	p.userBreaker = nil
+	getAllFail := func(ctx context.Context, u string, out any) error {
+		calls = append(calls, u)
+		return errors.New("GET => 429")
+	}
+	p.getJSON = getAllFail
+	calls = nil
+	p.pollOnce(context.Background())
+	p.pollOnce(context.Background())
+	if len(calls) != 2 {
+		t.Fatalf("全局连续 2 次失败后应停止本轮并跳过下一轮，实际请求 %d 次", len(calls))
+	}
+}
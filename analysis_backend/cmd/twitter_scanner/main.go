@@ -6,8 +6,10 @@ import (
 	"context"
 	"flag"
 	"log"
-	"net/url"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -18,6 +20,11 @@ func main() {
 	interval := flag.Duration("interval", 2*time.Minute, "poll interval")
 	// 可选：命令行指定用户名，优先于配置；多用户用逗号
 	usersFlag := flag.String("users", "", "comma-separated twitter usernames (override config)")
+	limit := flag.Int("limit", 5, "max tweets fetched per user per poll (<=100)")
+	userSpacing := flag.Duration("user-spacing", 2*time.Second, "delay between users within one poll (rate limit)")
+	breakerThreshold := flag.Int("breaker-threshold", 3, "consecutive failures before a user is paused (0 = disabled)")
+	globalBreakerThreshold := flag.Int("global-breaker-threshold", 6, "consecutive failures across all users before polling is paused (0 = disabled)")
+	breakerCooldown := flag.Duration("breaker-cooldown", 15*time.Minute, "how long a tripped breaker pauses fetching")
 	flag.Parse()
 
 	var cfg config.Config
@@ -50,50 +57,14 @@ func main() {
 		*interval = time.Duration(cfg.Twitter.IntervalSeconds) * time.Second
 	}
 
-	log.Printf("[twitter_scanner] start; api=%s interval=%s users=%v (proxy from config)", *apiBase, *interval, users)
+	log.Printf("[twitter_scanner] start; api=%s interval=%s spacing=%s users=%v (proxy from config)", *apiBase, *interval, *userSpacing, users)
 
-	ctx := context.Background()
-	//tk := time.NewTicker(*interval)
-	//defer tk.Stop()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	//for {
-	//	for _, u := range users {
-	//		// 调后端 fetch（由后端持 Bearer 调官方 API 并入库）
-	//		// /twitter/fetch?username=u&limit=50&store=1
-	//		v := url.Values{}
-	//		v.Set("username", u)
-	//		v.Set("limit", "5")
-	//		v.Set("store", "1")
-	//		fetchURL := strings.TrimRight(*apiBase, "/") + "/twitter/fetch?" + v.Encode()
-	//
-	//		var out any
-	//		if err := netutil.GetJSON(ctx, fetchURL, &out); err != nil {
-	//			log.Printf("fetch %s err: %v", u, err)
-	//		} else {
-	//			log.Printf("fetched %s ok", u)
-	//		}
-	//		// 可按需 sleep 少许，避免一瞬间打满
-	//		time.Sleep(500 * time.Millisecond)
-	//	}
-	//	<-tk.C
-	//}
-
-	for _, u := range users {
-		// 调后端 fetch（由后端持 Bearer 调官方 API 并入库）
-		// /twitter/fetch?username=u&limit=50&store=1
-		v := url.Values{}
-		v.Set("username", u)
-		v.Set("limit", "5")
-		v.Set("store", "1")
-		fetchURL := strings.TrimRight(*apiBase, "/") + "/twitter/fetch?" + v.Encode()
-
-		var out any
-		if err := netutil.GetJSON(ctx, fetchURL, &out); err != nil {
-			log.Printf("fetch %s err: %v", u, err)
-		} else {
-			log.Printf("fetched %s ok", u)
-		}
-		// 可按需 sleep 少许，避免一瞬间打满
-		time.Sleep(500 * time.Millisecond)
-	}
+	p := newTwitterPoller(*apiBase, users, *limit, *interval, *userSpacing, netutil.GetJSON)
+	p.userBreaker = newFetchBreaker(*breakerThreshold, *breakerCooldown)
+	p.globalBreaker = newFetchBreaker(*globalBreakerThreshold, *breakerCooldown)
+	p.Run(ctx)
+	log.Printf("[twitter_scanner] stopped")
 }
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// twitterFetchResp /twitter/fetch 的响应（只取需要的字段）
+type twitterFetchResp struct {
+	Count    int    `json:"count"`
+	NewestID string `json:"newest_id"`
+}
+
+// twitterPoller 按 interval 轮询各用户；用户之间间隔 spacing 以避开限流，
+// 记住每个用户的 newest_id 作为下一轮的 since_id，只拉增量
+type twitterPoller struct {
+	apiBase  string
+	users    []string
+	limit    int
+	interval time.Duration
+	spacing  time.Duration
+	getJSON  func(ctx context.Context, u string, out any) error
+
+	// 可选熔断：userBreaker 按用户，globalBreaker 统计所有用户的连续失败
+	userBreaker   *fetchBreaker
+	globalBreaker *fetchBreaker
+	now           func() time.Time
+
+	sinceIDs map[string]string
+}
+
+func newTwitterPoller(apiBase string, users []string, limit int, interval, spacing time.Duration, getJSON func(ctx context.Context, u string, out any) error) *twitterPoller {
+	return &twitterPoller{
+		apiBase:  strings.TrimRight(apiBase, "/"),
+		users:    users,
+		limit:    limit,
+		interval: interval,
+		spacing:  spacing,
+		getJSON:  getJSON,
+		now:      time.Now,
+		sinceIDs: map[string]string{},
+	}
+}
+
+// Run 立即轮询一轮，之后每个 interval 一轮，直到 ctx 取消
+func (p *twitterPoller) Run(ctx context.Context) {
+	tk := time.NewTicker(p.interval)
+	defer tk.Stop()
+	for {
+		p.pollOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+		}
+	}
+}
+
+// pollOnce 依次拉取每个用户（跳过熔断中的用户）；ctx 取消时立即返回
+func (p *twitterPoller) pollOnce(ctx context.Context) {
+	if p.globalBreaker != nil && !p.globalBreaker.Allow(globalBreakerKey, p.now()) {
+		log.Printf("[breaker] global open, skip this poll")
+		return
+	}
+	requested := 0
+	for _, u := range p.users {
+		if p.userBreaker != nil && !p.userBreaker.Allow(u, p.now()) {
+			log.Printf("[breaker] %s open, skipped", u)
+			continue
+		}
+		if requested > 0 && p.spacing > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(p.spacing):
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 调后端 fetch（由后端持 Bearer 调官方 API 并入库）
+		v := url.Values{}
+		v.Set("username", u)
+		v.Set("limit", strconv.Itoa(p.limit))
+		v.Set("store", "1")
+		if id := p.sinceIDs[u]; id != "" {
+			v.Set("since_id", id)
+		}
+		var out twitterFetchResp
+		requested++
+		err := p.getJSON(ctx, p.apiBase+"/twitter/fetch?"+v.Encode(), &out)
+		p.record(u, err)
+		if err != nil {
+			log.Printf("fetch %s err: %v", u, err)
+			if p.globalBreaker != nil && !p.globalBreaker.Allow(globalBreakerKey, p.now()) {
+				return
+			}
+			continue
+		}
+		if out.NewestID != "" {
+			p.sinceIDs[u] = out.NewestID
+		}
+		log.Printf("fetched %s ok (new=%d since_id=%s)", u, out.Count, p.sinceIDs[u])
+	}
+}
+
+// record 把一次请求结果计入熔断器
+func (p *twitterPoller) record(user string, err error) {
+	for _, b := range []struct {
+		br  *fetchBreaker
+		key string
+	}{{p.userBreaker, user}, {p.globalBreaker, globalBreakerKey}} {
+		if b.br == nil {
+			continue
+		}
+		if err != nil {
+			b.br.Failure(b.key, p.now(), err)
+		} else {
+			b.br.Success(b.key)
+		}
+	}
+}
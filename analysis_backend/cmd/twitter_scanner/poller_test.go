@@ -0,0 +1,70 @@
+package main
+
+import (
+	"analysis/internal/netutil"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fetchCall struct {
+	at       time.Time
+	username string
+	sinceID  string
+}
+
+// TestTwitterPollerPollsRepeatedlyWithSpacing 多轮轮询、用户间有间隔，第二轮起带上 since_id
+func TestTwitterPollerPollsRepeatedlyWithSpacing(t *testing.T) {
+	var mu sync.Mutex
+	var calls []fetchCall
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		mu.Lock()
+		calls = append(calls, fetchCall{at: time.Now(), username: q.Get("username"), sinceID: q.Get("since_id")})
+		n := len(calls)
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]any{"count": 1, "newest_id": strconv.Itoa(10000 + n)})
+	}))
+	defer srv.Close()
+
+	spacing := 30 * time.Millisecond
+	p := newTwitterPoller(srv.URL, []string{"alice", "bob"}, 5, 150*time.Millisecond, spacing, netutil.GetJSON)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(400 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("取消后 Run 应退出")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) < 4 {
+		t.Fatalf("期望至少两轮（4 次请求），实际 %d", len(calls))
+	}
+	for i := 0; i+1 < len(calls); i += 2 {
+		if calls[i].username != "alice" || calls[i+1].username != "bob" {
+			t.Fatalf("第%d轮请求顺序不符: %+v", i/2+1, calls[i:i+2])
+		}
+		if gap := calls[i+1].at.Sub(calls[i].at); gap < spacing {
+			t.Errorf("第%d轮用户间隔 %v 小于 %v", i/2+1, gap, spacing)
+		}
+	}
+	if calls[0].sinceID != "" || calls[1].sinceID != "" {
+		t.Errorf("首轮不应带 since_id: %+v", calls[:2])
+	}
+	if calls[2].sinceID != "10001" || calls[3].sinceID != "10002" {
+		t.Errorf("第二轮应带上一轮的 newest_id: %+v", calls[2:4])
+	}
+}
@@ -0,0 +1,65 @@
+package addr
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"strings"
+)
+
+// NormalizeAddressType 把 PoR 清单里的 type/label 文本归一为 models.AddressType*；无法识别返回空串
+func NormalizeAddressType(label string) string {
+	l := strings.ToLower(strings.TrimSpace(label))
+	switch {
+	case l == "":
+		return ""
+	case depositLabelRE.MatchString(l):
+		return models.AddressTypeDeposit
+	case strings.Contains(l, "cold") || strings.Contains(l, "冷"):
+		return models.AddressTypeCold
+	case strings.Contains(l, "hot") || strings.Contains(l, "热"):
+		return models.AddressTypeHot
+	case strings.Contains(l, "stak") || strings.Contains(l, "质押"):
+		return models.AddressTypeStaking
+	default:
+		return ""
+	}
+}
+
+// TypeIndex chain|address -> 地址类型，用于给扫描出的事件打标签
+type TypeIndex map[string]string
+
+func typeIndexKey(chain, address string) string {
+	return util.NormalizeChainNameLoose(chain) + "|" + strings.ToLower(strings.TrimSpace(address))
+}
+
+// NewTypeIndex 从地址清单构建索引；同一地址出现多次时保留第一个非空类型
+func NewTypeIndex(rows []models.AddressRow) TypeIndex {
+	ix := TypeIndex{}
+	for _, r := range rows {
+		if r.Type == "" {
+			continue
+		}
+		k := typeIndexKey(r.Chain, r.Address)
+		if _, ok := ix[k]; !ok {
+			ix[k] = r.Type
+		}
+	}
+	return ix
+}
+
+// Lookup 查询地址类型，未标注返回空串
+func (ix TypeIndex) Lookup(chain, address string) string {
+	return ix[typeIndexKey(chain, address)]
+}
+
+// Tag 按命中的监控地址给事件填充 AddressType（已有值的不覆盖）
+func (ix TypeIndex) Tag(events []models.Event) {
+	if len(ix) == 0 {
+		return
+	}
+	for i := range events {
+		if events[i].AddressType == "" {
+			events[i].AddressType = ix.Lookup(events[i].Chain, events[i].Address)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package addr
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBinancePORTypesPropagateToEvents PoR 清单中的 type 列被归一后写入 AddressRow，并通过索引打到事件上
+func TestBinancePORTypesPropagateToEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wallet_address.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("wallet_address.csv")
+	_, _ = w.Write([]byte("coin,network,address,type\n" +
+		"ETH,ETH,0xAbC0000000000000000000000000000000000001,Cold Wallet\n" +
+		"ETH,ETH,0xabc0000000000000000000000000000000000002,hot\n" +
+		"BTC,BTC,bc1qdeposit,Deposit\n" +
+		"BTC,BTC,bc1qplain,\n"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	util.SetAllowed("BTC,ETH")
+	rows, err := RowsFromBinancePORZip(path, "binance", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTypes := []string{models.AddressTypeCold, models.AddressTypeHot, models.AddressTypeDeposit, ""}
+	if len(rows) != len(wantTypes) {
+		t.Fatalf("期望 %d 行，实际 %d", len(wantTypes), len(rows))
+	}
+	for i, r := range rows {
+		if r.Type != wantTypes[i] {
+			t.Errorf("第%d行类型期望 %q，实际 %q", i, wantTypes[i], r.Type)
+		}
+	}
+
+	// 不包含充值地址时按归一后的类型过滤
+	noDeposit, _ := RowsFromBinancePORZip(path, "binance", false)
+	if len(noDeposit) != 3 {
+		t.Errorf("排除充值地址后应剩 3 行，实际 %d", len(noDeposit))
+	}
+
+	// 扫描器上报的事件：EVM 地址小写、链名可能是别名
+	events := []models.Event{
+		{Chain: "ethereum", Address: "0xabc0000000000000000000000000000000000001", Direction: "out"},
+		{Chain: "eth", Address: "0xABC0000000000000000000000000000000000002", Direction: "out"},
+		{Chain: "bitcoin", Address: "bc1qdeposit", Direction: "in"},
+		{Chain: "bitcoin", Address: "bc1qplain", Direction: "in"},
+		{Chain: "bitcoin", Address: "bc1qdeposit", Direction: "in", AddressType: models.AddressTypeHot},
+	}
+	NewTypeIndex(rows).Tag(events)
+	want := []string{models.AddressTypeCold, models.AddressTypeHot, models.AddressTypeDeposit, "", models.AddressTypeHot}
+	for i, e := range events {
+		if e.AddressType != want[i] {
+			t.Errorf("事件%d类型期望 %q，实际 %q", i, want[i], e.AddressType)
+		}
+	}
+}
+
+// TestNormalizeAddressType 中英文标签归一
+func TestNormalizeAddressType(t *testing.T) {
+	cases := map[string]string{
+		"Deposit":        models.AddressTypeDeposit,
+		"充值地址":           models.AddressTypeDeposit,
+		"cold_wallet":    models.AddressTypeCold,
+		"冷钱包":            models.AddressTypeCold,
+		"HOT":            models.AddressTypeHot,
+		"ETH Staking":    models.AddressTypeStaking,
+		"":               "",
+		"exchange owned": "",
+	}
+	for in, want := range cases {
+		if got := NormalizeAddressType(in); got != want {
+			t.Errorf("NormalizeAddressType(%q) = %q，期望 %q", in, got, want)
+		}
+	}
+}
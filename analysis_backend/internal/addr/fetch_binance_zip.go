@@ -0,0 +1,143 @@
+package addr
+
+import (
+	"analysis/internal/util"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FetchBinancePORZip 下载 Binance PoR 地址 zip 到 cacheDir，返回本地路径，可直接交给 RowsFromBinancePORZip 解析。
+//   - 按 URL 中的文件名缓存：本地已有同名完整文件时不再下载
+//   - 下载中断时保留 .part 文件，重试时通过 Range 续传；服务端不支持 Range 则从头下载
+//   - 下载完成后校验 zip 结构，损坏则删除并重新下载
+//
+// retry 为 nil 时使用 util.DefaultRetryConfig()。
+func FetchBinancePORZip(ctx context.Context, zipURL, cacheDir string, retry *util.RetryConfig) (string, error) {
+	u, err := url.Parse(zipURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid binance por url %q: %w", zipURL, err)
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || !strings.HasSuffix(strings.ToLower(name), ".zip") {
+		return "", fmt.Errorf("binance por url must point to a .zip file: %s", zipURL)
+	}
+	if cacheDir == "" {
+		cacheDir = "."
+	}
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(cacheDir, name)
+	if st, err := os.Stat(dst); err == nil && st.Size() > 0 {
+		log.Printf("[addr] binance por zip cached: %s (%d bytes)", dst, st.Size())
+		return dst, nil
+	}
+
+	if retry == nil {
+		cfg := util.DefaultRetryConfig()
+		retry = &cfg
+	}
+	start := time.Now()
+	if err := util.Retry(ctx, func() error { return downloadWithResume(ctx, zipURL, dst) }, retry); err != nil {
+		return "", fmt.Errorf("download binance por zip: %w", err)
+	}
+	st, _ := os.Stat(dst)
+	log.Printf("[addr] binance por zip downloaded: %s (%d bytes, %s)", dst, st.Size(), time.Since(start).Round(time.Millisecond))
+	return dst, nil
+}
+
+// downloadWithResume 单次下载尝试：从 dst.part 已有字节处续传，完整且校验通过后重命名为 dst
+func downloadWithResume(ctx context.Context, zipURL, dst string) error {
+	part := dst + ".part"
+	var offset int64
+	if st, err := os.Stat(part); err == nil {
+		offset = st.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, zipURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "por-collector")
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start := contentRangeStart(resp.Header.Get("Content-Range")); start != offset {
+			_ = os.Remove(part)
+			return &util.RetryableError{Err: fmt.Errorf("unexpected content-range %q for offset %d", resp.Header.Get("Content-Range"), offset), Retryable: true}
+		}
+		flags |= os.O_APPEND
+		log.Printf("[addr] resuming binance por download at %d bytes", offset)
+	case http.StatusOK:
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// .part 已是完整文件（上次在重命名前中断）
+		return finalizeZip(part, dst)
+	default:
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s => %d: %s", zipURL, resp.StatusCode, string(b))
+	}
+
+	f, err := os.OpenFile(part, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	n, copyErr := io.Copy(f, resp.Body)
+	if err := f.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return copyErr
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return io.ErrUnexpectedEOF
+	}
+	return finalizeZip(part, dst)
+}
+
+// finalizeZip 校验下载的 zip 后重命名为最终文件；损坏则删除 .part 以便下次重新下载
+func finalizeZip(part, dst string) error {
+	zr, err := zip.OpenReader(part)
+	if err != nil {
+		_ = os.Remove(part)
+		return &util.RetryableError{Err: fmt.Errorf("downloaded binance por zip is invalid: %w", err), Retryable: true}
+	}
+	zr.Close()
+	return os.Rename(part, dst)
+}
+
+// contentRangeStart 解析 "bytes start-end/total" 中的 start，无法解析时返回 -1
+func contentRangeStart(v string) int64 {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "bytes ")
+	i := strings.IndexByte(v, '-')
+	if i <= 0 {
+		return -1
+	}
+	start, err := strconv.ParseInt(v[:i], 10, 64)
+	if err != nil {
+		return -1
+	}
+	return start
+}
@@ -0,0 +1,126 @@
+package addr
+
+import (
+	"analysis/internal/util"
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// buildPORZip 生成一个包含地址 CSV 的 PoR zip；填充足够多的行，保证中断时只传了一部分
+func buildPORZip(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "wallet_address.csv", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte("coin,network,address\n"))
+	for i := 0; i < 200; i++ {
+		_, _ = w.Write([]byte("BTC,BTC,bc1qexampleaddress" + strconv.Itoa(i) + "\n"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestFetchBinancePORZipResume 第一次下载传到一半断开，重试时带 Range 续传，结果与原文件一致；再次调用命中缓存
+func TestFetchBinancePORZipResume(t *testing.T) {
+	content := buildPORZip(t)
+	half := len(content) / 2
+
+	var mu sync.Mutex
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		if first {
+			// 声明完整长度但只写一半后断开连接
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(content[:half])
+			if hj, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hj.Hijack(); err == nil {
+					conn.Close()
+				}
+			}
+			return
+		}
+		http.ServeContent(w, r, "wallet_address_20250901.zip", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	cacheDir := t.TempDir()
+	retry := &util.RetryConfig{MaxRetries: 2, InitialDelay: time.Millisecond, MaxDelay: time.Millisecond, Multiplier: 1}
+	url := srv.URL + "/por/wallet_address_20250901.zip"
+
+	got, err := FetchBinancePORZip(context.Background(), url, cacheDir, retry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != filepath.Join(cacheDir, "wallet_address_20250901.zip") {
+		t.Errorf("缓存路径不符: %s", got)
+	}
+	data, err := os.ReadFile(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatalf("续传后文件内容不一致: got %d bytes, want %d", len(data), len(content))
+	}
+	if _, err := os.Stat(got + ".part"); !os.IsNotExist(err) {
+		t.Error("下载完成后不应残留 .part 文件")
+	}
+
+	mu.Lock()
+	if len(ranges) != 2 || ranges[0] != "" {
+		t.Fatalf("期望一次完整请求加一次续传请求，实际 Range 记录 %q", ranges)
+	}
+	if want := "bytes=" + strconv.Itoa(half) + "-"; ranges[1] != want {
+		t.Errorf("重试应从已下载位置续传，期望 Range=%q，实际 %q", want, ranges[1])
+	}
+	mu.Unlock()
+
+	// 解析结果与直接读取原始 zip 一致
+	util.SetAllowed("BTC")
+	local := filepath.Join(t.TempDir(), "local.zip")
+	if err := os.WriteFile(local, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := RowsFromBinancePORZip(local, "binance", false)
+	rows, err := RowsFromBinancePORZip(got, "binance", false)
+	if err != nil || len(rows) != 200 || !reflect.DeepEqual(rows, want) {
+		t.Errorf("解析结果不符: rows=%d err=%v", len(rows), err)
+	}
+
+	// 再次调用命中缓存，不发请求
+	if _, err := FetchBinancePORZip(context.Background(), url, cacheDir, retry); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(ranges) != 2 {
+		t.Errorf("命中缓存时不应再次下载，请求数 %d", len(ranges))
+	}
+}
+
+// TestFetchBinancePORZipRejectsNonZipURL URL 不是 zip 文件时直接报错
+func TestFetchBinancePORZipRejectsNonZipURL(t *testing.T) {
+	if _, err := FetchBinancePORZip(context.Background(), "https://example.com/por/", t.TempDir(), nil); err == nil {
+		t.Error("非 zip URL 应返回错误")
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"strings"
 )
 
+// depositLabelRE 匹配充值地址的类型标签
+var depositLabelRE = regexp.MustCompile(`(?i)(deposit|充值|收款|充币|入金)`)
+
 func RowsFromBinancePORZip(zipPath, entity string, includeDeposit bool) ([]models.AddressRow, error) {
 	if zipPath == "" {
 		return nil, nil
@@ -22,7 +25,6 @@ func RowsFromBinancePORZip(zipPath, entity string, includeDeposit bool) ([]model
 	defer r.Close()
 
 	var out []models.AddressRow
-	depRE := regexp.MustCompile(`(?i)(deposit|充值|收款|充币|入金)`)
 
 	for _, f := range r.File {
 		name := strings.ToLower(f.Name)
@@ -84,16 +86,19 @@ func RowsFromBinancePORZip(zipPath, entity string, includeDeposit bool) ([]model
 			if addr == "" || chain == "" {
 				continue
 			}
-			if !includeDeposit && it >= 0 {
-				if depRE.MatchString(strings.TrimSpace(row[it])) {
-					continue
-				}
+			typ := ""
+			if it >= 0 && it < len(row) {
+				typ = NormalizeAddressType(row[it])
+			}
+			if !includeDeposit && typ == models.AddressTypeDeposit {
+				continue
 			}
 			out = append(out, models.AddressRow{
 				Entity:  entity,
 				Chain:   chain,
 				Address: addr,
 				Source:  f.Name,
+				Type:    typ,
 			})
 		}
 	}
@@ -4,10 +4,19 @@ import (
 	"analysis/internal/config"
 	"analysis/internal/models"
 	"analysis/internal/util"
+	"log"
 )
 
 func RowsFromConfig(cfg config.Config) []models.AddressRow {
+	rows, _ := RowsAndXPubsFromConfig(cfg)
+	return rows
+}
+
+// RowsAndXPubsFromConfig 同 RowsFromConfig；networks.bitcoin 中的扩展公钥/描述符展开为派生地址，
+// 另返回各实体的 XPubSet（实体名 -> 集合），扫描中据此继续派生。无法解析的扩展公钥记日志后跳过
+func RowsAndXPubsFromConfig(cfg config.Config) ([]models.AddressRow, map[string]*XPubSet) {
 	var out []models.AddressRow
+	xpubs := map[string]*XPubSet{}
 	for _, e := range cfg.Entities {
 		for net, addrs := range e.Networks {
 			chain := util.NormalizeChainNameLoose(net)
@@ -16,6 +25,22 @@ func RowsFromConfig(cfg config.Config) []models.AddressRow {
 				if a == "" {
 					continue
 				}
+				if chain == "bitcoin" && IsXPubSpec(a) {
+					set := xpubs[e.Name]
+					if set == nil {
+						set = NewXPubSet(e.XPubGap)
+					}
+					derived, err := set.Add(a)
+					if err != nil {
+						log.Printf("[addr] entity=%s skip bitcoin xpub: %v", e.Name, err)
+						continue
+					}
+					xpubs[e.Name] = set
+					for _, d := range derived {
+						out = append(out, models.AddressRow{Entity: e.Name, Chain: chain, Address: d, Source: "config-xpub"})
+					}
+					continue
+				}
 				out = append(out, models.AddressRow{
 					Entity:  e.Name,
 					Chain:   chain,
@@ -25,7 +50,7 @@ func RowsFromConfig(cfg config.Config) []models.AddressRow {
 			}
 		}
 	}
-	return out
+	return out, xpubs
 }
 
 func stringsTrimSpace(s string) string {
@@ -161,6 +161,7 @@ func parseOneCSV(data []byte, name string, entity string, includeStaking bool) (
 						Chain:   "ethereum",
 						Address: strings.TrimSpace(dep),
 						Source:  name,
+						Type:    models.AddressTypeStaking,
 					})
 					stats.IncludedStakingRows++
 				}
@@ -170,6 +171,7 @@ func parseOneCSV(data []byte, name string, entity string, includeStaking bool) (
 						Chain:   "ethereum",
 						Address: strings.TrimSpace(withd),
 						Source:  name,
+						Type:    models.AddressTypeStaking,
 					})
 					stats.IncludedStakingRows++
 				}
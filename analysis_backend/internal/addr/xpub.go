@@ -0,0 +1,363 @@
+package addr
+
+import (
+	"analysis/internal/util"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// 交易所常公布扩展公钥（xpub/ypub/zpub）而不是成千上万个地址：按 BIP32 非硬化派生 receive(0)/change(1) 两个分支，
+// 按前缀（BIP44 xpub/tpub -> P2PKH，BIP49 ypub/upub -> P2SH-P2WPKH，BIP84 zpub/vpub -> P2WPKH）或描述符
+// （pkh(...)、sh(wpkh(...))、wpkh(...)，可带 [指纹/路径] 与 /0/*、/<0;1>/* 后缀和 #校验和）决定地址类型。
+// 每个分支先派生 gap 个地址；扫描中发现使用了某个地址后，保证其后仍有 gap 个未使用地址（XPubSet.MarkUsed）。
+
+// DefaultXPubGap 未配置 entities[].xpub_gap 时每个分支预先派生的地址数（BIP44 建议的 gap limit）
+const DefaultXPubGap = 20
+
+// 地址脚本类型
+const (
+	ScriptP2PKH      = "p2pkh"
+	ScriptP2SHP2WPKH = "p2sh-p2wpkh"
+	ScriptP2WPKH     = "p2wpkh"
+)
+
+var errXPub = errors.New("invalid extended public key")
+
+// xpubVersions SLIP-0132 版本字节 -> (脚本类型, 是否测试网)；Ypub/Zpub 等多签版本不支持
+var xpubVersions = map[uint32]struct {
+	script  string
+	testnet bool
+}{
+	0x0488B21E: {ScriptP2PKH, false},      // xpub
+	0x049D7CB2: {ScriptP2SHP2WPKH, false}, // ypub
+	0x04B24746: {ScriptP2WPKH, false},     // zpub
+	0x043587CF: {ScriptP2PKH, true},       // tpub
+	0x044A5262: {ScriptP2SHP2WPKH, true},  // upub
+	0x045F1CF6: {ScriptP2WPKH, true},      // vpub
+}
+
+// XPub 解析后的扩展公钥
+type XPub struct {
+	Script   string
+	Testnet  bool
+	Branches []uint32 // 要派生的分支，默认 receive(0) 与 change(1)
+
+	key       []byte // 33 字节压缩公钥
+	chainCode []byte
+}
+
+// IsXPubSpec 粗略判断配置里的 BTC 条目是扩展公钥/描述符而不是普通地址
+func IsXPubSpec(s string) bool {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "(") {
+		return true
+	}
+	if len(s) < 100 {
+		return false
+	}
+	switch s[:4] {
+	case "xpub", "ypub", "zpub", "tpub", "upub", "vpub":
+		return true
+	}
+	return false
+}
+
+// ParseXPub 解析扩展公钥或描述符；描述符的脚本类型优先于前缀
+func ParseXPub(spec string) (*XPub, error) {
+	s := strings.TrimSpace(spec)
+	if i := strings.IndexByte(s, '#'); i >= 0 { // 描述符校验和
+		s = s[:i]
+	}
+	script := ""
+	for _, w := range []struct{ prefix, script string }{
+		{"sh(wpkh(", ScriptP2SHP2WPKH}, {"wpkh(", ScriptP2WPKH}, {"pkh(", ScriptP2PKH},
+	} {
+		if strings.HasPrefix(s, w.prefix) {
+			inner := strings.TrimPrefix(s, w.prefix)
+			closing := strings.Repeat(")", strings.Count(w.prefix, "("))
+			if !strings.HasSuffix(inner, closing) {
+				return nil, fmt.Errorf("%w: unbalanced descriptor %q", errXPub, spec)
+			}
+			s, script = strings.TrimSuffix(inner, closing), w.script
+			break
+		}
+	}
+	if script == "" && strings.Contains(s, "(") {
+		return nil, fmt.Errorf("%w: unsupported descriptor %q (want pkh/wpkh/sh(wpkh))", errXPub, spec)
+	}
+	if strings.HasPrefix(s, "[") { // [指纹/派生路径] 只是来源信息
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return nil, fmt.Errorf("%w: unterminated key origin %q", errXPub, spec)
+		}
+		s = s[i+1:]
+	}
+	key, path, _ := strings.Cut(s, "/")
+
+	raw, ok := util.Base58Decode(key)
+	if !ok || len(raw) != 82 || !bytes.Equal(util.Base58Checksum(raw[:78]), raw[78:]) {
+		return nil, fmt.Errorf("%w: bad base58check %q", errXPub, key)
+	}
+	ver, ok := xpubVersions[binary.BigEndian.Uint32(raw[:4])]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported version %x (private or multisig key?)", errXPub, raw[:4])
+	}
+	x := &XPub{Script: ver.script, Testnet: ver.testnet, Branches: []uint32{0, 1}, chainCode: raw[13:45], key: raw[45:78]}
+	if script != "" {
+		x.Script = script
+	}
+	if _, err := crypto.DecompressPubkey(x.key); err != nil {
+		return nil, fmt.Errorf("%w: %v", errXPub, err)
+	}
+	if path != "" {
+		branches, err := parseXPubPath(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v in %q", errXPub, err, spec)
+		}
+		x.Branches = branches
+	}
+	return x, nil
+}
+
+// parseXPubPath 描述符中密钥之后的路径：0/*、1/*、<0;1>/*（只支持一层非硬化分支加通配符）
+func parseXPubPath(path string) ([]uint32, error) {
+	branch, rest, ok := strings.Cut(path, "/")
+	if !ok || rest != "*" {
+		return nil, fmt.Errorf("unsupported path %q (want <branch>/*)", path)
+	}
+	var out []uint32
+	for _, b := range strings.Split(strings.Trim(branch, "<>"), ";") {
+		var n uint32
+		if _, err := fmt.Sscanf(b, "%d", &n); err != nil || strings.ContainsAny(b, "'hH") || n >= 1<<31 {
+			return nil, fmt.Errorf("unsupported branch %q (hardened or not a number)", b)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// child 非硬化子公钥派生（BIP32 CKDpub）
+func child(key, chainCode []byte, i uint32) ([]byte, []byte, error) {
+	mac := hmac.New(sha512.New, chainCode)
+	mac.Write(key)
+	_ = binary.Write(mac, binary.BigEndian, i)
+	sum := mac.Sum(nil)
+	il, ir := sum[:32], sum[32:]
+
+	curve := crypto.S256()
+	if new(big.Int).SetBytes(il).Cmp(curve.Params().N) >= 0 {
+		return nil, nil, fmt.Errorf("derive child %d: invalid tweak", i)
+	}
+	pub, err := crypto.DecompressPubkey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, ty := curve.ScalarBaseMult(il)
+	x, y := curve.Add(tx, ty, pub.X, pub.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil, fmt.Errorf("derive child %d: point at infinity", i)
+	}
+	out := make([]byte, 33)
+	out[0] = 0x02 + byte(y.Bit(0))
+	x.FillBytes(out[1:])
+	return out, ir, nil
+}
+
+// Address 派生 branch/index 处的地址
+func (x *XPub) Address(branch, index uint32) (string, error) {
+	k, c, err := child(x.key, x.chainCode, branch)
+	if err != nil {
+		return "", err
+	}
+	k, _, err = child(k, c, index)
+	if err != nil {
+		return "", err
+	}
+	return x.encode(hash160(k))
+}
+
+// Derive 派生 branch 上 [from, to) 的地址
+func (x *XPub) Derive(branch, from, to uint32) ([]string, error) {
+	k, c, err := child(x.key, x.chainCode, branch)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, to-from)
+	for i := from; i < to; i++ {
+		ck, _, err := child(k, c, i)
+		if err != nil {
+			return nil, err
+		}
+		a, err := x.encode(hash160(ck))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func (x *XPub) encode(h []byte) (string, error) {
+	switch x.Script {
+	case ScriptP2PKH:
+		return base58Check(map[bool]byte{false: 0x00, true: 0x6f}[x.Testnet], h), nil
+	case ScriptP2SHP2WPKH:
+		redeem := append([]byte{0x00, 0x14}, h...)
+		return base58Check(map[bool]byte{false: 0x05, true: 0xc4}[x.Testnet], hash160(redeem)), nil
+	case ScriptP2WPKH:
+		return bech32SegwitV0(map[bool]string{false: "bc", true: "tb"}[x.Testnet], h)
+	}
+	return "", fmt.Errorf("unsupported script %q", x.Script)
+}
+
+func hash160(b []byte) []byte {
+	s := sha256.Sum256(b)
+	r := ripemd160.New()
+	r.Write(s[:])
+	return r.Sum(nil)
+}
+
+func base58Check(version byte, payload []byte) string {
+	raw := append([]byte{version}, payload...)
+	return util.Base58Encode(append(raw, util.Base58Checksum(raw)...))
+}
+
+// bech32SegwitV0 BIP173 编码 v0 见证程序
+func bech32SegwitV0(hrp string, program []byte) (string, error) {
+	const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	data := []byte{0}
+	acc, bits := 0, 0
+	for _, b := range program {
+		acc = (acc<<8 | int(b)) & 0x1fff
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			data = append(data, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		data = append(data, byte(acc<<(5-bits)&31))
+	}
+	polymod := func(values []byte) uint32 {
+		gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+		chk := uint32(1)
+		for _, v := range values {
+			top := chk >> 25
+			chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+			for i := 0; i < 5; i++ {
+				if top>>i&1 == 1 {
+					chk ^= gen[i]
+				}
+			}
+		}
+		return chk
+	}
+	var exp []byte
+	for _, c := range hrp {
+		exp = append(exp, byte(c>>5))
+	}
+	exp = append(exp, 0)
+	for _, c := range hrp {
+		exp = append(exp, byte(c&31))
+	}
+	mod := polymod(append(append(exp, data...), 0, 0, 0, 0, 0, 0)) ^ 1
+	var sb strings.Builder
+	sb.WriteString(hrp + "1")
+	for _, d := range data {
+		sb.WriteByte(charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(charset[mod>>(5*(5-i))&31])
+	}
+	return sb.String(), nil
+}
+
+// XPubSet 一个实体的扩展公钥及已派生的地址；扫描发现使用了靠近末尾的地址时继续派生。不加锁，只在扫描主协程使用
+type XPubSet struct {
+	gap     uint32
+	keys    []*XPub
+	derived []map[uint32]uint32 // keys[i] 各分支已派生的数量
+	index   map[string]xpubPos  // 地址 -> 位置
+}
+
+type xpubPos struct {
+	key           int
+	branch, index uint32
+}
+
+// NewXPubSet gap<=0 时使用 DefaultXPubGap
+func NewXPubSet(gap int) *XPubSet {
+	if gap <= 0 {
+		gap = DefaultXPubGap
+	}
+	return &XPubSet{gap: uint32(gap), index: map[string]xpubPos{}}
+}
+
+// Add 解析扩展公钥并在每个分支派生 gap 个地址，返回派生出的地址
+func (s *XPubSet) Add(spec string) ([]string, error) {
+	x, err := ParseXPub(spec)
+	if err != nil {
+		return nil, err
+	}
+	s.keys = append(s.keys, x)
+	s.derived = append(s.derived, map[uint32]uint32{})
+	var out []string
+	for _, b := range x.Branches {
+		addrs, err := s.extend(len(s.keys)-1, b, s.gap)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, addrs...)
+	}
+	return out, nil
+}
+
+func (s *XPubSet) extend(key int, branch, to uint32) ([]string, error) {
+	from := s.derived[key][branch]
+	if to <= from {
+		return nil, nil
+	}
+	addrs, err := s.keys[key].Derive(branch, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range addrs {
+		s.index[a] = xpubPos{key: key, branch: branch, index: from + uint32(i)}
+	}
+	s.derived[key][branch] = to
+	return addrs, nil
+}
+
+// MarkUsed 地址出现在链上时调用：若它之后不足 gap 个已派生地址，继续派生并返回新地址（非本集合地址返回 nil）
+func (s *XPubSet) MarkUsed(address string) []string {
+	p, ok := s.index[address]
+	if !ok {
+		return nil
+	}
+	addrs, err := s.extend(p.key, p.branch, p.index+1+s.gap)
+	if err != nil {
+		return nil
+	}
+	return addrs
+}
+
+// Addresses 当前已派生的全部地址（排序）
+func (s *XPubSet) Addresses() []string {
+	out := make([]string, 0, len(s.index))
+	for a := range s.index {
+		out = append(out, a)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,155 @@
+package addr
+
+import (
+	"analysis/internal/config"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// BIP84 测试向量（助记词 abandon ... about，m/84'/0'/0'）
+const bip84ZPub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+
+// 同一助记词 m/44'/0'/0' 的 xpub
+const bip44XPub = "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"
+
+// TestDeriveBIP84Vector zpub 派生出 BIP84 公布的 receive/change 地址
+func TestDeriveBIP84Vector(t *testing.T) {
+	x, err := ParseXPub(bip84ZPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Script != ScriptP2WPKH || x.Testnet {
+		t.Fatalf("zpub 应为主网 P2WPKH: %+v", x)
+	}
+	recv, err := x.Derive(0, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g"}
+	if strings.Join(recv, ",") != strings.Join(want, ",") {
+		t.Errorf("receive 地址不符: %v", recv)
+	}
+	if c, _ := x.Address(1, 0); c != "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el" {
+		t.Errorf("change 地址不符: %s", c)
+	}
+
+	x, err = ParseXPub(bip44XPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, _ := x.Address(0, 0); a != "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA" {
+		t.Errorf("BIP44 首个地址不符: %s", a)
+	}
+}
+
+// TestXPubScriptsAndNetworks 描述符决定脚本类型；测试网前缀编码 tb1/2/m 地址；BIP49 向量
+func TestXPubScriptsAndNetworks(t *testing.T) {
+	// 描述符：带来源信息、分支通配符与校验和，脚本类型以描述符为准
+	x, err := ParseXPub("wpkh([73c5da0a/84'/0'/0']" + bip44XPub + "/0/*)#abcd1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Script != ScriptP2WPKH || len(x.Branches) != 1 || x.Branches[0] != 0 {
+		t.Errorf("描述符解析不符: %+v", x)
+	}
+	if a, _ := x.Address(0, 0); !strings.HasPrefix(a, "bc1q") {
+		t.Errorf("wpkh 描述符应派生 bech32 地址: %s", a)
+	}
+	x, err = ParseXPub("sh(wpkh(" + bip84ZPub + "/<0;1>/*))")
+	if err != nil || x.Script != ScriptP2SHP2WPKH || len(x.Branches) != 2 {
+		t.Fatalf("sh(wpkh) 描述符解析不符: %+v %v", x, err)
+	}
+	if a, _ := x.Address(0, 0); !strings.HasPrefix(a, "3") {
+		t.Errorf("P2SH 主网地址应以 3 开头: %s", a)
+	}
+
+	// BIP49 测试向量：公钥 -> 测试网 P2SH-P2WPKH
+	pk, _ := hex.DecodeString("03a1af804ac108a8a51782198c2d034b28bf90c8803f5a53f76276fa69a4eae77f")
+	if a, _ := (&XPub{Script: ScriptP2SHP2WPKH, Testnet: true}).encode(hash160(pk)); a != "2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2" {
+		t.Errorf("BIP49 向量不符: %s", a)
+	}
+	if a, _ := (&XPub{Script: ScriptP2WPKH, Testnet: true}).encode(hash160(pk)); !strings.HasPrefix(a, "tb1q") {
+		t.Errorf("测试网 P2WPKH 应以 tb1q 开头: %s", a)
+	}
+	if a, _ := (&XPub{Script: ScriptP2PKH, Testnet: true}).encode(hash160(pk)); a[0] != 'm' && a[0] != 'n' {
+		t.Errorf("测试网 P2PKH 应以 m/n 开头: %s", a)
+	}
+
+	for _, bad := range []string{
+		bip84ZPub[:len(bip84ZPub)-1] + "x",     // 校验和错误
+		"tr(" + bip84ZPub + ")",                // 不支持的描述符
+		"wpkh(" + bip84ZPub + "/0'/*)",         // 硬化分支无法由公钥派生
+		"wpkh(" + bip84ZPub,                    // 括号不匹配
+		"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z", // 普通地址
+	} {
+		if _, err := ParseXPub(bad); err == nil {
+			t.Errorf("%q 应报错", bad)
+		}
+	}
+	if !IsXPubSpec(bip84ZPub) || !IsXPubSpec("wpkh("+bip84ZPub+")") || IsXPubSpec("bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu") {
+		t.Error("IsXPubSpec 判断不符")
+	}
+}
+
+// TestXPubSetGapExtension 每个分支先派生 gap 个；用到第 i 个地址后保证其后仍有 gap 个
+func TestXPubSetGapExtension(t *testing.T) {
+	s := NewXPubSet(3)
+	addrs, err := s.Add(bip84ZPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 6 || addrs[0] != "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu" {
+		t.Fatalf("receive+change 各 3 个: %v", addrs)
+	}
+	if more := s.MarkUsed(addrs[0]); len(more) != 1 {
+		t.Errorf("receive/0 已用，应补 1 个（保持 3 个未用）: %v", more)
+	}
+	if more := s.MarkUsed(addrs[0]); len(more) != 0 {
+		t.Errorf("重复标记不应再派生: %v", more)
+	}
+	if more := s.MarkUsed(addrs[5]); len(more) != 3 {
+		t.Errorf("change/2 已用，应补 3 个: %v", more)
+	}
+	if s.MarkUsed("bc1qunrelated") != nil {
+		t.Error("非本集合的地址不应派生")
+	}
+	if n := len(s.Addresses()); n != 10 {
+		t.Errorf("共应派生 10 个地址，实际 %d", n)
+	}
+}
+
+// TestRowsFromConfigExpandsXPub networks.bitcoin 中的 zpub 展开为派生地址，普通地址照常保留，无效的扩展公钥跳过
+func TestRowsFromConfigExpandsXPub(t *testing.T) {
+	var cfg config.Config
+	cfg.Entities = []config.EntityCfg{{
+		Name:    "binance",
+		XPubGap: 2,
+		Networks: map[string][]string{
+			"BTC": {bip84ZPub, "bc1qplain", "xpub" + strings.Repeat("1", 107)},
+			"ETH": {"0xabc"},
+		},
+	}}
+	rows, xpubs := RowsAndXPubsFromConfig(cfg)
+	var derived, plain int
+	for _, r := range rows {
+		switch r.Source {
+		case "config-xpub":
+			derived++
+			if r.Chain != "bitcoin" || r.Entity != "binance" {
+				t.Errorf("派生地址行不符: %+v", r)
+			}
+		case "config":
+			plain++
+		}
+	}
+	if derived != 4 || plain != 2 {
+		t.Errorf("期望 4 个派生地址（2 分支 × gap 2）+ 2 个普通地址，实际 %d + %d", derived, plain)
+	}
+	if xpubs["binance"] == nil || len(xpubs["binance"].Addresses()) != 4 {
+		t.Errorf("应返回实体的扩展公钥集合: %+v", xpubs)
+	}
+	if n := len(RowsFromConfig(cfg)); n != 6 {
+		t.Errorf("RowsFromConfig 也应展开，实际 %d 行", n)
+	}
+}
@@ -4,7 +4,9 @@ package main
 import (
 	"analysis/internal/config"
 	pdb "analysis/internal/db"
+	"analysis/internal/price"
 	"analysis/internal/server"
+	"analysis/internal/util"
 	"context"
 	"flag"
 	"fmt"
@@ -25,7 +27,16 @@ var (
 	pmTo          = flag.String("pm-to", "", "Comma-separated recipients")
 	pmStream      = flag.String("pm-stream", "outbound", "Postmark message stream")
 
-	xBearer = flag.String("x-bearer", "", "Twitter/X API Bearer token (can also be set via TWITTER_BEARER_TOKEN env var)")
+	reserveDropPct     = flag.Float64("reserve-drop-pct", 0, "alert when an entity's reserve USD falls by this percent within -reserve-drop-window (0 disables)")
+	reserveDropWindow  = flag.Duration("reserve-drop-window", 24*time.Hour, "look-back window for reserve drop alerts")
+	reserveDropWebhook = flag.String("reserve-drop-webhook", "", "optional webhook URL receiving reserve drop alerts as JSON")
+
+	ingestNetFlow      = flag.Bool("ingest-net-flow", false, "store a per-entity per-coin net flow summary (in - out) for every ingested window and return it in the ingest response")
+	ingestUSDValue     = flag.Bool("ingest-usd-value", false, "tag ingested transfer events with usd_value (amount x price at the event time, cached; needs pricing.enable)")
+	porOnly            = flag.String("por-only", "BTC, ETH, USDT, USDC, SOL", "symbols included by POST /admin/por/run (same as cmd/por -only; entity coin scopes in config still apply)")
+	portfolioLiveDelta = flag.Bool("portfolio-live-delta", false, "apply ingested transfer events to the latest portfolio snapshot immediately and invalidate /portfolio/latest cache")
+
+	xBearer = flag.String("x-bearer", "", "Twitter/X API Bearer token(s), comma separated for rotation (can also be set via TWITTER_BEARER_TOKENS / TWITTER_BEARER_TOKEN env var)")
 )
 
 // cleanupZombieStrategies 清理后端重启时状态为running或pending但实际已停止的僵尸策略
@@ -112,6 +123,7 @@ func main() {
 	config.ApplyProxy(&cfg)
 
 	gdb, err := pdb.OpenMySQL(pdb.Options{
+		Driver:       cfg.Database.Driver,
 		DSN:          cfg.Database.DSN,
 		Automigrate:  cfg.Database.Automigrate,
 		MaxOpenConns: cfg.Database.MaxOpenConns,
@@ -242,22 +254,47 @@ func main() {
 		api.Mailer = server.NewPostmarkMailer(*pmServerToken, *pmFrom, recipients, *pmStream)
 	}
 
+	// 交易所储备下跌告警：基于组合快照，需要数据库
+	if *reserveDropPct > 0 && gdb != nil {
+		monitor := server.NewReserveDropMonitor(gdb.GormDB(), server.ReserveDropConfig{
+			DropPercent: *reserveDropPct,
+			Window:      *reserveDropWindow,
+		})
+		monitor.Mailer = api.Mailer
+		monitor.WebhookURL = strings.TrimSpace(*reserveDropWebhook)
+		monitorCtx, stopMonitor := context.WithCancel(context.Background())
+		defer stopMonitor()
+		go monitor.Run(monitorCtx)
+	}
+
+	// 手动触发 PoR（POST /admin/por/run）：币种范围是进程级设置，启动时按 cmd/por 的方式设置一次
+	if gdb != nil {
+		util.SetAllowed(*porOnly)
+		for ent, coins := range cfg.EntityCoinScopes() {
+			util.SetEntityAllowed(ent, coins)
+		}
+		porCtx, stopPOR := context.WithCancel(context.Background())
+		defer stopPOR()
+		api.SetPORRunner(server.NewPORRunner(porCtx, server.NewPORCompute(gdb.GormDB(), cfg)))
+	}
+
 	// 优化：安全地获取 Twitter Bearer Token（优先级：命令行参数 > 环境变量 > 配置文件）
-	api.XBearer = strings.TrimSpace(*xBearer)
-	if api.XBearer == "" {
+	// 每一级都支持逗号分隔的多个 token，遇到 429 时轮换
+	bearers := server.ParseBearerTokens(*xBearer)
+	if len(bearers) == 0 {
 		// 尝试从环境变量获取
-		if envToken := os.Getenv("TWITTER_BEARER_TOKEN"); envToken != "" {
-			api.XBearer = strings.TrimSpace(envToken)
-		}
+		bearers = server.ParseBearerTokens(os.Getenv("TWITTER_BEARER_TOKENS") + "," + os.Getenv("TWITTER_BEARER_TOKEN"))
 	}
-	if api.XBearer == "" {
+	if len(bearers) == 0 {
 		// 最后尝试从配置文件获取
-		if cfg.Twitter.Bearer != "" {
-			api.XBearer = cfg.Twitter.Bearer
-		}
+		bearers = append(server.ParseBearerTokens(cfg.Twitter.Bearer), cfg.Twitter.Bearers...)
 	}
-	// 如果仍然为空，记录警告但不强制退出（某些功能可能不需要 Twitter API）
-	if api.XBearer == "" {
+	api.XBearers = server.NewTwitterBearerPool(bearers)
+	if api.XBearers.Len() > 0 {
+		api.XBearer = bearers[0]
+		log.Printf("Twitter bearer pool: %d token(s)", api.XBearers.Len())
+	} else {
+		// 如果仍然为空，记录警告但不强制退出（某些功能可能不需要 Twitter API）
 		log.Printf("[WARN] Twitter Bearer Token not configured. Twitter-related features may not work.")
 	}
 
@@ -288,6 +325,8 @@ func main() {
 	c.AllowHeaders = []string{"Authorization", "Content-Type", "Origin"}
 	c.AllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions}
 	r.Use(cors.New(c))
+	// 响应压缩放在 CORS 之后：预检请求由 CORS 直接返回，不经过压缩
+	r.Use(server.GzipMiddleware(server.DefaultGzipMinSize))
 
 	// 健康检查
 	r.GET("/healthz", func(ctx *gin.Context) {
@@ -298,11 +337,26 @@ func main() {
 	r.POST("/auth/register", api.Register)
 	r.POST("/auth/login", api.Login)
 	r.GET("/me", api.JWTAuth(), api.Me)
+	r.GET("/me/export", api.JWTAuth(), api.ExportMyData)
 
 	// cursor & ingest events
+	// WS 转账确认数阈值：chains[].confirmations
+	confirmations := map[string]uint64{}
+	for _, ch := range cfg.Chains {
+		confirmations[ch.Name] = ch.Confirmations
+	}
+	server.SetTransferConfirmations(confirmations)
 	r.GET("/sync/cursor", server.GetCursor(gdb.GormDB()))
-	r.POST("/sync/cursor", server.GetCursor(gdb.GormDB()))
-	r.POST("/ingest/events", server.IngestEvents(gdb.GormDB()))
+	r.POST("/sync/cursor", server.SetCursor(gdb.GormDB()))
+	ingestOpts := server.IngestOptions{PortfolioDelta: *portfolioLiveDelta, NetFlow: *ingestNetFlow, Cache: cache, Chains: config.BuildChainCfg(&cfg)}
+	if *ingestUSDValue {
+		if !cfg.Pricing.Enable {
+			log.Printf("[ingest] -ingest-usd-value set but pricing.enable is false, usd_value stays empty")
+		}
+		ingestOpts.Prices = price.NewCache(cfg, 5*time.Minute)
+	}
+	r.POST("/ingest/events", server.IngestEvents(gdb.GormDB(), ingestOpts))
+	r.POST("/ingest/canonical", server.IngestCanonicalBlocks(gdb.GormDB(), ingestOpts))
 
 	r.POST("/ingest/binance/market", api.IngestBinanceMarket)
 
@@ -347,6 +401,8 @@ func main() {
 		server.CacheMiddleware(cache, pdb.CacheTypeAggregate, 5*time.Minute, server.AnnouncementsCacheKey),
 		api.ListAnnouncements)
 	r.GET("/announcements/latest-time", api.GetLatestAnnouncementTime)
+	r.GET("/announcements/search", api.SearchAnnouncements)
+	r.GET("/announcements/impact", api.ListAnnouncementImpacts)
 
 	// 推荐接口（临时公开用于测试）
 	r.GET("/recommendations/coins", api.GetCoinRecommendations)
@@ -458,6 +514,7 @@ func main() {
 		priv.GET("/flows/weekly", api.GetWeeklyFlows)
 		priv.GET("/flows/daily_by_chain", api.GetDailyFlowsByChain)
 		priv.GET("/transfers/recent", server.ListTransfers(api))
+		priv.GET("/transfers/flows", server.TransferFlows(api))
 		priv.GET("/whales/arkham", server.ListArkhamWatches(api))
 		priv.POST("/whales/arkham", server.CreateArkhamWatch(api))
 		priv.POST("/whales/arkham/query", server.QueryArkhamAddress(api))
@@ -613,6 +670,10 @@ func main() {
 		priv.POST("/cache/warmup", api.WarmupCache)
 		priv.POST("/cache/clear", api.ClearCache)
 		priv.POST("/cache/invalidate/user/:userId", api.InvalidateUserCache)
+		priv.GET("/admin/cache/keys", api.ListCacheKeys)
+		priv.POST("/admin/cache/flush", api.FlushCache)
+		priv.POST("/admin/por/run", api.TriggerPORRun)
+		priv.POST("/announcements/impact/refresh", api.RefreshAnnouncementImpacts)
 
 		// Data preprocessing and caching routes
 		priv.GET("/data/cache/stats", api.GetDataCacheStats)
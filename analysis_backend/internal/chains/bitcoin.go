@@ -1,6 +1,7 @@
 package chains
 
 import (
+	"analysis/internal/config"
 	"analysis/internal/flow"
 	"analysis/internal/models"
 	"analysis/internal/netutil"
@@ -9,8 +10,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"math/big"
 	"net/http"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -214,6 +217,84 @@ func btcListTxs(ctx context.Context, esplora, addr string, start, end time.Time)
 	return all, nil
 }
 
+// EsploraBlockTxs 按 paging 从单个 Esplora 端点分页读取区块全部交易。
+// 各后端实际页大小不一（10/25 或整块一次返回），偏移按每页实际返回条数推进，直到空页；
+// 能读到区块声明的 tx_count 时读满即停并以其为上限，后端忽略偏移重复返回同一页时也停止。
+// get 为空时使用 netutil.GetJSON。任一页读取失败或读到的交易少于 tx_count 时返回错误；
+// 只有超过 MaxOffset 会返回部分结果，此时 truncated 为 true，由调用方决定如何处理。
+func EsploraBlockTxs[T any](ctx context.Context, esplora, blockHash string, paging config.EsploraPaging,
+	get func(ctx context.Context, url string, out any) error) (txs []T, truncated bool, err error) {
+	if get == nil {
+		get = netutil.GetJSON
+	}
+	pageSize := paging.PageSize
+	if pageSize <= 0 {
+		pageSize = config.DefaultEsploraPageSize
+	}
+	configured := pageSize
+	base := strings.TrimRight(strings.TrimSpace(esplora), "/")
+	declared := esploraBlockTxCount(ctx, base, blockHash, get)
+
+	// complete 翻页结束：有声明交易数时核对是否读满
+	complete := func(all []T) ([]T, bool, error) {
+		if declared > 0 && len(all) < declared {
+			return nil, false, fmt.Errorf("esplora block %s: read %d of %d txs (%s)", blockHash, len(all), declared, base)
+		}
+		return all, false, nil
+	}
+
+	var all []T
+	var prev []T
+	for offset := 0; ; offset += len(prev) {
+		if declared > 0 && len(all) >= declared {
+			return all[:declared], false, nil
+		}
+		if paging.MaxOffset > 0 && offset > paging.MaxOffset {
+			log.Printf("[esplora] block %s 交易被截断: 偏移 %d 超过 max_offset=%d，已读 %d 笔 (%s)",
+				blockHash, offset, paging.MaxOffset, len(all), base)
+			return all, true, nil
+		}
+		u := fmt.Sprintf("%s/block/%s/txs", base, blockHash)
+		if offset > 0 {
+			u = fmt.Sprintf("%s/block/%s/txs/%d", base, blockHash, offset)
+		}
+		var page []T
+		if err := get(ctx, u, &page); err != nil {
+			// 没有声明交易数且上一页不足一页（按配置或首页条数）时，多数后端对越界偏移直接报错，视为已读完
+			if offset > 0 && declared <= 0 && (len(prev) < configured || len(prev) < pageSize) {
+				return all, false, nil
+			}
+			return nil, false, fmt.Errorf("esplora block %s txs at offset %d (%s): %w", blockHash, offset, base, err)
+		}
+		if len(page) == 0 {
+			return complete(all)
+		}
+		if offset == 0 {
+			// 以首页实际条数为准，用于判断末页
+			if len(page) != pageSize {
+				log.Printf("[esplora] block %s 首页返回 %d 笔，与配置页大小 %d 不同 (%s)", blockHash, len(page), pageSize, base)
+			}
+			pageSize = len(page)
+		} else if reflect.DeepEqual(page[0], prev[0]) {
+			// 后端忽略偏移（整块一次返回等），继续翻页只会重复同一页
+			return complete(all)
+		}
+		all = append(all, page...)
+		prev = page
+	}
+}
+
+// esploraBlockTxCount 区块声明的交易数（/block/{hash} 的 tx_count），读取失败返回 0
+func esploraBlockTxCount(ctx context.Context, base, blockHash string, get func(ctx context.Context, url string, out any) error) int {
+	var blk struct {
+		TxCount int `json:"tx_count"`
+	}
+	if err := get(ctx, fmt.Sprintf("%s/block/%s", base, blockHash), &blk); err != nil {
+		return 0
+	}
+	return blk.TxCount
+}
+
 func BTCFlows(ctx context.Context, esplora, addr string, start, end time.Time, wb models.WeeklyBucket, db models.DailyBucket) error {
 	txs, err := btcListTxs(ctx, esplora, addr, start, end)
 	if err != nil {
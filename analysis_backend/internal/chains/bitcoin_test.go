@@ -0,0 +1,254 @@
+package chains
+
+import (
+	"analysis/internal/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testTx struct {
+	Txid string `json:"txid"`
+}
+
+// esploraBlockServer 模拟 Esplora 区块交易分页接口：/block/{hash}/txs[/{offset}]，每页 pageSize 条
+func esploraBlockServer(t *testing.T, total, pageSize int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) < 3 || parts[0] != "block" || parts[2] != "txs" {
+			http.NotFound(w, r)
+			return
+		}
+		offset := 0
+		if len(parts) == 4 {
+			offset, _ = strconv.Atoi(parts[3])
+		}
+		if offset%pageSize != 0 {
+			http.Error(w, "invalid offset", http.StatusBadRequest)
+			return
+		}
+		page := []testTx{}
+		for i := offset; i < total && i < offset+pageSize; i++ {
+			page = append(page, testTx{Txid: fmt.Sprintf("tx%d", i)})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+}
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+// TestEsploraBlockTxsReadsAllPages 区块交易超过一页时按端点页大小翻页读完，不记录截断
+func TestEsploraBlockTxsReadsAllPages(t *testing.T) {
+	srv := esploraBlockServer(t, 205, 50)
+	defer srv.Close()
+	logs := captureLog(t)
+
+	paging := config.ChainCfg{EsploraPaging: []config.EsploraPaging{{Endpoint: srv.URL + "/", PageSize: 50}}}.EsploraPagingFor(srv.URL)
+	if paging.PageSize != 50 || paging.MaxOffset != config.DefaultEsploraMaxOffset {
+		t.Fatalf("端点分页参数不符: %+v", paging)
+	}
+	txs, _, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000abc", paging, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 205 {
+		t.Fatalf("期望读到 205 笔交易，实际 %d", len(txs))
+	}
+	for i, tx := range txs {
+		if tx.Txid != fmt.Sprintf("tx%d", i) {
+			t.Fatalf("第%d笔交易顺序不符: %s", i, tx.Txid)
+		}
+	}
+	if strings.Contains(logs.String(), "截断") {
+		t.Errorf("完整读取时不应记录截断: %s", logs.String())
+	}
+}
+
+// TestEsploraBlockTxsLogsTruncation 超过 max_offset 时返回已读部分、报告截断并记录日志
+func TestEsploraBlockTxsLogsTruncation(t *testing.T) {
+	srv := esploraBlockServer(t, 100, 25)
+	defer srv.Close()
+	logs := captureLog(t)
+
+	txs, truncated, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000def", config.EsploraPaging{PageSize: 25, MaxOffset: 50}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !truncated {
+		t.Error("超过 max_offset 时应向调用方报告截断")
+	}
+	if len(txs) != 75 {
+		t.Errorf("偏移 0/25/50 三页共 75 笔，实际 %d", len(txs))
+	}
+	out := logs.String()
+	if !strings.Contains(out, "000def") || !strings.Contains(out, "max_offset=50") || !strings.Contains(out, "已读 75 笔") {
+		t.Errorf("截断日志不符: %q", out)
+	}
+
+	// max_offset < 0 表示不限制
+	logs.Reset()
+	txs, truncated, _ = EsploraBlockTxs[testTx](context.Background(), srv.URL, "000def", config.EsploraPaging{PageSize: 25, MaxOffset: -1}, nil)
+	if len(txs) != 100 || truncated || logs.Len() != 0 {
+		t.Errorf("不限制偏移时应读完 100 笔且无截断日志，实际 %d 笔，日志 %q", len(txs), logs.String())
+	}
+}
+
+// variableEsploraServer 模拟页大小不稳定的 Esplora：按请求次序轮流返回 sizes 中的条数，接受任意偏移；
+// oneShot 时忽略偏移总是返回整块；txCount>0 时 /block/{hash} 返回声明的交易数
+func variableEsploraServer(t *testing.T, total int, sizes []int, oneShot bool, txCount int) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) == 2 && parts[0] == "block" && txCount > 0 {
+			_ = json.NewEncoder(w).Encode(map[string]int{"tx_count": txCount})
+			return
+		}
+		if len(parts) < 3 || parts[0] != "block" || parts[2] != "txs" {
+			http.NotFound(w, r)
+			return
+		}
+		requests++
+		offset, size := 0, total
+		if len(parts) == 4 {
+			offset, _ = strconv.Atoi(parts[3])
+		}
+		if oneShot {
+			offset = 0
+		} else {
+			size = sizes[(requests-1)%len(sizes)]
+		}
+		page := []testTx{}
+		for i := offset; i < total && i < offset+size; i++ {
+			page = append(page, testTx{Txid: fmt.Sprintf("tx%d", i)})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	})), &requests
+}
+
+// TestEsploraBlockTxsVariablePageSize 页大小在 10/25 间变化时按实际返回条数推进偏移，读完全部交易
+func TestEsploraBlockTxsVariablePageSize(t *testing.T) {
+	for _, txCount := range []int{0, 137} {
+		srv, _ := variableEsploraServer(t, 137, []int{25, 10, 10, 25}, false, txCount)
+		logs := captureLog(t)
+
+		txs, _, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000var", config.EsploraPaging{PageSize: 25, MaxOffset: -1}, nil)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txs) != 137 {
+			t.Fatalf("tx_count=%d 期望读到 137 笔交易，实际 %d", txCount, len(txs))
+		}
+		for i, tx := range txs {
+			if tx.Txid != fmt.Sprintf("tx%d", i) {
+				t.Fatalf("第%d笔交易顺序不符: %s", i, tx.Txid)
+			}
+		}
+		if strings.Contains(logs.String(), "截断") {
+			t.Errorf("完整读取时不应记录截断: %s", logs.String())
+		}
+	}
+}
+
+// TestEsploraBlockTxsOneShotBackend 整块一次返回且忽略偏移的后端不会无限翻页
+func TestEsploraBlockTxsOneShotBackend(t *testing.T) {
+	for _, txCount := range []int{0, 300} {
+		srv, requests := variableEsploraServer(t, 300, nil, true, txCount)
+		txs, _, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000one", config.EsploraPaging{PageSize: 25, MaxOffset: -1}, nil)
+		srv.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txs) != 300 {
+			t.Errorf("tx_count=%d 期望 300 笔交易，实际 %d", txCount, len(txs))
+		}
+		// 有声明交易数时读满即停；否则第二页重复首页后停止
+		if want := map[int]int{0: 2, 300: 1}[txCount]; *requests != want {
+			t.Errorf("tx_count=%d 期望请求 %d 页，实际 %d", txCount, want, *requests)
+		}
+	}
+}
+
+// TestEsploraBlockTxsDeclaredCountCap 后端返回超出声明交易数时以 tx_count 为上限
+func TestEsploraBlockTxsDeclaredCountCap(t *testing.T) {
+	srv, _ := variableEsploraServer(t, 60, []int{25}, false, 40)
+	defer srv.Close()
+
+	txs, _, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000cap", config.EsploraPaging{PageSize: 25, MaxOffset: -1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 40 {
+		t.Errorf("应截到声明的 40 笔，实际 %d", len(txs))
+	}
+}
+
+// TestEsploraBlockTxsPageFailure 翻页失败或读到的交易少于 tx_count 时返回错误，不返回部分结果
+func TestEsploraBlockTxsPageFailure(t *testing.T) {
+	paging := config.EsploraPaging{PageSize: 25, MaxOffset: -1}
+	newServer := func(txCount, failAt int, shortAt int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+			if len(parts) == 2 {
+				if txCount <= 0 {
+					http.NotFound(w, r)
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]int{"tx_count": txCount})
+				return
+			}
+			offset := 0
+			if len(parts) == 4 {
+				offset, _ = strconv.Atoi(parts[3])
+			}
+			if offset == failAt {
+				http.Error(w, "upstream timeout", http.StatusBadGateway)
+				return
+			}
+			page := []testTx{}
+			for i := offset; i < 100 && i < offset+25 && (shortAt <= 0 || i < shortAt); i++ {
+				page = append(page, testTx{Txid: fmt.Sprintf("tx%d", i)})
+			}
+			_ = json.NewEncoder(w).Encode(page)
+		}))
+	}
+
+	for _, tc := range []struct {
+		name                     string
+		txCount, failAt, shortAt int
+	}{
+		{"有 tx_count 时中间页失败", 100, 50, 0},
+		{"无 tx_count 时整页之后失败", 0, 50, 0},
+		{"读到的交易少于 tx_count", 100, -1, 60},
+	} {
+		srv := newServer(tc.txCount, tc.failAt, tc.shortAt)
+		txs, truncated, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000bad", paging, nil)
+		srv.Close()
+		if err == nil || txs != nil || truncated {
+			t.Errorf("%s: 期望返回错误且无部分结果，实际 %d 笔 truncated=%v err=%v", tc.name, len(txs), truncated, err)
+		}
+	}
+
+	// 无 tx_count、首页就不足配置页大小：越界偏移报错视为已读完
+	srv := newServer(0, 1, 1)
+	defer srv.Close()
+	if txs, truncated, err := EsploraBlockTxs[testTx](context.Background(), srv.URL, "000small", paging, nil); err != nil || len(txs) != 1 || truncated {
+		t.Errorf("单页小区块应正常读完，实际 %d 笔 truncated=%v err=%v", len(txs), truncated, err)
+	}
+}
@@ -206,8 +206,9 @@ func EVMERC20Flows(ctx context.Context, rpcURL string, token config.TokenERC20,
 	return nil
 }
 
+// ETHNativeFlowsEtherscan ETH 原生资金流；是否在币种范围内由调用方判断（见 util.IsAllowedFor）
 func ETHNativeFlowsEtherscan(ctx context.Context, etherscanKey, rpcURL, addr string, start, end time.Time, wb models.WeeklyBucket, db models.DailyBucket) error {
-	if etherscanKey == "" || !util.IsAllowed("ETH") {
+	if etherscanKey == "" {
 		return nil
 	}
 	rpc, err := gethrpc.DialContext(ctx, rpcURL)
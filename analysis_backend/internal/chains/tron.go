@@ -13,6 +13,7 @@ import (
 	"time"
 )
 
+// TronTRC20 查询 trc20 中各代币余额；币种范围由调用方过滤 trc20（可能是实体级范围，见 util.IsAllowedFor）
 func TronTRC20(ctx context.Context, addr string, trc20 []config.TokenTRC20) (map[string]*big.Int, map[string]int, error) {
 	var t struct {
 		TokenBalances []struct{ TokenId, Balance string } `json:"trc20token_balances"`
@@ -28,13 +29,10 @@ func TronTRC20(ctx context.Context, addr string, trc20 []config.TokenTRC20) (map
 	dec := map[string]int{}
 	for _, it := range t.TokenBalances {
 		if tok, ok := want[strings.ToLower(it.TokenId)]; ok {
-			if !util.IsAllowed(tok.Symbol) {
-				continue
-			}
 			z := new(big.Int)
 			z.SetString(it.Balance, 10)
 			out[tok.Symbol] = z
-			dec[tok.Symbol] = 6
+			dec[tok.Symbol] = tok.DecimalsOr(6)
 		}
 	}
 	return out, dec, nil
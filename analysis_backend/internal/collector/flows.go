@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"analysis/internal/chains"
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"context"
+	"time"
+)
+
+// GroupByEntity 按实体分组地址；实体为空的归入 unknown
+func GroupByEntity(rows []models.AddressRow) map[string][]models.AddressRow {
+	group := map[string][]models.AddressRow{}
+	for _, r := range rows {
+		ent := r.Entity
+		if ent == "" {
+			ent = "unknown"
+		}
+		group[ent] = append(group[ent], r)
+	}
+	return group
+}
+
+// CollectFlows 统计实体地址在 [start, end) 内的链上资金流，累加到 wb / dbkt（可为 nil，map 值传递）。
+// 单个地址查询失败只跳过该地址；EVM 只统计 USDT/USDC，ETH 原生币需要 etherscanKey
+func CollectFlows(ctx context.Context, entity string, rows []models.AddressRow, chainsCfg map[string]config.ChainCfg, start, end time.Time, wb models.WeeklyBucket, dbkt models.DailyBucket, etherscanKey string) {
+	for _, r := range rows {
+		switch r.Chain {
+		case "bitcoin":
+			if util.IsAllowedFor(entity, "BTC") && chainsCfg["bitcoin"].Esplora != "" {
+				_ = chains.BTCFlows(ctx, chainsCfg["bitcoin"].Esplora, r.Address, start, end, wb, dbkt)
+			}
+		case "solana":
+			if util.IsAllowedFor(entity, "SOL") && chainsCfg["solana"].RPC != "" {
+				_ = chains.SolFlowsSOL(ctx, chainsCfg["solana"].RPC, r.Address, start, end, wb, dbkt)
+			}
+			for _, t := range chainsCfg["solana"].SPL {
+				if util.IsAllowedFor(entity, t.Symbol) {
+					_ = chains.SolFlowsSPL(ctx, chainsCfg["solana"].RPC, r.Address, t.Mint, t.Symbol, start, end, wb, dbkt)
+				}
+			}
+		case "tron":
+			for _, t := range chainsCfg["tron"].TRC20 {
+				if util.IsAllowedFor(entity, t.Symbol) {
+					_ = chains.TronTRC20Flows(ctx, r.Address, t.Contract, start, end, t.Symbol, wb, dbkt)
+				}
+			}
+		default: // EVM-like
+			cc := chainsCfg[r.Chain]
+			owner := r.EVM()
+			for _, tok := range cc.ERC20 {
+				if util.IsAllowedFor(entity, tok.Symbol) && (tok.Symbol == "USDT" || tok.Symbol == "USDC") {
+					_ = chains.EVMERC20Flows(ctx, cc.RPC, tok, owner, start, end, wb, dbkt)
+				}
+			}
+			if r.Chain == "ethereum" && etherscanKey != "" && util.IsAllowedFor(entity, "ETH") {
+				_ = chains.ETHNativeFlowsEtherscan(ctx, etherscanKey, cc.RPC, r.Address, start, end, wb, dbkt)
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package collector
+
+import (
+	"analysis/internal/models"
+	"testing"
+)
+
+// TestGroupByEntity 按实体分组，实体为空的归入 unknown
+func TestGroupByEntity(t *testing.T) {
+	group := GroupByEntity([]models.AddressRow{
+		{Entity: "binance", Chain: "ethereum", Address: "0x1"},
+		{Entity: "okx", Chain: "tron", Address: "T1"},
+		{Entity: "binance", Chain: "bitcoin", Address: "bc1q"},
+		{Chain: "solana", Address: "So1"},
+	})
+	if len(group) != 3 || len(group["binance"]) != 2 || len(group["okx"]) != 1 || len(group["unknown"]) != 1 {
+		t.Fatalf("分组不符: %+v", group)
+	}
+	if group["binance"][1].Address != "bc1q" {
+		t.Errorf("组内应保持原顺序: %+v", group["binance"])
+	}
+}
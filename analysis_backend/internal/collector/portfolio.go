@@ -7,6 +7,7 @@ import (
 	"analysis/internal/util"
 	"context"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
@@ -18,6 +19,14 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 		Entity:   entity,
 		Holdings: map[string]models.Holding{},
 		TS:       time.Now().UTC().Unix(),
+		ByType:   map[string]map[string]models.Holding{},
+	}
+
+	// 同时累加到总持仓和当前地址类型的持仓
+	var byType map[string]models.Holding
+	addHolding := func(chain, symbol string, dec int, amt *big.Int) {
+		util.AddEntityHolding(p.Holdings, entity, chain, symbol, dec, amt, px)
+		util.AddEntityHolding(byType, entity, chain, symbol, dec, amt, px)
 	}
 
 	seen := map[string]struct{}{}
@@ -29,9 +38,18 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 		}
 		seen[k] = struct{}{}
 
+		typ := r.Type
+		if typ == "" {
+			typ = models.AddressTypeUnknown
+		}
+		if p.ByType[typ] == nil {
+			p.ByType[typ] = map[string]models.Holding{}
+		}
+		byType = p.ByType[typ]
+
 		switch r.Chain {
 		case "bitcoin":
-			if !util.IsAllowed("BTC") {
+			if !util.IsAllowedFor(entity, "BTC") {
 				continue
 			}
 			cc := chainsCfg["bitcoin"]
@@ -39,7 +57,7 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 				continue
 			}
 			if bal, err := chains.BTCAddressBalance(ctx, cc.Esplora, r.Address); err == nil {
-				util.AddHolding(p.Holdings, "bitcoin", "BTC", 8, bal, px)
+				addHolding("bitcoin", "BTC", 8, bal)
 			}
 
 		case "solana":
@@ -47,17 +65,17 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 			if cc.RPC == "" {
 				continue
 			}
-			if util.IsAllowed("SOL") {
+			if util.IsAllowedFor(entity, "SOL") {
 				if bal, err := chains.SolNative(ctx, cc.RPC, r.Address); err == nil {
-					util.AddHolding(p.Holdings, "solana", "SOL", 9, bal, px)
+					addHolding("solana", "SOL", 9, bal)
 				}
 			}
 			for _, t := range cc.SPL {
-				if !util.IsAllowed(t.Symbol) {
+				if !util.IsAllowedFor(entity, t.Symbol) {
 					continue
 				}
 				if bal, dec, err := chains.SolSPL(ctx, cc.RPC, r.Address, t.Mint); err == nil && bal.Sign() > 0 {
-					util.AddHolding(p.Holdings, "solana", t.Symbol, dec, bal, px)
+					addHolding("solana", t.Symbol, t.DecimalsOr(dec), bal)
 				}
 			}
 
@@ -68,7 +86,7 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 			}
 			want := []config.TokenTRC20{}
 			for _, t := range cc.TRC20 {
-				if util.IsAllowed(t.Symbol) {
+				if util.IsAllowedFor(entity, t.Symbol) {
 					want = append(want, t)
 				}
 			}
@@ -77,7 +95,7 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 			}
 			if m, dec, err := chains.TronTRC20(ctx, r.Address, want); err == nil {
 				for sym, bal := range m {
-					util.AddHolding(p.Holdings, "tron", sym, dec[sym], bal, px)
+					addHolding("tron", sym, dec[sym], bal)
 				}
 			}
 
@@ -87,17 +105,17 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 				continue
 			}
 			ea := r.EVM()
-			if util.IsAllowed("ETH") && (r.Chain == "ethereum" || r.Chain == "arbitrum" || r.Chain == "optimism" || r.Chain == "base") {
+			if util.IsAllowedFor(entity, "ETH") && evmHoldsETH(r.Chain) {
 				if native, err := chains.EVMNativeBalance(ctx, cc.RPC, ea); err == nil && native.Sign() > 0 {
-					util.AddHolding(p.Holdings, r.Chain, "ETH", 18, native, px)
+					addHolding(r.Chain, "ETH", 18, native)
 				}
 			}
 			for _, t := range cc.ERC20 {
-				if !util.IsAllowed(t.Symbol) {
+				if !util.IsAllowedFor(entity, t.Symbol) {
 					continue
 				}
 				if bal, dec, err := chains.EVMERC20Balance(ctx, cc.RPC, common.HexToAddress(t.Address), ea); err == nil && bal.Sign() > 0 {
-					util.AddHolding(p.Holdings, r.Chain, t.Symbol, dec, bal, px)
+					addHolding(r.Chain, t.Symbol, t.DecimalsOr(dec), bal)
 				}
 			}
 		}
@@ -106,5 +124,10 @@ func ComputePortfolio(ctx context.Context, entity string, rows []models.AddressR
 	for _, h := range p.Holdings {
 		p.TotalUSD += h.ValueUSD
 	}
+	for typ, hs := range p.ByType {
+		if len(hs) == 0 {
+			delete(p.ByType, typ)
+		}
+	}
 	return p, nil
 }
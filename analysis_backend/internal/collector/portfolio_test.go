@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestComputePortfolioByAddressType 持仓按地址类型拆分，各类型之和等于总持仓
+func TestComputePortfolioByAddressType(t *testing.T) {
+	balances := map[string]int64{
+		"bc1qcold1":   300_000_000,
+		"bc1qcold2":   200_000_000,
+		"bc1qhot":     50_000_000,
+		"bc1qunknown": 10_000_000,
+	}
+	esplora := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr := strings.TrimPrefix(r.URL.Path, "/address/")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"chain_stats":   map[string]int64{"funded_txo_sum": balances[addr]},
+			"mempool_stats": map[string]int64{},
+		})
+	}))
+	defer esplora.Close()
+
+	util.SetAllowed("BTC")
+	rows := []models.AddressRow{
+		{Entity: "binance", Chain: "bitcoin", Address: "bc1qcold1", Type: models.AddressTypeCold},
+		{Entity: "binance", Chain: "bitcoin", Address: "bc1qcold2", Type: models.AddressTypeCold},
+		{Entity: "binance", Chain: "bitcoin", Address: "bc1qhot", Type: models.AddressTypeHot},
+		{Entity: "binance", Chain: "bitcoin", Address: "bc1qunknown"},
+	}
+	chainsCfg := map[string]config.ChainCfg{"bitcoin": {Esplora: esplora.URL}}
+	p, err := ComputePortfolio(context.Background(), "binance", rows, chainsCfg, map[string]float64{"BTC": 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := p.Holdings["bitcoin:BTC"].Amount; got != "5.60000000" {
+		t.Errorf("总持仓期望 5.6 BTC，实际 %s", got)
+	}
+	want := map[string]string{
+		models.AddressTypeCold:    "5.00000000",
+		models.AddressTypeHot:     "0.50000000",
+		models.AddressTypeUnknown: "0.10000000",
+	}
+	if len(p.ByType) != len(want) {
+		t.Fatalf("应有 %d 个地址类型，实际 %v", len(want), p.ByType)
+	}
+	sumUSD := 0.0
+	for typ, amount := range want {
+		h := p.ByType[typ]["bitcoin:BTC"]
+		if h.Amount != amount {
+			t.Errorf("%s 持仓期望 %s，实际 %s", typ, amount, h.Amount)
+		}
+		sumUSD += h.ValueUSD
+	}
+	if diff := sumUSD - p.TotalUSD; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("各类型市值之和 %.4f 应等于总市值 %.4f", sumUSD, p.TotalUSD)
+	}
+}
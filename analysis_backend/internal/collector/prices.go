@@ -0,0 +1,131 @@
+package collector
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"context"
+	"sort"
+	"strings"
+)
+
+// PriceFetcher 按币种批量取 USD 价格（price.FetchPrices 的形状），缺价的币种不出现在结果中
+type PriceFetcher func(ctx context.Context, syms []string) (map[string]float64, error)
+
+// evmHoldsETH 以 ETH 为原生币的 EVM 链（ComputePortfolio 只对这些链取原生余额）
+func evmHoldsETH(chain string) bool {
+	switch chain {
+	case "ethereum", "arbitrum", "optimism", "base":
+		return true
+	}
+	return false
+}
+
+// PortfolioCoins 预扫描：按 ComputePortfolio 相同的规则（实体币种范围、链配置）列出实体可能出现的全部币种，已排序去重
+func PortfolioCoins(entity string, rows []models.AddressRow, chainsCfg map[string]config.ChainCfg) []string {
+	set := map[string]struct{}{}
+	add := func(sym string) {
+		if util.IsAllowedFor(entity, sym) {
+			set[strings.ToUpper(sym)] = struct{}{}
+		}
+	}
+	seenChain := map[string]struct{}{}
+	for _, r := range rows {
+		if _, ok := seenChain[r.Chain]; ok {
+			continue
+		}
+		seenChain[r.Chain] = struct{}{}
+		cc := chainsCfg[r.Chain]
+		switch r.Chain {
+		case "bitcoin":
+			if cc.Esplora != "" {
+				add("BTC")
+			}
+		case "solana":
+			if cc.RPC == "" {
+				continue
+			}
+			add("SOL")
+			for _, t := range cc.SPL {
+				add(t.Symbol)
+			}
+		case "tron":
+			for _, t := range cc.TRC20 {
+				add(t.Symbol)
+			}
+		default: // EVM
+			if cc.RPC == "" {
+				continue
+			}
+			if evmHoldsETH(r.Chain) {
+				add("ETH")
+			}
+			for _, t := range cc.ERC20 {
+				add(t.Symbol)
+			}
+		}
+	}
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// PriceSet 批量预取的价格与取不到价格的币种
+type PriceSet struct {
+	Prices   map[string]float64
+	Unpriced []string // 已排序；估值时这些币种 value_usd 为 0
+}
+
+// PrefetchPrices 汇总所有实体可能出现的币种，一次批量取价。取价失败时全部币种记为缺价并返回错误，调用方可继续（只是不估值）
+func PrefetchPrices(ctx context.Context, fetch PriceFetcher, group map[string][]models.AddressRow, chainsCfg map[string]config.ChainCfg) (PriceSet, error) {
+	set := map[string]struct{}{}
+	for ent, rows := range group {
+		for _, s := range PortfolioCoins(ent, rows, chainsCfg) {
+			set[s] = struct{}{}
+		}
+	}
+	coins := make([]string, 0, len(set))
+	for s := range set {
+		coins = append(coins, s)
+	}
+	sort.Strings(coins)
+
+	ps := PriceSet{Prices: map[string]float64{}}
+	var err error
+	if len(coins) > 0 {
+		var px map[string]float64
+		if px, err = fetch(ctx, coins); err == nil {
+			for s, v := range px {
+				ps.Prices[strings.ToUpper(s)] = v
+			}
+		}
+	}
+	for _, s := range coins {
+		if _, ok := ps.Prices[s]; !ok {
+			ps.Unpriced = append(ps.Unpriced, s)
+		}
+	}
+	return ps, err
+}
+
+// UnpricedHoldings 估值后仍缺价的币种（持仓非零但价格表中没有），已排序去重
+func UnpricedHoldings(p models.Portfolio, px map[string]float64) []string {
+	set := map[string]struct{}{}
+	for _, h := range p.Holdings {
+		if _, ok := px[strings.ToUpper(h.Symbol)]; ok {
+			continue
+		}
+		if parseRat(h.Amount).Sign() != 0 {
+			set[strings.ToUpper(h.Symbol)] = struct{}{}
+		}
+	}
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,74 @@
+package collector
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestPrefetchPricesCoversEntityScopes 各实体币种范围不同时，预扫描汇总所有实体的币种一次取价；取不到价格的币种显式列出
+func TestPrefetchPricesCoversEntityScopes(t *testing.T) {
+	util.SetAllowed("BTC,ETH")
+	util.SetEntityAllowed("okx", "ETH,USDT,PEPE")
+	util.SetEntityAllowed("bitget", "SOL,JUP")
+	defer util.ResetEntityAllowed()
+
+	chainsCfg := map[string]config.ChainCfg{
+		"bitcoin":  {Esplora: "http://esplora"},
+		"ethereum": {RPC: "http://eth", ERC20: []config.TokenERC20{{Symbol: "USDT"}, {Symbol: "PEPE"}, {Symbol: "USDC"}}},
+		"solana":   {RPC: "http://sol", SPL: []config.TokenSPL{{Symbol: "JUP"}}},
+	}
+	group := map[string][]models.AddressRow{
+		"binance": {{Chain: "bitcoin", Address: "bc1"}, {Chain: "ethereum", Address: "0x1"}},
+		"okx":     {{Chain: "ethereum", Address: "0x2"}, {Chain: "bitcoin", Address: "bc2"}},
+		"bitget":  {{Chain: "solana", Address: "So1"}},
+	}
+
+	var asked [][]string
+	fetch := func(ctx context.Context, syms []string) (map[string]float64, error) {
+		asked = append(asked, syms)
+		return map[string]float64{"BTC": 60000, "ETH": 3000, "usdt": 1, "SOL": 150}, nil
+	}
+	ps, err := PrefetchPrices(context.Background(), fetch, group, chainsCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"BTC", "ETH", "JUP", "PEPE", "SOL", "USDT"}
+	if len(asked) != 1 || !reflect.DeepEqual(asked[0], want) {
+		t.Fatalf("应一次批量请求全部币种 %v，实际 %v", want, asked)
+	}
+	if !reflect.DeepEqual(ps.Unpriced, []string{"JUP", "PEPE"}) {
+		t.Errorf("缺价币种不符: %v", ps.Unpriced)
+	}
+	for _, s := range want {
+		_, priced := ps.Prices[s]
+		if priced == (strings.Contains("JUP,PEPE", s)) {
+			t.Errorf("%s 必须要么有价格、要么列为缺价: prices=%v unpriced=%v", s, ps.Prices, ps.Unpriced)
+		}
+	}
+
+	failing := func(ctx context.Context, syms []string) (map[string]float64, error) {
+		return nil, errors.New("rate limited")
+	}
+	ps, err = PrefetchPrices(context.Background(), failing, group, chainsCfg)
+	if err == nil || !reflect.DeepEqual(ps.Unpriced, want) || len(ps.Prices) != 0 {
+		t.Errorf("取价失败时全部币种应列为缺价: %+v %v", ps, err)
+	}
+}
+
+// TestUnpricedHoldings 估值后持仓非零但没有价格的币种被列出，零持仓忽略
+func TestUnpricedHoldings(t *testing.T) {
+	p := models.Portfolio{Holdings: map[string]models.Holding{
+		"ethereum:ETH":  {Symbol: "ETH", Amount: "1.00000000"},
+		"ethereum:PEPE": {Symbol: "PEPE", Amount: "1000.00000000"},
+		"solana:JUP":    {Symbol: "JUP", Amount: "0.00000000"},
+	}}
+	if got := UnpricedHoldings(p, map[string]float64{"ETH": 3000}); !reflect.DeepEqual(got, []string{"PEPE"}) {
+		t.Errorf("缺价持仓不符: %v", got)
+	}
+}
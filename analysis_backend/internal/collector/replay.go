@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"analysis/internal/models"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReplayPortfolio 仅凭已入库的转账事件重建实体在 at 时刻的持仓（每币种 in 减 out），不查询链上余额。
+// base 非空时以其为起点（如某次实时快照），只累加 base.TS 之后的事件；为空则从零开始，结果为净流量。
+// 同一事件（chain+txid+logIndex+address+direction）重复出现只计一次。
+func ReplayPortfolio(entity string, base *models.Portfolio, events []models.Event, at time.Time, px map[string]float64) models.Portfolio {
+	total := map[string]*big.Rat{}
+	byType := map[string]map[string]*big.Rat{}
+	meta := map[string]models.Holding{}
+
+	var since time.Time
+	if base != nil {
+		since = time.Unix(base.TS, 0).UTC()
+		for k, h := range base.Holdings {
+			total[k] = parseRat(h.Amount)
+			meta[k] = h
+		}
+		for typ, hs := range base.ByType {
+			byType[typ] = map[string]*big.Rat{}
+			for k, h := range hs {
+				byType[typ][k] = parseRat(h.Amount)
+			}
+		}
+	}
+
+	seen := map[string]struct{}{}
+	for _, e := range events {
+		if entity != "" && e.Entity != "" && !strings.EqualFold(e.Entity, entity) {
+			continue
+		}
+		if e.TS.After(at) || (base != nil && !e.TS.After(since)) {
+			continue
+		}
+		amt, ok := new(big.Rat).SetString(strings.TrimSpace(e.Amount))
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(e.Direction) {
+		case "in":
+		case "out":
+			amt.Neg(amt)
+		default:
+			continue
+		}
+		dk := strings.Join([]string{e.Chain, e.TxID, strconv.Itoa(e.LogIndex), strings.ToLower(e.Address), e.Direction}, "|")
+		if _, dup := seen[dk]; dup {
+			continue
+		}
+		seen[dk] = struct{}{}
+
+		sym := strings.ToUpper(strings.TrimSpace(e.Coin))
+		key := e.Chain + ":" + sym
+		if _, ok := meta[key]; !ok {
+			meta[key] = models.Holding{Symbol: sym, Chain: e.Chain}
+		}
+		addRat(total, key, amt)
+
+		typ := e.AddressType
+		if typ == "" {
+			typ = models.AddressTypeUnknown
+		}
+		if byType[typ] == nil {
+			byType[typ] = map[string]*big.Rat{}
+		}
+		addRat(byType[typ], key, amt)
+	}
+
+	p := models.Portfolio{
+		Entity:   entity,
+		Holdings: map[string]models.Holding{},
+		TS:       at.UTC().Unix(),
+		ByType:   map[string]map[string]models.Holding{},
+	}
+	for k, amt := range total {
+		h := replayHolding(meta[k], amt, px)
+		p.Holdings[k] = h
+		p.TotalUSD += h.ValueUSD
+	}
+	for typ, hs := range byType {
+		if len(hs) == 0 {
+			continue
+		}
+		p.ByType[typ] = map[string]models.Holding{}
+		for k, amt := range hs {
+			p.ByType[typ][k] = replayHolding(meta[k], amt, px)
+		}
+	}
+	return p
+}
+
+// HoldingDrift 重建持仓与实时持仓的差异（Diff = Replayed - Live）
+type HoldingDrift struct {
+	Key      string  `json:"key"` // chain:SYMBOL
+	Replayed string  `json:"replayed"`
+	Live     string  `json:"live"`
+	Diff     string  `json:"diff"`
+	DiffPct  float64 `json:"diff_pct"` // 相对实时持仓的百分比；实时为 0 时为 100
+}
+
+// ReconcilePortfolio 对比重建持仓与实时快照，返回相对偏差超过 tolerancePct（百分比）的币种，按 key 排序
+func ReconcilePortfolio(replayed, live models.Portfolio, tolerancePct float64) []HoldingDrift {
+	keys := map[string]struct{}{}
+	for k := range replayed.Holdings {
+		keys[k] = struct{}{}
+	}
+	for k := range live.Holdings {
+		keys[k] = struct{}{}
+	}
+
+	out := []HoldingDrift{}
+	for k := range keys {
+		r := parseRat(replayed.Holdings[k].Amount)
+		l := parseRat(live.Holdings[k].Amount)
+		diff := new(big.Rat).Sub(r, l)
+		if diff.Sign() == 0 {
+			continue
+		}
+		pct := 100.0
+		if l.Sign() != 0 {
+			pct, _ = new(big.Rat).Quo(new(big.Rat).Abs(diff), new(big.Rat).Abs(l)).Float64()
+			pct *= 100
+		}
+		if pct <= tolerancePct {
+			continue
+		}
+		out = append(out, HoldingDrift{
+			Key:      k,
+			Replayed: r.FloatString(8),
+			Live:     l.FloatString(8),
+			Diff:     diff.FloatString(8),
+			DiffPct:  pct,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+func replayHolding(h models.Holding, amt *big.Rat, px map[string]float64) models.Holding {
+	h.Amount = amt.FloatString(8)
+	h.ValueUSD = 0
+	if p, ok := px[h.Symbol]; ok {
+		f, _ := amt.Float64()
+		h.ValueUSD = f * p
+	}
+	return h
+}
+
+func addRat(m map[string]*big.Rat, key string, v *big.Rat) {
+	if m[key] == nil {
+		m[key] = new(big.Rat)
+	}
+	m[key].Add(m[key], v)
+}
+
+func parseRat(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
@@ -0,0 +1,101 @@
+package collector
+
+import (
+	"analysis/internal/models"
+	"testing"
+	"time"
+)
+
+func seededEvents(t0 time.Time) []models.Event {
+	return []models.Event{
+		{Entity: "binance", Chain: "ethereum", Coin: "usdt", Direction: "in", Amount: "1000.5", TS: t0, TxID: "0x1", LogIndex: 3, Address: "0xa", AddressType: models.AddressTypeHot},
+		{Entity: "binance", Chain: "ethereum", Coin: "USDT", Direction: "out", Amount: "200.25", TS: t0.Add(time.Hour), TxID: "0x2", LogIndex: 1, Address: "0xa", AddressType: models.AddressTypeHot},
+		// 重复入库的同一事件只计一次
+		{Entity: "binance", Chain: "ethereum", Coin: "USDT", Direction: "out", Amount: "200.25", TS: t0.Add(time.Hour), TxID: "0x2", LogIndex: 1, Address: "0xa", AddressType: models.AddressTypeHot},
+		{Entity: "binance", Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "2", TS: t0.Add(2 * time.Hour), TxID: "b1", LogIndex: -1, Address: "bc1qcold", AddressType: models.AddressTypeCold},
+		{Entity: "binance", Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "0.5", TS: t0.Add(3 * time.Hour), TxID: "b2", LogIndex: -1, Address: "bc1qx"},
+		// 其他实体、晚于 at 的事件不计入
+		{Entity: "okx", Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "9", TS: t0, TxID: "o1", LogIndex: -1, Address: "bc1qokx"},
+		{Entity: "binance", Chain: "bitcoin", Coin: "BTC", Direction: "out", Amount: "1", TS: t0.Add(48 * time.Hour), TxID: "b3", LogIndex: -1, Address: "bc1qcold"},
+	}
+}
+
+// TestReplayPortfolioFromEvents 事件流按 in-out 汇总出持仓，按地址类型拆分
+func TestReplayPortfolioFromEvents(t *testing.T) {
+	t0 := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	at := t0.Add(24 * time.Hour)
+	p := ReplayPortfolio("binance", nil, seededEvents(t0), at, map[string]float64{"BTC": 100, "USDT": 1})
+
+	want := map[string]string{
+		"ethereum:USDT": "800.25000000",
+		"bitcoin:BTC":   "2.50000000",
+	}
+	if len(p.Holdings) != len(want) {
+		t.Fatalf("期望 %d 个持仓，实际 %v", len(want), p.Holdings)
+	}
+	for k, amt := range want {
+		if got := p.Holdings[k].Amount; got != amt {
+			t.Errorf("%s 期望 %s，实际 %s", k, amt, got)
+		}
+	}
+	if p.TotalUSD != 800.25+250 {
+		t.Errorf("总市值期望 1050.25，实际 %v", p.TotalUSD)
+	}
+	if p.TS != at.Unix() {
+		t.Errorf("快照时间应为 at，实际 %d", p.TS)
+	}
+	if got := p.ByType[models.AddressTypeCold]["bitcoin:BTC"].Amount; got != "2.00000000" {
+		t.Errorf("cold BTC 期望 2，实际 %s", got)
+	}
+	if got := p.ByType[models.AddressTypeUnknown]["bitcoin:BTC"].Amount; got != "0.50000000" {
+		t.Errorf("未标注地址 BTC 期望 0.5，实际 %s", got)
+	}
+
+	// 时点快照：只回放到第一笔 BTC 之前
+	early := ReplayPortfolio("binance", nil, seededEvents(t0), t0.Add(90*time.Minute), nil)
+	if _, ok := early.Holdings["bitcoin:BTC"]; ok {
+		t.Errorf("BTC 事件晚于快照时间，不应计入: %v", early.Holdings)
+	}
+}
+
+// TestReplayPortfolioFromBaseline 以实时快照为起点，只累加其后的事件
+func TestReplayPortfolioFromBaseline(t *testing.T) {
+	t0 := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	base := &models.Portfolio{
+		Entity:   "binance",
+		TS:       t0.Add(90 * time.Minute).Unix(),
+		Holdings: map[string]models.Holding{"bitcoin:BTC": {Symbol: "BTC", Chain: "bitcoin", Decimals: 8, Amount: "10"}},
+	}
+	p := ReplayPortfolio("binance", base, seededEvents(t0), t0.Add(72*time.Hour), nil)
+	if got := p.Holdings["bitcoin:BTC"]; got.Amount != "11.50000000" || got.Decimals != 8 {
+		t.Errorf("BTC 期望 10+2+0.5-1=11.5 且保留精度，实际 %+v", got)
+	}
+	if _, ok := p.Holdings["ethereum:USDT"]; ok {
+		t.Errorf("快照之前的 USDT 事件不应计入: %v", p.Holdings)
+	}
+}
+
+// TestReconcilePortfolio 与实时快照对账，超出容忍度的币种报告偏差
+func TestReconcilePortfolio(t *testing.T) {
+	replayed := models.Portfolio{Holdings: map[string]models.Holding{
+		"bitcoin:BTC":   {Amount: "11.5"},
+		"ethereum:USDT": {Amount: "1000"},
+		"ethereum:ETH":  {Amount: "3"},
+	}}
+	live := models.Portfolio{Holdings: map[string]models.Holding{
+		"bitcoin:BTC":   {Amount: "11.5"},
+		"ethereum:USDT": {Amount: "1000.5"}, // 0.05% 偏差，在容忍范围内
+		"solana:SOL":    {Amount: "40"},
+		"ethereum:ETH":  {Amount: "2"},
+	}}
+	drift := ReconcilePortfolio(replayed, live, 0.1)
+	if len(drift) != 2 {
+		t.Fatalf("期望 2 项偏差，实际 %+v", drift)
+	}
+	if d := drift[0]; d.Key != "ethereum:ETH" || d.Diff != "1.00000000" || d.DiffPct != 50 {
+		t.Errorf("ETH 偏差不符: %+v", d)
+	}
+	if d := drift[1]; d.Key != "solana:SOL" || d.Diff != "-40.00000000" || d.DiffPct != 100 {
+		t.Errorf("SOL 偏差不符: %+v", d)
+	}
+}
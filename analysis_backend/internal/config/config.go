@@ -1,10 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"encoding/hex"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"analysis/internal/util"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -49,6 +54,14 @@ type Config struct {
 		ERC20   []TokenERC20 `yaml:"erc20,omitempty"`
 		SPL     []TokenSPL   `yaml:"spl,omitempty"`
 		TRC20   []TokenTRC20 `yaml:"trc20,omitempty"`
+		// NativeSymbol EVM 链原生币符号（如 linea -> ETH），未配置时使用内置映射
+		NativeSymbol string `yaml:"native_symbol,omitempty"`
+		// EsploraPaging 按端点覆盖区块交易分页参数，未配置的端点使用默认值
+		EsploraPaging []EsploraPaging `yaml:"esplora_paging,omitempty"`
+		// TraceInternal EVM 原生币内部转账扫描：debug(debug_traceBlockByNumber) / parity(trace_block)，为空不扫描（需 RPC 开放 trace 接口）
+		TraceInternal string `yaml:"trace_internal,omitempty"`
+		// Confirmations WS 转账推送的确认数阈值（达到后状态为 confirmed），0 使用内置默认值
+		Confirmations uint64 `yaml:"confirmations,omitempty"`
 	} `yaml:"chains"`
 
 	Entities []EntityCfg `yaml:"entities"`
@@ -58,18 +71,48 @@ type Config struct {
 	} `yaml:"services"`
 
 	Backtest struct {
-		Mode string `yaml:"mode"` // "full" or "lightweight"
+		Mode             string `yaml:"mode"`                // "full" or "lightweight"
+		DataSource       string `yaml:"data_source"`         // 历史K线来源：db（默认，只读数据库）/ live（优先交易所实时K线并与库内数据合并）
+		LiveFallback     bool   `yaml:"live_fallback"`       // db 模式下库内缺数据时实时拉取交易所K线并写回数据库（默认关闭，避免意外的API负载）
+		MaxRangeDays     int    `yaml:"max_range_days"`      // 单次加载历史数据的最大天数，超过后自动分段加载（默认 730）
+		HardMaxRangeDays int    `yaml:"hard_max_range_days"` // 回测允许的最大天数，超过直接报错（默认 3650）
 	} `yaml:"backtest"`
 
+	Recommendation struct {
+		MaxLimit int `yaml:"max_limit"` // 单次生成推荐数量上限（默认 50）
+	} `yaml:"recommendation"`
+
 	Database struct {
+		Driver       string `yaml:"driver"` // mysql（默认）/ sqlite
 		DSN          string `yaml:"dsn"`
 		Automigrate  bool   `yaml:"automigrate"`
 		MaxOpenConns int    `yaml:"max_open_conns"`
 		MaxIdleConns int    `yaml:"max_idle_conns"`
 	} `yaml:"database"`
 
+	// 扫描器事件下发目标：http（POST /ingest/events）/ kafka / nats，可多选
+	EventSink struct {
+		Targets   []string `yaml:"targets"`    // 为空时默认 ["http"]
+		BatchSize int      `yaml:"batch_size"` // 单次下发的最大事件数，<=0 使用默认值（1000）
+		Kafka     struct {
+			Brokers []string `yaml:"brokers"`
+			Topic   string   `yaml:"topic"`
+		} `yaml:"kafka"`
+		NATS struct {
+			URL     string `yaml:"url"`
+			Subject string `yaml:"subject"` // 前缀，实际 subject = <subject>.<entity>.<chain>
+		} `yaml:"nats"`
+	} `yaml:"event_sink"`
+
+	// 公告类型映射：各数据源类目 id -> 统一类型（new-listing / delisting / maintenance / activity / news / other）
+	Announcements struct {
+		CategoryMap map[string]map[string]string `yaml:"category_map"` // source -> 类目 id -> 类型；按数据源整体覆盖默认值
+		DefaultType string                       `yaml:"default_type"` // 类目未配置且关键词推断不出时的类型
+	} `yaml:"announcements"`
+
 	Twitter struct {
 		Bearer          string   `yaml:"bearer"`
+		Bearers         []string `yaml:"bearers"`          // 多个 token 轮换（遇到 429 自动切换）
 		MonitorUsers    []string `yaml:"monitor_users"`    // 扫描器用
 		IntervalSeconds int      `yaml:"interval_seconds"` // 扫描器用
 	} `yaml:"twitter"`
@@ -193,11 +236,86 @@ type Config struct {
 type EntityCfg struct {
 	Name     string              `yaml:"name"`
 	Networks map[string][]string `yaml:"networks"`
+	// Coins 该实体的币种范围（如 [BTC, ETH]），覆盖 -only 的全局设置；为空时沿用全局设置
+	Coins []string `yaml:"coins,omitempty"`
+	// XPubGap networks.bitcoin 中扩展公钥（xpub/ypub/zpub/描述符）每个分支预先派生的地址数，0 使用默认值 20
+	XPubGap int `yaml:"xpub_gap,omitempty"`
+}
+
+// EntityCoinScopes 配置了 coins 的实体：实体名 -> 逗号分隔的币种（格式同 util.SetEntityAllowed）
+func (c *Config) EntityCoinScopes() map[string]string {
+	out := map[string]string{}
+	for _, e := range c.Entities {
+		if e.Name != "" && len(e.Coins) > 0 {
+			out[e.Name] = strings.Join(e.Coins, ",")
+		}
+	}
+	return out
+}
+
+// DefaultRecommendationMaxLimit 未配置 recommendation.max_limit 时的推荐数量上限
+const DefaultRecommendationMaxLimit = 50
+
+// RecommendationMaxLimit 单次生成推荐数量上限；未加载配置或配置非正数时用默认值
+func (c *Config) RecommendationMaxLimit() int {
+	if c == nil || c.Recommendation.MaxLimit <= 0 {
+		return DefaultRecommendationMaxLimit
+	}
+	return c.Recommendation.MaxLimit
+}
+
+// decimals 可选：覆盖自动探测的精度（代理/非标合约探测失败或返回错误值时使用）
+// min_amount 可选：换算精度后低于该金额的转账（粉尘）不上报；为空或 0 不过滤
+type TokenERC20 struct {
+	Symbol, Address string
+	Decimals        *int   `yaml:"decimals,omitempty"`
+	MinAmount       string `yaml:"min_amount,omitempty"`
+}
+type TokenSPL struct {
+	Symbol, Mint string
+	Decimals     *int   `yaml:"decimals,omitempty"`
+	MinAmount    string `yaml:"min_amount,omitempty"`
+}
+type TokenTRC20 struct {
+	Symbol, Contract string
+	Decimals         *int `yaml:"decimals,omitempty"`
+}
+
+// MaxTokenDecimals 精度覆盖的合法上限
+const MaxTokenDecimals = 36
+
+// DecimalsOverride 配置的精度覆盖；未配置或越界时 ok=false
+func DecimalsOverride(d *int) (int, bool) {
+	if d == nil || *d < 0 || *d > MaxTokenDecimals {
+		return 0, false
+	}
+	return *d, true
 }
 
-type TokenERC20 struct{ Symbol, Address string }
-type TokenSPL struct{ Symbol, Mint string }
-type TokenTRC20 struct{ Symbol, Contract string }
+// DecimalsOr 有覆盖用覆盖，否则用 detected
+func (t TokenERC20) DecimalsOr(detected int) int { return decimalsOr(t.Decimals, detected) }
+func (t TokenSPL) DecimalsOr(detected int) int   { return decimalsOr(t.Decimals, detected) }
+func (t TokenTRC20) DecimalsOr(detected int) int { return decimalsOr(t.Decimals, detected) }
+
+func decimalsOr(d *int, detected int) int {
+	if v, ok := DecimalsOverride(d); ok {
+		return v
+	}
+	return detected
+}
+
+// Esplora 区块交易分页默认值（mempool.space / blockstream 每页 25 条）
+const (
+	DefaultEsploraPageSize  = 25
+	DefaultEsploraMaxOffset = 20000
+)
+
+// EsploraPaging 单个 Esplora 端点的区块交易分页参数
+type EsploraPaging struct {
+	Endpoint  string `yaml:"endpoint"`
+	PageSize  int    `yaml:"page_size"`  // 预期每页条数（实际以首页返回条数为准），<=0 使用默认值
+	MaxOffset int    `yaml:"max_offset"` // 最大翻页偏移，0 使用默认值，<0 不限制
+}
 
 func MustLoad(path string, out *Config) {
 	// 设置默认值
@@ -304,6 +422,31 @@ func setDefaults(cfg *Config) {
 	cfg.GridTrading.PerformanceMonitoring.EnableMetrics = true        // 默认启用性能指标收集
 	cfg.GridTrading.PerformanceMonitoring.MetricsIntervalMinutes = 5  // 每5分钟收集一次指标
 	cfg.GridTrading.PerformanceMonitoring.AlertWinRateThreshold = 0.4 // 胜率低于40%时告警
+
+	// 公告类目映射默认值（Binance catalog id / OKX annType / Bybit type.key）
+	cfg.Announcements.DefaultType = "other"
+	cfg.Announcements.CategoryMap = map[string]map[string]string{
+		"binance": {
+			"48":  "new-listing", // New Cryptocurrency Listing
+			"49":  "news",        // Latest Binance News
+			"93":  "activity",    // Latest Activities
+			"157": "maintenance", // Maintenance Updates
+			"161": "delisting",   // Delisting
+		},
+		"okx": {
+			"announcements-new-listings":                             "new-listing",
+			"announcements-delistings":                               "delisting",
+			"announcements-latest-announcements":                     "news",
+			"announcements-deposit-withdrawal-suspension-resumption": "maintenance",
+		},
+		"bybit": {
+			"new_crypto":          "new-listing",
+			"delistings":          "delisting",
+			"maintenance_updates": "maintenance",
+			"latest_activities":   "activity",
+			"latest_bybit_news":   "news",
+		},
+	}
 }
 
 func ApplyProxy(cfg *Config) {
@@ -326,22 +469,98 @@ func ApplyProxy(cfg *Config) {
 
 type ChainCfg struct {
 	Name, Type, RPC, Esplora string
+	NativeSymbol             string // 原生币符号；为空表示未知（不扫描原生币转账）
 	ERC20                    []TokenERC20
 	SPL                      []TokenSPL
 	TRC20                    []TokenTRC20
+	EsploraPaging            []EsploraPaging
+	TraceInternal            string // EVM 内部转账 trace 方式，为空不扫描
+}
+
+// EsploraPagingFor 返回端点的分页参数（已填充默认值）
+func (c ChainCfg) EsploraPagingFor(endpoint string) EsploraPaging {
+	endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	p := EsploraPaging{Endpoint: endpoint}
+	for _, ep := range c.EsploraPaging {
+		if strings.TrimRight(strings.TrimSpace(ep.Endpoint), "/") == endpoint {
+			p.PageSize, p.MaxOffset = ep.PageSize, ep.MaxOffset
+			break
+		}
+	}
+	if p.PageSize <= 0 {
+		p.PageSize = DefaultEsploraPageSize
+	}
+	if p.MaxOffset == 0 {
+		p.MaxOffset = DefaultEsploraMaxOffset
+	}
+	return p
+}
+
+// TokenContract 币种在资产标识（models.AssetIDFor）中的合约位，与扫描器一致：ERC20 为合约地址，SPL 为 mint，
+// TRC20 为去掉 41 前缀的 20 字节 hex；链上未配置该币种或 TRC20 地址无法解析时 ok=false
+func (c ChainCfg) TokenContract(symbol string) (string, bool) {
+	symbol = strings.TrimSpace(symbol)
+	for _, t := range c.ERC20 {
+		if strings.EqualFold(strings.TrimSpace(t.Symbol), symbol) {
+			return strings.TrimSpace(t.Address), true
+		}
+	}
+	for _, t := range c.SPL {
+		if strings.EqualFold(strings.TrimSpace(t.Symbol), symbol) {
+			return strings.TrimSpace(t.Mint), true
+		}
+	}
+	for _, t := range c.TRC20 {
+		if strings.EqualFold(strings.TrimSpace(t.Symbol), symbol) {
+			return tronContractHex(t.Contract)
+		}
+	}
+	return "", false
+}
+
+// tronContractHex base58check 的 Tron 合约地址 -> 不带 41 前缀的 20 字节 hex
+func tronContractHex(addr string) (string, bool) {
+	raw, ok := util.Base58Decode(strings.TrimSpace(addr))
+	if !ok || len(raw) != 25 || raw[0] != 0x41 || !bytes.Equal(util.Base58Checksum(raw[:21]), raw[21:]) {
+		return "", false
+	}
+	return hex.EncodeToString(raw[1:21]), true
+}
+
+// builtinNativeSymbols 已知 EVM 链（含别名）的原生币符号，配置未指定 native_symbol 时使用
+var builtinNativeSymbols = map[string]string{
+	"ethereum": "ETH", "eth": "ETH",
+	"bsc": "BNB", "bnb": "BNB", "bnbchain": "BNB", "bnbsmartchain": "BNB",
+	"polygon": "MATIC", "matic": "MATIC",
+	"avalanche": "AVAX", "avax": "AVAX", "avaxc": "AVAX", "avalanchec": "AVAX",
+	"fantom": "FTM", "ftm": "FTM",
+	"op": "ETH", "optimism": "ETH",
+	"arbitrum": "ETH", "arb": "ETH", "arbitrumone": "ETH",
+	"base": "ETH",
+}
+
+// NativeSymbol 链的原生币符号：优先使用配置，否则查内置映射；未知返回空串
+func NativeSymbol(chain, configured string) string {
+	if s := strings.ToUpper(strings.TrimSpace(configured)); s != "" {
+		return s
+	}
+	return builtinNativeSymbols[strings.ToLower(strings.TrimSpace(chain))]
 }
 
 func BuildChainCfg(cfg *Config) map[string]ChainCfg {
 	out := map[string]ChainCfg{}
 	for _, c := range cfg.Chains {
 		out[c.Name] = ChainCfg{
-			Name:    c.Name,
-			Type:    c.Type,
-			RPC:     c.RPC,
-			Esplora: c.Esplora,
-			ERC20:   c.ERC20,
-			SPL:     c.SPL,
-			TRC20:   c.TRC20,
+			Name:          c.Name,
+			Type:          c.Type,
+			RPC:           c.RPC,
+			Esplora:       c.Esplora,
+			NativeSymbol:  NativeSymbol(c.Name, c.NativeSymbol),
+			ERC20:         c.ERC20,
+			SPL:           c.SPL,
+			TRC20:         c.TRC20,
+			EsploraPaging: c.EsploraPaging,
+			TraceInternal: c.TraceInternal,
 		}
 	}
 	// 兜底
@@ -350,7 +569,7 @@ func BuildChainCfg(cfg *Config) map[string]ChainCfg {
 	}
 	if _, ok := out["ethereum"]; !ok {
 		out["ethereum"] = ChainCfg{
-			Name: "ethereum", Type: "evm", RPC: "https://eth.llamarpc.com",
+			Name: "ethereum", Type: "evm", RPC: "https://eth.llamarpc.com", NativeSymbol: "ETH",
 			ERC20: []TokenERC20{
 				{Symbol: "USDT", Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7"},
 				{Symbol: "USDC", Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"},
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,19 +20,33 @@ type Announcement struct {
 	Summary     string                       `gorm:"type:text" json:"summary"`
 	URL         string                       `gorm:"type:varchar(1024);unique'" json:"url"`
 	Category    string                       `gorm:"type:varchar(16);index" json:"category"` // newcoin | finance | other | event
+	Type        string                       `gorm:"type:varchar(32);index" json:"type"`     // 跨源统一类型：new-listing | delisting | maintenance | ...
 	Tags        datatypes.JSONType[[]string] `gorm:"type:json" json:"tags"`
 	ReleaseTime time.Time                    `gorm:"index" json:"release_time"`
 	Raw         datatypes.JSON               `gorm:"type:json" json:"raw"`
 	// 新增字段：多层次抓取支持
-	IsEvent   bool      `gorm:"default:false;index" json:"is_event"`     // 是否为重要事件（第二层验证标记）
-	Sentiment string    `gorm:"type:varchar(16);index" json:"sentiment"` // positive | neutral | negative
-	HeatScore int       `gorm:"default:0;index" json:"heat_score"`       // 热度分数 0-100
-	Exchange  string    `gorm:"type:varchar(32);index" json:"exchange"`  // 交易所名称（从 coincarp 提取）
-	Verified  bool      `gorm:"default:false" json:"verified"`           // 是否经过官方源验证（第三层）
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	IsEvent   bool   `gorm:"default:false;index" json:"is_event"`     // 是否为重要事件（第二层验证标记）
+	Sentiment string `gorm:"type:varchar(16);index" json:"sentiment"` // positive | neutral | negative
+	HeatScore int    `gorm:"default:0;index" json:"heat_score"`       // 热度分数 0-100
+	Exchange  string `gorm:"type:varchar(32);index" json:"exchange"`  // 交易所名称（从 coincarp 提取）
+	Verified  bool   `gorm:"default:false" json:"verified"`           // 是否经过官方源验证（第三层）
+	// 跨源合并：同一事件（币种+类型+时间相近）只保留一条规范记录，Sources 记录所有来源
+	MergeKey  string                                      `gorm:"type:varchar(64);index" json:"merge_key,omitempty"`
+	Sources   datatypes.JSONType[[]AnnouncementSourceRef] `gorm:"type:json" json:"sources"`
+	CreatedAt time.Time                                   `json:"created_at"`
+	UpdatedAt time.Time                                   `json:"updated_at"`
 }
 
+// AnnouncementSourceRef 合并后规范记录引用的来源
+type AnnouncementSourceRef struct {
+	Source     string `json:"source"`
+	ExternalID string `json:"external_id"`
+	URL        string `json:"url"`
+}
+
+// CrossSourceMergeWindow 跨源合并的时间窗口：同币种同类型且发布时间相差不超过该值视为同一事件
+var CrossSourceMergeWindow = 6 * time.Hour
+
 // 批量Upsert（URL 唯一，支持多数据源合并）
 func SaveAnnouncements(db *gorm.DB, items []Announcement) ([]Announcement, error) {
 	if len(items) == 0 {
@@ -39,17 +55,17 @@ func SaveAnnouncements(db *gorm.DB, items []Announcement) ([]Announcement, error
 	err := db.Clauses(clause.OnConflict{
 		Columns: []clause.Column{{Name: "url"}},
 		DoUpdates: clause.AssignmentColumns([]string{
-			"title", "summary", "category", "tags", "release_time", "raw",
-			"is_event", "sentiment", "heat_score", "exchange", "verified", "news_code", "updated_at",
+			"title", "summary", "category", "type", "tags", "release_time", "raw",
+			"is_event", "sentiment", "heat_score", "exchange", "verified", "news_code", "merge_key", "sources", "updated_at",
 		}),
 	}).Create(&items).Error
 	return items, err
 }
 
-// 合并多数据源的公告（用于去重和验证）
-func MergeAnnouncements(db *gorm.DB, items []Announcement) error {
+// 合并多数据源的公告（用于去重和验证），返回新插入的记录数（并入已有记录或按 URL 更新的不计）
+func MergeAnnouncements(db *gorm.DB, items []Announcement) (int, error) {
 	if len(items) == 0 {
-		return nil
+		return 0, nil
 	}
 	// 按 URL 分组，合并不同数据源的信息
 	urlMap := make(map[string]*Announcement)
@@ -80,6 +96,143 @@ func MergeAnnouncements(db *gorm.DB, items []Announcement) error {
 	for _, item := range urlMap {
 		merged = append(merged, *item)
 	}
-	_, err := SaveAnnouncements(db, merged)
-	return err
+	// 跨源合并：批内先合并，再与库中时间窗口内的规范记录合并
+	merged = MergeCrossSource(merged, CrossSourceMergeWindow)
+	toSave := make([]Announcement, 0, len(merged))
+	for _, item := range merged {
+		if item.MergeKey != "" {
+			var existing Announcement
+			err := db.Where("merge_key = ? AND url <> ? AND release_time BETWEEN ? AND ?",
+				item.MergeKey, item.URL,
+				item.ReleaseTime.Add(-CrossSourceMergeWindow), item.ReleaseTime.Add(CrossSourceMergeWindow)).
+				Order("release_time ASC").First(&existing).Error
+			if err == nil {
+				mergeInto(&existing, item)
+				if err := db.Save(&existing).Error; err != nil {
+					return 0, err
+				}
+				continue
+			}
+		}
+		toSave = append(toSave, item)
+	}
+	if len(toSave) == 0 {
+		return 0, nil
+	}
+	urls := make([]string, 0, len(toSave))
+	for _, item := range toSave {
+		urls = append(urls, item.URL)
+	}
+	var existed int64
+	if err := db.Model(&Announcement{}).Where("url IN ?", urls).Count(&existed).Error; err != nil {
+		return 0, err
+	}
+	if _, err := SaveAnnouncements(db, toSave); err != nil {
+		return 0, err
+	}
+	return len(toSave) - int(existed), nil
+}
+
+// 标题中括号内的币种，如 "Binance Will List Foo (FOO)"
+var announcementSymbolRe = regexp.MustCompile(`\(([A-Z0-9]{2,10})\)`)
+
+// AnnouncementMergeKey 币种+类型组成的合并键；提取不到币种时返回空（不参与跨源合并）
+func AnnouncementMergeKey(a Announcement) string {
+	sym := ""
+	if m := announcementSymbolRe.FindStringSubmatch(a.Title); len(m) == 2 {
+		sym = m[1]
+	} else {
+		for _, t := range a.Tags.Data() {
+			if t != "" && t == strings.ToUpper(t) && len(t) >= 2 && len(t) <= 10 {
+				sym = t
+				break
+			}
+		}
+	}
+	if sym == "" {
+		return ""
+	}
+	return sym + "|" + strings.ToLower(a.Category)
+}
+
+// MergeCrossSource 按合并键 + 时间窗口合并同一事件的多源公告，返回合并后的规范记录（不修改 items）
+func MergeCrossSource(items []Announcement, window time.Duration) []Announcement {
+	out := make([]Announcement, 0, len(items))
+	byKey := map[string][]int{} // mergeKey -> out 下标
+	items = append([]Announcement(nil), items...)
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ReleaseTime.Before(items[j].ReleaseTime) })
+	for _, it := range items {
+		if len(it.Sources.Data()) == 0 {
+			it.Sources = datatypes.NewJSONType([]AnnouncementSourceRef{{Source: it.Source, ExternalID: it.ExternalID, URL: it.URL}})
+		}
+		if it.MergeKey == "" {
+			it.MergeKey = AnnouncementMergeKey(it)
+		}
+		if it.MergeKey == "" {
+			out = append(out, it)
+			continue
+		}
+		merged := false
+		for _, idx := range byKey[it.MergeKey] {
+			d := it.ReleaseTime.Sub(out[idx].ReleaseTime)
+			if d < 0 {
+				d = -d
+			}
+			if d <= window {
+				mergeInto(&out[idx], it)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			byKey[it.MergeKey] = append(byKey[it.MergeKey], len(out))
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// mergeInto 将 src 合并进规范记录 dst：来源取并集，官方源优先提供标题/摘要
+func mergeInto(dst *Announcement, src Announcement) {
+	refs := dst.Sources.Data()
+	if len(refs) == 0 {
+		refs = []AnnouncementSourceRef{{Source: dst.Source, ExternalID: dst.ExternalID, URL: dst.URL}}
+	}
+	have := map[string]bool{}
+	for _, r := range refs {
+		have[r.URL] = true
+	}
+	for _, r := range src.Sources.Data() {
+		if !have[r.URL] {
+			have[r.URL] = true
+			refs = append(refs, r)
+		}
+	}
+	dst.Sources = datatypes.NewJSONType(refs)
+
+	if src.Verified && !dst.Verified {
+		dst.Title = src.Title
+		if src.Summary != "" {
+			dst.Summary = src.Summary
+		}
+		dst.Verified = true
+	}
+	if src.Type != "" && (dst.Type == "" || dst.Type == AnnouncementTypeOther) {
+		dst.Type = src.Type
+	}
+	if src.IsEvent {
+		dst.IsEvent = true
+	}
+	if src.HeatScore > dst.HeatScore {
+		dst.HeatScore = src.HeatScore
+	}
+	if dst.Sentiment == "" {
+		dst.Sentiment = src.Sentiment
+	}
+	if dst.Exchange == "" {
+		dst.Exchange = src.Exchange
+	}
+	if !src.ReleaseTime.IsZero() && src.ReleaseTime.Before(dst.ReleaseTime) {
+		dst.ReleaseTime = src.ReleaseTime
+	}
 }
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnnouncementImpact 公告发布后标的价格在窗口内的变化（用于验证公告因子）
+type AnnouncementImpact struct {
+	ID             uint64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	AnnouncementID uint64    `gorm:"not null;uniqueIndex:uk_announcement_impact_window,priority:1" json:"announcement_id"`
+	WindowMinutes  int       `gorm:"not null;uniqueIndex:uk_announcement_impact_window,priority:2" json:"window_minutes"`
+	Symbol         string    `gorm:"size:32;index" json:"symbol"` // 交易对，如 FOOUSDT
+	Kind           string    `gorm:"size:16" json:"kind"`         // spot | futures
+	Type           string    `gorm:"size:32;index" json:"type"`   // 公告统一类型
+	ReleaseTime    time.Time `gorm:"index" json:"release_time"`
+	BasePrice      float64   `json:"base_price"`       // 公告发布后首根 K 线开盘价
+	EndPrice       float64   `json:"end_price"`        // 窗口内最后一根 K 线收盘价
+	ChangePercent  float64   `json:"change_percent"`   // (end - base) / base * 100
+	MaxUpPercent   float64   `json:"max_up_percent"`   // 窗口内最高价相对 base 的涨幅
+	MaxDownPercent float64   `json:"max_down_percent"` // 窗口内最低价相对 base 的跌幅（负数）
+	KlineCount     int       `json:"kline_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (AnnouncementImpact) TableName() string { return "announcement_impacts" }
+
+// AnnouncementSymbol 从公告标题/标签中提取币种（与跨源合并键同一规则），提取不到返回空
+func AnnouncementSymbol(a Announcement) string {
+	key := AnnouncementMergeKey(a)
+	if i := strings.Index(key, "|"); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// ComputeAnnouncementImpact 用 [start, start+window) 内的 K 线计算价格影响；K 线需按 open_time 升序。
+// 窗口内没有有效 K 线时返回 false。
+func ComputeAnnouncementImpact(klines []MarketKline, start time.Time, window time.Duration) (AnnouncementImpact, bool) {
+	end := start.Add(window)
+	var out AnnouncementImpact
+	high, low := math.Inf(-1), math.Inf(1)
+	for _, k := range klines {
+		if k.OpenTime.Before(start) || !k.OpenTime.Before(end) {
+			continue
+		}
+		open, err1 := strconv.ParseFloat(k.OpenPrice, 64)
+		cl, err2 := strconv.ParseFloat(k.ClosePrice, 64)
+		hi, err3 := strconv.ParseFloat(k.HighPrice, 64)
+		lo, err4 := strconv.ParseFloat(k.LowPrice, 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+		if out.KlineCount == 0 {
+			if open <= 0 {
+				continue
+			}
+			out.BasePrice = open
+		}
+		out.EndPrice = cl
+		high = math.Max(high, hi)
+		low = math.Min(low, lo)
+		out.KlineCount++
+	}
+	if out.KlineCount == 0 {
+		return out, false
+	}
+	out.WindowMinutes = int(window / time.Minute)
+	out.ChangePercent = (out.EndPrice - out.BasePrice) / out.BasePrice * 100
+	out.MaxUpPercent = (high - out.BasePrice) / out.BasePrice * 100
+	out.MaxDownPercent = (low - out.BasePrice) / out.BasePrice * 100
+	return out, true
+}
+
+// RefreshAnnouncementImpacts 计算 since 之后上币公告的价格影响并写入 announcement_impacts。
+// 只计算已完整结束的窗口；已计算过的 (公告, 窗口) 会被覆盖，可重复执行。返回写入条数。
+func RefreshAnnouncementImpacts(gdb *gorm.DB, since time.Time, kind, interval string, windows []time.Duration) (int, error) {
+	if len(windows) == 0 {
+		return 0, nil
+	}
+	minWindow, maxWindow := windows[0], windows[0]
+	for _, w := range windows {
+		minWindow = min(minWindow, w)
+		maxWindow = max(maxWindow, w)
+	}
+
+	var anns []Announcement
+	if err := gdb.Where("type = ? AND release_time >= ? AND release_time <= ?",
+		AnnouncementTypeNewListing, since, time.Now().Add(-minWindow)).
+		Order("release_time ASC").Find(&anns).Error; err != nil {
+		return 0, fmt.Errorf("failed to query announcements: %w", err)
+	}
+
+	saved := 0
+	for _, a := range anns {
+		sym := AnnouncementSymbol(a)
+		if sym == "" {
+			continue
+		}
+		pair := sym + "USDT"
+		start := a.ReleaseTime
+		end := start.Add(maxWindow)
+		klines, err := GetMarketKlines(gdb, pair, kind, interval, &start, &end, 0)
+		if err != nil {
+			return saved, err
+		}
+		rows := make([]AnnouncementImpact, 0, len(windows))
+		for _, w := range windows {
+			if start.Add(w).After(time.Now()) {
+				continue // 窗口尚未结束
+			}
+			imp, ok := ComputeAnnouncementImpact(klines, start, w)
+			if !ok {
+				continue
+			}
+			imp.AnnouncementID = a.ID
+			imp.Symbol = pair
+			imp.Kind = kind
+			imp.Type = a.Type
+			imp.ReleaseTime = a.ReleaseTime
+			rows = append(rows, imp)
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		if err := gdb.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "announcement_id"}, {Name: "window_minutes"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"symbol", "kind", "type", "release_time", "base_price", "end_price",
+				"change_percent", "max_up_percent", "max_down_percent", "kline_count", "updated_at",
+			}),
+		}).Create(&rows).Error; err != nil {
+			return saved, fmt.Errorf("failed to save announcement impacts: %w", err)
+		}
+		saved += len(rows)
+	}
+	return saved, nil
+}
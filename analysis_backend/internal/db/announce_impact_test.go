@@ -0,0 +1,97 @@
+package db
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestRefreshAnnouncementImpacts 上币公告发布后按窗口统计 K 线涨跌幅
+func TestRefreshAnnouncementImpacts(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&Announcement{}, &MarketKline{}, &AnnouncementImpact{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	release := time.Date(2025, 8, 1, 10, 0, 30, 0, time.UTC)
+	anns := []Announcement{
+		{Source: "binance", ExternalID: "1", URL: "u1", Title: "Binance Will List Foo (FOO)", Type: AnnouncementTypeNewListing, ReleaseTime: release},
+		{Source: "binance", ExternalID: "2", URL: "u2", Title: "Binance Will Delist Bar (BAR)", Type: AnnouncementTypeDelisting, ReleaseTime: release},
+	}
+	if err := gdb.Create(&anns).Error; err != nil {
+		t.Fatalf("写入公告失败: %v", err)
+	}
+
+	// 公告前一根 K 线不计入；之后每分钟开盘价 +1，从 100 开始
+	var klines []MarketKline
+	for i := -1; i < 90; i++ {
+		open := 100 + float64(i)
+		f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+		klines = append(klines, MarketKline{
+			Symbol: "FOOUSDT", Kind: "spot", Interval: "1m",
+			OpenTime:  time.Date(2025, 8, 1, 10, 1, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute),
+			OpenPrice: f(open), ClosePrice: f(open + 1), HighPrice: f(open + 2), LowPrice: f(open - 3),
+		})
+	}
+	klines = append(klines, MarketKline{
+		Symbol: "BARUSDT", Kind: "spot", Interval: "1m", OpenTime: release.Add(time.Minute),
+		OpenPrice: "1", ClosePrice: "1", HighPrice: "1", LowPrice: "1",
+	})
+	if err := gdb.Create(&klines).Error; err != nil {
+		t.Fatalf("写入 K 线失败: %v", err)
+	}
+
+	windows := []time.Duration{15 * time.Minute, time.Hour}
+	saved, err := RefreshAnnouncementImpacts(gdb, release.Add(-time.Hour), "spot", "1m", windows)
+	if err != nil {
+		t.Fatalf("计算失败: %v", err)
+	}
+	if saved != 2 {
+		t.Fatalf("期望写入 2 条（仅上币公告 × 2 个窗口），实际 %d", saved)
+	}
+
+	var rows []AnnouncementImpact
+	gdb.Order("window_minutes ASC").Find(&rows)
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	want := []struct {
+		window              int
+		count               int
+		end, change, up, dn float64
+	}{
+		// 15 分钟：10:01..10:15 共 15 根，收盘 115，最高 116，最低 97
+		{15, 15, 115, 15, 16, -3},
+		// 60 分钟：10:01..11:00 共 60 根，收盘 160，最高 161
+		{60, 60, 160, 60, 61, -3},
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.AnnouncementID != anns[0].ID || r.Symbol != "FOOUSDT" || r.WindowMinutes != w.window {
+			t.Fatalf("窗口 %d 记录不符: %+v", w.window, r)
+		}
+		if r.KlineCount != w.count || !near(r.BasePrice, 100) || !near(r.EndPrice, w.end) {
+			t.Errorf("窗口 %d: 期望 %d 根 K 线 base=100 end=%v，实际 %+v", w.window, w.count, w.end, r)
+		}
+		if !near(r.ChangePercent, w.change) || !near(r.MaxUpPercent, w.up) || !near(r.MaxDownPercent, w.dn) {
+			t.Errorf("窗口 %d: 期望涨跌 %v/%v/%v，实际 %v/%v/%v", w.window, w.change, w.up, w.dn,
+				r.ChangePercent, r.MaxUpPercent, r.MaxDownPercent)
+		}
+	}
+
+	// 重复执行覆盖而非新增
+	if _, err := RefreshAnnouncementImpacts(gdb, release.Add(-time.Hour), "spot", "1m", windows); err != nil {
+		t.Fatalf("重复计算失败: %v", err)
+	}
+	var n int64
+	gdb.Model(&AnnouncementImpact{}).Count(&n)
+	if n != 2 {
+		t.Errorf("重复执行后应仍为 2 条，实际 %d", n)
+	}
+}
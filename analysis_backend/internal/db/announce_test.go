@@ -0,0 +1,157 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMergeCrossSource 同一上币事件来自 coincarp 与 binance 时合并为一条
+func TestMergeCrossSource(t *testing.T) {
+	ts := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	items := []Announcement{
+		{
+			Source: "coincarp", ExternalID: "cc1", URL: "https://www.coincarp.com/zh/exchange/announcement/cc1",
+			Title: "币安将上线 Foo (FOO)", Category: "newcoin", ReleaseTime: ts.Add(20 * time.Minute),
+			Sources: datatypes.NewJSONType([]AnnouncementSourceRef{{Source: "coincarp", ExternalID: "cc1", URL: "https://www.coincarp.com/zh/exchange/announcement/cc1"}}),
+		},
+		{
+			Source: "coincarp", ExternalID: "bn1", URL: "https://www.binance.com/en/support/announcement/bn1",
+			Title: "Binance Will List Foo (FOO)", Category: "newcoin", ReleaseTime: ts, Verified: true,
+			Sources: datatypes.NewJSONType([]AnnouncementSourceRef{{Source: "binance", ExternalID: "bn1", URL: "https://www.binance.com/en/support/announcement/bn1"}}),
+		},
+		{
+			// 同币种但时间相差过大，视为不同事件
+			Source: "coincarp", ExternalID: "cc2", URL: "https://www.coincarp.com/zh/exchange/announcement/cc2",
+			Title: "OKX 上线 Foo (FOO)", Category: "newcoin", ReleaseTime: ts.Add(48 * time.Hour),
+		},
+		{
+			// 提取不到币种，不参与合并
+			Source: "coincarp", ExternalID: "cc3", URL: "https://www.coincarp.com/zh/exchange/announcement/cc3",
+			Title: "系统维护通知", Category: "other", ReleaseTime: ts,
+		},
+	}
+
+	out := MergeCrossSource(items, 6*time.Hour)
+	if len(out) != 3 {
+		t.Fatalf("期望合并为 3 条，实际 %d", len(out))
+	}
+	if items[0].ExternalID != "cc1" || items[1].ExternalID != "bn1" {
+		t.Errorf("不应就地排序调用方切片: %s, %s", items[0].ExternalID, items[1].ExternalID)
+	}
+	var canon *Announcement
+	for i := range out {
+		if out[i].MergeKey == "FOO|newcoin" && len(out[i].Sources.Data()) == 2 {
+			canon = &out[i]
+		}
+	}
+	if canon == nil {
+		t.Fatalf("未找到合并后的规范记录: %+v", out)
+	}
+	if !canon.Verified || canon.Title != "Binance Will List Foo (FOO)" {
+		t.Errorf("规范记录应取官方源标题并标记 verified: %+v", canon)
+	}
+	if !canon.ReleaseTime.Equal(ts) {
+		t.Errorf("规范记录应取最早发布时间，实际 %s", canon.ReleaseTime)
+	}
+	srcs := map[string]bool{}
+	for _, r := range canon.Sources.Data() {
+		srcs[r.Source] = true
+	}
+	if !srcs["coincarp"] || !srcs["binance"] {
+		t.Errorf("sources 应同时包含 coincarp 与 binance: %+v", canon.Sources.Data())
+	}
+}
+
+// TestResolveAnnouncementType 类目 id 按配置映射，未知 id 回退到关键词推断/默认类型
+func TestResolveAnnouncementType(t *testing.T) {
+	catMap := map[string]map[string]string{
+		"binance": {"48": "new-listing", "161": "delisting", "157": "maintenance", "93": "activity"},
+		"bybit":   {"new_crypto": "new-listing"},
+	}
+	cases := []struct {
+		name                          string
+		source, catID, title, summary string
+		defaultType                   string
+		want                          string
+	}{
+		{"binance 48", "binance", "48", "Binance Will Add Foo (FOO)", "", "other", "new-listing"},
+		{"binance 161", "Binance", "161", "Notice of Removal of Spot Trading Pairs", "", "other", "delisting"},
+		{"binance 157", "binance", "157", "Wallet Upgrade", "", "other", "maintenance"},
+		{"binance 93", "binance", "93", "Trading Competition", "", "other", "activity"},
+		{"bybit key", "bybit", "new_crypto", "New Spot Pair", "", "other", "new-listing"},
+		{"未知 id 按标题推断下架", "binance", "999", "Binance Will Delist BAR", "", "other", "delisting"},
+		{"未知 id 按标题推断上币", "okx", "x", "OKX to list Foo (FOO)", "", "other", "new-listing"},
+		{"无 id 按摘要推断维护", "coincarp", "", "公告", "系统维护通知", "other", "maintenance"},
+		{"未知 id 使用默认类型", "binance", "999", "Monthly Report", "", "news", "news"},
+		{"默认类型为空时为 other", "upbit", "", "Monthly Report", "", "", "other"},
+	}
+	for _, c := range cases {
+		got := ResolveAnnouncementType(catMap, c.defaultType, c.source, c.catID, c.title, c.summary)
+		if got != c.want {
+			t.Errorf("%s: 期望 %s，实际 %s", c.name, c.want, got)
+		}
+	}
+}
+
+// TestMergeAnnouncementsDB 入库时与库中窗口内的规范记录合并，只统计新插入的记录
+func TestMergeAnnouncementsDB(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&Announcement{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	if err := gdb.Exec("CREATE UNIQUE INDEX IF NOT EXISTS uk_announcements_url ON announcements(url)").Error; err != nil {
+		t.Fatalf("建唯一索引失败: %v", err)
+	}
+
+	ts := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	first := []Announcement{
+		{Source: "binance", ExternalID: "bn1", URL: "https://binance/bn1", Title: "Binance Will List Foo (FOO)", Category: "newcoin", ReleaseTime: ts, Verified: true},
+		{Source: "coincarp", ExternalID: "cc9", URL: "https://coincarp/cc9", Title: "系统维护通知", Category: "other", ReleaseTime: ts},
+	}
+	saved, err := MergeAnnouncements(gdb, first)
+	if err != nil || saved != 2 {
+		t.Fatalf("首批应插入 2 条: saved=%d err=%v", saved, err)
+	}
+	if first[0].URL != "https://binance/bn1" || first[1].URL != "https://coincarp/cc9" {
+		t.Errorf("不应改动调用方切片顺序: %+v", first)
+	}
+
+	// 第二批：coincarp 的同一上币事件并入库中规范记录；维护通知按 URL 更新；另有一条新公告
+	second := []Announcement{
+		{Source: "coincarp", ExternalID: "cc1", URL: "https://coincarp/cc1", Title: "币安将上线 Foo (FOO)", Category: "newcoin", ReleaseTime: ts.Add(30 * time.Minute), HeatScore: 80},
+		{Source: "coincarp", ExternalID: "cc9", URL: "https://coincarp/cc9", Title: "系统维护通知（更新）", Category: "other", ReleaseTime: ts},
+		{Source: "coincarp", ExternalID: "cc2", URL: "https://coincarp/cc2", Title: "OKX 上线 Bar (BAR)", Category: "newcoin", ReleaseTime: ts},
+	}
+	saved, err = MergeAnnouncements(gdb, second)
+	if err != nil || saved != 1 {
+		t.Fatalf("第二批只应新插入 1 条: saved=%d err=%v", saved, err)
+	}
+
+	// 批内按 URL 分组后插入顺序不固定，按 URL 取记录
+	var rows []Announcement
+	if err := gdb.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("库中应有 3 条，实际 %d", len(rows))
+	}
+	byURL := map[string]Announcement{}
+	for _, r := range rows {
+		byURL[r.URL] = r
+	}
+	canon := byURL["https://binance/bn1"]
+	if canon.Title != "Binance Will List Foo (FOO)" || canon.HeatScore != 80 || len(canon.Sources.Data()) != 2 {
+		t.Errorf("规范记录应合并 coincarp 来源并保留官方标题: %+v", canon)
+	}
+	if got := byURL["https://coincarp/cc9"]; got.Title != "系统维护通知（更新）" {
+		t.Errorf("同 URL 应更新原记录: %+v", got)
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"strings"
+)
+
+// 跨数据源统一的公告类型
+const (
+	AnnouncementTypeNewListing  = "new-listing"
+	AnnouncementTypeDelisting   = "delisting"
+	AnnouncementTypeMaintenance = "maintenance"
+	AnnouncementTypeActivity    = "activity"
+	AnnouncementTypeNews        = "news"
+	AnnouncementTypeOther       = "other"
+)
+
+// ResolveAnnouncementType 将数据源类目 id 映射为统一类型。
+// categoryMap 为 source -> (类目 id -> 类型)；类目 id 未配置时按标题/摘要关键词推断，推断不出则返回 defaultType（为空时为 other）。
+func ResolveAnnouncementType(categoryMap map[string]map[string]string, defaultType, source, categoryID, title, summary string) string {
+	source = strings.ToLower(strings.TrimSpace(source))
+	categoryID = strings.TrimSpace(categoryID)
+	if categoryID != "" && categoryID != "0" {
+		if t := strings.TrimSpace(categoryMap[source][categoryID]); t != "" {
+			return strings.ToLower(t)
+		}
+	}
+	if t := inferAnnouncementType(title + " " + summary); t != "" {
+		return t
+	}
+	if defaultType = strings.ToLower(strings.TrimSpace(defaultType)); defaultType != "" {
+		return defaultType
+	}
+	return AnnouncementTypeOther
+}
+
+// inferAnnouncementType 关键词推断（下架须先于上币判断，"delist" 包含 "list"）
+func inferAnnouncementType(text string) string {
+	txt := strings.ToLower(text)
+	rules := []struct {
+		typ   string
+		words []string
+	}{
+		{AnnouncementTypeDelisting, []string{"delist", "下架", "下线", "상장폐지"}},
+		{AnnouncementTypeMaintenance, []string{"maintenance", "维护", "暂停充值", "暂停提现", "점검"}},
+		{AnnouncementTypeNewListing, []string{"new listing", "will list", "to list", "listing", "上线", "上币", "新币", "상장"}},
+	}
+	for _, r := range rules {
+		for _, w := range r.words {
+			if strings.Contains(txt, w) {
+				return r.typ
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AssetFlow 转账事件在一段时间内按资产汇总的流入/流出
+type AssetFlow struct {
+	Key    string `json:"key"`             // 分组键：asset_id 或币种
+	Chain  string `json:"chain,omitempty"` // 按资产分组时为资产所在链
+	Coin   string `json:"coin"`            // 币种符号
+	In     string `json:"in"`              // 十进制字符串
+	Out    string `json:"out"`             // 十进制字符串
+	Net    string `json:"net"`             // in - out
+	Events int    `json:"events"`          // 参与汇总的事件数
+	// Unresolved 按资产分组时，没有 asset_id（合约未知）的事件按 链/币种 单独汇总，Key 为空
+	Unresolved bool `json:"unresolved,omitempty"`
+}
+
+// SumTransferFlows 汇总实体在 [start, end) 内的转账事件：byAsset 时按 asset_id 分组（区分各链的同名代币），
+// 否则按币种合并；没有 asset_id 的事件不另造标识，按 链/币种 归组并标记 Unresolved。结果按分组键、链、币种排序
+func SumTransferFlows(gdb *gorm.DB, entity string, start, end time.Time, byAsset bool) ([]AssetFlow, error) {
+	var rows []TransferEvent
+	q := gdb.Select("chain", "coin", "asset_id", "direction", "amount").
+		Where("occurred_at >= ? AND occurred_at < ?", start.UTC(), end.UTC())
+	if entity != "" {
+		q = q.Where("entity = ?", entity)
+	}
+	if err := q.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	type acc struct {
+		key, chain, coin string
+		in, out          *big.Float
+		events           int
+	}
+	sums := map[string]*acc{}
+	for _, r := range rows {
+		amt, ok := new(big.Float).SetPrec(256).SetString(strings.TrimSpace(r.Amount))
+		if !ok || (r.Direction != "in" && r.Direction != "out") {
+			continue
+		}
+		coin := strings.ToUpper(r.Coin)
+		key, chain, group := coin, "", coin
+		if byAsset {
+			chain = strings.ToLower(r.Chain)
+			key, group = r.AssetID, r.AssetID
+			if key == "" {
+				group = "\x00" + chain + "\x00" + coin // 未解析的事件不与任何 asset_id 相撞
+			}
+		}
+		a := sums[group]
+		if a == nil {
+			a = &acc{key: key, chain: chain, coin: coin, in: new(big.Float).SetPrec(256), out: new(big.Float).SetPrec(256)}
+			sums[group] = a
+		}
+		if r.Direction == "in" {
+			a.in.Add(a.in, amt)
+		} else {
+			a.out.Add(a.out, amt)
+		}
+		a.events++
+	}
+
+	out := make([]AssetFlow, 0, len(sums))
+	for _, a := range sums {
+		net := new(big.Float).SetPrec(256).Sub(a.in, a.out)
+		out = append(out, AssetFlow{
+			Key: a.key, Chain: a.chain, Coin: a.coin,
+			In: fstr(a.in, 18), Out: fstr(a.out, 18), Net: fstr(net, 18), Events: a.events,
+			Unresolved: byAsset && a.key == "",
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Key != out[j].Key {
+			return out[i].Key < out[j].Key
+		}
+		if out[i].Chain != out[j].Chain {
+			return out[i].Chain < out[j].Chain
+		}
+		return out[i].Coin < out[j].Coin
+	})
+	return out, nil
+}
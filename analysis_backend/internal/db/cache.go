@@ -19,6 +19,19 @@ type CacheInterface interface {
 	Exists(ctx context.Context, key string) (bool, error)
 }
 
+// CacheKeyInfo 缓存键及剩余 TTL（TTL < 0 表示永不过期）
+type CacheKeyInfo struct {
+	Key string
+	TTL time.Duration
+}
+
+// CacheInspector 支持列出与按前缀清理键的缓存（MemoryCache、RedisCache 均实现）
+type CacheInspector interface {
+	Keys(ctx context.Context, prefix string) ([]CacheKeyInfo, error)
+	// Flush 删除以 prefix 开头的键（prefix 为空时清空全部），返回删除数量
+	Flush(ctx context.Context, prefix string) (int, error)
+}
+
 // CacheWrapper 缓存包装器
 type CacheWrapper struct {
 	cache CacheInterface
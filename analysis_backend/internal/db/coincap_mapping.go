@@ -162,9 +162,11 @@ func NewCoinCapMarketDataService(db *gorm.DB) *CoinCapMarketDataService {
 }
 
 // UpsertMarketData 插入或更新市值数据
+// 已存在时 FirstOrCreate 会把查到的旧记录读进 data，因此 Assign 必须使用副本，否则更新的是旧值
 func (s *CoinCapMarketDataService) UpsertMarketData(ctx context.Context, data *CoinCapMarketData) error {
+	assign := *data
 	return s.db.WithContext(ctx).Where(CoinCapMarketData{Symbol: data.Symbol}).
-		Assign(data).
+		Assign(assign).
 		FirstOrCreate(data).Error
 }
 
@@ -349,3 +351,36 @@ func (s *CoinCapMarketDataService) GetMarketDataByMarketCapRangeAndSymbolsPaged(
 	err := s.db.WithContext(ctx).Raw(query, minCap, maxCap, symbols, limit, offset).Scan(&dataList).Error
 	return dataList, err
 }
+
+// GetUnmappedBaseAssets 获取交易对中缺少CoinCap映射的基础资产（仅统计活跃交易对）
+// quoteAsset 为空时统计所有计价资产；返回未映射资产列表（按字母排序）和参与统计的基础资产总数
+func (s *CoinCapMappingService) GetUnmappedBaseAssets(ctx context.Context, quoteAsset string) ([]string, int64, error) {
+	where := "e.is_active = ?"
+	args := []interface{}{true}
+	if quoteAsset != "" {
+		where += " AND e.quote_asset = ?"
+		args = append(args, strings.ToUpper(strings.TrimSpace(quoteAsset)))
+	}
+
+	var total int64
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT COUNT(DISTINCT UPPER(e.base_asset))
+		FROM binance_exchange_info e
+		WHERE `+where, args...).Scan(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var unmapped []string
+	err = s.db.WithContext(ctx).Raw(`
+		SELECT DISTINCT UPPER(e.base_asset)
+		FROM binance_exchange_info e
+		LEFT JOIN coin_cap_asset_mappings m ON m.symbol = UPPER(e.base_asset)
+		WHERE m.id IS NULL AND `+where+`
+		ORDER BY UPPER(e.base_asset)`, args...).Scan(&unmapped).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return unmapped, total, nil
+}
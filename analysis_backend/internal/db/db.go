@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -55,6 +56,7 @@ func (d *databaseImpl) Close() error {
 }
 
 type Options struct {
+	Driver          string // mysql（默认）/ sqlite；sqlite 用于本地调试与端到端测试，DSN 如 file::memory:?cache=shared
 	DSN             string
 	Automigrate     bool
 	MaxOpenConns    int
@@ -71,6 +73,14 @@ func OpenMySQL(opt Options) (Database, error) {
 		SkipDefaultTransaction: false,
 	}
 
+	switch opt.Driver {
+	case "", DriverMySQL:
+	case DriverSQLite:
+		return openSQLite(opt, config)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", opt.Driver)
+	}
+
 	gdb, err := gorm.Open(mysql.Open(opt.DSN), config)
 	if err != nil {
 		return nil, err
@@ -103,59 +113,19 @@ func OpenMySQL(opt Options) (Database, error) {
 	}
 
 	// 死锁优化：设置连接最大等待时间，避免长时间等待导致的死锁
-	sqlDB.SetConnMaxLifetime(30 * time.Minute)  // 连接最大生存时间
-	sqlDB.SetConnMaxIdleTime(10 * time.Minute)   // 连接最大空闲时间
+	sqlDB.SetConnMaxLifetime(30 * time.Minute) // 连接最大生存时间
+	sqlDB.SetConnMaxIdleTime(10 * time.Minute) // 连接最大空闲时间
 
 	// 优化查询超时设置，减少死锁等待时间
-	gdb.Exec("SET SESSION innodb_lock_wait_timeout = 10")     // InnoDB锁等待超时10秒
+	gdb.Exec("SET SESSION innodb_lock_wait_timeout = 10")            // InnoDB锁等待超时10秒
 	gdb.Exec("SET SESSION transaction_isolation = 'READ-COMMITTED'") // 使用读已提交隔离级别，减少锁竞争
 
 	if opt.Automigrate {
+		if err := dropLegacyIndexes(gdb); err != nil {
+			return nil, err
+		}
 		// 使用 Set 方法确保字段会被添加/修改
-		if err := gdb.Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4").AutoMigrate(
-			&PortfolioSnapshot{},
-			&Holding{},
-			&WeeklyFlow{},
-			&DailyFlow{},
-			&TransferEvent{},
-			&TransferCursor{},
-			&ArkhamWatch{},
-			&WhaleWatch{},
-			&ScheduledOrder{},
-			&BracketLink{},
-			&BinanceMarketSnapshot{},
-			&BinanceMarketTop{},
-			&BinanceSymbolBlacklist{},
-			&Announcement{},
-			&TwitterPost{},
-			&User{},
-			&CoinRecommendation{},
-			&BacktestRecord{},
-			&SimulatedTrade{},
-			&RecommendationPerformance{},
-			&MarketKline{},
-			&PriceCache{},
-			&TechnicalIndicatorsCache{},
-			&FeatureCache{},
-			&MLModel{},
-			&AutoExecuteSettings{},
-			&CoinCapAssetMapping{},
-			&CoinCapMarketData{},
-			&StrategyExecution{},
-			&StrategyExecutionStep{},
-			&BinanceExchangeInfo{},
-			&BinanceFuturesContract{},
-			&BinanceFundingRate{},
-			&BinanceOrderBookDepth{},
-			&Binance24hStats{},
-			&Binance24hStatsHistory{}, // 24小时统计数据历史表
-			&BinanceTrade{},
-			&FilterCorrection{},
-			// 新增的系统完善相关的表
-			&ExternalOperation{}, // 外部操作记录
-			&OperationLog{},      // 操作日志记录
-			&AuditTrail{},        // 审计追踪记录
-		); err != nil {
+		if err := gdb.Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8mb4").AutoMigrate(migrateModels()...); err != nil {
 			return nil, fmt.Errorf("AutoMigrate failed: %w", err)
 		}
 
@@ -167,3 +137,115 @@ func OpenMySQL(opt Options) (Database, error) {
 	}
 	return NewDatabase(gdb), nil
 }
+
+// Options.Driver 取值
+const (
+	DriverMySQL  = "mysql"
+	DriverSQLite = "sqlite"
+)
+
+// OpenSQLite 以 SQLite 打开数据库（忽略 opt.Driver），用于测试与轻量部署
+func OpenSQLite(opt Options) (Database, error) {
+	return openSQLite(opt, &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Warn),
+		SkipDefaultTransaction: false,
+	})
+}
+
+// openSQLite SQLite 连接：不执行 MySQL 专有的会话设置与索引优化；
+// 内存库每个连接各自独立，因此限制为单连接。
+// 注意 SQLite 的索引名在库内全局唯一，模型新增命名索引时不能与其他表重名。
+func openSQLite(opt Options, config *gorm.Config) (Database, error) {
+	config.PrepareStmt = false
+	gdb, err := gorm.Open(sqlite.Open(opt.DSN), config)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := gdb.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if opt.Automigrate {
+		if err := dropLegacyIndexes(gdb); err != nil {
+			return nil, err
+		}
+		if err := gdb.AutoMigrate(migrateModels()...); err != nil {
+			return nil, fmt.Errorf("AutoMigrate failed: %w", err)
+		}
+	}
+	return NewDatabase(gdb), nil
+}
+
+// legacyIndexes 模型改名后遗留在旧库中的索引（AutoMigrate 只建新索引、不删旧索引）
+var legacyIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&MLModel{}, "idx_expires_cleanup"}, // 已改名为 idx_ml_models_expires，避免与 feature_cache 的同名索引在 SQLite 上冲突
+}
+
+// dropLegacyIndexes 删除遗留的旧索引，须在 AutoMigrate 之前执行
+func dropLegacyIndexes(gdb *gorm.DB) error {
+	m := gdb.Migrator()
+	for _, idx := range legacyIndexes {
+		if !m.HasTable(idx.model) || !m.HasIndex(idx.model, idx.name) {
+			continue
+		}
+		if err := m.DropIndex(idx.model, idx.name); err != nil {
+			return fmt.Errorf("drop legacy index %s: %w", idx.name, err)
+		}
+	}
+	return nil
+}
+
+// migrateModels 自动迁移的表
+func migrateModels() []interface{} {
+	return []interface{}{
+		&PortfolioSnapshot{},
+		&Holding{},
+		&WeeklyFlow{},
+		&DailyFlow{},
+		&TransferEvent{},
+		&NetFlow{},
+		&TransferCursor{},
+		&ArkhamWatch{},
+		&WhaleWatch{},
+		&ScheduledOrder{},
+		&BracketLink{},
+		&BinanceMarketSnapshot{},
+		&BinanceMarketTop{},
+		&BinanceSymbolBlacklist{},
+		&Announcement{},
+		&AnnouncementImpact{},
+		&ExchangePriceQuote{},
+		&TwitterPost{},
+		&User{},
+		&CoinRecommendation{},
+		&BacktestRecord{},
+		&SimulatedTrade{},
+		&RecommendationPerformance{},
+		&MarketKline{},
+		&PriceCache{},
+		&TechnicalIndicatorsCache{},
+		&FeatureCache{},
+		&MLModel{},
+		&AutoExecuteSettings{},
+		&CoinCapAssetMapping{},
+		&CoinCapMarketData{},
+		&StrategyExecution{},
+		&StrategyExecutionStep{},
+		&BinanceExchangeInfo{},
+		&BinanceFuturesContract{},
+		&BinanceFundingRate{},
+		&BinanceOrderBookDepth{},
+		&Binance24hStats{},
+		&Binance24hStatsHistory{}, // 24小时统计数据历史表
+		&BinanceTrade{},
+		&FilterCorrection{},
+		// 新增的系统完善相关的表
+		&ExternalOperation{}, // 外部操作记录
+		&OperationLog{},      // 操作日志记录
+		&AuditTrail{},        // 审计追踪记录
+	}
+}
@@ -79,6 +79,7 @@ func OpenMySQLOptimized(opt OptimizedOptions) (*gorm.DB, error) {
 			&WeeklyFlow{},
 			&DailyFlow{},
 			&TransferEvent{},
+			&NetFlow{},
 			&TransferCursor{},
 			&ScheduledOrder{},
 			&BracketLink{},
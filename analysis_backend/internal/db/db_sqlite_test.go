@@ -0,0 +1,217 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"analysis/internal/models"
+)
+
+// openTestSQLite 以内存 SQLite 打开并执行全部模型迁移
+func openTestSQLite(t *testing.T) Database {
+	t.Helper()
+	database, err := OpenMySQL(Options{Driver: DriverSQLite, DSN: "file::memory:", Automigrate: true})
+	if err != nil {
+		t.Fatalf("sqlite 迁移失败: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// TestSQLiteAutoMigrateAllModels 全部模型都能在 SQLite 上建表
+func TestSQLiteAutoMigrateAllModels(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	for _, m := range migrateModels() {
+		if !gdb.Migrator().HasTable(m) {
+			t.Errorf("表未创建: %T", m)
+		}
+	}
+}
+
+// TestDropLegacyMLModelIndex 旧库 ml_models 上的 idx_expires_cleanup 在迁移前删除，
+// 之后 feature_cache 才能建同名索引（SQLite 索引名库内全局唯一）
+func TestDropLegacyMLModelIndex(t *testing.T) {
+	database, err := OpenSQLite(Options{DSN: "file::memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	gdb := database.GormDB()
+	m := gdb.Migrator()
+
+	// 模拟改名前的库结构
+	if err := gdb.AutoMigrate(&MLModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DropIndex(&MLModel{}, "idx_ml_models_expires"); err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.Exec("CREATE INDEX idx_expires_cleanup ON ml_models(expires_at)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dropLegacyIndexes(gdb); err != nil {
+		t.Fatalf("删除旧索引失败: %v", err)
+	}
+	if m.HasIndex(&MLModel{}, "idx_expires_cleanup") {
+		t.Error("ml_models 的旧索引 idx_expires_cleanup 应被删除")
+	}
+	if err := gdb.AutoMigrate(&FeatureCache{}); err != nil {
+		t.Fatalf("feature_cache 迁移失败: %v", err)
+	}
+	if !m.HasIndex(&FeatureCache{}, "idx_expires_cleanup") {
+		t.Error("feature_cache 应有索引 idx_expires_cleanup")
+	}
+	// 已删除或表不存在时再次执行不报错
+	if err := dropLegacyIndexes(gdb); err != nil {
+		t.Fatalf("重复执行不应报错: %v", err)
+	}
+}
+
+// TestSQLiteTransferEventsAndCursor 转账事件去重写入、回读与游标读写
+func TestSQLiteTransferEventsAndCursor(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	ts := time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1.5", TS: ts, TxID: "0xa", LogIndex: -1},
+		{Chain: "ethereum", Coin: "USDT", Direction: "out", Amount: "250", TS: ts.Add(time.Hour), TxID: "0xb", LogIndex: 3},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "0", TS: ts, TxID: "0xc", LogIndex: -1},
+	}
+	inserted, err := SaveTransferEvents(gdb, "run-1", "acme", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inserted) != 2 {
+		t.Fatalf("期望写入 2 条（过滤零金额），实际 %d", len(inserted))
+	}
+	// 重复写入被唯一键忽略
+	if _, err := SaveTransferEvents(gdb, "run-2", "acme", events[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadTransferEvents(gdb, "acme", ts.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].TxID != "0xa" || got[1].TxID != "0xb" || got[1].Direction != "out" {
+		t.Fatalf("回读事件不符: %+v", got)
+	}
+	if !got[0].TS.Equal(ts) {
+		t.Errorf("发生时间期望 %v，实际 %v", ts, got[0].TS)
+	}
+
+	if err := UpsertCursor(gdb, "acme", "ethereum", 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpsertCursor(gdb, "acme", "ethereum", 120); err != nil {
+		t.Fatal(err)
+	}
+	if cur, err := GetCursor(gdb, "acme", "ethereum"); err != nil || cur != 120 {
+		t.Fatalf("游标期望 120，实际 %d (err=%v)", cur, err)
+	}
+}
+
+// TestSaveTransferEventsReplacesOrphanedBlocks 重组后带新区块哈希重新下发：孤块上的旧事件被替换，未带哈希的事件不受影响
+func TestSaveTransferEventsReplacesOrphanedBlocks(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	ts := time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC)
+	orphaned := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "100", TS: ts, TxID: "0xa", LogIndex: 1, BlockNumber: 10, BlockHash: "0xOLD10"},
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "7", TS: ts, TxID: "0xdropped", LogIndex: 2, BlockNumber: 10, BlockHash: "0xold10"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "1", TS: ts, TxID: "0xkeep", LogIndex: -1, BlockNumber: 9, BlockHash: "0xh9"},
+		{Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "2", TS: ts, TxID: "0xbtc", LogIndex: -1},
+	}
+	if _, err := SaveTransferEvents(gdb, "run-1", "acme", orphaned); err != nil {
+		t.Fatal(err)
+	}
+
+	// 0xa 重新打包进 11 号新区块，0xdropped 未被打包
+	canonical := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "100", TS: ts.Add(time.Minute), TxID: "0xa", LogIndex: 0, BlockNumber: 11, BlockHash: "0xnew11"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "3", TS: ts, TxID: "0xc", LogIndex: -1, BlockNumber: 10, BlockHash: "0xnew10"},
+	}
+	inserted, err := SaveTransferEvents(gdb, "run-2", "acme", canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inserted) != 2 {
+		t.Fatalf("期望写入 2 条新区块事件，实际 %d", len(inserted))
+	}
+
+	var rows []TransferEvent
+	gdb.Order("tx_id").Find(&rows)
+	got := map[string]string{}
+	for _, r := range rows {
+		got[r.TxID] = r.BlockHash
+	}
+	want := map[string]string{"0xa": "0xnew11", "0xc": "0xnew10", "0xkeep": "0xh9", "0xbtc": ""}
+	if len(got) != len(want) {
+		t.Fatalf("重组后事件不符: %v", got)
+	}
+	for tx, h := range want {
+		if got[tx] != h {
+			t.Errorf("%s 区块哈希期望 %q，实际 %q", tx, h, got[tx])
+		}
+	}
+}
+
+// TestDeleteNonCanonicalTransfersEmptyRescan 重组后重扫的区间没有任何新事件：按上报的规范哈希删除区间内的孤块事件，
+// 区间外、哈希一致、其他实体/链以及未带哈希的事件保留
+func TestDeleteNonCanonicalTransfersEmptyRescan(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	ts := time.Date(2025, 9, 1, 8, 0, 0, 0, time.UTC)
+	stored := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "100", TS: ts, TxID: "0xorphan10", LogIndex: 1, BlockNumber: 10, BlockHash: "0xOLD10"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "2", TS: ts, TxID: "0xorphan12", LogIndex: -1, BlockNumber: 12, BlockHash: "0xold12"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TS: ts, TxID: "0xkeep11", LogIndex: -1, BlockNumber: 11, BlockHash: "0xh11"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TS: ts, TxID: "0xbefore", LogIndex: -1, BlockNumber: 9, BlockHash: "0xold9"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TS: ts, TxID: "0xnohash", LogIndex: -1, BlockNumber: 10},
+		{Chain: "bsc", Coin: "BNB", Direction: "in", Amount: "1", TS: ts, TxID: "0xbsc", LogIndex: -1, BlockNumber: 10, BlockHash: "0xbsc10"},
+	}
+	if _, err := SaveTransferEvents(gdb, "run-1", "acme", stored); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveTransferEvents(gdb, "run-1", "other", stored[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	// 规范链重扫 10..12 没有产生任何事件，SaveTransferEvents 不会被调用，只上报规范哈希
+	canonical := map[uint64]string{10: "0xnew10", 11: "0xH11", 12: "0xnew12"}
+	deleted, err := DeleteNonCanonicalTransfers(gdb, "acme", "ethereum", 10, 12, canonical)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 2 {
+		t.Fatalf("期望删除 2 条孤块事件，实际 %d: %+v", len(deleted), deleted)
+	}
+
+	var left []string
+	gdb.Model(&TransferEvent{}).Where("entity = ?", "acme").Order("tx_id").Pluck("tx_id", &left)
+	want := []string{"0xbefore", "0xbsc", "0xkeep11", "0xnohash"}
+	if len(left) != len(want) {
+		t.Fatalf("剩余事件期望 %v，实际 %v", want, left)
+	}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Errorf("剩余事件期望 %v，实际 %v", want, left)
+			break
+		}
+	}
+	var others int64
+	gdb.Model(&TransferEvent{}).Where("entity = ?", "other").Count(&others)
+	if others != 1 {
+		t.Errorf("其他实体的事件不应被删除，剩余 %d", others)
+	}
+
+	// 再次上报同一区间不再删除
+	if deleted, err := DeleteNonCanonicalTransfers(gdb, "acme", "ethereum", 10, 12, canonical); err != nil || len(deleted) != 0 {
+		t.Errorf("重复上报不应再删除: %d %v", len(deleted), err)
+	}
+}
+
+// TestOpenUnsupportedDriver 未知驱动直接报错，不回退到 MySQL
+func TestOpenUnsupportedDriver(t *testing.T) {
+	if _, err := OpenMySQL(Options{Driver: "postgres", DSN: "x"}); err == nil {
+		t.Fatal("期望未知驱动报错")
+	}
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ExchangePriceQuote 单个交易所的最新报价；多交易所聚合后的权威价格仍写入 price_caches
+type ExchangePriceQuote struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Symbol      string    `gorm:"size:32;uniqueIndex:uk_exchange_quote,priority:1" json:"symbol"`
+	Kind        string    `gorm:"size:16;uniqueIndex:uk_exchange_quote,priority:2" json:"kind"` // spot / futures
+	Exchange    string    `gorm:"size:16;uniqueIndex:uk_exchange_quote,priority:3" json:"exchange"`
+	Price       float64   `json:"price"`
+	QuoteVolume float64   `json:"quote_volume"` // 24h 成交额（USDT），作为 VWAP 权重
+	Outlier     bool      `json:"outlier"`      // 本轮因偏离过大被剔除
+	LastUpdated time.Time `gorm:"index" json:"last_updated"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SaveExchangePriceQuotes 按 (symbol, kind, exchange) 覆盖保存各交易所报价
+func SaveExchangePriceQuotes(gdb *gorm.DB, quotes []ExchangePriceQuote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+	return gdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "symbol"}, {Name: "kind"}, {Name: "exchange"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price", "quote_volume", "outlier", "last_updated", "updated_at"}),
+	}).Create(&quotes).Error
+}
+
+// ListExchangePriceQuotes 读取某交易对各交易所的最新报价
+func ListExchangePriceQuotes(gdb *gorm.DB, symbol, kind string) ([]ExchangePriceQuote, error) {
+	var out []ExchangePriceQuote
+	err := gdb.Where("symbol = ? AND kind = ?", symbol, kind).Order("exchange ASC").Find(&out).Error
+	return out, err
+}
@@ -52,4 +52,16 @@ func SaveFundingRates(gdb *gorm.DB, rates []BinanceFundingRate) error {
 	}
 
 	return tx.Commit().Error
-}
\ No newline at end of file
+}
+
+// GetFundingRates 查询 [start, end] 内的资金费率，按结算时间升序（funding_time 为毫秒时间戳）
+func GetFundingRates(gdb *gorm.DB, symbol string, start, end time.Time) ([]BinanceFundingRate, error) {
+	var rates []BinanceFundingRate
+	err := gdb.Where("symbol = ? AND funding_time >= ? AND funding_time <= ?", symbol, start.UnixMilli(), end.UnixMilli()).
+		Order("funding_time ASC").
+		Find(&rates).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询资金费率失败 %s: %w", symbol, err)
+	}
+	return rates, nil
+}
@@ -4,9 +4,12 @@ package db
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
+	"analysis/internal/util"
+
 	"gorm.io/gorm"
 )
 
@@ -63,6 +66,21 @@ type RealtimeGainersItem struct {
 	Confidence         *float64 `gorm:"type:DOUBLE" json:"confidence,omitempty"`
 }
 
+// 榜单类型：涨幅榜沿用原 kind（spot / futures），跌幅榜以 kind + "_losers" 单独存储，
+// 这样按 kind 查询的现有读取方只会看到涨幅榜
+const (
+	MarketBoardGainers = "gainers"
+	MarketBoardLosers  = "losers"
+)
+
+// MarketBoardKind 榜单在快照表中的 kind 值
+func MarketBoardKind(kind, board string) string {
+	if board == MarketBoardLosers {
+		return kind + "_" + MarketBoardLosers
+	}
+	return kind
+}
+
 // 保存一整份快照（同 kind+bucket 会被覆盖）
 func SaveBinanceMarket(gdb *gorm.DB, kind string, bucket, fetchedAt time.Time, items []BinanceMarketTop) (*BinanceMarketSnapshot, error) {
 	snap := &BinanceMarketSnapshot{
@@ -108,6 +126,36 @@ func SaveBinanceMarket(gdb *gorm.DB, kind string, bucket, fetchedAt time.Time, i
 	return snap, nil
 }
 
+// SaveBinanceMarketWithRetry 遇到死锁/锁等待/连接类错误时整份快照重试写入（每次都是完整事务）
+func SaveBinanceMarketWithRetry(gdb *gorm.DB, kind string, bucket, fetchedAt time.Time, items []BinanceMarketTop, maxAttempts int) (*BinanceMarketSnapshot, error) {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		// 回滚的事务可能已回填主键，每次重试用干净的副本
+		batch := make([]BinanceMarketTop, len(items))
+		copy(batch, items)
+		for i := range batch {
+			batch[i].ID = 0
+		}
+		snap, err := SaveBinanceMarket(gdb, kind, bucket, fetchedAt, batch)
+		if err == nil {
+			return snap, nil
+		}
+		lastErr = err
+		errorType := classifyDatabaseError(err)
+		if !isRetryableError(errorType) || attempt == maxAttempts {
+			break
+		}
+		delay := calculateBackoffDelay(attempt, errorType, 200*time.Millisecond)
+		log.Printf("[SaveBinanceMarket] %s %s 写入失败（%s），%v 后重试 %d/%d: %v",
+			kind, bucket.Format(time.RFC3339), errorType, delay, attempt, maxAttempts, err)
+		time.Sleep(delay)
+	}
+	return nil, lastErr
+}
+
 // 按时间范围读取快照 + TOP
 func ListBinanceMarket(gdb *gorm.DB, kind string, start, end time.Time) ([]BinanceMarketSnapshot, map[uint][]BinanceMarketTop, error) {
 	var snaps []BinanceMarketSnapshot
@@ -461,42 +509,42 @@ func CompressOldMarketData(gdb *gorm.DB, compressAfterDays int) error {
 	})
 }
 
+// DailyMarketSnapshot 日聚合快照（由小时快照压缩而来）
+type DailyMarketSnapshot struct {
+	ID        uint      `gorm:"primaryKey"`
+	Kind      string    `gorm:"size:16;index:idx_daily_kind_date,priority:1"`
+	Date      time.Time `gorm:"type:date;index:idx_daily_kind_date,priority:2"` // YYYY-MM-DD
+	DataCount int       `gorm:"default:0"`                                      // 当天数据点数量
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// DailyMarketTop 日聚合市场数据
+type DailyMarketTop struct {
+	ID              uint      `gorm:"primaryKey"`
+	DailySnapshotID uint      `gorm:"index"`
+	Symbol          string    `gorm:"size:32;index:idx_daily_symbol_date"`
+	Date            time.Time `gorm:"type:date;index:idx_daily_symbol_date"`
+	AvgPrice        float64   `gorm:"type:decimal(20,8)"` // 日均价
+	OpenPrice       float64   `gorm:"type:decimal(20,8)"` // 开盘价
+	HighPrice       float64   `gorm:"type:decimal(20,8)"` // 最高价
+	LowPrice        float64   `gorm:"type:decimal(20,8)"` // 最低价
+	ClosePrice      float64   `gorm:"type:decimal(20,8)"` // 收盘价
+	AvgVolume       float64   `gorm:"type:decimal(20,8)"` // 日均成交量
+	MaxVolume       float64   `gorm:"type:decimal(20,8)"` // 最大成交量
+	AvgPctChange    float64   `gorm:"type:decimal(10,4)"` // 日均涨跌幅
+	MaxPctChange    float64   `gorm:"type:decimal(10,4)"` // 最大涨跌幅
+	MinPctChange    float64   `gorm:"type:decimal(10,4)"` // 最小涨跌幅
+	AvgMarketCapUSD *float64  `gorm:"type:decimal(20,2)"` // 日均市值
+	DataPoints      int       `gorm:"default:0"`          // 数据点数量
+	CreatedAt       time.Time
+}
+
 // createDailyAggregationTable 创建日聚合数据表
 func createDailyAggregationTable(tx *gorm.DB) error {
 	// 注意：这里使用GORM的AutoMigrate来创建表
 	// 在实际使用时，可能需要手动创建表以获得更好的控制
 
-	// 日聚合快照表
-	type DailyMarketSnapshot struct {
-		ID        uint      `gorm:"primaryKey"`
-		Kind      string    `gorm:"size:16;index:idx_daily_kind_date,priority:1"`
-		Date      time.Time `gorm:"type:date;index:idx_daily_kind_date,priority:2"` // YYYY-MM-DD
-		DataCount int       `gorm:"default:0"`                                      // 当天数据点数量
-		CreatedAt time.Time
-		UpdatedAt time.Time
-	}
-
-	// 日聚合市场数据表
-	type DailyMarketTop struct {
-		ID              uint      `gorm:"primaryKey"`
-		DailySnapshotID uint      `gorm:"index"`
-		Symbol          string    `gorm:"size:32;index:idx_daily_symbol_date"`
-		Date            time.Time `gorm:"type:date;index:idx_daily_symbol_date"`
-		AvgPrice        float64   `gorm:"type:decimal(20,8)"` // 日均价
-		OpenPrice       float64   `gorm:"type:decimal(20,8)"` // 开盘价
-		HighPrice       float64   `gorm:"type:decimal(20,8)"` // 最高价
-		LowPrice        float64   `gorm:"type:decimal(20,8)"` // 最低价
-		ClosePrice      float64   `gorm:"type:decimal(20,8)"` // 收盘价
-		AvgVolume       float64   `gorm:"type:decimal(20,8)"` // 日均成交量
-		MaxVolume       float64   `gorm:"type:decimal(20,8)"` // 最大成交量
-		AvgPctChange    float64   `gorm:"type:decimal(10,4)"` // 日均涨跌幅
-		MaxPctChange    float64   `gorm:"type:decimal(10,4)"` // 最大涨跌幅
-		MinPctChange    float64   `gorm:"type:decimal(10,4)"` // 最小涨跌幅
-		AvgMarketCapUSD *float64  `gorm:"type:decimal(20,2)"` // 日均市值
-		DataPoints      int       `gorm:"default:0"`          // 数据点数量
-		CreatedAt       time.Time
-	}
-
 	// 创建表（如果不存在）
 	if err := tx.AutoMigrate(&DailyMarketSnapshot{}, &DailyMarketTop{}); err != nil {
 		log.Printf("[MarketDataCompression] Warning: failed to auto-migrate daily tables: %v", err)
@@ -570,24 +618,111 @@ func aggregateSingleDay(tx *gorm.DB, date time.Time) error {
 		return nil
 	}
 
-	// 按交易对分组聚合数据
-	symbolGroups := make(map[string][]BinanceMarketTop)
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	snapByID := make(map[uint]BinanceMarketSnapshot, len(snapshots))
+	snapCount := make(map[string]int)
+	for _, snap := range snapshots {
+		snapByID[snap.ID] = snap
+		snapCount[snap.Kind]++
+	}
+
+	// 按 kind -> 交易对分组
+	groups := make(map[string]map[string][]BinanceMarketTop)
 	for _, data := range marketData {
-		symbolGroups[data.Symbol] = append(symbolGroups[data.Symbol], data)
+		kind := snapByID[data.SnapshotID].Kind
+		if groups[kind] == nil {
+			groups[kind] = make(map[string][]BinanceMarketTop)
+		}
+		groups[kind][data.Symbol] = append(groups[kind][data.Symbol], data)
 	}
 
-	// 创建日聚合记录（这里简化实现，实际应该插入到日表中）
-	// 注意：这里只是演示，实际实现需要创建具体的日表结构
-	log.Printf("[MarketDataCompression] Would aggregate %d symbols for date %s", len(symbolGroups), dateStr)
+	for kind, symbols := range groups {
+		// 重复压缩同一天时先清掉旧的日聚合
+		var oldIDs []uint
+		if err := tx.Model(&DailyMarketSnapshot{}).Where("kind = ? AND date = ?", kind, dayStart).Pluck("id", &oldIDs).Error; err != nil {
+			return err
+		}
+		if len(oldIDs) > 0 {
+			if err := tx.Where("daily_snapshot_id IN ?", oldIDs).Delete(&DailyMarketTop{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", oldIDs).Delete(&DailyMarketSnapshot{}).Error; err != nil {
+				return err
+			}
+		}
+
+		daily := DailyMarketSnapshot{Kind: kind, Date: dayStart, DataCount: snapCount[kind]}
+		if err := tx.Create(&daily).Error; err != nil {
+			return err
+		}
 
-	// 实际实现时，应该：
-	// 1. 为每种kind（spot/futures）创建日快照记录
-	// 2. 为每个交易对计算日聚合数据
-	// 3. 插入到日表中
+		rows := make([]DailyMarketTop, 0, len(symbols))
+		for symbol, items := range symbols {
+			if row, ok := aggregateDailyTop(dayStart, items, snapByID); ok {
+				row.DailySnapshotID = daily.ID
+				row.Symbol = symbol
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("[MarketDataCompression] Aggregated %d %s symbols for date %s", len(rows), kind, dateStr)
+	}
 
 	return nil
 }
 
+// aggregateDailyTop 用当天各小时快照计算单个交易对的日 OHLC / 均值
+func aggregateDailyTop(dayStart time.Time, items []BinanceMarketTop, snapByID map[uint]BinanceMarketSnapshot) (DailyMarketTop, bool) {
+	var prices, volumes, pcts, caps []util.TimePoint
+	for _, it := range items {
+		at := snapByID[it.SnapshotID].Bucket
+		if p, err := strconv.ParseFloat(it.LastPrice, 64); err == nil && p > 0 {
+			prices = append(prices, util.TimePoint{Time: at, Value: p})
+		}
+		if v, err := strconv.ParseFloat(it.Volume, 64); err == nil {
+			volumes = append(volumes, util.TimePoint{Time: at, Value: v})
+		}
+		pcts = append(pcts, util.TimePoint{Time: at, Value: it.PctChange})
+		if it.MarketCapUSD != nil {
+			caps = append(caps, util.TimePoint{Time: at, Value: *it.MarketCapUSD})
+		}
+	}
+
+	// 夏令时切换日不是 24h，桶长取当天实际时长，保证整天落在一个桶里
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	day := func(points []util.TimePoint) util.TimeBucket {
+		return util.DownsampleRange(points, dayStart, dayEnd, dayEnd.Sub(dayStart))[0]
+	}
+	price := day(prices)
+	if price.Count == 0 {
+		return DailyMarketTop{}, false
+	}
+	volume := day(volumes)
+	pct := day(pcts)
+	row := DailyMarketTop{
+		Date:         dayStart,
+		AvgPrice:     price.Mean,
+		OpenPrice:    price.Open,
+		HighPrice:    price.High,
+		LowPrice:     price.Low,
+		ClosePrice:   price.Close,
+		AvgVolume:    volume.Mean,
+		MaxVolume:    volume.High,
+		AvgPctChange: pct.Mean,
+		MaxPctChange: pct.High,
+		MinPctChange: pct.Low,
+		DataPoints:   price.Count,
+	}
+	if c := day(caps); c.Count > 0 {
+		row.AvgMarketCapUSD = &c.Mean
+	}
+	return row, true
+}
+
 // deleteAggregatedHourlyData 删除已聚合的小时数据
 func deleteAggregatedHourlyData(tx *gorm.DB, cutoffDate time.Time) error {
 	log.Printf("[MarketDataCompression] Deleting aggregated hourly data older than %s", cutoffDate.Format("2006-01-02"))
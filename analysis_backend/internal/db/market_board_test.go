@@ -0,0 +1,86 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMarketBoardsSaveReload 涨幅榜 / 跌幅榜写入后按 kind 读回内容与排名一致；同槽重写只覆盖对应榜单
+func TestMarketBoardsSaveReload(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&BinanceMarketSnapshot{}, &BinanceMarketTop{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	bucket := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	fetched := bucket.Add(5 * time.Minute)
+	mcap := 1.5e9
+	gainers := []BinanceMarketTop{
+		{Symbol: "AAAUSDT", LastPrice: "1.2", Volume: "100", PctChange: 25, MarketCapUSD: &mcap},
+		{Symbol: "BBBUSDT", LastPrice: "3.4", Volume: "200", PctChange: 12},
+	}
+	losers := []BinanceMarketTop{
+		{Symbol: "ZZZUSDT", LastPrice: "0.5", Volume: "300", PctChange: -18},
+		{Symbol: "YYYUSDT", LastPrice: "7.8", Volume: "400", PctChange: -9},
+		{Symbol: "XXXUSDT", LastPrice: "9.1", Volume: "500", PctChange: -4},
+	}
+	losersKind := MarketBoardKind("futures", MarketBoardLosers)
+	if _, err := SaveBinanceMarket(gdb, MarketBoardKind("futures", MarketBoardGainers), bucket, fetched, gainers); err != nil {
+		t.Fatalf("保存涨幅榜失败: %v", err)
+	}
+	if _, err := SaveBinanceMarket(gdb, losersKind, bucket, fetched, losers); err != nil {
+		t.Fatalf("保存跌幅榜失败: %v", err)
+	}
+
+	reload := func(kind string) []BinanceMarketTop {
+		t.Helper()
+		snaps, tops, err := ListBinanceMarket(gdb, kind, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatalf("%s: 读取失败: %v", kind, err)
+		}
+		if len(snaps) != 1 || !snaps[0].Bucket.Equal(bucket) || !snaps[0].FetchedAt.Equal(fetched) {
+			t.Fatalf("%s: 期望 1 个 %s 快照，实际 %+v", kind, bucket, snaps)
+		}
+		return tops[snaps[0].ID]
+	}
+	check := func(kind string, got, want []BinanceMarketTop) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: 期望 %d 条，实际 %d", kind, len(want), len(got))
+		}
+		for i := range want {
+			g, w := got[i], want[i]
+			if g.Symbol != w.Symbol || g.Rank != i+1 || g.PctChange != w.PctChange || g.LastPrice != w.LastPrice || g.Volume != w.Volume {
+				t.Errorf("%s 第 %d 名不一致: got %+v want %+v", kind, i+1, g, w)
+			}
+		}
+	}
+
+	gotGainers := reload("futures")
+	check("futures", gotGainers, gainers)
+	if gotGainers[0].MarketCapUSD == nil || *gotGainers[0].MarketCapUSD != mcap || gotGainers[1].MarketCapUSD != nil {
+		t.Errorf("市值字段未按原值读回: %+v", gotGainers)
+	}
+	check(losersKind, reload(losersKind), losers)
+
+	// 同槽重写跌幅榜：旧条目被替换，涨幅榜不受影响
+	rewritten := []BinanceMarketTop{{Symbol: "WWWUSDT", LastPrice: "2", Volume: "1", PctChange: -30}}
+	if _, err := SaveBinanceMarket(gdb, losersKind, bucket, fetched, rewritten); err != nil {
+		t.Fatalf("重写跌幅榜失败: %v", err)
+	}
+	check(losersKind, reload(losersKind), rewritten)
+	check("futures", reload("futures"), gainers)
+
+	var orphans int64
+	gdb.Model(&BinanceMarketTop{}).Where("snapshot_id NOT IN (?)", gdb.Model(&BinanceMarketSnapshot{}).Select("id")).Count(&orphans)
+	if orphans != 0 {
+		t.Errorf("重写后不应残留旧快照条目，实际 %d 条", orphans)
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ComputeNetFlows 把一次写入的转账事件按 实体/币种 汇总为净流量（Net = sum(in) - sum(out)），
+// 结果按 实体、币种 排序；方向不是 in/out 或金额无法解析的事件不计入
+func ComputeNetFlows(runID string, rows []TransferEvent) []NetFlow {
+	type key struct{ entity, coin string }
+	type acc struct {
+		in, out    *big.Float
+		events     int
+		start, end time.Time
+	}
+	sums := map[key]*acc{}
+	for _, r := range rows {
+		amt, ok := new(big.Float).SetPrec(256).SetString(strings.TrimSpace(r.Amount))
+		if !ok || (r.Direction != "in" && r.Direction != "out") {
+			continue
+		}
+		k := key{r.Entity, strings.ToUpper(r.Coin)}
+		a := sums[k]
+		if a == nil {
+			a = &acc{in: new(big.Float).SetPrec(256), out: new(big.Float).SetPrec(256)}
+			a.start, a.end = r.OccurredAt, r.OccurredAt
+			sums[k] = a
+		}
+		if r.Direction == "in" {
+			a.in.Add(a.in, amt)
+		} else {
+			a.out.Add(a.out, amt)
+		}
+		a.events++
+		if r.OccurredAt.Before(a.start) {
+			a.start = r.OccurredAt
+		}
+		if r.OccurredAt.After(a.end) {
+			a.end = r.OccurredAt
+		}
+	}
+
+	out := make([]NetFlow, 0, len(sums))
+	for k, a := range sums {
+		net := new(big.Float).SetPrec(256).Sub(a.in, a.out)
+		out = append(out, NetFlow{
+			RunID: runID, Entity: k.entity, Coin: k.coin,
+			In: fstr(a.in, 18), Out: fstr(a.out, 18), Net: fstr(net, 18),
+			Events: a.events, WindowStart: a.start, WindowEnd: a.end,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Entity != out[j].Entity {
+			return out[i].Entity < out[j].Entity
+		}
+		return out[i].Coin < out[j].Coin
+	})
+	return out
+}
+
+// SaveNetFlows 写入净流量汇总；同一 run_id/实体/币种 重复写入时忽略
+func SaveNetFlows(gdb *gorm.DB, flows []NetFlow) error {
+	if len(flows) == 0 {
+		return nil
+	}
+	return gdb.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(flows, 500).Error
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+// TestComputeNetFlowsPerCoin 净流量按 实体/币种 汇总，等于 sum(in) - sum(out)，跨链同币种合并
+func TestComputeNetFlowsPerCoin(t *testing.T) {
+	t0 := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	rows := []TransferEvent{
+		{Entity: "acme", Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "1000.5", OccurredAt: t0.Add(2 * time.Minute)},
+		{Entity: "acme", Chain: "tron", Coin: "usdt", Direction: "in", Amount: "250", OccurredAt: t0},
+		{Entity: "acme", Chain: "ethereum", Coin: "USDT", Direction: "out", Amount: "300.25", OccurredAt: t0.Add(time.Minute)},
+		{Entity: "acme", Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "2", OccurredAt: t0},
+		{Entity: "acme", Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "0.5", OccurredAt: t0},
+		{Entity: "other", Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "0.1", OccurredAt: t0},
+		{Entity: "acme", Chain: "ethereum", Coin: "ETH", Direction: "self", Amount: "9", OccurredAt: t0}, // 未知方向不计
+	}
+	flows := ComputeNetFlows("run-1", rows)
+	if len(flows) != 3 {
+		t.Fatalf("期望 3 条汇总，实际 %+v", flows)
+	}
+	want := []struct{ entity, coin, in, out, net string }{
+		{"acme", "ETH", "0.5", "2.0", "-1.5"},
+		{"acme", "USDT", "1250.5", "300.25", "950.25"},
+		{"other", "BTC", "0.1", "0.0", "0.1"},
+	}
+	for i, w := range want {
+		f := flows[i]
+		if f.Entity != w.entity || f.Coin != w.coin || f.In != w.in || f.Out != w.out || f.Net != w.net || f.RunID != "run-1" {
+			t.Errorf("第 %d 条汇总不符: %+v, want %+v", i, f, w)
+		}
+	}
+	if usdt := flows[1]; usdt.Events != 3 || !usdt.WindowStart.Equal(t0) || !usdt.WindowEnd.Equal(t0.Add(2*time.Minute)) {
+		t.Errorf("USDT 事件数/时间范围不符: %+v", usdt)
+	}
+
+	gdb := openTestSQLite(t).GormDB()
+	if err := SaveNetFlows(gdb, flows); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveNetFlows(gdb, flows); err != nil {
+		t.Fatalf("重复写入应忽略: %v", err)
+	}
+	var n int64
+	gdb.Model(&NetFlow{}).Count(&n)
+	if n != 3 {
+		t.Errorf("期望落库 3 条，实际 %d", n)
+	}
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ApplyTransferDeltas 把新写入的转账事件按 链/币种 的净流入直接加到实体最新的持仓快照上（增量更新），
+// 不必等下一次完整 PoR 运行。只应用发生在快照 AsOf 之后的事件（之前的已计入快照）；
+// 持仓的 USD 估值按原单价同比缩放，新出现的币种无单价、估值记 0，快照总额随之重算。
+// 余额在 0 处截断：流出超过快照余额时记 0，超出部分不结转，之后的流入从 0 开始累加（余额会比真实值偏高，
+// 多为快照本身滞后，以下一次完整 PoR 运行为准）；冲回被截断的流出同样按全额加回。
+// 返回被更新快照的 实体 -> run_id；实体没有快照时跳过
+func ApplyTransferDeltas(gdb *gorm.DB, rows []TransferEvent) (map[string]string, error) {
+	var updated map[string]string
+	err := gdb.Transaction(func(tx *gorm.DB) (err error) {
+		updated, err = applyTransferDeltas(tx, rows, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// applyTransferDeltas 在事务 tx 内计入 added 的净流入，并冲回 removed（被删除的孤块事件）此前计入的净流入
+func applyTransferDeltas(tx *gorm.DB, added, removed []TransferEvent) (map[string]string, error) {
+	type entityRows struct{ added, removed []TransferEvent }
+	byEntity := map[string]*entityRows{}
+	var order []string
+	group := func(r TransferEvent) *entityRows {
+		if byEntity[r.Entity] == nil {
+			byEntity[r.Entity] = &entityRows{}
+			order = append(order, r.Entity)
+		}
+		return byEntity[r.Entity]
+	}
+	for _, r := range added {
+		g := group(r)
+		g.added = append(g.added, r)
+	}
+	for _, r := range removed {
+		g := group(r)
+		g.removed = append(g.removed, r)
+	}
+	updated := map[string]string{}
+	for _, entity := range order {
+		runID, ok, err := applyEntityTransferDeltas(tx, entity, byEntity[entity].added, byEntity[entity].removed)
+		if err != nil {
+			return nil, fmt.Errorf("apply portfolio delta entity=%s: %w", entity, err)
+		}
+		if ok {
+			updated[entity] = runID
+		}
+	}
+	return updated, nil
+}
+
+func applyEntityTransferDeltas(tx *gorm.DB, entity string, added, removed []TransferEvent) (string, bool, error) {
+	var snap PortfolioSnapshot
+	if err := tx.Where("entity = ?", entity).Order("created_at desc").Limit(1).Find(&snap).Error; err != nil {
+		return "", false, err
+	}
+	if snap.ID == 0 {
+		return "", false, nil
+	}
+
+	type key struct{ chain, symbol string }
+	deltas := map[key]*big.Float{}
+	var order []key
+	accumulate := func(e TransferEvent, reverse bool) {
+		if !e.OccurredAt.After(snap.AsOf) {
+			return
+		}
+		amt, ok := new(big.Float).SetPrec(256).SetString(strings.TrimSpace(e.Amount))
+		if !ok {
+			return
+		}
+		switch e.Direction {
+		case "in":
+		case "out":
+			amt.Neg(amt)
+		default:
+			return
+		}
+		if reverse {
+			amt.Neg(amt)
+		}
+		k := key{e.Chain, strings.ToUpper(e.Coin)}
+		if deltas[k] == nil {
+			deltas[k] = new(big.Float).SetPrec(256)
+			order = append(order, k)
+		}
+		deltas[k].Add(deltas[k], amt)
+	}
+	for _, e := range added {
+		accumulate(e, false)
+	}
+	// 被删除的事件只在当初计入过（发生在快照之后）时冲回，判断条件与计入时相同
+	for _, e := range removed {
+		accumulate(e, true)
+	}
+	if len(order) == 0 {
+		return "", false, nil
+	}
+
+	for _, k := range order {
+		var h Holding
+		if err := tx.Where("run_id = ? AND entity = ? AND chain = ? AND UPPER(symbol) = ?", snap.RunID, entity, k.chain, k.symbol).
+			Limit(1).Find(&h).Error; err != nil {
+			return "", false, err
+		}
+		old := parseBigFloat(h.Amount)
+		amount := new(big.Float).SetPrec(256).Add(old, deltas[k])
+		if amount.Sign() < 0 {
+			amount.SetInt64(0) // 快照之后的流出超过快照余额（多为快照本身滞后），不记负数，超出部分不结转
+		}
+
+		if h.ID == 0 {
+			if amount.Sign() == 0 {
+				continue
+			}
+			h = Holding{RunID: snap.RunID, Entity: entity, Chain: k.chain, Symbol: k.symbol, Amount: fstr(amount, 18), Decimals: 18, ValueUSD: "0"}
+			if err := tx.Create(&h).Error; err != nil {
+				return "", false, err
+			}
+			continue
+		}
+
+		value := new(big.Float)
+		if old.Sign() > 0 {
+			price := new(big.Float).Quo(parseBigFloat(h.ValueUSD), old)
+			value.Mul(price, amount)
+		}
+		if err := tx.Model(&Holding{}).Where("id = ?", h.ID).
+			Updates(map[string]any{"amount": fstr(amount, 18), "value_usd": fstr(value, 8)}).Error; err != nil {
+			return "", false, err
+		}
+	}
+
+	var values []string
+	if err := tx.Model(&Holding{}).Where("run_id = ? AND entity = ?", snap.RunID, entity).Pluck("value_usd", &values).Error; err != nil {
+		return "", false, err
+	}
+	total := new(big.Float)
+	for _, v := range values {
+		total.Add(total, parseBigFloat(v))
+	}
+	if err := tx.Model(&PortfolioSnapshot{}).Where("id = ?", snap.ID).Update("total_usd", fstr(total, 8)).Error; err != nil {
+		return "", false, err
+	}
+	return snap.RunID, true, nil
+}
+
+// parseBigFloat 解析十进制字符串，空串或非法时为 0
+func parseBigFloat(s string) *big.Float {
+	f, ok := new(big.Float).SetPrec(256).SetString(strings.TrimSpace(s))
+	if !ok {
+		return new(big.Float).SetPrec(256)
+	}
+	return f
+}
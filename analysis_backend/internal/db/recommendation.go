@@ -116,6 +116,32 @@ func GetLatestRecommendations(gdb *gorm.DB, kind string, limit int) ([]CoinRecom
 	return recommendations, nil
 }
 
+// GetRecommendationsPage 按 (rank, id) 游标分页读取同一批推荐。
+// generatedAt 为零值时取最新一批；返回实际读取的批次时间（无数据时为零值），
+// 后续翻页应传回该时间，保证新批次写入后分页结果不漂移
+func GetRecommendationsPage(gdb *gorm.DB, kind string, generatedAt time.Time, afterRank int, afterID uint, limit int) ([]CoinRecommendation, time.Time, error) {
+	if generatedAt.IsZero() {
+		var latest CoinRecommendation
+		err := gdb.Select("generated_at").Where("kind = ?", kind).
+			Order("generated_at DESC").Limit(1).Find(&latest).Error
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		if latest.GeneratedAt.IsZero() {
+			return nil, time.Time{}, nil
+		}
+		generatedAt = latest.GeneratedAt
+	}
+
+	var recommendations []CoinRecommendation
+	err := gdb.Where("kind = ? AND generated_at = ?", kind, generatedAt).
+		Where("`rank` > ? OR (`rank` = ? AND id > ?)", afterRank, afterRank, afterID).
+		Order("`rank` ASC, id ASC").
+		Limit(limit).
+		Find(&recommendations).Error
+	return recommendations, generatedAt, err
+}
+
 // GetRecommendationsByDate 根据日期获取推荐结果
 // date: 日期字符串，格式 YYYY-MM-DD，会查询该日期当天的所有推荐
 func GetRecommendationsByDate(gdb *gorm.DB, kind string, date time.Time) ([]CoinRecommendation, error) {
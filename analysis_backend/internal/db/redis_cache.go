@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -119,6 +121,72 @@ func (r *RedisCache) batchDelete(ctx context.Context, keys []string) error {
 	return err
 }
 
+// prefixPattern 前缀转为 SCAN 模式（转义通配符）
+func prefixPattern(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(prefix) + "*"
+}
+
+// Keys 列出以 prefix 开头的键及剩余 TTL
+func (r *RedisCache) Keys(ctx context.Context, prefix string) ([]CacheKeyInfo, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, prefixPattern(prefix), 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.DurationCmd, len(keys))
+	for i, k := range keys {
+		cmds[i] = pipe.TTL(ctx, k)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	out := make([]CacheKeyInfo, 0, len(keys))
+	for i, k := range keys {
+		ttl := cmds[i].Val()
+		if ttl == -2 {
+			continue // 扫描后已过期
+		}
+		if ttl < 0 {
+			ttl = -1
+		}
+		out = append(out, CacheKeyInfo{Key: k, TTL: ttl})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// Flush 删除以 prefix 开头的键，返回删除数量
+func (r *RedisCache) Flush(ctx context.Context, prefix string) (int, error) {
+	iter := r.client.Scan(ctx, 0, prefixPattern(prefix), 100).Iterator()
+	var keys []string
+	n := 0
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+		if len(keys) >= 100 {
+			if err := r.batchDelete(ctx, keys); err != nil {
+				return n, err
+			}
+			n += len(keys)
+			keys = keys[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return n, err
+	}
+	if err := r.batchDelete(ctx, keys); err != nil {
+		return n, err
+	}
+	return n + len(keys), nil
+}
+
 // Close 关闭 Redis 连接
 func (r *RedisCache) Close() error {
 	if r.client != nil {
@@ -205,6 +273,38 @@ func (m *MemoryCache) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// Keys 列出以 prefix 开头且未过期的键及剩余 TTL
+func (m *MemoryCache) Keys(ctx context.Context, prefix string) ([]CacheKeyInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	out := make([]CacheKeyInfo, 0, len(m.data))
+	for key, item := range m.data {
+		if !strings.HasPrefix(key, prefix) || now.After(item.expiresAt) {
+			continue
+		}
+		out = append(out, CacheKeyInfo{Key: key, TTL: item.expiresAt.Sub(now)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// Flush 删除以 prefix 开头的键，返回删除数量
+func (m *MemoryCache) Flush(ctx context.Context, prefix string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for key := range m.data {
+		if strings.HasPrefix(key, prefix) {
+			delete(m.data, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
 // cleanup 定期清理过期键
 func (m *MemoryCache) cleanup() {
 	ticker := time.NewTicker(1 * time.Minute)
@@ -2,6 +2,7 @@ package db
 
 import (
 	"analysis/internal/models"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -22,8 +23,10 @@ func fstr(x *big.Float, prec int) string {
 	return s
 }
 
+// SaveAll 在单个事务内写入一次 PoR 运行的余额快照、周度与日度资金流；
+// 任一行写入失败整体回滚，返回的错误注明失败的表与实体/币种/日期。
 func SaveAll(gdb *gorm.DB, runID string, asOf time.Time, portfolios []models.Portfolio, weekly []models.WeeklyResult, daily []models.DailyResult) error {
-	return gdb.Transaction(func(tx *gorm.DB) error {
+	err := gdb.Transaction(func(tx *gorm.DB) error {
 		for _, p := range portfolios {
 			ps := PortfolioSnapshot{
 				RunID:    runID,
@@ -32,7 +35,7 @@ func SaveAll(gdb *gorm.DB, runID string, asOf time.Time, portfolios []models.Por
 				AsOf:     asOf.UTC(),
 			}
 			if err := tx.Create(&ps).Error; err != nil {
-				return err
+				return fmt.Errorf("save portfolio snapshot entity=%s: %w", p.Entity, err)
 			}
 			for _, h := range p.Holdings {
 				txh := Holding{
@@ -46,7 +49,7 @@ func SaveAll(gdb *gorm.DB, runID string, asOf time.Time, portfolios []models.Por
 					//AsOf:     asOf.UTC(),
 				}
 				if err := tx.Create(&txh).Error; err != nil {
-					return err
+					return fmt.Errorf("save holding entity=%s %s/%s: %w", p.Entity, h.Chain, h.Symbol, err)
 				}
 			}
 		}
@@ -70,7 +73,7 @@ func SaveAll(gdb *gorm.DB, runID string, asOf time.Time, portfolios []models.Por
 						Net:    fstr(net, 18),
 					}
 					if err := tx.Create(&item).Error; err != nil {
-						return err
+						return fmt.Errorf("save weekly flow entity=%s coin=%s week=%s: %w", wr.Entity, coin, wk, err)
 					}
 				}
 			}
@@ -95,13 +98,17 @@ func SaveAll(gdb *gorm.DB, runID string, asOf time.Time, portfolios []models.Por
 						Net:    fstr(net, 18),
 					}
 					if err := tx.Create(&item).Error; err != nil {
-						return err
+						return fmt.Errorf("save daily flow entity=%s coin=%s day=%s: %w", dr.Entity, coin, dk, err)
 					}
 				}
 			}
 		}
 		return nil
 	})
+	if err != nil {
+		return fmt.Errorf("SaveAll run_id=%s rolled back: %w", runID, err)
+	}
+	return nil
 }
 
 // SaveFilterCorrection 保存或更新过滤器修正记录
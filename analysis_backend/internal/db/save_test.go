@@ -0,0 +1,223 @@
+package db
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"analysis/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// saveAllFixture 一个实体的余额快照 + 周度 + 日度结果
+func saveAllFixture() ([]models.Portfolio, []models.WeeklyResult, []models.DailyResult) {
+	portfolios := []models.Portfolio{{
+		Entity: "acme",
+		Holdings: map[string]models.Holding{
+			"ethereum:ETH": {Chain: "ethereum", Symbol: "ETH", Amount: "1.5", Decimals: 18, ValueUSD: 3750},
+			"bitcoin:BTC":  {Chain: "bitcoin", Symbol: "BTC", Amount: "0.5", Decimals: 8, ValueUSD: 30000},
+		},
+		TotalUSD: 33750,
+	}}
+	weekly := []models.WeeklyResult{{Entity: "acme", Data: models.WeeklyBucket{
+		"ETH": {"2025-W36": {In: big.NewFloat(1.5)}},
+	}}}
+	daily := []models.DailyResult{{Entity: "acme", Data: models.DailyBucket{
+		"ETH": {"2025-09-01": {In: big.NewFloat(1.5)}},
+		"BTC": {"2025-09-01": {In: big.NewFloat(0.5), Out: big.NewFloat(0.1)}},
+	}}}
+	return portfolios, weekly, daily
+}
+
+// countRunRows 统计某次运行在四张 PoR 表中的行数
+func countRunRows(t *testing.T, gdb *gorm.DB, runID string) map[string]int64 {
+	t.Helper()
+	out := map[string]int64{}
+	for name, m := range map[string]interface{}{
+		"portfolio": &PortfolioSnapshot{}, "holding": &Holding{}, "weekly": &WeeklyFlow{}, "daily": &DailyFlow{},
+	} {
+		var n int64
+		if err := gdb.Model(m).Where("run_id = ?", runID).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		out[name] = n
+	}
+	return out
+}
+
+func TestSaveAllWritesAllTables(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	portfolios, weekly, daily := saveAllFixture()
+	if err := SaveAll(gdb, "run-ok", time.Now(), portfolios, weekly, daily); err != nil {
+		t.Fatal(err)
+	}
+	got := countRunRows(t, gdb, "run-ok")
+	want := map[string]int64{"portfolio": 1, "holding": 2, "weekly": 1, "daily": 2}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s 期望 %d 行，实际 %d", k, w, got[k])
+		}
+	}
+}
+
+// TestSaveAllRollsBackOnMidSaveFailure 日度写入阶段注入失败，之前写入的快照/持仓/周度全部回滚
+func TestSaveAllRollsBackOnMidSaveFailure(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	injected := errors.New("injected failure")
+	if err := gdb.Callback().Create().Before("gorm:create").Register("test:fail_daily", func(tx *gorm.DB) {
+		if tx.Statement.Table == "daily_flows" {
+			tx.AddError(injected)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	portfolios, weekly, daily := saveAllFixture()
+	err := SaveAll(gdb, "run-fail", time.Now(), portfolios, weekly, daily)
+	if !errors.Is(err, injected) {
+		t.Fatalf("期望返回注入的错误，实际 %v", err)
+	}
+	if !strings.Contains(err.Error(), "daily flow entity=acme") || !strings.Contains(err.Error(), "run_id=run-fail") {
+		t.Errorf("错误信息应注明失败位置: %v", err)
+	}
+	for k, n := range countRunRows(t, gdb, "run-fail") {
+		if n != 0 {
+			t.Errorf("回滚后 %s 仍残留 %d 行", k, n)
+		}
+	}
+}
+
+// TestSaveAllRollsBackOnConflict 同一 run/实体重复写入违反唯一键，第二次整体回滚，不产生重复行
+func TestSaveAllRollsBackOnConflict(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	portfolios, weekly, daily := saveAllFixture()
+	if err := SaveAll(gdb, "run-dup", time.Now(), portfolios, weekly, daily); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveAll(gdb, "run-dup", time.Now(), portfolios, weekly, daily); err == nil {
+		t.Fatal("期望唯一键冲突报错")
+	}
+	got := countRunRows(t, gdb, "run-dup")
+	want := map[string]int64{"portfolio": 1, "holding": 2, "weekly": 1, "daily": 2}
+	for k, w := range want {
+		if got[k] != w {
+			t.Errorf("%s 期望 %d 行，实际 %d", k, w, got[k])
+		}
+	}
+}
+
+// TestApplyTransferDeltasOutflow 流出按原单价同比缩减估值，超过余额时归零；无快照的实体跳过
+func TestApplyTransferDeltasOutflow(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	portfolios, _, _ := saveAllFixture()
+	asOf := time.Now().UTC().Add(-time.Hour)
+	if err := SaveAll(gdb, "run-1", asOf, portfolios, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	updated, err := ApplyTransferDeltas(gdb, []TransferEvent{
+		{Entity: "acme", Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "0.5", OccurredAt: now},
+		{Entity: "acme", Chain: "bitcoin", Coin: "BTC", Direction: "out", Amount: "3", OccurredAt: now},
+		{Entity: "nobody", Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "1", OccurredAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || updated["acme"] != "run-1" {
+		t.Fatalf("只应更新 acme 的快照，实际 %v", updated)
+	}
+
+	var eth, btc Holding
+	gdb.Where("run_id = ? AND symbol = ?", "run-1", "ETH").First(&eth)
+	gdb.Where("run_id = ? AND symbol = ?", "run-1", "BTC").First(&btc)
+	if eth.Amount != "1" || eth.ValueUSD != "2500" {
+		t.Errorf("ETH 应为 1 个、估值 2500，实际 %s / %s", eth.Amount, eth.ValueUSD)
+	}
+	if btc.Amount != "0" || btc.ValueUSD != "0" {
+		t.Errorf("BTC 流出超过余额应归零，实际 %s / %s", btc.Amount, btc.ValueUSD)
+	}
+	var snap PortfolioSnapshot
+	gdb.Where("run_id = ?", "run-1").First(&snap)
+	if snap.TotalUSD != "2500" {
+		t.Errorf("快照总额应重算为 2500，实际 %s", snap.TotalUSD)
+	}
+}
+
+// TestApplyTransferDeltasFloorAtZero 余额在 0 处截断、超出的流出不结转：之后的流入从 0 起算（比真实余额偏高），
+// 且归零后失去单价，估值记 0，直到下一次完整 PoR 运行
+func TestApplyTransferDeltasFloorAtZero(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	portfolios, _, _ := saveAllFixture()
+	if err := SaveAll(gdb, "run-1", time.Now().UTC().Add(-time.Hour), portfolios, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	// 0.5 BTC 流出 3 BTC：记 0，而不是 -2.5
+	if _, err := ApplyTransferDeltas(gdb, []TransferEvent{{Entity: "acme", Chain: "bitcoin", Coin: "BTC", Direction: "out", Amount: "3", OccurredAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	// 之后流入 1 BTC：从 0 起算为 1（不结转 -2.5 的缺口）
+	if _, err := ApplyTransferDeltas(gdb, []TransferEvent{{Entity: "acme", Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "1", OccurredAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	var btc Holding
+	gdb.Where("run_id = ? AND symbol = ?", "run-1", "BTC").First(&btc)
+	if btc.Amount != "1" || btc.ValueUSD != "0" {
+		t.Errorf("截断后流入应从 0 起算且估值为 0，实际 %s / %s", btc.Amount, btc.ValueUSD)
+	}
+}
+
+// TestSaveTransferEventsWithDeltasReversesOrphans 重组替换的孤块事件在同一事务内从持仓中冲回：
+// 重新打包的交易净额不变，未被重新打包的交易被冲回；规范哈希校正（没有新事件）同样冲回
+func TestSaveTransferEventsWithDeltasReversesOrphans(t *testing.T) {
+	gdb := openTestSQLite(t).GormDB()
+	portfolios, _, _ := saveAllFixture()
+	asOf := time.Now().UTC().Add(-time.Hour)
+	if err := SaveAll(gdb, "run-1", asOf, portfolios, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	ts := time.Now().UTC()
+	ethAmount := func() string {
+		t.Helper()
+		var h Holding
+		gdb.Where("run_id = ? AND symbol = ?", "run-1", "ETH").First(&h)
+		return h.Amount
+	}
+
+	orphaned := []models.Event{
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TS: ts, TxID: "0xmoved", LogIndex: -1, BlockNumber: 10, BlockHash: "0xold10"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "0.25", TS: ts, TxID: "0xdropped", LogIndex: -1, BlockNumber: 10, BlockHash: "0xold10"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "0.5", TS: ts, TxID: "0xlater", LogIndex: -1, BlockNumber: 12, BlockHash: "0xold12"},
+	}
+	if _, updated, err := SaveTransferEventsWithDeltas(gdb, "run-a", "acme", orphaned); err != nil || updated["acme"] != "run-1" {
+		t.Fatalf("写入失败: %v %v", updated, err)
+	}
+	if got := ethAmount(); got != "2.25" {
+		t.Fatalf("1.5 + 1 + 0.25 - 0.5 = 2.25，实际 %s", got)
+	}
+
+	// 0xmoved 重新打包进 11 号新区块，0xdropped 未被打包：10 号的两条被删除并冲回，新事件计入
+	canonical := []models.Event{
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TS: ts, TxID: "0xmoved", LogIndex: -1, BlockNumber: 11, BlockHash: "0xnew11"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TS: ts, TxID: "0xother", LogIndex: -1, BlockNumber: 10, BlockHash: "0xnew10"},
+	}
+	inserted, _, err := SaveTransferEventsWithDeltas(gdb, "run-b", "acme", canonical)
+	if err != nil || len(inserted) != 2 {
+		t.Fatalf("写入失败: %d %v", len(inserted), err)
+	}
+	if got := ethAmount(); got != "3" {
+		t.Fatalf("冲回 0xdropped 后 2.25 - 0.25 + 1 = 3，实际 %s", got)
+	}
+
+	// 12 号被替换且规范链上没有事件：只上报规范哈希，0xlater 被删除并冲回
+	deleted, updated, err := DeleteNonCanonicalTransfersWithDeltas(gdb, "acme", "ethereum", 12, 12, map[uint64]string{12: "0xnew12"})
+	if err != nil || len(deleted) != 1 || updated["acme"] != "run-1" {
+		t.Fatalf("校正失败: deleted=%d updated=%v err=%v", len(deleted), updated, err)
+	}
+	if got := ethAmount(); got != "3.5" {
+		t.Errorf("冲回流出 0.5 后应为 3.5，实际 %s", got)
+	}
+}
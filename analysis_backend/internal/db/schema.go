@@ -59,17 +59,37 @@ type TransferEvent struct {
 	Entity     string    `gorm:"size:64;uniqueIndex:ux_te"`
 	Chain      string    `gorm:"size:32;uniqueIndex:ux_te"`
 	Coin       string    `gorm:"size:16;uniqueIndex:ux_te"`
+	AssetID    string    `gorm:"size:160;index"`           // 链内资产标识 "<链>:<合约/mint>"，原生币为 "<链>:native"
 	Direction  string    `gorm:"size:8;uniqueIndex:ux_te"` // "in"/"out"
 	Amount     string    `gorm:"type:decimal(38,18)"`
 	TxID       string    `gorm:"size:128;uniqueIndex:ux_te"`
 	Address    string    `gorm:"size:128;uniqueIndex:ux_te"` // 命中的监控地址
 	From       string    `gorm:"size:128"`
 	To         string    `gorm:"size:128"`
-	LogIndex   int       `gorm:"uniqueIndex:ux_te;default:-1"` // ERC20: 链上 logIndex；原生: -1
+	LogIndex   int       `gorm:"uniqueIndex:ux_te;default:-1"` // ERC20: 链上 logIndex；原生: -1；原生内部转账: -(2+序号)
+	AddrType   string    `gorm:"size:16;index"`                // 命中地址类型：hot/cold/deposit/staking，未标注为空
+	BlockNum   uint64    `gorm:"index"`                        // EVM 区块高度，其它链为 0
+	BlockHash  string    `gorm:"size:80"`                      // EVM 区块哈希，重组时用于替换孤块事件
+	USDValue   *float64  `gorm:"type:decimal(38,8)"`           // 发生时的美元价值，无价格时为 NULL
 	OccurredAt time.Time `gorm:"index"`
 	CreatedAt  time.Time
 }
 
+// 每次写入（扫描窗口）按 实体/币种 汇总的净流量，In/Out 只计本次新插入的事件
+type NetFlow struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	RunID       string    `gorm:"type:char(36);index:idx_nf_run_ent_coin,unique" json:"run_id"`
+	Entity      string    `gorm:"size:64;index:idx_nf_run_ent_coin,unique;index:idx_nf_ent_coin_end,priority:1" json:"entity"`
+	Coin        string    `gorm:"size:16;index:idx_nf_run_ent_coin,unique;index:idx_nf_ent_coin_end,priority:2" json:"coin"`
+	In          string    `gorm:"type:decimal(38,18)" json:"in"`
+	Out         string    `gorm:"type:decimal(38,18)" json:"out"`
+	Net         string    `gorm:"type:decimal(38,18)" json:"net"`
+	Events      int       `json:"events"`                                                 // 参与汇总的事件数
+	WindowStart time.Time `json:"window_start"`                                           // 事件最早发生时间
+	WindowEnd   time.Time `gorm:"index:idx_nf_ent_coin_end,priority:3" json:"window_end"` // 事件最晚发生时间
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 // 扫描游标（断点续扫）
 type TransferCursor struct {
 	ID        uint   `gorm:"primaryKey"`
@@ -471,7 +491,7 @@ type MLModel struct {
 	ModelData       []byte    `gorm:"type:longblob" json:"model_data"`                                     // 序列化的模型数据
 	Performance     string    `gorm:"type:json;not null" json:"performance"`                               // 性能指标(JSON格式)
 	TrainedAt       time.Time `gorm:"not null;index" json:"trained_at"`                                    // 训练完成时间
-	ExpiresAt       time.Time `gorm:"not null;index:idx_expires_cleanup" json:"expires_at"`                // 过期时间
+	ExpiresAt       time.Time `gorm:"not null;index:idx_ml_models_expires" json:"expires_at"`              // 过期时间
 	TrainingSamples int       `gorm:"not null" json:"training_samples"`                                    // 训练样本数量
 	FeatureCount    int       `gorm:"not null" json:"feature_count"`                                       // 特征数量
 	Accuracy        float64   `gorm:"type:decimal(5,4);not null;index" json:"accuracy"`                    // 准确率(0-1)
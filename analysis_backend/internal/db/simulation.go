@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -197,8 +198,9 @@ type AsyncBacktestRecord struct {
 	EndDate        string          `gorm:"column:end_date;size:10;not null" json:"end_date"`
 	InitialCapital decimal.Decimal `gorm:"column:initial_capital;type:decimal(20,8);not null" json:"initial_capital"`
 	PositionSize   decimal.Decimal `gorm:"column:position_size;type:decimal(8,2);not null" json:"position_size"`
-	Status         string          `gorm:"column:status;size:16;default:'pending';index" json:"status"` // pending/running/completed/failed
-	Result         *string         `gorm:"column:result;type:json" json:"result,omitempty"`             // 回测结果JSON字符串
+	Fingerprint    string          `gorm:"column:fingerprint;size:64;index" json:"fingerprint,omitempty"` // 回测配置指纹，相同配置的结果可复用
+	Status         string          `gorm:"column:status;size:16;default:'pending';index" json:"status"`   // pending/running/completed/failed
+	Result         *string         `gorm:"column:result;type:json" json:"result,omitempty"`               // 回测结果JSON字符串
 	ErrorMessage   string          `gorm:"column:error_message;type:text" json:"error_message,omitempty"`
 	CreatedAt      time.Time       `gorm:"column:created_at;index:idx_created_at" json:"created_at"`
 	UpdatedAt      time.Time       `gorm:"column:updated_at" json:"updated_at"`
@@ -220,6 +222,23 @@ func UpdateAsyncBacktestRecord(gdb *gorm.DB, record *AsyncBacktestRecord) error
 	return gdb.Save(record).Error
 }
 
+// FindCompletedAsyncBacktestByFingerprint 用户最近一次已完成且配置指纹相同的回测记录，没有时返回 nil
+func FindCompletedAsyncBacktestByFingerprint(gdb *gorm.DB, userID uint, fingerprint string) (*AsyncBacktestRecord, error) {
+	if fingerprint == "" {
+		return nil, nil
+	}
+	var record AsyncBacktestRecord
+	err := gdb.Where("user_id = ? AND fingerprint = ? AND status = ? AND result IS NOT NULL", userID, fingerprint, "completed").
+		Order("id DESC").First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
 // GetAsyncBacktestRecords 获取用户的异步回测记录
 func GetAsyncBacktestRecords(gdb *gorm.DB, userID uint, page, limit int, status, symbol string) ([]AsyncBacktestRecord, int64, error) {
 	var records []AsyncBacktestRecord
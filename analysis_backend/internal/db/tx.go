@@ -11,9 +11,52 @@ import (
 )
 
 func SaveTransferEvents(gdb *gorm.DB, runID, entity string, events []models.Event) ([]TransferEvent, error) {
-	if len(events) == 0 {
+	rows := transferRows(runID, entity, events)
+	if len(rows) == 0 {
 		return nil, nil
 	}
+	var inserted []TransferEvent
+	err := gdb.Transaction(func(tx *gorm.DB) (err error) {
+		inserted, _, err = saveTransferRows(tx, rows)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// SaveTransferEventsWithDeltas 同 SaveTransferEvents，并在同一事务内增量更新实体最新持仓快照（见 ApplyTransferDeltas）：
+// 新插入的事件计入，被替换删除的孤块事件冲回。返回新插入的记录与被更新快照的 实体 -> run_id
+func SaveTransferEventsWithDeltas(gdb *gorm.DB, runID, entity string, events []models.Event) ([]TransferEvent, map[string]string, error) {
+	rows := transferRows(runID, entity, events)
+	if len(rows) == 0 {
+		return nil, nil, nil
+	}
+	var inserted []TransferEvent
+	var updated map[string]string
+	err := gdb.Transaction(func(tx *gorm.DB) error {
+		ins, deleted, err := saveTransferRows(tx, rows)
+		if err != nil {
+			return err
+		}
+		if updated, err = applyTransferDeltas(tx, ins, deleted); err != nil {
+			return err
+		}
+		inserted = ins
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return inserted, updated, nil
+}
+
+// transferRows 事件转为待写入的记录，过滤零金额
+func transferRows(runID, entity string, events []models.Event) []TransferEvent {
+	if len(events) == 0 {
+		return nil
+	}
 	now := time.Now().UTC()
 	rows := make([]TransferEvent, 0, len(events))
 
@@ -35,6 +78,7 @@ func SaveTransferEvents(gdb *gorm.DB, runID, entity string, events []models.Even
 			Entity:     ent,
 			Chain:      e.Chain,
 			Coin:       e.Coin,
+			AssetID:    e.AssetID,
 			Direction:  e.Direction,
 			Amount:     strings.TrimSpace(e.Amount),
 			TxID:       e.TxID,
@@ -42,27 +86,159 @@ func SaveTransferEvents(gdb *gorm.DB, runID, entity string, events []models.Even
 			From:       e.From,
 			To:         e.To,
 			LogIndex:   e.LogIndex,
+			AddrType:   e.AddressType,
+			BlockNum:   e.BlockNumber,
+			BlockHash:  strings.ToLower(strings.TrimSpace(e.BlockHash)),
+			USDValue:   e.USDValue,
 			OccurredAt: ts.UTC(),
 			CreatedAt:  now,
 		})
 	}
-	if len(rows) == 0 {
-		return nil, nil
+	return rows
+}
+
+// saveTransferRows 在事务 tx 内删除被这批事件替换的孤块事件并写入新记录，返回新插入与被删除的记录。
+// 逐条插入并按影响行数判断是否新插入：批量插入遇到部分冲突时，驱动返回的自增 ID 会按位置错配到被忽略的行上
+func saveTransferRows(tx *gorm.DB, rows []TransferEvent) (inserted, deleted []TransferEvent, err error) {
+	deleted, err = deleteOrphanedTransfers(tx, rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	inserted = make([]TransferEvent, 0, len(rows))
+	for i := range rows {
+		// 唯一键冲突忽略
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows[i])
+		if res.Error != nil {
+			return nil, nil, res.Error
+		}
+		if res.RowsAffected > 0 {
+			inserted = append(inserted, rows[i])
+		}
+	}
+	return inserted, deleted, nil
+}
+
+// deleteOrphanedTransfers 链重组后扫描器会带新的区块哈希重新下发事件：
+// 同一实体/链上，落在这批事件所在高度、或属于这批交易，但区块哈希不在这批哈希中的旧记录视为孤块事件，删除后由新事件替换。
+// 未携带区块哈希的事件（非 EVM 链、旧版扫描器）不参与。返回被删除的记录
+func deleteOrphanedTransfers(tx *gorm.DB, rows []TransferEvent) ([]TransferEvent, error) {
+	type scope struct{ entity, chain string }
+	type batch struct {
+		heights map[uint64]bool
+		txIDs   map[string]bool
+		hashes  map[string]bool
+	}
+	batches := map[scope]*batch{}
+	for _, r := range rows {
+		if r.BlockHash == "" {
+			continue
+		}
+		k := scope{r.Entity, r.Chain}
+		b := batches[k]
+		if b == nil {
+			b = &batch{heights: map[uint64]bool{}, txIDs: map[string]bool{}, hashes: map[string]bool{}}
+			batches[k] = b
+		}
+		b.heights[r.BlockNum] = true
+		b.txIDs[r.TxID] = true
+		b.hashes[r.BlockHash] = true
 	}
 
-	// 唯一键冲突忽略
-	if err := gdb.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+	keys := func(m map[string]bool) []string {
+		out := make([]string, 0, len(m))
+		for k := range m {
+			out = append(out, k)
+		}
+		return out
+	}
+	var deleted []TransferEvent
+	for k, b := range batches {
+		heights := make([]uint64, 0, len(b.heights))
+		for h := range b.heights {
+			heights = append(heights, h)
+		}
+		var orphans []TransferEvent
+		if err := tx.Where("entity = ? AND chain = ? AND block_hash <> '' AND block_hash NOT IN ?", k.entity, k.chain, keys(b.hashes)).
+			Where("block_num IN ? OR tx_id IN ?", heights, keys(b.txIDs)).
+			Find(&orphans).Error; err != nil {
+			return nil, err
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		ids := make([]uint, len(orphans))
+		for i, r := range orphans {
+			ids[i] = r.ID
+		}
+		if err := tx.Delete(&TransferEvent{}, ids).Error; err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, orphans...)
+	}
+	return deleted, nil
+}
+
+// DeleteNonCanonicalTransfers 扫描器重组回退后重扫 [from, to]，并上报区间内每个高度的规范区块哈希：
+// 实体/链在该区间内 block_hash 与对应高度规范哈希不一致的记录都是孤块事件，全部删除——
+// 重扫窗口没有新事件（孤块上的交易未被重新打包）时也会删除。hashes 未覆盖的高度、未携带区块哈希的记录不处理。
+// 返回被删除的记录
+func DeleteNonCanonicalTransfers(gdb *gorm.DB, entity, chain string, from, to uint64, hashes map[uint64]string) ([]TransferEvent, error) {
+	var deleted []TransferEvent
+	err := gdb.Transaction(func(tx *gorm.DB) (err error) {
+		deleted, err = deleteNonCanonicalTransfers(tx, entity, chain, from, to, hashes)
+		return err
+	})
+	if err != nil {
 		return nil, err
 	}
+	return deleted, nil
+}
+
+// DeleteNonCanonicalTransfersWithDeltas 同 DeleteNonCanonicalTransfers，并在同一事务内从实体最新持仓快照中冲回被删除事件的净流入。
+// 返回被删除的记录与被更新快照的 实体 -> run_id
+func DeleteNonCanonicalTransfersWithDeltas(gdb *gorm.DB, entity, chain string, from, to uint64, hashes map[uint64]string) ([]TransferEvent, map[string]string, error) {
+	var deleted []TransferEvent
+	var updated map[string]string
+	err := gdb.Transaction(func(tx *gorm.DB) error {
+		del, err := deleteNonCanonicalTransfers(tx, entity, chain, from, to, hashes)
+		if err != nil {
+			return err
+		}
+		if updated, err = applyTransferDeltas(tx, nil, del); err != nil {
+			return err
+		}
+		deleted = del
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return deleted, updated, nil
+}
 
-	// 仅返回真正新插入的记录（ID>0）
-	inserted := make([]TransferEvent, 0, len(rows))
+func deleteNonCanonicalTransfers(tx *gorm.DB, entity, chain string, from, to uint64, hashes map[uint64]string) ([]TransferEvent, error) {
+	var rows []TransferEvent
+	if err := tx.Where("entity = ? AND chain = ? AND block_hash <> '' AND block_num BETWEEN ? AND ?", entity, chain, from, to).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var deleted []TransferEvent
+	ids := make([]uint, 0, len(rows))
 	for _, r := range rows {
-		if r.ID > 0 {
-			inserted = append(inserted, r)
+		want, ok := hashes[r.BlockNum]
+		if !ok || strings.EqualFold(strings.TrimSpace(want), r.BlockHash) {
+			continue
 		}
+		ids = append(ids, r.ID)
+		deleted = append(deleted, r)
 	}
-	return inserted, nil
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if err := tx.Delete(&TransferEvent{}, ids).Error; err != nil {
+		return nil, err
+	}
+	return deleted, nil
 }
 
 func isZero(s string) bool {
@@ -76,3 +252,21 @@ func isZero(s string) bool {
 	}
 	return r.Cmp(new(big.Rat)) == 0
 }
+
+// LoadTransferEvents 读取实体截至 until（含）的全部转账事件，按发生时间升序；用于事件回放重建持仓
+func LoadTransferEvents(gdb *gorm.DB, entity string, until time.Time) ([]models.Event, error) {
+	var rows []TransferEvent
+	if err := gdb.Where("entity = ? AND occurred_at <= ?", entity, until.UTC()).
+		Order("occurred_at ASC, id ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]models.Event, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, models.Event{
+			Entity: r.Entity, Chain: r.Chain, Coin: r.Coin, AssetID: r.AssetID, Direction: r.Direction, Amount: r.Amount,
+			TS: r.OccurredAt.UTC(), TxID: r.TxID, From: r.From, To: r.To, Address: r.Address,
+			LogIndex: r.LogIndex, AddressType: r.AddrType, BlockNumber: r.BlockNum, BlockHash: r.BlockHash,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserActivityExport 用户活动导出（推荐浏览/反馈、模拟交易、定时订单）
+type UserActivityExport struct {
+	UserID                 uint                         `json:"user_id"`
+	ExportedAt             time.Time                    `json:"exported_at"`
+	RecommendationActivity []UserBehavior               `json:"recommendation_activity"`
+	RecommendationFeedback []UserRecommendationFeedback `json:"recommendation_feedback"`
+	SimulatedTrades        []SimulatedTrade             `json:"simulated_trades"`
+	ScheduledOrders        []ScheduledOrder             `json:"scheduled_orders"`
+}
+
+// recommendationActionTypes 导出时视为“推荐相关”的行为类型
+var recommendationActionTypes = []string{
+	ActionTypeRecommendationView,
+	ActionTypeRecommendationClick,
+	ActionTypeRecommendationSave,
+	ActionTypeRecommendationFollow,
+}
+
+// ExportUserActivity 导出指定用户的全部活动；所有查询都按 user_id 过滤
+func ExportUserActivity(gdb *gorm.DB, userID uint) (*UserActivityExport, error) {
+	out := &UserActivityExport{
+		UserID:                 userID,
+		ExportedAt:             time.Now().UTC(),
+		RecommendationActivity: []UserBehavior{},
+		RecommendationFeedback: []UserRecommendationFeedback{},
+		SimulatedTrades:        []SimulatedTrade{},
+		ScheduledOrders:        []ScheduledOrder{},
+	}
+	if err := gdb.Where("user_id = ? AND action_type IN ?", userID, recommendationActionTypes).
+		Order("created_at ASC").Find(&out.RecommendationActivity).Error; err != nil {
+		return nil, err
+	}
+	if err := gdb.Where("user_id = ?", userID).Order("created_at ASC").Find(&out.RecommendationFeedback).Error; err != nil {
+		return nil, err
+	}
+	if err := gdb.Where("user_id = ?", userID).Order("created_at ASC").Find(&out.SimulatedTrades).Error; err != nil {
+		return nil, err
+	}
+	if err := gdb.Where("user_id = ?", userID).Order("created_at ASC").Find(&out.ScheduledOrders).Error; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,31 @@
+package flow
+
+import (
+	"analysis/internal/models"
+	"math/big"
+	"strings"
+)
+
+// FromEvents 把转账事件按币种汇总为周度/日度资金流（in 计流入，out 计流出），用于从事件历史生成 flows
+func FromEvents(entity string, events []models.Event) (models.WeeklyResult, models.DailyResult) {
+	weekly := models.WeeklyResult{Entity: entity, Data: models.WeeklyBucket{}}
+	daily := models.DailyResult{Entity: entity, Data: models.DailyBucket{}}
+	for _, e := range events {
+		var in bool
+		switch strings.ToLower(e.Direction) {
+		case "in":
+			in = true
+		case "out":
+		default:
+			continue
+		}
+		amt, ok := new(big.Float).SetString(strings.TrimSpace(e.Amount))
+		if !ok || amt.Sign() == 0 {
+			continue
+		}
+		ts := e.TS.UTC()
+		AddWeekly(weekly.Data, e.Coin, ts, in, amt)
+		AddDaily(daily.Data, e.Coin, ts, in, amt)
+	}
+	return weekly, daily
+}
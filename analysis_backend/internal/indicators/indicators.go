@@ -0,0 +1,178 @@
+// Package indicators 技术指标的唯一实现，实盘推荐评分与回测引擎共用，保证两边算出的指标一致。
+// 所有函数只看序列的最后一个值（最新一根K线），序列按时间升序；数据不足时返回各指标约定的中性值。
+//
+// 回测引擎原先各自实现的口径随之改变（回测结果会与旧版不同，由 server 包的 TestBacktestIndicatorRegression 固定）：
+//   - RSI：旧版取 period 个涨跌幅的简单均值（calculateRSIForPrices 等取的是序列开头），现为全序列 Wilder 平滑，价格全程不变一律返回 50
+//   - 布林带：旧版 calculateBollingerPositionSimple 用样本标准差（除以 n-1），现统一为总体标准差
+//   - ATR：旧版为 period 个收益率绝对值的简单均值，现为 Wilder 平滑的真实波幅除以当前价格
+//   - EMA：旧版以首个值为初值，现以前 period 个值的 SMA 为初值
+//   - 波动率：不再剔除 ±50% 以上的收益率，改用总体标准差
+package indicators
+
+import "math"
+
+// Windows 各指标的回看窗口
+type Windows struct {
+	RSI        int     `json:"rsi"`
+	ATR        int     `json:"atr"`
+	Bollinger  int     `json:"bollinger"`
+	BollingerK float64 `json:"bollinger_k"` // 布林带标准差倍数
+	Volatility int     `json:"volatility"`
+}
+
+// DefaultWindows 默认回看窗口：RSI/ATR 14，布林带 20/2σ，波动率 20
+func DefaultWindows() Windows {
+	return Windows{RSI: 14, ATR: 14, Bollinger: 20, BollingerK: 2, Volatility: 20}
+}
+
+// SMA 最近 period 个值的简单均值；数据不足返回 0
+func SMA(values []float64, period int) float64 {
+	if period <= 0 || len(values) < period {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values[len(values)-period:] {
+		sum += v
+	}
+	return sum / float64(period)
+}
+
+// EMA 指数移动平均，以前 period 个值的 SMA 作为初值，平滑系数 2/(period+1)；数据不足返回 0
+func EMA(values []float64, period int) float64 {
+	if period <= 0 || len(values) < period {
+		return 0
+	}
+	k := 2.0 / (float64(period) + 1)
+	ema := SMA(values[:period], period)
+	for _, v := range values[period:] {
+		ema = v*k + ema*(1-k)
+	}
+	return ema
+}
+
+// RSI Wilder 相对强弱指数：前 period 个涨跌幅的均值为初值，之后按 Wilder 平滑。
+// 数据不足（少于 period+1 个值）或价格全程不变时返回 50；只涨不跌返回 100
+func RSI(closes []float64, period int) float64 {
+	if period <= 0 || len(closes) < period+1 {
+		return 50
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		gain, loss := splitChange(closes[i] - closes[i-1])
+		avgGain += gain
+		avgLoss += loss
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+
+	for i := period + 1; i < len(closes); i++ {
+		gain, loss := splitChange(closes[i] - closes[i-1])
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+	}
+
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+func splitChange(change float64) (gain, loss float64) {
+	if change > 0 {
+		return change, 0
+	}
+	return 0, -change
+}
+
+// ATR Wilder 平均真实波幅。真实波幅 TR = max(高-低, |高-前收|, |低-前收|)，
+// 前 period 个 TR 的均值为初值，之后按 Wilder 平滑。只有收盘价时 highs/lows 可直接传 closes。
+// 三个序列长度须一致且不少于 period+1，否则返回 0
+func ATR(highs, lows, closes []float64, period int) float64 {
+	n := len(closes)
+	if period <= 0 || n < period+1 || len(highs) != n || len(lows) != n {
+		return 0
+	}
+
+	tr := func(i int) float64 {
+		prev := closes[i-1]
+		return math.Max(highs[i]-lows[i], math.Max(math.Abs(highs[i]-prev), math.Abs(lows[i]-prev)))
+	}
+
+	atr := 0.0
+	for i := 1; i <= period; i++ {
+		atr += tr(i)
+	}
+	atr /= float64(period)
+	for i := period + 1; i < n; i++ {
+		atr = (atr*float64(period-1) + tr(i)) / float64(period)
+	}
+	return atr
+}
+
+// Bands 布林带
+type Bands struct {
+	Middle   float64 `json:"middle"`
+	Upper    float64 `json:"upper"`
+	Lower    float64 `json:"lower"`
+	Width    float64 `json:"width"`     // (上轨-下轨)/中轨
+	PercentB float64 `json:"percent_b"` // (收盘-下轨)/(上轨-下轨)，0=下轨，1=上轨，可超出 [0,1]
+}
+
+// Bollinger 最近 period 个收盘价的布林带，标准差按总体计算（除以 period），上下轨为中轨 ±k 倍标准差。
+// 数据不足时返回零值且 PercentB=0.5；上下轨重合时 PercentB=0.5
+func Bollinger(closes []float64, period int, k float64) Bands {
+	if period <= 0 || len(closes) < period {
+		return Bands{PercentB: 0.5}
+	}
+
+	window := closes[len(closes)-period:]
+	middle := SMA(window, period)
+	variance := 0.0
+	for _, v := range window {
+		variance += (v - middle) * (v - middle)
+	}
+	std := math.Sqrt(variance / float64(period))
+
+	b := Bands{Middle: middle, Upper: middle + k*std, Lower: middle - k*std, PercentB: 0.5}
+	if middle != 0 {
+		b.Width = (b.Upper - b.Lower) / middle
+	}
+	if b.Upper != b.Lower {
+		b.PercentB = (closes[len(closes)-1] - b.Lower) / (b.Upper - b.Lower)
+	}
+	return b
+}
+
+// Volatility 最近 period 个简单收益率的总体标准差（不年化）；前值为 0 的收益率跳过。
+// 少于 period+1 个收盘价返回 0
+func Volatility(closes []float64, period int) float64 {
+	if period <= 0 || len(closes) < period+1 {
+		return 0
+	}
+
+	window := closes[len(closes)-period-1:]
+	returns := make([]float64, 0, period)
+	for i := 1; i < len(window); i++ {
+		if window[i-1] != 0 {
+			returns = append(returns, (window[i]-window[i-1])/window[i-1])
+		}
+	}
+	if len(returns) == 0 {
+		return 0
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(variance / float64(len(returns)))
+}
@@ -0,0 +1,117 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tol float64) bool { return math.Abs(a-b) <= tol }
+
+// Wilder RSI 经典示例（StockCharts 14 日 RSI 教程的收盘价）；教程表格对中间值做了四舍五入，
+// 其 70.53/66.32/66.55 与全精度结果相差约 0.07，这里用全精度参考值
+var rsiCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+}
+
+// TestRSIReference 前 14 个涨跌幅取均值作初值，之后 Wilder 平滑
+func TestRSIReference(t *testing.T) {
+	for n, want := range map[int]float64{15: 70.4641, 16: 66.2496, 17: 66.4809, 20: 57.9150} {
+		if got := RSI(rsiCloses[:n], 14); !near(got, want, 1e-3) {
+			t.Errorf("RSI(%d 个收盘价) = %.4f，期望 %.4f", n, got, want)
+		}
+	}
+}
+
+// TestRSIEdgeCases 数据不足/价格不变为 50，只涨不跌为 100，只跌不涨为 0
+func TestRSIEdgeCases(t *testing.T) {
+	if got := RSI(rsiCloses[:14], 14); got != 50 {
+		t.Errorf("数据不足应返回 50，实际 %v", got)
+	}
+	if got := RSI([]float64{5, 5, 5, 5}, 3); got != 50 {
+		t.Errorf("价格不变应返回 50，实际 %v", got)
+	}
+	if got := RSI([]float64{1, 2, 3, 4}, 3); got != 100 {
+		t.Errorf("只涨不跌应返回 100，实际 %v", got)
+	}
+	if got := RSI([]float64{4, 3, 2, 1}, 3); got != 0 {
+		t.Errorf("只跌不涨应返回 0，实际 %v", got)
+	}
+}
+
+// TestEMAReference StockCharts 10 日 EMA 教程：前 10 个值的 SMA 为初值
+func TestEMAReference(t *testing.T) {
+	prices := []float64{
+		22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+		22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+	}
+	want := map[int]float64{10: 22.22, 11: 22.21, 12: 22.24, 15: 22.52, 16: 22.80, 20: 23.34}
+	for n, w := range want {
+		if got := EMA(prices[:n], 10); !near(got, w, 0.005) {
+			t.Errorf("EMA(%d 个值) = %.4f，期望 %.2f", n, got, w)
+		}
+	}
+	if EMA(prices[:9], 10) != 0 {
+		t.Error("数据不足应返回 0")
+	}
+}
+
+// TestSMA 取最后 period 个值
+func TestSMA(t *testing.T) {
+	if got := SMA([]float64{1, 2, 3, 4, 5}, 3); got != 4 {
+		t.Errorf("SMA = %v，期望 4", got)
+	}
+	if SMA([]float64{1, 2}, 3) != 0 || SMA([]float64{1, 2}, 0) != 0 {
+		t.Error("数据不足或周期非法应返回 0")
+	}
+}
+
+// TestATRReference 手算：TR=[1.5,1.1,1.9]，初值 (1.5+1.1)/2=1.3，Wilder 平滑 (1.3+1.9)/2=1.6
+func TestATRReference(t *testing.T) {
+	highs := []float64{10.5, 11.5, 11.2, 12.4}
+	lows := []float64{9.5, 10.2, 10.1, 11.0}
+	closes := []float64{10, 11, 10.5, 12}
+	if got := ATR(highs[:3], lows[:3], closes[:3], 2); !near(got, 1.3, 1e-9) {
+		t.Errorf("ATR 初值 = %v，期望 1.3", got)
+	}
+	if got := ATR(highs, lows, closes, 2); !near(got, 1.6, 1e-9) {
+		t.Errorf("ATR = %v，期望 1.6", got)
+	}
+	// 只有收盘价：TR 退化为收盘价变动的绝对值
+	if got := ATR(closes, closes, closes, 3); !near(got, (1+0.5+1.5)/3, 1e-9) {
+		t.Errorf("收盘价 ATR = %v，期望 1", got)
+	}
+	if ATR(highs, lows[:3], closes, 2) != 0 || ATR(highs[:2], lows[:2], closes[:2], 2) != 0 {
+		t.Error("长度不一致或数据不足应返回 0")
+	}
+}
+
+// TestBollingerReference 1..5 的 5 周期布林带：中轨 3，总体标准差 √2
+func TestBollingerReference(t *testing.T) {
+	b := Bollinger([]float64{9, 1, 2, 3, 4, 5}, 5, 2)
+	std := math.Sqrt2
+	if !near(b.Middle, 3, 1e-9) || !near(b.Upper, 3+2*std, 1e-9) || !near(b.Lower, 3-2*std, 1e-9) {
+		t.Fatalf("布林带不符: %+v", b)
+	}
+	if !near(b.Width, 4*std/3, 1e-9) || !near(b.PercentB, (5-(3-2*std))/(4*std), 1e-9) {
+		t.Errorf("宽度/位置不符: %+v", b)
+	}
+
+	if flat := Bollinger([]float64{2, 2, 2}, 3, 2); flat.PercentB != 0.5 || flat.Width != 0 {
+		t.Errorf("价格不变时位置应为 0.5、宽度 0，实际 %+v", flat)
+	}
+	if short := Bollinger([]float64{1, 2}, 3, 2); short != (Bands{PercentB: 0.5}) {
+		t.Errorf("数据不足应返回中性值，实际 %+v", short)
+	}
+}
+
+// TestVolatilityReference 收益率 [0.1,-0.1,0.1] 的总体标准差 = √(0.08/9)
+func TestVolatilityReference(t *testing.T) {
+	closes := []float64{50, 100, 110, 99, 108.9}
+	if got := Volatility(closes, 3); !near(got, math.Sqrt(0.08/9), 1e-9) {
+		t.Errorf("Volatility = %v，期望 %v", got, math.Sqrt(0.08/9))
+	}
+	if Volatility(closes[:3], 3) != 0 {
+		t.Error("数据不足应返回 0")
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import "strings"
+
+// AssetNative 原生币在资产标识中的合约位
+const AssetNative = "native"
+
+// AssetIDFor 资产的规范标识 "<链>:<合约/mint>"，原生币为 "<链>:native"：
+// 同名代币（如 Ethereum/Tron/Solana 上的 USDT）按链和合约区分，链名与合约统一小写
+func AssetIDFor(chain, contract string) string {
+	contract = strings.ToLower(strings.TrimSpace(contract))
+	if contract == "" {
+		contract = AssetNative
+	}
+	return strings.ToLower(strings.TrimSpace(chain)) + ":" + contract
+}
@@ -4,15 +4,20 @@ import "time"
 
 // 扫描器 -> API 上报的统一事件
 type Event struct {
-	Entity    string    `json:"entity"`
-	Chain     string    `json:"chain"`
-	Coin      string    `json:"coin"`
-	Direction string    `json:"direction"` // "in" / "out"
-	Amount    string    `json:"amount"`    // 十进制字符串
-	TS        time.Time `json:"ts"`        // 发生时间(UTC)
-	TxID      string    `json:"txid"`
-	From      string    `json:"from"`
-	To        string    `json:"to"`
-	Address   string    `json:"address"`   // 命中的监控地址
-	LogIndex  int       `json:"log_index"` // ERC20: 链上 logIndex；原生: -1
+	Entity      string    `json:"entity"`
+	Chain       string    `json:"chain"`
+	Coin        string    `json:"coin"`               // 币种符号，同名代币跨链相同（如 USDT）
+	AssetID     string    `json:"asset_id,omitempty"` // 链内资产标识，见 AssetIDFor；区分各链的同名代币
+	Direction   string    `json:"direction"`          // "in" / "out"
+	Amount      string    `json:"amount"`             // 十进制字符串
+	TS          time.Time `json:"ts"`                 // 发生时间(UTC)
+	TxID        string    `json:"txid"`
+	From        string    `json:"from"`
+	To          string    `json:"to"`
+	Address     string    `json:"address"`                // 命中的监控地址
+	LogIndex    int       `json:"log_index"`              // ERC20: 链上 logIndex；原生: -1；原生内部转账: -(2+序号)
+	AddressType string    `json:"address_type,omitempty"` // 命中地址的类型：hot/cold/deposit/staking
+	BlockNumber uint64    `json:"block_number,omitempty"` // EVM: 所在区块高度
+	BlockHash   string    `json:"block_hash,omitempty"`   // EVM: 所在区块哈希，重组后 API 据此替换孤块事件
+	USDValue    *float64  `json:"usd_value,omitempty"`    // 发生时的美元价值；API 开启 -ingest-usd-value 时按价格补齐
 }
@@ -34,13 +34,25 @@ type Portfolio struct {
 	Holdings map[string]Holding `json:"holdings"`
 	TotalUSD float64            `json:"total_usd"`
 	TS       int64              `json:"timestamp"`
+	// 按地址类型拆分的持仓：type -> (chain:SYMBOL -> Holding)，未标注类型的地址归入 unknown
+	ByType map[string]map[string]Holding `json:"by_type,omitempty"`
 }
 
+// 地址类型（钱包用途），来自 PoR 清单的 type/label 列
+const (
+	AddressTypeHot     = "hot"
+	AddressTypeCold    = "cold"
+	AddressTypeDeposit = "deposit"
+	AddressTypeStaking = "staking"
+	AddressTypeUnknown = "unknown"
+)
+
 type AddressRow struct {
 	Entity  string
 	Chain   string
 	Address string
 	Source  string
+	Type    string // 地址类型：hot/cold/deposit/staking，未标注为空
 }
 
 func (r AddressRow) EVM() common.Address { return common.HexToAddress(r.Address) }
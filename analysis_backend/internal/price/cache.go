@@ -0,0 +1,140 @@
+package price
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/netutil"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CurrentFunc 批量取当前美元价格（与 FetchPrices 同签名，测试可替换）
+type CurrentFunc func(ctx context.Context, syms []string) (map[string]float64, error)
+
+// HistoryFunc 取币种在某个 UTC 日的美元价格；没有该日数据时 ok=false
+type HistoryFunc func(ctx context.Context, sym string, day time.Time) (float64, bool, error)
+
+// Cache 带缓存的美元价格查询：发生时间在 RecentWindow 内用当前价（按 TTL 缓存），更早的按 (币种, UTC 日) 取历史价并一直缓存。
+// 查询失败与无价格同样缓存 TTL，避免每个事件都打一次价格接口
+type Cache struct {
+	Current      CurrentFunc
+	History      HistoryFunc   // 为 nil 时历史事件也用当前价
+	TTL          time.Duration // 当前价与失败结果的缓存时长，<=0 时按 5 分钟
+	RecentWindow time.Duration // 不超过该时长的事件视为“当前”，<=0 时按 1 小时
+
+	mu      sync.Mutex
+	current map[string]cachedPrice
+	history map[string]cachedPrice // SYM|2006-01-02
+	now     func() time.Time
+}
+
+type cachedPrice struct {
+	usd     float64
+	ok      bool
+	expires time.Time // 零值表示不过期（历史价）
+}
+
+// NewCache 用配置中的 CoinGecko 接口构造价格缓存；pricing.enable 关闭时所有查询返回无价格
+func NewCache(cfg config.Config, ttl time.Duration) *Cache {
+	return &Cache{
+		Current: func(ctx context.Context, syms []string) (map[string]float64, error) {
+			return FetchPrices(ctx, cfg, syms)
+		},
+		History: func(ctx context.Context, sym string, day time.Time) (float64, bool, error) {
+			return FetchHistoricalPrice(ctx, cfg, sym, day)
+		},
+		TTL: ttl,
+	}
+}
+
+// USD coin 在 at 时刻的美元价格
+func (c *Cache) USD(ctx context.Context, coin string, at time.Time) (float64, bool) {
+	sym := strings.ToUpper(strings.TrimSpace(coin))
+	if sym == "" {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current == nil {
+		c.current, c.history = map[string]cachedPrice{}, map[string]cachedPrice{}
+	}
+	now := time.Now()
+	if c.now != nil {
+		now = c.now()
+	}
+	recent := c.RecentWindow
+	if recent <= 0 {
+		recent = time.Hour
+	}
+	ttl := c.TTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	if c.History == nil || at.IsZero() || now.Sub(at) <= recent {
+		if p, ok := c.current[sym]; ok && now.Before(p.expires) {
+			return p.usd, p.ok
+		}
+		p := cachedPrice{expires: now.Add(ttl)}
+		prices, err := c.Current(ctx, []string{sym})
+		if err != nil {
+			log.Printf("[price] current %s: %v", sym, err)
+		} else {
+			p.usd, p.ok = prices[sym]
+		}
+		c.current[sym] = p
+		return p.usd, p.ok
+	}
+
+	day := at.UTC().Truncate(24 * time.Hour)
+	key := sym + "|" + day.Format("2006-01-02")
+	if p, ok := c.history[key]; ok && (p.expires.IsZero() || now.Before(p.expires)) {
+		return p.usd, p.ok
+	}
+	var p cachedPrice
+	usd, ok, err := c.History(ctx, sym, day)
+	if err != nil {
+		log.Printf("[price] history %s %s: %v", sym, day.Format("2006-01-02"), err)
+		p.expires = now.Add(ttl)
+	} else {
+		p.usd, p.ok = usd, ok
+	}
+	c.history[key] = p
+	return p.usd, p.ok
+}
+
+// FetchHistoricalPrice CoinGecko /coins/{id}/history 取某日（UTC 00:00）的美元价格；未映射的币种返回 ok=false
+func FetchHistoricalPrice(ctx context.Context, cfg config.Config, sym string, day time.Time) (float64, bool, error) {
+	if !cfg.Pricing.Enable {
+		return 0, false, nil
+	}
+	id := cfg.Pricing.Map[strings.ToUpper(sym)]
+	if id == "" {
+		return 0, false, nil
+	}
+	u := fmt.Sprintf("%s/api/v3/coins/%s/history?date=%s&localization=false", coinGeckoBase(cfg.Pricing.CoinGeckoEndpoint), id, day.UTC().Format("02-01-2006"))
+	var raw struct {
+		MarketData *struct {
+			CurrentPrice map[string]float64 `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := netutil.GetJSON(ctx, u, &raw); err != nil {
+		return 0, false, err
+	}
+	if raw.MarketData == nil {
+		return 0, false, nil
+	}
+	v, ok := raw.MarketData.CurrentPrice["usd"]
+	return v, ok, nil
+}
+
+// coinGeckoBase 配置的是 .../api/v3/simple/price，其它接口需要基础域名
+func coinGeckoBase(endpoint string) string {
+	if i := strings.Index(endpoint, "/api/v3"); i >= 0 {
+		return strings.TrimSuffix(endpoint[:i], "/")
+	}
+	return strings.TrimSuffix(endpoint, "/")
+}
@@ -0,0 +1,88 @@
+package price
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCacheCurrentAndHistory 近期事件用当前价并按 TTL 缓存；历史事件按 UTC 日取历史价并一直缓存
+func TestCacheCurrentAndHistory(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var curCalls, histCalls int
+	c := &Cache{
+		Current: func(_ context.Context, syms []string) (map[string]float64, error) {
+			curCalls++
+			return map[string]float64{"ETH": 3000}, nil
+		},
+		History: func(_ context.Context, sym string, day time.Time) (float64, bool, error) {
+			histCalls++
+			if sym == "ETH" && day.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+				return 3400, true, nil
+			}
+			return 0, false, nil
+		},
+		TTL: time.Minute,
+		now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	if v, ok := c.USD(ctx, "eth", now.Add(-10*time.Minute)); !ok || v != 3000 {
+		t.Fatalf("近期事件应用当前价，实际 %v %v", v, ok)
+	}
+	c.USD(ctx, "ETH", now)
+	if curCalls != 1 {
+		t.Fatalf("TTL 内应命中缓存，实际请求 %d 次", curCalls)
+	}
+	now = now.Add(2 * time.Minute)
+	c.USD(ctx, "ETH", now)
+	if curCalls != 2 {
+		t.Fatalf("TTL 过期后应重新请求，实际 %d 次", curCalls)
+	}
+
+	for _, at := range []time.Time{time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC), time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)} {
+		if v, ok := c.USD(ctx, "ETH", at); !ok || v != 3400 {
+			t.Fatalf("历史事件应用当日价格，实际 %v %v", v, ok)
+		}
+	}
+	if _, ok := c.USD(ctx, "ETH", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Error("没有历史价格时应返回 false")
+	}
+	now = now.Add(24 * time.Hour)
+	c.USD(ctx, "ETH", time.Date(2024, 3, 1, 5, 0, 0, 0, time.UTC))
+	if histCalls != 2 {
+		t.Errorf("同一天的历史价只请求一次且不过期，实际 %d 次", histCalls)
+	}
+}
+
+// TestCacheFailureIsCached 查询失败缓存 TTL，不会每个事件都请求一次
+func TestCacheFailureIsCached(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	calls := 0
+	c := &Cache{
+		Current: func(context.Context, []string) (map[string]float64, error) {
+			calls++
+			return nil, errors.New("429")
+		},
+		now: func() time.Time { return now },
+	}
+	for i := 0; i < 3; i++ {
+		if _, ok := c.USD(context.Background(), "BTC", now); ok {
+			t.Fatal("失败时不应返回价格")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("失败结果应缓存，实际请求 %d 次", calls)
+	}
+}
+
+// TestCoinGeckoBase simple/price 地址取基础域名
+func TestCoinGeckoBase(t *testing.T) {
+	if got := coinGeckoBase("https://api.coingecko.com/api/v3/simple/price"); got != "https://api.coingecko.com" {
+		t.Errorf("实际 %s", got)
+	}
+	if got := coinGeckoBase("https://proxy.example/"); got != "https://proxy.example" {
+		t.Errorf("实际 %s", got)
+	}
+}
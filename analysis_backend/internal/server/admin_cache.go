@@ -0,0 +1,73 @@
+package server
+
+import (
+	"net/http"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ===== 缓存运维接口 =====
+
+// cacheInspector 当前缓存后端的检查能力；未配置或不支持时返回 nil
+func (s *Server) cacheInspector() pdb.CacheInspector {
+	if s.cache == nil {
+		return nil
+	}
+	ins, _ := s.cache.(pdb.CacheInspector)
+	return ins
+}
+
+// ListCacheKeys GET /admin/cache/keys?prefix=
+// 列出缓存键及剩余 TTL（秒，-1 表示永不过期）
+func (s *Server) ListCacheKeys(c *gin.Context) {
+	ins := s.cacheInspector()
+	if ins == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "cache backend does not support inspection"})
+		return
+	}
+	prefix := c.Query("prefix")
+	keys, err := ins.Keys(c.Request.Context(), prefix)
+	if err != nil {
+		s.InternalServerError(c, "列出缓存键失败", err)
+		return
+	}
+	items := make([]gin.H, 0, len(keys))
+	for _, k := range keys {
+		ttl := int64(-1)
+		if k.TTL >= 0 {
+			ttl = int64(k.TTL.Seconds())
+		}
+		items = append(items, gin.H{"key": k.Key, "ttl_seconds": ttl})
+	}
+	c.JSON(http.StatusOK, gin.H{"prefix": prefix, "count": len(items), "keys": items})
+}
+
+// FlushCache POST /admin/cache/flush
+// body: {"prefix": "market:"}；prefix 为空时清空全部，需显式传 {"all": true}
+func (s *Server) FlushCache(c *gin.Context) {
+	ins := s.cacheInspector()
+	if ins == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "cache backend does not support flush"})
+		return
+	}
+	var req struct {
+		Prefix string `json:"prefix"`
+		All    bool   `json:"all"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body", "details": err.Error()})
+		return
+	}
+	if req.Prefix == "" && !req.All {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required (or set all=true to flush everything)"})
+		return
+	}
+	n, err := ins.Flush(c.Request.Context(), req.Prefix)
+	if err != nil {
+		s.InternalServerError(c, "清理缓存失败", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prefix": req.Prefix, "flushed": n})
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAdminCacheRouter(cache pdb.CacheInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	s := &Server{}
+	s.SetCache(cache)
+	r := gin.New()
+	r.GET("/admin/cache/keys", s.ListCacheKeys)
+	r.POST("/admin/cache/flush", s.FlushCache)
+	return r
+}
+
+type cacheKeysResp struct {
+	Count int `json:"count"`
+	Keys  []struct {
+		Key        string `json:"key"`
+		TTLSeconds int64  `json:"ttl_seconds"`
+	} `json:"keys"`
+}
+
+func listCacheKeys(t *testing.T, r *gin.Engine, prefix string) cacheKeysResp {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/cache/keys?prefix="+prefix, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("keys status=%d body=%s", w.Code, w.Body.String())
+	}
+	var out cacheKeysResp
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func flushCache(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/cache/flush", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestAdminCacheKeysAndFlush 列表反映当前键与 TTL，按前缀清理只删除匹配的键
+func TestAdminCacheKeysAndFlush(t *testing.T) {
+	ctx := context.Background()
+	cache := pdb.NewMemoryCache()
+	_ = cache.Set(ctx, "market:BTC", []byte("1"), 2*time.Minute)
+	_ = cache.Set(ctx, "market:ETH", []byte("2"), 2*time.Minute)
+	_ = cache.Set(ctx, "flows:binance", []byte("3"), 10*time.Minute)
+	r := newAdminCacheRouter(cache)
+
+	all := listCacheKeys(t, r, "")
+	if all.Count != 3 || all.Keys[0].Key != "flows:binance" {
+		t.Fatalf("期望 3 个按键名排序的键，实际 %+v", all)
+	}
+	if ttl := all.Keys[0].TTLSeconds; ttl <= 540 || ttl > 600 {
+		t.Errorf("flows:binance TTL 应接近 600s，实际 %d", ttl)
+	}
+	if got := listCacheKeys(t, r, "market:"); got.Count != 2 {
+		t.Errorf("market: 前缀应有 2 个键，实际 %+v", got)
+	}
+
+	if w := flushCache(r, `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("未指定前缀且未确认 all 应拒绝，实际 %d", w.Code)
+	}
+	w := flushCache(r, `{"prefix":"market:"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"flushed":2`) {
+		t.Fatalf("flush prefix status=%d body=%s", w.Code, w.Body.String())
+	}
+	if ok, _ := cache.Exists(ctx, "market:BTC"); ok {
+		t.Error("market:BTC 应已被清理")
+	}
+	if got := listCacheKeys(t, r, ""); got.Count != 1 || got.Keys[0].Key != "flows:binance" {
+		t.Errorf("清理后只应剩 flows:binance，实际 %+v", got)
+	}
+
+	if w := flushCache(r, `{"all":true}`); w.Code != http.StatusOK {
+		t.Fatalf("flush all status=%d", w.Code)
+	}
+	if got := listCacheKeys(t, r, ""); got.Count != 0 {
+		t.Errorf("全部清理后应为空，实际 %+v", got)
+	}
+}
+
+// plainCache 不支持检查的缓存后端
+type plainCache struct{ pdb.CacheInterface }
+
+// TestAdminCacheUnsupportedBackend 缓存后端不支持检查时返回 501
+func TestAdminCacheUnsupportedBackend(t *testing.T) {
+	r := newAdminCacheRouter(plainCache{})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/cache/keys", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("期望 501，实际 %d", w.Code)
+	}
+}
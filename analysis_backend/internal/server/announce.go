@@ -18,6 +18,7 @@ const (
 )
 
 type binanceIngestItem struct {
+	CatalogID  int       `json:"catalog_id"` // Binance catalog id（48/49/93...），按配置映射为统一类型
 	Code       string    `json:"code"`
 	Title      string    `json:"title"`
 	URL        string    `json:"url"`
@@ -109,6 +110,22 @@ func (s *Server) normalizeAnnouncement(
 	}
 }
 
+// sourceRefs 记录公告的真实来源（Source 字段统一为 coincarp，来源信息保存在 sources 中）
+func sourceRefs(source string, ann pdb.Announcement) datatypes.JSONType[[]pdb.AnnouncementSourceRef] {
+	return datatypes.NewJSONType([]pdb.AnnouncementSourceRef{{Source: source, ExternalID: ann.ExternalID, URL: ann.URL}})
+}
+
+// announcementType 按配置将数据源类目映射为统一类型（announcements.category_map）
+func (s *Server) announcementType(source, categoryID string, ann pdb.Announcement) string {
+	var catMap map[string]map[string]string
+	defaultType := ""
+	if s.cfg != nil {
+		catMap = s.cfg.Announcements.CategoryMap
+		defaultType = s.cfg.Announcements.DefaultType
+	}
+	return pdb.ResolveAnnouncementType(catMap, defaultType, source, categoryID, ann.Title, ann.Summary)
+}
+
 // ---- Ingest ----
 
 func (s *Server) IngestBinanceAnnouncements(c *gin.Context) {
@@ -125,9 +142,12 @@ func (s *Server) IngestBinanceAnnouncements(c *gin.Context) {
 	for _, it := range req.Items {
 		ann := s.normalizeAnnouncement(it.Code, it.Title, it.URL, it.Tags, it.Summary, it.ReleaseMS, it.ReleaseISO, it.CreatedAt, "")
 		ann.Verified = true // 官方源验证标记
+		ann.Sources = sourceRefs("binance", ann)
+		ann.Type = s.announcementType("binance", strconv.Itoa(it.CatalogID), ann)
 		rows = append(rows, ann)
 	}
-	out, err := pdb.SaveAnnouncements(s.db.DB(), rows)
+	// 跨源合并后入库（同一上币事件与 coincarp 等来源合并为一条）
+	saved, err := pdb.MergeAnnouncements(s.db.DB(), rows)
 	if err != nil {
 		s.DatabaseError(c, "保存公告", err)
 		return
@@ -136,7 +156,7 @@ func (s *Server) IngestBinanceAnnouncements(c *gin.Context) {
 	if s.cache != nil {
 		_ = s.InvalidateAnnouncementsCache(c.Request.Context())
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": len(out)})
+	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": saved})
 }
 
 func (s *Server) IngestUpbitAnnouncements(c *gin.Context) {
@@ -153,9 +173,12 @@ func (s *Server) IngestUpbitAnnouncements(c *gin.Context) {
 	for _, it := range req.Items {
 		ann := s.normalizeAnnouncement(it.Code, it.Title, it.URL, it.Tags, it.Summary, it.ReleaseMS, it.ReleaseISO, it.CreatedAt, "")
 		ann.Verified = true // 官方源验证标记
+		ann.Sources = sourceRefs("upbit", ann)
+		ann.Type = s.announcementType("upbit", "", ann)
 		rows = append(rows, ann)
 	}
-	out, err := pdb.SaveAnnouncements(s.db.DB(), rows)
+	// 跨源合并后入库（同一上币事件与 coincarp 等来源合并为一条）
+	saved, err := pdb.MergeAnnouncements(s.db.DB(), rows)
 	if err != nil {
 		s.DatabaseError(c, "保存公告", err)
 		return
@@ -164,7 +187,7 @@ func (s *Server) IngestUpbitAnnouncements(c *gin.Context) {
 	if s.cache != nil {
 		_ = s.InvalidateAnnouncementsCache(c.Request.Context())
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": len(out)})
+	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": saved})
 }
 
 // 通用公告 ingest（仅支持 coincarp 数据源）
@@ -175,6 +198,7 @@ type genericIngestItem struct {
 	Summary    string   `json:"summary"`
 	URL        string   `json:"url"`
 	Tags       []string `json:"tags"`
+	Category   string   `json:"category"` // 数据源原始类目（如 OKX annType / Bybit type.key）
 	ReleaseMS  int64    `json:"release_ms"`
 	Exchange   string   `json:"exchange"`
 	IsEvent    bool     `json:"is_event"`
@@ -198,9 +222,15 @@ func (s *Server) IngestGenericAnnouncements(c *gin.Context) {
 		return
 	}
 
+	source := strings.ToLower(strings.TrimSpace(c.Param("source")))
+	if source == "" {
+		source = sourceCoincarp
+	}
 	rows := make([]pdb.Announcement, 0, len(req.Items))
 	for _, it := range req.Items {
 		ann := s.normalizeAnnouncement(it.ExternalID, it.Title, it.URL, it.Tags, it.Summary, it.ReleaseMS, "", time.Time{}, it.NewsCode)
+		ann.Sources = sourceRefs(source, ann)
+		ann.Type = s.announcementType(source, it.Category, ann)
 		// 设置扩展字段
 		ann.Exchange = it.Exchange
 		ann.IsEvent = it.IsEvent
@@ -210,7 +240,7 @@ func (s *Server) IngestGenericAnnouncements(c *gin.Context) {
 		rows = append(rows, ann)
 	}
 
-	err := pdb.MergeAnnouncements(s.db.DB(), rows)
+	saved, err := pdb.MergeAnnouncements(s.db.DB(), rows)
 	if err != nil {
 		s.DatabaseError(c, "合并公告", err)
 		return
@@ -219,17 +249,18 @@ func (s *Server) IngestGenericAnnouncements(c *gin.Context) {
 	if s.cache != nil {
 		_ = s.InvalidateAnnouncementsCache(c.Request.Context())
 	}
-	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": len(rows)})
+	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": saved})
 }
 
 // ---- Query ----
 
 // ListAnnouncements 查询公告列表（仅 coincarp 数据源，支持分页）
-// GET /announcements/recent?categories=newcoin,finance&q=listing&page=1&page_size=50&is_event=true&verified=true&sentiment=positive&exchange=binance
+// GET /announcements/recent?categories=newcoin,finance&types=new-listing,delisting&q=listing&page=1&page_size=50&is_event=true&verified=true&sentiment=positive&exchange=binance
 // 兼容旧格式：limit/offset 会自动转换为 page/page_size
 func (s *Server) ListAnnouncements(c *gin.Context) {
 	q := strings.TrimSpace(c.Query("q"))
 	categories := parseCSV(c.Query("categories"))
+	types := parseCSV(c.Query("types"))
 
 	// 分页参数：优先使用 page/page_size，兼容 limit/offset
 	// 分页参数（兼容旧格式 limit 和 offset）
@@ -285,6 +316,9 @@ func (s *Server) ListAnnouncements(c *gin.Context) {
 	if len(categories) > 0 {
 		baseQuery = baseQuery.Where("category IN ?", categories)
 	}
+	if len(types) > 0 {
+		baseQuery = baseQuery.Where("type IN ?", types)
+	}
 	if q != "" {
 		pat := "%" + strings.ToLower(q) + "%"
 		baseQuery = baseQuery.Where("LOWER(title) LIKE ? OR LOWER(summary) LIKE ?", pat, pat)
@@ -357,6 +391,77 @@ func (s *Server) ListAnnouncements(c *gin.Context) {
 	})
 }
 
+// SearchAnnouncements 公告全文搜索（标题/摘要），按相关度排序，支持来源与时间过滤
+// GET /announcements/search?q=staking&source=binance&from=2025-01-01&to=2025-06-30&page=1&page_size=20
+// from/to 支持 YYYY-MM-DD、RFC3339 或 Unix 秒；to 为日期时包含当天
+func (s *Server) SearchAnnouncements(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		s.ValidationError(c, "q", "搜索关键词不能为空")
+		return
+	}
+	params := AnnouncementSearchParams{
+		Q:                q,
+		Source:           c.Query("source"),
+		PaginationParams: ParsePaginationParams(c.Query("page"), c.Query("page_size"), 20, 100),
+	}
+	if v := strings.TrimSpace(c.Query("from")); v != "" {
+		t, _, ok := parseSearchTime(v)
+		if !ok {
+			s.ValidationError(c, "from", "时间格式错误")
+			return
+		}
+		params.From = &t
+	}
+	if v := strings.TrimSpace(c.Query("to")); v != "" {
+		t, dateOnly, ok := parseSearchTime(v)
+		if !ok {
+			s.ValidationError(c, "to", "时间格式错误")
+			return
+		}
+		if dateOnly {
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		params.To = &t
+	}
+	if params.From != nil && params.To != nil && params.From.After(*params.To) {
+		s.ValidationError(c, "from", "开始时间不能晚于结束时间")
+		return
+	}
+
+	rows, total, mode, err := s.db.SearchAnnouncements(params)
+	if err != nil {
+		s.DatabaseError(c, "搜索公告", err)
+		return
+	}
+	totalPages := int((total + int64(params.PageSize) - 1) / int64(params.PageSize))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       rows,
+		"total":       total,
+		"page":        params.Page,
+		"page_size":   params.PageSize,
+		"total_pages": totalPages,
+		"mode":        mode,
+	})
+}
+
+// parseSearchTime 解析搜索时间参数；dateOnly 表示仅给出日期
+func parseSearchTime(v string) (t time.Time, dateOnly bool, ok bool) {
+	if d, err := time.Parse("2006-01-02", v); err == nil {
+		return d.UTC(), true, true
+	}
+	if d, err := time.Parse(time.RFC3339, v); err == nil {
+		return d.UTC(), false, true
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+		return time.Unix(n, 0).UTC(), false, true
+	}
+	return time.Time{}, false, false
+}
+
 // GetLatestAnnouncementTime 获取最新的公告时间（用于增量同步，仅 coincarp 数据源）
 // GET /announcements/latest-time
 func (s *Server) GetLatestAnnouncementTime(c *gin.Context) {
@@ -387,6 +492,85 @@ func (s *Server) GetLatestAnnouncementTime(c *gin.Context) {
 	})
 }
 
+// 公告价格影响默认统计窗口
+var defaultAnnouncementImpactWindows = []time.Duration{15 * time.Minute, time.Hour, 4 * time.Hour, 24 * time.Hour}
+
+// RefreshAnnouncementImpacts 计算上币公告发布后的价格影响并入库
+// POST /announcements/impact/refresh?since=2025-01-01&kind=spot&interval=1m&windows=15,60,240
+// since 默认 7 天前；windows 单位为分钟
+func (s *Server) RefreshAnnouncementImpacts(c *gin.Context) {
+	since := time.Now().UTC().Add(-7 * 24 * time.Hour)
+	if v := strings.TrimSpace(c.Query("since")); v != "" {
+		t, _, ok := parseSearchTime(v)
+		if !ok {
+			s.ValidationError(c, "since", "时间格式错误")
+			return
+		}
+		since = t
+	}
+	kind := strings.ToLower(strings.TrimSpace(c.DefaultQuery("kind", "spot")))
+	if kind != "spot" && kind != "futures" {
+		s.ValidationError(c, "kind", "仅支持 spot 或 futures")
+		return
+	}
+	interval := strings.TrimSpace(c.DefaultQuery("interval", "1m"))
+	windows := defaultAnnouncementImpactWindows
+	if v := parseCSV(c.Query("windows")); len(v) > 0 {
+		windows = make([]time.Duration, 0, len(v))
+		for _, m := range v {
+			n, err := strconv.Atoi(m)
+			if err != nil || n <= 0 {
+				s.ValidationError(c, "windows", "窗口必须为正整数分钟")
+				return
+			}
+			windows = append(windows, time.Duration(n)*time.Minute)
+		}
+	}
+
+	saved, err := pdb.RefreshAnnouncementImpacts(s.db.DB(), since, kind, interval, windows)
+	if err != nil {
+		s.DatabaseError(c, "计算公告价格影响", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "saved": saved})
+}
+
+// ListAnnouncementImpacts 查询公告价格影响数据
+// GET /announcements/impact?symbol=FOOUSDT&window=60&page=1&page_size=50
+func (s *Server) ListAnnouncementImpacts(c *gin.Context) {
+	pagination := ParsePaginationParams(c.Query("page"), c.Query("page_size"), 50, 200)
+	q := s.db.DB().Model(&pdb.AnnouncementImpact{})
+	if sym := strings.ToUpper(strings.TrimSpace(c.Query("symbol"))); sym != "" {
+		q = q.Where("symbol = ?", sym)
+	}
+	if v := strings.TrimSpace(c.Query("window")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.ValidationError(c, "window", "窗口必须为正整数分钟")
+			return
+		}
+		q = q.Where("window_minutes = ?", n)
+	}
+
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		s.DatabaseError(c, "统计公告价格影响", err)
+		return
+	}
+	var rows []pdb.AnnouncementImpact
+	if err := q.Order("release_time DESC, window_minutes ASC").
+		Limit(pagination.PageSize).Offset(pagination.Offset).Find(&rows).Error; err != nil {
+		s.DatabaseError(c, "查询公告价格影响", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":     rows,
+		"total":     total,
+		"page":      pagination.Page,
+		"page_size": pagination.PageSize,
+	})
+}
+
 func parseCSV(s string) []string {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newAnnouncementSearchServer(t *testing.T) *Server {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.Announcement{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	ts := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	ref := func(src, url string) datatypes.JSONType[[]pdb.AnnouncementSourceRef] {
+		return datatypes.NewJSONType([]pdb.AnnouncementSourceRef{{Source: src, URL: url}})
+	}
+	rows := []pdb.Announcement{
+		{Source: "coincarp", ExternalID: "1", URL: "u1", Title: "Binance Launches ETH Staking", Summary: "earn rewards", ReleaseTime: ts, Sources: ref("binance", "u1")},
+		{Source: "coincarp", ExternalID: "2", URL: "u2", Title: "OKX Savings Update", Summary: "staking rates adjusted", ReleaseTime: ts.Add(24 * time.Hour), Sources: ref("okx", "u2")},
+		{Source: "coincarp", ExternalID: "3", URL: "u3", Title: "Binance Staking Promotion", Summary: "", ReleaseTime: ts.AddDate(0, 2, 0), Sources: ref("binance", "u3")},
+		{Source: "coincarp", ExternalID: "4", URL: "u4", Title: "Binance Will List Foo (FOO)", Summary: "new listing", ReleaseTime: ts, Sources: ref("binance", "u4")},
+	}
+	if err := gdb.Create(&rows).Error; err != nil {
+		t.Fatalf("写入公告失败: %v", err)
+	}
+	return &Server{db: NewGormDatabase(gdb)}
+}
+
+func doAnnouncementSearch(t *testing.T, s *Server, query string) (int, []string, int64) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/announcements/search", s.SearchAnnouncements)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/announcements/search?"+query, nil))
+	var resp struct {
+		Items []pdb.Announcement `json:"items"`
+		Total int64              `json:"total"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	urls := make([]string, 0, len(resp.Items))
+	for _, it := range resp.Items {
+		urls = append(urls, it.URL)
+	}
+	return w.Code, urls, resp.Total
+}
+
+// TestSearchAnnouncements 关键词命中标题或摘要，标题命中优先，来源/时间过滤可组合
+func TestSearchAnnouncements(t *testing.T) {
+	s := newAnnouncementSearchServer(t)
+
+	cases := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"关键词匹配标题和摘要", "q=staking", []string{"u3", "u1", "u2"}},
+		{"多个关键词需同时命中", "q=binance+staking", []string{"u3", "u1"}},
+		{"按来源过滤", "q=staking&source=okx", []string{"u2"}},
+		{"按时间过滤（to 为日期时包含当天）", "q=staking&from=2025-03-01&to=2025-03-02", []string{"u1", "u2"}},
+		{"来源与时间组合", "q=staking&source=binance&to=2025-03-31", []string{"u1"}},
+		{"分页", "q=staking&page=2&page_size=2", []string{"u2"}},
+	}
+	for _, c := range cases {
+		code, got, total := doAnnouncementSearch(t, s, c.query)
+		if code != http.StatusOK {
+			t.Fatalf("%s: 期望 200，实际 %d", c.name, code)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: 期望 %v，实际 %v", c.name, c.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("%s: 期望 %v，实际 %v", c.name, c.want, got)
+				break
+			}
+		}
+		if c.name != "分页" && total != int64(len(c.want)) {
+			t.Errorf("%s: total 期望 %d，实际 %d", c.name, len(c.want), total)
+		}
+	}
+
+	if code, _, _ := doAnnouncementSearch(t, s, "q="); code != http.StatusBadRequest {
+		t.Errorf("缺少关键词应返回 400，实际 %d", code)
+	}
+	if code, _, _ := doAnnouncementSearch(t, s, "q=staking&from=bad"); code != http.StatusBadRequest {
+		t.Errorf("时间格式错误应返回 400，实际 %d", code)
+	}
+}
@@ -839,7 +839,9 @@ func (s *Server) RunMonteCarloAnalysisAPI(c *gin.Context) {
 		EndDate       time.Time `json:"end_date" binding:"required"`
 		Strategy      string    `json:"strategy"`
 		Simulations   int       `json:"simulations"`
-		BootstrapSize int       `json:"bootstrap_size"`
+		BootstrapSize int       `json:"bootstrap_size"` // 每次重采样的交易笔数，默认与实际交易数相同
+		Method        string    `json:"method"`         // bootstrap / shuffle
+		Seed          int64     `json:"seed"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -854,9 +856,6 @@ func (s *Server) RunMonteCarloAnalysisAPI(c *gin.Context) {
 	if req.Simulations == 0 {
 		req.Simulations = 1000
 	}
-	if req.BootstrapSize == 0 {
-		req.BootstrapSize = 252 // 一年的交易日
-	}
 
 	ctx := c.Request.Context()
 
@@ -878,6 +877,8 @@ func (s *Server) RunMonteCarloAnalysisAPI(c *gin.Context) {
 		Simulations:     req.Simulations,
 		ConfidenceLevel: 0.95,
 		BootstrapSize:   req.BootstrapSize,
+		Method:          req.Method,
+		Seed:            req.Seed,
 	}
 
 	// 执行蒙特卡洛分析
@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
 )
 
 // RunWalkForwardAnalysis 执行走步前进分析
@@ -21,18 +23,22 @@ func (be *BacktestEngine) RunWalkForwardAnalysis(ctx context.Context, config Bac
 	return result, nil
 }
 
-// RunMonteCarloAnalysis 执行蒙特卡洛分析
+// RunMonteCarloAnalysis 执行蒙特卡洛分析：回测后对已实现交易收益重采样，给出结果的置信区间
 func (be *BacktestEngine) RunMonteCarloAnalysis(ctx context.Context, config BacktestConfig, analysis MonteCarloAnalysis) (*MonteCarloResult, error) {
 	log.Printf("[INFO] Starting Monte Carlo analysis with %d simulations", analysis.Simulations)
 
-	// 简化实现：这里应该实现完整的蒙特卡洛分析逻辑
-	// 目前返回一个基本的结构
+	backtest, err := be.RunBacktest(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("回测失败: %w", err)
+	}
 
-	result := &MonteCarloResult{
-		Analysis: analysis,
+	result, err := be.runTradeMonteCarlo(backtest, analysis)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Printf("[INFO] Monte Carlo analysis completed")
+	log.Printf("[INFO] Monte Carlo analysis completed: median return=%.2f%%, max drawdown P5/P95=%.2f%%/%.2f%%",
+		result.MedianReturn*100, result.MaxDrawdownP5*100, result.MaxDrawdownP95*100)
 	return result, nil
 }
 
@@ -64,13 +70,43 @@ func (be *BacktestEngine) RunAttributionAnalysis(ctx context.Context, config Bac
 	return &analysis, nil
 }
 
-// CompareStrategies 比较策略
-func (be *BacktestEngine) CompareStrategies(ctx context.Context, configs []BacktestConfig) (*StrategyComparison, error) {
+// CompareStrategies 比较策略：逐个回测后按总收益排名；指定基准时附带按市场环境拆分的对比
+func (be *BacktestEngine) CompareStrategies(ctx context.Context, configs []BacktestConfig, benchmarkSymbol string) (*StrategyComparison, error) {
 	log.Printf("[INFO] Starting strategy comparison for %d strategies", len(configs))
 
-	// 简化实现：这里应该实现完整的策略比较逻辑
 	result := &StrategyComparison{
-		Strategies: make([]StrategyResult, len(configs)),
+		BenchmarkSymbol: benchmarkSymbol,
+		Strategies:      make([]StrategyResult, 0, len(configs)),
+	}
+
+	for _, config := range configs {
+		backtest, err := be.RunBacktest(ctx, config)
+		if err != nil {
+			return nil, fmt.Errorf("策略%s回测失败: %w", config.Strategy, err)
+		}
+		sr := StrategyResult{
+			Config: config,
+			Result: *backtest,
+			Score:  backtest.Summary.TotalReturn,
+		}
+
+		if benchmarkSymbol != "" {
+			benchmark, err := be.getHistoricalData(ctx, benchmarkSymbol, config.StartDate, config.EndDate)
+			if err != nil {
+				log.Printf("[WARN] 获取基准%s历史数据失败，跳过分环境对比: %v", benchmarkSymbol, err)
+			} else {
+				sr.RegimeBreakdown = be.buildRegimeBreakdown(backtest.DailyReturns, benchmark)
+				sr.RegimeBreakdown.BenchmarkSymbol = benchmarkSymbol
+			}
+		}
+		result.Strategies = append(result.Strategies, sr)
+	}
+
+	sort.SliceStable(result.Strategies, func(i, j int) bool {
+		return result.Strategies[i].Score > result.Strategies[j].Score
+	})
+	for i := range result.Strategies {
+		result.Strategies[i].Rank = i + 1
 	}
 
 	log.Printf("[INFO] Strategy comparison completed")
@@ -107,7 +143,8 @@ func (be *BacktestEngine) OptimizeStrategy(ctx context.Context, config BacktestC
 
 // StrategyComparison 策略比较
 type StrategyComparison struct {
-	Strategies []StrategyResult `json:"strategies"`
+	BenchmarkSymbol string           `json:"benchmark_symbol,omitempty"`
+	Strategies      []StrategyResult `json:"strategies"`
 }
 
 // StrategyResult 策略结果
@@ -116,6 +153,8 @@ type StrategyResult struct {
 	Result BacktestResult `json:"result"`
 	Rank   int            `json:"rank"`
 	Score  float64        `json:"score"`
+
+	RegimeBreakdown *RegimeBreakdown `json:"regime_breakdown,omitempty"` // 按市场环境拆分的策略与基准对比
 }
 
 // BatchBacktestResult 批量回测结果
@@ -47,7 +47,8 @@ func (s *Server) RunBacktestAPI(c *gin.Context) {
 // POST /api/backtest/compare
 func (s *Server) CompareStrategiesAPI(c *gin.Context) {
 	var request struct {
-		Configs []BacktestConfig `json:"configs" binding:"required"`
+		Configs         []BacktestConfig `json:"configs" binding:"required"`
+		BenchmarkSymbol string           `json:"benchmark_symbol"` // 分环境对比的基准，默认 BTCUSDT
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -73,8 +74,12 @@ func (s *Server) CompareStrategiesAPI(c *gin.Context) {
 		}
 	}
 
+	if request.BenchmarkSymbol == "" {
+		request.BenchmarkSymbol = "BTCUSDT"
+	}
+
 	// 使用analysis模块的回测引擎
-	comparison, err := s.backtestEngine.CompareStrategies(c.Request.Context(), request.Configs)
+	comparison, err := s.backtestEngine.CompareStrategies(c.Request.Context(), request.Configs, request.BenchmarkSymbol)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -392,6 +397,12 @@ func (s *Server) validateBacktestConfig(config BacktestConfig) error {
 		return fmt.Errorf("开始日期不能晚于结束日期")
 	}
 
+	if s.backtestEngine != nil {
+		if _, err := s.backtestEngine.checkBacktestRange(config.StartDate, config.EndDate); err != nil {
+			return err
+		}
+	}
+
 	if config.InitialCash <= 0 {
 		return fmt.Errorf("初始资金必须大于0")
 	}
@@ -427,6 +438,16 @@ func (s *Server) validateBacktestConfig(config BacktestConfig) error {
 	if config.Commission < 0 || config.Commission > 0.1 {
 		return fmt.Errorf("手续费率必须在0-0.1之间")
 	}
+	for market, rate := range config.CommissionByMarket {
+		if rate < 0 || rate > 0.1 {
+			return fmt.Errorf("市场%s手续费率必须在0-0.1之间", market)
+		}
+	}
+	for symbol, rate := range config.CommissionBySymbol {
+		if rate < 0 || rate > 0.1 {
+			return fmt.Errorf("币种%s手续费率必须在0-0.1之间", symbol)
+		}
+	}
 
 	return nil
 }
@@ -461,6 +482,7 @@ func (s *Server) SaveBacktestResultAPI(c *gin.Context) {
 		Strategy:     request.Config.Strategy,
 		StartDate:    request.Config.StartDate.Format("2006-01-02"),
 		EndDate:      request.Config.EndDate.Format("2006-01-02"),
+		Fingerprint:  BacktestFingerprint(request.Config, nil),
 		Status:       "completed",                 // 手动保存的一定是完成状态
 		PositionSize: decimal.NewFromFloat(100.0), // 默认100%
 	}
@@ -543,6 +565,7 @@ func (s *Server) GetSavedBacktestsAPI(c *gin.Context) {
 			"end_date":        record.EndDate,
 			"initial_capital": record.InitialCapital.InexactFloat64(),
 			"position_size":   record.PositionSize.InexactFloat64(),
+			"fingerprint":     record.Fingerprint,
 			"status":          record.Status,
 			"result":          record.Result,
 			"error_message":   record.ErrorMessage,
@@ -593,6 +616,7 @@ func (s *Server) RunStrategyBacktestAPI(c *gin.Context) {
 		Symbol     string    `json:"symbol" binding:"required"`
 		StartDate  time.Time `json:"start_date"`
 		EndDate    time.Time `json:"end_date"`
+		Force      bool      `json:"force"` // 忽略相同配置的已完成结果，强制重新回测
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -627,6 +651,26 @@ func (s *Server) RunStrategyBacktestAPI(c *gin.Context) {
 
 	// 将策略配置转换为回测配置
 	backtestConfig := s.convertStrategyToBacktestConfig(strategy, request.StrategyID, request.Symbol, request.StartDate, request.EndDate)
+	fingerprint := BacktestFingerprint(backtestConfig, strategy.Conditions)
+
+	// 相同配置已有完成结果时直接复用
+	if !request.Force {
+		if cached, result := s.cachedBacktestResult(userID, fingerprint); result != nil {
+			log.Printf("[INFO] 策略回测命中缓存 ID=%d fingerprint=%s", cached.ID, fingerprint)
+			c.JSON(http.StatusOK, gin.H{
+				"success":     true,
+				"data":        result,
+				"record_id":   cached.ID,
+				"cached":      true,
+				"fingerprint": fingerprint,
+				"strategy": gin.H{
+					"id":   strategy.ID,
+					"name": strategy.Name,
+				},
+			})
+			return
+		}
+	}
 
 	// 检查回测引擎是否已初始化
 	if s.backtestEngine == nil {
@@ -641,6 +685,7 @@ func (s *Server) RunStrategyBacktestAPI(c *gin.Context) {
 		Strategy:     "strategy", // 使用固定策略类型
 		StartDate:    request.StartDate.Format("2006-01-02"),
 		EndDate:      request.EndDate.Format("2006-01-02"),
+		Fingerprint:  fingerprint,
 		Status:       "running",
 		PositionSize: decimal.NewFromFloat(100.0), // 默认100%
 	}
@@ -687,9 +732,11 @@ func (s *Server) RunStrategyBacktestAPI(c *gin.Context) {
 	log.Printf("[INFO] ✅ 策略回测完成并保存记录 ID=%d", record.ID)
 
 	c.JSON(http.StatusOK, gin.H{
-		"success":   true,
-		"data":      result,
-		"record_id": record.ID,
+		"success":     true,
+		"data":        result,
+		"record_id":   record.ID,
+		"cached":      false,
+		"fingerprint": fingerprint,
 		"strategy": gin.H{
 			"id":   strategy.ID,
 			"name": strategy.Name,
@@ -6,6 +6,7 @@ import (
 	"log"
 	"math"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -216,10 +217,27 @@ func (rc *ResultCache) generateBacktestKey(config BacktestConfig) string {
 	// 基于配置参数生成唯一键
 	key := fmt.Sprintf("strategy:%s_maxpos:%.2f_stoploss:%.2f_takeprofit:%.2f_commission:%.4f",
 		config.Strategy, config.MaxPosition, config.StopLoss, config.TakeProfit, config.Commission)
+	// 手续费覆盖会改变成交成本，不同覆盖的配置不能共用结果
+	key += fmt.Sprintf("_market:%s_free:%t_bymarket:%s_bysymbol:%s",
+		config.Market, config.CommissionFree, formatRateMap(config.CommissionByMarket), formatRateMap(config.CommissionBySymbol))
 	hash := md5.Sum([]byte(key))
 	return fmt.Sprintf("%x", hash)
 }
 
+// formatRateMap 按键排序后格式化费率表，保证同一内容生成相同的键
+func formatRateMap(m map[string]float64) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%g", k, m[k])
+	}
+	return strings.Join(parts, ",")
+}
+
 // WarmUpCache 预热缓存
 func (rc *ResultCache) WarmUpCache(configs []BacktestConfig, backtestFunc func(BacktestConfig) (*BacktestResult, error)) {
 	for _, config := range configs {
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestCommissionRateResolution 币种覆盖优先于市场覆盖，未配置时回退到统一费率
+func TestCommissionRateResolution(t *testing.T) {
+	cfg := BacktestConfig{
+		Commission:         0.001,
+		Market:             "futures",
+		CommissionByMarket: map[string]float64{"futures": 0.0005},
+		CommissionBySymbol: map[string]float64{"BTCUSDT": 0.0002},
+	}
+	cases := []struct {
+		symbol string
+		want   float64
+	}{
+		{"BTCUSDT", 0.0002},
+		{"btcusdt", 0.0002},
+		{"ETHUSDT", 0.0005},
+	}
+	for _, c := range cases {
+		if got := cfg.CommissionRate(c.symbol); got != c.want {
+			t.Errorf("%s 手续费率期望 %v，实际 %v", c.symbol, c.want, got)
+		}
+	}
+
+	cfg.Market = "" // 默认现货市场，无现货覆盖时使用统一费率
+	if got := cfg.CommissionRate("ETHUSDT"); got != 0.001 {
+		t.Errorf("未覆盖的币种应回退到统一费率 0.001，实际 %v", got)
+	}
+}
+
+// TestExecuteStrategyTradeUsesSymbolCommission 交易执行与平仓盈亏都使用币种对应的费率
+func TestExecuteStrategyTradeUsesSymbolCommission(t *testing.T) {
+	be := &BacktestEngine{}
+	cfg := BacktestConfig{
+		InitialCash:        10000,
+		MaxPosition:        0.5,
+		Commission:         0.001,
+		CommissionBySymbol: map[string]float64{"BTCUSDT": 0.0002},
+	}
+	now := time.Now()
+	decision := StrategyDecisionResult{Action: "sell", Multiplier: 1}
+
+	for _, tc := range []struct {
+		symbol string
+		rate   float64
+	}{
+		{"BTCUSDT", 0.0002},
+		{"ETHUSDT", 0.001},
+	} {
+		result := &BacktestResult{Config: cfg}
+		state := &StrategySimulationState{Cash: 10000, SymbolStats: map[string]*SymbolPerformance{}}
+		// 名义价值 10000*0.5 = 5000
+		data := MarketData{Symbol: tc.symbol, Price: 100, LastUpdated: now}
+		if err := be.executeStrategyTrade(decision, data, cfg, result, state); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Trades) != 1 {
+			t.Fatalf("%s 应产生 1 笔交易，实际 %d", tc.symbol, len(result.Trades))
+		}
+		want := 5000 * tc.rate
+		if got := result.Trades[0].Commission; math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s 手续费期望 %v，实际 %v", tc.symbol, want, got)
+		}
+		if math.Abs(state.Cash-(10000-want)) > 1e-9 {
+			t.Errorf("%s 扣费后现金期望 %v，实际 %v", tc.symbol, 10000-want, state.Cash)
+		}
+	}
+
+	// 平仓盈亏按卖出币种的费率计算卖出侧手续费
+	result := &BacktestResult{Config: cfg, Trades: []TradeRecord{
+		{Symbol: "BTCUSDT", Side: "buy", Quantity: 10, Price: 100, Commission: 0.2},
+	}}
+	pnl := be.calculateTradePnL(result, "BTCUSDT", "sell", 110, 10)
+	// (110-100)*10 - (0.2 + 1100*0.0002)
+	if want := 100 - 0.2 - 0.22; math.Abs(pnl-want) > 1e-9 {
+		t.Errorf("平仓盈亏期望 %v，实际 %v", want, pnl)
+	}
+}
+
+// TestBacktestCacheKeyIncludesCommissionOverrides 手续费覆盖不同的配置不能命中同一条缓存；覆盖表的遍历顺序不影响键
+func TestBacktestCacheKeyIncludesCommissionOverrides(t *testing.T) {
+	rc := NewResultCache(10, time.Hour)
+	base := BacktestConfig{Strategy: "ml", MaxPosition: 0.5, Commission: 0.001}
+	bySymbol := base
+	bySymbol.CommissionBySymbol = map[string]float64{"BTCUSDT": 0.0002}
+	byMarket := base
+	byMarket.Market = "futures"
+	byMarket.CommissionByMarket = map[string]float64{"futures": 0.0005}
+	free := base
+	free.CommissionFree = true
+
+	seen := map[string]string{}
+	for name, cfg := range map[string]BacktestConfig{"base": base, "bySymbol": bySymbol, "byMarket": byMarket, "free": free} {
+		key := rc.generateBacktestKey(cfg)
+		if other, ok := seen[key]; ok {
+			t.Errorf("%s 与 %s 生成了相同的缓存键", name, other)
+		}
+		seen[key] = name
+	}
+
+	a, b := base, base
+	a.CommissionBySymbol = map[string]float64{"BTCUSDT": 0.0002, "ETHUSDT": 0.0004, "SOLUSDT": 0.0006}
+	b.CommissionBySymbol = map[string]float64{"SOLUSDT": 0.0006, "ETHUSDT": 0.0004, "BTCUSDT": 0.0002}
+	if rc.generateBacktestKey(a) != rc.generateBacktestKey(b) {
+		t.Error("内容相同的覆盖表应生成相同的缓存键")
+	}
+
+	rc.SetBacktestResult(base, &BacktestResult{Config: base})
+	if _, ok := rc.GetBacktestResult(bySymbol); ok {
+		t.Error("带币种覆盖的配置不应命中统一费率的缓存结果")
+	}
+}
+
+// TestHyperparameterBacktestUsesSymbolCommission 超参数回测的买卖手续费按币种覆盖费率计算
+func TestHyperparameterBacktestUsesSymbolCommission(t *testing.T) {
+	cfg := &BacktestConfig{
+		Symbol:             "BTCUSDT",
+		InitialCash:        10000,
+		PositionSize:       0.5,
+		StopLoss:           -1, // 作为买入阈值：总是买入
+		TakeProfit:         0.001,
+		Commission:         0.001,
+		CommissionBySymbol: map[string]float64{"BTCUSDT": 0.0002},
+	}
+	now := time.Now()
+	data := make([]MarketData, 60)
+	for i := range data {
+		data[i] = MarketData{Symbol: "BTCUSDT", Price: 100 + float64(i), LastUpdated: now.Add(time.Duration(i) * time.Hour)}
+	}
+	result, err := NewHyperparameterOptimizer().runBacktestWithConfig(t.Context(), cfg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Trades) == 0 {
+		t.Fatal("应产生交易")
+	}
+	for _, tr := range result.Trades {
+		if want := tr.Quantity * tr.Price * 0.0002; math.Abs(tr.Commission-want) > 1e-9 {
+			t.Errorf("%s 手续费期望 %v（币种费率），实际 %v", tr.Side, want, tr.Commission)
+		}
+	}
+}
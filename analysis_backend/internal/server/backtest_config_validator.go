@@ -115,6 +115,72 @@ func (cv *ConfigValidator) validateRiskConfig(config *BacktestConfig) error {
 	if config.Commission < 0 || config.Commission > 0.01 {
 		return fmt.Errorf("手续费率必须在0-1%%之间，当前值: %.4f", config.Commission)
 	}
+	for market, rate := range config.CommissionByMarket {
+		if rate < 0 || rate > 0.01 {
+			return fmt.Errorf("市场%s手续费率必须在0-1%%之间，当前值: %.4f", market, rate)
+		}
+	}
+	for symbol, rate := range config.CommissionBySymbol {
+		if rate < 0 || rate > 0.01 {
+			return fmt.Errorf("币种%s手续费率必须在0-1%%之间，当前值: %.4f", symbol, rate)
+		}
+	}
+
+	// 验证合约配置
+	if config.Leverage < 0 || config.Leverage > maxFuturesLeverage {
+		return fmt.Errorf("杠杆倍数必须在0-%d之间，当前值: %.2f", maxFuturesLeverage, config.Leverage)
+	}
+	if config.Leverage > 1 && !config.IsFutures() {
+		return fmt.Errorf("杠杆仅支持合约回测（market=futures），当前市场: %s", config.Market)
+	}
+	if config.MaintenanceMarginRate < 0 || config.MaintenanceMarginRate >= 1 {
+		return fmt.Errorf("维持保证金率必须在0-1之间，当前值: %.4f", config.MaintenanceMarginRate)
+	}
+
+	// 验证组合级限制
+	if config.MaxConcurrentPositions < 0 {
+		return fmt.Errorf("最大持仓数不能为负数，当前值: %d", config.MaxConcurrentPositions)
+	}
+	if config.MaxGrossExposure < 0 {
+		return fmt.Errorf("最大总敞口不能为负数，当前值: %.2f", config.MaxGrossExposure)
+	}
+
+	if config.RiskFreeRate != nil && (*config.RiskFreeRate <= -1 || *config.RiskFreeRate >= 1) {
+		return fmt.Errorf("无风险利率必须在-100%%到100%%之间，当前值: %.4f", *config.RiskFreeRate)
+	}
+	if config.MaxHoldingPeriod < 0 {
+		return fmt.Errorf("最大持有周期不能为负数，当前值: %d", config.MaxHoldingPeriod)
+	}
+
+	// 验证再平衡配置
+	if config.RebalanceInterval < 0 {
+		return fmt.Errorf("再平衡周期不能为负数，当前值: %d", config.RebalanceInterval)
+	}
+	switch config.RebalanceWeighting {
+	case "", RebalanceEqualWeight, RebalanceScoreWeight:
+	default:
+		return fmt.Errorf("不支持的再平衡权重方式: %s", config.RebalanceWeighting)
+	}
+	if config.RebalanceTolerance < 0 || config.RebalanceTolerance >= 1 {
+		return fmt.Errorf("再平衡容忍度必须在0-1之间，当前值: %.2f", config.RebalanceTolerance)
+	}
+
+	if config.MinDataQuality < 0 || config.MinDataQuality > 1 {
+		return fmt.Errorf("数据质量下限必须在0-1之间，当前值: %.2f", config.MinDataQuality)
+	}
+
+	// 验证数据预处理配置
+	if pp := config.Preprocessing; pp != nil {
+		if pp.MaxGapBars < 0 || pp.SpikeThreshold < 0 {
+			return fmt.Errorf("缺口填充K线数和坏点阈值不能为负数")
+		}
+		if pp.MaxMissingRatio < 0 || pp.MaxMissingRatio >= 1 {
+			return fmt.Errorf("最大缺失比例必须在0-1之间，当前值: %.2f", pp.MaxMissingRatio)
+		}
+		if pp.SpikeAction != "" && pp.SpikeAction != "clip" && pp.SpikeAction != "flag" {
+			return fmt.Errorf("不支持的坏点处理方式: %s", pp.SpikeAction)
+		}
+	}
 
 	return nil
 }
@@ -11,6 +11,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // EnhancedDataManager enhanced data manager
@@ -211,16 +213,19 @@ func (edm *EnhancedDataManager) collectDataFromMultipleSources(ctx context.Conte
 		log.Printf("[INFO] Database data skipped due to quality issues (nil returned)")
 	}
 
-	// 2. Binance API data (real-time supplement)
-	if apiData, err := edm.fetchFromBinanceAPI(ctx, symbol, startDate, endDate); err == nil {
-		dataSources["binance_api"] = apiData
-		log.Printf("[INFO] Binance API data: %d entries", len(apiData))
-	}
+	// 2-3. Live API sources only in live mode; db mode already falls back inside fetchFromDatabase
+	if edm.backtestEngine != nil && edm.backtestEngine.historicalDataSource() == BacktestDataSourceLive {
+		// 2. Binance API data (real-time supplement)
+		if apiData, err := edm.fetchFromBinanceAPI(ctx, symbol, startDate, endDate); err == nil {
+			dataSources["binance_api"] = apiData
+			log.Printf("[INFO] Binance API data: %d entries", len(apiData))
+		}
 
-	// 3. CoinGecko API data (backup source)
-	if cgData, err := edm.fetchFromCoinGeckoAPI(ctx, symbol, startDate, endDate); err == nil {
-		dataSources["coingecko_api"] = cgData
-		log.Printf("[INFO] CoinGecko API data: %d entries", len(cgData))
+		// 3. CoinGecko API data (backup source)
+		if cgData, err := edm.fetchFromCoinGeckoAPI(ctx, symbol, startDate, endDate); err == nil {
+			dataSources["coingecko_api"] = cgData
+			log.Printf("[INFO] CoinGecko API data: %d entries", len(cgData))
+		}
 	}
 
 	// Check if we have any real data sources available
@@ -384,36 +389,34 @@ func (edm *EnhancedDataManager) fetchFromDatabase(ctx context.Context, symbol st
 	// Strategy 1: First check database for existing data
 	dbKlines, dbErr := db.GetMarketKlines(edm.backtestEngine.db.DB(), dbSymbol, "spot", "1d", &startDate, &endDate, maxDataPoints)
 
-	// Strategy 2: Always try to get fresh data from API (more reliable for historical data)
-	log.Printf("[DATA_ACQUISITION] Attempting to fetch fresh data from Binance API for %s", symbol)
-	apiData, apiErr := edm.fetchFromAPIDirect(ctx, symbol, startDate, endDate, maxDataPoints)
+	// Strategy 2: live mode prefers fresh API data, merged with database data to fill gaps
+	if edm.backtestEngine.historicalDataSource() == BacktestDataSourceLive {
+		log.Printf("[DATA_ACQUISITION] Attempting to fetch fresh data from Binance API for %s", symbol)
+		apiData, apiErr := edm.fetchFromAPIDirect(ctx, symbol, startDate, endDate, maxDataPoints)
 
-	if apiErr == nil && len(apiData) >= 30 {
-		// API data is available and sufficient - use it as primary source
-		log.Printf("[DATA_ACQUISITION] Using %d data points from API for %s", len(apiData), symbol)
+		if apiErr == nil && len(apiData) >= backtestMinDataPoints {
+			log.Printf("[DATA_ACQUISITION] Using %d data points from API for %s", len(apiData), symbol)
 
-		// If database also has data, merge intelligently to avoid gaps
-		if dbErr == nil && len(dbKlines) > 0 {
-			mergedData := edm.mergeDataSources(apiData, convertKlinesToMarketData(dbKlines))
-			log.Printf("[DATA_MERGE] Merged API and database data: %d points total", len(mergedData))
-			return mergedData, nil
-		}
+			// If database also has data, merge intelligently to avoid gaps
+			if dbErr == nil && len(dbKlines) > 0 {
+				mergedData := edm.mergeDataSources(apiData, convertKlinesToMarketData(dbKlines))
+				log.Printf("[DATA_MERGE] Merged API and database data: %d points total", len(mergedData))
+				return mergedData, nil
+			}
 
-		return apiData, nil
+			return apiData, nil
+		}
+		log.Printf("[DATA_FALLBACK] API data insufficient (%d points), using database data", len(apiData))
 	}
 
-	// Strategy 3: API failed or insufficient, fall back to database with expansion
-	log.Printf("[DATA_FALLBACK] API data insufficient (%d points), using database data", len(apiData))
+	// Strategy 3: database, expanding the range when short
 	if dbErr != nil {
-		log.Printf("[WARN] Database query also failed: %v", dbErr)
-		if apiErr == nil && len(apiData) > 0 {
-			return apiData, nil // Return whatever API data we have
-		}
-		return nil, fmt.Errorf("both database and API failed to provide sufficient data")
+		log.Printf("[WARN] Database query failed for %s: %v", symbol, dbErr)
+		dbKlines = nil
 	}
 
 	// If database data is insufficient, try to expand the search range
-	if len(dbKlines) < 50 { // Need at least 50 days for meaningful backtesting
+	if dbErr == nil && len(dbKlines) < 50 { // Need at least 50 days for meaningful backtesting
 		log.Printf("[INFO] Database has limited data (%d points for %s), expanding search range", len(dbKlines), symbol)
 
 		// Try to get data from an earlier start date (up to 2 years back)
@@ -427,10 +430,14 @@ func (edm *EnhancedDataManager) fetchFromDatabase(ctx context.Context, symbol st
 		}
 	}
 
-	// Final check: ensure we have minimum required data
-	if len(dbKlines) < 30 {
-		return nil, fmt.Errorf("insufficient historical data for %s: only %d data points available, minimum 30 required",
-			symbol, len(dbKlines))
+	// Final check: the range is not synced yet, fetch live klines and cache them when allowed
+	if len(dbKlines) < backtestMinDataPoints {
+		if edm.backtestEngine.liveDataAllowed() {
+			log.Printf("[LIVE_FALLBACK] Database has %d points for %s, fetching missing klines from exchange", len(dbKlines), symbol)
+			return edm.fetchFromAPIAndSave(ctx, symbol, startDate, endDate, maxDataPoints)
+		}
+		return nil, fmt.Errorf("insufficient historical data for %s: only %d data points available, minimum %d required "+
+			"(sync klines first or enable backtest.live_fallback)", symbol, len(dbKlines), backtestMinDataPoints)
 	}
 
 	// Log data range information
@@ -482,9 +489,11 @@ func (edm *EnhancedDataManager) fetchFromDatabase(ctx context.Context, symbol st
 			return nil, nil
 		}
 
-		// 如果数据质量问题但数据量足够，尝试从API补充更高质量的数据
-		log.Printf("[QUALITY_FALLBACK] Attempting to fetch fresh data from API for %s", symbol)
-		return edm.fetchFromAPIAndSave(ctx, symbol, startDate, endDate, maxDataPoints)
+		// 如果数据质量问题但数据量足够，允许访问交易所时尝试从API补充更高质量的数据
+		if edm.backtestEngine.liveDataAllowed() {
+			log.Printf("[QUALITY_FALLBACK] Attempting to fetch fresh data from API for %s", symbol)
+			return edm.fetchFromAPIAndSave(ctx, symbol, startDate, endDate, maxDataPoints)
+		}
 	}
 
 	return marketData, nil
@@ -598,11 +607,6 @@ func (edm *EnhancedDataManager) hasUnrealisticPriceStability(data []MarketData)
 }
 
 func (edm *EnhancedDataManager) fetchFromBinanceAPI(ctx context.Context, symbol string, startDate, endDate time.Time) ([]MarketData, error) {
-	if edm.backtestEngine == nil || edm.backtestEngine.server == nil {
-		log.Printf("[WARN] Server instance not available for Binance API call")
-		return []MarketData{}, nil
-	}
-
 	// 计算需要的数据点数量（每天一个数据点）
 	days := int(endDate.Sub(startDate).Hours()/24) + 1
 	if days > 365 {
@@ -610,7 +614,7 @@ func (edm *EnhancedDataManager) fetchFromBinanceAPI(ctx context.Context, symbol
 	}
 
 	// 调用Binance API获取历史K线数据
-	klines, err := edm.backtestEngine.server.fetchBinanceKlinesWithTimeRange(ctx, symbol, "spot", "1d", days, &startDate, &endDate)
+	klines, err := edm.fetchKlines(ctx, symbol, "spot", "1d", days, &startDate, &endDate)
 	if err != nil {
 		log.Printf("[WARN] Failed to fetch Binance API data for %s: %v", symbol, err)
 		return []MarketData{}, nil
@@ -1358,7 +1362,7 @@ func (edm *EnhancedDataManager) fetchFromAPIAndSave(ctx context.Context, symbol
 	log.Printf("[API_FALLBACK] Fetching data from Binance API for %s (%s to %s)", symbol, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
 
 	// 从API获取K线数据
-	klines, err := edm.backtestEngine.server.fetchBinanceKlinesWithTimeRange(ctx, symbol, "spot", "1d", maxDataPoints, &startDate, &endDate)
+	klines, err := edm.fetchKlines(ctx, symbol, "spot", "1d", maxDataPoints, &startDate, &endDate)
 	if err != nil {
 		log.Printf("[API_FALLBACK] Failed to fetch from API for %s: %v", symbol, err)
 		return nil, fmt.Errorf("failed to fetch from API: %w", err)
@@ -1369,8 +1373,8 @@ func (edm *EnhancedDataManager) fetchFromAPIAndSave(ctx context.Context, symbol
 		return []MarketData{}, nil
 	}
 
-	// 保存到数据库
-	if err := edm.saveKlinesToDatabase(symbol, "spot", "1d", klines); err != nil {
+	// 保存到数据库（与 fetchFromDatabase 查询使用相同的交易对格式，下次回测直接命中）
+	if err := edm.saveKlinesToDatabase(edm.convertToDatabaseSymbol(symbol, "spot"), "spot", "1d", klines); err != nil {
 		log.Printf("[API_FALLBACK] Failed to save API data to database for %s: %v", symbol, err)
 		// 保存失败但仍返回数据，不影响回测
 	}
@@ -1409,7 +1413,7 @@ func (edm *EnhancedDataManager) fetchFromAPIAndSave(ctx context.Context, symbol
 	return marketData, nil
 }
 
-// saveKlinesToDatabase 将K线数据保存到数据库
+// saveKlinesToDatabase 将K线数据保存到数据库，已存在的开盘时间跳过（重复回测不会产生重复K线）
 func (edm *EnhancedDataManager) saveKlinesToDatabase(symbol, kind, interval string, klines []BinanceKline) error {
 	if len(klines) == 0 {
 		return fmt.Errorf("no kline data to save")
@@ -1420,73 +1424,57 @@ func (edm *EnhancedDataManager) saveKlinesToDatabase(symbol, kind, interval stri
 		return fmt.Errorf("database connection is nil")
 	}
 
-	// 开启事务
-	tx := gdb.Begin()
-	if tx.Error != nil {
-		return fmt.Errorf("failed to begin transaction: %w", tx.Error)
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// 为每个K线数据创建数据库记录
+	now := time.Now()
+	rows := make([]db.MarketKline, 0, len(klines))
 	for _, kline := range klines {
-		marketKline := db.MarketKline{
+		rows = append(rows, db.MarketKline{
 			Symbol:     symbol,
 			Kind:       kind,
 			Interval:   interval,
-			OpenTime:   time.Unix(int64(kline.OpenTime/1000), 0),
+			OpenTime:   time.UnixMilli(int64(kline.OpenTime)).UTC(),
 			OpenPrice:  kline.Open,
 			HighPrice:  kline.High,
 			LowPrice:   kline.Low,
 			ClosePrice: kline.Close,
 			Volume:     kline.Volume,
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
-		}
+			CreatedAt:  now,
+			UpdatedAt:  now,
+		})
+	}
 
-		// 使用ON DUPLICATE KEY UPDATE来避免重复插入
-		query := `
-			INSERT INTO market_klines
-			(symbol, kind, ` + "`interval`" + `, open_time, open_price, high_price, low_price, close_price, volume, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-			ON DUPLICATE KEY UPDATE
-				open_price = VALUES(open_price),
-				high_price = VALUES(high_price),
-				low_price = VALUES(low_price),
-				close_price = VALUES(close_price),
-				volume = VALUES(volume),
-				updated_at = VALUES(updated_at)
-		`
-
-		if err := tx.Exec(query,
-			marketKline.Symbol,
-			marketKline.Kind,
-			marketKline.Interval,
-			marketKline.OpenTime,
-			marketKline.OpenPrice,
-			marketKline.HighPrice,
-			marketKline.LowPrice,
-			marketKline.ClosePrice,
-			marketKline.Volume,
-			marketKline.CreatedAt,
-			marketKline.UpdatedAt,
-		).Error; err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to save kline for %s %s %s: %w",
-				marketKline.Symbol, marketKline.Kind, marketKline.Interval, err)
+	saved := 0
+	err := gdb.Transaction(func(tx *gorm.DB) error {
+		var existing []time.Time
+		if err := tx.Model(&db.MarketKline{}).
+			Where("symbol = ? AND kind = ? AND `interval` = ? AND open_time BETWEEN ? AND ?",
+				symbol, kind, interval, rows[0].OpenTime, rows[len(rows)-1].OpenTime).
+			Pluck("open_time", &existing).Error; err != nil {
+			return fmt.Errorf("query existing klines: %w", err)
+		}
+		seen := make(map[int64]bool, len(existing))
+		for _, t := range existing {
+			seen[t.Unix()] = true
 		}
-	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		missing := make([]db.MarketKline, 0, len(rows))
+		for _, row := range rows {
+			if !seen[row.OpenTime.Unix()] {
+				seen[row.OpenTime.Unix()] = true
+				missing = append(missing, row)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		saved = len(missing)
+		return tx.CreateInBatches(missing, 100).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save klines for %s %s %s: %w", symbol, kind, interval, err)
 	}
 
-	log.Printf("[DB_SAVE] Successfully saved %d kline records for %s %s %s",
-		len(klines), symbol, kind, interval)
+	log.Printf("[DB_SAVE] Successfully saved %d/%d kline records for %s %s %s",
+		saved, len(klines), symbol, kind, interval)
 	return nil
 }
 
@@ -1515,7 +1503,7 @@ func (edm *EnhancedDataManager) fetchFromAPIDirect(ctx context.Context, symbol s
 		symbol, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), limit)
 
 	// Use the server's Binance API fetch method
-	klines, err := edm.backtestEngine.server.fetchBinanceKlinesWithTimeRange(ctx, symbol, "spot", "1d", limit, &startDate, &endDate)
+	klines, err := edm.fetchKlines(ctx, symbol, "spot", "1d", limit, &startDate, &endDate)
 	if err != nil {
 		log.Printf("[API_DIRECT] Failed to fetch from API for %s: %v", symbol, err)
 		return nil, err
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"fmt"
 	"math"
 	"sort"
+	"time"
 )
 
 // DataPreprocessor 数据预处理器
@@ -474,3 +476,144 @@ type OutlierInfo struct {
 	ZScore         float64 `json:"z_score,omitempty"`
 	ModifiedZScore float64 `json:"modified_z_score,omitempty"`
 }
+
+// DataPreprocessingConfig 回测数据预处理配置，各项为 0 时不启用对应处理
+type DataPreprocessingConfig struct {
+	MaxGapBars      int     `json:"max_gap_bars"`      // 缺口不超过该K线数时前向填充
+	SpikeThreshold  float64 `json:"spike_threshold"`   // 价格偏离相邻K线中位数超过该比例视为坏点
+	SpikeAction     string  `json:"spike_action"`      // clip（默认，裁剪到阈值边界）/ flag（仅记录）
+	MaxMissingRatio float64 `json:"max_missing_ratio"` // 缺失K线占比超过该值的币种不参与回测
+}
+
+// 数据清洗动作
+const (
+	CleaningGapFilled     = "gap_filled"
+	CleaningSpikeClipped  = "spike_clipped"
+	CleaningSpikeFlagged  = "spike_flagged"
+	CleaningSymbolDropped = "symbol_dropped"
+)
+
+// DataCleaningAction 一条数据清洗记录
+type DataCleaningAction struct {
+	Symbol    string    `json:"symbol"`
+	Action    string    `json:"action"`
+	Timestamp time.Time `json:"timestamp"`
+	Count     int       `json:"count,omitempty"`    // 填充的K线数
+	Original  float64   `json:"original,omitempty"` // 坏点原始价格
+	Adjusted  float64   `json:"adjusted,omitempty"` // 裁剪后的价格
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// barInterval 以相邻时间差的中位数作为K线周期
+func barInterval(data []MarketData) time.Duration {
+	if len(data) < 2 {
+		return 0
+	}
+	diffs := make([]time.Duration, 0, len(data)-1)
+	for i := 1; i < len(data); i++ {
+		if d := data[i].LastUpdated.Sub(data[i-1].LastUpdated); d > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+	if len(diffs) == 0 {
+		return 0
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i] < diffs[j] })
+	return diffs[len(diffs)/2]
+}
+
+// Preprocess 按配置清洗单个币种的K线：剔除缺失过多的币种、处理价格坏点、前向填充小缺口。
+// 返回清洗后的数据、清洗记录，以及该币种是否被剔除。
+func (dp *DataPreprocessor) Preprocess(symbol string, data []MarketData, cfg DataPreprocessingConfig) ([]MarketData, []DataCleaningAction, bool) {
+	var actions []DataCleaningAction
+	if len(data) < 2 {
+		return data, actions, false
+	}
+
+	interval := barInterval(data)
+	if interval <= 0 {
+		return data, actions, false
+	}
+
+	// 1. 缺失比例
+	if cfg.MaxMissingRatio > 0 {
+		expected := int(data[len(data)-1].LastUpdated.Sub(data[0].LastUpdated)/interval) + 1
+		missing := expected - len(data)
+		if ratio := float64(missing) / float64(expected); missing > 0 && ratio > cfg.MaxMissingRatio {
+			actions = append(actions, DataCleaningAction{
+				Symbol:    symbol,
+				Action:    CleaningSymbolDropped,
+				Timestamp: data[0].LastUpdated,
+				Count:     missing,
+				Detail:    fmt.Sprintf("缺失%d/%d根K线(%.1f%%)，超过上限%.1f%%", missing, expected, ratio*100, cfg.MaxMissingRatio*100),
+			})
+			return nil, actions, true
+		}
+	}
+
+	cleaned := make([]MarketData, len(data))
+	copy(cleaned, data)
+
+	// 2. 价格坏点：与前后各两根K线的中位数比较，基于原始数据判断，避免裁剪结果影响后续判断
+	if cfg.SpikeThreshold > 0 {
+		for i := range data {
+			neighbors := make([]float64, 0, 4)
+			for j := i - 2; j <= i+2; j++ {
+				if j != i && j >= 0 && j < len(data) && data[j].Price > 0 {
+					neighbors = append(neighbors, data[j].Price)
+				}
+			}
+			if len(neighbors) < 2 {
+				continue
+			}
+			sort.Float64s(neighbors)
+			ref := (neighbors[(len(neighbors)-1)/2] + neighbors[len(neighbors)/2]) / 2
+			deviation := (data[i].Price - ref) / ref
+			if math.Abs(deviation) <= cfg.SpikeThreshold {
+				continue
+			}
+
+			action := DataCleaningAction{
+				Symbol:    symbol,
+				Action:    CleaningSpikeFlagged,
+				Timestamp: data[i].LastUpdated,
+				Original:  data[i].Price,
+				Detail:    fmt.Sprintf("偏离相邻中位数%.2f%%", deviation*100),
+			}
+			if cfg.SpikeAction != "flag" {
+				bound := ref * (1 + math.Copysign(cfg.SpikeThreshold, deviation))
+				cleaned[i].Price = bound
+				action.Action = CleaningSpikeClipped
+				action.Adjusted = bound
+			}
+			actions = append(actions, action)
+		}
+	}
+
+	// 3. 小缺口前向填充
+	if cfg.MaxGapBars > 0 {
+		filled := make([]MarketData, 0, len(cleaned))
+		filled = append(filled, cleaned[0])
+		for i := 1; i < len(cleaned); i++ {
+			prev := cleaned[i-1]
+			missing := int(cleaned[i].LastUpdated.Sub(prev.LastUpdated)/interval) - 1
+			if missing > 0 && missing <= cfg.MaxGapBars {
+				for k := 1; k <= missing; k++ {
+					bar := prev
+					bar.LastUpdated = prev.LastUpdated.Add(time.Duration(k) * interval)
+					filled = append(filled, bar)
+				}
+				actions = append(actions, DataCleaningAction{
+					Symbol:    symbol,
+					Action:    CleaningGapFilled,
+					Timestamp: prev.LastUpdated.Add(interval),
+					Count:     missing,
+				})
+			}
+			filled = append(filled, cleaned[i])
+		}
+		cleaned = filled
+	}
+
+	return cleaned, actions, false
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// preprocessTestData 每小时一根K线，第10根为坏点（价格翻倍），第20-21根缺失
+func preprocessTestData() []MarketData {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data []MarketData
+	for i := 0; i < 40; i++ {
+		if i == 20 || i == 21 {
+			continue
+		}
+		price := 100 + float64(i)*0.1
+		if i == 10 {
+			price = 200
+		}
+		data = append(data, MarketData{Symbol: "ETHUSDT", Price: price, LastUpdated: start.Add(time.Duration(i) * time.Hour)})
+	}
+	return data
+}
+
+// TestPreprocessClipsSpikeAndFillsGap 坏点被裁剪到阈值边界，小缺口被前向填充，且都有清洗记录
+func TestPreprocessClipsSpikeAndFillsGap(t *testing.T) {
+	dp := NewDataPreprocessor()
+	data := preprocessTestData()
+
+	cleaned, actions, dropped := dp.Preprocess("ETHUSDT", data, DataPreprocessingConfig{
+		MaxGapBars:     3,
+		SpikeThreshold: 0.1,
+	})
+	if dropped {
+		t.Fatal("缺失比例未配置时不应剔除币种")
+	}
+	if len(cleaned) != 40 {
+		t.Fatalf("填充后应有 40 根K线，实际 %d", len(cleaned))
+	}
+
+	// 相邻四根K线中位数 = (100.9 + 101.1) / 2 = 101，裁剪到 101 * 1.1
+	if want := 101 * 1.1; math.Abs(cleaned[10].Price-want) > 1e-9 {
+		t.Errorf("坏点应裁剪到 %.4f，实际 %.4f", want, cleaned[10].Price)
+	}
+	for _, i := range []int{20, 21} {
+		if !cleaned[i].LastUpdated.Equal(data[0].LastUpdated.Add(time.Duration(i)*time.Hour)) || cleaned[i].Price != cleaned[19].Price {
+			t.Errorf("第%d根K线应前向填充为 %.4f，实际 %+v", i, cleaned[19].Price, cleaned[i])
+		}
+	}
+
+	var clipped, filled int
+	for _, a := range actions {
+		switch a.Action {
+		case CleaningSpikeClipped:
+			clipped++
+			if a.Original != 200 || !a.Timestamp.Equal(data[10].LastUpdated) {
+				t.Errorf("坏点记录不符: %+v", a)
+			}
+		case CleaningGapFilled:
+			filled++
+			if a.Count != 2 {
+				t.Errorf("缺口记录应为 2 根K线: %+v", a)
+			}
+		default:
+			t.Errorf("意外的清洗动作: %+v", a)
+		}
+	}
+	if clipped != 1 || filled != 1 {
+		t.Errorf("期望 1 条坏点记录和 1 条缺口记录，实际 %d/%d", clipped, filled)
+	}
+	if data[10].Price != 200 {
+		t.Error("预处理不应修改原始数据")
+	}
+}
+
+// TestPreprocessFlagAndDrop 仅标记模式不改价格；缺口超过上限不填充；缺失过多时剔除币种
+func TestPreprocessFlagAndDrop(t *testing.T) {
+	dp := NewDataPreprocessor()
+	data := preprocessTestData()
+
+	cleaned, actions, _ := dp.Preprocess("ETHUSDT", data, DataPreprocessingConfig{
+		MaxGapBars:     1,
+		SpikeThreshold: 0.1,
+		SpikeAction:    "flag",
+	})
+	if len(cleaned) != len(data) || cleaned[10].Price != 200 {
+		t.Errorf("flag 模式不应修改价格，缺口超过上限不应填充: len=%d price=%.2f", len(cleaned), cleaned[10].Price)
+	}
+	if len(actions) != 1 || actions[0].Action != CleaningSpikeFlagged {
+		t.Errorf("应只有一条坏点标记记录: %+v", actions)
+	}
+
+	// 缺失 2/40 = 5% 超过 4% 上限
+	cleaned, actions, dropped := dp.Preprocess("ETHUSDT", data, DataPreprocessingConfig{MaxMissingRatio: 0.04, MaxGapBars: 3})
+	if !dropped || cleaned != nil {
+		t.Fatal("缺失比例超过上限时应剔除币种")
+	}
+	if len(actions) != 1 || actions[0].Action != CleaningSymbolDropped || actions[0].Count != 2 {
+		t.Errorf("剔除记录不符: %+v", actions)
+	}
+
+	if _, _, dropped := dp.Preprocess("ETHUSDT", data, DataPreprocessingConfig{MaxMissingRatio: 0.1}); dropped {
+		t.Error("缺失比例未超过上限时不应剔除")
+	}
+}
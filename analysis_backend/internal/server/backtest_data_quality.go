@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+)
+
+// defaultQualitySpikeThreshold 质量评分中价格偏离相邻K线中位数超过该比例视为跳点
+const defaultQualitySpikeThreshold = 0.3
+
+// 质量评分各项权重：完整度、缺口、跳点、停滞行
+const (
+	qualityWeightCompleteness = 0.4
+	qualityWeightGaps         = 0.2
+	qualityWeightSpikes       = 0.2
+	qualityWeightStale        = 0.2
+)
+
+// DataQualityScore 单个币种回测前的数据质量评分，Score 在 0-1 之间，越高越好
+type DataQualityScore struct {
+	Symbol       string  `json:"symbol"`
+	Score        float64 `json:"score"`
+	Points       int     `json:"points"`       // 实际K线数
+	Completeness float64 `json:"completeness"` // 实际K线数 / 按周期应有的K线数
+	Gaps         int     `json:"gaps"`         // 缺口个数（相邻K线间隔超过一个周期）
+	Spikes       int     `json:"spikes"`       // 偏离相邻中位数的跳点数
+	StaleRows    int     `json:"stale_rows"`   // 价格与成交量都未更新或价格非正的K线数
+	Excluded     bool    `json:"excluded,omitempty"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// scoreDataQuality 按完整度、缺口数、跳点数、停滞行数计算数据质量评分；
+// spikeThreshold<=0 时使用默认阈值，跳点检测与预处理一致（与前后各两根K线的中位数比较）
+func scoreDataQuality(symbol string, data []MarketData, spikeThreshold float64) DataQualityScore {
+	q := DataQualityScore{Symbol: symbol, Points: len(data)}
+	if len(data) == 0 {
+		return q
+	}
+	if spikeThreshold <= 0 {
+		spikeThreshold = defaultQualitySpikeThreshold
+	}
+
+	expected := len(data)
+	if interval := barInterval(data); interval > 0 {
+		expected = int(data[len(data)-1].LastUpdated.Sub(data[0].LastUpdated)/interval) + 1
+		for i := 1; i < len(data); i++ {
+			if data[i].LastUpdated.Sub(data[i-1].LastUpdated) > interval*3/2 {
+				q.Gaps++
+			}
+		}
+	}
+	q.Completeness = math.Min(1, float64(len(data))/float64(expected))
+
+	for i := range data {
+		if data[i].Price <= 0 || (i > 0 && data[i].Price == data[i-1].Price && data[i].Volume24h == data[i-1].Volume24h) {
+			q.StaleRows++
+			continue
+		}
+		neighbors := make([]float64, 0, 4)
+		for j := i - 2; j <= i+2; j++ {
+			if j != i && j >= 0 && j < len(data) && data[j].Price > 0 {
+				neighbors = append(neighbors, data[j].Price)
+			}
+		}
+		if len(neighbors) < 2 {
+			continue
+		}
+		sort.Float64s(neighbors)
+		ref := (neighbors[(len(neighbors)-1)/2] + neighbors[len(neighbors)/2]) / 2
+		if math.Abs(data[i].Price-ref)/ref > spikeThreshold {
+			q.Spikes++
+		}
+	}
+
+	ratio := func(n, of int) float64 {
+		if of <= 0 {
+			return 0
+		}
+		return math.Min(1, float64(n)/float64(of))
+	}
+	score := qualityWeightCompleteness*q.Completeness +
+		qualityWeightGaps*(1-ratio(q.Gaps, expected-1)) +
+		qualityWeightSpikes*(1-ratio(q.Spikes, len(data))) +
+		qualityWeightStale*(1-ratio(q.StaleRows, len(data)))
+	q.Score = math.Round(score*1e4) / 1e4
+	return q
+}
+
+// checkDataQuality 回测前评分币种原始数据，低于 MinDataQuality 的标记为剔除；返回评分和是否保留
+func (be *BacktestEngine) checkDataQuality(symbol string, data []MarketData, config BacktestConfig) (DataQualityScore, bool) {
+	spikeThreshold := 0.0
+	if config.Preprocessing != nil {
+		spikeThreshold = config.Preprocessing.SpikeThreshold
+	}
+	q := scoreDataQuality(symbol, data, spikeThreshold)
+	if config.MinDataQuality > 0 && q.Score < config.MinDataQuality {
+		q.Excluded = true
+		q.Reason = fmt.Sprintf("质量评分%.2f低于下限%.2f", q.Score, config.MinDataQuality)
+		log.Printf("[DataQuality] %s %s（缺口%d，跳点%d，停滞%d，完整度%.1f%%），跳过此币种",
+			symbol, q.Reason, q.Gaps, q.Spikes, q.StaleRows, q.Completeness*100)
+		return q, false
+	}
+	return q, true
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// qualityTestSeries 逐小时K线，价格缓慢上涨，成交量每根不同
+func qualityTestSeries(sym string, n int) []MarketData {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]MarketData, n)
+	for i := range data {
+		data[i] = MarketData{
+			Symbol:      sym,
+			Price:       100 + float64(i)*0.1,
+			Volume24h:   1000 + float64(i),
+			LastUpdated: start.Add(time.Duration(i) * time.Hour),
+		}
+	}
+	return data
+}
+
+// TestDataQualityExcludesDirtySymbol 干净序列评分为满分并保留；含缺口、跳点、停滞行的序列低于下限被剔除
+func TestDataQualityExcludesDirtySymbol(t *testing.T) {
+	be := &BacktestEngine{}
+	config := BacktestConfig{MinDataQuality: 0.9}
+
+	clean := qualityTestSeries("CLEANUSDT", 100)
+	q, ok := be.checkDataQuality("CLEANUSDT", clean, config)
+	if !ok || q.Excluded || q.Score != 1 || q.Gaps != 0 || q.Spikes != 0 || q.StaleRows != 0 || q.Completeness != 1 {
+		t.Fatalf("干净序列应满分保留: %+v", q)
+	}
+
+	dirty := qualityTestSeries("DIRTYUSDT", 100)
+	// 停滞：20..39 价格与成交量不再更新
+	for i := 20; i < 40; i++ {
+		dirty[i].Price, dirty[i].Volume24h = dirty[19].Price, dirty[19].Volume24h
+	}
+	// 跳点：50、70 价格翻倍
+	dirty[50].Price *= 2
+	dirty[70].Price *= 2
+	// 缺口：删除 80..89 与 92..95 两段
+	dirty = append(dirty[:80], dirty[90:]...)
+	dirty = append(dirty[:82], dirty[86:]...)
+
+	q, ok = be.checkDataQuality("DIRTYUSDT", dirty, config)
+	if ok || !q.Excluded || q.Reason == "" {
+		t.Fatalf("脏数据应被剔除: %+v", q)
+	}
+	if q.Gaps != 2 || q.Spikes != 2 || q.StaleRows != 20 || q.Points != 86 {
+		t.Errorf("质量指标不符: %+v", q)
+	}
+	// 0.4*86/100 + 0.2*(1-2/99) + 0.2*(1-2/86) + 0.2*(1-20/86)
+	want := 0.4*0.86 + 0.2*(1-2.0/99) + 0.2*(1-2.0/86) + 0.2*(1-20.0/86)
+	if math.Abs(q.Score-want) > 1e-4 {
+		t.Errorf("评分期望 %.4f，实际 %.4f", want, q.Score)
+	}
+
+	// 未设置下限时只评分不剔除
+	if q, ok := be.checkDataQuality("DIRTYUSDT", dirty, BacktestConfig{}); !ok || q.Excluded {
+		t.Errorf("未设置下限时不应剔除: %+v", q)
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 回测历史K线来源
+const (
+	BacktestDataSourceDB   = "db"   // 只读数据库，缺数据时视 live_fallback 决定是否实时补拉
+	BacktestDataSourceLive = "live" // 优先交易所实时K线，与库内数据合并
+)
+
+// backtestMinDataPoints 回测所需的最少K线数
+const backtestMinDataPoints = 30
+
+// klineFetchFunc 交易所K线拉取函数，签名与 Server.fetchBinanceKlinesWithTimeRange 一致
+type klineFetchFunc func(ctx context.Context, symbol, kind, interval string, limit int, startTime, endTime *time.Time) ([]BinanceKline, error)
+
+// historicalDataSource 当前生效的数据来源，未配置或无法识别时按 db 处理
+func (be *BacktestEngine) historicalDataSource() string {
+	if strings.EqualFold(strings.TrimSpace(be.dataSource), BacktestDataSourceLive) {
+		return BacktestDataSourceLive
+	}
+	return BacktestDataSourceDB
+}
+
+// liveDataAllowed 是否允许回测期间访问交易所：live 模式，或 db 模式开启了实时补数据
+func (be *BacktestEngine) liveDataAllowed() bool {
+	return be.historicalDataSource() == BacktestDataSourceLive || be.liveDataFallback
+}
+
+// fetchKlines 从交易所拉取K线，优先使用注入的 klineFetcher
+func (edm *EnhancedDataManager) fetchKlines(ctx context.Context, symbol, kind, interval string, limit int, startTime, endTime *time.Time) ([]BinanceKline, error) {
+	be := edm.backtestEngine
+	if be == nil {
+		return nil, fmt.Errorf("backtest engine not available")
+	}
+	if be.klineFetcher != nil {
+		return be.klineFetcher(ctx, symbol, kind, interval, limit, startTime, endTime)
+	}
+	if be.server == nil {
+		return nil, fmt.Errorf("server instance not available for exchange kline fetch")
+	}
+	return be.server.fetchBinanceKlinesWithTimeRange(ctx, symbol, kind, interval, limit, startTime, endTime)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stubKlineFetcher 按日返回区间内的K线，记录调用次数
+type stubKlineFetcher struct {
+	calls int
+}
+
+func (f *stubKlineFetcher) fetch(_ context.Context, symbol, kind, interval string, limit int, startTime, endTime *time.Time) ([]BinanceKline, error) {
+	f.calls++
+	var klines []BinanceKline
+	for t, i := *startTime, 0; !t.After(*endTime) && i < limit; t, i = t.AddDate(0, 0, 1), i+1 {
+		price := fmt.Sprintf("%.2f", 100+float64(i%7)*3+float64(i)*0.5)
+		klines = append(klines, BinanceKline{
+			OpenTime: float64(t.UnixMilli()), Open: price, High: price, Low: price, Close: price, Volume: "1000",
+		})
+	}
+	return klines, nil
+}
+
+func newDataSourceTestEngine(t *testing.T, liveFallback bool) (*BacktestEngine, *stubKlineFetcher, *gorm.DB) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.MarketKline{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	fetcher := &stubKlineFetcher{}
+	be := &BacktestEngine{db: NewGormDatabase(gdb), liveDataFallback: liveFallback, klineFetcher: fetcher.fetch}
+	return be, fetcher, gdb
+}
+
+func fetchBacktestKlines(be *BacktestEngine, symbol string, start, end time.Time) ([]MarketData, error) {
+	edm := be.initializeEnhancedDataManager()
+	edm.setBacktestEngine(be)
+	return edm.fetchFromDatabase(context.Background(), symbol, start, end)
+}
+
+// TestLiveFallbackFetchesAndCaches 库内无数据时实时拉取并写回数据库，再次回测直接命中缓存
+func TestLiveFallbackFetchesAndCaches(t *testing.T) {
+	be, fetcher, gdb := newDataSourceTestEngine(t, true)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 59)
+
+	data, err := fetchBacktestKlines(be, "SOL", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.calls != 1 || len(data) != 60 {
+		t.Fatalf("期望实时拉取 1 次得到 60 条，实际调用 %d 次、%d 条", fetcher.calls, len(data))
+	}
+
+	var n int64
+	gdb.Model(&pdb.MarketKline{}).Where("symbol = ? AND kind = ? AND `interval` = ?", "SOLUSDT", "spot", "1d").Count(&n)
+	if n != 60 {
+		t.Fatalf("期望缓存 60 条 SOLUSDT 日K，实际 %d", n)
+	}
+
+	again, err := fetchBacktestKlines(be, "SOL", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetcher.calls != 1 {
+		t.Errorf("缓存命中后不应再访问交易所，实际调用 %d 次", fetcher.calls)
+	}
+	if len(again) != 60 || again[0].Source != "database" || !again[0].LastUpdated.Equal(start) {
+		t.Errorf("第二次应从数据库读到相同区间，实际 %d 条", len(again))
+	}
+
+	// 重复写入同一区间不产生重复K线
+	klines, _ := fetcher.fetch(context.Background(), "SOL", "spot", "1d", 100, &start, &end)
+	edm := be.initializeEnhancedDataManager()
+	edm.setBacktestEngine(be)
+	if err := edm.saveKlinesToDatabase("SOLUSDT", "spot", "1d", klines); err != nil {
+		t.Fatal(err)
+	}
+	gdb.Model(&pdb.MarketKline{}).Where("symbol = ?", "SOLUSDT").Count(&n)
+	if n != 60 {
+		t.Errorf("重复缓存后期望仍为 60 条，实际 %d", n)
+	}
+}
+
+// TestLiveFallbackDisabled 未开启实时补数据时不访问交易所，返回提示同步数据的错误
+func TestLiveFallbackDisabled(t *testing.T) {
+	be, fetcher, _ := newDataSourceTestEngine(t, false)
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := fetchBacktestKlines(be, "SOL", start, start.AddDate(0, 0, 59))
+	if err == nil || !strings.Contains(err.Error(), "live_fallback") {
+		t.Fatalf("期望数据不足错误并提示 live_fallback，实际 %v", err)
+	}
+	if fetcher.calls != 0 {
+		t.Errorf("关闭实时补数据时不应访问交易所，实际调用 %d 次", fetcher.calls)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	pdb "analysis/internal/db"
+	"analysis/internal/indicators"
 )
 
 // SymbolState 单个币种的状态
@@ -24,6 +25,12 @@ type SymbolState struct {
 	LastBuyPrice   float64      // 最后买入价格
 	Data           []MarketData // 历史数据
 	Reason         string       // 最后交易的原因
+
+	// 合约回测
+	Margin      float64            // 持仓占用的保证金（已扣除资金费）
+	FundingPaid float64            // 累计支付的资金费
+	Funding     []FundingRatePoint // 回测区间内的资金费结算点，按时间升序
+	fundingNext int                // 下一个未处理的结算点
 }
 
 // TradeOpportunity 交易机会
@@ -297,6 +304,15 @@ type BacktestEngine struct {
 	// ===== AI止损系统：实时性能统计 =====
 	symbolPerformanceStats map[string]*SymbolPerformance // 实时符号性能统计
 	performanceMutex       sync.RWMutex                  // 性能统计互斥锁
+
+	// 历史数据来源
+	dataSource       string         // BacktestDataSourceDB / BacktestDataSourceLive
+	liveDataFallback bool           // db 模式下库内缺数据时实时拉取并缓存
+	klineFetcher     klineFetchFunc // 交易所K线拉取，nil 时使用 server.fetchBinanceKlinesWithTimeRange
+
+	// 回测时间范围限制（天），0 表示使用默认值
+	maxRangeDays     int // 单段上限，超过后分段加载历史数据
+	hardMaxRangeDays int // 硬上限，超过直接拒绝
 }
 
 // DynamicThresholdManager 动态阈值管理器
@@ -521,12 +537,24 @@ func (be *BacktestEngine) runStrategySimulation(ctx context.Context, config Back
 	// 获取所有币种的历史数据
 	symbolData := make(map[string][]MarketData)
 	for _, symbol := range symbols {
-		data, err := be.getHistoricalData(ctx, symbol, config.StartDate, config.EndDate)
+		data, err := be.loadHistoricalData(ctx, symbol, config.StartDate, config.EndDate)
 		if err != nil {
 			log.Printf("[StrategySimulation] 获取%s历史数据失败: %v，跳过", symbol, err)
 			continue
 		}
 
+		score, ok := be.checkDataQuality(symbol, data, config)
+		result.DataQuality = append(result.DataQuality, score)
+		if !ok {
+			continue
+		}
+
+		data, actions, dropped := be.preprocessSymbolData(symbol, data, config)
+		result.DataCleaning = append(result.DataCleaning, actions...)
+		if dropped {
+			continue
+		}
+
 		if len(data) < 30 {
 			log.Printf("[StrategySimulation] %s历史数据不足(%d < 30)，跳过", symbol, len(data))
 			continue
@@ -544,6 +572,7 @@ func (be *BacktestEngine) runStrategySimulation(ctx context.Context, config Back
 	simulationState := &StrategySimulationState{
 		Cash:        config.InitialCash,
 		Positions:   make(map[string]float64),
+		LastPrices:  make(map[string]float64),
 		SymbolStats: make(map[string]*SymbolPerformance),
 		StartDate:   config.StartDate,
 		EndDate:     config.EndDate,
@@ -557,6 +586,7 @@ func (be *BacktestEngine) runStrategySimulation(ctx context.Context, config Back
 
 	// 计算最终统计
 	be.calculateSimulationSummary(result, simulationState)
+	markTradeStatus(result, evaluablePeriods(symbolData, 0))
 
 	log.Printf("[StrategySimulation] 策略模拟完成，总交易: %d, 总收益率: %.2f%%",
 		len(result.Trades), result.Summary.TotalReturn*100)
@@ -568,6 +598,7 @@ func (be *BacktestEngine) runStrategySimulation(ctx context.Context, config Back
 type StrategySimulationState struct {
 	Cash        float64                       // 可用现金
 	Positions   map[string]float64            // 持仓数量 (symbol -> quantity)
+	LastPrices  map[string]float64            // 最新价格 (symbol -> price)，用于计算总敞口
 	SymbolStats map[string]*SymbolPerformance // 币种统计
 	StartDate   time.Time                     // 开始日期
 	EndDate     time.Time                     // 结束日期
@@ -589,6 +620,8 @@ func (be *BacktestEngine) simulateStrategyExecution(ctx context.Context, config
 			log.Printf("[StrategySimulation] 处理进度: %d/%d", i, len(allDataPoints))
 		}
 
+		state.LastPrices[dataPoint.Symbol] = dataPoint.Price
+
 		// 检查是否应该执行交易
 		decision := be.evaluateStrategyDecision(strategy, dataPoint, symbolData)
 
@@ -603,6 +636,20 @@ func (be *BacktestEngine) simulateStrategyExecution(ctx context.Context, config
 	return nil
 }
 
+// preprocessSymbolData 按配置清洗币种数据，返回清洗后的数据、清洗记录和是否剔除该币种
+func (be *BacktestEngine) preprocessSymbolData(symbol string, data []MarketData, config BacktestConfig) ([]MarketData, []DataCleaningAction, bool) {
+	if config.Preprocessing == nil {
+		return data, nil, false
+	}
+	cleaned, actions, dropped := NewDataPreprocessor().Preprocess(symbol, data, *config.Preprocessing)
+	if dropped {
+		log.Printf("[DataPreprocess] %s缺失数据过多，跳过此币种", symbol)
+	} else if len(actions) > 0 {
+		log.Printf("[DataPreprocess] %s执行%d项数据清洗", symbol, len(actions))
+	}
+	return cleaned, actions, dropped
+}
+
 // collectAllDataPoints 收集所有数据点
 func (be *BacktestEngine) collectAllDataPoints(symbolData map[string][]MarketData) []MarketData {
 	var allPoints []MarketData
@@ -737,8 +784,12 @@ func (be *BacktestEngine) executeStrategyTrade(decision StrategyDecisionResult,
 	quantity := (state.Cash * config.MaxPosition * decision.Multiplier) / price
 
 	if decision.Action == "sell" && quantity > 0 {
+		if err := checkPortfolioLimits(config, state.Positions, state.LastPrices, symbol, quantity*price); err != nil {
+			return err
+		}
+
 		// 执行做空（简化实现）
-		commission := quantity * price * config.Commission
+		commission := quantity * price * config.CommissionRate(symbol)
 		state.Cash -= commission
 
 		// 记录交易
@@ -754,6 +805,11 @@ func (be *BacktestEngine) executeStrategyTrade(decision StrategyDecisionResult,
 		}
 
 		result.Trades = append(result.Trades, trade)
+		if state.LastPrices == nil {
+			state.LastPrices = make(map[string]float64)
+		}
+		adjustStrategyPosition(state, symbol, -quantity)
+		state.LastPrices[symbol] = price
 
 		// 更新统计
 		if state.SymbolStats[symbol] == nil {
@@ -765,6 +821,82 @@ func (be *BacktestEngine) executeStrategyTrade(decision StrategyDecisionResult,
 			symbol, quantity, price)
 	}
 
+	// 买入平掉已有空头：数量不超过空头仓位，完全平仓后释放持仓名额
+	if decision.Action == "buy" && state.Positions[symbol] < 0 && quantity > 0 {
+		quantity = math.Min(quantity, -state.Positions[symbol])
+		commission := quantity * price * config.CommissionRate(symbol)
+		state.Cash -= commission
+
+		result.Trades = append(result.Trades, TradeRecord{
+			Symbol:     symbol,
+			Side:       "buy",
+			Quantity:   quantity,
+			Price:      price,
+			Timestamp:  dataPoint.LastUpdated,
+			Commission: commission,
+			PnL:        be.calculateTradePnL(result, symbol, "buy", price, quantity),
+			Reason:     decision.Reason,
+		})
+		adjustStrategyPosition(state, symbol, quantity)
+		if state.LastPrices == nil {
+			state.LastPrices = make(map[string]float64)
+		}
+		state.LastPrices[symbol] = price
+
+		if state.SymbolStats[symbol] == nil {
+			state.SymbolStats[symbol] = &SymbolPerformance{Symbol: symbol}
+		}
+		state.SymbolStats[symbol].TotalTrades++
+
+		log.Printf("[StrategyTrade] 买入平空: %s, 数量: %.4f, 价格: %.4f, 剩余持仓: %.4f",
+			symbol, quantity, price, state.Positions[symbol])
+	}
+
+	return nil
+}
+
+// positionDust 持仓数量的绝对值低于该值视为已平仓（浮点误差）
+const positionDust = 1e-9
+
+// adjustStrategyPosition 调整持仓数量；归零的持仓从 Positions 中删除，不再占用 MaxConcurrentPositions 名额
+func adjustStrategyPosition(state *StrategySimulationState, symbol string, delta float64) {
+	if state.Positions == nil {
+		state.Positions = make(map[string]float64)
+	}
+	qty := state.Positions[symbol] + delta
+	if math.Abs(qty) < positionDust {
+		delete(state.Positions, symbol)
+		return
+	}
+	state.Positions[symbol] = qty
+}
+
+// checkPortfolioLimits 检查组合级限制：新开仓时持仓币种数不得超过 MaxConcurrentPositions，
+// 且加上本次名义价值后的总敞口不得超过 MaxGrossExposure * InitialCash
+// positions / prices 为当前持仓数量与最新价（symbol -> 值）
+func checkPortfolioLimits(config BacktestConfig, positions, prices map[string]float64, symbol string, notional float64) error {
+	if config.MaxConcurrentPositions > 0 && math.Abs(positions[symbol]) < positionDust {
+		open := 0
+		for _, qty := range positions {
+			if math.Abs(qty) >= positionDust {
+				open++
+			}
+		}
+		if open >= config.MaxConcurrentPositions {
+			return fmt.Errorf("%s 开仓被拒绝: 持仓数 %d 已达上限 %d", symbol, open, config.MaxConcurrentPositions)
+		}
+	}
+
+	if config.MaxGrossExposure > 0 {
+		exposure := 0.0
+		for sym, qty := range positions {
+			exposure += math.Abs(qty) * prices[sym]
+		}
+		limit := config.MaxGrossExposure * config.InitialCash
+		if exposure+notional > limit {
+			return fmt.Errorf("%s 开仓被拒绝: 总敞口 %.2f + %.2f 超过上限 %.2f", symbol, exposure, notional, limit)
+		}
+	}
 	return nil
 }
 
@@ -820,7 +952,7 @@ func (be *BacktestEngine) calculateSimulationSummary(result *BacktestResult, sta
 	}
 
 	// 计算夏普比率
-	sharpeRatio := be.calculateSharpeRatioFromPnLs(pnls)
+	sharpeRatio := be.calculateSharpeRatioFromPnLs(pnls, result.Config.InitialCash, result.Config.PeriodRiskFreeRate(0))
 
 	// 如果没有交易记录，使用默认值
 	if totalTrades == 0 {
@@ -860,7 +992,7 @@ func (be *BacktestEngine) calculateTradePnL(result *BacktestResult, symbol, side
 			// 对于做多：(卖出价格 - 买入价格) * 数量
 			pnl := (price - trade.Price) * quantity
 			// 扣除手续费
-			totalCommission := trade.Commission + (price * quantity * result.Config.Commission)
+			totalCommission := trade.Commission + (price * quantity * result.Config.CommissionRate(symbol))
 			pnl -= totalCommission
 
 			// 更新买入交易的PnL（可选，也可以只在卖出时记录）
@@ -882,33 +1014,43 @@ func (be *BacktestEngine) calculateTradePnL(result *BacktestResult, symbol, side
 }
 
 // calculateSharpeRatioFromPnLs 从PnL数据计算夏普比率
-func (be *BacktestEngine) calculateSharpeRatioFromPnLs(pnls []float64) float64 {
+// capital>0 时先把 PnL 换算成相对初始资金的收益率，再扣除每周期无风险利率 periodRiskFree；
+// 无风险利率为 0 时结果与直接用 PnL 计算相同
+func (be *BacktestEngine) calculateSharpeRatioFromPnLs(pnls []float64, capital, periodRiskFree float64) float64 {
 	if len(pnls) < 2 {
 		return 0.0
 	}
 
+	returns := pnls
+	if capital > 0 {
+		returns = make([]float64, len(pnls))
+		for i, pnl := range pnls {
+			returns[i] = pnl / capital
+		}
+	}
+
 	// 计算平均收益率和标准差
 	sum := 0.0
-	for _, pnl := range pnls {
-		sum += pnl
+	for _, r := range returns {
+		sum += r
 	}
-	mean := sum / float64(len(pnls))
+	mean := sum / float64(len(returns))
 
 	// 计算方差
 	variance := 0.0
-	for _, pnl := range pnls {
-		variance += (pnl - mean) * (pnl - mean)
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
 	}
-	variance /= float64(len(pnls) - 1)
+	variance /= float64(len(returns) - 1)
 
 	// 计算标准差
 	std := math.Sqrt(variance)
 
-	// 计算夏普比率（假设无风险利率为0）
+	// 计算夏普比率（超额收益 / 波动）
 	if std > 0 {
 		// 年化处理（假设交易频率）
-		annualizedReturn := mean * 252 // 假设252个交易日
-		annualizedStd := std * math.Sqrt(252)
+		annualizedReturn := (mean - periodRiskFree) * tradingPeriodsPerYear
+		annualizedStd := std * math.Sqrt(tradingPeriodsPerYear)
 		return annualizedReturn / annualizedStd
 	}
 
@@ -939,6 +1081,11 @@ func NewAdaptiveFrequencyManager() *AdaptiveFrequencyManager {
 func (be *BacktestEngine) RunBacktest(ctx context.Context, config BacktestConfig) (*BacktestResult, error) {
 	var symbols []string
 
+	// 范围超过硬上限直接拒绝，避免加载过多数据耗尽内存
+	if _, err := be.checkBacktestRange(config.StartDate, config.EndDate); err != nil {
+		return nil, err
+	}
+
 	// 检查是否为用户策略回测
 	if config.UserStrategyID > 0 {
 		// 用户策略回测：使用策略逻辑选择币种
@@ -979,13 +1126,27 @@ func (be *BacktestEngine) RunBacktest(ctx context.Context, config BacktestConfig
 
 	// 获取所有币种的历史数据
 	symbolData := make(map[string][]MarketData)
+	var cleaning []DataCleaningAction
+	var quality []DataQualityScore
 	for _, symbol := range symbols {
-		data, err := be.getHistoricalData(ctx, symbol, config.StartDate, config.EndDate)
+		data, err := be.loadHistoricalData(ctx, symbol, config.StartDate, config.EndDate)
 		if err != nil {
 			log.Printf("[RunBacktest] 获取%s历史数据失败: %v，跳过此币种", symbol, err)
 			continue
 		}
 
+		score, ok := be.checkDataQuality(symbol, data, config)
+		quality = append(quality, score)
+		if !ok {
+			continue
+		}
+
+		data, actions, dropped := be.preprocessSymbolData(symbol, data, config)
+		cleaning = append(cleaning, actions...)
+		if dropped {
+			continue
+		}
+
 		if len(data) < 50 {
 			log.Printf("[RunBacktest] %s历史数据不足(%d < 50)，跳过此币种", symbol, len(data))
 			continue
@@ -1009,6 +1170,8 @@ func (be *BacktestEngine) RunBacktest(ctx context.Context, config BacktestConfig
 		Performance:     PerformanceMetrics{},
 		PortfolioValues: []float64{},
 		SymbolStats:     make(map[string]*SymbolPerformance),
+		DataCleaning:    cleaning,
+		DataQuality:     quality,
 	}
 
 	// 根据策略类型执行相应的回测逻辑
@@ -1032,6 +1195,7 @@ func (be *BacktestEngine) RunBacktest(ctx context.Context, config BacktestConfig
 
 	// 计算绩效指标
 	be.calculatePerformanceMetrics(result)
+	markTradeStatus(result, evaluablePeriods(symbolData, backtestWarmupPeriods))
 
 	// 计算数据统计
 	totalDataPoints := 0
@@ -1069,6 +1233,10 @@ func (be *BacktestEngine) runMultiSymbolDeepLearningStrategy(ctx context.Context
 	totalCash := config.InitialCash
 	availableCash := totalCash
 
+	if config.IsFutures() {
+		be.loadFundingRates(symbolStates, config.StartDate, config.EndDate)
+	}
+
 	// 找到所有币种数据的最小长度，作为回测周期
 	minDataLength := int(^uint(0) >> 1) // max int
 	for _, data := range symbolData {
@@ -1138,6 +1306,9 @@ func (be *BacktestEngine) runMultiSymbolDeepLearningStrategy(ctx context.Context
 
 		currentDate := symbolData[mainSymbol][i].LastUpdated
 
+		// 合约回测：先结算资金费并检查强平
+		be.applyFuturesCarry(symbolStates, result, i, currentDate, config)
+
 		// 0. 动态币种选择：评估和轮换币种（如果启用）
 		if be.dynamicSelector != nil {
 			// 移除频繁的周期检查日志
@@ -1180,14 +1351,18 @@ func (be *BacktestEngine) runMultiSymbolDeepLearningStrategy(ctx context.Context
 			}
 		}
 
-		// 3. 检查是否需要平仓
+		// 3. 检查是否需要平仓：先按最大持有周期强制平仓，再走止盈止损
+		be.enforceMaxHoldingPeriod(symbolStates, &availableCash, result, i, currentDate, config)
 		be.checkMultiSymbolExits(symbolStates, &availableCash, &totalCash, result, currentDate, config)
 
+		// 3.1 按周期再平衡到目标权重（如果启用）
+		be.maybeRebalance(symbolStates, &availableCash, result, i, 50, currentDate, config)
+
 		// 4. 更新每日收益
 		portfolioValue := availableCash
 		for _, state := range symbolStates {
 			if state.Position > 0 && i < len(state.Data) {
-				portfolioValue += state.Position * state.Data[i].Price
+				portfolioValue += config.positionValue(state, state.Data[i].Price)
 			}
 		}
 
@@ -1576,12 +1751,19 @@ func (be *BacktestEngine) executeMultiSymbolTrade(opportunity *TradeOpportunity,
 		return nil // 跳过交易，不报错
 	}
 
+	// 组合级限制：持仓币种数与总敞口
+	positions, prices := multiSymbolPositions(symbolStates)
+	if err := checkPortfolioLimits(*config, positions, prices, opportunity.Symbol, positionSize*opportunity.Price); err != nil {
+		log.Printf("[PORTFOLIO_LIMITS] %v", err)
+		return nil // 不执行交易，但不报错
+	}
+
 	// 执行买入
-	commission := positionSize * opportunity.Price * config.Commission
+	commission := positionSize * opportunity.Price * config.CommissionRate(opportunity.Symbol)
 
 	opportunity.State.Position = positionSize
 	opportunity.State.LastBuyPrice = opportunity.Price // 记录买入价格
-	*availableCash -= (positionSize*opportunity.Price + commission)
+	*availableCash -= (config.reserveMargin(opportunity.State, positionSize*opportunity.Price) + commission)
 	opportunity.State.LastTradeIndex = len(opportunity.State.Data) - 1 // 简化处理
 	opportunity.State.HoldTime = 0
 
@@ -1604,6 +1786,23 @@ func (be *BacktestEngine) executeMultiSymbolTrade(opportunity *TradeOpportunity,
 	return nil
 }
 
+// multiSymbolPositions 多币种回测的持仓数量与最新价（无行情时取最后买入价），空仓币种不计入
+func multiSymbolPositions(symbolStates map[string]*SymbolState) (positions, prices map[string]float64) {
+	positions = make(map[string]float64)
+	prices = make(map[string]float64)
+	for symbol, st := range symbolStates {
+		if st == nil || math.Abs(st.Position) < positionDust {
+			continue
+		}
+		positions[symbol] = st.Position
+		prices[symbol] = st.LastBuyPrice
+		if n := len(st.Data); n > 0 {
+			prices[symbol] = st.Data[n-1].Price
+		}
+	}
+	return positions, prices
+}
+
 // ===== 阶段三优化：智能仓位大小计算 =====
 func (be *BacktestEngine) calculateOptimizedPositionSize(opportunity *TradeOpportunity, symbolStates map[string]*SymbolState, availableCash float64, config *BacktestConfig) float64 {
 	// 1. 计算基础仓位大小
@@ -2528,8 +2727,8 @@ func (be *BacktestEngine) checkMultiSymbolExits(symbolStates map[string]*SymbolS
 
 		if shouldExit {
 			// 执行平仓
-			commission := state.Position * currentPrice * config.Commission
-			*availableCash += (state.Position*currentPrice - commission)
+			commission := state.Position * currentPrice * config.CommissionRate(state.Symbol)
+			*availableCash += (config.releaseMargin(state, state.Position, currentPrice) - commission)
 
 			// 更新交易记录
 			for i := len(result.Trades) - 1; i >= 0; i-- {
@@ -5595,28 +5794,7 @@ func (be *BacktestEngine) calculateTrend(prices []float64) float64 {
 
 // calculateRSIForPrices 计算价格序列的RSI
 func (be *BacktestEngine) calculateRSIForPrices(prices []float64, period int) float64 {
-	if len(prices) < period+1 {
-		return 50.0
-	}
-
-	gains := 0.0
-	losses := 0.0
-
-	for i := 1; i <= period; i++ {
-		change := prices[i] - prices[i-1]
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
-		}
-	}
-
-	if losses == 0 {
-		return 100.0
-	}
-
-	rs := gains / losses
-	return 100.0 - (100.0 / (1.0 + rs))
+	return indicators.RSI(prices, period)
 }
 
 // CorrelationClusters 相关性聚类
@@ -7328,7 +7506,7 @@ func (be *BacktestEngine) selectCoinsForBacktest(ctx context.Context, config Bac
 
 	for _, symbol := range candidateSymbols {
 		// 获取该币种的历史数据
-		data, err := be.getHistoricalData(ctx, symbol, config.StartDate, config.EndDate)
+		data, err := be.loadHistoricalData(ctx, symbol, config.StartDate, config.EndDate)
 		if err != nil {
 			log.Printf("[CoinSelection] 获取%s历史数据失败: %v", symbol, err)
 			continue
@@ -7730,7 +7908,7 @@ func (be *BacktestEngine) initializeDynamicCoinSelector(ctx context.Context, con
 func (selector *DynamicCoinSelector) initializeActiveSymbols(be *BacktestEngine) {
 	for _, symbol := range selector.candidateSymbols {
 		// 获取历史数据验证币种可用性
-		data, err := be.getHistoricalData(selector.ctx, symbol, selector.config.StartDate, selector.config.EndDate)
+		data, err := be.loadHistoricalData(selector.ctx, symbol, selector.config.StartDate, selector.config.EndDate)
 		if err != nil {
 			log.Printf("[DynamicSelector] %s数据获取失败: %v", symbol, err)
 			continue
@@ -10467,28 +10645,7 @@ func (be *BacktestEngine) calculateBearMarketDurationFromRegime() int {
 
 // calculateRSISimple 简化的RSI计算
 func (be *BacktestEngine) calculateRSISimple(prices []float64, period int) float64 {
-	if len(prices) < period+1 {
-		return 50.0
-	}
-
-	gains := 0.0
-	losses := 0.0
-
-	for i := 1; i <= period; i++ {
-		change := prices[i] - prices[i-1]
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
-		}
-	}
-
-	if losses == 0 {
-		return 100.0
-	}
-
-	rs := gains / losses
-	return 100.0 - (100.0 / (1.0 + rs))
+	return indicators.RSI(prices, period)
 }
 
 // calculateMACDSimple 简化的MACD计算
@@ -10509,54 +10666,25 @@ func (be *BacktestEngine) calculateMACDSimple(data []MarketData) float64 {
 	return ema12 - ema26
 }
 
-// calculateBollingerPositionSimple 简化的布林带位置计算
+// calculateBollingerPositionSimple 简化的布林带位置计算：20周期，(价格-中轨)/(2σ)
 func (be *BacktestEngine) calculateBollingerPositionSimple(data []MarketData) float64 {
 	if len(data) < 20 {
 		return 0.0
 	}
 
-	prices := make([]float64, len(data))
-	for i, d := range data {
-		prices[i] = d.Price
-	}
-
-	// 计算SMA
-	sma := 0.0
-	for i := len(prices) - 20; i < len(prices); i++ {
-		sma += prices[i]
-	}
-	sma /= 20.0
-
-	// 计算标准差
-	variance := 0.0
-	for i := len(prices) - 20; i < len(prices); i++ {
-		variance += (prices[i] - sma) * (prices[i] - sma)
-	}
-	variance /= 19.0
-	std := math.Sqrt(variance)
-
-	currentPrice := prices[len(prices)-1]
-	if std == 0 {
+	bands := indicators.Bollinger(marketDataPrices(data), 20, 2)
+	if bands.Upper == bands.Middle {
 		return 0.0
 	}
-
-	return (currentPrice - sma) / (2 * std) // 标准化到[-1,1]区间
+	return (data[len(data)-1].Price - bands.Middle) / (bands.Upper - bands.Middle) // 标准化到[-1,1]区间
 }
 
-// calculateEMASimple 简化的EMA计算
+// calculateEMASimple 简化的EMA计算，数据不足时返回最新价格
 func (be *BacktestEngine) calculateEMASimple(prices []float64, period int) float64 {
 	if len(prices) < period {
 		return prices[len(prices)-1]
 	}
-
-	multiplier := 2.0 / (float64(period) + 1.0)
-	ema := prices[0]
-
-	for i := 1; i < len(prices); i++ {
-		ema = (prices[i] * multiplier) + (ema * (1 - multiplier))
-	}
-
-	return ema
+	return indicators.EMA(prices, period)
 }
 
 // calculateHistoricalVolatilitySimple 简化的历史波动率计算
@@ -12006,47 +12134,18 @@ func (be *BacktestEngine) calculateEWMAVolatility(data []MarketData, currentInde
 }
 
 // calculateATR 计算平均真实波幅 (Average True Range) - 适配当前数据结构
+// 没有High/Low，以收盘价计算 indicators.ATR，再除以当前价格得到百分比
 func (be *BacktestEngine) calculateATR(data []MarketData, currentIndex int, period int) float64 {
 	if currentIndex < period || len(data) <= currentIndex {
 		return 0.02 // 默认ATR值
 	}
 
-	priceChanges := make([]float64, 0, period)
-
-	// 计算价格变化幅度（由于没有High/Low，使用价格变化的绝对值）
-	for i := currentIndex - period + 1; i <= currentIndex; i++ {
-		if i < 0 || i >= len(data) {
-			continue
-		}
-
-		currentPrice := data[i].Price
-		var previousPrice float64
-		if i > 0 {
-			previousPrice = data[i-1].Price
-		} else {
-			previousPrice = currentPrice
-		}
-
-		// 使用价格变化的绝对值作为波动性度量
-		priceChange := math.Abs(currentPrice - previousPrice)
-		if previousPrice > 0 {
-			// 标准化为百分比变化
-			priceChange = priceChange / previousPrice
-		}
-		priceChanges = append(priceChanges, priceChange)
-	}
-
-	if len(priceChanges) == 0 {
+	closes := marketDataPrices(data[currentIndex-period : currentIndex+1])
+	price := closes[len(closes)-1]
+	if price <= 0 {
 		return 0.02
 	}
-
-	// 计算ATR (简单移动平均)
-	sum := 0.0
-	for _, change := range priceChanges {
-		sum += change
-	}
-
-	atr := sum / float64(len(priceChanges))
+	atr := indicators.ATR(closes, closes, closes, period) / price
 
 	// 限制在合理范围内 (0.5% - 50%)
 	return math.Max(0.005, math.Min(atr, 0.5))
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	pdb "analysis/internal/db"
+)
+
+// backtestFingerprintVersion 指纹算法版本，归一化规则变化时递增，使旧指纹自然失效
+const backtestFingerprintVersion = 1
+
+// BacktestFingerprint 回测配置指纹：策略、参数、币种、日期区间、周期等全部配置的 sha256（十六进制）。
+// 币种统一大写、多币种按字典序排列；日期按 UTC 取到天，与记录里的 start_date/end_date 粒度一致。
+// strategyParams 为配置之外影响结果的参数（如用户策略的条件），可为 nil。
+// 配置无法序列化时返回空串，表示不参与复用。
+func BacktestFingerprint(cfg BacktestConfig, strategyParams interface{}) string {
+	norm := cfg
+	norm.Symbol = strings.ToUpper(strings.TrimSpace(cfg.Symbol))
+	norm.Symbols = make([]string, 0, len(cfg.Symbols))
+	for _, sym := range cfg.Symbols {
+		norm.Symbols = append(norm.Symbols, strings.ToUpper(strings.TrimSpace(sym)))
+	}
+	sort.Strings(norm.Symbols)
+	norm.StartDate = truncateToUTCDay(cfg.StartDate)
+	norm.EndDate = truncateToUTCDay(cfg.EndDate)
+
+	payload := struct {
+		Version int            `json:"version"`
+		Config  BacktestConfig `json:"config"`
+		Params  interface{}    `json:"params,omitempty"`
+	}{backtestFingerprintVersion, norm, strategyParams}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func truncateToUTCDay(t time.Time) time.Time {
+	u := t.UTC()
+	return time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// cachedBacktestResult 查找用户相同指纹的已完成回测；未命中或结果无法解析时返回 nil
+func (s *Server) cachedBacktestResult(userID uint, fingerprint string) (*pdb.AsyncBacktestRecord, *BacktestResult) {
+	record, err := pdb.FindCompletedAsyncBacktestByFingerprint(s.db.DB(), userID, fingerprint)
+	if err != nil {
+		log.Printf("[WARN] 查询回测指纹缓存失败 fingerprint=%s: %v", fingerprint, err)
+		return nil, nil
+	}
+	if record == nil || record.Result == nil {
+		return nil, nil
+	}
+	var result BacktestResult
+	if err := json.Unmarshal([]byte(*record.Result), &result); err != nil {
+		log.Printf("[WARN] 回测缓存结果解析失败 ID=%d: %v", record.ID, err)
+		return nil, nil
+	}
+	return record, &result
+}
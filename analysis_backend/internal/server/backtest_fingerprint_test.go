@@ -0,0 +1,138 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestBacktestFingerprintDeterministic 相同配置指纹相同；币种大小写/顺序、日期的时分秒不影响指纹
+func TestBacktestFingerprintDeterministic(t *testing.T) {
+	base := BacktestConfig{
+		Symbols:     []string{"ETHUSDT", "BTCUSDT"},
+		StartDate:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC),
+		Strategy:    "buy_and_hold",
+		InitialCash: 10000,
+		MaxPosition: 0.5,
+		Timeframe:   "1d",
+		Commission:  0.001,
+	}
+	fp := BacktestFingerprint(base, nil)
+	if len(fp) != 64 {
+		t.Fatalf("指纹应为 sha256 十六进制，实际 %q", fp)
+	}
+	if again := BacktestFingerprint(base, nil); again != fp {
+		t.Fatalf("相同配置指纹不一致: %s vs %s", fp, again)
+	}
+
+	same := base
+	same.Symbols = []string{"btcusdt", " ethusdt"}
+	same.StartDate = time.Date(2025, 1, 1, 8, 30, 0, 0, time.FixedZone("CST", 8*3600))
+	same.EndDate = base.EndDate.Add(15 * time.Hour)
+	if got := BacktestFingerprint(same, nil); got != fp {
+		t.Errorf("仅大小写/顺序/时分秒不同的配置应得到相同指纹")
+	}
+
+	variants := map[string]func(c *BacktestConfig){
+		"strategy":  func(c *BacktestConfig) { c.Strategy = "ml_prediction" },
+		"timeframe": func(c *BacktestConfig) { c.Timeframe = "4h" },
+		"symbol":    func(c *BacktestConfig) { c.Symbols = []string{"BTCUSDT"} },
+		"range":     func(c *BacktestConfig) { c.EndDate = c.EndDate.AddDate(0, 0, 1) },
+		"param":     func(c *BacktestConfig) { c.StopLoss = 0.05 },
+	}
+	for name, mutate := range variants {
+		c := base
+		mutate(&c)
+		if BacktestFingerprint(c, nil) == fp {
+			t.Errorf("%s 变化后指纹应不同", name)
+		}
+	}
+	if BacktestFingerprint(base, map[string]float64{"rsi": 30}) == fp {
+		t.Error("策略参数变化后指纹应不同")
+	}
+}
+
+func newBacktestCacheTestServer(t *testing.T) (*Server, *gorm.DB) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TradingStrategy{}, &pdb.AsyncBacktestRecord{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	return &Server{db: NewGormDatabase(gdb)}, gdb
+}
+
+func postStrategyBacktest(t *testing.T, s *Server, uid uint, body map[string]any) (int, map[string]any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/backtest/strategy", func(c *gin.Context) { c.Set("uid", uid) }, s.RunStrategyBacktestAPI)
+	raw, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/backtest/strategy", bytes.NewReader(raw)))
+	var out map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &out)
+	return w.Code, out
+}
+
+// TestRunStrategyBacktestReusesCachedResult 相同配置直接返回已完成结果；force 时绕过缓存重新回测
+func TestRunStrategyBacktestReusesCachedResult(t *testing.T) {
+	s, gdb := newBacktestCacheTestServer(t)
+	uid := uint(7)
+	strategy := pdb.TradingStrategy{UserID: uid, Name: "ma-cross"}
+	strategy.Conditions.StopLossPercent = 5
+	if err := gdb.Create(&strategy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	cfg := s.convertStrategyToBacktestConfig(strategy, strategy.ID, "BTCUSDT", start, end)
+	resultJSON, _ := json.Marshal(BacktestResult{Config: cfg, Summary: BacktestSummary{TotalReturn: 0.12, TotalTrades: 3}})
+	resultStr := string(resultJSON)
+	cached := pdb.AsyncBacktestRecord{
+		UserID: uid, Symbol: "BTCUSDT", Strategy: "strategy", StartDate: "2025-01-01", EndDate: "2025-06-30",
+		Fingerprint: BacktestFingerprint(cfg, strategy.Conditions), Status: "completed", Result: &resultStr,
+	}
+	if err := gdb.Create(&cached).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	req := map[string]any{"strategy_id": strategy.ID, "symbol": "BTCUSDT", "start_date": start, "end_date": end}
+	code, out := postStrategyBacktest(t, s, uid, req)
+	if code != http.StatusOK || out["cached"] != true || out["record_id"] != float64(cached.ID) {
+		t.Fatalf("期望复用缓存记录 %d，实际 status=%d body=%v", cached.ID, code, out)
+	}
+	if data, _ := out["data"].(map[string]any); data == nil || data["summary"].(map[string]any)["total_return"] != 0.12 {
+		t.Errorf("缓存结果不符: %v", out["data"])
+	}
+
+	// 其他用户不复用
+	if code, _ := postStrategyBacktest(t, s, uid+1, req); code == http.StatusOK {
+		t.Error("其他用户不应命中缓存")
+	}
+
+	// force：绕过缓存走回测引擎（测试中未初始化，返回 500），且不新建记录
+	req["force"] = true
+	if code, out := postStrategyBacktest(t, s, uid, req); code != http.StatusInternalServerError || out["cached"] != nil {
+		t.Fatalf("force 应绕过缓存，实际 status=%d body=%v", code, out)
+	}
+	var n int64
+	gdb.Model(&pdb.AsyncBacktestRecord{}).Count(&n)
+	if n != 1 {
+		t.Errorf("期望仍只有 1 条回测记录，实际 %d", n)
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	pdb "analysis/internal/db"
+)
+
+// ExitReasonLiquidation 合约保证金耗尽的强制平仓原因
+const ExitReasonLiquidation = "liquidation"
+
+// maxFuturesLeverage 杠杆倍数上限（与币安 U 本位合约一致）
+const maxFuturesLeverage = 125
+
+// FundingRatePoint 一次资金费结算
+type FundingRatePoint struct {
+	Time      time.Time
+	Rate      float64 // 正数为多头支付
+	MarkPrice float64 // 结算时的标记价格，为 0 时按当周期价格
+}
+
+// IsFutures 是否为合约回测
+func (c BacktestConfig) IsFutures() bool {
+	return strings.EqualFold(c.Market, "futures")
+}
+
+// FuturesLeverage 合约回测的杠杆倍数；现货或未配置时为 1
+func (c BacktestConfig) FuturesLeverage() float64 {
+	if !c.IsFutures() || c.Leverage <= 1 {
+		return 1
+	}
+	return c.Leverage
+}
+
+// reserveMargin 开仓名义价值 notional 需要占用的现金（不含手续费）：现货为全额，合约为 notional/杠杆并计入保证金
+func (c BacktestConfig) reserveMargin(state *SymbolState, notional float64) float64 {
+	if !c.IsFutures() {
+		return notional
+	}
+	margin := notional / c.FuturesLeverage()
+	state.Margin += margin
+	return margin
+}
+
+// releaseMargin 按 price 平掉 quantity 数量的持仓后回到现金的金额（不含手续费，Position 由调用方扣减）：
+// 现货为 quantity*price；合约为对应比例的保证金加已实现盈亏，不低于 0
+func (c BacktestConfig) releaseMargin(state *SymbolState, quantity, price float64) float64 {
+	if !c.IsFutures() || state.Position <= 0 {
+		return quantity * price
+	}
+	margin := state.Margin * math.Min(quantity/state.Position, 1)
+	state.Margin -= margin
+	return math.Max(0, margin+quantity*(price-state.LastBuyPrice))
+}
+
+// positionValue 持仓按 price 计的权益：现货为市值，合约为保证金加浮动盈亏（不低于 0）
+func (c BacktestConfig) positionValue(state *SymbolState, price float64) float64 {
+	if !c.IsFutures() {
+		return state.Position * price
+	}
+	return math.Max(0, state.Margin+state.Position*(price-state.LastBuyPrice))
+}
+
+// loadFundingRates 合约回测从同步的资金费率表加载各币种回测区间内的结算点；没有数据库或数据时不计资金费
+func (be *BacktestEngine) loadFundingRates(symbolStates map[string]*SymbolState, start, end time.Time) {
+	if be.db == nil || be.db.DB() == nil {
+		return
+	}
+	for sym, state := range symbolStates {
+		rates, err := pdb.GetFundingRates(be.db.DB(), futuresSymbol(sym), start, end)
+		if err != nil {
+			log.Printf("[FUTURES] %s 加载资金费率失败，不计资金费: %v", sym, err)
+			continue
+		}
+		state.Funding = state.Funding[:0]
+		for _, r := range rates {
+			state.Funding = append(state.Funding, FundingRatePoint{
+				Time:      time.UnixMilli(r.FundingTime).UTC(),
+				Rate:      r.FundingRate,
+				MarkPrice: r.MarkPrice,
+			})
+		}
+		state.fundingNext = 0
+		log.Printf("[FUTURES] %s 加载资金费率 %d 条", sym, len(state.Funding))
+	}
+}
+
+// futuresSymbol 资金费率表使用 U 本位合约符号（如 BTCUSDT）
+func futuresSymbol(sym string) string {
+	sym = strings.ToUpper(strings.TrimSpace(sym))
+	if !strings.HasSuffix(sym, "USDT") {
+		sym += "USDT"
+	}
+	return sym
+}
+
+// applyFuturesCarry 合约持仓在每个周期开始时先结算 (上一周期, 本周期] 内的资金费（从保证金扣除），再检查强平：
+// 保证金加浮动盈亏不高于维持保证金时按当周期价格强平，剩余保证金全部损失，不回到现金
+func (be *BacktestEngine) applyFuturesCarry(symbolStates map[string]*SymbolState, result *BacktestResult, index int, timestamp time.Time, config *BacktestConfig) {
+	if !config.IsFutures() {
+		return
+	}
+
+	symbols := make([]string, 0, len(symbolStates))
+	for sym := range symbolStates {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	for _, sym := range symbols {
+		state := symbolStates[sym]
+		if index >= len(state.Data) {
+			continue
+		}
+		price := state.Data[index].Price
+
+		// 开仓前的结算点只推进游标，不计费
+		for state.fundingNext < len(state.Funding) && !state.Funding[state.fundingNext].Time.After(timestamp) {
+			fp := state.Funding[state.fundingNext]
+			state.fundingNext++
+			if state.Position <= 0 || price <= 0 {
+				continue
+			}
+			mark := fp.MarkPrice
+			if mark <= 0 {
+				mark = price
+			}
+			cost := state.Position * mark * fp.Rate
+			state.Margin -= cost
+			state.FundingPaid += cost
+			result.FundingCost += cost
+		}
+
+		if state.Position <= 0 || price <= 0 || state.LastBuyPrice <= 0 {
+			continue
+		}
+		equity := state.Margin + state.Position*(price-state.LastBuyPrice)
+		if equity > config.MaintenanceMarginRate*state.Position*price {
+			continue
+		}
+		be.liquidatePosition(state, result, index, timestamp, price, equity, config)
+	}
+}
+
+// liquidatePosition 强平记录与状态重置；盈亏记法与其它平仓一致（价格收益率，并回填到对应的买入记录）
+func (be *BacktestEngine) liquidatePosition(state *SymbolState, result *BacktestResult, index int, timestamp time.Time, price, equity float64, config *BacktestConfig) {
+	pnl := (price - state.LastBuyPrice) / state.LastBuyPrice
+	for i := len(result.Trades) - 1; i >= 0; i-- {
+		if result.Trades[i].Symbol == state.Symbol && result.Trades[i].Side == "buy" && result.Trades[i].PnL == 0 {
+			result.Trades[i].PnL = pnl
+			break
+		}
+	}
+	exit := TradeRecord{
+		Symbol:    state.Symbol,
+		Side:      "sell",
+		Quantity:  state.Position,
+		Price:     price,
+		Timestamp: timestamp,
+		PnL:       pnl,
+		Reason:    ExitReasonLiquidation,
+	}
+	result.Trades = append(result.Trades, exit)
+	result.Liquidations++
+
+	log.Printf("[LIQUIDATION] %s %.0fx 保证金耗尽强平: 开仓=%.4f, 价格=%.4f, 数量=%.4f, 剩余权益=%.2f, 累计资金费=%.2f",
+		state.Symbol, config.FuturesLeverage(), state.LastBuyPrice, price, state.Position, equity, state.FundingPaid)
+
+	if be.dynamicSelector != nil {
+		be.dynamicSelector.UpdatePerformance(state.Symbol, &exit)
+	}
+	if be.symbolPerformanceStats != nil {
+		be.updateSymbolPerformanceStats(state.Symbol, pnl, false)
+	}
+
+	state.Position = 0
+	state.Margin = 0
+	state.HoldTime = 0
+	state.LastTradeIndex = index
+	state.Reason = ExitReasonLiquidation
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// futuresTestState 按 prices 构造逐小时行情，每周期都有一次资金费结算
+func futuresTestState(sym string, prices []float64, rate float64) *SymbolState {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	state := &SymbolState{Symbol: sym, LastTradeIndex: -10}
+	for i, p := range prices {
+		ts := start.Add(time.Duration(i) * time.Hour)
+		state.Data = append(state.Data, MarketData{Symbol: sym, Price: p, LastUpdated: ts})
+		state.Funding = append(state.Funding, FundingRatePoint{Time: ts, Rate: rate})
+	}
+	return state
+}
+
+// TestFuturesLeveragedPositionLiquidated 10 倍杠杆多仓逐周期扣资金费，保证金耗尽后按当周期价格强平
+func TestFuturesLeveragedPositionLiquidated(t *testing.T) {
+	be := &BacktestEngine{}
+	config := &BacktestConfig{InitialCash: 1000, Market: "futures", Leverage: 10, MaintenanceMarginRate: 0.005}
+	result := &BacktestResult{Config: *config}
+	state := futuresTestState("BTCUSDT", []float64{100, 99, 97, 92, 90}, 0.01)
+	states := map[string]*SymbolState{"BTCUSDT": state}
+
+	// 第 0 周期开仓前的结算点只推进游标，不计费
+	be.applyFuturesCarry(states, result, 0, state.Data[0].LastUpdated, config)
+	if result.FundingCost != 0 || state.fundingNext != 1 {
+		t.Fatalf("开仓前不应计资金费: cost=%v next=%d", result.FundingCost, state.fundingNext)
+	}
+
+	cash := config.InitialCash
+	cash -= config.reserveMargin(state, 100)
+	state.Position, state.LastBuyPrice = 1, 100
+	result.Trades = append(result.Trades, TradeRecord{Symbol: "BTCUSDT", Side: "buy", Quantity: 1, Price: 100})
+	if cash != 990 || state.Margin != 10 {
+		t.Fatalf("10 倍杠杆应占用 10 保证金，实际 cash=%v margin=%v", cash, state.Margin)
+	}
+
+	for i := 1; i <= 3; i++ {
+		be.applyFuturesCarry(states, result, i, state.Data[i].LastUpdated, config)
+		if i < 3 && state.Position != 1 {
+			t.Fatalf("第 %d 周期不应强平: %+v", i, state)
+		}
+	}
+
+	// 资金费 = 数量 * 结算价 * 费率：0.99 + 0.97 + 0.92；第 3 周期权益 10-2.88-8 < 维持保证金
+	if want := 2.88; math.Abs(result.FundingCost-want) > 1e-9 || math.Abs(state.FundingPaid-want) > 1e-9 {
+		t.Errorf("累计资金费期望 %v，实际 result=%v state=%v", want, result.FundingCost, state.FundingPaid)
+	}
+	if result.Liquidations != 1 {
+		t.Fatalf("期望强平 1 次，实际 %d", result.Liquidations)
+	}
+	exit := result.Trades[len(result.Trades)-1]
+	if exit.Side != "sell" || exit.Reason != ExitReasonLiquidation || exit.Price != 92 || math.Abs(exit.PnL+0.08) > 1e-9 {
+		t.Errorf("强平记录不符: %+v", exit)
+	}
+	if result.Trades[0].PnL != exit.PnL {
+		t.Errorf("对应买入记录应回填收益率: %+v", result.Trades[0])
+	}
+	if state.Position != 0 || state.Margin != 0 || state.Reason != ExitReasonLiquidation {
+		t.Errorf("强平后状态未重置: %+v", state)
+	}
+	// 剩余保证金全部损失，现金不变
+	if cash != 990 || config.positionValue(state, 90) != 0 {
+		t.Errorf("强平后不应有资金回到现金: cash=%v", cash)
+	}
+
+	// 已平仓后后续结算点不再计费
+	be.applyFuturesCarry(states, result, 4, state.Data[4].LastUpdated, config)
+	if math.Abs(result.FundingCost-2.88) > 1e-9 || result.Liquidations != 1 {
+		t.Errorf("平仓后不应继续计费或强平: cost=%v liq=%d", result.FundingCost, result.Liquidations)
+	}
+}
+
+// TestFuturesMarginRoundTrip 合约平仓返还保证金加已实现盈亏；现货仍按全额市值
+func TestFuturesMarginRoundTrip(t *testing.T) {
+	futures := BacktestConfig{Market: "futures", Leverage: 5, Commission: 0.001, CommissionFree: true}
+	state := &SymbolState{Symbol: "ETHUSDT"}
+	if got := futures.reserveMargin(state, 2*100); got != 40 {
+		t.Fatalf("5 倍杠杆名义 200 应占用 40，实际 %v", got)
+	}
+	state.Position, state.LastBuyPrice = 2, 100
+	if got := futures.positionValue(state, 110); got != 60 {
+		t.Errorf("权益期望 40+20=60，实际 %v", got)
+	}
+	if got := futures.releaseMargin(state, 1, 110); got != 30 {
+		t.Errorf("平一半期望返还 20+10=30，实际 %v", got)
+	}
+	if state.Margin != 20 {
+		t.Errorf("剩余保证金期望 20，实际 %v", state.Margin)
+	}
+	if futures.CommissionRate("ETHUSDT") != 0 {
+		t.Errorf("免手续费时费率应为 0")
+	}
+
+	spot := BacktestConfig{Leverage: 5}
+	spotState := &SymbolState{Symbol: "ETHUSDT", Position: 2, LastBuyPrice: 100}
+	if spot.FuturesLeverage() != 1 || spot.reserveMargin(spotState, 200) != 200 || spot.releaseMargin(spotState, 2, 110) != 220 {
+		t.Errorf("现货回测不应使用杠杆")
+	}
+}
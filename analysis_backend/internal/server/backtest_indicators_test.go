@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// indicatorTestSeries 带缓慢上行趋势的双正弦价格序列，用于固定回测指标与信号的取值
+func indicatorTestSeries() []MarketData {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]MarketData, 120)
+	for i := range data {
+		p := 100 + 0.1*float64(i) + 5*math.Sin(float64(i)*2*math.Pi/30) + 1.5*math.Sin(float64(i)*1.7)
+		data[i] = MarketData{Symbol: "BTCUSDT", Price: p, LastUpdated: start.Add(time.Duration(i) * time.Hour)}
+	}
+	return data
+}
+
+// TestBacktestIndicatorRegression 固定回测引擎改用 internal/indicators 后的指标与方向信号：
+// RSI/ATR 为 Wilder 平滑，布林带为总体标准差。指标口径再变化时这里会失败，需同时确认回测结果的变化
+func TestBacktestIndicatorRegression(t *testing.T) {
+	be := &BacktestEngine{}
+	data := indicatorTestSeries()
+	near := func(name string, got, want float64) {
+		t.Helper()
+		if math.Abs(got-want) > 1e-8 {
+			t.Errorf("%s = %.10f, 期望 %.10f", name, got, want)
+		}
+	}
+
+	tail := data[len(data)-20:]
+	near("rsi_14", be.calculateRSI(tail, 14), 49.0504218224)
+	near("bollinger_position", be.calculateBollingerPosition(tail), 0.5068036121)
+	near("atr_14", be.calculateATR(data, len(data)-1, 14), 0.0144470520)
+	near("volatility_19", be.calculateVolatility(tail, 19), 0.0165102330)
+
+	// predictPriceDirection 的逐根得分与集成策略买入阈值 0.3 下的信号数量
+	for _, tc := range []struct {
+		bar  int
+		want float64
+	}{{25, 0.4900778848}, {40, 0.7306661167}, {60, 0.1050721428}, {119, 0.2034029559}} {
+		near(fmt.Sprintf("direction@%d", tc.bar), be.predictPriceDirection(context.Background(), data[:tc.bar+1], "BTCUSDT"), tc.want)
+	}
+	buys := 0
+	for i := 20; i < len(data); i++ {
+		if be.predictPriceDirection(context.Background(), data[:i+1], "BTCUSDT") > 0.3 {
+			buys++
+		}
+	}
+	if buys != 73 {
+		t.Errorf("买入信号数量 = %d, 期望 73", buys)
+	}
+}
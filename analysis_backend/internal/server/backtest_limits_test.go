@@ -0,0 +1,147 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func newLimitTestState(cash float64) *StrategySimulationState {
+	return &StrategySimulationState{
+		Cash:        cash,
+		Positions:   map[string]float64{},
+		LastPrices:  map[string]float64{},
+		SymbolStats: map[string]*SymbolPerformance{},
+	}
+}
+
+// TestExecuteStrategyTradeMaxConcurrentPositions 持仓币种数达到上限后拒绝新币种开仓，已有持仓可继续加仓
+func TestExecuteStrategyTradeMaxConcurrentPositions(t *testing.T) {
+	be := &BacktestEngine{}
+	cfg := BacktestConfig{InitialCash: 10000, MaxPosition: 0.1, MaxConcurrentPositions: 2}
+	result := &BacktestResult{Config: cfg}
+	state := newLimitTestState(10000)
+	decision := StrategyDecisionResult{Action: "sell", Multiplier: 1}
+	now := time.Now()
+
+	for _, sym := range []string{"BTCUSDT", "ETHUSDT"} {
+		if err := be.executeStrategyTrade(decision, MarketData{Symbol: sym, Price: 100, LastUpdated: now}, cfg, result, state); err != nil {
+			t.Fatalf("%s 开仓不应被拒绝: %v", sym, err)
+		}
+	}
+	if err := be.executeStrategyTrade(decision, MarketData{Symbol: "SOLUSDT", Price: 100, LastUpdated: now}, cfg, result, state); err == nil {
+		t.Error("持仓数已达上限，SOLUSDT 开仓应被拒绝")
+	}
+	if err := be.executeStrategyTrade(decision, MarketData{Symbol: "BTCUSDT", Price: 100, LastUpdated: now}, cfg, result, state); err != nil {
+		t.Errorf("已有持仓的币种加仓不受持仓数限制: %v", err)
+	}
+	if len(result.Trades) != 3 {
+		t.Errorf("期望 3 笔成交，实际 %d", len(result.Trades))
+	}
+	if _, ok := state.Positions["SOLUSDT"]; ok {
+		t.Error("被拒绝的开仓不应留下持仓")
+	}
+}
+
+// TestExecuteStrategyTradeMaxGrossExposure 总敞口超过上限时拒绝开仓，价格上涨后敞口按最新价重算
+func TestExecuteStrategyTradeMaxGrossExposure(t *testing.T) {
+	be := &BacktestEngine{}
+	// 上限 = 0.5 * 10000 = 5000，每笔名义价值 = 10000 * 0.2 = 2000
+	cfg := BacktestConfig{InitialCash: 10000, MaxPosition: 0.2, MaxGrossExposure: 0.5}
+	result := &BacktestResult{Config: cfg}
+	state := newLimitTestState(10000)
+	decision := StrategyDecisionResult{Action: "sell", Multiplier: 1}
+	now := time.Now()
+
+	for _, sym := range []string{"BTCUSDT", "ETHUSDT"} {
+		if err := be.executeStrategyTrade(decision, MarketData{Symbol: sym, Price: 100, LastUpdated: now}, cfg, result, state); err != nil {
+			t.Fatalf("%s 开仓不应被拒绝: %v", sym, err)
+		}
+	}
+	if err := be.executeStrategyTrade(decision, MarketData{Symbol: "SOLUSDT", Price: 100, LastUpdated: now}, cfg, result, state); err == nil {
+		t.Error("总敞口 4000 + 2000 超过上限 5000，开仓应被拒绝")
+	}
+	if len(result.Trades) != 2 {
+		t.Errorf("期望 2 笔成交，实际 %d", len(result.Trades))
+	}
+
+	// 不设上限时不受限制
+	cfg.MaxGrossExposure = 0
+	if err := be.executeStrategyTrade(decision, MarketData{Symbol: "SOLUSDT", Price: 100, LastUpdated: now}, cfg, result, state); err != nil {
+		t.Errorf("未配置敞口上限时不应拒绝: %v", err)
+	}
+}
+
+// TestExecuteStrategyTradeClosedPositionFreesSlot 空头被买入完全平掉后从持仓中删除，释放持仓名额
+func TestExecuteStrategyTradeClosedPositionFreesSlot(t *testing.T) {
+	be := &BacktestEngine{}
+	cfg := BacktestConfig{InitialCash: 10000, MaxPosition: 0.1, MaxConcurrentPositions: 2}
+	result := &BacktestResult{Config: cfg}
+	state := newLimitTestState(10000)
+	sell := StrategyDecisionResult{Action: "sell", Multiplier: 1}
+	buy := StrategyDecisionResult{Action: "buy", Multiplier: 1}
+	now := time.Now()
+	trade := func(d StrategyDecisionResult, sym string) error {
+		return be.executeStrategyTrade(d, MarketData{Symbol: sym, Price: 100, LastUpdated: now}, cfg, result, state)
+	}
+
+	for _, sym := range []string{"BTCUSDT", "ETHUSDT"} {
+		if err := trade(sell, sym); err != nil {
+			t.Fatalf("%s 开仓不应被拒绝: %v", sym, err)
+		}
+	}
+	if err := trade(sell, "SOLUSDT"); err == nil {
+		t.Fatal("持仓数已达上限，SOLUSDT 开仓应被拒绝")
+	}
+
+	// 买入平掉 BTC 空头（数量 10000*0.1/100 = 10，与开仓数量相同）
+	if err := trade(buy, "BTCUSDT"); err != nil {
+		t.Fatalf("平仓不应被拒绝: %v", err)
+	}
+	if _, ok := state.Positions["BTCUSDT"]; ok {
+		t.Errorf("平仓后 BTCUSDT 应从持仓中删除: %v", state.Positions)
+	}
+	if err := trade(sell, "SOLUSDT"); err != nil {
+		t.Errorf("平仓释放名额后 SOLUSDT 应可开仓: %v", err)
+	}
+
+	// 没有空头时买入不产生交易
+	before := len(result.Trades)
+	if err := trade(buy, "XRPUSDT"); err != nil || len(result.Trades) != before {
+		t.Errorf("无持仓时买入不应成交: err=%v trades=%d", err, len(result.Trades)-before)
+	}
+}
+
+// TestExecuteMultiSymbolTradeRespectsPortfolioLimits 多币种回测开仓同样受持仓币种数限制，平仓（Position=0）的币种不占名额
+func TestExecuteMultiSymbolTradeRespectsPortfolioLimits(t *testing.T) {
+	be := &BacktestEngine{}
+	cfg := &BacktestConfig{InitialCash: 10000, PositionSize: 0.1, MaxConcurrentPositions: 1}
+	now := time.Now()
+	newState := func(sym string) *SymbolState {
+		return &SymbolState{Symbol: sym, Data: []MarketData{{Symbol: sym, Price: 100, LastUpdated: now}}}
+	}
+	states := map[string]*SymbolState{"BTCUSDT": newState("BTCUSDT"), "ETHUSDT": newState("ETHUSDT")}
+	result := &BacktestResult{Config: *cfg}
+	available, total := 10000.0, 10000.0
+	open := func(sym string) {
+		t.Helper()
+		opp := &TradeOpportunity{Symbol: sym, Action: "buy", Confidence: 0.8, Score: 0.8, Price: 100, State: states[sym]}
+		if err := be.executeMultiSymbolTrade(opp, states, &available, &total, result, now, cfg); err != nil {
+			t.Fatalf("%s: %v", sym, err)
+		}
+	}
+
+	open("BTCUSDT")
+	if states["BTCUSDT"].Position <= 0 {
+		t.Fatalf("BTCUSDT 应开仓成功: %+v", states["BTCUSDT"])
+	}
+	open("ETHUSDT")
+	if states["ETHUSDT"].Position != 0 || len(result.Trades) != 1 {
+		t.Fatalf("持仓数已达上限，ETHUSDT 开仓应被拒绝: pos=%v trades=%d", states["ETHUSDT"].Position, len(result.Trades))
+	}
+
+	states["BTCUSDT"].Position = 0 // BTC 平仓
+	open("ETHUSDT")
+	if states["ETHUSDT"].Position <= 0 {
+		t.Errorf("BTCUSDT 平仓后 ETHUSDT 应可开仓")
+	}
+}
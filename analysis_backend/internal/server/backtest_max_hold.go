@@ -0,0 +1,75 @@
+package server
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// ExitReasonMaxHold 超过最大持有周期的强制平仓原因
+const ExitReasonMaxHold = "max_hold"
+
+// enforceMaxHoldingPeriod 持仓周期达到 MaxHoldingPeriod 的币种按当前周期价格强制平仓，不论信号与盈亏；
+// 盈亏记法与 checkMultiSymbolExits 一致（收益率，并回填到对应的买入记录）
+func (be *BacktestEngine) enforceMaxHoldingPeriod(symbolStates map[string]*SymbolState, availableCash *float64, result *BacktestResult, index int, timestamp time.Time, config *BacktestConfig) {
+	if config.MaxHoldingPeriod <= 0 {
+		return
+	}
+
+	symbols := make([]string, 0, len(symbolStates))
+	for sym := range symbolStates {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	for _, sym := range symbols {
+		state := symbolStates[sym]
+		if state.Position <= 0 || state.HoldTime < config.MaxHoldingPeriod || index >= len(state.Data) {
+			continue
+		}
+		price := state.Data[index].Price
+		if price <= 0 {
+			continue
+		}
+
+		pnl := 0.0
+		if state.LastBuyPrice > 0 {
+			pnl = (price - state.LastBuyPrice) / state.LastBuyPrice
+		}
+		commission := state.Position * price * config.CommissionRate(sym)
+		*availableCash += config.releaseMargin(state, state.Position, price) - commission
+
+		for i := len(result.Trades) - 1; i >= 0; i-- {
+			if result.Trades[i].Symbol == sym && result.Trades[i].Side == "buy" && result.Trades[i].PnL == 0 {
+				result.Trades[i].PnL = pnl
+				break
+			}
+		}
+		exit := TradeRecord{
+			Symbol:     sym,
+			Side:       "sell",
+			Quantity:   state.Position,
+			Price:      price,
+			Timestamp:  timestamp,
+			Commission: commission,
+			PnL:        pnl,
+			Reason:     ExitReasonMaxHold,
+		}
+		result.Trades = append(result.Trades, exit)
+
+		log.Printf("[MAX_HOLD] %s 持有%d周期达到上限%d，强制平仓: 价格=%.4f, 数量=%.4f, 盈亏=%.2f%%",
+			sym, state.HoldTime, config.MaxHoldingPeriod, price, state.Position, pnl*100)
+
+		if be.dynamicSelector != nil {
+			be.dynamicSelector.UpdatePerformance(sym, &exit)
+		}
+		if be.symbolPerformanceStats != nil {
+			be.updateSymbolPerformanceStats(sym, pnl, pnl > 0)
+		}
+
+		state.Position = 0
+		state.HoldTime = 0
+		state.LastTradeIndex = index
+		state.Reason = ExitReasonMaxHold
+	}
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// maxHoldTestState 在 openIndex 以 100 买入 1 个单位的持仓，之后价格每周期 +1
+func maxHoldTestState(sym string, periods, openIndex int, result *BacktestResult) *SymbolState {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := make([]MarketData, periods)
+	for i := range data {
+		data[i] = MarketData{Symbol: sym, Price: 100 + float64(i-openIndex), LastUpdated: start.Add(time.Duration(i) * time.Hour)}
+	}
+	result.Trades = append(result.Trades, TradeRecord{Symbol: sym, Side: "buy", Quantity: 1, Price: 100, Timestamp: data[openIndex].LastUpdated})
+	return &SymbolState{Symbol: sym, Position: 1, LastBuyPrice: 100, LastTradeIndex: openIndex, Data: data}
+}
+
+// runMaxHoldLoop 按主回测循环的顺序：先检查强制平仓，再累加持仓周期
+func runMaxHoldLoop(be *BacktestEngine, states map[string]*SymbolState, cash *float64, result *BacktestResult, from, to int, config *BacktestConfig) {
+	for i := from; i < to; i++ {
+		be.enforceMaxHoldingPeriod(states, cash, result, i, states["AUSDT"].Data[i].LastUpdated, config)
+		for _, st := range states {
+			if st.Position > 0 {
+				st.HoldTime++
+			}
+		}
+	}
+}
+
+// TestMaxHoldingPeriodForcesExit 持仓超过最大持有周期后以 max_hold 强制平仓，盈利持仓同样平仓
+func TestMaxHoldingPeriodForcesExit(t *testing.T) {
+	be := &BacktestEngine{}
+	config := &BacktestConfig{InitialCash: 1000, Commission: 0.001, MaxHoldingPeriod: 5}
+	result := &BacktestResult{Config: *config}
+	states := map[string]*SymbolState{
+		"AUSDT": maxHoldTestState("AUSDT", 20, 2, result),
+		"BUSDT": maxHoldTestState("BUSDT", 20, 8, result),
+	}
+	// B 在第 8 周期才开仓，此前不持仓
+	states["BUSDT"].Position = 0
+	cash := 800.0
+
+	runMaxHoldLoop(be, states, &cash, result, 3, 8, config)
+	states["BUSDT"].Position = 1
+	runMaxHoldLoop(be, states, &cash, result, 8, 12, config)
+
+	var exits []TradeRecord
+	for _, tr := range result.Trades {
+		if tr.Side == "sell" {
+			exits = append(exits, tr)
+		}
+	}
+	if len(exits) != 1 {
+		t.Fatalf("期望仅 AUSDT 被强制平仓 1 次，实际 %+v", exits)
+	}
+	exit := exits[0]
+	// 第 3 周期起每周期 HoldTime+1，第 8 周期检查时 HoldTime=5 达到上限
+	if exit.Symbol != "AUSDT" || exit.Reason != ExitReasonMaxHold || !exit.Timestamp.Equal(states["AUSDT"].Data[8].LastUpdated) {
+		t.Fatalf("强制平仓记录不符: %+v", exit)
+	}
+	if exit.Price != 106 || math.Abs(exit.PnL-0.06) > 1e-9 {
+		t.Errorf("平仓价格/收益率期望 106/0.06，实际 %v/%v", exit.Price, exit.PnL)
+	}
+	if want := 800 + 106 - 106*0.001; math.Abs(cash-want) > 1e-9 {
+		t.Errorf("平仓后现金期望 %v，实际 %v", want, cash)
+	}
+	if a := states["AUSDT"]; a.Position != 0 || a.HoldTime != 0 || a.Reason != ExitReasonMaxHold {
+		t.Errorf("平仓后状态未重置: %+v", a)
+	}
+	if result.Trades[0].PnL != exit.PnL {
+		t.Errorf("对应买入记录应回填收益率: %+v", result.Trades[0])
+	}
+	if b := states["BUSDT"]; b.Position != 1 || b.HoldTime != 4 {
+		t.Errorf("BUSDT 未到上限不应平仓: %+v", b)
+	}
+}
+
+// TestMaxHoldingPeriodDisabled 未配置时不强制平仓
+func TestMaxHoldingPeriodDisabled(t *testing.T) {
+	be := &BacktestEngine{}
+	config := &BacktestConfig{InitialCash: 1000}
+	result := &BacktestResult{Config: *config}
+	states := map[string]*SymbolState{"AUSDT": maxHoldTestState("AUSDT", 50, 0, result)}
+	cash := 900.0
+
+	runMaxHoldLoop(be, states, &cash, result, 1, 50, config)
+	if len(result.Trades) != 1 || states["AUSDT"].Position != 1 {
+		t.Fatalf("未启用时不应平仓: %+v", result.Trades)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// 蒙特卡洛重采样方式
+const (
+	MonteCarloBootstrap = "bootstrap" // 有放回重采样，收益与回撤都会变化
+	MonteCarloShuffle   = "shuffle"   // 仅打乱顺序，最终收益不变，只评估回撤对顺序的敏感度
+)
+
+// 未指定种子时使用固定种子，保证相同输入得到相同分布
+const defaultMonteCarloSeed int64 = 42
+
+// realizedTradeReturns 按原始顺序把已实现盈亏换算为占当时权益的收益率
+func realizedTradeReturns(result *BacktestResult) []float64 {
+	equity := result.Config.InitialCash
+	var returns []float64
+	for _, trade := range result.Trades {
+		if trade.Side != "sell" || trade.PnL == 0 || equity <= 0 {
+			continue
+		}
+		returns = append(returns, trade.PnL/equity)
+		equity += trade.PnL
+	}
+	return returns
+}
+
+// simulateEquityPath 按给定顺序复利，返回总收益率和最大回撤
+func simulateEquityPath(returns []float64) (float64, float64) {
+	equity, peak, maxDrawdown := 1.0, 1.0, 0.0
+	for _, r := range returns {
+		equity *= 1 + r
+		if equity > peak {
+			peak = equity
+		}
+		if peak > 0 {
+			maxDrawdown = math.Max(maxDrawdown, (peak-equity)/peak)
+		}
+	}
+	return equity - 1, maxDrawdown
+}
+
+// sortedPercentile 对已排序数据做线性插值分位数，p 取 0-1
+func sortedPercentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	pos := p * float64(len(sorted)-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(pos-float64(lo))
+}
+
+// runTradeMonteCarlo 对已实现交易收益做多次重采样/打乱，得到收益与最大回撤的分布
+func (be *BacktestEngine) runTradeMonteCarlo(result *BacktestResult, analysis MonteCarloAnalysis) (*MonteCarloResult, error) {
+	returns := realizedTradeReturns(result)
+	if len(returns) < 2 {
+		return nil, fmt.Errorf("已实现交易不足（%d笔），无法进行蒙特卡洛分析", len(returns))
+	}
+
+	if analysis.Simulations <= 0 {
+		analysis.Simulations = 1000
+	}
+	if analysis.Method == "" {
+		analysis.Method = MonteCarloBootstrap
+	}
+	if analysis.Method != MonteCarloBootstrap && analysis.Method != MonteCarloShuffle {
+		return nil, fmt.Errorf("不支持的蒙特卡洛方式: %s", analysis.Method)
+	}
+	if analysis.BootstrapSize <= 0 || analysis.Method == MonteCarloShuffle {
+		analysis.BootstrapSize = len(returns)
+	}
+	if analysis.ConfidenceLevel <= 0 || analysis.ConfidenceLevel >= 1 {
+		analysis.ConfidenceLevel = 0.9
+	}
+	if analysis.Seed == 0 {
+		analysis.Seed = defaultMonteCarloSeed
+	}
+	rng := rand.New(rand.NewSource(analysis.Seed))
+
+	simReturns := make([]float64, analysis.Simulations)
+	simDrawdowns := make([]float64, analysis.Simulations)
+	sample := make([]float64, analysis.BootstrapSize)
+	for i := 0; i < analysis.Simulations; i++ {
+		if analysis.Method == MonteCarloShuffle {
+			copy(sample, returns)
+			rng.Shuffle(len(sample), func(a, b int) { sample[a], sample[b] = sample[b], sample[a] })
+		} else {
+			for j := range sample {
+				sample[j] = returns[rng.Intn(len(returns))]
+			}
+		}
+		simReturns[i], simDrawdowns[i] = simulateEquityPath(sample)
+	}
+
+	mc := &MonteCarloResult{Trades: len(returns)}
+	mc.OriginalReturn, mc.OriginalMaxDrawdown = simulateEquityPath(returns)
+
+	sort.Float64s(simReturns)
+	sort.Float64s(simDrawdowns)
+	mc.MedianReturn = sortedPercentile(simReturns, 0.5)
+	mc.ReturnP5 = sortedPercentile(simReturns, 0.05)
+	mc.ReturnP95 = sortedPercentile(simReturns, 0.95)
+	mc.MedianMaxDrawdown = sortedPercentile(simDrawdowns, 0.5)
+	mc.MaxDrawdownP5 = sortedPercentile(simDrawdowns, 0.05)
+	mc.MaxDrawdownP95 = sortedPercentile(simDrawdowns, 0.95)
+
+	// 收益分布的矩
+	mean, std := be.calculateMeanAndStdDev(simReturns)
+	dist := MonteCarloDistribution{
+		Mean:      mean,
+		StdDev:    std,
+		MinReturn: simReturns[0],
+		MaxReturn: simReturns[len(simReturns)-1],
+	}
+	if std > 0 {
+		var m3, m4 float64
+		for _, r := range simReturns {
+			z := (r - mean) / std
+			m3 += z * z * z
+			m4 += z * z * z * z
+		}
+		n := float64(len(simReturns))
+		dist.Skewness = m3 / n
+		dist.Kurtosis = m4/n - 3
+	}
+	analysis.Distribution = dist
+
+	tail := (1 - analysis.ConfidenceLevel) / 2
+	analysis.ConfidenceIntervals = []ConfidenceInterval{{
+		Level:      analysis.ConfidenceLevel,
+		LowerBound: sortedPercentile(simReturns, tail),
+		UpperBound: sortedPercentile(simReturns, 1-tail),
+	}}
+	mc.Analysis = analysis
+	return mc, nil
+}
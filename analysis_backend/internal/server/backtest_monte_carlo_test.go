@@ -0,0 +1,81 @@
+package server
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func monteCarloTestResult() *BacktestResult {
+	pnls := []float64{300, -200, 450, -500, 120, 80, -150, 600, -250, 200, -100, 350}
+	result := &BacktestResult{Config: BacktestConfig{InitialCash: 10000}}
+	for _, pnl := range pnls {
+		result.Trades = append(result.Trades,
+			TradeRecord{Symbol: "BTCUSDT", Side: "buy", PnL: pnl},
+			TradeRecord{Symbol: "BTCUSDT", Side: "sell", PnL: pnl},
+		)
+	}
+	return result
+}
+
+// TestTradeMonteCarloDistribution 分布被计算出来，且分位数有序、结果可复现
+func TestTradeMonteCarloDistribution(t *testing.T) {
+	be := &BacktestEngine{}
+	result := monteCarloTestResult()
+
+	mc, err := be.runTradeMonteCarlo(result, MonteCarloAnalysis{Simulations: 500, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mc.Trades != 12 || mc.Analysis.Simulations != 500 || mc.Analysis.Method != MonteCarloBootstrap {
+		t.Fatalf("默认参数不符: trades=%d analysis=%+v", mc.Trades, mc.Analysis)
+	}
+	// 只统计卖出侧已实现盈亏：总盈亏 900，复利收益与原始权益曲线一致
+	if math.Abs(mc.OriginalReturn-0.09) > 1e-9 {
+		t.Errorf("原始收益期望 9%%，实际 %.6f", mc.OriginalReturn)
+	}
+
+	if !(mc.ReturnP5 <= mc.MedianReturn && mc.MedianReturn <= mc.ReturnP95) {
+		t.Errorf("收益分位数无序: p5=%.4f median=%.4f p95=%.4f", mc.ReturnP5, mc.MedianReturn, mc.ReturnP95)
+	}
+	if !(mc.MaxDrawdownP5 <= mc.MedianMaxDrawdown && mc.MedianMaxDrawdown <= mc.MaxDrawdownP95) {
+		t.Errorf("回撤分位数无序: p5=%.4f median=%.4f p95=%.4f", mc.MaxDrawdownP5, mc.MedianMaxDrawdown, mc.MaxDrawdownP95)
+	}
+	if mc.ReturnP5 == mc.ReturnP95 || mc.MaxDrawdownP5 < 0 {
+		t.Errorf("有放回重采样应产生收益分布: %+v", mc)
+	}
+	dist := mc.Analysis.Distribution
+	if dist.StdDev <= 0 || dist.MinReturn > mc.ReturnP5 || dist.MaxReturn < mc.ReturnP95 {
+		t.Errorf("分布统计不符: %+v", dist)
+	}
+	ci := mc.Analysis.ConfidenceIntervals
+	if len(ci) != 1 || ci[0].LowerBound > ci[0].UpperBound {
+		t.Errorf("置信区间不符: %+v", ci)
+	}
+
+	again, _ := be.runTradeMonteCarlo(result, MonteCarloAnalysis{Simulations: 500, Seed: 7})
+	if !reflect.DeepEqual(mc, again) {
+		t.Error("相同种子应得到相同结果")
+	}
+}
+
+// TestTradeMonteCarloShuffle 仅打乱顺序时最终收益不变，回撤随顺序变化
+func TestTradeMonteCarloShuffle(t *testing.T) {
+	be := &BacktestEngine{}
+	mc, err := be.runTradeMonteCarlo(monteCarloTestResult(), MonteCarloAnalysis{Simulations: 300, Method: MonteCarloShuffle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range []float64{mc.ReturnP5, mc.MedianReturn, mc.ReturnP95} {
+		if math.Abs(r-mc.OriginalReturn) > 1e-9 {
+			t.Errorf("打乱顺序不应改变复利收益: %.6f vs %.6f", r, mc.OriginalReturn)
+		}
+	}
+	if mc.MaxDrawdownP5 >= mc.MaxDrawdownP95 {
+		t.Errorf("打乱顺序后回撤应有分布: p5=%.4f p95=%.4f", mc.MaxDrawdownP5, mc.MaxDrawdownP95)
+	}
+
+	if _, err := be.runTradeMonteCarlo(&BacktestResult{}, MonteCarloAnalysis{}); err == nil {
+		t.Error("没有已实现交易时应返回错误")
+	}
+}
@@ -73,7 +73,7 @@ func (be *BacktestEngine) calculateSummary(result *BacktestResult, initialCash f
 		meanReturn, stdDev := be.calculateMeanAndStdDev(dailyReturns)
 		if stdDev > 0 {
 			// 假设无风险利率为0.02 (2%)
-			riskFreeRate := 0.02 / 252 // 日化无风险利率
+			riskFreeRate := result.Config.PeriodRiskFreeRate(0.02) // 日化无风险利率，默认2%
 			sharpeRatio = (meanReturn - riskFreeRate) / stdDev
 		}
 	}
@@ -122,7 +122,7 @@ func (be *BacktestEngine) calculatePerformanceMetrics(result *BacktestResult) {
 	meanReturn, volatility := be.calculateMeanAndStdDev(dailyReturns)
 
 	// 计算夏普比率
-	riskFreeRate := 0.02 / 252 // 日化无风险利率2%
+	riskFreeRate := result.Config.PeriodRiskFreeRate(0.02) // 日化无风险利率，默认2%
 	sharpeRatio := 0.0
 	if volatility > 0 {
 		sharpeRatio = (meanReturn - riskFreeRate) / volatility
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// 回测时间范围限制（天）
+const (
+	DefaultBacktestMaxRangeDays     = 730  // 单次加载的最大范围，超过后按此大小分段加载历史数据
+	DefaultBacktestHardMaxRangeDays = 3650 // 允许的最大范围，超过直接拒绝
+	backtestMinChunkDays            = 60   // 末段不足该天数时并入前一段，避免单段数据点过少
+)
+
+// ErrBacktestRangeTooLarge 回测时间范围超过硬上限
+var ErrBacktestRangeTooLarge = errors.New("backtest range too large")
+
+// backtestRangeLimits 当前生效的 单段上限/硬上限（天），未配置时使用默认值；硬上限不小于单段上限
+func (be *BacktestEngine) backtestRangeLimits() (maxDays, hardDays int) {
+	maxDays, hardDays = be.maxRangeDays, be.hardMaxRangeDays
+	if maxDays <= 0 {
+		maxDays = DefaultBacktestMaxRangeDays
+	}
+	if hardDays <= 0 {
+		hardDays = DefaultBacktestHardMaxRangeDays
+	}
+	if hardDays < maxDays {
+		hardDays = maxDays
+	}
+	return maxDays, hardDays
+}
+
+// checkBacktestRange 校验回测范围：超过硬上限返回 ErrBacktestRangeTooLarge；超过单段上限时 chunked 为 true
+func (be *BacktestEngine) checkBacktestRange(startDate, endDate time.Time) (chunked bool, err error) {
+	maxDays, hardDays := be.backtestRangeLimits()
+	days := backtestRangeDays(startDate, endDate)
+	if days > hardDays {
+		return false, fmt.Errorf("%w: 回测时间范围 %d 天，最多支持 %d 天，请缩短范围", ErrBacktestRangeTooLarge, days, hardDays)
+	}
+	return days > maxDays, nil
+}
+
+// backtestRangeDays 范围跨越的天数（不足一天按一天计）
+func backtestRangeDays(startDate, endDate time.Time) int {
+	d := endDate.Sub(startDate)
+	if d <= 0 {
+		return 0
+	}
+	return int((d + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// backtestRangeChunks 把 [startDate, endDate] 切成不超过 days 天的连续分段，过短的末段并入前一段
+func backtestRangeChunks(startDate, endDate time.Time, days int) [][2]time.Time {
+	if days <= 0 || !endDate.After(startDate) {
+		return [][2]time.Time{{startDate, endDate}}
+	}
+	var chunks [][2]time.Time
+	for s := startDate; s.Before(endDate); {
+		e := s.AddDate(0, 0, days)
+		if e.After(endDate) || endDate.Sub(e) < backtestMinChunkDays*24*time.Hour {
+			e = endDate
+		}
+		chunks = append(chunks, [2]time.Time{s, e})
+		s = e
+	}
+	return chunks
+}
+
+// loadHistoricalData 获取回测区间的历史数据；超过单段上限时分段获取再按时间合并去重，
+// 避免单次查询超出数据点上限被截断。某段失败只跳过该段，全部失败才返回错误
+func (be *BacktestEngine) loadHistoricalData(ctx context.Context, symbol string, startDate, endDate time.Time) ([]MarketData, error) {
+	chunked, err := be.checkBacktestRange(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if !chunked {
+		return be.getHistoricalData(ctx, symbol, startDate, endDate)
+	}
+
+	maxDays, _ := be.backtestRangeLimits()
+	chunks := backtestRangeChunks(startDate, endDate, maxDays)
+	log.Printf("[INFO] %s backtest range %s to %s exceeds %d days, loading in %d chunks",
+		symbol, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), maxDays, len(chunks))
+
+	byTime := map[int64]MarketData{}
+	var lastErr error
+	for _, c := range chunks {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := be.getHistoricalData(ctx, symbol, c[0], c[1])
+		if err != nil {
+			log.Printf("[WARN] %s chunk %s to %s failed: %v", symbol, c[0].Format("2006-01-02"), c[1].Format("2006-01-02"), err)
+			lastErr = err
+			continue
+		}
+		for _, d := range data {
+			// 库内数据不足时数据源会向前扩展查询范围，只保留本段内的数据点
+			if d.LastUpdated.Before(c[0]) || d.LastUpdated.After(c[1]) {
+				continue
+			}
+			byTime[d.LastUpdated.Unix()] = d
+		}
+	}
+	if len(byTime) == 0 {
+		return nil, fmt.Errorf("all %d chunks failed for %s: %w", len(chunks), symbol, lastErr)
+	}
+
+	merged := make([]MarketData, 0, len(byTime))
+	for _, d := range byTime {
+		merged = append(merged, d)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].LastUpdated.Before(merged[j].LastUpdated) })
+	return merged, nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestBacktestRangeTooLargeRejected 超过硬上限的范围直接拒绝，不加载任何数据
+func TestBacktestRangeTooLargeRejected(t *testing.T) {
+	be, fetcher, _ := newDataSourceTestEngine(t, true)
+	be.maxRangeDays, be.hardMaxRangeDays = 100, 300
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := be.RunBacktest(context.Background(), BacktestConfig{Symbol: "SOL", StartDate: start, EndDate: start.AddDate(0, 0, 301)})
+	if !errors.Is(err, ErrBacktestRangeTooLarge) {
+		t.Fatalf("期望 ErrBacktestRangeTooLarge，实际 %v", err)
+	}
+	if fetcher.calls != 0 {
+		t.Errorf("被拒绝的范围不应拉取数据，实际调用 %d 次", fetcher.calls)
+	}
+
+	s := &Server{backtestEngine: be}
+	if err := s.validateBacktestConfig(BacktestConfig{Symbol: "SOL", StartDate: start, EndDate: start.AddDate(0, 0, 400), InitialCash: 1000, Strategy: "buy_and_hold", MaxPosition: 1}); !errors.Is(err, ErrBacktestRangeTooLarge) {
+		t.Errorf("接口校验也应拒绝超限范围，实际 %v", err)
+	}
+}
+
+// TestBacktestRangeChunkedLoad 超过单段上限但在硬上限内的范围分段加载，合并后按时间有序且不重复
+func TestBacktestRangeChunkedLoad(t *testing.T) {
+	be, fetcher, _ := newDataSourceTestEngine(t, false)
+	be.dataSource = BacktestDataSourceLive
+	be.maxRangeDays, be.hardMaxRangeDays = 100, 300
+	starts := map[time.Time]bool{}
+	be.klineFetcher = func(ctx context.Context, symbol, kind, interval string, limit int, startTime, endTime *time.Time) ([]BinanceKline, error) {
+		starts[*startTime] = true
+		return fetcher.fetch(ctx, symbol, kind, interval, limit, startTime, endTime)
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 279)
+
+	data, err := be.loadHistoricalData(context.Background(), "SOL", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starts) != 3 || !starts[start] || !starts[start.AddDate(0, 0, 100)] || !starts[start.AddDate(0, 0, 200)] {
+		t.Errorf("期望按 100 天分 3 段拉取，实际起点 %v", starts)
+	}
+	if len(data) != 280 {
+		t.Fatalf("期望合并后 280 个日K，实际 %d", len(data))
+	}
+	for i := 1; i < len(data); i++ {
+		if !data[i].LastUpdated.After(data[i-1].LastUpdated) {
+			t.Fatalf("第 %d 个数据点未按时间递增: %v <= %v", i, data[i].LastUpdated, data[i-1].LastUpdated)
+		}
+	}
+}
+
+// TestBacktestRangeChunks 末段过短时并入前一段
+func TestBacktestRangeChunks(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	chunks := backtestRangeChunks(start, start.AddDate(0, 0, 230), 100)
+	if len(chunks) != 2 || !chunks[0][1].Equal(start.AddDate(0, 0, 100)) || !chunks[1][1].Equal(start.AddDate(0, 0, 230)) {
+		t.Fatalf("分段不符: %v", chunks)
+	}
+	if got := backtestRangeChunks(start, start.AddDate(0, 0, 50), 100); len(got) != 1 {
+		t.Errorf("未超限范围应为单段: %v", got)
+	}
+}
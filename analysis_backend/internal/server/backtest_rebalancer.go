@@ -0,0 +1,161 @@
+package server
+
+import (
+	"log"
+	"math"
+	"sort"
+	"time"
+)
+
+// 再平衡权重方式
+const (
+	RebalanceEqualWeight = "equal_weight"   // 等权重
+	RebalanceScoreWeight = "score_weighted" // 按评分（近期动量）加权
+)
+
+// 评分加权时计算动量的回看周期
+const rebalanceScoreLookback = 20
+
+// RebalanceEvent 一次再平衡记录
+type RebalanceEvent struct {
+	Index     int                `json:"index"`
+	Timestamp time.Time          `json:"timestamp"`
+	Value     float64            `json:"value"`    // 再平衡时的组合价值
+	Turnover  float64            `json:"turnover"` // 本次成交额 / 组合价值
+	Targets   map[string]float64 `json:"targets"`  // 目标权重
+	Weights   map[string]float64 `json:"weights"`  // 再平衡后的实际权重
+}
+
+// rebalanceTargetWeights 计算目标权重；评分加权时负分视为 0，评分全为 0 时退化为等权重
+func rebalanceTargetWeights(scores map[string]float64, weighting string) map[string]float64 {
+	weights := make(map[string]float64, len(scores))
+	if len(scores) == 0 {
+		return weights
+	}
+
+	if weighting == RebalanceScoreWeight {
+		total := 0.0
+		for _, s := range scores {
+			total += math.Max(s, 0)
+		}
+		if total > 0 {
+			for sym, s := range scores {
+				weights[sym] = math.Max(s, 0) / total
+			}
+			return weights
+		}
+	}
+
+	for sym := range scores {
+		weights[sym] = 1 / float64(len(scores))
+	}
+	return weights
+}
+
+// maybeRebalance 按配置的周期（相对 startIndex）触发再平衡
+func (be *BacktestEngine) maybeRebalance(symbolStates map[string]*SymbolState, availableCash *float64, result *BacktestResult, index, startIndex int, timestamp time.Time, config *BacktestConfig) {
+	if config.RebalanceInterval <= 0 || index < startIndex || (index-startIndex)%config.RebalanceInterval != 0 {
+		return
+	}
+	be.rebalancePortfolio(symbolStates, availableCash, result, index, timestamp, config)
+}
+
+// rebalancePortfolio 将各币种持仓调整到目标权重：先卖出超配部分释放现金，再买入低配部分，
+// 偏离不超过 RebalanceTolerance 的币种不调整；手续费按 CommissionRate 计入
+func (be *BacktestEngine) rebalancePortfolio(symbolStates map[string]*SymbolState, availableCash *float64, result *BacktestResult, index int, timestamp time.Time, config *BacktestConfig) {
+	prices := make(map[string]float64)
+	scores := make(map[string]float64)
+	value := *availableCash
+	for sym, state := range symbolStates {
+		if index >= len(state.Data) || state.Data[index].Price <= 0 {
+			continue
+		}
+		prices[sym] = state.Data[index].Price
+		scores[sym] = be.calculateRecentReturn(state.Data, index, rebalanceScoreLookback)
+		value += config.positionValue(state, prices[sym])
+	}
+	if value <= 0 || len(prices) == 0 {
+		return
+	}
+
+	targets := rebalanceTargetWeights(scores, config.RebalanceWeighting)
+	symbols := make([]string, 0, len(prices))
+	for sym := range prices {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	traded := 0.0
+	trade := func(sym, side string, quantity float64) {
+		state := symbolStates[sym]
+		price := prices[sym]
+		notional := quantity * price
+		commission := notional * config.CommissionRate(sym)
+		record := TradeRecord{
+			Symbol:     sym,
+			Side:       side,
+			Quantity:   quantity,
+			Price:      price,
+			Timestamp:  timestamp,
+			Commission: commission,
+			Reason:     "rebalance",
+		}
+		if side == "sell" {
+			*availableCash += config.releaseMargin(state, quantity, price) - commission
+			if state.LastBuyPrice > 0 {
+				record.PnL = (price-state.LastBuyPrice)*quantity - commission
+			}
+			state.Position -= quantity
+			if state.Position <= 1e-12 {
+				state.Position = 0
+				state.HoldTime = 0
+			}
+		} else {
+			*availableCash -= config.reserveMargin(state, notional) + commission
+			// 持仓成本按加权平均更新
+			state.LastBuyPrice = (state.Position*state.LastBuyPrice + notional) / (state.Position + quantity)
+			state.Position += quantity
+		}
+		state.LastTradeIndex = index
+		state.Reason = "rebalance"
+		result.Trades = append(result.Trades, record)
+		traded += notional
+	}
+
+	// 先卖后买，保证买入时有足够现金
+	for _, sym := range symbols {
+		diff := targets[sym]*value - symbolStates[sym].Position*prices[sym]
+		if diff < 0 && -diff/value > config.RebalanceTolerance {
+			trade(sym, "sell", math.Min(-diff/prices[sym], symbolStates[sym].Position))
+		}
+	}
+	for _, sym := range symbols {
+		diff := targets[sym]*value - symbolStates[sym].Position*prices[sym]
+		if diff > 0 && diff/value > config.RebalanceTolerance {
+			budget := math.Min(diff, *availableCash/(1/config.FuturesLeverage()+config.CommissionRate(sym)))
+			if budget > 0 {
+				trade(sym, "buy", budget/prices[sym])
+			}
+		}
+	}
+
+	event := RebalanceEvent{
+		Index:     index,
+		Timestamp: timestamp,
+		Value:     value,
+		Turnover:  traded / value,
+		Targets:   targets,
+		Weights:   make(map[string]float64, len(symbols)),
+	}
+	after := *availableCash
+	for _, sym := range symbols {
+		after += config.positionValue(symbolStates[sym], prices[sym])
+	}
+	for _, sym := range symbols {
+		event.Weights[sym] = symbolStates[sym].Position * prices[sym] / after
+	}
+	result.Rebalances = append(result.Rebalances, event)
+	result.Turnover += event.Turnover
+
+	log.Printf("[REBALANCE] 周期%d 再平衡完成: 组合价值=%.2f, 换手率=%.2f%%", index, value, event.Turnover*100)
+}
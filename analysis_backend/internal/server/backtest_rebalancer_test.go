@@ -0,0 +1,109 @@
+package server
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// rebalanceTestStates 构造价格按固定比例漂移的币种：A 上涨、B 持平、C 下跌
+func rebalanceTestStates(periods int) map[string]*SymbolState {
+	drift := map[string]float64{"AUSDT": 0.02, "BUSDT": 0, "CUSDT": -0.01}
+	states := make(map[string]*SymbolState)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for sym, d := range drift {
+		data := make([]MarketData, periods)
+		price := 100.0
+		for i := range data {
+			data[i] = MarketData{Symbol: sym, Price: price, LastUpdated: start.Add(time.Duration(i) * time.Hour)}
+			price *= 1 + d
+		}
+		states[sym] = &SymbolState{Symbol: sym, LastTradeIndex: -10, Data: data}
+	}
+	return states
+}
+
+// TestRebalanceOnSchedule 按周期触发再平衡，且每次再平衡后权重回到目标附近
+func TestRebalanceOnSchedule(t *testing.T) {
+	be := &BacktestEngine{}
+	config := &BacktestConfig{
+		InitialCash:        10000,
+		Commission:         0.001,
+		RebalanceInterval:  5,
+		RebalanceWeighting: RebalanceEqualWeight,
+		RebalanceTolerance: 0.01,
+	}
+	result := &BacktestResult{Config: *config}
+	states := rebalanceTestStates(30)
+	cash := config.InitialCash
+
+	for i := 3; i < 30; i++ {
+		be.maybeRebalance(states, &cash, result, i, 3, states["AUSDT"].Data[i].LastUpdated, config)
+	}
+
+	var indexes []int
+	for _, ev := range result.Rebalances {
+		indexes = append(indexes, ev.Index)
+	}
+	if want := []int{3, 8, 13, 18, 23, 28}; !reflect.DeepEqual(indexes, want) {
+		t.Fatalf("再平衡周期不符: got %v want %v", indexes, want)
+	}
+
+	for _, ev := range result.Rebalances {
+		for sym, w := range ev.Weights {
+			if math.Abs(w-1.0/3) > config.RebalanceTolerance+0.005 {
+				t.Errorf("周期%d %s 再平衡后权重 %.4f 偏离目标 1/3", ev.Index, sym, w)
+			}
+		}
+	}
+
+	// 首次建仓换手率约为 100%，之后因价格漂移仍有调仓
+	if first := result.Rebalances[0].Turnover; first < 0.99 || first > 1.0 {
+		t.Errorf("首次建仓换手率期望约 1，实际 %.4f", first)
+	}
+	if result.Rebalances[1].Turnover <= 0 {
+		t.Error("价格漂移后再平衡应产生换手")
+	}
+	sum := 0.0
+	commission := 0.0
+	for _, ev := range result.Rebalances {
+		sum += ev.Turnover
+	}
+	for _, tr := range result.Trades {
+		commission += tr.Commission
+		if tr.Reason != "rebalance" {
+			t.Errorf("再平衡交易原因应为 rebalance，实际 %s", tr.Reason)
+		}
+	}
+	if math.Abs(result.Turnover-sum) > 1e-9 {
+		t.Errorf("累计换手率 %.4f 应等于各次之和 %.4f", result.Turnover, sum)
+	}
+	if commission <= 0 || cash < 0 {
+		t.Errorf("再平衡应计入手续费且现金不为负: commission=%.4f cash=%.4f", commission, cash)
+	}
+}
+
+// TestRebalanceScoreWeighted 评分加权时动量越强权重越高，负动量不分配权重
+func TestRebalanceScoreWeighted(t *testing.T) {
+	weights := rebalanceTargetWeights(map[string]float64{"A": 0.3, "B": 0.1, "C": -0.2}, RebalanceScoreWeight)
+	if math.Abs(weights["A"]-0.75) > 1e-9 || math.Abs(weights["B"]-0.25) > 1e-9 || weights["C"] != 0 {
+		t.Errorf("评分加权权重不符: %v", weights)
+	}
+	weights = rebalanceTargetWeights(map[string]float64{"A": -0.1, "B": 0}, RebalanceScoreWeight)
+	if weights["A"] != 0.5 || weights["B"] != 0.5 {
+		t.Errorf("评分全为非正时应退化为等权重: %v", weights)
+	}
+
+	be := &BacktestEngine{}
+	config := &BacktestConfig{InitialCash: 10000, RebalanceInterval: 1, RebalanceWeighting: RebalanceScoreWeight}
+	result := &BacktestResult{Config: *config}
+	states := rebalanceTestStates(30)
+	cash := config.InitialCash
+	be.rebalancePortfolio(states, &cash, result, 25, states["AUSDT"].Data[25].LastUpdated, config)
+
+	ev := result.Rebalances[0]
+	if math.Abs(ev.Weights["AUSDT"]-1) > 1e-6 || states["CUSDT"].Position != 0 {
+		t.Errorf("只有 AUSDT 有正动量，应全部配置到 AUSDT: %v", ev.Weights)
+	}
+}
@@ -0,0 +1,123 @@
+package server
+
+// 分环境报告使用的三类粗粒度市场环境
+const (
+	RegimeBull = "bull"
+	RegimeBear = "bear"
+	RegimeChop = "chop"
+)
+
+// 判定市场环境所需的最少基准历史数据点
+const regimeWarmupPeriods = 21
+
+// RegimeStats 某一市场环境下策略与基准的表现
+type RegimeStats struct {
+	Regime          string  `json:"regime"`
+	Periods         int     `json:"periods"`
+	StrategyReturn  float64 `json:"strategy_return"`  // 该环境内各周期收益的复合收益
+	BenchmarkReturn float64 `json:"benchmark_return"` // 同期基准复合收益
+	ExcessReturn    float64 `json:"excess_return"`    // 策略 - 基准
+	WinRate         float64 `json:"win_rate"`         // 策略跑赢基准的周期占比
+}
+
+// RegimeBreakdown 按市场环境拆分的策略/基准对比
+type RegimeBreakdown struct {
+	BenchmarkSymbol string        `json:"benchmark_symbol"`
+	Regimes         []RegimeStats `json:"regimes"`      // 固定顺序：bull, bear, chop
+	Unclassified    int           `json:"unclassified"` // 预热期或无法与基准对齐的周期数
+}
+
+// regimeBucket 把细分市场环境归并为 bull / bear / chop
+func regimeBucket(regime MarketRegime) string {
+	switch regime {
+	case MarketRegimeStrongBull, MarketRegimeWeakBull:
+		return RegimeBull
+	case MarketRegimeStrongBear, MarketRegimeWeakBear, MarketRegimeExtremeBear:
+		return RegimeBear
+	default:
+		return RegimeChop
+	}
+}
+
+// classifyBenchmarkRegime 用基准的历史价格构建环境特征，交给 classifyMarketRegime 分类；
+// 这里的趋势与动量保留方向，保证下跌行情能被识别为熊市
+func (be *BacktestEngine) classifyBenchmarkRegime(history []MarketData) string {
+	n := len(history)
+	if n < regimeWarmupPeriods {
+		return ""
+	}
+
+	change := func(periods int) float64 {
+		prev := history[n-1-periods].Price
+		if prev <= 0 {
+			return 0
+		}
+		return (history[n-1].Price - prev) / prev
+	}
+
+	state := map[string]float64{
+		"trend_20":      change(20),
+		"momentum_10":   change(10),
+		"volatility_20": be.calculateVolatility(history, 20),
+		"rsi_14":        be.calculateRSI(history, 14),
+		"volume_ratio":  1.0,
+	}
+	return regimeBucket(classifyMarketRegime(state))
+}
+
+// buildRegimeBreakdown 把策略每个周期的收益按当期基准所处的市场环境归类并汇总。
+// 环境只使用该周期开始前的基准数据判定，避免前视偏差。
+func (be *BacktestEngine) buildRegimeBreakdown(returns []DailyReturn, benchmark []MarketData) *RegimeBreakdown {
+	index := make(map[int64]int, len(benchmark))
+	for i, md := range benchmark {
+		index[md.LastUpdated.Unix()] = i
+	}
+
+	type accum struct {
+		periods, wins      int
+		strategy, baseline float64
+	}
+	acc := map[string]*accum{
+		RegimeBull: {strategy: 1, baseline: 1},
+		RegimeBear: {strategy: 1, baseline: 1},
+		RegimeChop: {strategy: 1, baseline: 1},
+	}
+
+	breakdown := &RegimeBreakdown{}
+	for i := 1; i < len(returns); i++ {
+		j, ok := index[returns[i].Date.Unix()]
+		if !ok || j == 0 || benchmark[j-1].Price <= 0 {
+			breakdown.Unclassified++
+			continue
+		}
+		regime := be.classifyBenchmarkRegime(benchmark[:j])
+		if regime == "" {
+			breakdown.Unclassified++
+			continue
+		}
+
+		strategyRet := returns[i].Return
+		benchmarkRet := benchmark[j].Price/benchmark[j-1].Price - 1
+
+		a := acc[regime]
+		a.periods++
+		a.strategy *= 1 + strategyRet
+		a.baseline *= 1 + benchmarkRet
+		if strategyRet > benchmarkRet {
+			a.wins++
+		}
+	}
+
+	for _, regime := range []string{RegimeBull, RegimeBear, RegimeChop} {
+		a := acc[regime]
+		stats := RegimeStats{Regime: regime, Periods: a.periods}
+		if a.periods > 0 {
+			stats.StrategyReturn = a.strategy - 1
+			stats.BenchmarkReturn = a.baseline - 1
+			stats.ExcessReturn = stats.StrategyReturn - stats.BenchmarkReturn
+			stats.WinRate = float64(a.wins) / float64(a.periods)
+		}
+		breakdown.Regimes = append(breakdown.Regimes, stats)
+	}
+	return breakdown
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// regimeTestSeries 生成依次经历上涨、下跌、横盘三段行情的基准序列，
+// 以及只在上涨段赚钱的策略收益（上涨 +2%、下跌 -1%、横盘 0）
+func regimeTestSeries() ([]MarketData, []DailyReturn) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	benchmark := make([]MarketData, 300)
+	returns := make([]DailyReturn, 300)
+	price, value := 100.0, 10000.0
+	for i := range benchmark {
+		strategyRet := 0.0
+		switch {
+		case i == 0:
+		case i < 100:
+			price *= 1.01
+			strategyRet = 0.02
+		case i < 200:
+			price *= 0.99
+			strategyRet = -0.01
+		default:
+			price = benchmark[199].Price * (1 + 0.005*math.Sin(float64(i)*math.Pi/2))
+		}
+		value *= 1 + strategyRet
+		ts := start.Add(time.Duration(i) * time.Hour)
+		benchmark[i] = MarketData{Symbol: "BTCUSDT", Price: price, LastUpdated: ts}
+		returns[i] = DailyReturn{Date: ts, Value: value, Return: strategyRet}
+	}
+	return benchmark, returns
+}
+
+// TestRegimeBreakdownAttribution 每个周期按基准当期环境归类，分环境统计与逐期手工汇总一致
+func TestRegimeBreakdownAttribution(t *testing.T) {
+	be := &BacktestEngine{}
+	benchmark, returns := regimeTestSeries()
+
+	// 典型时点的环境判定
+	for _, tc := range []struct {
+		end  int
+		want string
+	}{{80, RegimeBull}, {160, RegimeBear}, {280, RegimeChop}} {
+		if got := be.classifyBenchmarkRegime(benchmark[:tc.end]); got != tc.want {
+			t.Errorf("截至第%d期应判定为 %s，实际 %s", tc.end, tc.want, got)
+		}
+	}
+
+	// 追加一个与基准时间对不上的周期，应计入未分类
+	returns = append(returns, DailyReturn{Date: benchmark[299].LastUpdated.Add(30 * time.Minute), Return: 0.5})
+
+	breakdown := be.buildRegimeBreakdown(returns, benchmark)
+	if len(breakdown.Regimes) != 3 {
+		t.Fatalf("应包含 bull/bear/chop 三类环境，实际 %d", len(breakdown.Regimes))
+	}
+
+	type expect struct {
+		periods, wins      int
+		strategy, baseline float64
+	}
+	want := map[string]*expect{
+		RegimeBull: {strategy: 1, baseline: 1},
+		RegimeBear: {strategy: 1, baseline: 1},
+		RegimeChop: {strategy: 1, baseline: 1},
+	}
+	unclassified := 1
+	for i := 1; i < len(benchmark); i++ {
+		regime := be.classifyBenchmarkRegime(benchmark[:i])
+		if regime == "" {
+			unclassified++
+			continue
+		}
+		bret := benchmark[i].Price/benchmark[i-1].Price - 1
+		e := want[regime]
+		e.periods++
+		e.strategy *= 1 + returns[i].Return
+		e.baseline *= 1 + bret
+		if returns[i].Return > bret {
+			e.wins++
+		}
+	}
+	if breakdown.Unclassified != unclassified {
+		t.Errorf("未分类周期期望 %d，实际 %d", unclassified, breakdown.Unclassified)
+	}
+
+	total := 0
+	for _, rs := range breakdown.Regimes {
+		e := want[rs.Regime]
+		total += rs.Periods
+		if rs.Periods == 0 {
+			t.Errorf("%s 环境应有周期", rs.Regime)
+			continue
+		}
+		if rs.Periods != e.periods ||
+			math.Abs(rs.StrategyReturn-(e.strategy-1)) > 1e-9 ||
+			math.Abs(rs.BenchmarkReturn-(e.baseline-1)) > 1e-9 ||
+			math.Abs(rs.ExcessReturn-(rs.StrategyReturn-rs.BenchmarkReturn)) > 1e-12 ||
+			math.Abs(rs.WinRate-float64(e.wins)/float64(e.periods)) > 1e-12 {
+			t.Errorf("%s 环境统计不符: %+v", rs.Regime, rs)
+		}
+	}
+	if total+breakdown.Unclassified != len(returns)-1 {
+		t.Errorf("各环境周期数之和 %d + 未分类 %d 应等于 %d", total, breakdown.Unclassified, len(returns)-1)
+	}
+
+	// 策略只在牛市有效：牛市跑赢基准，熊市亏损
+	bull, bear := breakdown.Regimes[0], breakdown.Regimes[1]
+	if bull.ExcessReturn <= 0 || bear.StrategyReturn >= 0 {
+		t.Errorf("牛市应跑赢基准、熊市应亏损: bull=%+v bear=%+v", bull, bear)
+	}
+}
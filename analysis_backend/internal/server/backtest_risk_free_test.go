@@ -0,0 +1,73 @@
+package server
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+// TestSharpeFromPnLsRiskFreeRate 默认无风险利率为 0（结果与换算前一致），非零利率按周期扣除后夏普下降
+func TestSharpeFromPnLsRiskFreeRate(t *testing.T) {
+	be := &BacktestEngine{}
+	pnls := []float64{120, -40, 80, 30, -10, 60}
+	capital := 10000.0
+
+	// 手工计算：收益率 = pnl / capital
+	returns := make([]float64, len(pnls))
+	mean := 0.0
+	for i, p := range pnls {
+		returns[i] = p / capital
+		mean += returns[i]
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+
+	zero := be.calculateSharpeRatioFromPnLs(pnls, capital, BacktestConfig{}.PeriodRiskFreeRate(0))
+	if want := mean * 252 / (std * math.Sqrt(252)); math.Abs(zero-want) > 1e-9 {
+		t.Fatalf("零无风险利率夏普期望 %v，实际 %v", want, zero)
+	}
+	if raw := be.calculateSharpeRatioFromPnLs(pnls, 0, 0); math.Abs(raw-zero) > 1e-9 {
+		t.Errorf("零无风险利率时按收益率与按原始 PnL 计算应一致: %v vs %v", raw, zero)
+	}
+
+	cfg := BacktestConfig{RiskFreeRate: floatPtr(0.05)}
+	withRF := be.calculateSharpeRatioFromPnLs(pnls, capital, cfg.PeriodRiskFreeRate(0))
+	if want := (mean - 0.05/252) * 252 / (std * math.Sqrt(252)); math.Abs(withRF-want) > 1e-9 {
+		t.Fatalf("5%% 无风险利率夏普期望 %v，实际 %v", want, withRF)
+	}
+	if withRF >= zero {
+		t.Errorf("非零无风险利率应降低夏普: %v >= %v", withRF, zero)
+	}
+}
+
+// TestPerformanceMetricsRiskFreeRate 日收益绩效指标默认沿用 2%，配置的利率越高夏普/索提诺越低
+func TestPerformanceMetricsRiskFreeRate(t *testing.T) {
+	values := []float64{10000, 10050, 10020, 10110, 10090, 10180, 10150, 10240}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	metrics := func(rf *float64) PerformanceMetrics {
+		result := &BacktestResult{Config: BacktestConfig{RiskFreeRate: rf}}
+		for i, v := range values {
+			result.DailyReturns = append(result.DailyReturns, DailyReturn{Date: start.AddDate(0, 0, i), Value: v})
+		}
+		(&BacktestEngine{}).calculatePerformanceMetrics(result)
+		return result.Performance
+	}
+
+	def, two := metrics(nil), metrics(floatPtr(0.02))
+	if def.SharpeRatio != two.SharpeRatio || def.SortinoRatio != two.SortinoRatio {
+		t.Errorf("未配置时应等同 2%%: %+v vs %+v", def, two)
+	}
+	zero, high := metrics(floatPtr(0)), metrics(floatPtr(0.5))
+	if !(zero.SharpeRatio > two.SharpeRatio && two.SharpeRatio > high.SharpeRatio) {
+		t.Errorf("夏普应随无风险利率上升而下降: 0%%=%v 2%%=%v 50%%=%v", zero.SharpeRatio, two.SharpeRatio, high.SharpeRatio)
+	}
+	if !(zero.SortinoRatio > high.SortinoRatio) {
+		t.Errorf("索提诺应随无风险利率上升而下降: 0%%=%v 50%%=%v", zero.SortinoRatio, high.SortinoRatio)
+	}
+}
@@ -3971,9 +3971,9 @@ func (be *BacktestEngine) checkCapitalProtection(result *BacktestResult, config
 		calculatedCapital := initialCapital
 		for _, trade := range result.Trades {
 			if trade.Side == "buy" {
-				calculatedCapital -= trade.Quantity * trade.Price * (1 + config.Commission)
+				calculatedCapital -= trade.Quantity * trade.Price * (1 + config.CommissionRate(trade.Symbol))
 			} else if trade.Side == "sell" {
-				calculatedCapital += trade.Quantity * trade.Price * (1 - config.Commission)
+				calculatedCapital += trade.Quantity * trade.Price * (1 - config.CommissionRate(trade.Symbol))
 			}
 		}
 		currentCapital = calculatedCapital
@@ -0,0 +1,53 @@
+package server
+
+import "log"
+
+// 回测结果状态：区分“正常完成”与“一笔未成交”，零成交不再和盈亏平衡混为一谈
+const (
+	BacktestStatusCompleted = "completed"
+	BacktestStatusNoTrades  = "no_trades"
+)
+
+// 零成交原因
+const (
+	NoTradesReasonNoSignals        = "no_signals"        // 数据足够但策略从未触发买卖信号
+	NoTradesReasonInsufficientData = "insufficient_data" // 扣除预热期后没有可评估的周期
+)
+
+// backtestWarmupPeriods 多币种主循环的预热周期数，此前的数据只用于计算指标
+const backtestWarmupPeriods = 50
+
+// evaluablePeriods 扣除预热期后可评估的周期数，按最短的币种数据计算
+func evaluablePeriods(symbolData map[string][]MarketData, warmup int) int {
+	if len(symbolData) == 0 {
+		return 0
+	}
+	minLen := int(^uint(0) >> 1)
+	for _, data := range symbolData {
+		if len(data) < minLen {
+			minLen = len(data)
+		}
+	}
+	if minLen <= warmup {
+		return 0
+	}
+	return minLen - warmup
+}
+
+// markTradeStatus 按成交情况设置回测状态：有成交为 completed；
+// 零成交为 no_trades，并按可评估周期数区分数据不足与无信号
+func markTradeStatus(result *BacktestResult, periods int) {
+	if len(result.Trades) > 0 {
+		result.Status = BacktestStatusCompleted
+		result.StatusReason = ""
+		return
+	}
+
+	result.Status = BacktestStatusNoTrades
+	if periods <= 0 {
+		result.StatusReason = NoTradesReasonInsufficientData
+	} else {
+		result.StatusReason = NoTradesReasonNoSignals
+	}
+	log.Printf("[Backtest] 回测期间没有任何成交: reason=%s, 可评估周期=%d", result.StatusReason, periods)
+}
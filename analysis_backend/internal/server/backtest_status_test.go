@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+)
+
+func flatSeries(n int) []MarketData {
+	data := make([]MarketData, n)
+	for i := range data {
+		data[i] = MarketData{Symbol: "BTCUSDT", Price: 100}
+	}
+	return data
+}
+
+// TestMarkTradeStatus 零成交区分无信号与数据不足；有成交为 completed
+func TestMarkTradeStatus(t *testing.T) {
+	enough := map[string][]MarketData{"BTCUSDT": flatSeries(120), "ETHUSDT": flatSeries(80)}
+	short := map[string][]MarketData{"BTCUSDT": flatSeries(120), "ETHUSDT": flatSeries(backtestWarmupPeriods)}
+
+	if got := evaluablePeriods(enough, backtestWarmupPeriods); got != 30 {
+		t.Errorf("可评估周期期望 30（按最短币种），实际 %d", got)
+	}
+
+	r := &BacktestResult{}
+	markTradeStatus(r, evaluablePeriods(enough, backtestWarmupPeriods))
+	if r.Status != BacktestStatusNoTrades || r.StatusReason != NoTradesReasonNoSignals {
+		t.Errorf("有数据但零成交应为 no_trades/no_signals，实际 %s/%s", r.Status, r.StatusReason)
+	}
+
+	r = &BacktestResult{}
+	markTradeStatus(r, evaluablePeriods(short, backtestWarmupPeriods))
+	if r.Status != BacktestStatusNoTrades || r.StatusReason != NoTradesReasonInsufficientData {
+		t.Errorf("预热后无可评估周期应为 no_trades/insufficient_data，实际 %s/%s", r.Status, r.StatusReason)
+	}
+
+	r = &BacktestResult{Trades: []TradeRecord{{Symbol: "BTCUSDT", Side: "buy"}}, StatusReason: NoTradesReasonNoSignals}
+	markTradeStatus(r, 0)
+	if r.Status != BacktestStatusCompleted || r.StatusReason != "" {
+		t.Errorf("有成交应为 completed 且无原因，实际 %s/%s", r.Status, r.StatusReason)
+	}
+}
+
+// TestNoTradesStatusInAPIResponse 零成交状态随结果序列化，并原样出现在策略回测接口响应中
+func TestNoTradesStatusInAPIResponse(t *testing.T) {
+	s, gdb := newBacktestCacheTestServer(t)
+	uid := uint(3)
+	strategy := pdb.TradingStrategy{UserID: uid, Name: "quiet"}
+	if err := gdb.Create(&strategy).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)
+	cfg := s.convertStrategyToBacktestConfig(strategy, strategy.ID, "BTCUSDT", start, end)
+	result := BacktestResult{Config: cfg, Trades: []TradeRecord{}}
+	markTradeStatus(&result, 10)
+
+	raw, _ := json.Marshal(result)
+	resultStr := string(raw)
+	record := pdb.AsyncBacktestRecord{
+		UserID: uid, Symbol: "BTCUSDT", Strategy: "strategy", StartDate: "2025-01-01", EndDate: "2025-03-31",
+		Fingerprint: BacktestFingerprint(cfg, strategy.Conditions), Status: "completed", Result: &resultStr,
+	}
+	if err := gdb.Create(&record).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	code, out := postStrategyBacktest(t, s, uid, map[string]any{
+		"strategy_id": strategy.ID, "symbol": "BTCUSDT", "start_date": start, "end_date": end,
+	})
+	if code != http.StatusOK {
+		t.Fatalf("期望 200，实际 %d body=%v", code, out)
+	}
+	data, _ := out["data"].(map[string]any)
+	if data == nil || data["status"] != BacktestStatusNoTrades || data["status_reason"] != NoTradesReasonNoSignals {
+		t.Fatalf("响应应包含 no_trades 状态与原因，实际 %v", out["data"])
+	}
+}
@@ -3,58 +3,28 @@ package server
 import (
 	"log"
 	"math"
+
+	"analysis/internal/indicators"
 )
 
 // 注意：calculateTrendStrength函数已移至backtest_risk_manager.go中实现更完整的版本
 
-// calculateVolatility 计算波动率
-func (be *BacktestEngine) calculateVolatility(data []MarketData, period int) float64 {
-	if len(data) < period+1 {
-		return 0.001 // 返回最小波动率
-	}
-
-	// 计算收益率的标准差
-	returns := make([]float64, 0, period)
-	for i := len(data) - period; i < len(data); i++ {
-		if i > 0 {
-			prevPrice := data[i-1].Price
-			currPrice := data[i].Price
-			if prevPrice > 0 {
-				returnRate := (currPrice - prevPrice) / prevPrice
-				// 限制极端收益率（超过±50%可能是异常数据）
-				if math.Abs(returnRate) < 0.5 {
-					returns = append(returns, returnRate)
-				}
-			}
-		}
+// marketDataPrices 提取价格序列
+func marketDataPrices(data []MarketData) []float64 {
+	prices := make([]float64, len(data))
+	for i, md := range data {
+		prices[i] = md.Price
 	}
+	return prices
+}
 
-	if len(returns) < 3 { // 需要至少3个收益率来计算有意义的波动率
+// calculateVolatility 计算波动率（收益率标准差，与实盘推荐共用 indicators.Volatility）
+func (be *BacktestEngine) calculateVolatility(data []MarketData, period int) float64 {
+	if len(data) < period+1 {
 		return 0.001 // 返回最小波动率
 	}
 
-	// 计算标准差
-	mean := 0.0
-	for _, ret := range returns {
-		mean += ret
-	}
-	mean /= float64(len(returns))
-
-	variance := 0.0
-	for _, ret := range returns {
-		variance += (ret - mean) * (ret - mean)
-	}
-	variance /= float64(len(returns) - 1)
-
-	volatility := math.Sqrt(variance)
-
-	// 调试：如果波动率为0，记录详细信息
-	if volatility == 0.0 && len(returns) > 0 {
-		log.Printf("[DEBUG_VOLATILITY] 波动率为0，收益率数量=%d，样本收益率: %.6f, %.6f, %.6f...",
-			len(returns), returns[0], returns[1], returns[2])
-		// 确保波动率不为0，至少返回一个最小值
-		volatility = 0.0001 // 最小波动率0.01%
-	}
+	volatility := indicators.Volatility(marketDataPrices(data), period)
 
 	// 数据验证：波动率不应该超过合理范围
 	if volatility > 1.0 { // 波动率超过100%是不合理的
@@ -115,63 +85,9 @@ func (be *BacktestEngine) calculateVolumeTrend(data []MarketData, period int) fl
 	return slope
 }
 
-// calculateRSI 计算RSI指标
+// calculateRSI 计算RSI指标（与实盘推荐共用 indicators.RSI）
 func (be *BacktestEngine) calculateRSI(data []MarketData, period int) float64 {
-	if len(data) < period+1 {
-		return 50 // 默认中性值
-	}
-
-	// 检查数据质量：如果所有价格都相同，返回50
-	allSame := true
-	firstPrice := data[0].Price
-	for _, md := range data {
-		if md.Price != firstPrice {
-			allSame = false
-			break
-		}
-	}
-	if allSame {
-		return 50 // 数据无变化，返回中性RSI
-	}
-
-	gains := make([]float64, 0, period)
-	losses := make([]float64, 0, period)
-
-	// 计算价格变化
-	for i := len(data) - period - 1; i < len(data)-1; i++ {
-		change := data[i+1].Price - data[i].Price
-		if change > 0 {
-			gains = append(gains, change)
-			losses = append(losses, 0)
-		} else {
-			gains = append(gains, 0)
-			losses = append(losses, -change)
-		}
-	}
-
-	// 计算平均涨幅和跌幅
-	avgGain := 0.0
-	avgLoss := 0.0
-
-	for i := 0; i < len(gains); i++ {
-		avgGain += gains[i]
-		avgLoss += losses[i]
-	}
-
-	if len(gains) > 0 {
-		avgGain /= float64(len(gains))
-		avgLoss /= float64(len(losses))
-	}
-
-	// 计算RSI
-	if avgLoss == 0 {
-		return 100
-	}
-
-	rs := avgGain / avgLoss
-	rsi := 100 - (100 / (1 + rs))
-
-	return rsi
+	return indicators.RSI(marketDataPrices(data), period)
 }
 
 // calculateMACDSignal 计算MACD信号
@@ -218,32 +134,12 @@ func (be *BacktestEngine) calculateMACDSignal(data []MarketData) float64 {
 
 // calculateEMAFromData 从MarketData计算EMA
 func (be *BacktestEngine) calculateEMAFromData(data []MarketData, period int) float64 {
-	if len(data) < period {
-		return 0
-	}
-
-	prices := make([]float64, len(data))
-	for i, md := range data {
-		prices[i] = md.Price
-	}
-
-	return be.calculateEMA(prices[len(prices)-period:], period)
+	return indicators.EMA(marketDataPrices(data), period)
 }
 
 // calculateEMA 计算指数移动平均
 func (be *BacktestEngine) calculateEMA(values []float64, period int) float64 {
-	if len(values) == 0 {
-		return 0
-	}
-
-	multiplier := 2.0 / (float64(period) + 1.0)
-	ema := values[0]
-
-	for i := 1; i < len(values); i++ {
-		ema = (values[i] * multiplier) + (ema * (1 - multiplier))
-	}
-
-	return ema
+	return indicators.EMA(values, period)
 }
 
 // 注意：calculateSignalConsistency函数已移至backtest_strategy_executor.go中实现更完整的版本
@@ -549,32 +445,17 @@ func (be *BacktestEngine) calculateResistanceLevel(data []MarketData) float64 {
 	return resistanceStrength
 }
 
-// calculateBollingerPosition 计算布林带位置
+// calculateBollingerPosition 计算布林带位置：以整段数据为窗口，(价格-中轨)/(2σ)，标准化到[-1, 1]范围
 func (be *BacktestEngine) calculateBollingerPosition(data []MarketData) float64 {
 	if len(data) < 20 {
 		return 0
 	}
 
-	// 计算20日简单移动平均
-	sum := 0.0
-	for _, md := range data {
-		sum += md.Price
-	}
-	sma := sum / float64(len(data))
-
-	// 计算标准差
-	variance := 0.0
-	for _, md := range data {
-		variance += (md.Price - sma) * (md.Price - sma)
-	}
-	stdDev := math.Sqrt(variance / float64(len(data)))
-
-	if stdDev == 0 {
+	bands := indicators.Bollinger(marketDataPrices(data), len(data), 2)
+	if bands.Upper == bands.Middle {
 		return 0
 	}
-
-	currentPrice := data[len(data)-1].Price
-	return (currentPrice - sma) / (2 * stdDev) // 标准化到[-1, 1]范围
+	return (data[len(data)-1].Price - bands.Middle) / (bands.Upper - bands.Middle)
 }
 
 // calculateStochasticK 计算随机指标K值
@@ -698,44 +579,7 @@ func (be *BacktestEngine) calculateVolumeRSI(data []MarketData) float64 {
 
 // calculateRSIFromValues 从数值数组计算RSI
 func (be *BacktestEngine) calculateRSIFromValues(values []float64, period int) float64 {
-	if len(values) < period+1 {
-		return 50
-	}
-
-	gains := 0.0
-	losses := 0.0
-
-	// 计算初始平均值
-	for i := 1; i <= period; i++ {
-		change := values[i] - values[i-1]
-		if change > 0 {
-			gains += change
-		} else {
-			losses -= change
-		}
-	}
-
-	avgGain := gains / float64(period)
-	avgLoss := losses / float64(period)
-
-	// 计算后续值
-	for i := period + 1; i < len(values); i++ {
-		change := values[i] - values[i-1]
-		if change > 0 {
-			avgGain = (avgGain*13 + change) / 14
-			avgLoss = (avgLoss*13 + 0) / 14
-		} else {
-			avgGain = (avgGain*13 + 0) / 14
-			avgLoss = (avgLoss*13 - change) / 14
-		}
-	}
-
-	if avgLoss == 0 {
-		return 100
-	}
-
-	rs := avgGain / avgLoss
-	return 100 - (100 / (1 + rs))
+	return indicators.RSI(values, period)
 }
 
 // calculateVolumePriceTrend 计算成交量价格趋势(VPT)，标准化为-1到1之间
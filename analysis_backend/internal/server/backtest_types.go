@@ -1,6 +1,9 @@
 package server
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // MarketRegime 市场环境枚举
 type MarketRegime int
@@ -125,10 +128,86 @@ type BacktestConfig struct {
 	MaxConsecutiveLosses int       `json:"max_consecutive_losses"` // 最大连续亏损次数
 	MinCapitalRatio      float64   `json:"min_capital_ratio"`      // 最低资本比例
 
+	// 最大持有周期：持仓达到该周期数后不论信号强制平仓（exit_reason = max_hold），0 表示不启用；
+	// 与 MaxHoldTime 不同，后者只在亏损时超时平仓
+	MaxHoldingPeriod int `json:"max_holding_period,omitempty"`
+
+	// 组合级限制
+	MaxConcurrentPositions int     `json:"max_concurrent_positions,omitempty"` // 同时持仓的最大币种数，0 表示不限制
+	MaxGrossExposure       float64 `json:"max_gross_exposure,omitempty"`       // 最大总敞口（占初始资金的倍数），0 表示不限制
+
+	// 组合再平衡：每 RebalanceInterval 个周期把持仓调整到目标权重，0 表示不启用
+	RebalanceInterval  int     `json:"rebalance_interval,omitempty"`
+	RebalanceWeighting string  `json:"rebalance_weighting,omitempty"` // equal_weight（默认）/ score_weighted
+	RebalanceTolerance float64 `json:"rebalance_tolerance,omitempty"` // 权重偏离不超过该值时不调整
+
+	// 数据预处理（缺口填充、坏点处理、剔除缺失过多的币种），为空时不处理
+	Preprocessing *DataPreprocessingConfig `json:"preprocessing,omitempty"`
+	// 数据质量下限（0-1）：回测前按完整度、缺口、跳点、停滞行为各币种评分，低于该值的币种不参与回测；0 表示只评分不剔除
+	MinDataQuality float64 `json:"min_data_quality,omitempty"`
+
+	// 年化无风险利率（如 0.03 表示 3%），按 252 个周期折算后从收益中扣除，用于夏普/索提诺；
+	// 未设置时沿用各指标原有假设（按交易盈亏计算的夏普为 0，日收益绩效指标为 2%）
+	RiskFreeRate *float64 `json:"risk_free_rate,omitempty"`
+
+	// 手续费覆盖：优先按币种，其次按市场（spot/futures），都未配置时使用 Commission
+	Market             string             `json:"market,omitempty"`               // 回测市场：spot / futures，为空视为 spot
+	CommissionByMarket map[string]float64 `json:"commission_by_market,omitempty"` // 按市场覆盖的手续费率
+	CommissionBySymbol map[string]float64 `json:"commission_by_symbol,omitempty"` // 按币种覆盖的手续费率
+	CommissionFree     bool               `json:"commission_free,omitempty"`      // 免手续费：忽略以上所有费率
+
+	// 合约回测（Market=futures）：按杠杆占用保证金，保证金耗尽强平，持仓期间结算资金费（取自同步的资金费率）；
+	// 只作用于多币种回测主循环，用户策略回测仍按现货计算
+	Leverage              float64 `json:"leverage,omitempty"`                // 杠杆倍数，<=1 表示不加杠杆
+	MaintenanceMarginRate float64 `json:"maintenance_margin_rate,omitempty"` // 维持保证金率（占名义价值），0 表示保证金耗尽才强平
+
 	// 用户策略相关字段
 	UserStrategyID uint `json:"user_strategy_id,omitempty"` // 用户策略ID，为0表示普通回测
 }
 
+// CommissionRate 返回某币种适用的手续费率：免手续费 > 币种覆盖 > 市场覆盖 > 统一费率
+func (c BacktestConfig) CommissionRate(symbol string) float64 {
+	if c.CommissionFree {
+		return 0
+	}
+	if rate, ok := lookupFold(c.CommissionBySymbol, symbol); ok {
+		return rate
+	}
+	market := c.Market
+	if market == "" {
+		market = "spot"
+	}
+	if rate, ok := lookupFold(c.CommissionByMarket, market); ok {
+		return rate
+	}
+	return c.Commission
+}
+
+// tradingPeriodsPerYear 年化换算使用的周期数（按 252 个交易日）
+const tradingPeriodsPerYear = 252
+
+// PeriodRiskFreeRate 每周期无风险利率：年化利率 / 252；未配置 RiskFreeRate 时使用 defaultAnnual
+func (c BacktestConfig) PeriodRiskFreeRate(defaultAnnual float64) float64 {
+	annual := defaultAnnual
+	if c.RiskFreeRate != nil {
+		annual = *c.RiskFreeRate
+	}
+	return annual / tradingPeriodsPerYear
+}
+
+// lookupFold 忽略大小写查找 map 中的值
+func lookupFold(m map[string]float64, key string) (float64, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
 // SymbolPerformance 单个币种的性能统计
 type SymbolPerformance struct {
 	Symbol        string  `json:"symbol"`
@@ -159,6 +238,14 @@ type BacktestResult struct {
 	WinRate         float64                       `json:"win_rate"`
 	MaxDrawdown     float64                       `json:"max_drawdown"`
 	SharpeRatio     float64                       `json:"sharpe_ratio"`
+	Rebalances      []RebalanceEvent              `json:"rebalances,omitempty"`    // 再平衡记录
+	Turnover        float64                       `json:"turnover,omitempty"`      // 累计换手率
+	DataCleaning    []DataCleaningAction          `json:"data_cleaning,omitempty"` // 数据预处理记录
+	DataQuality     []DataQualityScore            `json:"data_quality,omitempty"`  // 回测前各币种数据质量评分（含被剔除的币种）
+	Status          string                        `json:"status,omitempty"`        // completed / no_trades
+	StatusReason    string                        `json:"status_reason,omitempty"` // 零成交原因：no_signals / insufficient_data
+	FundingCost     float64                       `json:"funding_cost,omitempty"`  // 合约回测累计支付的资金费（负数为净收入）
+	Liquidations    int                           `json:"liquidations,omitempty"`  // 合约回测强平次数
 }
 
 // BacktestSummary 回测摘要
@@ -270,6 +357,8 @@ type MonteCarloAnalysis struct {
 	Simulations         int                    `json:"simulations"`      // 模拟次数
 	ConfidenceLevel     float64                `json:"confidence_level"` // 置信水平
 	BootstrapSize       int                    `json:"bootstrap_size"`   // 自举样本大小
+	Method              string                 `json:"method"`           // bootstrap（默认）/ shuffle
+	Seed                int64                  `json:"seed"`             // 随机种子，0 使用默认种子
 	Distribution        MonteCarloDistribution `json:"distribution"`
 	Scenarios           []MonteCarloScenario   `json:"scenarios"`
 	ConfidenceIntervals []ConfidenceInterval   `json:"confidence_intervals"`
@@ -277,7 +366,16 @@ type MonteCarloAnalysis struct {
 
 // MonteCarloResult 蒙特卡洛分析结果
 type MonteCarloResult struct {
-	Analysis MonteCarloAnalysis `json:"analysis"`
+	Analysis            MonteCarloAnalysis `json:"analysis"`
+	Trades              int                `json:"trades"`                // 参与重采样的已实现交易数
+	OriginalReturn      float64            `json:"original_return"`       // 原始交易顺序的复利收益
+	OriginalMaxDrawdown float64            `json:"original_max_drawdown"` // 原始交易顺序的最大回撤
+	MedianReturn        float64            `json:"median_return"`
+	ReturnP5            float64            `json:"return_p5"`
+	ReturnP95           float64            `json:"return_p95"`
+	MedianMaxDrawdown   float64            `json:"median_max_drawdown"`
+	MaxDrawdownP5       float64            `json:"max_drawdown_p5"`
+	MaxDrawdownP95      float64            `json:"max_drawdown_p95"`
 }
 
 // MonteCarloDistribution 蒙特卡洛分布
@@ -43,12 +43,23 @@ func CacheMiddleware(cache pdb.CacheInterface, cacheType pdb.CacheType, ttl time
 			key = defaultCacheKey(c)
 		}
 
+		// 根据缓存类型获取 TTL（如果 cacheType 为 -1，使用传入的 ttl）
+		var cacheTTL time.Duration
+		if cacheType < 0 {
+			cacheTTL = ttl
+		} else {
+			cacheTTL = pdb.DefaultCacheTTL.GetTTL(cacheType)
+		}
+		// 客户端缓存时间与服务端缓存 TTL 保持一致；只用于 200 响应
+		cacheControl := cacheControlValue(c, cacheTTL)
+
 		// 尝试从缓存获取
 		ctx := c.Request.Context()
 		cached, err := cache.Get(ctx, key)
 		if err == nil && len(cached) > 0 {
 			// 设置响应头
 			c.Header("X-Cache", "HIT")
+			c.Header("Cache-Control", cacheControl)
 			c.Data(http.StatusOK, "application/json", cached)
 			c.Abort()
 
@@ -71,21 +82,15 @@ func CacheMiddleware(cache pdb.CacheInterface, cacheType pdb.CacheType, ttl time
 		w := &cacheResponseWriter{
 			ResponseWriter: c.Writer,
 			body:           make([]byte, 0),
+			cacheControl:   cacheControl,
 		}
 		c.Writer = w
 
 		c.Next()
+		w.setCacheControl() // 处理器没有写响应体时，头部由 gin 在返回后写出
 
-		// 只缓存成功的响应（状态码 200）
-		if c.Writer.Status() == http.StatusOK && len(w.body) > 0 {
-			// 根据缓存类型获取 TTL（如果 cacheType 为 -1，使用传入的 ttl）
-			var cacheTTL time.Duration
-			if cacheType < 0 {
-				cacheTTL = ttl
-			} else {
-				cacheTTL = pdb.DefaultCacheTTL.GetTTL(cacheType)
-			}
-
+		// 只缓存成功的响应（状态码 200），处理器标记跳过的（如降级数据）除外
+		if c.Writer.Status() == http.StatusOK && len(w.body) > 0 && !c.GetBool(cacheSkipKey) {
 			// 优化：使用协程池异步写入缓存，避免创建过多 goroutine
 			cacheKey := key
 			cacheData := make([]byte, len(w.body))
@@ -110,17 +115,55 @@ func CacheMiddleware(cache pdb.CacheInterface, cacheType pdb.CacheType, ttl time
 	}
 }
 
-// cacheResponseWriter 用于捕获响应内容
+// cacheControlValue 生成 Cache-Control 头；带认证信息的请求只允许客户端私有缓存，避免被共享代理缓存
+func cacheControlValue(c *gin.Context, ttl time.Duration) string {
+	scope := "public"
+	if c.GetHeader("Authorization") != "" {
+		scope = "private"
+	}
+	return fmt.Sprintf("%s, max-age=%d", scope, int(ttl/time.Second))
+}
+
+// cacheSkipKey 处理器在 gin.Context 上设置该键后，本次响应不写入缓存
+const cacheSkipKey = "cache_skip"
+
+// cacheResponseWriter 用于捕获响应内容，并在写出头部前按状态码设置 Cache-Control
 type cacheResponseWriter struct {
 	gin.ResponseWriter
-	body []byte
+	body         []byte
+	cacheControl string // 200 响应使用的 Cache-Control
+}
+
+// setCacheControl 头部写出前调用：200 允许客户端缓存，其他状态码 no-store，避免错误响应被缓存；
+// 处理器已自行设置 Cache-Control 时（如降级数据）保持不变
+func (w *cacheResponseWriter) setCacheControl() {
+	if w.Written() || w.Header().Get("Cache-Control") != "" {
+		return
+	}
+	if w.Status() == http.StatusOK {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	} else {
+		w.Header().Set("Cache-Control", "no-store")
+	}
+}
+
+func (w *cacheResponseWriter) WriteHeaderNow() {
+	w.setCacheControl()
+	w.ResponseWriter.WriteHeaderNow()
 }
 
 func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	w.setCacheControl()
 	w.body = append(w.body, b...)
 	return w.ResponseWriter.Write(b)
 }
 
+func (w *cacheResponseWriter) WriteString(s string) (int, error) {
+	w.setCacheControl()
+	w.body = append(w.body, s...)
+	return w.ResponseWriter.WriteString(s)
+}
+
 // defaultCacheKey 默认缓存键生成器（优化：使用字符串构建器）
 func defaultCacheKey(c *gin.Context) string {
 	// 使用 URL 路径和查询参数生成键
@@ -952,9 +952,16 @@ func (alg *CoinSelectionAlgorithm) applyRiskFiltering(scores map[string]*CoinSco
 		filtered = append(filtered, score)
 	}
 
-	// 按总分排序
+	// 按总分排序（同分按市值、交易对，保证顺序确定）
+	marketCap := func(symbol string) *float64 {
+		if f := features[symbol]; f != nil {
+			return f.MarketCap
+		}
+		return nil
+	}
 	sort.Slice(filtered, func(i, j int) bool {
-		return filtered[i].TotalScore > filtered[j].TotalScore
+		a, b := filtered[i], filtered[j]
+		return recommendationRanksBefore(a.TotalScore, b.TotalScore, marketCap(a.Symbol), marketCap(b.Symbol), a.Symbol, b.Symbol)
 	})
 
 	// 限制候选数量
@@ -9,7 +9,10 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"analysis/internal/db"
@@ -21,8 +24,22 @@ type CoinCapMarketDataSyncService struct {
 	baseURL           string
 	apiKey            string
 	httpClient        *http.Client
+
+	// 分页拉取参数
+	pageSize        int           // 每页资产数
+	workers         int           // 并发拉取的页数上限，1 即串行
+	requestInterval time.Duration // 相邻两次请求的最小间隔（所有 worker 共享），用于遵守 API 限速
+	maxPages        int           // 最多拉取的页数，防止接口异常时无限翻页
 }
 
+// CoinCap 分页拉取默认参数
+const (
+	defaultCoinCapPageSize        = 500
+	defaultCoinCapFetchWorkers    = 4
+	defaultCoinCapRequestInterval = 250 * time.Millisecond
+	defaultCoinCapMaxPages        = 40
+)
+
 // NewCoinCapMarketDataSyncService 创建CoinCap市值数据同步服务
 func NewCoinCapMarketDataSyncService(marketDataService *db.CoinCapMarketDataService, apiKey string) *CoinCapMarketDataSyncService {
 	return &CoinCapMarketDataSyncService{
@@ -32,25 +49,158 @@ func NewCoinCapMarketDataSyncService(marketDataService *db.CoinCapMarketDataServ
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		pageSize:        defaultCoinCapPageSize,
+		workers:         defaultCoinCapFetchWorkers,
+		requestInterval: defaultCoinCapRequestInterval,
+		maxPages:        defaultCoinCapMaxPages,
 	}
 }
 
+// SetIngestOptions 调整分页拉取参数，非正数保持原值（interval 为 0 表示不限速）
+func (s *CoinCapMarketDataSyncService) SetIngestOptions(pageSize, workers int, interval time.Duration) {
+	if pageSize > 0 {
+		s.pageSize = pageSize
+	}
+	if workers > 0 {
+		s.workers = workers
+	}
+	if interval >= 0 {
+		s.requestInterval = interval
+	}
+}
+
+// coinCapPage 一页拉取结果
+type coinCapPage struct {
+	index  int
+	assets []CoinCapAssetItem
+	err    error
+}
+
 // SyncAllMarketData 同步所有CoinCap市值数据
+// 多个 worker 按页并发拉取（共享请求间隔限速），由当前 goroutine 作为唯一写入方按页序逐页入库，
+// 因此写入顺序与串行拉取一致；遇到不满一页的响应即认为到达末页。
 func (s *CoinCapMarketDataSyncService) SyncAllMarketData(ctx context.Context) error {
-	log.Printf("[coincap-market-sync] 开始同步CoinCap市值数据...")
+	log.Printf("[coincap-market-sync] 开始同步CoinCap市值数据（每页 %d 条，并发 %d）...", s.pageSize, s.workers)
 
-	// 获取所有资产数据
-	assets, err := s.fetchAllAssets(ctx)
-	if err != nil {
-		return fmt.Errorf("获取CoinCap资产数据失败: %w", err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// lastPage 为已知末页之后的页号，只会被调小
+	var lastPage atomic.Int64
+	lastPage.Store(int64(s.maxPages))
+	markLastPage := func(next int) {
+		for {
+			cur := lastPage.Load()
+			if int64(next) >= cur || lastPage.CompareAndSwap(cur, int64(next)) {
+				return
+			}
+		}
+	}
+
+	var throttle <-chan time.Time
+	if s.requestInterval > 0 {
+		ticker := time.NewTicker(s.requestInterval)
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	pageIndexes := make(chan int)
+	go func() {
+		defer close(pageIndexes)
+		for p := 0; int64(p) < lastPage.Load(); p++ {
+			select {
+			case pageIndexes <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan coinCapPage)
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range pageIndexes {
+				if int64(p) >= lastPage.Load() {
+					continue
+				}
+				if throttle != nil {
+					select {
+					case <-throttle:
+					case <-ctx.Done():
+						return
+					}
+				}
+				assets, err := s.fetchAssetsPage(ctx, p*s.pageSize, s.pageSize)
+				if err == nil && len(assets) < s.pageSize {
+					markLastPage(p + 1)
+				}
+				results <- coinCapPage{index: p, assets: assets, err: err}
+			}
+		}()
 	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-	log.Printf("[coincap-market-sync] 获取到 %d 个资产，开始保存市值数据...", len(assets))
+	// 唯一写入方：按页序写入，乱序到达的页先暂存；末页之后的页（含其错误）直接忽略
+	var firstErr error
+	pending := make(map[int]coinCapPage)
+	seen := make(map[string]bool)
+	next, saved := 0, 0
+	for page := range results {
+		if firstErr != nil {
+			continue
+		}
+		pending[page.index] = page
+		for firstErr == nil && int64(next) < lastPage.Load() {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if ready.err != nil {
+				firstErr = fmt.Errorf("获取CoinCap资产数据失败（第 %d 页）: %w", next+1, ready.err)
+				cancel()
+				break
+			}
+			n, err := s.savePage(ctx, ready.assets, seen)
+			if err != nil {
+				firstErr = fmt.Errorf("批量保存市值数据失败（第 %d 页）: %w", next+1, err)
+				cancel()
+				break
+			}
+			saved += n
+			next++
+			log.Printf("[coincap-market-sync] 第 %d 页完成: 获取 %d 条，保存 %d 条，累计 %d 条", next, len(ready.assets), n, saved)
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if int64(next) < lastPage.Load() {
+		return fmt.Errorf("CoinCap分页拉取中断: 仅完成 %d 页", next)
+	}
+	if next == s.maxPages {
+		log.Printf("[coincap-market-sync] 已达到最大页数 %d，剩余资产未拉取", s.maxPages)
+	}
+
+	log.Printf("[coincap-market-sync] 市值数据同步完成，共 %d 页，保存了 %d 条记录", next, saved)
+	return nil
+}
 
-	// 转换为数据库模型并批量保存
+// savePage 转换并保存一页资产；翻页期间排名变动可能导致同一资产出现在相邻两页，按 AssetID 去重
+func (s *CoinCapMarketDataSyncService) savePage(ctx context.Context, assets []CoinCapAssetItem, seen map[string]bool) (int, error) {
 	dataList := make([]*db.CoinCapMarketData, 0, len(assets))
 	for _, asset := range assets {
-		data := &db.CoinCapMarketData{
+		if seen[asset.ID] {
+			continue
+		}
+		seen[asset.ID] = true
+		dataList = append(dataList, &db.CoinCapMarketData{
 			Symbol:            strings.ToUpper(strings.TrimSuffix(asset.Symbol, "USDT")),
 			AssetID:           asset.ID,
 			Name:              asset.Name,
@@ -64,33 +214,30 @@ func (s *CoinCapMarketDataSyncService) SyncAllMarketData(ctx context.Context) er
 			VWAP24Hr:          asset.VWAP24Hr,
 			Explorer:          asset.Explorer,
 			UpdatedAt:         time.Now(),
-		}
-		dataList = append(dataList, data)
+		})
+	}
+	if len(dataList) == 0 {
+		return 0, nil
 	}
-
-	// 批量保存到数据库
 	if err := s.marketDataService.BatchUpsertMarketData(ctx, dataList); err != nil {
-		return fmt.Errorf("批量保存市值数据失败: %w", err)
+		return 0, err
 	}
-
-	log.Printf("[coincap-market-sync] 市值数据同步完成，保存了 %d 条记录", len(dataList))
-	return nil
+	return len(dataList), nil
 }
 
-// fetchAllAssets 从CoinCap API获取所有资产
-func (s *CoinCapMarketDataSyncService) fetchAllAssets(ctx context.Context) ([]CoinCapAssetItem, error) {
+// fetchAssetsPage 从CoinCap API获取一页资产
+func (s *CoinCapMarketDataSyncService) fetchAssetsPage(ctx context.Context, offset, limit int) ([]CoinCapAssetItem, error) {
 	u := fmt.Sprintf("%s/assets", s.baseURL)
 
 	// 构建查询参数
 	q := url.Values{}
-	q.Set("limit", "2000") // CoinCap最多支持2000个资产
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
 	if s.apiKey != "" {
 		q.Set("apiKey", s.apiKey)
 	}
 	u += "?" + q.Encode()
 
-	log.Printf("[coincap-market-sync] 请求URL: %s", u)
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %w", err)
@@ -110,14 +257,12 @@ func (s *CoinCapMarketDataSyncService) fetchAllAssets(ctx context.Context) ([]Co
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API请求失败: %s => %d, body: %s", u, resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("API请求失败: offset=%d => %d, body: %s", offset, resp.StatusCode, string(bodyBytes))
 	}
 
 	var response CoinCapAssetResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
-
-	log.Printf("[coincap-market-sync] 成功获取 %d 个资产数据", len(response.Data))
 	return response.Data, nil
 }
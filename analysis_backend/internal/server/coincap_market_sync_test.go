@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// coinCapTestAssets 23 个资产：第 9、10 位是同一资产（模拟翻页时排名变动），
+// 第 2、17 位的交易符号相同（入库时后者覆盖前者，用于校验写入顺序）
+func coinCapTestAssets() []CoinCapAssetItem {
+	assets := make([]CoinCapAssetItem, 0, 23)
+	for i := 0; i < 23; i++ {
+		id := fmt.Sprintf("asset-%d", i)
+		symbol := fmt.Sprintf("C%d", i)
+		switch i {
+		case 10:
+			id, symbol = "asset-9", "C9"
+		case 17:
+			symbol = "C2"
+		}
+		assets = append(assets, CoinCapAssetItem{
+			ID:        id,
+			Rank:      strconv.Itoa(i + 1),
+			Symbol:    symbol,
+			Name:      "Coin " + id,
+			Price:     strconv.Itoa(1000 - i),
+			MarketCap: strconv.Itoa((1000 - i) * 1000),
+		})
+	}
+	return assets
+}
+
+// newCoinCapMockAPI 按 limit/offset 分页返回资产；越靠前的页响应越慢，使并发拉取的页乱序到达
+func newCoinCapMockAPI(t *testing.T, assets []CoinCapAssetItem) (*httptest.Server, *int32) {
+	t.Helper()
+	var inFlight, maxInFlight int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cur := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			old := atomic.LoadInt32(&maxInFlight)
+			if cur <= old || atomic.CompareAndSwapInt32(&maxInFlight, old, cur) {
+				break
+			}
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		time.Sleep(time.Duration(len(assets)-offset) * time.Millisecond)
+
+		page := []CoinCapAssetItem{}
+		if offset < len(assets) {
+			end := offset + limit
+			if end > len(assets) {
+				end = len(assets)
+			}
+			page = assets[offset:end]
+		}
+		_ = json.NewEncoder(w).Encode(CoinCapAssetResponse{Data: page})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &maxInFlight
+}
+
+// syncCoinCapRows 用给定并发数同步到独立的内存库，返回按符号排序的入库行（忽略自增ID和时间）
+func syncCoinCapRows(t *testing.T, baseURL string, workers int) []pdb.CoinCapMarketData {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.CoinCapMarketData{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	svc := NewCoinCapMarketDataSyncService(pdb.NewCoinCapMarketDataService(gdb), "")
+	svc.baseURL = baseURL
+	svc.SetIngestOptions(5, workers, 0)
+	if err := svc.SyncAllMarketData(context.Background()); err != nil {
+		t.Fatalf("workers=%d 同步失败: %v", workers, err)
+	}
+
+	var rows []pdb.CoinCapMarketData
+	if err := gdb.Order("symbol").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := range rows {
+		rows[i].ID = 0
+		rows[i].UpdatedAt = time.Time{}
+		rows[i].CreatedAt = time.Time{}
+	}
+	return rows
+}
+
+// TestCoinCapConcurrentSyncMatchesSerial 并发分页拉取与串行拉取写入的行完全一致
+func TestCoinCapConcurrentSyncMatchesSerial(t *testing.T) {
+	assets := coinCapTestAssets()
+	srv, maxInFlight := newCoinCapMockAPI(t, assets)
+
+	serial := syncCoinCapRows(t, srv.URL, 1)
+	if got := atomic.LoadInt32(maxInFlight); got != 1 {
+		t.Fatalf("串行模式不应有并发请求，实际最大并发 %d", got)
+	}
+	atomic.StoreInt32(maxInFlight, 0)
+	concurrent := syncCoinCapRows(t, srv.URL, 4)
+	if got := atomic.LoadInt32(maxInFlight); got < 2 || got > 4 {
+		t.Errorf("并发模式最大并发应在 2-4 之间，实际 %d", got)
+	}
+
+	// 23 个资产去掉 1 个重复 ID、1 个重复符号
+	if len(serial) != 21 {
+		t.Fatalf("串行入库应为 21 行，实际 %d", len(serial))
+	}
+	if !reflect.DeepEqual(serial, concurrent) {
+		t.Errorf("并发与串行入库结果不一致:\nserial=%+v\nconcurrent=%+v", serial, concurrent)
+	}
+	for _, row := range concurrent {
+		if row.Symbol == "C2" && row.AssetID != "asset-17" {
+			t.Errorf("相同符号应由后一页覆盖，实际 %+v", row)
+		}
+	}
+}
+
+// TestCoinCapSyncPageError 中间页失败时整体返回错误
+func TestCoinCapSyncPageError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("offset") == "5" {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(CoinCapAssetResponse{Data: coinCapTestAssets()[:5]})
+	}))
+	defer srv.Close()
+
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.AutoMigrate(&pdb.CoinCapMarketData{}); err != nil {
+		t.Fatal(err)
+	}
+	svc := NewCoinCapMarketDataSyncService(pdb.NewCoinCapMarketDataService(gdb), "")
+	svc.baseURL = srv.URL
+	svc.SetIngestOptions(5, 3, 0)
+	if err := svc.SyncAllMarketData(context.Background()); err == nil {
+		t.Fatal("第 2 页失败时应返回错误")
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -242,3 +244,135 @@ func (s *CoinCapAssetSyncService) SearchAssets(ctx context.Context, query string
 
 	return results, nil
 }
+
+// CoinCapCoverageReport 交易对与CoinCap映射的覆盖率报告
+type CoinCapCoverageReport struct {
+	QuoteAsset    string                  `json:"quote_asset,omitempty"`
+	TotalSymbols  int64                   `json:"total_symbols"`  // 参与统计的基础资产数
+	MappedSymbols int64                   `json:"mapped_symbols"` // 已有映射的基础资产数
+	Coverage      float64                 `json:"coverage"`       // 映射覆盖率 0-1
+	Unmapped      []CoinCapUnmappedSymbol `json:"unmapped"`
+}
+
+// CoinCapUnmappedSymbol 缺少映射的基础资产及候选映射
+type CoinCapUnmappedSymbol struct {
+	Symbol      string                   `json:"symbol"`
+	Suggestions []db.CoinCapAssetMapping `json:"suggestions,omitempty"`
+}
+
+// CoverageReport 统计交易对基础资产的CoinCap映射覆盖情况；没有映射的资产也就没有市值数据，
+// 这是回测中 getHistoricalMarketCap 查不到数据的常见原因。maxSuggestions > 0 时为每个未映射资产给出模糊匹配候选
+func (s *CoinCapAssetSyncService) CoverageReport(ctx context.Context, quoteAsset string, maxSuggestions int) (*CoinCapCoverageReport, error) {
+	unmapped, total, err := s.db.GetUnmappedBaseAssets(ctx, quoteAsset)
+	if err != nil {
+		return nil, fmt.Errorf("查询未映射交易对失败: %w", err)
+	}
+
+	report := &CoinCapCoverageReport{
+		QuoteAsset:    strings.ToUpper(strings.TrimSpace(quoteAsset)),
+		TotalSymbols:  total,
+		MappedSymbols: total - int64(len(unmapped)),
+		Unmapped:      make([]CoinCapUnmappedSymbol, 0, len(unmapped)),
+	}
+	if total > 0 {
+		report.Coverage = float64(report.MappedSymbols) / float64(total)
+	}
+
+	var mappings []db.CoinCapAssetMapping
+	if maxSuggestions > 0 && len(unmapped) > 0 {
+		if mappings, err = s.db.GetAllMappings(ctx); err != nil {
+			return nil, fmt.Errorf("获取映射数据失败: %w", err)
+		}
+	}
+	for _, symbol := range unmapped {
+		report.Unmapped = append(report.Unmapped, CoinCapUnmappedSymbol{
+			Symbol:      symbol,
+			Suggestions: suggestCoinCapMappings(symbol, mappings, maxSuggestions),
+		})
+	}
+	return report, nil
+}
+
+// suggestCoinCapMappings 为未映射的交易符号挑选候选映射，按匹配程度、CoinCap排名排序
+// 匹配规则：去掉 1000/1M 等面值前缀后符号相同；符号编辑距离为 1；资产名称与符号一致或包含符号
+func suggestCoinCapMappings(symbol string, mappings []db.CoinCapAssetMapping, limit int) []db.CoinCapAssetMapping {
+	if limit <= 0 || len(mappings) == 0 {
+		return nil
+	}
+	target := stripDenominationPrefix(strings.ToUpper(symbol))
+
+	type candidate struct {
+		mapping db.CoinCapAssetMapping
+		score   int // 越小越匹配
+		rank    int
+	}
+	var candidates []candidate
+	for _, m := range mappings {
+		mSymbol := strings.ToUpper(m.Symbol)
+		compactName := strings.ToUpper(strings.ReplaceAll(m.Name, " ", ""))
+
+		score := -1
+		switch {
+		case mSymbol == target || compactName == target:
+			score = 0
+		case len(target) >= 3 && symbolEditDistance(target, mSymbol) == 1:
+			score = 1
+		case len(target) >= 4 && strings.Contains(compactName, target):
+			score = 2
+		}
+		if score < 0 {
+			continue
+		}
+		rank, err := strconv.Atoi(m.Rank)
+		if err != nil || rank <= 0 {
+			rank = math.MaxInt32
+		}
+		candidates = append(candidates, candidate{mapping: m, score: score, rank: rank})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score < candidates[j].score
+		}
+		return candidates[i].rank < candidates[j].rank
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	suggestions := make([]db.CoinCapAssetMapping, len(candidates))
+	for i, c := range candidates {
+		suggestions[i] = c.mapping
+	}
+	return suggestions
+}
+
+// stripDenominationPrefix 去掉合约交易对常见的面值前缀，如 1000PEPE、1000000MOG、1MBABYDOGE
+func stripDenominationPrefix(symbol string) string {
+	for _, prefix := range []string{"1000000", "1000", "100", "1M"} {
+		if rest := strings.TrimPrefix(symbol, prefix); rest != symbol && len(rest) >= 2 && rest[0] >= 'A' && rest[0] <= 'Z' {
+			return rest
+		}
+	}
+	return symbol
+}
+
+// symbolEditDistance 计算两个符号的编辑距离
+func symbolEditDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
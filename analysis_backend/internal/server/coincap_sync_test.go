@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newCoverageTestService 预置交易对与映射：BTC/ETH 已映射，1000PEPE、ETHW、ZZZ 未映射，
+// LUNA 已下架不参与统计，XYZ 只有 FDUSD 交易对
+func newCoverageTestService(t *testing.T) *CoinCapAssetSyncService {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.BinanceExchangeInfo{}, &pdb.CoinCapAssetMapping{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+
+	pairs := []struct{ base, quote, market string }{
+		{"BTC", "USDT", "spot"}, {"BTC", "USDT", "futures"}, {"BTC", "FDUSD", "spot"},
+		{"ETH", "USDT", "spot"}, {"1000PEPE", "USDT", "futures"}, {"ETHW", "USDT", "spot"},
+		{"ZZZ", "USDT", "spot"}, {"LUNA", "USDT", "spot"}, {"XYZ", "FDUSD", "spot"},
+	}
+	for _, p := range pairs {
+		info := pdb.BinanceExchangeInfo{Symbol: p.base + p.quote, Status: "TRADING", BaseAsset: p.base, QuoteAsset: p.quote, MarketType: p.market}
+		if err := gdb.Create(&info).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gdb.Model(&pdb.BinanceExchangeInfo{}).Where("base_asset = ?", "LUNA").Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	mappingService := pdb.NewCoinCapMappingService(gdb)
+	err = mappingService.BatchUpsertAssetMappings(context.Background(), []pdb.CoinCapAssetMapping{
+		{Symbol: "BTC", AssetID: "bitcoin", Name: "Bitcoin", Rank: "1"},
+		{Symbol: "ETH", AssetID: "ethereum", Name: "Ethereum", Rank: "2"},
+		{Symbol: "PEPE", AssetID: "pepe", Name: "Pepe", Rank: "30"},
+		{Symbol: "ETHFI", AssetID: "ether-fi", Name: "ether.fi", Rank: "150"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCoinCapAssetSyncService(mappingService, "")
+}
+
+// TestCoinCapCoverageReport 未映射列表只包含活跃交易对，且候选映射来自模糊匹配
+func TestCoinCapCoverageReport(t *testing.T) {
+	svc := newCoverageTestService(t)
+	ctx := context.Background()
+
+	report, err := svc.CoverageReport(ctx, "usdt", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalSymbols != 5 || report.MappedSymbols != 2 || report.Coverage != 0.4 {
+		t.Errorf("覆盖率统计不符: total=%d mapped=%d coverage=%.2f", report.TotalSymbols, report.MappedSymbols, report.Coverage)
+	}
+
+	suggested := make(map[string][]string)
+	var unmapped []string
+	for _, item := range report.Unmapped {
+		unmapped = append(unmapped, item.Symbol)
+		for _, m := range item.Suggestions {
+			suggested[item.Symbol] = append(suggested[item.Symbol], m.AssetID)
+		}
+	}
+	if want := []string{"1000PEPE", "ETHW", "ZZZ"}; !reflect.DeepEqual(unmapped, want) {
+		t.Errorf("未映射列表期望 %v，实际 %v", want, unmapped)
+	}
+	want := map[string][]string{
+		"1000PEPE": {"pepe"},     // 去掉面值前缀后精确匹配
+		"ETHW":     {"ethereum"}, // 编辑距离为 1
+	}
+	if !reflect.DeepEqual(suggested, want) {
+		t.Errorf("候选映射期望 %v，实际 %v", want, suggested)
+	}
+
+	// 不过滤计价资产时包含只在 FDUSD 交易的 XYZ；suggest 为 0 时不给候选
+	report, err = svc.CoverageReport(ctx, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalSymbols != 6 || len(report.Unmapped) != 4 || report.Unmapped[2].Symbol != "XYZ" {
+		t.Errorf("不过滤计价资产时统计不符: %+v", report)
+	}
+	for _, item := range report.Unmapped {
+		if len(item.Suggestions) != 0 {
+			t.Errorf("suggest=0 时不应给出候选: %+v", item)
+		}
+	}
+}
@@ -59,6 +59,8 @@ func SetCursor(gdb *gorm.DB) gin.HandlerFunc {
 			DatabaseErrorHelper(c, "更新游标", err)
 			return
 		}
+		// 游标是下一个待扫区块，之前的区块均已出块：推进链高度并推送达到确认数的转账
+		noteChainTip(chain, body.Block-1)
 		c.JSON(http.StatusOK, gin.H{"ok": true, "block": strconv.FormatUint(body.Block, 10)})
 	}
 }
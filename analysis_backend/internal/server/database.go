@@ -3,11 +3,13 @@ package server
 import (
 	"encoding/json"
 	"log"
+	"strings"
 	"time"
 
 	pdb "analysis/internal/db"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Database 数据库接口，抽象数据库操作
@@ -63,6 +65,7 @@ type Database interface {
 
 	// 公告相关操作
 	ListAnnouncements(params AnnouncementQueryParams) ([]pdb.Announcement, int64, error)
+	SearchAnnouncements(params AnnouncementSearchParams) ([]pdb.Announcement, int64, string, error)
 	GetLatestAnnouncementTime() (*time.Time, error)
 
 	// Twitter 相关操作
@@ -89,6 +92,7 @@ type Database interface {
 // PortfolioSnapshotQueryParams 投资组合快照查询参数
 type PortfolioSnapshotQueryParams struct {
 	Entity    string
+	RunID     string
 	Keyword   string
 	StartDate string
 	EndDate   string
@@ -128,6 +132,15 @@ type AnnouncementQueryParams struct {
 	PaginationParams
 }
 
+// AnnouncementSearchParams 公告全文搜索参数
+type AnnouncementSearchParams struct {
+	Q      string
+	Source string     // 真实来源（匹配 source 或合并后的 sources）
+	From   *time.Time // 发布时间下限（含）
+	To     *time.Time // 发布时间上限（含）
+	PaginationParams
+}
+
 // TwitterPostQueryParams Twitter 推文查询参数
 type TwitterPostQueryParams struct {
 	Username  string
@@ -212,6 +225,9 @@ func (g *gormDatabase) ListPortfolioSnapshots(params PortfolioSnapshotQueryParam
 	if params.Entity != "" {
 		q = q.Where("entity = ?", params.Entity)
 	}
+	if params.RunID != "" {
+		q = q.Where("run_id = ?", params.RunID)
+	}
 	if params.Keyword != "" {
 		q = q.Where("run_id LIKE ?", "%"+params.Keyword+"%")
 	}
@@ -432,6 +448,83 @@ func (g *gormDatabase) ListAnnouncements(params AnnouncementQueryParams) ([]pdb.
 	return announcements, total, nil
 }
 
+// 公告搜索模式
+const (
+	announcementSearchFullText = "fulltext"
+	announcementSearchLike     = "like"
+)
+
+// SearchAnnouncements 按关键词搜索公告标题/摘要，按相关度 + 发布时间排序。
+// MySQL 下使用 FULLTEXT 索引（ft_announcements_title_summary）；其他数据库或索引缺失时回退到 LIKE。
+// 第三个返回值为实际使用的搜索模式（fulltext / like）。
+func (g *gormDatabase) SearchAnnouncements(params AnnouncementSearchParams) ([]pdb.Announcement, int64, string, error) {
+	isMySQL := g.db.Dialector.Name() == "mysql"
+	base := g.db.Model(&pdb.Announcement{})
+	if src := strings.ToLower(strings.TrimSpace(params.Source)); src != "" {
+		if isMySQL {
+			base = base.Where("(source = ? OR JSON_CONTAINS(sources, JSON_OBJECT('source', ?)))", src, src)
+		} else {
+			base = base.Where("(source = ? OR sources LIKE ?)", src, `%"source":"`+src+`"%`)
+		}
+	}
+	if params.From != nil {
+		base = base.Where("release_time >= ?", params.From.UTC())
+	}
+	if params.To != nil {
+		base = base.Where("release_time <= ?", params.To.UTC())
+	}
+	// 新会话：全文检索失败回退 LIKE 时不带上 MATCH 条件
+	base = base.Session(&gorm.Session{})
+
+	if isMySQL {
+		rows, total, err := g.searchAnnouncementsFullText(base, params)
+		if err == nil {
+			return rows, total, announcementSearchFullText, nil
+		}
+		log.Printf("[announcements] fulltext search failed, fallback to LIKE: %v", err)
+	}
+	rows, total, err := g.searchAnnouncementsLike(base, params)
+	return rows, total, announcementSearchLike, err
+}
+
+func (g *gormDatabase) searchAnnouncementsFullText(base *gorm.DB, params AnnouncementSearchParams) ([]pdb.Announcement, int64, error) {
+	q := base.Where("MATCH(title, summary) AGAINST (? IN NATURAL LANGUAGE MODE)", params.Q).Session(&gorm.Session{})
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var rows []pdb.Announcement
+	err := q.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "MATCH(title, summary) AGAINST (? IN NATURAL LANGUAGE MODE) DESC, release_time DESC",
+		Vars:               []interface{}{params.Q},
+		WithoutParentheses: true,
+	}}).Offset(params.Offset).Limit(params.PageSize).Find(&rows).Error
+	return rows, total, err
+}
+
+// searchAnnouncementsLike LIKE 回退：每个关键词都需命中标题或摘要，标题命中排在前面
+func (g *gormDatabase) searchAnnouncementsLike(base *gorm.DB, params AnnouncementSearchParams) ([]pdb.Announcement, int64, error) {
+	q := base
+	terms := strings.Fields(strings.ToLower(params.Q))
+	for _, t := range terms {
+		pat := "%" + t + "%"
+		q = q.Where("(LOWER(title) LIKE ? OR LOWER(summary) LIKE ?)", pat, pat)
+	}
+	q = q.Session(&gorm.Session{})
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	pat := "%" + strings.ToLower(strings.TrimSpace(params.Q)) + "%"
+	var rows []pdb.Announcement
+	err := q.Clauses(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "(CASE WHEN LOWER(title) LIKE ? THEN 2 ELSE 0 END + CASE WHEN LOWER(summary) LIKE ? THEN 1 ELSE 0 END) DESC, release_time DESC",
+		Vars:               []interface{}{pat, pat},
+		WithoutParentheses: true,
+	}}).Offset(params.Offset).Limit(params.PageSize).Find(&rows).Error
+	return rows, total, err
+}
+
 // GetLatestAnnouncementTime 获取最新公告时间
 // 优化：只查询 release_time 字段，减少数据传输
 func (g *gormDatabase) GetLatestAnnouncementTime() (*time.Time, error) {
@@ -0,0 +1,406 @@
+package server
+
+// 端到端测试夹具：SQLite 内存库 + 进程内 API（ingest / cursor / flows / portfolio），
+// 加上 EVM JSON-RPC、Esplora、Solana RPC 三个模拟链节点；扫描器以子进程方式真实运行（go build cmd/scanner）。
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// e2eBaseTime 模拟链上区块时间的起点（2025-09-01 00:00:00 UTC）
+const e2eBaseTime int64 = 1756684800
+
+type e2eHarness struct {
+	t   *testing.T
+	gdb *gorm.DB
+	api *httptest.Server
+
+	evm     *httptest.Server
+	esplora *httptest.Server
+	solana  *httptest.Server
+}
+
+// newE2EHarness 启动 API 与三个模拟链节点；链上数据由 e2eChainData 描述
+func newE2EHarness(t *testing.T, data e2eChainData) *e2eHarness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	dsn := fmt.Sprintf("file:e2e_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	database, err := pdb.OpenMySQL(pdb.Options{Driver: pdb.DriverSQLite, DSN: dsn, Automigrate: true})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	gdb := database.GormDB()
+
+	s := &Server{db: NewGormDatabase(gdb)}
+	r := gin.New()
+	r.GET("/sync/cursor", GetCursor(gdb))
+	r.POST("/sync/cursor", SetCursor(gdb))
+	r.POST("/ingest/events", IngestEvents(gdb))
+	r.GET("/flows/daily", s.GetDailyFlows)
+	r.GET("/portfolio/latest", s.GetLatestPortfolio)
+
+	h := &e2eHarness{
+		t:       t,
+		gdb:     gdb,
+		api:     httptest.NewServer(r),
+		evm:     httptest.NewServer(data.evmHandler()),
+		esplora: httptest.NewServer(data.esploraHandler()),
+		solana:  httptest.NewServer(data.solanaHandler()),
+	}
+	t.Cleanup(func() {
+		h.api.Close()
+		h.evm.Close()
+		h.esplora.Close()
+		h.solana.Close()
+	})
+	return h
+}
+
+// writeScannerConfig 生成扫描器配置：链节点指向模拟服务，监控地址来自 entities
+func (h *e2eHarness) writeScannerConfig(data e2eChainData) string {
+	h.t.Helper()
+	cfg := fmt.Sprintf(`chains:
+  - name: ethereum
+    type: evm
+    rpc: %s
+    erc20:
+      - symbol: USDT
+        address: %s
+  - name: bitcoin
+    type: bitcoin
+    esplora: %s
+  - name: solana
+    type: solana
+    rpc: %s
+entities:
+  - name: %s
+    networks:
+      ethereum: [%s]
+      bitcoin: [%s]
+      solana: [%s]
+`, h.evm.URL, data.USDTContract, h.esplora.URL, h.solana.URL,
+		data.Entity, data.EVMAddress, data.BTCAddress, data.SOLAddress)
+	p := filepath.Join(h.t.TempDir(), "config.yaml")
+	if err := os.WriteFile(p, []byte(cfg), 0o644); err != nil {
+		h.t.Fatal(err)
+	}
+	return p
+}
+
+var (
+	e2eScannerOnce sync.Once
+	e2eScannerBin  string
+	e2eScannerErr  error
+)
+
+// buildScanner 编译 cmd/scanner（同一进程内只编译一次）
+func buildScanner(t *testing.T) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("端到端测试需要编译扫描器，-short 模式跳过")
+	}
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("未找到 go 工具链，跳过端到端测试")
+	}
+	e2eScannerOnce.Do(func() {
+		dir, err := os.MkdirTemp("", "e2e-scanner-")
+		if err != nil {
+			e2eScannerErr = err
+			return
+		}
+		e2eScannerBin = filepath.Join(dir, "scanner")
+		cmd := exec.Command(goBin, "build", "-o", e2eScannerBin, "analysis/cmd/scanner")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			e2eScannerErr = fmt.Errorf("go build cmd/scanner: %v\n%s", err, out)
+		}
+	})
+	if e2eScannerErr != nil {
+		t.Fatal(e2eScannerErr)
+	}
+	return e2eScannerBin
+}
+
+// runScanner 以子进程启动扫描器，测试结束时停止
+func (h *e2eHarness) runScanner(configPath string, extraArgs ...string) {
+	h.t.Helper()
+	bin := buildScanner(h.t)
+	args := append([]string{
+		"-config", configPath,
+		"-api", h.api.URL,
+		"-zip-binance", "",
+		"-okx-por", "",
+		"-exclude-chains", "",
+		"-start-block", "100",
+		"-confirmations", "ethereum=0,bitcoin=0,solana=0", // 模拟链只有几个区块，扫到链头
+		"-poll", "100ms",
+		"-sol-rps", "0",
+		"-only", "BTC,ETH,SOL,USDT",
+		"-v=false",
+	}, extraArgs...)
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, bin, args...)
+	logPath := filepath.Join(h.t.TempDir(), "scanner.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		cancel()
+		h.t.Fatal(err)
+	}
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		cancel()
+		h.t.Fatalf("start scanner: %v", err)
+	}
+	h.t.Cleanup(func() {
+		cancel()
+		_ = cmd.Wait()
+		_ = logFile.Close()
+		if h.t.Failed() {
+			if b, err := os.ReadFile(logPath); err == nil {
+				h.t.Logf("scanner log:\n%s", b)
+			}
+		}
+	})
+}
+
+// waitFor 轮询直到 cond 成立或超时
+func (h *e2eHarness) waitFor(what string, timeout time.Duration, cond func() bool) {
+	h.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	h.t.Fatalf("超时等待: %s", what)
+}
+
+// getJSON 请求进程内 API 并解码响应
+func (h *e2eHarness) getJSON(path string, out any) int {
+	h.t.Helper()
+	resp, err := http.Get(h.api.URL + path)
+	if err != nil {
+		h.t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, out); err != nil {
+		h.t.Fatalf("GET %s decode: %v body=%s", path, err, body)
+	}
+	return resp.StatusCode
+}
+
+// ===== 模拟链数据 =====
+
+// e2eChainData 三条链上各一笔与监控地址相关的转账
+type e2eChainData struct {
+	Entity       string
+	EVMAddress   string // 监控的以太坊地址（小写）
+	EVMPeer      string
+	USDTContract string
+	BTCAddress   string
+	SOLAddress   string
+	SOLPeer      string
+
+	EVMHead, BTCHead, SOLHead uint64
+	ETHInBlock                uint64 // 原生 ETH 转入的区块（1.5 ETH）
+	USDTOutBlock              uint64 // USDT 转出的区块（250 USDT）
+	BTCInHeight               uint64 // BTC 转入的高度（0.5 BTC）
+	SOLInSlot                 uint64 // SOL 转入的 slot（2 SOL）
+}
+
+func defaultE2EChainData() e2eChainData {
+	return e2eChainData{
+		Entity:       "binance",
+		EVMAddress:   "0x28c6c06298d514db089934071355e5743bf21d60",
+		EVMPeer:      "0x1111111111111111111111111111111111111111",
+		USDTContract: "0xdac17f958d2ee523a2206206994597c13d831ec7",
+		BTCAddress:   "bc1qe2ewatched",
+		SOLAddress:   "HotWa11et1111111111111111111111111111111111",
+		SOLPeer:      "User111111111111111111111111111111111111111",
+		EVMHead:      105, BTCHead: 101, SOLHead: 102,
+		ETHInBlock: 102, USDTOutBlock: 103, BTCInHeight: 100, SOLInSlot: 101,
+	}
+}
+
+func e2eHex(n uint64) string { return "0x" + strconv.FormatUint(n, 16) }
+
+// padTopic 地址左补零为 32 字节 topic
+func padTopic(addr string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(strings.ToLower(addr), "0x")
+}
+
+func writeRPC(w http.ResponseWriter, id any, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+// evmHandler 以太坊 JSON-RPC：eth_blockNumber / eth_getBlockByNumber / eth_getLogs / eth_call(decimals)
+func (d e2eChainData) evmHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     any               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch req.Method {
+		case "eth_blockNumber":
+			writeRPC(w, req.ID, e2eHex(d.EVMHead))
+		case "eth_getBlockByNumber":
+			var numHex string
+			var full bool
+			_ = json.Unmarshal(req.Params[0], &numHex)
+			_ = json.Unmarshal(req.Params[1], &full)
+			n, _ := strconv.ParseUint(strings.TrimPrefix(numHex, "0x"), 16, 64)
+			txs := []any{}
+			if n == d.ETHInBlock {
+				tx := map[string]any{
+					"hash": "0xethin", "from": d.EVMPeer, "to": d.EVMAddress,
+					"value": "0x14d1120d7b160000", // 1.5 ETH
+				}
+				if full {
+					txs = append(txs, tx)
+				} else {
+					txs = append(txs, tx["hash"])
+				}
+			}
+			writeRPC(w, req.ID, map[string]any{
+				"number":       e2eHex(n),
+				"timestamp":    e2eHex(uint64(e2eBaseTime) + n*12),
+				"transactions": txs,
+			})
+		case "eth_getLogs":
+			var p struct {
+				FromBlock string `json:"fromBlock"`
+				ToBlock   string `json:"toBlock"`
+				Topics    []any  `json:"topics"`
+			}
+			_ = json.Unmarshal(req.Params[0], &p)
+			from, _ := strconv.ParseUint(strings.TrimPrefix(p.FromBlock, "0x"), 16, 64)
+			to, _ := strconv.ParseUint(strings.TrimPrefix(p.ToBlock, "0x"), 16, 64)
+			logs := []any{}
+			// 只有按 from 过滤（topics[1] 含监控地址）时返回这笔转出
+			fromFilter, _ := json.Marshal(p.Topics[1])
+			if d.USDTOutBlock >= from && d.USDTOutBlock <= to && strings.Contains(string(fromFilter), padTopic(d.EVMAddress)) {
+				logs = append(logs, map[string]any{
+					"address":         d.USDTContract,
+					"topics":          []string{transferTopicHex, padTopic(d.EVMAddress), padTopic(d.EVMPeer)},
+					"data":            "0x" + fmt.Sprintf("%064x", 250_000_000),
+					"blockNumber":     e2eHex(d.USDTOutBlock),
+					"transactionHash": "0xusdtout",
+					"logIndex":        "0x0",
+				})
+			}
+			writeRPC(w, req.ID, logs)
+		case "eth_call":
+			writeRPC(w, req.ID, "0x"+fmt.Sprintf("%064x", 6))
+		default:
+			writeRPC(w, req.ID, nil)
+		}
+	})
+}
+
+// transferTopicHex ERC20 Transfer(address,address,uint256)
+const transferTopicHex = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// esploraHandler Esplora：tip 高度、高度->哈希、区块交易
+func (d e2eChainData) esploraHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case path == "/blocks/tip/height":
+			fmt.Fprint(w, d.BTCHead)
+		case strings.HasPrefix(path, "/block-height/"):
+			fmt.Fprintf(w, "hash%s", strings.TrimPrefix(path, "/block-height/"))
+		case strings.HasPrefix(path, "/block/") && strings.HasSuffix(path, "/txs"):
+			txs := []any{}
+			if path == fmt.Sprintf("/block/hash%d/txs", d.BTCInHeight) {
+				txs = append(txs, map[string]any{
+					"txid":   "btcin",
+					"status": map[string]any{"block_time": e2eBaseTime + int64(d.BTCInHeight)*600},
+					"vin": []any{map[string]any{"prevout": map[string]any{
+						"value": 60_000_000, "scriptpubkey_type": "v0_p2wpkh", "scriptpubkey_address": "bc1qpeer",
+					}}},
+					"vout": []any{
+						map[string]any{"value": 50_000_000, "scriptpubkey_type": "v0_p2wpkh", "scriptpubkey_address": d.BTCAddress},
+						map[string]any{"value": 9_990_000, "scriptpubkey_type": "v0_p2wpkh", "scriptpubkey_address": "bc1qpeer"},
+					},
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(txs)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// solanaHandler Solana RPC：getSlot / getBlock（jsonParsed）
+func (d e2eChainData) solanaHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     any               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch req.Method {
+		case "getSlot":
+			writeRPC(w, req.ID, d.SOLHead)
+		case "getBlock":
+			var slot uint64
+			_ = json.Unmarshal(req.Params[0], &slot)
+			txs := []any{}
+			if slot == d.SOLInSlot {
+				txs = append(txs, map[string]any{
+					"transaction": map[string]any{
+						"signatures": []string{"solin"},
+						"message": map[string]any{
+							"accountKeys": []any{map[string]any{"pubkey": d.SOLPeer}, map[string]any{"pubkey": d.SOLAddress}},
+							"instructions": []any{map[string]any{"program": "system", "parsed": map[string]any{
+								"type": "transfer",
+								"info": map[string]any{"source": d.SOLPeer, "destination": d.SOLAddress, "lamports": 2_000_000_000},
+							}}},
+						},
+					},
+					"meta": map[string]any{
+						"fee":          5000,
+						"preBalances":  []int64{5_000_000_000, 0},
+						"postBalances": []int64{2_999_995_000, 2_000_000_000},
+					},
+				})
+			}
+			writeRPC(w, req.ID, map[string]any{"blockTime": e2eBaseTime + int64(slot), "transactions": txs})
+		default:
+			writeRPC(w, req.ID, nil)
+		}
+	})
+}
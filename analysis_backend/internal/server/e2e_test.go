@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"analysis/internal/collector"
+	pdb "analysis/internal/db"
+	"analysis/internal/flow"
+	"analysis/internal/models"
+)
+
+// TestE2EScannerIngestToFlowsAndPortfolio 扫描器扫描模拟链 -> /ingest/events 入库 -> 事件生成 flows/持仓快照 -> API 查询
+func TestE2EScannerIngestToFlowsAndPortfolio(t *testing.T) {
+	data := defaultE2EChainData()
+	h := newE2EHarness(t, data)
+	h.runScanner(h.writeScannerConfig(data))
+
+	// 1) 三条链上的 4 笔转账全部入库，且各链游标推进到链头之后
+	h.waitFor("转账事件入库与游标推进", 60*time.Second, func() bool {
+		var n int64
+		h.gdb.Model(&pdb.TransferEvent{}).Where("entity = ?", data.Entity).Count(&n)
+		if n < 4 {
+			return false
+		}
+		for chain, head := range map[string]uint64{"ethereum": data.EVMHead, "bitcoin": data.BTCHead, "solana": data.SOLHead} {
+			if cur, err := pdb.GetCursor(h.gdb, data.Entity, chain); err != nil || cur != head+1 {
+				return false
+			}
+		}
+		return true
+	})
+
+	var rows []pdb.TransferEvent
+	h.gdb.Where("entity = ?", data.Entity).Order("chain, coin").Find(&rows)
+	if len(rows) != 4 {
+		t.Fatalf("期望 4 笔转账事件，实际 %d: %+v", len(rows), rows)
+	}
+	want := map[string]struct{ dir, amount string }{
+		"bitcoin:BTC":   {"in", "0.5"},
+		"ethereum:ETH":  {"in", "1.5"},
+		"ethereum:USDT": {"out", "250"},
+		"solana:SOL":    {"in", "2"},
+	}
+	for _, r := range rows {
+		w, ok := want[r.Chain+":"+r.Coin]
+		if !ok {
+			t.Errorf("意外的事件: %+v", r)
+			continue
+		}
+		if r.Direction != w.dir || !sameAmount(r.Amount, w.amount) {
+			t.Errorf("%s:%s 期望 %s %s，实际 %s %s", r.Chain, r.Coin, w.dir, w.amount, r.Direction, r.Amount)
+		}
+	}
+
+	// 2) 以入库事件生成一次快照（flows + 持仓），写入与 PoR 相同的表
+	asOf := time.Unix(e2eBaseTime, 0).UTC().Add(24 * time.Hour)
+	events, err := pdb.LoadTransferEvents(h.gdb, data.Entity, asOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weekly, daily := flow.FromEvents(data.Entity, events)
+	portfolio := collector.ReplayPortfolio(data.Entity, nil, events, asOf, map[string]float64{"BTC": 60000, "ETH": 2500, "SOL": 150, "USDT": 1})
+	if err := pdb.SaveAll(h.gdb, "e2e-run-1", asOf, []models.Portfolio{portfolio}, []models.WeeklyResult{weekly}, []models.DailyResult{daily}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3) /flows/daily 反映入库事件
+	var flows struct {
+		Data map[string][]flowRow `json:"data"`
+	}
+	if code := h.getJSON("/flows/daily?entity="+data.Entity, &flows); code != http.StatusOK {
+		t.Fatalf("/flows/daily status=%d", code)
+	}
+	wantFlows := map[string]flowRow{
+		"BTC":  {Day: "2025-09-01", In: 0.5, Net: 0.5},
+		"ETH":  {Day: "2025-09-01", In: 1.5, Net: 1.5},
+		"USDT": {Day: "2025-09-01", Out: 250, Net: -250},
+		"SOL":  {Day: "2025-09-01", In: 2, Net: 2},
+	}
+	for coin, w := range wantFlows {
+		got := flows.Data[coin]
+		// SQLite 的 date 列读回带时间部分，只比较日期前缀
+		if len(got) != 1 || !strings.HasPrefix(got[0].Day, w.Day) || got[0].In != w.In || got[0].Out != w.Out || got[0].Net != w.Net {
+			t.Errorf("%s 日度资金流期望 %+v，实际 %+v", coin, w, got)
+		}
+	}
+
+	// 4) /portfolio/latest 反映入库事件
+	var pf struct {
+		RunID    string       `json:"run_id"`
+		TotalUSD float64      `json:"total_usd"`
+		Holdings []HoldingDTO `json:"holdings"`
+	}
+	if code := h.getJSON("/portfolio/latest?entity="+data.Entity, &pf); code != http.StatusOK {
+		t.Fatalf("/portfolio/latest status=%d", code)
+	}
+	if pf.RunID != "e2e-run-1" || len(pf.Holdings) != 4 {
+		t.Fatalf("持仓快照不符: %+v", pf)
+	}
+	for _, hd := range pf.Holdings {
+		w := want[hd.Chain+":"+hd.Symbol]
+		amount := w.amount
+		if w.dir == "out" {
+			amount = "-" + amount
+		}
+		if !sameAmount(hd.Amount, amount) {
+			t.Errorf("%s:%s 持仓期望 %s，实际 %s", hd.Chain, hd.Symbol, amount, hd.Amount)
+		}
+	}
+	if wantUSD := 0.5*60000 + 1.5*2500 - 250 + 2*150; pf.TotalUSD != wantUSD {
+		t.Errorf("总市值期望 %v，实际 %v", wantUSD, pf.TotalUSD)
+	}
+}
+
+// sameAmount 按数值比较十进制字符串（SQLite 的 decimal 列会去掉尾随零）
+func sameAmount(a, b string) bool {
+	fa, errA := strconv.ParseFloat(a, 64)
+	fb, errB := strconv.ParseFloat(b, 64)
+	return errA == nil && errB == nil && fa == fb
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultGzipMinSize 响应体小于该字节数时不压缩，小响应压缩收益不抵 CPU 开销
+const DefaultGzipMinSize = 1024
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// GzipMiddleware 对支持 gzip 的客户端压缩超过 minSize 的响应。
+// 响应先缓冲到 minSize，超过后才切换为流式压缩，因此小响应保持原样；WebSocket 升级请求直接跳过。
+func GzipMiddleware(minSize int) gin.HandlerFunc {
+	if minSize <= 0 {
+		minSize = DefaultGzipMinSize
+	}
+	return func(c *gin.Context) {
+		req := c.Request
+		if req.Method == http.MethodHead ||
+			!strings.Contains(req.Header.Get("Accept-Encoding"), "gzip") ||
+			strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+			c.Next()
+			return
+		}
+
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		w := &gzipResponseWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = w
+		defer w.finish()
+
+		c.Next()
+	}
+}
+
+// gzipResponseWriter 在响应体达到阈值前缓冲，达到后写出 gzip 头并改为压缩输出
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize     int
+	buf         bytes.Buffer
+	gz          *gzip.Writer
+	passthrough bool // 已决定不压缩（已有编码或提前 Flush）
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() < w.minSize {
+		return len(b), nil
+	}
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		w.passthrough = true
+	} else {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriterPool.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	if err := w.flushBuffer(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// flushBuffer 把缓冲区内容写到当前输出（压缩或原样）
+func (w *gzipResponseWriter) flushBuffer() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// Flush 流式响应提前 Flush 时不再等待阈值，剩余内容按当前模式输出
+func (w *gzipResponseWriter) Flush() {
+	if w.gz == nil {
+		w.passthrough = true
+		_ = w.flushBuffer()
+	} else {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
+
+// finish 请求结束时输出未达阈值的缓冲内容，或关闭 gzip 流
+func (w *gzipResponseWriter) finish() {
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.gz.Reset(nil)
+		gzipWriterPool.Put(w.gz)
+		w.gz = nil
+		return
+	}
+	_ = w.flushBuffer()
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// newCompressionTestRouter 按 main.go 的顺序挂载 CORS、压缩和响应缓存
+func newCompressionTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	cfg := cors.DefaultConfig()
+	cfg.AllowOrigins = []string{"http://app.example.org"}
+	cfg.AllowCredentials = true
+	cfg.AllowHeaders = []string{"Authorization", "Content-Type", "Origin"}
+	r.Use(cors.New(cfg))
+	r.Use(GzipMiddleware(DefaultGzipMinSize))
+
+	cache := pdb.NewMemoryCache()
+	large := strings.Repeat(`{"symbol":"BTCUSDT","price":"65000.12"},`, 200)
+	r.GET("/large", CacheMiddleware(cache, pdb.CacheTypeRealTime, 0, nil), func(c *gin.Context) {
+		c.String(http.StatusOK, large)
+	})
+	r.GET("/small", CacheMiddleware(cache, -1, 30*time.Second, nil), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.GET("/fail", CacheMiddleware(cache, -1, 30*time.Second, nil), func(c *gin.Context) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+	})
+	r.GET("/fail-empty", CacheMiddleware(cache, -1, 30*time.Second, nil), func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+	return r
+}
+
+// TestGzipAppliedToLargeBodies 大响应被压缩且可解压，小响应原样返回，CORS 头不受影响
+func TestGzipAppliedToLargeBodies(t *testing.T) {
+	r := newCompressionTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/large", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Origin", "http://app.example.org")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("大响应应被 gzip 压缩，headers=%v", w.Header())
+	}
+	if w.Header().Get("Vary") == "" || !strings.Contains(strings.Join(w.Header().Values("Vary"), ","), "Accept-Encoding") {
+		t.Errorf("压缩响应应带 Vary: Accept-Encoding，实际 %v", w.Header().Values("Vary"))
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "http://app.example.org" {
+		t.Errorf("压缩后 CORS 头应保留，实际 %q", got)
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(body), `{"symbol":"BTCUSDT"`) || len(body) != 200*len(`{"symbol":"BTCUSDT","price":"65000.12"},`) {
+		t.Errorf("解压后内容不符，长度 %d", len(body))
+	}
+
+	// 小响应不压缩
+	req = httptest.NewRequest(http.MethodGet, "/small", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != `{"ok":true}` {
+		t.Errorf("小响应不应压缩: encoding=%q body=%q", w.Header().Get("Content-Encoding"), w.Body.String())
+	}
+
+	// 客户端不支持 gzip 时原样返回
+	req = httptest.NewRequest(http.MethodGet, "/large", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Header().Get("Content-Encoding") != "" || !strings.HasPrefix(w.Body.String(), `{"symbol"`) {
+		t.Errorf("未声明 gzip 的请求不应压缩: encoding=%q", w.Header().Get("Content-Encoding"))
+	}
+
+	// 预检请求由 CORS 处理
+	req = httptest.NewRequest(http.MethodOptions, "/large", nil)
+	req.Header.Set("Origin", "http://app.example.org")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || !strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "Authorization") {
+		t.Errorf("预检请求应允许 Authorization 头: code=%d headers=%v", w.Code, w.Header())
+	}
+}
+
+// TestCacheControlMatchesRouteTTL Cache-Control 的 max-age 与路由的服务端缓存 TTL 一致
+func TestCacheControlMatchesRouteTTL(t *testing.T) {
+	r := newCompressionTestRouter()
+	realTime := int(pdb.DefaultCacheTTL.GetTTL(pdb.CacheTypeRealTime) / time.Second)
+
+	for _, tc := range []struct {
+		path, auth, want string
+	}{
+		{"/large", "", "public, max-age=" + strconv.Itoa(realTime)},
+		{"/small", "", "public, max-age=30"},
+		{"/small?scope=me", "Bearer token", "private, max-age=30"},
+	} {
+		// 第二次请求命中缓存，头部应保持一致
+		for _, xcache := range []string{"MISS", "HIT"} {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if got := w.Header().Get("X-Cache"); got != xcache {
+				t.Errorf("%s X-Cache 期望 %s，实际 %s", tc.path, xcache, got)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tc.want {
+				t.Errorf("%s (auth=%q, %s) Cache-Control 期望 %q，实际 %q", tc.path, tc.auth, xcache, tc.want, got)
+			}
+			if xcache == "MISS" {
+				// 缓存为异步写入，等待落地后再验证命中
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+	}
+}
+
+// TestCacheControlNoStoreOnError 错误响应不带 max-age，也不写入服务端缓存
+func TestCacheControlNoStoreOnError(t *testing.T) {
+	r := newCompressionTestRouter()
+	for _, path := range []string{"/fail", "/fail-empty"} {
+		for i := 0; i < 2; i++ {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			if w.Code < http.StatusInternalServerError {
+				t.Fatalf("%s 期望 5xx，实际 %d", path, w.Code)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("%s 错误响应 Cache-Control 期望 no-store，实际 %q", path, got)
+			}
+			if got := w.Header().Get("X-Cache"); got != "MISS" {
+				t.Errorf("%s 错误响应不应被缓存，X-Cache=%q", path, got)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+}
@@ -160,7 +160,10 @@ type Server struct {
 	db                     Database // 使用接口而非具体实现
 	Mailer                 Mailer
 	XBearer                string
+	XBearers               *TwitterBearerPool // 多 token 轮换；为空时只用 XBearer
+	twitterBearerOnce      sync.Once
 	cache                  pdb.CacheInterface // 缓存接口
+	porRunner              *PORRunner         // 手动触发的 PoR 运行
 	arkhamClient           *ArkhamClient
 	nansenClient           *NansenClient
 	cfg                    *config.Config
@@ -3122,6 +3125,13 @@ func (s *Server) initBacktestEngine() {
 		log.Printf("[WARN] 回测引擎初始化失败，将使用简化版本")
 		return
 	}
+	if s.cfg != nil {
+		s.backtestEngine.dataSource = s.cfg.Backtest.DataSource
+		s.backtestEngine.liveDataFallback = s.cfg.Backtest.LiveFallback
+		s.backtestEngine.maxRangeDays = s.cfg.Backtest.MaxRangeDays
+		s.backtestEngine.hardMaxRangeDays = s.cfg.Backtest.HardMaxRangeDays
+		log.Printf("[INIT] 回测历史数据来源: %s，实时补数据: %v", s.backtestEngine.historicalDataSource(), s.backtestEngine.liveDataFallback)
+	}
 
 	// 初始化策略回测引擎
 	s.strategyBacktestEngine = NewStrategyBacktestEngine(s.db, s.dataManager)
@@ -3689,9 +3699,11 @@ func (s *Server) ListEntities(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"entities": ents})
 }
 
-// GET /runs?entity=&page=1&page_size=50
+// GET /runs?entity=&run_id=&page=1&page_size=50
+// 指定 run_id 且为 /admin/por/run 触发的运行时，额外返回 run（状态）
 func (s *Server) ListRuns(c *gin.Context) {
 	entity := strings.TrimSpace(c.Query("entity"))
+	runID := strings.TrimSpace(c.Query("run_id"))
 
 	// 分页参数
 	pagination := ParsePaginationParams(
@@ -3709,6 +3721,7 @@ func (s *Server) ListRuns(c *gin.Context) {
 	// 使用接口方法查询
 	params := PortfolioSnapshotQueryParams{
 		Entity:           entity,
+		RunID:            runID,
 		Keyword:          keyword,
 		StartDate:        startDate,
 		EndDate:          endDate,
@@ -3745,7 +3758,7 @@ func (s *Server) ListRuns(c *gin.Context) {
 		totalPages = 1
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"items":       out,
 		"total":       total,
 		"page":        pagination.Page,
@@ -3753,7 +3766,13 @@ func (s *Server) ListRuns(c *gin.Context) {
 		"total_pages": totalPages,
 		// 兼容字段
 		"runs": out,
-	})
+	}
+	if runID != "" && s.porRunner != nil {
+		if st, ok := s.porRunner.Get(runID); ok {
+			resp["run"] = st
+		}
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // —— helper —— //
@@ -4661,8 +4680,9 @@ func (s *Server) ForceGenerateRecommendations(c *gin.Context) {
 	kind := c.DefaultQuery("kind", "spot")
 	limitStr := c.DefaultQuery("limit", "5")
 	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 || limit > 50 {
-		s.ValidationError(c, "limit", "limit参数必须是1-50之间的整数")
+	maxLimit := s.cfg.RecommendationMaxLimit()
+	if err != nil || limit <= 0 || limit > maxLimit {
+		s.ValidationError(c, "limit", fmt.Sprintf("limit参数必须是1-%d之间的整数", maxLimit))
 		return
 	}
 
@@ -326,6 +326,7 @@ func (ho *HyperparameterOptimizer) runBacktestWithConfig(ctx context.Context, co
 	entryPrice := 0.0
 	totalTrades := 0
 	winningTrades := 0
+	commissionRate := config.CommissionRate(config.Symbol) // 含币种 / 市场覆盖与免手续费
 
 	// 使用真实的决策逻辑进行回测
 	for i := 50; i < len(data); i++ { // 跳过前50个数据点进行预热
@@ -380,7 +381,7 @@ func (ho *HyperparameterOptimizer) runBacktestWithConfig(ctx context.Context, co
 					Quantity:   quantity,
 					Price:      currentPrice,
 					Timestamp:  data[i].LastUpdated,
-					Commission: positionSize * config.Commission,
+					Commission: positionSize * commissionRate,
 					PnL:        0, // 买入时PnL为0
 				}
 				result.Trades = append(result.Trades, trade)
@@ -394,7 +395,7 @@ func (ho *HyperparameterOptimizer) runBacktestWithConfig(ctx context.Context, co
 				// 止损卖出
 				exitValue := position * currentPrice
 				pnl := exitValue - (position * entryPrice)
-				cash += exitValue - (exitValue * config.Commission)
+				cash += exitValue - (exitValue * commissionRate)
 
 				if pnl > 0 {
 					winningTrades++
@@ -407,7 +408,7 @@ func (ho *HyperparameterOptimizer) runBacktestWithConfig(ctx context.Context, co
 					Quantity:   position,
 					Price:      currentPrice,
 					Timestamp:  data[i].LastUpdated,
-					Commission: exitValue * config.Commission,
+					Commission: exitValue * commissionRate,
 					PnL:        pnl,
 				}
 				result.Trades = append(result.Trades, trade)
@@ -419,7 +420,7 @@ func (ho *HyperparameterOptimizer) runBacktestWithConfig(ctx context.Context, co
 				// 止盈卖出
 				exitValue := position * currentPrice
 				pnl := exitValue - (position * entryPrice)
-				cash += exitValue - (exitValue * config.Commission)
+				cash += exitValue - (exitValue * commissionRate)
 
 				winningTrades++
 
@@ -430,7 +431,7 @@ func (ho *HyperparameterOptimizer) runBacktestWithConfig(ctx context.Context, co
 					Quantity:   position,
 					Price:      currentPrice,
 					Timestamp:  data[i].LastUpdated,
-					Commission: exitValue * config.Commission,
+					Commission: exitValue * commissionRate,
 					PnL:        pnl,
 				}
 				result.Trades = append(result.Trades, trade)
@@ -1,19 +1,48 @@
 package server
 
 import (
+	"analysis/internal/config"
 	pdb "analysis/internal/db"
 	"analysis/internal/models"
+	"context"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// IngestOptions 事件写入后的附加动作
+type IngestOptions struct {
+	// PortfolioDelta 把新事件的净流入直接应用到实体最新持仓快照，并失效 /portfolio/latest 缓存，
+	// 不必等下一次完整 PoR 运行或缓存 TTL 过期；因重组被删除的孤块事件在同一事务内冲回
+	PortfolioDelta bool
+	// NetFlow 按 实体/币种 汇总本次新写入事件的净流量（in - out）并落库，随响应返回，看板无需重新聚合事件
+	NetFlow bool
+	Cache   pdb.CacheInterface
+	// Prices 非 nil 时按事件发生时间查币种美元价格，写入 usd_value（上报方已带 usd_value 的事件保持不变）
+	Prices USDPricer
+	// Chains 链配置（config.BuildChainCfg），为未携带 asset_id 的事件按币种查合约 / mint 补齐
+	Chains map[string]config.ChainCfg
+}
+
+// USDPricer 币种在某一时刻的美元价格（price.Cache 实现，带缓存）
+type USDPricer interface {
+	USD(ctx context.Context, coin string, at time.Time) (float64, bool)
+}
+
 // POST /ingest/events?entity=binance
 // Body: []models.Event
-func IngestEvents(gdb *gorm.DB) gin.HandlerFunc {
+func IngestEvents(gdb *gorm.DB, opts ...IngestOptions) gin.HandlerFunc {
+	var opt IngestOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
 	return func(c *gin.Context) {
 		entity := strings.TrimSpace(c.Query("entity"))
 		var evs []models.Event
@@ -22,15 +51,191 @@ func IngestEvents(gdb *gorm.DB) gin.HandlerFunc {
 			JSONBindErrorHelper(c, err)
 			return
 		}
+		if n := fillAssetIDs(evs, opt.Chains); n > 0 {
+			log.Printf("[ingest] entity=%s %d events without asset_id could not be resolved from chain config", entity, n)
+		}
+		if opt.Prices != nil {
+			enrichUSDValue(c.Request.Context(), opt.Prices, evs)
+		}
 		runID := uuid.NewString()
-		rows, err := pdb.SaveTransferEvents(gdb, runID, entity, evs)
+		var rows []pdb.TransferEvent
+		var updated map[string]string
+		var err error
+		if opt.PortfolioDelta {
+			rows, updated, err = pdb.SaveTransferEventsWithDeltas(gdb, runID, entity, evs)
+		} else {
+			rows, err = pdb.SaveTransferEvents(gdb, runID, entity, evs)
+		}
 		if err != nil {
 			// 优化：使用统一的错误处理
 			DatabaseErrorHelper(c, "保存转账事件", err)
 			return
 		}
+		invalidatePortfolioDelta(c.Request.Context(), opt.Cache, updated)
 		// 只广播新插入的记录
 		BroadcastTransfers(entity, rows)
-		c.JSON(http.StatusOK, gin.H{"ok": true, "saved": len(rows), "run_id": runID})
+		resp := gin.H{"ok": true, "saved": len(rows), "run_id": runID}
+		if opt.NetFlow {
+			flows := pdb.ComputeNetFlows(runID, rows)
+			if err := pdb.SaveNetFlows(gdb, flows); err != nil {
+				// 事件已落库，汇总失败只记日志
+				log.Printf("[ingest] save net flows run=%s: %v", runID, err)
+			}
+			resp["net_flows"] = flows
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// maxCanonicalRange 单次校正的最大区块数
+const maxCanonicalRange = 10000
+
+// POST /ingest/canonical?entity=binance&chain=ethereum
+// Body: {"from": 100, "to": 112, "hashes": {"100": "0x..", ..., "112": "0x.."}}
+// 扫描器重组回退后重扫 [from, to]，上报区间内每个高度的规范区块哈希；删除区间内区块哈希不符的孤块事件，
+// 重扫没有产生新事件时孤块事件同样会被删除
+func IngestCanonicalBlocks(gdb *gorm.DB, opts ...IngestOptions) gin.HandlerFunc {
+	var opt IngestOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	type req struct {
+		From   uint64            `json:"from"`
+		To     uint64            `json:"to"`
+		Hashes map[uint64]string `json:"hashes"`
+	}
+	return func(c *gin.Context) {
+		entity := strings.TrimSpace(c.Query("entity"))
+		chain := strings.TrimSpace(c.Query("chain"))
+		if entity == "" || chain == "" {
+			ValidationErrorHelper(c, "entity/chain", "entity 和 chain 参数不能为空")
+			return
+		}
+		var body req
+		if err := c.BindJSON(&body); err != nil {
+			JSONBindErrorHelper(c, err)
+			return
+		}
+		if body.To < body.From || body.To-body.From >= maxCanonicalRange {
+			ValidationErrorHelper(c, "from/to", "区块区间无效或超过上限")
+			return
+		}
+		for n := body.From; n <= body.To; n++ {
+			if strings.TrimSpace(body.Hashes[n]) == "" {
+				ValidationErrorHelper(c, "hashes", "缺少区块 "+strconv.FormatUint(n, 10)+" 的规范哈希")
+				return
+			}
+		}
+		var deleted []pdb.TransferEvent
+		var updated map[string]string
+		var err error
+		if opt.PortfolioDelta {
+			deleted, updated, err = pdb.DeleteNonCanonicalTransfersWithDeltas(gdb, entity, chain, body.From, body.To, body.Hashes)
+		} else {
+			deleted, err = pdb.DeleteNonCanonicalTransfers(gdb, entity, chain, body.From, body.To, body.Hashes)
+		}
+		if err != nil {
+			DatabaseErrorHelper(c, "删除孤块事件", err)
+			return
+		}
+		invalidatePortfolioDelta(c.Request.Context(), opt.Cache, updated)
+		if len(deleted) > 0 {
+			log.Printf("[ingest] entity=%s chain=%s blocks=%d..%d removed %d orphaned events", entity, chain, body.From, body.To, len(deleted))
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "deleted": len(deleted)})
+	}
+}
+
+// fillAssetIDs 补齐未携带 asset_id 的事件（旧版扫描器）：原生币为 "<链>:native"，代币按链配置的 ERC20/SPL/TRC20 查合约，
+// 与扫描器生成的 asset_id 一致；查不到的留空（按资产汇总时单独标记），返回未能补齐的条数
+func fillAssetIDs(evs []models.Event, chains map[string]config.ChainCfg) int {
+	unresolved := 0
+	for i := range evs {
+		e := &evs[i]
+		if strings.TrimSpace(e.AssetID) != "" {
+			e.AssetID = strings.ToLower(strings.TrimSpace(e.AssetID))
+			continue
+		}
+		cc, hasCfg := ingestChainCfg(chains, e.Chain)
+		native := cc.NativeSymbol
+		if native == "" {
+			native = ingestNativeSymbol(e.Chain)
+		}
+		if native != "" && strings.EqualFold(strings.TrimSpace(e.Coin), native) {
+			e.AssetID = models.AssetIDFor(e.Chain, "")
+			continue
+		}
+		if hasCfg {
+			if contract, ok := cc.TokenContract(e.Coin); ok {
+				e.AssetID = models.AssetIDFor(e.Chain, contract)
+				continue
+			}
+		}
+		unresolved++
+	}
+	return unresolved
+}
+
+// ingestChainCfg 按链名（不区分大小写）查链配置
+func ingestChainCfg(chains map[string]config.ChainCfg, chain string) (config.ChainCfg, bool) {
+	if cc, ok := chains[chain]; ok {
+		return cc, true
+	}
+	for name, cc := range chains {
+		if strings.EqualFold(name, strings.TrimSpace(chain)) {
+			return cc, true
+		}
+	}
+	return config.ChainCfg{}, false
+}
+
+// ingestNativeSymbol 链的原生币符号：非 EVM 链固定，EVM 链查内置映射
+func ingestNativeSymbol(chain string) string {
+	switch strings.ToLower(strings.TrimSpace(chain)) {
+	case "bitcoin", "btc":
+		return "BTC"
+	case "solana", "sol":
+		return "SOL"
+	case "tron", "trx":
+		return "TRX"
+	}
+	return config.NativeSymbol(chain, "")
+}
+
+// enrichUSDValue 金额 × 发生时价格；没有价格或金额无法解析的事件 usd_value 留空
+func enrichUSDValue(ctx context.Context, prices USDPricer, evs []models.Event) {
+	for i := range evs {
+		e := &evs[i]
+		if e.USDValue != nil {
+			continue
+		}
+		amt, err := strconv.ParseFloat(strings.TrimSpace(e.Amount), 64)
+		if err != nil {
+			continue
+		}
+		px, ok := prices.USD(ctx, e.Coin, e.TS)
+		if !ok {
+			continue
+		}
+		v := math.Round(amt*px*1e8) / 1e8
+		e.USDValue = &v
+	}
+}
+
+// invalidatePortfolioDelta 持仓快照被增量更新后失效对应实体的缓存；updated 为 实体 -> run_id
+func invalidatePortfolioDelta(ctx context.Context, cache pdb.CacheInterface, updated map[string]string) {
+	if cache == nil {
+		return
+	}
+	for entity, runID := range updated {
+		// 路由缓存（CacheMiddleware）与 GetLatestPortfolio 内部按 run_id 的缓存
+		for _, key := range []string{
+			BuildCacheKey("cache:v1:portfolio:latest", entity),
+			BuildCacheKey("cache:portfolio:latest", entity, runID),
+		} {
+			if err := cache.Delete(ctx, key); err != nil {
+				log.Printf("[ingest] invalidate portfolio cache %s: %v", key, err)
+			}
+		}
 	}
 }
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"analysis/internal/config"
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestIngestEventsAssetID 各链 USDT 按 asset_id 分开汇总；旧版扫描器未带 asset_id 时按链配置查合约 / mint 补齐，
+// 与扫描器带 asset_id 的同一代币归为一组，原生币映射为 <链>:native；链配置里没有的代币不另造标识，单独标记
+func TestIngestEventsAssetID(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TransferEvent{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	chains := map[string]config.ChainCfg{
+		"solana": {Name: "solana", Type: "solana", SPL: []config.TokenSPL{{Symbol: "USDT", Mint: "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"}}},
+		"tron":   {Name: "tron", Type: "tron", TRC20: []config.TokenTRC20{{Symbol: "USDT", Contract: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t"}}},
+	}
+	r.POST("/ingest/events", IngestEvents(gdb, IngestOptions{Chains: chains}))
+
+	ethUSDT := models.AssetIDFor("ethereum", "0xdAC17F958D2ee523a2206206994597C13D831ec7")
+	tronUSDT := models.AssetIDFor("tron", "a614f803b6fd780986a42c78ec9c7f77e6ded13c")
+	solUSDT := models.AssetIDFor("solana", "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB")
+	now := time.Now().UTC().Add(-time.Hour)
+	events := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", AssetID: ethUSDT, Direction: "in", Amount: "500", TxID: "0x1", Address: "0xhot", LogIndex: 1, TS: now},
+		{Chain: "tron", Coin: "USDT", AssetID: tronUSDT, Direction: "in", Amount: "300", TxID: "t1", Address: "Thot", LogIndex: 0, TS: now},
+		{Chain: "tron", Coin: "USDT", Direction: "out", Amount: "100", TxID: "t2", Address: "Thot", LogIndex: 0, TS: now},
+		{Chain: "solana", Coin: "USDT", Direction: "in", Amount: "50", TxID: "s1", Address: "Shot", TS: now},
+		{Chain: "solana", Coin: "USDT", AssetID: solUSDT, Direction: "in", Amount: "25", TxID: "s2", Address: "Shot", TS: now},
+		{Chain: "ethereum", Coin: "PEPE", Direction: "in", Amount: "7", TxID: "0x3", Address: "0xhot", LogIndex: 2, TS: now},
+		{Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "2", TxID: "0x2", Address: "0xhot", LogIndex: -1, TS: now},
+	}
+	body, _ := json.Marshal(events)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest/events?entity=binance", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("写入事件失败: %d %s", w.Code, w.Body.String())
+	}
+
+	if ethUSDT != "ethereum:0xdac17f958d2ee523a2206206994597c13d831ec7" {
+		t.Errorf("合约地址应统一小写: %s", ethUSDT)
+	}
+	flows, err := pdb.SumTransferFlows(gdb, "binance", now.Add(-time.Minute), now.Add(time.Minute), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		ethUSDT:           "500",
+		tronUSDT:          "200",
+		solUSDT:           "75",
+		"ethereum:native": "-2",
+		"":                "7",
+	}
+	if len(flows) != len(want) {
+		t.Fatalf("期望 %d 个资产分组，实际 %+v", len(want), flows)
+	}
+	for _, f := range flows {
+		if f.Key == "" && (!f.Unresolved || f.Chain != "ethereum" || f.Coin != "PEPE") {
+			t.Errorf("未配置合约的代币应留空 asset_id 并标记: %+v", f)
+		}
+		if net, ok := want[f.Key]; !ok || decimalOf(f.Net) != decimalOf(net) {
+			t.Errorf("资产 %s 净流量期望 %s，实际 %+v", f.Key, net, f)
+		}
+	}
+
+	byCoin, err := pdb.SumTransferFlows(gdb, "binance", now.Add(-time.Minute), now.Add(time.Minute), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byCoin) != 3 || byCoin[2].Key != "USDT" || decimalOf(byCoin[2].Net) != 775 || byCoin[2].Events != 5 {
+		t.Errorf("按币种汇总应合并各链 USDT: %+v", byCoin)
+	}
+}
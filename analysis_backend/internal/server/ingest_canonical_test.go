@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestIngestCanonicalBlocksRemovesOrphans 重组后重扫的区间没有新事件：上报规范哈希即删除孤块事件；缺高度或区间无效时拒绝
+func TestIngestCanonicalBlocksRemovesOrphans(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TransferEvent{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ingest/events", IngestEvents(gdb))
+	r.POST("/ingest/canonical", IngestCanonicalBlocks(gdb))
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+		return w
+	}
+
+	ts := time.Now().UTC().Add(-time.Hour)
+	events := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "100", TxID: "0xa", LogIndex: 1, TS: ts, BlockNumber: 20, BlockHash: "0xold20"},
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1", TxID: "0xb", LogIndex: -1, TS: ts, BlockNumber: 21, BlockHash: "0xh21"},
+	}
+	if w := post("/ingest/events?entity=binance", events); w.Code != http.StatusOK {
+		t.Fatalf("写入事件失败: %d %s", w.Code, w.Body.String())
+	}
+
+	for _, bad := range []map[string]any{
+		{"from": 20, "to": 21, "hashes": map[string]string{"20": "0xnew20"}},
+		{"from": 21, "to": 20, "hashes": map[string]string{}},
+		{"from": 0, "to": maxCanonicalRange, "hashes": map[string]string{}},
+	} {
+		if w := post("/ingest/canonical?entity=binance&chain=ethereum", bad); w.Code != http.StatusBadRequest {
+			t.Errorf("%v: 期望 400，实际 %d", bad, w.Code)
+		}
+	}
+
+	w := post("/ingest/canonical?entity=binance&chain=ethereum", map[string]any{
+		"from": 20, "to": 21, "hashes": map[string]string{"20": "0xnew20", "21": "0xh21"},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("校正失败: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Deleted int `json:"deleted"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Deleted != 1 {
+		t.Errorf("期望删除 1 条孤块事件，实际 %d", resp.Deleted)
+	}
+	var left []string
+	gdb.Model(&pdb.TransferEvent{}).Pluck("tx_id", &left)
+	if len(left) != 1 || left[0] != "0xb" {
+		t.Errorf("剩余事件期望 [0xb]，实际 %v", left)
+	}
+}
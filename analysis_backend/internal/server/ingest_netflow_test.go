@@ -0,0 +1,79 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func decimalOf(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// TestIngestEventsNetFlow 开启净流量汇总后，每次写入按币种记录 sum(in) - sum(out)，重复事件不再计入
+func TestIngestEventsNetFlow(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TransferEvent{}, &pdb.NetFlow{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ingest/events", IngestEvents(gdb, IngestOptions{NetFlow: true}))
+
+	now := time.Now().UTC()
+	events := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "500", TxID: "0x1", Address: "0xhot", LogIndex: 1, TS: now},
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "120", TxID: "0x2", Address: "0xhot", LogIndex: 1, TS: now},
+		{Chain: "ethereum", Coin: "USDT", Direction: "out", Amount: "200", TxID: "0x3", Address: "0xhot", LogIndex: 1, TS: now},
+		{Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "3", TxID: "0x4", Address: "0xhot", LogIndex: -1, TS: now},
+	}
+	post := func(evs []models.Event) (string, []pdb.NetFlow) {
+		t.Helper()
+		body, _ := json.Marshal(evs)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest/events?entity=binance", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("写入事件失败: %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			RunID    string        `json:"run_id"`
+			NetFlows []pdb.NetFlow `json:"net_flows"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.RunID, resp.NetFlows
+	}
+
+	runID, flows := post(events)
+	if len(flows) != 2 || flows[0].Coin != "ETH" || flows[0].Net != "-3.0" || flows[1].Coin != "USDT" || flows[1].Net != "420.0" {
+		t.Fatalf("净流量应为 ETH -3、USDT 500+120-200=420，实际 %+v", flows)
+	}
+	var stored []pdb.NetFlow
+	gdb.Where("run_id = ? AND entity = ?", runID, "binance").Order("coin").Find(&stored)
+	if len(stored) != 2 || decimalOf(stored[1].In) != 620 || decimalOf(stored[1].Out) != 200 || decimalOf(stored[1].Net) != 420 || stored[1].Events != 3 {
+		t.Errorf("落库的净流量不符: %+v", stored)
+	}
+
+	// 重复下发：只有新事件计入
+	_, flows = post(append(events, models.Event{Chain: "ethereum", Coin: "USDT", Direction: "out", Amount: "20", TxID: "0x5", Address: "0xhot", LogIndex: 1, TS: now}))
+	if len(flows) != 1 || flows[0].Coin != "USDT" || flows[0].Net != "-20.0" || flows[0].Events != 1 {
+		t.Errorf("重复事件不应计入净流量，实际 %+v", flows)
+	}
+}
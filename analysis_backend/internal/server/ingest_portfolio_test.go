@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type latestPortfolioResp struct {
+	RunID    string       `json:"run_id"`
+	TotalUSD float64      `json:"total_usd"`
+	Holdings []HoldingDTO `json:"holdings"`
+}
+
+func (p latestPortfolioResp) holding(chain, symbol string) (HoldingDTO, bool) {
+	for _, h := range p.Holdings {
+		if h.Chain == chain && h.Symbol == symbol {
+			return h, true
+		}
+	}
+	return HoldingDTO{}, false
+}
+
+// TestIngestEventsPortfolioDelta 开启增量更新后，流入事件立即计入缓存与存储的最新持仓
+func TestIngestEventsPortfolioDelta(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TransferEvent{}, &pdb.PortfolioSnapshot{}, &pdb.Holding{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	asOf := time.Now().UTC().Add(-time.Hour)
+	if err := pdb.SaveAll(gdb, "run-1", asOf, []models.Portfolio{{
+		Entity:   "binance",
+		Holdings: map[string]models.Holding{"ethereum:USDT": {Chain: "ethereum", Symbol: "USDT", Amount: "1000", Decimals: 6, ValueUSD: 1000}},
+		TotalUSD: 1000,
+	}}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	cache := pdb.NewMemoryCache()
+	s := &Server{db: NewGormDatabase(gdb), cache: cache}
+	r := gin.New()
+	r.GET("/portfolio/latest", CacheMiddleware(cache, pdb.CacheTypeRealTime, time.Minute, PortfolioCacheKey), s.GetLatestPortfolio)
+	r.POST("/ingest/events", IngestEvents(gdb, IngestOptions{PortfolioDelta: true, Cache: cache}))
+
+	get := func() latestPortfolioResp {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/portfolio/latest?entity=binance", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("期望 200，实际 %d %s", w.Code, w.Body.String())
+		}
+		var p latestPortfolioResp
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	if h, _ := get().holding("ethereum", "USDT"); h.Amount != "1000" {
+		t.Fatalf("初始持仓不符: %+v", h)
+	}
+	// 等待 handler 异步写入按 run_id 的内部缓存，确保两层缓存都已就绪
+	innerKey := BuildCacheKey("cache:portfolio:latest", "binance", "run-1")
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		if b, _ := cache.Get(context.Background(), innerKey); len(b) > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("内部缓存未写入")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	events := []models.Event{
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "250", TxID: "0xnew", Address: "0xhot", LogIndex: 1, TS: time.Now().UTC()},
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "999", TxID: "0xold", Address: "0xhot", LogIndex: 1, TS: asOf.Add(-time.Minute)}, // 已计入快照
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "2", TxID: "0xeth", Address: "0xhot", LogIndex: -1, TS: time.Now().UTC()},
+	}
+	body, _ := json.Marshal(events)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest/events?entity=binance", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("写入事件失败: %d %s", w.Code, w.Body.String())
+	}
+
+	p := get()
+	usdt, _ := p.holding("ethereum", "USDT")
+	if usdt.Amount != "1250" || usdt.ValueUSD != 1250 || p.TotalUSD != 1250 {
+		t.Fatalf("流入应计入缓存持仓: usdt=%+v total=%v", usdt, p.TotalUSD)
+	}
+	if eth, ok := p.holding("ethereum", "ETH"); !ok || eth.Amount != "2" {
+		t.Errorf("快照中没有的币种应新增持仓: %+v", p.Holdings)
+	}
+
+	var stored pdb.Holding
+	gdb.Where("run_id = ? AND entity = ? AND symbol = ?", "run-1", "binance", "USDT").First(&stored)
+	if stored.Amount != "1250" {
+		t.Errorf("存储的持仓应同步更新，实际 %q", stored.Amount)
+	}
+
+	// 重复下发的事件不重复计入
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest/events?entity=binance", bytes.NewReader(body)))
+	if usdt, _ := get().holding("ethereum", "USDT"); usdt.Amount != "1250" {
+		t.Errorf("重复事件不应再次计入，实际 %q", usdt.Amount)
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// stubPricer 按 币种|日期 返回价格，并记录查询
+type stubPricer struct {
+	prices map[string]float64
+	calls  []string
+}
+
+func (p *stubPricer) USD(_ context.Context, coin string, at time.Time) (float64, bool) {
+	key := coin + "|" + at.UTC().Format("2006-01-02")
+	p.calls = append(p.calls, key)
+	v, ok := p.prices[key]
+	return v, ok
+}
+
+// TestIngestEventsUSDValue 开启 USD 估值后按事件发生日的价格计算 usd_value 并落库；无价格留空，上报方自带的值不覆盖
+func TestIngestEventsUSDValue(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TransferEvent{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	now := time.Now().UTC()
+	past := time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC)
+	pricer := &stubPricer{prices: map[string]float64{
+		"ETH|" + now.Format("2006-01-02"):  3000,
+		"ETH|2024-03-01":                   3400.5,
+		"USDT|" + now.Format("2006-01-02"): 1,
+	}}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ingest/events", IngestEvents(gdb, IngestOptions{Prices: pricer}))
+
+	given := 99.0
+	events := []models.Event{
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "1.5", TxID: "0x1", Address: "0xhot", LogIndex: -1, TS: now},
+		{Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "2", TxID: "0x2", Address: "0xhot", LogIndex: -1, TS: past},
+		{Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "250.25", TxID: "0x3", Address: "0xhot", LogIndex: 1, TS: now},
+		{Chain: "ethereum", Coin: "PEPE", Direction: "in", Amount: "1000", TxID: "0x4", Address: "0xhot", LogIndex: 2, TS: now},
+		{Chain: "ethereum", Coin: "USDC", Direction: "in", Amount: "99", TxID: "0x5", Address: "0xhot", LogIndex: 3, TS: now, USDValue: &given},
+	}
+	body, _ := json.Marshal(events)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ingest/events?entity=binance", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("写入事件失败: %d %s", w.Code, w.Body.String())
+	}
+
+	var rows []pdb.TransferEvent
+	gdb.Order("tx_id").Find(&rows)
+	if len(rows) != 5 {
+		t.Fatalf("期望落库 5 条，实际 %d", len(rows))
+	}
+	want := map[string]*float64{"0x1": ptr(4500), "0x2": ptr(6801), "0x3": ptr(250.25), "0x4": nil, "0x5": ptr(99)}
+	for _, row := range rows {
+		w := want[row.TxID]
+		switch {
+		case w == nil && row.USDValue != nil:
+			t.Errorf("%s 无价格时 usd_value 应为空，实际 %v", row.TxID, *row.USDValue)
+		case w != nil && (row.USDValue == nil || *row.USDValue != *w):
+			t.Errorf("%s usd_value 期望 %v，实际 %v", row.TxID, *w, row.USDValue)
+		}
+	}
+	if len(pricer.calls) != 4 {
+		t.Errorf("自带 usd_value 的事件不应查价，实际查询 %v", pricer.calls)
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
@@ -3,6 +3,7 @@ package server
 import (
 	pdb "analysis/internal/db"
 	"analysis/internal/netutil"
+	"analysis/internal/util"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -56,10 +57,51 @@ type DynamicGridRange struct {
 	Reason     string  `json:"reason"`
 }
 
+// marketIngestSymbolRe 交易对只允许大写字母和数字
+var marketIngestSymbolRe = regexp.MustCompile(`^[A-Z0-9]{2,32}$`)
+
+// marketIngestMaxAttempts 快照写库遇到可重试错误时的最大尝试次数
+const marketIngestMaxAttempts = 3
+
+// marketIngestRowError 被拒绝的行及原因
+type marketIngestRowError struct {
+	Index  int    `json:"index"`
+	Symbol string `json:"symbol"`
+	Reason string `json:"reason"`
+}
+
+// validateMarketIngestRow 校验单行数据，返回拒绝原因（空表示通过）
+func validateMarketIngestRow(symbol, lastPrice, volume string, pct float64, seen map[string]bool) string {
+	if symbol == "" {
+		return "symbol 为空"
+	}
+	if !marketIngestSymbolRe.MatchString(symbol) {
+		return "symbol 格式错误"
+	}
+	if seen[symbol] {
+		return "symbol 重复"
+	}
+	if p, err := strconv.ParseFloat(strings.TrimSpace(lastPrice), 64); err != nil || p <= 0 || math.IsInf(p, 0) {
+		return "last_price 必须为正数"
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(volume), 64); err != nil || v < 0 || math.IsInf(v, 0) {
+		return "volume 必须为非负数"
+	}
+	if math.IsNaN(pct) || math.IsInf(pct, 0) {
+		return "price_change_percent 无效"
+	}
+	return ""
+}
+
 // 给采集进程写的入口：POST /ingest/binance/market
+// board=losers 的跌幅榜单独存储（见 pdb.MarketBoardKind），不影响按 kind 读取的涨幅榜
+// 逐行校验，合法行整体写入同一快照（单事务，可重试错误自动重试），非法行在响应中给出原因：
+// {"ok":true,"accepted":48,"rejected":2,"errors":[{"index":3,"symbol":"","reason":"symbol 为空"}]}
+// 全部行都被拒绝时返回 422，不写库
 func (s *Server) IngestBinanceMarket(c *gin.Context) {
 	var body struct {
 		Kind      string `json:"kind"`
+		Board     string `json:"board"` // gainers（默认）| losers
 		Bucket    string `json:"bucket"`
 		FetchedAt string `json:"fetched_at"`
 		Items     []struct {
@@ -77,9 +119,22 @@ func (s *Server) IngestBinanceMarket(c *gin.Context) {
 		s.JSONBindError(c, err)
 		return
 	}
+	body.Kind = strings.ToLower(strings.TrimSpace(body.Kind))
 	if body.Kind == "" {
 		body.Kind = "spot"
 	}
+	if body.Kind != "spot" && body.Kind != "futures" {
+		s.ValidationError(c, "kind", "仅支持 spot 或 futures")
+		return
+	}
+	body.Board = strings.ToLower(strings.TrimSpace(body.Board))
+	if body.Board == "" {
+		body.Board = pdb.MarketBoardGainers
+	}
+	if body.Board != pdb.MarketBoardGainers && body.Board != pdb.MarketBoardLosers {
+		s.ValidationError(c, "board", "仅支持 gainers 或 losers")
+		return
+	}
 
 	bucket, err := time.Parse(time.RFC3339, body.Bucket)
 	if err != nil {
@@ -98,21 +153,43 @@ func (s *Server) IngestBinanceMarket(c *gin.Context) {
 	bucket = bucket.UTC().Truncate(1 * time.Hour)
 
 	rows := make([]pdb.BinanceMarketTop, 0, len(body.Items))
+	rejected := make([]marketIngestRowError, 0)
+	seen := make(map[string]bool, len(body.Items))
 	for i, it := range body.Items {
+		symbol := strings.ToUpper(strings.TrimSpace(it.Symbol))
+		if reason := validateMarketIngestRow(symbol, it.LastPrice, it.Volume, it.PriceChangePercent, seen); reason != "" {
+			rejected = append(rejected, marketIngestRowError{Index: i, Symbol: it.Symbol, Reason: reason})
+			continue
+		}
+		seen[symbol] = true
 		rows = append(rows, pdb.BinanceMarketTop{
-			Symbol:            it.Symbol,
-			LastPrice:         it.LastPrice,
-			Volume:            it.Volume,
+			Symbol:            symbol,
+			LastPrice:         strings.TrimSpace(it.LastPrice),
+			Volume:            strings.TrimSpace(it.Volume),
 			PctChange:         it.PriceChangePercent,
-			Rank:              i + 1,
+			Rank:              len(rows) + 1,
 			MarketCapUSD:      it.MarketCapUSD,
 			FDVUSD:            it.FDVUSD,
 			CirculatingSupply: it.CirculatingSupply,
 			TotalSupply:       it.TotalSupply,
 		})
 	}
+	if len(rejected) > 0 {
+		log.Printf("[IngestBinanceMarket] %s %s: accepted=%d rejected=%d",
+			body.Kind, bucket.Format(time.RFC3339), len(rows), len(rejected))
+	}
+	if len(rows) == 0 && len(rejected) > 0 {
+		// 整批都不合法时不覆盖已有快照
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"ok":       false,
+			"accepted": 0,
+			"rejected": len(rejected),
+			"errors":   rejected,
+		})
+		return
+	}
 
-	if _, err := pdb.SaveBinanceMarket(s.db.DB(), body.Kind, bucket, fetchedAt, rows); err != nil {
+	if _, err := pdb.SaveBinanceMarketWithRetry(s.db.DB(), pdb.MarketBoardKind(body.Kind, body.Board), bucket, fetchedAt, rows, marketIngestMaxAttempts); err != nil {
 		s.DatabaseError(c, "保存市场数据", err)
 		return
 	}
@@ -122,7 +199,12 @@ func (s *Server) IngestBinanceMarket(c *gin.Context) {
 		log.Printf("[WARN] Failed to invalidate market cache: %v", err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	c.JSON(http.StatusOK, gin.H{
+		"ok":       true,
+		"accepted": len(rows),
+		"rejected": len(rejected),
+		"errors":   rejected,
+	})
 }
 
 // binanceMarketParams 市场查询参数
@@ -625,6 +707,9 @@ func (s *Server) GetBinanceMarket(c *gin.Context) {
 	// 查询市场数据
 	snaps, tops, err := pdb.ListBinanceMarket(s.db.DB(), params.Kind, startUTC, endUTC)
 	if err != nil {
+		if s.serveStaleBinanceMarket(c, params, err) {
+			return
+		}
 		s.DatabaseError(c, "查询市场数据", err)
 		return
 	}
@@ -632,15 +717,84 @@ func (s *Server) GetBinanceMarket(c *gin.Context) {
 	// 过滤和格式化数据
 	out, err := s.filterAndFormatMarketDataWithCategory(snaps, tops, params.Kind, params.Category, c.Request.Context())
 	if err != nil {
+		if s.serveStaleBinanceMarket(c, params, err) {
+			return
+		}
 		s.InternalServerError(c, "处理市场数据失败", err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"kind":     params.Kind,
 		"interval": params.IntervalMin,
 		"data":     out,
-	})
+	}
+	// as_of 取数据本身的时间：最新快照的时间桶，区间内没有快照时取区间起点
+	asOf := startUTC
+	for _, snap := range snaps {
+		if snap.Bucket.After(asOf) {
+			asOf = snap.Bucket
+		}
+	}
+	s.saveLastGoodBinanceMarket(c.Request.Context(), params, asOf.UTC(), resp)
+	c.JSON(http.StatusOK, resp)
+}
+
+// 最近一次成功响应的保留时间：上游或数据库故障期间用它降级
+const marketLastGoodTTL = 24 * time.Hour
+
+// marketLastGood 最近一次成功的榜单响应
+type marketLastGood struct {
+	AsOf     time.Time       `json:"as_of"` // 数据对应的时间（最新快照的时间桶），不是写入缓存的时间
+	Response json.RawMessage `json:"response"`
+}
+
+// marketLastGoodKey 本次查询的降级键；键里带日期，只会退回同一日期的数据
+func marketLastGoodKey(params *binanceMarketParams) string {
+	return BuildCacheKey("cache:v1:market:lkg", fmt.Sprintf("%s:%d:%s:%s:%s:%s",
+		params.Kind, params.IntervalMin, params.Date, params.Slot, params.Location.String(), params.Category))
+}
+
+// saveLastGoodBinanceMarket 记录成功响应，供故障时降级使用
+func (s *Server) saveLastGoodBinanceMarket(ctx context.Context, params *binanceMarketParams, asOf time.Time, resp gin.H) {
+	if s.cache == nil {
+		return
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(marketLastGood{AsOf: asOf, Response: body})
+	if err != nil {
+		return
+	}
+	key := marketLastGoodKey(params)
+	if err := s.cache.Set(ctx, key, data, marketLastGoodTTL); err != nil {
+		log.Printf("[WARN] Failed to save last-good market data (key=%s): %v", key, err)
+	}
+}
+
+// serveStaleBinanceMarket 无法生成新数据时返回最近一次成功的响应，并标记 stale 与 as_of；没有可用数据时返回 false
+func (s *Server) serveStaleBinanceMarket(c *gin.Context, params *binanceMarketParams, cause error) bool {
+	if s.cache == nil {
+		return false
+	}
+	data, err := s.cache.Get(c.Request.Context(), marketLastGoodKey(params))
+	if err != nil || len(data) == 0 {
+		return false
+	}
+	var lkg marketLastGood
+	var resp gin.H
+	if json.Unmarshal(data, &lkg) != nil || json.Unmarshal(lkg.Response, &resp) != nil {
+		return false
+	}
+	log.Printf("[WARN] Serving stale market data (kind=%s, date=%s, as_of=%s): %v", params.Kind, params.Date, lkg.AsOf.Format(time.RFC3339), cause)
+	resp["stale"] = true
+	resp["as_of"] = lkg.AsOf
+	c.Set(cacheSkipKey, true) // 降级数据不写入响应缓存
+	c.Header("Cache-Control", "no-cache")
+	c.JSON(http.StatusOK, resp)
+	return true
 }
 
 // WSRealTimeGainers WebSocket实时涨幅榜 - 新版本（使用数据同步器）
@@ -1516,6 +1670,7 @@ func (s *Server) SaveRealtimeGainersData(ctx context.Context, kind string, gaine
 
 // GetRealtimeGainersHistoryAPI 获取涨幅榜历史数据API
 // GET /market/binance/realtime-gainers/history?kind=spot&start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z&symbol=BTC&limit=10
+// 指定 symbol 时可加 bucket=5m/1h 等，额外返回按时间桶降采样的价格序列 series
 func (s *Server) GetRealtimeGainersHistoryAPI(c *gin.Context) {
 	kind := strings.ToLower(strings.TrimSpace(c.DefaultQuery("kind", "spot")))
 	startTimeStr := c.Query("start_time")
@@ -1528,6 +1683,15 @@ func (s *Server) GetRealtimeGainersHistoryAPI(c *gin.Context) {
 		limit = 20
 	}
 
+	var bucket time.Duration
+	if v := strings.TrimSpace(c.Query("bucket")); v != "" {
+		bucket, err = time.ParseDuration(v)
+		if err != nil || bucket <= 0 {
+			s.ValidationError(c, "bucket", "bucket 必须是正的时长，如 5m、1h")
+			return
+		}
+	}
+
 	// 解析时间
 	var startTime, endTime time.Time
 	if startTimeStr != "" {
@@ -1577,7 +1741,7 @@ func (s *Server) GetRealtimeGainersHistoryAPI(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"data":          result,
 		"count":         len(result),
 		"kind":          kind,
@@ -1586,7 +1750,35 @@ func (s *Server) GetRealtimeGainersHistoryAPI(c *gin.Context) {
 			"start": startTimeStr,
 			"end":   endTimeStr,
 		},
-	})
+	}
+	if bucket > 0 && symbol != "" {
+		resp["series"] = gainersPriceSeries(snapshots, itemsMap, bucket)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// gainersPriceSeries 把单个交易对在各快照中的价格按时间桶降采样为 OHLC
+func gainersPriceSeries(snapshots []pdb.RealtimeGainersSnapshot, itemsMap map[uint][]pdb.RealtimeGainersItem, bucket time.Duration) []gin.H {
+	var points []util.TimePoint
+	for _, snapshot := range snapshots {
+		for _, item := range itemsMap[snapshot.ID] {
+			points = append(points, util.TimePoint{Time: snapshot.Timestamp, Value: item.CurrentPrice})
+		}
+	}
+	buckets := util.Downsample(points, bucket)
+	series := make([]gin.H, 0, len(buckets))
+	for _, b := range buckets {
+		series = append(series, gin.H{
+			"timestamp": b.Start.Unix(),
+			"open":      b.Open,
+			"high":      b.High,
+			"low":       b.Low,
+			"close":     b.Close,
+			"mean":      b.Mean,
+			"count":     b.Count,
+		})
+	}
+	return series
 }
 
 // GetRealtimeGainersStatsAPI 获取涨幅榜数据统计API
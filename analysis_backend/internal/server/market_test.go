@@ -0,0 +1,201 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newMarketIngestServer(t *testing.T) (*Server, *gorm.DB) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开 sqlite 失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.BinanceMarketSnapshot{}, &pdb.BinanceMarketTop{}); err != nil {
+		t.Fatalf("建表失败: %v", err)
+	}
+	return &Server{db: NewGormDatabase(gdb)}, gdb
+}
+
+type marketIngestResp struct {
+	OK       bool                   `json:"ok"`
+	Accepted int                    `json:"accepted"`
+	Rejected int                    `json:"rejected"`
+	Errors   []marketIngestRowError `json:"errors"`
+}
+
+func doMarketIngest(t *testing.T, s *Server, body string) (int, marketIngestResp) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ingest/binance/market", s.IngestBinanceMarket)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/ingest/binance/market", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	var resp marketIngestResp
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+// TestIngestBinanceMarketPartial 合法行入库、非法行给出原因，rank 按合法行重新编号
+func TestIngestBinanceMarketPartial(t *testing.T) {
+	s, gdb := newMarketIngestServer(t)
+
+	code, resp := doMarketIngest(t, s, `{"kind":"spot","bucket":"2025-08-01T10:20:00Z","items":[
+		{"symbol":"BTCUSDT","last_price":"65000.1","volume":"1200","price_change_percent":3.2},
+		{"symbol":"","last_price":"1","volume":"1"},
+		{"symbol":"ETHUSDT","last_price":"abc","volume":"10"},
+		{"symbol":"solusdt","last_price":"150","volume":"300","price_change_percent":5},
+		{"symbol":"BTCUSDT","last_price":"65000","volume":"1"},
+		{"symbol":"BNB-USDT","last_price":"500","volume":"1"},
+		{"symbol":"XRPUSDT","last_price":"0.5","volume":"-1"}
+	]}`)
+	if code != http.StatusOK || !resp.OK {
+		t.Fatalf("期望 200 ok，实际 %d %+v", code, resp)
+	}
+	if resp.Accepted != 2 || resp.Rejected != 5 {
+		t.Fatalf("期望 accepted=2 rejected=5，实际 %+v", resp)
+	}
+	wantReasons := map[int]string{1: "symbol 为空", 2: "last_price 必须为正数", 4: "symbol 重复", 5: "symbol 格式错误", 6: "volume 必须为非负数"}
+	for _, e := range resp.Errors {
+		if wantReasons[e.Index] != e.Reason {
+			t.Errorf("第 %d 行: 期望原因 %q，实际 %q", e.Index, wantReasons[e.Index], e.Reason)
+		}
+	}
+
+	var snaps []pdb.BinanceMarketSnapshot
+	gdb.Find(&snaps)
+	if len(snaps) != 1 || snaps[0].Bucket.Minute() != 0 {
+		t.Fatalf("期望 1 个对齐到整点的快照，实际 %+v", snaps)
+	}
+	var tops []pdb.BinanceMarketTop
+	gdb.Order("`rank` ASC").Find(&tops)
+	if len(tops) != 2 || tops[0].Symbol != "BTCUSDT" || tops[1].Symbol != "SOLUSDT" || tops[1].Rank != 2 {
+		t.Errorf("入库行不符: %+v", tops)
+	}
+}
+
+// TestIngestBinanceMarketAllRejected 整批非法时返回 422 且不覆盖已有快照
+func TestIngestBinanceMarketAllRejected(t *testing.T) {
+	s, gdb := newMarketIngestServer(t)
+
+	if code, _ := doMarketIngest(t, s, `{"kind":"futures","bucket":"2025-08-01T10:00:00Z","items":[
+		{"symbol":"BTCUSDT","last_price":"65000","volume":"1"}]}`); code != http.StatusOK {
+		t.Fatalf("首批写入失败: %d", code)
+	}
+	code, resp := doMarketIngest(t, s, `{"kind":"futures","bucket":"2025-08-01T10:00:00Z","items":[
+		{"symbol":"","last_price":"1","volume":"1"},
+		{"symbol":"ETHUSDT","last_price":"0","volume":"1"}]}`)
+	if code != http.StatusUnprocessableEntity || resp.OK || resp.Rejected != 2 || len(resp.Errors) != 2 {
+		t.Fatalf("期望 422 且 rejected=2，实际 %d %+v", code, resp)
+	}
+	var n int64
+	gdb.Model(&pdb.BinanceMarketTop{}).Where("symbol = ?", "BTCUSDT").Count(&n)
+	if n != 1 {
+		t.Errorf("已有快照不应被覆盖，BTCUSDT 行数 %d", n)
+	}
+
+	if code, _ := doMarketIngest(t, s, `{"kind":"margin","bucket":"2025-08-01T10:00:00Z","items":[]}`); code != http.StatusBadRequest {
+		t.Errorf("非法 kind 应返回 400，实际 %d", code)
+	}
+}
+
+// TestIngestBinanceMarketLosersBoard 跌幅榜与涨幅榜分别存储为独立快照
+func TestIngestBinanceMarketLosersBoard(t *testing.T) {
+	s, gdb := newMarketIngestServer(t)
+
+	if code, _ := doMarketIngest(t, s, `{"kind":"spot","bucket":"2025-08-01T10:00:00Z","items":[
+		{"symbol":"AAAUSDT","last_price":"1","volume":"1","price_change_percent":12}]}`); code != http.StatusOK {
+		t.Fatalf("涨幅榜写入失败: %d", code)
+	}
+	if code, _ := doMarketIngest(t, s, `{"kind":"spot","board":"losers","bucket":"2025-08-01T10:00:00Z","items":[
+		{"symbol":"ZZZUSDT","last_price":"1","volume":"1","price_change_percent":-15},
+		{"symbol":"YYYUSDT","last_price":"1","volume":"1","price_change_percent":-9}]}`); code != http.StatusOK {
+		t.Fatalf("跌幅榜写入失败: %d", code)
+	}
+
+	for kind, want := range map[string]int{"spot": 1, pdb.MarketBoardKind("spot", pdb.MarketBoardLosers): 2} {
+		snaps, tops, err := pdb.ListBinanceMarket(gdb, kind, time.Time{}, time.Time{})
+		if err != nil || len(snaps) != 1 || len(tops[snaps[0].ID]) != want {
+			t.Errorf("%s: 期望 1 个快照 %d 条，实际 %d 个快照 %v (err=%v)", kind, want, len(snaps), tops, err)
+		}
+	}
+
+	if code, _ := doMarketIngest(t, s, `{"kind":"spot","board":"movers","bucket":"2025-08-01T10:00:00Z","items":[]}`); code != http.StatusBadRequest {
+		t.Errorf("非法 board 应返回 400，实际 %d", code)
+	}
+}
+
+func doGetBinanceMarket(t *testing.T, s *Server, query string) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/market/binance/top", s.GetBinanceMarket)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/market/binance/top?"+query, nil))
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+// TestGetBinanceMarketServesStaleOnDBError 数据库不可用时返回最近一次成功的数据并标记 stale，而不是 500
+func TestGetBinanceMarketServesStaleOnDBError(t *testing.T) {
+	s, gdb := newMarketIngestServer(t)
+	s.cache = pdb.NewMemoryCache()
+	// 预置 exchangeInfo 缓存，避免测试访问币安接口
+	_ = s.cache.Set(t.Context(), "exchange_info_spot", []byte("{}"), time.Hour)
+
+	bucket := time.Date(2025, 8, 1, 10, 0, 0, 0, time.UTC)
+	snap := pdb.BinanceMarketSnapshot{Kind: "spot", Bucket: bucket, FetchedAt: bucket}
+	if err := gdb.Create(&snap).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.Create(&pdb.BinanceMarketTop{SnapshotID: snap.ID, Symbol: "BTCUSDT", LastPrice: "65000", Volume: "1", PctChange: 3, Rank: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	code, fresh := doGetBinanceMarket(t, s, "kind=spot&date=2025-08-01&tz=UTC")
+	if code != http.StatusOK || fresh["stale"] != nil {
+		t.Fatalf("正常请求应返回新数据: %d %v", code, fresh)
+	}
+	if data, _ := fresh["data"].([]interface{}); len(data) != 1 {
+		t.Fatalf("期望 1 个快照: %v", fresh)
+	}
+
+	sqlDB, _ := gdb.DB()
+	sqlDB.Close()
+
+	code, stale := doGetBinanceMarket(t, s, "kind=spot&date=2025-08-01&tz=UTC")
+	if code != http.StatusOK || stale["stale"] != true || stale["as_of"] == nil {
+		t.Fatalf("数据库故障时应返回 stale 数据: %d %v", code, stale)
+	}
+	if data, _ := stale["data"].([]interface{}); len(data) != 1 {
+		t.Errorf("stale 数据应与最近一次成功响应一致: %v", stale)
+	}
+	// as_of 是数据的时间（最新快照时间桶），不是写入缓存的时间
+	if stale["as_of"] != bucket.Format(time.RFC3339) {
+		t.Errorf("as_of 应为数据时间 %s，实际 %v", bucket.Format(time.RFC3339), stale["as_of"])
+	}
+
+	// 其他日期没有成功过的数据时，不能拿 08-01 的数据冒充
+	if code, stale = doGetBinanceMarket(t, s, "kind=spot&date=2025-08-02&tz=UTC"); code == http.StatusOK {
+		t.Errorf("不应退回其他日期的数据: %d %v", code, stale)
+	}
+
+	// 从未成功过的市场仍返回错误
+	if code, _ = doGetBinanceMarket(t, s, "kind=futures&date=2025-08-01&tz=UTC"); code == http.StatusOK {
+		t.Errorf("没有可用的降级数据时不应返回 200")
+	}
+}
@@ -495,7 +495,8 @@ func (mlps *MLPretrainingService) buildTrainingData(symbol string, historicalDat
 		// 波动率特征
 		X.Set(i, featureIdx, safeFloat(calculateVolatility(prices, 20), 0)) // 波动率
 		featureIdx++
-		bbUpper, bbMiddle, bbLower, bbPosition, bbWidth := calculateBollingerBands(prices, 20, 2.0)
+		bb := calculateBollingerBands(prices, 20, 2.0)
+		bbUpper, bbMiddle, bbLower, bbPosition, bbWidth := bb.Upper, bb.Middle, bb.Lower, bb.PercentB, bb.Width
 		X.Set(i, featureIdx, safeFloat(bbUpper, prices[len(prices)-1]))
 		featureIdx++
 		X.Set(i, featureIdx, safeFloat(bbMiddle, prices[len(prices)-1]))
@@ -122,7 +122,7 @@ func calculateTechnicalIndicators(klines []BinanceKline) (TechnicalIndicators, e
 	// 计算完整的指标
 	rsi := calculateRSI(closes, 14)
 	macd, signal, hist := calculateMACD(closes, 12, 26, 9)
-	bbMiddle, bbUpper, bbLower, bbWidth, bbPosition := calculateBollingerBands(closes, 20, 2.0)
+	bb := calculateBollingerBands(closes, 20, 2.0)
 	trend := determineTrend(rsi, macd, signal)
 
 	indicators := TechnicalIndicators{
@@ -130,11 +130,11 @@ func calculateTechnicalIndicators(klines []BinanceKline) (TechnicalIndicators, e
 		MACD:       macd,
 		MACDSignal: signal,
 		MACDHist:   hist,
-		BBUpper:    bbUpper,
-		BBMiddle:   bbMiddle,
-		BBLower:    bbLower,
-		BBWidth:    bbWidth,
-		BBPosition: bbPosition,
+		BBUpper:    bb.Upper,
+		BBMiddle:   bb.Middle,
+		BBLower:    bb.Lower,
+		BBWidth:    bb.Width,
+		BBPosition: bb.PercentB,
 		Trend:      trend,
 	}
 
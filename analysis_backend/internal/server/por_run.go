@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"analysis/internal/addr"
+	"analysis/internal/collector"
+	"analysis/internal/config"
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+	"analysis/internal/price"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ===== 手动触发 PoR =====
+
+// PoR 运行状态
+const (
+	PORRunQueued    = "queued"
+	PORRunRunning   = "running"
+	PORRunCompleted = "completed"
+	PORRunFailed    = "failed"
+)
+
+const (
+	porRunDefaultWindow = 7 * 24 * time.Hour
+	porRunMaxWindow     = 90 * 24 * time.Hour
+	porRunKeep          = 100 // 内存中保留的运行记录数
+)
+
+// PORRunRequest 一次 PoR 计算的范围：Entity 为空表示全部实体，资金流统计 [From, To)
+type PORRunRequest struct {
+	Entity string    `json:"entity,omitempty"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+}
+
+// PORRunStatus 运行状态，GET /runs?run_id= 返回
+type PORRunStatus struct {
+	RunID      string     `json:"run_id"`
+	Entity     string     `json:"entity,omitempty"`
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Entities   int        `json:"entities"` // 已写入的实体数
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// PORComputeFunc 计算并写入一次 PoR（快照与资金流使用同一个 runID），返回写入的实体数
+type PORComputeFunc func(ctx context.Context, req PORRunRequest, runID string, asOf time.Time) (int, error)
+
+// PORRunner 后台执行 PoR：同一时间只跑一个，其余排队；状态只保存在内存中
+type PORRunner struct {
+	compute PORComputeFunc
+	slot    chan struct{}
+	ctx     context.Context
+
+	mu    sync.Mutex
+	runs  map[string]*PORRunStatus
+	order []string // 按创建顺序，超过 porRunKeep 时淘汰最早的已结束记录
+}
+
+// NewPORRunner ctx 取消后排队中的运行直接失败，进行中的运行随 ctx 取消
+func NewPORRunner(ctx context.Context, compute PORComputeFunc) *PORRunner {
+	return &PORRunner{compute: compute, slot: make(chan struct{}, 1), ctx: ctx, runs: map[string]*PORRunStatus{}}
+}
+
+// Start 登记一次运行并在后台执行，立即返回 run_id
+func (r *PORRunner) Start(req PORRunRequest) string {
+	st := &PORRunStatus{
+		RunID:     uuid.NewString(),
+		Entity:    req.Entity,
+		From:      req.From,
+		To:        req.To,
+		Status:    PORRunQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	r.mu.Lock()
+	r.runs[st.RunID] = st
+	r.order = append(r.order, st.RunID)
+	r.evictLocked()
+	r.mu.Unlock()
+
+	go r.run(st.RunID, req)
+	return st.RunID
+}
+
+func (r *PORRunner) run(runID string, req PORRunRequest) {
+	select {
+	case r.slot <- struct{}{}:
+		defer func() { <-r.slot }()
+	case <-r.ctx.Done():
+		r.finish(runID, 0, r.ctx.Err())
+		return
+	}
+
+	asOf := time.Now().UTC()
+	r.update(runID, func(st *PORRunStatus) {
+		st.Status = PORRunRunning
+		st.StartedAt = &asOf
+	})
+	log.Printf("[por] run_id=%s entity=%q window=[%s, %s) started", runID, req.Entity, req.From.Format(time.RFC3339), req.To.Format(time.RFC3339))
+	n, err := r.compute(r.ctx, req, runID, asOf)
+	r.finish(runID, n, err)
+}
+
+func (r *PORRunner) finish(runID string, entities int, err error) {
+	now := time.Now().UTC()
+	r.update(runID, func(st *PORRunStatus) {
+		st.Entities = entities
+		st.FinishedAt = &now
+		st.Status = PORRunCompleted
+		if err != nil {
+			st.Status, st.Error = PORRunFailed, err.Error()
+		}
+	})
+	if err != nil {
+		log.Printf("[por] run_id=%s failed: %v", runID, err)
+	} else {
+		log.Printf("[por] run_id=%s completed entities=%d", runID, entities)
+	}
+}
+
+func (r *PORRunner) update(runID string, fn func(*PORRunStatus)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if st, ok := r.runs[runID]; ok {
+		fn(st)
+	}
+}
+
+func (r *PORRunner) evictLocked() {
+	for i := 0; len(r.order) > porRunKeep && i < len(r.order); {
+		st := r.runs[r.order[i]]
+		if st != nil && (st.Status == PORRunQueued || st.Status == PORRunRunning) {
+			i++
+			continue
+		}
+		delete(r.runs, r.order[i])
+		r.order = append(r.order[:i], r.order[i+1:]...)
+	}
+}
+
+// Get 运行状态的副本
+func (r *PORRunner) Get(runID string) (PORRunStatus, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st, ok := r.runs[runID]
+	if !ok {
+		return PORRunStatus{}, false
+	}
+	return *st, true
+}
+
+// NewPORCompute 与 cmd/por 相同的计算：地址取自配置，余额快照 + [From, To) 的周度/日度资金流，按实体事务写入。
+// 币种范围依赖 util.SetAllowed / SetEntityAllowed，由调用方在启动时设置
+func NewPORCompute(gdb *gorm.DB, cfg config.Config) PORComputeFunc {
+	return func(ctx context.Context, req PORRunRequest, runID string, asOf time.Time) (int, error) {
+		chainsCfg := config.BuildChainCfg(&cfg)
+		group := collector.GroupByEntity(addr.RowsFromConfig(cfg))
+		if req.Entity != "" {
+			rs, ok := group[req.Entity]
+			if !ok {
+				return 0, fmt.Errorf("entity %s has no addresses in config", req.Entity)
+			}
+			group = map[string][]models.AddressRow{req.Entity: rs}
+		}
+		if len(group) == 0 {
+			return 0, errors.New("no addresses in config")
+		}
+
+		priceSet, err := collector.PrefetchPrices(ctx, func(ctx context.Context, syms []string) (map[string]float64, error) {
+			return price.FetchPrices(ctx, cfg, syms)
+		}, group, chainsCfg)
+		if err != nil {
+			log.Printf("[por] run_id=%s price fetch failed: %v", runID, err)
+		}
+
+		saved := 0
+		for ent, rs := range group {
+			if err := ctx.Err(); err != nil {
+				return saved, err
+			}
+			var portfolios []models.Portfolio
+			if p, err := collector.ComputePortfolio(ctx, ent, rs, chainsCfg, priceSet.Prices); err != nil {
+				log.Printf("[por] run_id=%s compute portfolio %s: %v", runID, ent, err)
+			} else {
+				portfolios = append(portfolios, p)
+			}
+			wb, dbkt := models.WeeklyBucket{}, models.DailyBucket{}
+			collector.CollectFlows(ctx, ent, rs, chainsCfg, req.From, req.To, wb, dbkt, "")
+			var weekly []models.WeeklyResult
+			var daily []models.DailyResult
+			if len(wb) > 0 {
+				weekly = append(weekly, models.WeeklyResult{Entity: ent, Data: wb})
+			}
+			if len(dbkt) > 0 {
+				daily = append(daily, models.DailyResult{Entity: ent, Data: dbkt})
+			}
+			if len(portfolios)+len(weekly)+len(daily) == 0 {
+				continue
+			}
+			if err := pdb.SaveAll(gdb, runID, asOf, portfolios, weekly, daily); err != nil {
+				log.Printf("[por] run_id=%s entity=%s rolled back: %v", runID, ent, err)
+				continue
+			}
+			saved++
+		}
+		if saved == 0 {
+			return 0, errors.New("no entity saved")
+		}
+		return saved, nil
+	}
+}
+
+// SetPORRunner 设置 PoR 运行器；未设置时 /admin/por/run 返回 501
+func (s *Server) SetPORRunner(r *PORRunner) {
+	s.porRunner = r
+}
+
+// TriggerPORRun POST /admin/por/run
+// body: {"entity": "binance", "from": "2025-09-01", "to": "2025-09-07"}；entity 为空表示全部实体，
+// from/to 支持日期（to 含当天）或 RFC3339，默认最近 7 天。返回 202 与 run_id，通过 GET /runs?run_id= 查询状态
+func (s *Server) TriggerPORRun(c *gin.Context) {
+	if s.porRunner == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "por runner not configured"})
+		return
+	}
+	var body struct {
+		Entity string `json:"entity"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			s.JSONBindError(c, err)
+			return
+		}
+	}
+
+	to := time.Now().UTC()
+	if v := strings.TrimSpace(body.To); v != "" {
+		t, dateOnly, ok := parseSearchTime(v)
+		if !ok {
+			s.ValidationError(c, "to", "时间格式应为 YYYY-MM-DD 或 RFC3339")
+			return
+		}
+		if dateOnly {
+			t = t.Add(24 * time.Hour)
+		}
+		to = t
+	}
+	from := to.Add(-porRunDefaultWindow)
+	if v := strings.TrimSpace(body.From); v != "" {
+		t, _, ok := parseSearchTime(v)
+		if !ok {
+			s.ValidationError(c, "from", "时间格式应为 YYYY-MM-DD 或 RFC3339")
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		s.ValidationError(c, "from", "必须早于 to")
+		return
+	}
+	if to.Sub(from) > porRunMaxWindow {
+		s.ValidationError(c, "to", fmt.Sprintf("时间窗口不能超过 %d 天", int(porRunMaxWindow.Hours()/24)))
+		return
+	}
+
+	req := PORRunRequest{Entity: strings.TrimSpace(body.Entity), From: from, To: to}
+	runID := s.porRunner.Start(req)
+	st, _ := s.porRunner.Get(runID)
+	c.JSON(http.StatusAccepted, gin.H{"run_id": runID, "run": st})
+}
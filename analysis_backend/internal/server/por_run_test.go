@@ -0,0 +1,165 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type porRunsResp struct {
+	Items []struct {
+		RunID  string `json:"run_id"`
+		Entity string `json:"entity"`
+	} `json:"items"`
+	Run *PORRunStatus `json:"run"`
+}
+
+func newPORRunRouter(t *testing.T, compute PORComputeFunc) (*gin.Engine, *gorm.DB) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.PortfolioSnapshot{}, &pdb.Holding{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	gin.SetMode(gin.TestMode)
+	s := &Server{db: NewGormDatabase(gdb)}
+	s.SetPORRunner(NewPORRunner(ctx, compute))
+	r := gin.New()
+	r.GET("/runs", s.ListRuns)
+	r.POST("/admin/por/run", s.TriggerPORRun)
+	return r, gdb
+}
+
+func triggerPORRun(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/admin/por/run", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// waitPORRun 轮询 GET /runs?run_id= 直到状态为 want
+func waitPORRun(t *testing.T, r *gin.Engine, runID, want string) porRunsResp {
+	t.Helper()
+	var out porRunsResp
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/runs?run_id="+runID, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("runs status=%d body=%s", w.Code, w.Body.String())
+		}
+		out = porRunsResp{}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.Run != nil && out.Run.Status == want {
+			return out
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待状态 %s 超时，最后一次: %+v", want, out.Run)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestTriggerPORRun 触发后立即返回 run_id，后台执行期间与完成后都能通过 /runs 查询状态，完成后快照按 run_id 可查
+func TestTriggerPORRun(t *testing.T) {
+	release := make(chan struct{})
+	got := make(chan PORRunRequest, 1)
+	var r *gin.Engine
+	var gdb *gorm.DB
+	r, gdb = newPORRunRouter(t, func(ctx context.Context, req PORRunRequest, runID string, asOf time.Time) (int, error) {
+		got <- req
+		<-release
+		return 1, pdb.SaveAll(gdb, runID, asOf, []models.Portfolio{{Entity: req.Entity, TotalUSD: 1000}}, nil, nil)
+	})
+
+	w := triggerPORRun(r, `{"entity":"binance","from":"2025-09-01","to":"2025-09-07"}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("期望 202，实际 %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		RunID string `json:"run_id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.RunID == "" {
+		t.Fatalf("应返回 run_id: %s", w.Body.String())
+	}
+
+	req := <-got
+	if req.Entity != "binance" || !req.From.Equal(time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)) || !req.To.Equal(time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("窗口应为 [09-01, 09-08)（to 含当天）: %+v", req)
+	}
+	running := waitPORRun(t, r, created.RunID, PORRunRunning)
+	if running.Run.StartedAt == nil || len(running.Items) != 0 {
+		t.Errorf("运行中应有开始时间且尚无快照: %+v items=%v", running.Run, running.Items)
+	}
+
+	close(release)
+	done := waitPORRun(t, r, created.RunID, PORRunCompleted)
+	if done.Run.Entities != 1 || done.Run.FinishedAt == nil || done.Run.Error != "" {
+		t.Errorf("完成状态不符: %+v", done.Run)
+	}
+	if len(done.Items) != 1 || done.Items[0].RunID != created.RunID || done.Items[0].Entity != "binance" {
+		t.Errorf("完成后应能按 run_id 查到快照: %+v", done.Items)
+	}
+}
+
+// TestTriggerPORRunFailedAndValidation 计算失败记为 failed 并带错误信息；非法窗口返回 400；未配置运行器返回 501
+func TestTriggerPORRunFailedAndValidation(t *testing.T) {
+	r, _ := newPORRunRouter(t, func(context.Context, PORRunRequest, string, time.Time) (int, error) {
+		return 0, errors.New("no entity saved")
+	})
+
+	w := triggerPORRun(r, "")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("空 body 应按默认窗口触发，实际 %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		RunID string        `json:"run_id"`
+		Run   *PORRunStatus `json:"run"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if d := created.Run.To.Sub(created.Run.From); d != porRunDefaultWindow || created.Run.Entity != "" {
+		t.Errorf("默认应为全部实体最近 7 天，实际 %+v", created.Run)
+	}
+	if st := waitPORRun(t, r, created.RunID, PORRunFailed); st.Run.Error != "no entity saved" {
+		t.Errorf("失败原因不符: %+v", st.Run)
+	}
+
+	for _, body := range []string{
+		`{"from":"2025-09-08","to":"2025-09-01"}`,
+		`{"from":"2025-01-01","to":"2025-09-01"}`,
+		`{"from":"yesterday"}`,
+	} {
+		if w := triggerPORRun(r, body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s 应返回 400，实际 %d", body, w.Code)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	bare := gin.New()
+	bare.POST("/admin/por/run", (&Server{}).TriggerPORRun)
+	if w := triggerPORRun(bare, "{}"); w.Code != http.StatusNotImplemented {
+		t.Errorf("未配置运行器应返回 501，实际 %d", w.Code)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	pdb "analysis/internal/db"
+	"analysis/internal/indicators"
 	"analysis/internal/netutil"
 	"context"
 	"crypto/md5"
@@ -1313,10 +1314,11 @@ func (s *Server) calculateTechnicalIndicatorsFromKlines(klines []BinanceKline) (
 		volumes = append(volumes, volume)
 	}
 
-	// 使用与GetTechnicalIndicators相同的计算逻辑
+	// 使用与GetTechnicalIndicators相同的计算逻辑，回看窗口与回测共用默认值
+	w := indicators.DefaultWindows()
 	if len(closes) < 60 {
 		// 数据不足，只计算基本指标
-		rsi := calculateRSI(closes, 14)
+		rsi := calculateRSI(closes, w.RSI)
 		macd, signal, hist := calculateMACD(closes, 12, 26, 9)
 		trend := determineTrend(rsi, macd, signal)
 
@@ -1333,15 +1335,16 @@ func (s *Server) calculateTechnicalIndicatorsFromKlines(klines []BinanceKline) (
 	}
 
 	// 数据充足，计算完整的技术指标
-	rsi := calculateRSI(closes, 14)
+	rsi := calculateRSI(closes, w.RSI)
 	macd, signal, hist := calculateMACD(closes, 12, 26, 9)
-	bbMiddle, bbUpper, bbLower, bbPosition, _ := calculateBollingerBands(closes, 20, 2.0)
+	bb := calculateBollingerBands(closes, w.Bollinger, w.BollingerK)
+	bbMiddle, bbUpper, bbLower, bbPosition := bb.Middle, bb.Upper, bb.Lower, bb.PercentB
 
 	// 计算支撑阻力位
 	supportLevel, resistanceLevel, _, _ := calculateSupportResistance(highs, lows, closes, 20)
 
 	// 计算波动率
-	volatility20 := calculateVolatility(closes, 20)
+	volatility20 := calculateVolatility(closes, w.Volatility)
 	volatility5 := calculateVolatility(closes, 5)
 
 	// 计算威廉指标
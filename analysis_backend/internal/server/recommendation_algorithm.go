@@ -45,6 +45,8 @@ func (s *Server) generateRecommendationsWithNewAlgorithm(ctx context.Context, ki
 		return s.generateRecommendationsWithLegacyAlgorithm(ctx, kind, limit, targetDate)
 	}
 
+	marketData = s.filterBlacklistedMarketData(ctx, kind, marketData)
+
 	if len(marketData) == 0 {
 		log.Printf("[WARN] No market data available, falling back to legacy algorithm")
 		return s.generateRecommendationsWithLegacyAlgorithm(ctx, kind, limit, targetDate)
@@ -111,6 +113,57 @@ func (s *Server) convertAlgorithmResultsToDBFormat(recommendations []CoinRecomme
 	return dbRecs, nil
 }
 
+// recommendationRanksBefore 推荐排序：总分降序；同分按市值降序（无市值排在后面），再按交易对升序，保证相同输入得到相同顺序
+func recommendationRanksBefore(scoreA, scoreB float64, capA, capB *float64, symbolA, symbolB string) bool {
+	if scoreA != scoreB {
+		return scoreA > scoreB
+	}
+	ca, cb := 0.0, 0.0
+	if capA != nil {
+		ca = *capA
+	}
+	if capB != nil {
+		cb = *capB
+	}
+	if ca != cb {
+		return ca > cb
+	}
+	return symbolA < symbolB
+}
+
+// sortRecommendationScores 按 recommendationRanksBefore 排序
+func sortRecommendationScores(scores []RecommendationScore) {
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		return recommendationRanksBefore(a.TotalScore, b.TotalScore, a.Data.MarketCapUSD, b.Data.MarketCapUSD, a.Symbol, b.Symbol)
+	})
+}
+
+// filterBlacklistedMarketData 从候选中剔除黑名单币种（与行情展示使用同一份 spot/futures 黑名单），并记录剔除数量。
+// 黑名单读取失败时不过滤，避免推荐整体中断
+func (s *Server) filterBlacklistedMarketData(ctx context.Context, kind string, data []MarketDataPoint) []MarketDataPoint {
+	blacklistMap, err := s.getCachedBlacklistMap(ctx, kind)
+	if err != nil {
+		log.Printf("[WARN] Failed to get %s blacklist for recommendations: %v", kind, err)
+		return data
+	}
+	if len(blacklistMap) == 0 {
+		return data
+	}
+
+	filtered := make([]MarketDataPoint, 0, len(data))
+	for _, item := range data {
+		if blacklistMap[strings.ToUpper(item.Symbol)] {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+	if removed := len(data) - len(filtered); removed > 0 {
+		log.Printf("[INFO] Recommendation candidates: filtered %d blacklisted %s symbols (%d -> %d)", removed, kind, len(data), len(filtered))
+	}
+	return filtered
+}
+
 // getAnnouncementData 获取公告数据
 func (s *Server) getAnnouncementData(ctx context.Context) (map[string]bool, error) {
 	return s.getAnnouncementDataForRecommendation(ctx)
@@ -132,6 +185,7 @@ func (s *Server) generateRecommendationsWithLegacyAlgorithm(ctx context.Context,
 	if err != nil {
 		return nil, fmt.Errorf("failed to get market data: %w", err)
 	}
+	marketData = s.filterBlacklistedMarketData(ctx, kind, marketData)
 
 	if len(marketData) == 0 {
 		return nil, fmt.Errorf("no market data available for legacy algorithm")
@@ -189,9 +243,7 @@ func (s *Server) generateRecommendationsWithLegacyAlgorithm(ctx context.Context,
 	}
 
 	// 8. 按评分排序
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].TotalScore > scores[j].TotalScore
-	})
+	sortRecommendationScores(scores)
 
 	// 9. 转换为CoinRecommendation格式
 	var recommendations []CoinRecommendation
@@ -426,7 +478,7 @@ func (s *Server) calculateScore(
 		},
 	}, 0)
 
-	return RecommendationScore{
+	score := RecommendationScore{
 		Symbol:       symbol,
 		BaseSymbol:   baseSymbol,
 		TotalScore:   totalScore,
@@ -449,6 +501,8 @@ func (s *Server) calculateScore(
 		PriceChange24h:      0,                 // 暂时不使用
 		LastUpdated:         time.Now(),
 	}
+	score.Data.MarketCapUSD = item.MarketCapUSD // 同分时按市值排序
+	return score
 }
 
 // calculateMarketScore 计算市场表现评分
@@ -522,12 +576,19 @@ func (s *Server) calculateRiskLevel(score float64) string {
 
 // formatRecommendations 格式化推荐结果
 func formatRecommendations(recs []pdb.CoinRecommendation, s *Server, ctx context.Context) []gin.H {
+	return formatRecommendationsWithPrice(recs, func(symbol, kind string) (float64, error) {
+		return s.getCurrentPrice(ctx, symbol, kind)
+	})
+}
+
+// formatRecommendationsWithPrice 格式化推荐结果，当前价格由 priceOf 提供
+func formatRecommendationsWithPrice(recs []pdb.CoinRecommendation, priceOf func(symbol, kind string) (float64, error)) []gin.H {
 	formatted := make([]gin.H, 0, len(recs))
 
 	for _, rec := range recs {
 		// 获取当前价格
 		currentPrice := 0.0
-		if price, err := s.getCurrentPrice(ctx, rec.Symbol, rec.Kind); err == nil {
+		if price, err := priceOf(rec.Symbol, rec.Kind); err == nil {
 			currentPrice = price
 		}
 
@@ -800,8 +861,10 @@ func (s *Server) generateRecommendationsSimpleForDate(ctx context.Context, kind
 
 	// 5. 计算每个币种的得分
 	scores := make([]RecommendationScore, 0, len(candidates))
+	blacklisted := 0
 	for _, item := range candidates {
 		if blacklistMap[strings.ToUpper(item.Symbol)] {
+			blacklisted++
 			continue
 		}
 
@@ -842,6 +905,9 @@ func (s *Server) generateRecommendationsSimpleForDate(ctx context.Context, kind
 			scores = append(scores, score)
 		}
 	}
+	if blacklisted > 0 {
+		log.Printf("[INFO] Simple method: filtered %d blacklisted %s symbols from %d candidates", blacklisted, kind, len(candidates))
+	}
 
 	// 6. 分析市场状态并计算动态权重
 	marketState := s.analyzeMarketState(candidates)
@@ -881,9 +947,7 @@ func (s *Server) generateRecommendationsSimpleForDate(ctx context.Context, kind
 			scores[i].Scores.Sentiment*weights.SentimentWeight
 	}
 
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].TotalScore > scores[j].TotalScore
-	})
+	sortRecommendationScores(scores)
 
 	if len(scores) > limit {
 		scores = scores[:limit]
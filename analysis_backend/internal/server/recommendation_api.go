@@ -55,12 +55,31 @@ func (s *Server) GetFallbackStatus(c *gin.Context) {
 
 // GetCoinRecommendations 获取币种推荐
 // GET /recommendations/coins?kind=spot&limit=5&refresh=false
+// 大结果集：limit>10 或带 cursor 时游标分页（limit<=100），stream=ndjson 时逐条流式输出（limit<=1000）
 func (s *Server) GetCoinRecommendations(c *gin.Context) {
 	kind := strings.ToLower(strings.TrimSpace(c.DefaultQuery("kind", "spot")))
 	if kind != "spot" && kind != "futures" {
 		kind = "spot"
 	}
 
+	if wantsRecommendationPaging(c) {
+		var after recommendationCursor
+		if raw := c.Query("cursor"); raw != "" {
+			cur, err := decodeRecommendationCursor(raw)
+			if err != nil {
+				s.ValidationError(c, "cursor", err.Error())
+				return
+			}
+			after = cur
+		}
+		if wantsRecommendationStream(c) {
+			s.streamRecommendations(c, kind, after)
+		} else {
+			s.serveRecommendationPage(c, kind, after)
+		}
+		return
+	}
+
 	limit := 5
 	if limitStr := c.Query("limit"); limitStr != "" {
 		if n, err := strconv.Atoi(limitStr); err == nil && n > 0 && n <= 10 {
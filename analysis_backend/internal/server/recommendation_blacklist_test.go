@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newBlacklistRecommendationServer 写入一个 spot 行情快照（涨幅最高的几个为黑名单币种）
+func newBlacklistRecommendationServer(t *testing.T, at time.Time) (*Server, *gorm.DB) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.BinanceMarketSnapshot{}, &pdb.BinanceMarketTop{}, &pdb.BinanceSymbolBlacklist{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	snap := pdb.BinanceMarketSnapshot{Kind: "spot", Bucket: at, FetchedAt: at, CreatedAt: at}
+	gdb.Create(&snap)
+	symbols := []string{"SCAMUSDT", "RUGUSDT", "BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "ADAUSDT"}
+	for i, sym := range symbols {
+		gdb.Create(&pdb.BinanceMarketTop{
+			SnapshotID: snap.ID, Symbol: sym, Rank: i + 1, CreatedAt: at,
+			LastPrice: fmt.Sprintf("%d", 10+i), Volume: fmt.Sprintf("%d", 5_000_000-i*100_000),
+			PctChange: float64(60 - i*5), // 黑名单币种涨幅最高、成交额最大
+		})
+	}
+	gdb.Create(&pdb.BinanceSymbolBlacklist{Kind: "spot", Symbol: "scamusdt"})
+	gdb.Create(&pdb.BinanceSymbolBlacklist{Kind: "spot", Symbol: "RUGUSDT"})
+	gdb.Create(&pdb.BinanceSymbolBlacklist{Kind: "futures", Symbol: "BTCUSDT"}) // 只屏蔽合约
+
+	return &Server{db: NewGormDatabase(gdb)}, gdb
+}
+
+// TestFilterBlacklistedMarketData 按 kind 读取黑名单，大小写不敏感
+func TestFilterBlacklistedMarketData(t *testing.T) {
+	s, _ := newBlacklistRecommendationServer(t, time.Now().UTC())
+	data := []MarketDataPoint{{Symbol: "SCAMUSDT"}, {Symbol: "rugusdt"}, {Symbol: "BTCUSDT"}}
+
+	spot := s.filterBlacklistedMarketData(context.Background(), "spot", data)
+	if len(spot) != 1 || spot[0].Symbol != "BTCUSDT" {
+		t.Fatalf("spot 应只剩 BTCUSDT，实际 %+v", spot)
+	}
+	futures := s.filterBlacklistedMarketData(context.Background(), "futures", data)
+	if len(futures) != 2 || futures[0].Symbol != "SCAMUSDT" {
+		t.Fatalf("futures 应只剔除 BTCUSDT，实际 %+v", futures)
+	}
+}
+
+// TestGeneratedRecommendationsExcludeBlacklist 生成的推荐中永远不出现黑名单币种
+func TestGeneratedRecommendationsExcludeBlacklist(t *testing.T) {
+	at := time.Now().UTC().Truncate(time.Minute)
+	s, _ := newBlacklistRecommendationServer(t, at)
+
+	recs, err := s.generateRecommendationsForDate(context.Background(), "spot", 10, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) == 0 {
+		t.Fatal("期望生成推荐")
+	}
+	for _, rec := range recs {
+		if rec.Symbol == "SCAMUSDT" || rec.Symbol == "RUGUSDT" {
+			t.Fatalf("推荐中出现黑名单币种 %s: %+v", rec.Symbol, recs)
+		}
+	}
+}
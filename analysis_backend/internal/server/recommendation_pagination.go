@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ============================================================================
+// /recommendations/coins 大结果集：游标分页 + NDJSON 流式输出
+// limit<=10 且不带 cursor/stream 的请求仍走推荐缓存，行为不变
+// ============================================================================
+
+const (
+	recommendationCachedMaxLimit = 10   // 走推荐缓存的最大条数
+	recommendationPageMaxLimit   = 100  // 分页模式单页最大条数
+	recommendationStreamMaxLimit = 1000 // 流式模式单次最大条数
+	recommendationStreamChunk    = 50   // 流式模式每次从数据库读取的条数
+)
+
+// recommendationCursor 分页游标：固定批次时间，按 (rank, id) 递增翻页
+type recommendationCursor struct {
+	GeneratedAt time.Time
+	Rank        int
+	ID          uint
+}
+
+// encode 编码为不透明字符串：base64url("批次纳秒时间戳:rank:id")
+func (c recommendationCursor) encode() string {
+	raw := fmt.Sprintf("%d:%d:%d", c.GeneratedAt.UnixNano(), c.Rank, c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeRecommendationCursor(s string) (recommendationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return recommendationCursor{}, errors.New("cursor 格式错误")
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 {
+		return recommendationCursor{}, errors.New("cursor 格式错误")
+	}
+	ts, err1 := strconv.ParseInt(parts[0], 10, 64)
+	rank, err2 := strconv.Atoi(parts[1])
+	id, err3 := strconv.ParseUint(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || ts <= 0 {
+		return recommendationCursor{}, errors.New("cursor 格式错误")
+	}
+	return recommendationCursor{GeneratedAt: time.Unix(0, ts).UTC(), Rank: rank, ID: uint(id)}, nil
+}
+
+// wantsRecommendationStream stream=ndjson|true 或 Accept: application/x-ndjson 时流式输出
+func wantsRecommendationStream(c *gin.Context) bool {
+	switch strings.ToLower(c.Query("stream")) {
+	case "ndjson", "true", "1":
+		return true
+	}
+	return strings.Contains(c.GetHeader("Accept"), "application/x-ndjson")
+}
+
+// wantsRecommendationPaging 带 cursor、请求流式输出或 limit 超过缓存上限时走分页路径
+func wantsRecommendationPaging(c *gin.Context) bool {
+	if c.Query("cursor") != "" || wantsRecommendationStream(c) {
+		return true
+	}
+	n, err := strconv.Atoi(c.Query("limit"))
+	return err == nil && n > recommendationCachedMaxLimit
+}
+
+// recommendationPageLimit 解析分页模式的 limit，非法值取默认，超出上限截断
+func recommendationPageLimit(c *gin.Context, max int) int {
+	limit := recommendationPageMaxLimit
+	if n, err := strconv.Atoi(c.Query("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	if limit > max {
+		limit = max
+	}
+	return limit
+}
+
+// fetchRecommendationPage 从 after 之后读取最多 limit 条；after 为零值时从最新批次开头读取
+func (s *Server) fetchRecommendationPage(kind string, after recommendationCursor, limit int) ([]pdb.CoinRecommendation, time.Time, error) {
+	return pdb.GetRecommendationsPage(s.db.DB(), kind, after.GeneratedAt, after.Rank, after.ID, limit)
+}
+
+// cachedRecommendationPrice 大结果集只读价格缓存，不逐个请求交易所；未命中时价格为 0
+func (s *Server) cachedRecommendationPrice(symbol, kind string) (float64, error) {
+	cache, err := pdb.GetPriceCache(s.db.DB(), symbol, kind)
+	if err != nil || cache == nil {
+		return 0, errors.New("价格缓存未命中")
+	}
+	return strconv.ParseFloat(cache.Price, 64)
+}
+
+// serveRecommendationPage 分页返回推荐，响应带 next_cursor/has_more
+func (s *Server) serveRecommendationPage(c *gin.Context, kind string, after recommendationCursor) {
+	limit := recommendationPageLimit(c, recommendationPageMaxLimit)
+
+	// 多取一条判断是否还有下一页
+	recs, batch, err := s.fetchRecommendationPage(kind, after, limit+1)
+	if err != nil {
+		s.DatabaseError(c, "查询推荐", err)
+		return
+	}
+
+	hasMore := len(recs) > limit
+	if hasMore {
+		recs = recs[:limit]
+	}
+	resp := gin.H{
+		"generated_at":    batch,
+		"kind":            kind,
+		"recommendations": formatRecommendationsWithPrice(recs, s.cachedRecommendationPrice),
+		"has_more":        hasMore,
+		"next_cursor":     "",
+		"cached":          false,
+		"cache_type":      "paged",
+	}
+	if hasMore {
+		last := recs[len(recs)-1]
+		resp["next_cursor"] = recommendationCursor{GeneratedAt: batch, Rank: last.Rank, ID: last.ID}.encode()
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// streamRecommendations 以 NDJSON 逐条输出推荐，每读一批刷新一次；
+// 达到 limit 后仍有剩余时，最后一行为 {"next_cursor": "..."}
+func (s *Server) streamRecommendations(c *gin.Context, kind string, after recommendationCursor) {
+	limit := recommendationPageLimit(c, recommendationStreamMaxLimit)
+
+	w := c.Writer
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		c.String(http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	ctx := c.Request.Context()
+	encoder := json.NewEncoder(w)
+	cursor := after
+	for sent := 0; sent < limit; {
+		if ctx.Err() != nil {
+			return
+		}
+		chunk := recommendationStreamChunk
+		if limit-sent < chunk {
+			chunk = limit - sent
+		}
+		recs, batch, err := s.fetchRecommendationPage(kind, cursor, chunk)
+		if err != nil {
+			log.Printf("[ERROR] 流式读取推荐失败: %v", err)
+			_ = encoder.Encode(gin.H{"error": err.Error()})
+			flusher.Flush()
+			return
+		}
+		for _, item := range formatRecommendationsWithPrice(recs, s.cachedRecommendationPrice) {
+			if err := encoder.Encode(item); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		if len(recs) < chunk {
+			return
+		}
+		last := recs[len(recs)-1]
+		cursor = recommendationCursor{GeneratedAt: batch, Rank: last.Rank, ID: last.ID}
+		sent += len(recs)
+	}
+
+	// 已输出 limit 条，探测是否还有剩余
+	if more, _, err := s.fetchRecommendationPage(kind, cursor, 1); err == nil && len(more) > 0 {
+		_ = encoder.Encode(gin.H{"next_cursor": cursor.encode()})
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,157 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newRecommendationPagingServer 写入旧批次 5 条、最新批次 n 条 spot 推荐，并为 rank=1 写价格缓存
+func newRecommendationPagingServer(t *testing.T, n int) (*Server, *gin.Engine, *gorm.DB) {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.CoinRecommendation{}, &pdb.PriceCache{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	latest := time.Date(2025, 3, 1, 8, 0, 0, 0, time.UTC)
+	insert := func(at time.Time, count int) {
+		recs := make([]pdb.CoinRecommendation, 0, count)
+		for i := 1; i <= count; i++ {
+			recs = append(recs, pdb.CoinRecommendation{
+				GeneratedAt: at, Kind: "spot", Symbol: fmt.Sprintf("C%03dUSDT", i), Rank: i, TotalScore: float64(100 - i%100),
+			})
+		}
+		if err := pdb.SaveRecommendations(gdb, "spot", at, recs); err != nil {
+			t.Fatalf("写入推荐失败: %v", err)
+		}
+	}
+	insert(latest.Add(-time.Hour), 5)
+	insert(latest, n)
+	gdb.Create(&pdb.PriceCache{Symbol: "C001USDT", Kind: "spot", Price: "1.5", LastUpdated: time.Now()})
+
+	gin.SetMode(gin.TestMode)
+	s := &Server{db: NewGormDatabase(gdb)}
+	r := gin.New()
+	r.GET("/recommendations/coins", s.GetCoinRecommendations)
+	return s, r, gdb
+}
+
+type recommendationPage struct {
+	Kind            string           `json:"kind"`
+	GeneratedAt     time.Time        `json:"generated_at"`
+	Recommendations []map[string]any `json:"recommendations"`
+	HasMore         bool             `json:"has_more"`
+	NextCursor      string           `json:"next_cursor"`
+	CacheType       string           `json:"cache_type"`
+}
+
+func getRecommendationPage(t *testing.T, r *gin.Engine, query string) recommendationPage {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/recommendations/coins?"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s: 期望 200，实际 %d %s", query, w.Code, w.Body.String())
+	}
+	var page recommendationPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return page
+}
+
+// TestCoinRecommendationsCursorPaging limit>10 走分页：逐页读完最新批次，不重复不遗漏，不混入旧批次
+func TestCoinRecommendationsCursorPaging(t *testing.T) {
+	_, r, gdb := newRecommendationPagingServer(t, 45)
+
+	first := getRecommendationPage(t, r, "kind=spot&limit=20")
+	if first.CacheType != "paged" || len(first.Recommendations) != 20 || !first.HasMore || first.NextCursor == "" {
+		t.Fatalf("首页不符: %d 条, has_more=%v, cursor=%q", len(first.Recommendations), first.HasMore, first.NextCursor)
+	}
+	if first.Recommendations[0]["current_price"].(float64) != 1.5 {
+		t.Errorf("应使用价格缓存，实际 %v", first.Recommendations[0]["current_price"])
+	}
+
+	// 翻页期间写入新批次，游标固定在原批次
+	pdb.SaveRecommendations(gdb, "spot", first.GeneratedAt.Add(time.Hour), []pdb.CoinRecommendation{{Kind: "spot", Symbol: "NEWUSDT", Rank: 1}})
+
+	var symbols []string
+	page := first
+	for {
+		for _, rec := range page.Recommendations {
+			symbols = append(symbols, rec["symbol"].(string))
+		}
+		if !page.HasMore {
+			break
+		}
+		page = getRecommendationPage(t, r, "kind=spot&limit=20&cursor="+page.NextCursor)
+	}
+	if len(symbols) != 45 || symbols[0] != "C001USDT" || symbols[44] != "C045USDT" {
+		t.Fatalf("分页合计应为最新批次 45 条，实际 %d 条: %v", len(symbols), symbols)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("最后一页不应返回 next_cursor，实际 %q", page.NextCursor)
+	}
+}
+
+// TestCoinRecommendationsBadCursor 非法游标返回 400
+func TestCoinRecommendationsBadCursor(t *testing.T) {
+	_, r, _ := newRecommendationPagingServer(t, 3)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/recommendations/coins?cursor=not-a-cursor", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("期望 400，实际 %d", w.Code)
+	}
+}
+
+// TestCoinRecommendationsNDJSONStream stream=ndjson 逐行输出推荐，达到 limit 后最后一行给出续读游标
+func TestCoinRecommendationsNDJSONStream(t *testing.T) {
+	_, r, _ := newRecommendationPagingServer(t, 120)
+
+	readLines := func(query string) []map[string]any {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/recommendations/coins?"+query, nil))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/x-ndjson") {
+			t.Fatalf("期望 200 NDJSON，实际 %d %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		var lines []map[string]any
+		sc := bufio.NewScanner(w.Body)
+		for sc.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &line); err != nil {
+				t.Fatalf("非法 NDJSON 行 %q: %v", sc.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	lines := readLines("kind=spot&stream=ndjson&limit=70")
+	if len(lines) != 71 || lines[69]["symbol"] != "C070USDT" {
+		t.Fatalf("期望 70 条推荐 + 1 行游标，实际 %d 行", len(lines))
+	}
+	cursor, _ := lines[70]["next_cursor"].(string)
+	if cursor == "" {
+		t.Fatalf("最后一行应为 next_cursor，实际 %v", lines[70])
+	}
+
+	rest := readLines("kind=spot&stream=ndjson&limit=500&cursor=" + cursor)
+	if len(rest) != 50 || rest[0]["symbol"] != "C071USDT" || rest[49]["symbol"] != "C120USDT" {
+		t.Fatalf("续读应返回剩余 50 条且无游标行，实际 %d 行", len(rest))
+	}
+}
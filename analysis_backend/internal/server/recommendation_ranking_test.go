@@ -0,0 +1,67 @@
+package server
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func rankingCap(v float64) *float64 { return &v }
+
+// TestSortRecommendationScoresTieBreak 同分按市值降序、再按交易对升序；输入顺序不同结果相同
+func TestSortRecommendationScoresTieBreak(t *testing.T) {
+	base := []RecommendationScore{
+		{Symbol: "CCCUSDT", TotalScore: 80},
+		{Symbol: "BBBUSDT", TotalScore: 80},
+		{Symbol: "AAAUSDT", TotalScore: 80},
+		{Symbol: "BIGUSDT", TotalScore: 80},
+		{Symbol: "MIDUSDT", TotalScore: 80},
+		{Symbol: "TOPUSDT", TotalScore: 95},
+		{Symbol: "LOWUSDT", TotalScore: 10},
+	}
+	base[3].Data.MarketCapUSD = rankingCap(5e9)
+	base[4].Data.MarketCapUSD = rankingCap(1e9)
+	base[1].Data.MarketCapUSD = rankingCap(1e9) // 与 MIDUSDT 同市值，按交易对
+
+	want := []string{"TOPUSDT", "BIGUSDT", "BBBUSDT", "MIDUSDT", "AAAUSDT", "CCCUSDT", "LOWUSDT"}
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 20; round++ {
+		scores := append([]RecommendationScore(nil), base...)
+		rng.Shuffle(len(scores), func(i, j int) { scores[i], scores[j] = scores[j], scores[i] })
+		sortRecommendationScores(scores)
+
+		got := make([]string, len(scores))
+		for i, s := range scores {
+			got[i] = s.Symbol
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("第 %d 轮排序不符:\n got %v\nwant %v", round, got, want)
+		}
+	}
+}
+
+// TestApplyRiskFilteringTieBreak 新算法的候选按 map 遍历收集，同分时也要得到确定顺序
+func TestApplyRiskFilteringTieBreak(t *testing.T) {
+	alg := &CoinSelectionAlgorithm{config: AlgorithmConfig{MaxCandidates: 3, MinScore: 0, RiskTolerance: 1}}
+	features := map[string]*CoinFeatures{
+		"ETHUSDT": {Symbol: "ETHUSDT", MarketCap: rankingCap(4e11)},
+		"BTCUSDT": {Symbol: "BTCUSDT", MarketCap: rankingCap(1e12)},
+		"SOLUSDT": {Symbol: "SOLUSDT"},
+		"ADAUSDT": {Symbol: "ADAUSDT"},
+	}
+
+	for round := 0; round < 20; round++ {
+		scores := map[string]*CoinScore{}
+		for sym := range features {
+			scores[sym] = &CoinScore{Symbol: sym, TotalScore: 70}
+		}
+		got := alg.applyRiskFiltering(scores, features)
+		var symbols []string
+		for _, s := range got {
+			symbols = append(symbols, s.Symbol)
+		}
+		if want := []string{"BTCUSDT", "ETHUSDT", "ADAUSDT"}; !reflect.DeepEqual(symbols, want) {
+			t.Fatalf("第 %d 轮候选顺序不符: %v", round, symbols)
+		}
+	}
+}
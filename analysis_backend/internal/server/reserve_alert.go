@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/gorm"
+)
+
+// ReserveDropConfig 交易所储备下跌告警配置
+type ReserveDropConfig struct {
+	DropPercent   float64       // 相对窗口内峰值的跌幅阈值（百分比，如 10 表示 10%）
+	Window        time.Duration // 与最新快照比较的回看窗口
+	CheckInterval time.Duration // 检查间隔
+}
+
+// DefaultReserveDropConfig 默认配置：24 小时内储备较峰值下跌 10% 告警，每 10 分钟检查一次
+func DefaultReserveDropConfig() ReserveDropConfig {
+	return ReserveDropConfig{
+		DropPercent:   10,
+		Window:        24 * time.Hour,
+		CheckInterval: 10 * time.Minute,
+	}
+}
+
+// ReserveDropAlert 单个实体的储备下跌告警
+type ReserveDropAlert struct {
+	Entity      string    `json:"entity"`
+	PeakUSD     float64   `json:"peak_usd"`
+	PeakAt      time.Time `json:"peak_at"`
+	LatestUSD   float64   `json:"latest_usd"`
+	LatestAt    time.Time `json:"latest_at"`
+	DropPercent float64   `json:"drop_percent"`
+}
+
+// DetectReserveDrops 按实体比较最新快照与窗口内此前快照的峰值，返回跌幅达到阈值的实体（按实体名排序）。
+// 窗口以各实体最新快照时间为终点；窗口内没有更早快照或峰值非正的实体不参与判断。
+func DetectReserveDrops(snaps []pdb.PortfolioSnapshot, dropPercent float64, window time.Duration) []ReserveDropAlert {
+	byEntity := make(map[string][]pdb.PortfolioSnapshot)
+	for _, s := range snaps {
+		byEntity[s.Entity] = append(byEntity[s.Entity], s)
+	}
+
+	var alerts []ReserveDropAlert
+	for entity, list := range byEntity {
+		sort.Slice(list, func(i, j int) bool { return list[i].AsOf.Before(list[j].AsOf) })
+		latest := list[len(list)-1]
+		latestUSD, err := strconv.ParseFloat(strings.TrimSpace(latest.TotalUSD), 64)
+		if err != nil {
+			continue
+		}
+
+		since := latest.AsOf.Add(-window)
+		var peak *pdb.PortfolioSnapshot
+		var peakUSD float64
+		for i := range list[:len(list)-1] {
+			s := &list[i]
+			if s.AsOf.Before(since) {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(s.TotalUSD), 64)
+			if err != nil {
+				continue
+			}
+			if peak == nil || v > peakUSD {
+				peak, peakUSD = s, v
+			}
+		}
+		if peak == nil || peakUSD <= 0 {
+			continue
+		}
+
+		drop := (peakUSD - latestUSD) / peakUSD * 100
+		if drop >= dropPercent {
+			alerts = append(alerts, ReserveDropAlert{
+				Entity:      entity,
+				PeakUSD:     peakUSD,
+				PeakAt:      peak.AsOf,
+				LatestUSD:   latestUSD,
+				LatestAt:    latest.AsOf,
+				DropPercent: drop,
+			})
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Entity < alerts[j].Entity })
+	return alerts
+}
+
+// ReserveDropMonitor 定时检查组合快照，储备异常下跌时发送邮件/Webhook 告警。
+// 同一实体在一次下跌期间只告警一次，跌幅回到阈值以内后才会重新告警。
+type ReserveDropMonitor struct {
+	db         *gorm.DB
+	cfg        ReserveDropConfig
+	Mailer     Mailer       // 可选，邮件告警
+	WebhookURL string       // 可选，以 JSON POST 推送告警
+	HTTPClient *http.Client // 可选，默认 10 秒超时
+	Now        func() time.Time
+
+	mu      sync.Mutex
+	alerted map[string]bool
+}
+
+// NewReserveDropMonitor 创建储备下跌告警任务；配置中非正值使用默认值
+func NewReserveDropMonitor(db *gorm.DB, cfg ReserveDropConfig) *ReserveDropMonitor {
+	def := DefaultReserveDropConfig()
+	if cfg.DropPercent <= 0 {
+		cfg.DropPercent = def.DropPercent
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = def.CheckInterval
+	}
+	return &ReserveDropMonitor{
+		db:      db,
+		cfg:     cfg,
+		Now:     time.Now,
+		alerted: make(map[string]bool),
+	}
+}
+
+// Run 立即检查一次，之后按间隔检查，直到 ctx 取消
+func (m *ReserveDropMonitor) Run(ctx context.Context) {
+	log.Printf("[ReserveDrop] 储备下跌告警已启动，阈值 %.2f%%，窗口 %v，检查间隔 %v", m.cfg.DropPercent, m.cfg.Window, m.cfg.CheckInterval)
+	ticker := time.NewTicker(m.cfg.CheckInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Check(ctx); err != nil {
+			log.Printf("[ReserveDrop] 检查失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("[ReserveDrop] 储备下跌告警已停止")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check 加载窗口内快照并判断，返回本次新发出的告警
+func (m *ReserveDropMonitor) Check(ctx context.Context) ([]ReserveDropAlert, error) {
+	since := m.Now().Add(-m.cfg.Window)
+	var snaps []pdb.PortfolioSnapshot
+	if err := m.db.WithContext(ctx).
+		Where("as_of >= ?", since).
+		Order("as_of ASC").
+		Find(&snaps).Error; err != nil {
+		return nil, fmt.Errorf("load portfolio snapshots: %w", err)
+	}
+
+	dropped := DetectReserveDrops(snaps, m.cfg.DropPercent, m.cfg.Window)
+
+	m.mu.Lock()
+	current := make(map[string]bool, len(dropped))
+	var fresh []ReserveDropAlert
+	for _, a := range dropped {
+		current[a.Entity] = true
+		if !m.alerted[a.Entity] {
+			fresh = append(fresh, a)
+		}
+	}
+	for entity := range m.alerted {
+		if !current[entity] {
+			log.Printf("[ReserveDrop] %s 储备已恢复到阈值以内", entity)
+		}
+	}
+	m.alerted = current
+	m.mu.Unlock()
+
+	if len(fresh) == 0 {
+		return nil, nil
+	}
+	for _, a := range fresh {
+		log.Printf("[ReserveDrop] %s 储备下跌 %.2f%%: %.2f -> %.2f USD", a.Entity, a.DropPercent, a.PeakUSD, a.LatestUSD)
+	}
+	m.notify(ctx, fresh)
+	return fresh, nil
+}
+
+// notify 发送邮件与 Webhook；发送失败只记录日志，不影响下次检查
+func (m *ReserveDropMonitor) notify(ctx context.Context, alerts []ReserveDropAlert) {
+	if m.Mailer != nil {
+		subject, html, text := m.formatMail(alerts)
+		if err := m.Mailer.Send(subject, html, text); err != nil {
+			log.Printf("[ReserveDrop] 发送告警邮件失败: %v", err)
+		}
+	}
+	if m.WebhookURL != "" {
+		if err := m.postWebhook(ctx, alerts); err != nil {
+			log.Printf("[ReserveDrop] 推送 Webhook 失败: %v", err)
+		}
+	}
+}
+
+func (m *ReserveDropMonitor) formatMail(alerts []ReserveDropAlert) (subject, html, text string) {
+	entities := make([]string, 0, len(alerts))
+	var b strings.Builder
+	fmt.Fprintf(&b, "以下实体储备在 %v 内较峰值下跌超过 %.2f%%：\n", m.cfg.Window, m.cfg.DropPercent)
+	for _, a := range alerts {
+		entities = append(entities, a.Entity)
+		fmt.Fprintf(&b, "- %s: %.2f USD (%s) -> %.2f USD (%s)，下跌 %.2f%%\n",
+			a.Entity, a.PeakUSD, a.PeakAt.UTC().Format(time.RFC3339),
+			a.LatestUSD, a.LatestAt.UTC().Format(time.RFC3339), a.DropPercent)
+	}
+	text = b.String()
+	subject = fmt.Sprintf("[reserve] %s 储备下跌告警", strings.Join(entities, ", "))
+	return subject, "<pre>" + text + "</pre>", text
+}
+
+func (m *ReserveDropMonitor) postWebhook(ctx context.Context, alerts []ReserveDropAlert) error {
+	body, err := json.Marshal(map[string]any{
+		"type":         "reserve_drop",
+		"threshold":    m.cfg.DropPercent,
+		"window_hours": m.cfg.Window.Hours(),
+		"alerts":       alerts,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := m.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("webhook http %d", resp.StatusCode)
+	}
+	return nil
+}
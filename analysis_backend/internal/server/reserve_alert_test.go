@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type recordMailer struct {
+	mu       sync.Mutex
+	subjects []string
+}
+
+func (m *recordMailer) Send(subject, htmlBody, textBody string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subjects = append(m.subjects, subject)
+	return nil
+}
+
+func (m *recordMailer) count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.subjects)
+}
+
+// TestDetectReserveDrops 小幅波动不告警，窗口内较峰值大幅下跌告警，超出窗口的峰值不参与比较
+func TestDetectReserveDrops(t *testing.T) {
+	base := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	snap := func(entity string, hours int, usd string) pdb.PortfolioSnapshot {
+		return pdb.PortfolioSnapshot{Entity: entity, TotalUSD: usd, AsOf: base.Add(time.Duration(hours) * time.Hour)}
+	}
+	snaps := []pdb.PortfolioSnapshot{
+		// 正常波动：±3% 以内
+		snap("okx", 0, "1000000000"), snap("okx", 4, "1020000000"), snap("okx", 8, "990000000"), snap("okx", 12, "1005000000"),
+		// 大幅流出：峰值 2.1B -> 1.7B，约 19%
+		snap("binance", 0, "2000000000"), snap("binance", 4, "2100000000"), snap("binance", 8, "1900000000"), snap("binance", 12, "1700000000"),
+		// 峰值在窗口之外：30 小时前的高点不算
+		snap("bybit", -30, "900000000"), snap("bybit", 0, "500000000"), snap("bybit", 12, "480000000"),
+		// 只有一张快照，无法比较
+		snap("kraken", 12, "100"),
+	}
+
+	alerts := DetectReserveDrops(snaps, 10, 24*time.Hour)
+	if len(alerts) != 1 {
+		t.Fatalf("期望只有 binance 告警，实际 %+v", alerts)
+	}
+	a := alerts[0]
+	if a.Entity != "binance" || a.PeakUSD != 2.1e9 || a.LatestUSD != 1.7e9 {
+		t.Errorf("告警内容不符: %+v", a)
+	}
+	if a.DropPercent < 19 || a.DropPercent > 19.1 {
+		t.Errorf("跌幅期望约 19.05%%，实际 %.4f", a.DropPercent)
+	}
+	if !a.PeakAt.Equal(base.Add(4 * time.Hour)) {
+		t.Errorf("峰值时间不符: %v", a.PeakAt)
+	}
+}
+
+// TestReserveDropMonitorAlertsOnce 按快照序列逐步写入：噪声不告警，大跌时邮件与 Webhook 各发一次，持续下跌不重复告警，恢复后可再次告警
+func TestReserveDropMonitorAlertsOnce(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gdb.AutoMigrate(&pdb.PortfolioSnapshot{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var hookMu sync.Mutex
+	var hooks []map[string]any
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		hookMu.Lock()
+		hooks = append(hooks, body)
+		hookMu.Unlock()
+	}))
+	defer hook.Close()
+
+	mailer := &recordMailer{}
+	m := NewReserveDropMonitor(gdb, ReserveDropConfig{DropPercent: 10, Window: 24 * time.Hour})
+	m.Mailer = mailer
+	m.WebhookURL = hook.URL
+
+	base := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	series := []struct {
+		usd       string
+		wantAlert bool
+	}{
+		{"1000000000", false},
+		{"1015000000", false}, // 噪声
+		{"985000000", false},  // 噪声
+		{"1010000000", false}, // 噪声
+		{"850000000", true},   // 较峰值 1.015B 跌 16%
+		{"840000000", false},  // 仍在下跌期间，不重复告警
+		{"1000000000", false}, // 恢复
+		{"880000000", true},   // 再次下跌
+	}
+	for i, step := range series {
+		now := base.Add(time.Duration(i) * time.Hour)
+		if err := gdb.Create(&pdb.PortfolioSnapshot{RunID: fmt.Sprintf("run-%d", i), Entity: "binance", TotalUSD: step.usd, AsOf: now}).Error; err != nil {
+			t.Fatal(err)
+		}
+		m.Now = func() time.Time { return now }
+		fresh, err := m.Check(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(fresh) == 1; got != step.wantAlert {
+			t.Errorf("第%d张快照(%s) 告警期望 %v，实际 %+v", i, step.usd, step.wantAlert, fresh)
+		}
+	}
+
+	if mailer.count() != 2 {
+		t.Errorf("期望发送 2 封告警邮件，实际 %d", mailer.count())
+	}
+	hookMu.Lock()
+	defer hookMu.Unlock()
+	if len(hooks) != 2 {
+		t.Fatalf("期望推送 2 次 Webhook，实际 %d", len(hooks))
+	}
+	if hooks[0]["type"] != "reserve_drop" {
+		t.Errorf("Webhook 类型不符: %v", hooks[0])
+	}
+	list, _ := hooks[0]["alerts"].([]any)
+	if len(list) != 1 || list[0].(map[string]any)["entity"] != "binance" {
+		t.Errorf("Webhook 告警内容不符: %v", hooks[0]["alerts"])
+	}
+}
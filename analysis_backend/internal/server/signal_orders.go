@@ -0,0 +1,253 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pdb "analysis/internal/db"
+)
+
+// ============================================================================
+// 信号 → 订单转换层：把策略当前信号换算为目标持仓，与实际持仓对账后只提交差额订单
+// ============================================================================
+
+// TargetPosition 目标持仓，Quantity 带方向：正为多头、负为空头，单位与下单数量一致
+type TargetPosition struct {
+	Symbol   string  `json:"symbol"`
+	Quantity float64 `json:"quantity"`
+}
+
+// OrderDelta 从当前持仓调整到目标持仓需要提交的一笔订单
+type OrderDelta struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`     // BUY / SELL
+	Quantity   float64 `json:"quantity"` // 正数
+	ReduceOnly bool    `json:"reduce_only"`
+	Current    float64 `json:"current"` // 对账时的持仓
+	Target     float64 `json:"target"`  // 目标持仓
+}
+
+// defaultMinOrderQty 低于该数量的差额视为浮点误差，不下单
+const defaultMinOrderQty = 1e-9
+
+// SignalTargets 把策略信号换算为目标持仓：buy 做多、sell 做空，数量为 baseQty×Multiplier（Multiplier<=0 按 1）；
+// 其他动作（skip/no_op/allow）及没有信号的持仓币种保持当前持仓
+func SignalTargets(signals map[string]StrategyDecisionResult, current map[string]float64, baseQty map[string]float64) []TargetPosition {
+	targets := make(map[string]float64, len(current)+len(signals))
+	for sym, qty := range current {
+		targets[strings.ToUpper(sym)] = qty
+	}
+	for sym, decision := range signals {
+		sym = strings.ToUpper(sym)
+		qty := baseQty[sym]
+		if decision.Multiplier > 0 {
+			qty *= decision.Multiplier
+		}
+		switch decision.Action {
+		case "buy":
+			targets[sym] = math.Abs(qty)
+		case "sell":
+			targets[sym] = -math.Abs(qty)
+		}
+	}
+
+	out := make([]TargetPosition, 0, len(targets))
+	for sym, qty := range targets {
+		out = append(out, TargetPosition{Symbol: sym, Quantity: qty})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// ReconcilePositions 对比当前与目标持仓，只生成差额订单。targets 视为完整的目标集合，
+// 不在其中的持仓币种按目标 0 平仓；多空反转拆成“reduce_only 平仓 + 反向开仓”两笔。
+// 返回顺序：先所有减仓/平仓单（释放保证金），再所有开仓/加仓单，同组内按币种排序
+func ReconcilePositions(current map[string]float64, targets []TargetPosition, minQty float64) []OrderDelta {
+	if minQty <= 0 {
+		minQty = defaultMinOrderQty
+	}
+
+	want := make(map[string]float64, len(targets))
+	for _, t := range targets {
+		want[strings.ToUpper(t.Symbol)] = t.Quantity
+	}
+	have := make(map[string]float64, len(current))
+	for sym, qty := range current {
+		have[strings.ToUpper(sym)] = qty
+		if _, ok := want[strings.ToUpper(sym)]; !ok {
+			want[strings.ToUpper(sym)] = 0
+		}
+	}
+
+	symbols := make([]string, 0, len(want))
+	for sym := range want {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	var reduces, opens []OrderDelta
+	for _, sym := range symbols {
+		cur, tgt := have[sym], want[sym]
+		if math.Abs(tgt-cur) < minQty {
+			continue
+		}
+
+		// 反向或缩小的部分先平掉
+		closeQty := 0.0
+		switch {
+		case cur > 0 && tgt < cur:
+			closeQty = cur - math.Max(tgt, 0)
+		case cur < 0 && tgt > cur:
+			closeQty = -cur + math.Min(tgt, 0)
+		}
+		if closeQty >= minQty {
+			side := "SELL"
+			if cur < 0 {
+				side = "BUY"
+			}
+			reduces = append(reduces, OrderDelta{Symbol: sym, Side: side, Quantity: closeQty, ReduceOnly: true, Current: cur, Target: tgt})
+		}
+
+		// 剩余部分为同向加仓或反向开仓
+		openQty := 0.0
+		switch {
+		case tgt > 0 && tgt > math.Max(cur, 0):
+			openQty = tgt - math.Max(cur, 0)
+		case tgt < 0 && tgt < math.Min(cur, 0):
+			openQty = math.Min(cur, 0) - tgt
+		}
+		if openQty >= minQty {
+			side := "BUY"
+			if tgt < 0 {
+				side = "SELL"
+			}
+			opens = append(opens, OrderDelta{Symbol: sym, Side: side, Quantity: openQty, Current: cur, Target: tgt})
+		}
+	}
+	return append(reduces, opens...)
+}
+
+// OrderExecutor 差额订单的提交方式
+type OrderExecutor interface {
+	Submit(order *pdb.ScheduledOrder) error
+	// Paper 是否纸面执行（不会产生真实下单）
+	Paper() bool
+}
+
+// PaperOrderExecutor 纸面执行：只在内存记录订单，不写库、不下单
+type PaperOrderExecutor struct {
+	mu     sync.Mutex
+	orders []pdb.ScheduledOrder
+}
+
+func (p *PaperOrderExecutor) Submit(order *pdb.ScheduledOrder) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.orders = append(p.orders, *order)
+	return nil
+}
+
+func (p *PaperOrderExecutor) Paper() bool { return true }
+
+// Orders 已记录的纸面订单
+func (p *PaperOrderExecutor) Orders() []pdb.ScheduledOrder {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]pdb.ScheduledOrder(nil), p.orders...)
+}
+
+// ScheduledOrderExecutor 真实执行：写入定时订单，由 OrderScheduler 到点下单
+type ScheduledOrderExecutor struct {
+	db Database
+}
+
+// NewScheduledOrderExecutor 创建真实执行器，需显式传给 SignalOrderTranslator 才会生效
+func NewScheduledOrderExecutor(db Database) *ScheduledOrderExecutor {
+	return &ScheduledOrderExecutor{db: db}
+}
+
+func (e *ScheduledOrderExecutor) Submit(order *pdb.ScheduledOrder) error {
+	return e.db.CreateScheduledOrder(order)
+}
+
+func (e *ScheduledOrderExecutor) Paper() bool { return false }
+
+// SignalOrderTranslator 把策略信号转换为定时订单（默认纸面执行）
+type SignalOrderTranslator struct {
+	UserID     uint
+	StrategyID *uint
+	Exchange   string  // 默认 binance_futures
+	Testnet    bool    // 写入订单的 testnet 标记
+	MinQty     float64 // 最小下单数量，<=0 使用 defaultMinOrderQty
+	Executor   OrderExecutor
+
+	now func() time.Time
+}
+
+// NewSignalOrderTranslator 创建转换器；executor 为 nil 时使用纸面执行
+func NewSignalOrderTranslator(userID uint, strategyID *uint, executor OrderExecutor) *SignalOrderTranslator {
+	if executor == nil {
+		executor = &PaperOrderExecutor{}
+	}
+	return &SignalOrderTranslator{
+		UserID:     userID,
+		StrategyID: strategyID,
+		Exchange:   "binance_futures",
+		Executor:   executor,
+		now:        time.Now,
+	}
+}
+
+// TranslateSignals 按信号生成目标持仓并提交差额订单
+func (t *SignalOrderTranslator) TranslateSignals(signals map[string]StrategyDecisionResult, current map[string]float64, baseQty map[string]float64) ([]pdb.ScheduledOrder, error) {
+	return t.Translate(current, SignalTargets(signals, current, baseQty))
+}
+
+// Translate 对账当前与目标持仓，逐笔提交差额订单；提交失败时返回已提交的订单和错误
+func (t *SignalOrderTranslator) Translate(current map[string]float64, targets []TargetPosition) ([]pdb.ScheduledOrder, error) {
+	deltas := ReconcilePositions(current, targets, t.MinQty)
+	submitted := make([]pdb.ScheduledOrder, 0, len(deltas))
+	for _, d := range deltas {
+		order := t.buildOrder(d)
+		if err := t.Executor.Submit(&order); err != nil {
+			return submitted, fmt.Errorf("提交订单失败 %s %s %s: %w", d.Symbol, d.Side, order.Quantity, err)
+		}
+		submitted = append(submitted, order)
+	}
+
+	if len(submitted) > 0 {
+		log.Printf("[SignalOrders] 用户%d 提交%d笔差额订单 (paper=%v)", t.UserID, len(submitted), t.Executor.Paper())
+	}
+	return submitted, nil
+}
+
+func (t *SignalOrderTranslator) buildOrder(d OrderDelta) pdb.ScheduledOrder {
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	exchange := t.Exchange
+	if exchange == "" {
+		exchange = "binance_futures"
+	}
+	return pdb.ScheduledOrder{
+		UserID:      t.UserID,
+		Exchange:    exchange,
+		Testnet:     t.Testnet,
+		Symbol:      d.Symbol,
+		Side:        d.Side,
+		OrderType:   "MARKET",
+		Quantity:    strconv.FormatFloat(math.Round(d.Quantity*1e8)/1e8, 'f', -1, 64), // 去掉浮点差额的尾差，交易所精度由调度器调整
+		ReduceOnly:  d.ReduceOnly,
+		StrategyID:  t.StrategyID,
+		WorkingType: "MARK_PRICE",
+		TriggerTime: now().UTC(),
+		Status:      "pending",
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	pdb "analysis/internal/db"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestReconcilePositionsDeltas 只生成当前与目标之间的差额；反手拆成平仓+开仓，减仓单排在开仓单之前
+func TestReconcilePositionsDeltas(t *testing.T) {
+	current := map[string]float64{
+		"BTCUSDT":  0.5,  // 加仓到 0.8
+		"ETHUSDT":  2,    // 减仓到 1.5
+		"SOLUSDT":  10,   // 反手做空 -4
+		"DOGEUSDT": -100, // 不在目标中 → 平仓
+		"BNBUSDT":  3,    // 已达目标，不下单
+	}
+	targets := []TargetPosition{
+		{Symbol: "BTCUSDT", Quantity: 0.8},
+		{Symbol: "ethusdt", Quantity: 1.5},
+		{Symbol: "SOLUSDT", Quantity: -4},
+		{Symbol: "BNBUSDT", Quantity: 3},
+		{Symbol: "XRPUSDT", Quantity: -50}, // 新开空
+	}
+
+	got := ReconcilePositions(current, targets, 0)
+	type brief struct {
+		Symbol, Side string
+		Qty          float64
+		Reduce       bool
+	}
+	var gotBrief []brief
+	for _, d := range got {
+		gotBrief = append(gotBrief, brief{d.Symbol, d.Side, roundQty(d.Quantity), d.ReduceOnly})
+	}
+	want := []brief{
+		{"DOGEUSDT", "BUY", 100, true},
+		{"ETHUSDT", "SELL", 0.5, true},
+		{"SOLUSDT", "SELL", 10, true},
+		{"BTCUSDT", "BUY", 0.3, false},
+		{"SOLUSDT", "SELL", 4, false},
+		{"XRPUSDT", "SELL", 50, false},
+	}
+	if !reflect.DeepEqual(gotBrief, want) {
+		t.Fatalf("差额订单不符:\n got  %+v\n want %+v", gotBrief, want)
+	}
+
+	if again := ReconcilePositions(map[string]float64{"BTCUSDT": 0.8}, []TargetPosition{{Symbol: "BTCUSDT", Quantity: 0.8}}, 0); len(again) != 0 {
+		t.Errorf("已达目标不应下单，实际 %+v", again)
+	}
+}
+
+func roundQty(q float64) float64 {
+	return float64(int64(q*1e8+0.5)) / 1e8
+}
+
+// TestSignalTargetsKeepsUnsignaledPositions buy/sell 按基准数量×倍数设定目标，无信号或 skip 的币种保持现状
+func TestSignalTargetsKeepsUnsignaledPositions(t *testing.T) {
+	signals := map[string]StrategyDecisionResult{
+		"BTCUSDT": {Action: "buy", Multiplier: 2},
+		"ETHUSDT": {Action: "sell"},
+		"SOLUSDT": {Action: "skip"},
+	}
+	current := map[string]float64{"SOLUSDT": 5, "DOGEUSDT": -100}
+	baseQty := map[string]float64{"BTCUSDT": 0.1, "ETHUSDT": 1, "SOLUSDT": 20}
+
+	got := SignalTargets(signals, current, baseQty)
+	want := []TargetPosition{
+		{Symbol: "BTCUSDT", Quantity: 0.2},
+		{Symbol: "DOGEUSDT", Quantity: -100},
+		{Symbol: "ETHUSDT", Quantity: -1},
+		{Symbol: "SOLUSDT", Quantity: 5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("目标持仓不符: got %+v want %+v", got, want)
+	}
+}
+
+// TestSignalOrderTranslatorPaperByDefault 默认纸面执行不写库；显式传入真实执行器才写入定时订单
+func TestSignalOrderTranslatorPaperByDefault(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.ScheduledOrder{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	strategyID := uint(9)
+	signals := map[string]StrategyDecisionResult{"BTCUSDT": {Action: "buy"}}
+	current := map[string]float64{"ETHUSDT": -1}
+	baseQty := map[string]float64{"BTCUSDT": 0.01}
+
+	paper := NewSignalOrderTranslator(1, &strategyID, nil)
+	orders, err := paper.TranslateSignals(signals, current, baseQty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !paper.Executor.Paper() || len(orders) != 1 || orders[0].Symbol != "BTCUSDT" || orders[0].Quantity != "0.01" {
+		t.Fatalf("纸面执行订单不符: %+v", orders)
+	}
+	if recorded := paper.Executor.(*PaperOrderExecutor).Orders(); len(recorded) != 1 {
+		t.Errorf("纸面执行器应记录 1 笔，实际 %d", len(recorded))
+	}
+	var n int64
+	gdb.Model(&pdb.ScheduledOrder{}).Count(&n)
+	if n != 0 {
+		t.Fatalf("纸面执行不应写入定时订单，实际 %d", n)
+	}
+
+	live := NewSignalOrderTranslator(1, &strategyID, NewScheduledOrderExecutor(NewGormDatabase(gdb)))
+	if _, err := live.Translate(map[string]float64{"BTCUSDT": 0.01, "ETHUSDT": -1}, []TargetPosition{{Symbol: "BTCUSDT", Quantity: 0.01}}); err != nil {
+		t.Fatal(err)
+	}
+	var saved []pdb.ScheduledOrder
+	gdb.Find(&saved)
+	if len(saved) != 1 || saved[0].Symbol != "ETHUSDT" || saved[0].Side != "BUY" || !saved[0].ReduceOnly ||
+		saved[0].Status != "pending" || saved[0].StrategyID == nil || *saved[0].StrategyID != strategyID {
+		t.Fatalf("真实执行应只写入 ETHUSDT 平空单，实际 %+v", saved)
+	}
+}
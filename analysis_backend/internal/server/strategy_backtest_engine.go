@@ -57,8 +57,12 @@ func NewStrategyBacktestEngine(db Database, dataManager *DataManager) *StrategyB
 
 // ExecuteStrategyBacktest 执行策略回测
 func (sbe *StrategyBacktestEngine) ExecuteStrategyBacktest(perf *pdb.RecommendationPerformance) (*StrategyExecutionResult, error) {
+	return24h := 0.0
+	if perf.Return24h != nil {
+		return24h = *perf.Return24h
+	}
 	log.Printf("[StrategyBacktest] 开始执行策略回测: %s, 推荐价格: %.8f, 24h收益: %.2f%%",
-		perf.Symbol, perf.RecommendedPrice, perf.Return24h)
+		perf.Symbol, perf.RecommendedPrice, return24h)
 
 	// 1. 解析策略配置
 	config, err := sbe.parseStrategyConfig(perf)
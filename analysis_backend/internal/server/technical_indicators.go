@@ -12,6 +12,7 @@ import (
 	"time"
 
 	pdb "analysis/internal/db"
+	"analysis/internal/indicators"
 	"analysis/internal/netutil"
 )
 
@@ -182,7 +183,7 @@ func (s *Server) GetTechnicalIndicatorsWithDataPoints(ctx context.Context, symbo
 	macd, signal, hist := calculateMACD(closes, 12, 26, 9)
 
 	// 计算布林带（20周期，2倍标准差）
-	bbMiddle, bbUpper, bbLower, bbWidth, bbPosition := calculateBollingerBands(closes, 20, 2.0)
+	bb := calculateBollingerBands(closes, 20, 2.0)
 
 	// 计算KDJ指标（14周期）
 	k, d, j := calculateKDJ(highs, lows, closes, 14)
@@ -236,11 +237,11 @@ func (s *Server) GetTechnicalIndicatorsWithDataPoints(ctx context.Context, symbo
 
 	// 计算信号强度
 	signalStrength := calculateSignalStrength(TechnicalIndicators{
-		RSI: rsi, MACD: macd, MACDSignal: signal, BBPosition: bbPosition,
+		RSI: rsi, MACD: macd, MACDSignal: signal, BBPosition: bb.PercentB,
 	})
 
 	// 确定风险等级
-	riskLevel := calculateRiskLevel(rsi, bbPosition, volatility20)
+	riskLevel := calculateRiskLevel(rsi, bb.PercentB, volatility20)
 
 	indicators := &TechnicalIndicators{
 		RSI:                rsi,
@@ -248,13 +249,13 @@ func (s *Server) GetTechnicalIndicatorsWithDataPoints(ctx context.Context, symbo
 		MACDSignal:         signal,
 		MACDHist:           hist,
 		Trend:              trend,
-		BBUpper:            bbUpper,
-		BBMiddle:           bbMiddle,
-		BBLower:            bbLower,
-		BollingerUpper:     bbUpper, // 别名
-		BollingerLower:     bbLower, // 别名
-		BBWidth:            bbWidth,
-		BBPosition:         bbPosition,
+		BBUpper:            bb.Upper,
+		BBMiddle:           bb.Middle,
+		BBLower:            bb.Lower,
+		BollingerUpper:     bb.Upper, // 别名
+		BollingerLower:     bb.Lower, // 别名
+		BBWidth:            bb.Width,
+		BBPosition:         bb.PercentB,
 		K:                  k,
 		D:                  d,
 		J:                  j,
@@ -299,46 +300,10 @@ func (s *Server) GetTechnicalIndicatorsWithDataPoints(ctx context.Context, symbo
 }
 
 // 技术指标计算函数（RSI, MACD, 布林带等）
-func calculateRSI(closes []float64, period int) float64 {
-	if len(closes) < period+1 {
-		return 50
-	}
-
-	gains := make([]float64, 0, len(closes)-1)
-	losses := make([]float64, 0, len(closes)-1)
-
-	for i := 1; i < len(closes); i++ {
-		change := closes[i] - closes[i-1]
-		if change > 0 {
-			gains = append(gains, change)
-			losses = append(losses, 0)
-		} else {
-			gains = append(gains, 0)
-			losses = append(losses, -change)
-		}
-	}
-
-	// 计算初始平均值
-	var avgGain, avgLoss float64
-	for i := 0; i < period; i++ {
-		avgGain += gains[i]
-		avgLoss += losses[i]
-	}
-	avgGain /= float64(period)
-	avgLoss /= float64(period)
-
-	// 计算后续的指数移动平均
-	for i := period; i < len(gains); i++ {
-		avgGain = (avgGain*float64(period-1) + gains[i]) / float64(period)
-		avgLoss = (avgLoss*float64(period-1) + losses[i]) / float64(period)
-	}
-
-	if avgLoss == 0 {
-		return 100
-	}
+// RSI/EMA/SMA/布林带/波动率统一委托 internal/indicators，与回测引擎共用同一实现
 
-	rs := avgGain / avgLoss
-	return 100 - (100 / (1 + rs))
+func calculateRSI(closes []float64, period int) float64 {
+	return indicators.RSI(closes, period)
 }
 
 func calculateMACD(closes []float64, fastPeriod, slowPeriod, signalPeriod int) (float64, float64, float64) {
@@ -370,64 +335,18 @@ func calculateMACD(closes []float64, fastPeriod, slowPeriod, signalPeriod int) (
 }
 
 func calculateEMA(values []float64, period int) float64 {
-	if len(values) < period {
-		return 0
-	}
-
-	multiplier := 2.0 / (float64(period) + 1.0)
-	ema := values[0]
-
-	for i := 1; i < len(values); i++ {
-		ema = (values[i] * multiplier) + (ema * (1 - multiplier))
-	}
-
-	return ema
+	return indicators.EMA(values, period)
 }
 
 func calculateSMA(values []float64, period int) float64 {
-	if len(values) < period {
-		return 0
-	}
-
-	sum := 0.0
-	for i := len(values) - period; i < len(values); i++ {
-		sum += values[i]
-	}
-	return sum / float64(period)
+	return indicators.SMA(values, period)
 }
 
-func calculateBollingerBands(closes []float64, period int, stdDev float64) (float64, float64, float64, float64, float64) {
-	if len(closes) < period {
-		return 0, 0, 0, 0, 0.5
-	}
-
-	// 计算SMA
-	middle := calculateSMA(closes, period)
-
-	// 计算标准差
-	sum := 0.0
-	for i := len(closes) - period; i < len(closes); i++ {
-		sum += math.Pow(closes[i]-middle, 2)
-	}
-	std := math.Sqrt(sum / float64(period))
-
-	upper := middle + (std * stdDev)
-	lower := middle - (std * stdDev)
-
-	// 计算宽度（百分比）
-	width := (upper - lower) / middle
-
-	// 计算当前位置（0-1之间，0=下轨，1=上轨）
-	currentPrice := closes[len(closes)-1]
-	var position float64
-	if upper != lower {
-		position = (currentPrice - lower) / (upper - lower)
-		position = math.Max(0, math.Min(1, position)) // 限制在0-1之间
-	} else {
-		position = 0.5
-	}
-
-	return middle, upper, lower, width, position
+// calculateBollingerBands 布林带，位置 PercentB 限制在 0-1 之间（0=下轨，1=上轨）
+func calculateBollingerBands(closes []float64, period int, stdDev float64) indicators.Bands {
+	bands := indicators.Bollinger(closes, period, stdDev)
+	bands.PercentB = math.Max(0, math.Min(1, bands.PercentB))
+	return bands
 }
 
 func determineTrend(rsi, macd, signal float64) string {
@@ -624,36 +543,7 @@ func calculateMomentumDivergence(closes, highs, lows []float64, period int) floa
 }
 
 func calculateVolatility(closes []float64, period int) float64 {
-	if len(closes) < period {
-		return 0
-	}
-
-	// 计算收益率
-	returns := make([]float64, 0, len(closes)-1)
-	for i := 1; i < len(closes); i++ {
-		if closes[i-1] != 0 {
-			ret := (closes[i] - closes[i-1]) / closes[i-1]
-			returns = append(returns, ret)
-		}
-	}
-
-	if len(returns) < period {
-		return 0
-	}
-
-	// 计算标准差
-	sum := 0.0
-	mean := 0.0
-	for _, r := range returns {
-		mean += r
-	}
-	mean /= float64(len(returns))
-
-	for _, r := range returns {
-		sum += math.Pow(r-mean, 2)
-	}
-
-	return math.Sqrt(sum / float64(len(returns)))
+	return indicators.Volatility(closes, period)
 }
 
 func calculateWilliamsR(highs, lows, closes []float64, period int) float64 {
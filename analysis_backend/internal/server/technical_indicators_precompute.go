@@ -348,7 +348,8 @@ func (tips *TechnicalIndicatorsPrecomputeService) computeTechnicalIndicators(kli
 	indicators.Momentum10 = calculateMomentum(closes, 10)
 
 	// 波动率指标
-	indicators.BBUpper, indicators.BBMiddle, indicators.BBLower, indicators.BBPosition, indicators.BBWidth = calculateBollingerBands(closes, 20, 2.0)
+	bb := calculateBollingerBands(closes, 20, 2.0)
+	indicators.BBUpper, indicators.BBMiddle, indicators.BBLower, indicators.BBPosition, indicators.BBWidth = bb.Upper, bb.Middle, bb.Lower, bb.PercentB, bb.Width
 
 	// 震荡指标
 	indicators.K, indicators.D, indicators.J = calculateKDJ(highs, lows, closes, 14)
@@ -0,0 +1,74 @@
+package server
+
+import (
+	pdb "analysis/internal/db"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTransferFlowsWindow /transfers/flows 未指定 start 时的回看窗口
+const defaultTransferFlowsWindow = 7 * 24 * time.Hour
+
+// GET /transfers/flows?entity=binance&start=2025-09-01&end=2025-09-08&group_by=asset
+// 按资产汇总转账事件的流入/流出：group_by=asset（默认）按 asset_id 区分各链的同名代币，group_by=coin 按币种合并。
+// start/end 支持 RFC3339 或 YYYY-MM-DD，区间 [start, end)，默认最近 7 天
+func TransferFlows(s *Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		entity := strings.TrimSpace(c.Query("entity"))
+		groupBy := strings.ToLower(strings.TrimSpace(c.DefaultQuery("group_by", "asset")))
+		if groupBy != "asset" && groupBy != "coin" {
+			ValidationErrorHelper(c, "group_by", "只支持 asset 或 coin")
+			return
+		}
+
+		end := time.Now().UTC()
+		if v := strings.TrimSpace(c.Query("end")); v != "" {
+			t, ok := parseFlowTime(v)
+			if !ok {
+				ValidationErrorHelper(c, "end", "时间格式应为 RFC3339 或 YYYY-MM-DD")
+				return
+			}
+			end = t
+		}
+		start := end.Add(-defaultTransferFlowsWindow)
+		if v := strings.TrimSpace(c.Query("start")); v != "" {
+			t, ok := parseFlowTime(v)
+			if !ok {
+				ValidationErrorHelper(c, "start", "时间格式应为 RFC3339 或 YYYY-MM-DD")
+				return
+			}
+			start = t
+		}
+		if !start.Before(end) {
+			ValidationErrorHelper(c, "start", "必须早于 end")
+			return
+		}
+
+		flows, err := pdb.SumTransferFlows(s.db.DB(), entity, start, end, groupBy == "asset")
+		if err != nil {
+			DatabaseErrorHelper(c, "汇总转账流量", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"entity":   entity,
+			"group_by": groupBy,
+			"start":    start,
+			"end":      end,
+			"items":    flows,
+		})
+	}
+}
+
+// parseFlowTime 解析 RFC3339 或 YYYY-MM-DD（UTC 零点）
+func parseFlowTime(v string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), true
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
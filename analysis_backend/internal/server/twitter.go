@@ -35,15 +35,8 @@ type twitterTweetsResp struct {
 }
 
 func (s *Server) getTwitterUserID(ctx context.Context, username string) (string, error) {
-	if s.XBearer == "" {
-		return "", errors.New("twitter bearer token not configured")
-	}
 	u := "https://api.twitter.com/2/users/by/username/" + url.PathEscape(username) + "?user.fields=username"
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	req.Header.Set("Authorization", "Bearer "+s.XBearer)
-
-	// 优化：使用复用的 HTTP 客户端
-	resp, err := TwitterHTTPClient.Do(req)
+	resp, err := s.twitterGet(ctx, u)
 	if err != nil {
 		return "", err
 	}
@@ -61,7 +54,8 @@ func (s *Server) getTwitterUserID(ctx context.Context, username string) (string,
 	return out.Data.ID, nil
 }
 
-func (s *Server) fetchTweets(ctx context.Context, uid, username string, limit int, paginationToken string) ([]pdb.TwitterPost, string, error) {
+// fetchTweets sinceID 非空时只返回比它更新的推文（增量拉取）
+func (s *Server) fetchTweets(ctx context.Context, uid, username string, limit int, paginationToken, sinceID string) ([]pdb.TwitterPost, string, error) {
 	// Twitter API v2 限制：max_results 最大为 100
 	if limit <= 0 {
 		limit = 5
@@ -77,13 +71,12 @@ func (s *Server) fetchTweets(ctx context.Context, uid, username string, limit in
 	if paginationToken != "" {
 		params.Set("pagination_token", paginationToken)
 	}
+	if sinceID != "" {
+		params.Set("since_id", sinceID)
+	}
 	u := "https://api.twitter.com/2/users/" + uid + "/tweets?" + params.Encode()
 
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
-	req.Header.Set("Authorization", "Bearer "+s.XBearer)
-
-	// 优化：使用复用的 HTTP 客户端
-	resp, err := TwitterHTTPClient.Do(req)
+	resp, err := s.twitterGet(ctx, u)
 	if err != nil {
 		return nil, "", err
 	}
@@ -109,7 +102,16 @@ func (s *Server) fetchTweets(ctx context.Context, uid, username string, limit in
 	return items, out.Meta.NextToken, nil
 }
 
-// GET /twitter/fetch?username={name}&limit=50&store=1
+// newerTweetID 推文 ID 为递增的十进制字符串，先比长度再比字典序
+func newerTweetID(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a > b
+}
+
+// GET /twitter/fetch?username={name}&limit=50&store=1&since_id={id}
+// 响应中的 newest_id 可作为下一次的 since_id
 func (s *Server) FetchTwitterUserPosts(c *gin.Context) {
 	username := strings.TrimSpace(c.Query("username"))
 	if username == "" {
@@ -125,6 +127,7 @@ func (s *Server) FetchTwitterUserPosts(c *gin.Context) {
 	}
 	store := c.DefaultQuery("store", "1") != "0"
 	paginationToken := strings.TrimSpace(c.Query("pagination_token"))
+	sinceID := strings.TrimSpace(c.Query("since_id"))
 
 	ctx := c.Request.Context()
 	uid, err := s.getTwitterUserID(ctx, username)
@@ -132,7 +135,7 @@ func (s *Server) FetchTwitterUserPosts(c *gin.Context) {
 		s.BadRequest(c, "获取 Twitter 用户信息失败", err)
 		return
 	}
-	items, nextToken, err := s.fetchTweets(ctx, uid, username, limit, paginationToken)
+	items, nextToken, err := s.fetchTweets(ctx, uid, username, limit, paginationToken, sinceID)
 	if err != nil {
 		s.BadRequest(c, "获取推文失败", err)
 		return
@@ -146,9 +149,16 @@ func (s *Server) FetchTwitterUserPosts(c *gin.Context) {
 		_ = s.InvalidateTwitterCache(c.Request.Context())
 	}
 
+	newestID := sinceID
+	for _, it := range items {
+		if newerTweetID(it.TweetID, newestID) {
+			newestID = it.TweetID
+		}
+	}
 	response := gin.H{
-		"items": items,
-		"count": len(items),
+		"items":     items,
+		"count":     len(items),
+		"newest_id": newestID,
 	}
 	if nextToken != "" {
 		response["next_token"] = nextToken
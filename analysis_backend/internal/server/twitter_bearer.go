@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"analysis/internal/util"
+)
+
+// ===== Twitter Bearer 轮换 =====
+// 多个 Bearer 轮流使用：某个 token 返回 429 时进入冷却（优先按 x-rate-limit-reset，否则指数退避），
+// 请求自动切到下一个可用 token；冷却结束后重新参与轮换。
+
+// errNoTwitterBearer 未配置任何 token
+var errNoTwitterBearer = errors.New("twitter bearer token not configured")
+
+// ErrTwitterRateLimited 所有 token 都被限流
+var ErrTwitterRateLimited = errors.New("all twitter bearer tokens are rate limited")
+
+const (
+	twitterBearerBaseCooldown = time.Minute
+	twitterBearerMaxCooldown  = 15 * time.Minute // Twitter 限流窗口为 15 分钟
+)
+
+// TwitterBearerPool Bearer token 池
+type TwitterBearerPool struct {
+	mu       sync.Mutex
+	tokens   []string
+	idx      int
+	cooldown map[string]time.Time
+	backoff  map[string]*util.Backoff
+	now      func() time.Time
+}
+
+// NewTwitterBearerPool 创建 token 池（去空、去重，保持顺序）
+func NewTwitterBearerPool(tokens []string) *TwitterBearerPool {
+	p := &TwitterBearerPool{cooldown: map[string]time.Time{}, backoff: map[string]*util.Backoff{}, now: time.Now}
+	seen := map[string]bool{}
+	for _, t := range tokens {
+		if t = strings.TrimSpace(t); t != "" && !seen[t] {
+			seen[t] = true
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	return p
+}
+
+// ParseBearerTokens 解析逗号/换行分隔的 token 列表
+func ParseBearerTokens(s string) []string {
+	var out []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == ';' }) {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Len token 数量
+func (p *TwitterBearerPool) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.tokens)
+}
+
+// Pick 从当前位置起选第一个不在冷却中的 token
+func (p *TwitterBearerPool) Pick() (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for i := 0; i < len(p.tokens); i++ {
+		idx := (p.idx + i) % len(p.tokens)
+		tok := p.tokens[idx]
+		if until, ok := p.cooldown[tok]; ok && now.Before(until) {
+			continue
+		}
+		p.idx = idx
+		return tok, true
+	}
+	return "", false
+}
+
+// MarkRateLimited token 被限流：冷却到 resetAt（为零时按指数退避），并轮换到下一个 token
+func (p *TwitterBearerPool) MarkRateLimited(tok string, resetAt time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	bo := p.backoff[tok]
+	if bo == nil {
+		bo = &util.Backoff{Base: twitterBearerBaseCooldown, Factor: 2, Max: twitterBearerMaxCooldown}
+		p.backoff[tok] = bo
+	}
+	wait, _ := bo.Next()
+	until := now.Add(wait)
+	if resetAt.After(now) {
+		until = resetAt
+	}
+	p.cooldown[tok] = until
+	p.idx = (p.idx + 1) % len(p.tokens)
+	log.Printf("[twitter] bearer #%d rate limited, cooling until %s", p.indexOf(tok), until.UTC().Format(time.RFC3339))
+}
+
+// MarkOK 请求成功：清理冷却与退避状态
+func (p *TwitterBearerPool) MarkOK(tok string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.cooldown, tok)
+	delete(p.backoff, tok)
+}
+
+// indexOf 日志里只打印序号，避免泄露 token
+func (p *TwitterBearerPool) indexOf(tok string) int {
+	for i, t := range p.tokens {
+		if t == tok {
+			return i
+		}
+	}
+	return -1
+}
+
+// twitterRateLimitReset 解析 x-rate-limit-reset（unix 秒）
+func twitterRateLimitReset(h http.Header) time.Time {
+	if v := h.Get("x-rate-limit-reset"); v != "" {
+		if sec, err := strconv.ParseInt(v, 10, 64); err == nil && sec > 0 {
+			return time.Unix(sec, 0)
+		}
+	}
+	return time.Time{}
+}
+
+// twitterBearers 当前使用的 token 池；未设置池时退回单个 XBearer
+func (s *Server) twitterBearers() *TwitterBearerPool {
+	s.twitterBearerOnce.Do(func() {
+		if s.XBearers == nil {
+			s.XBearers = NewTwitterBearerPool([]string{s.XBearer})
+		}
+	})
+	return s.XBearers
+}
+
+// twitterGet 携带 Bearer 发起 GET；429 时换下一个 token 重试，直到没有可用 token
+func (s *Server) twitterGet(ctx context.Context, u string) (*http.Response, error) {
+	pool := s.twitterBearers()
+	if pool.Len() == 0 {
+		return nil, errNoTwitterBearer
+	}
+	for attempt := 0; attempt < pool.Len(); attempt++ {
+		tok, ok := pool.Pick()
+		if !ok {
+			break
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
+		// 优化：使用复用的 HTTP 客户端
+		resp, err := TwitterHTTPClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			resp.Body.Close()
+			pool.MarkRateLimited(tok, twitterRateLimitReset(resp.Header))
+			continue
+		}
+		pool.MarkOK(tok)
+		return resp, nil
+	}
+	return nil, ErrTwitterRateLimited
+}
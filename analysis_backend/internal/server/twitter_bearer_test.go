@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTwitterBearerRotation 被限流的 token 进入冷却，请求切到下一个 token；冷却结束后重新使用
+func TestTwitterBearerRotation(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	reset := now.Add(5 * time.Minute)
+
+	var mu sync.Mutex
+	limited := map[string]bool{"Bearer tok-a": true}
+	var used []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		used = append(used, auth)
+		isLimited := limited[auth]
+		mu.Unlock()
+		if isLimited {
+			w.Header().Set("x-rate-limit-reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	pool := NewTwitterBearerPool([]string{"tok-a", "tok-b", "tok-a", " "})
+	pool.now = func() time.Time { return now }
+	s := &Server{XBearers: pool}
+
+	get := func() {
+		t.Helper()
+		resp, err := s.twitterGet(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("twitterGet: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	get()
+	if len(used) != 2 || used[0] != "Bearer tok-a" || used[1] != "Bearer tok-b" {
+		t.Fatalf("tok-a 限流后应切到 tok-b，实际 %v", used)
+	}
+
+	// 冷却期内不再使用 tok-a
+	used = nil
+	get()
+	if len(used) != 1 || used[0] != "Bearer tok-b" {
+		t.Fatalf("冷却期内应直接使用 tok-b，实际 %v", used)
+	}
+
+	// tok-b 也被限流：没有可用 token
+	mu.Lock()
+	limited["Bearer tok-b"] = true
+	mu.Unlock()
+	if _, err := s.twitterGet(context.Background(), srv.URL); err != ErrTwitterRateLimited {
+		t.Fatalf("全部限流时应返回 ErrTwitterRateLimited，实际 %v", err)
+	}
+
+	// x-rate-limit-reset 之后 tok-a 恢复
+	mu.Lock()
+	limited = map[string]bool{}
+	mu.Unlock()
+	now = reset
+	used = nil
+	get()
+	if len(used) != 1 || used[0] != "Bearer tok-a" {
+		t.Fatalf("冷却结束后应重新使用 tok-a，实际 %v", used)
+	}
+}
+
+// TestTwitterBearerBackoffWithoutReset 没有 x-rate-limit-reset 时按指数退避冷却
+func TestTwitterBearerBackoffWithoutReset(t *testing.T) {
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	pool := NewTwitterBearerPool([]string{"only"})
+	pool.now = func() time.Time { return now }
+
+	pool.MarkRateLimited("only", time.Time{})
+	if _, ok := pool.Pick(); ok {
+		t.Fatal("冷却期内不应有可用 token")
+	}
+	now = now.Add(twitterBearerBaseCooldown)
+	if _, ok := pool.Pick(); !ok {
+		t.Fatal("首次冷却应为基础时长")
+	}
+	pool.MarkRateLimited("only", time.Time{})
+	now = now.Add(twitterBearerBaseCooldown)
+	if _, ok := pool.Pick(); ok {
+		t.Fatal("连续限流时冷却应翻倍")
+	}
+	pool.MarkOK("only")
+	if _, ok := pool.Pick(); !ok {
+		t.Fatal("成功后应清理冷却")
+	}
+}
+
+// TestParseBearerTokens 逗号/换行分隔并去空
+func TestParseBearerTokens(t *testing.T) {
+	got := ParseBearerTokens(" a, b\nc,, ")
+	if len(got) != 3 || got[0] != "a" || got[2] != "c" {
+		t.Errorf("解析结果不符: %v", got)
+	}
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportMyData 导出当前登录用户的推荐浏览记录、模拟交易与定时订单
+// GET /me/export
+// 用户身份只取自 JWT，忽略请求中的任何 user_id 参数，保证只能导出自己的数据
+func (s *Server) ExportMyData(c *gin.Context) {
+	uidVal, ok := c.Get("uid")
+	uid, _ := uidVal.(uint)
+	if !ok || uid == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	export, err := pdb.ExportUserActivity(s.db.DB(), uid)
+	if err != nil {
+		s.DatabaseError(c, "导出用户数据", err)
+		return
+	}
+
+	filename := fmt.Sprintf("export_user%d_%s.json", uid, time.Now().UTC().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.JSON(http.StatusOK, export)
+}
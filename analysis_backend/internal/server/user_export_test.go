@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newUserExportTestServer(t *testing.T) *Server {
+	t.Helper()
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.UserBehavior{}, &pdb.UserRecommendationFeedback{}, &pdb.SimulatedTrade{}, &pdb.ScheduledOrder{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	alice, bob := uint(1), uint(2)
+	ts := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	seed := []any{
+		&[]pdb.UserBehavior{
+			{UserID: &alice, ActionType: pdb.ActionTypeRecommendationView, ActionValue: "BTC", CreatedAt: ts},
+			{UserID: &alice, ActionType: pdb.ActionTypePageView, ActionValue: "/home", CreatedAt: ts},
+			{UserID: &bob, ActionType: pdb.ActionTypeRecommendationView, ActionValue: "ETH", CreatedAt: ts},
+		},
+		&[]pdb.UserRecommendationFeedback{
+			{UserID: &alice, RecommendationID: 10, Symbol: "BTCUSDT", Action: pdb.FeedbackActionFollow, CreatedAt: ts},
+			{UserID: &bob, RecommendationID: 11, Symbol: "ETHUSDT", Action: pdb.FeedbackActionBuy, CreatedAt: ts},
+		},
+		&[]pdb.SimulatedTrade{
+			{UserID: alice, Symbol: "BTCUSDT", Side: "BUY", Quantity: "1", Price: "60000", TotalValue: "60000", CreatedAt: ts},
+			{UserID: bob, Symbol: "ETHUSDT", Side: "BUY", Quantity: "2", Price: "2500", TotalValue: "5000", CreatedAt: ts},
+		},
+		&[]pdb.ScheduledOrder{
+			{UserID: alice, Exchange: "binance_futures", Symbol: "BTCUSDT", Side: "BUY", OrderType: "MARKET", Quantity: "0.01", TriggerTime: ts},
+			{UserID: bob, Exchange: "binance_futures", Symbol: "ETHUSDT", Side: "SELL", OrderType: "MARKET", Quantity: "1", TriggerTime: ts},
+			{UserID: bob, Exchange: "binance_futures", Symbol: "SOLUSDT", Side: "BUY", OrderType: "MARKET", Quantity: "3", TriggerTime: ts},
+		},
+	}
+	for _, rows := range seed {
+		if err := gdb.Create(rows).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	return &Server{db: NewGormDatabase(gdb)}
+}
+
+func doUserExport(t *testing.T, s *Server, uid uint, query string) pdb.UserActivityExport {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me/export", func(c *gin.Context) { c.Set("uid", uid) }, s.ExportMyData)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/export"+query, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("export status=%d body=%s", w.Code, w.Body.String())
+	}
+	var out pdb.UserActivityExport
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestExportMyDataOnlyOwnRecords 导出只包含当前用户的记录，即使请求带了别人的 user_id
+func TestExportMyDataOnlyOwnRecords(t *testing.T) {
+	s := newUserExportTestServer(t)
+
+	got := doUserExport(t, s, 1, "?user_id=2")
+	if got.UserID != 1 {
+		t.Fatalf("导出用户应为 1，实际 %d", got.UserID)
+	}
+	if len(got.RecommendationActivity) != 1 || got.RecommendationActivity[0].ActionValue != "BTC" {
+		t.Errorf("推荐浏览记录应只有 alice 的 BTC（不含普通页面浏览）: %+v", got.RecommendationActivity)
+	}
+	if len(got.RecommendationFeedback) != 1 || got.RecommendationFeedback[0].RecommendationID != 10 {
+		t.Errorf("推荐反馈不符: %+v", got.RecommendationFeedback)
+	}
+	if len(got.SimulatedTrades) != 1 || got.SimulatedTrades[0].Symbol != "BTCUSDT" {
+		t.Errorf("模拟交易不符: %+v", got.SimulatedTrades)
+	}
+	if len(got.ScheduledOrders) != 1 || got.ScheduledOrders[0].UserID != 1 {
+		t.Errorf("定时订单不符: %+v", got.ScheduledOrders)
+	}
+
+	bob := doUserExport(t, s, 2, "")
+	if len(bob.ScheduledOrders) != 2 || len(bob.SimulatedTrades) != 1 {
+		t.Errorf("bob 的导出不符: orders=%d trades=%d", len(bob.ScheduledOrders), len(bob.SimulatedTrades))
+	}
+	for _, o := range bob.ScheduledOrders {
+		if o.UserID != 2 {
+			t.Errorf("bob 的导出混入了其他用户的订单: %+v", o)
+		}
+	}
+}
+
+// TestExportMyDataRequiresUser 没有登录身份时拒绝导出
+func TestExportMyDataRequiresUser(t *testing.T) {
+	s := newUserExportTestServer(t)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me/export", s.ExportMyData)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me/export", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("期望 401，实际 %d", w.Code)
+	}
+}
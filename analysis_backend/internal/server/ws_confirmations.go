@@ -0,0 +1,184 @@
+package server
+
+import (
+	pdb "analysis/internal/db"
+	"encoding/json"
+	"log"
+	"strings"
+	"sync"
+)
+
+/*** ===== WS 转账确认数 ===== ***/
+// 链高度取自扫描器上报的游标（POST /sync/cursor，游标为下一个待扫区块）与入库事件的区块高度；
+// 确认数 = 高度 - 所在区块 + 1。未达到阈值的转账先记为 unconfirmed，高度推进后达到阈值时
+// 推送 {"type":"confirmations"} 把状态更新为 confirmed。没有区块高度的事件（BTC/Solana）不带确认信息。
+
+const (
+	TransferUnconfirmed = "unconfirmed"
+	TransferConfirmed   = "confirmed"
+
+	defaultTransferConfirmations = 12   // 未配置且不在内置表中的链
+	maxPendingConfirmations      = 5000 // 每条链最多跟踪的未确认转账，超出丢弃最旧的
+)
+
+// builtinTransferConfirmations 常见链的默认确认数阈值，chains[].confirmations 可覆盖
+var builtinTransferConfirmations = map[string]uint64{
+	"ethereum": 12,
+	"bsc":      15,
+	"polygon":  128,
+	"arbitrum": 20,
+	"optimism": 20,
+	"base":     20,
+	"tron":     19,
+}
+
+// transferConfirmation 推送给客户端的确认状态（transferDTO 与确认更新消息共用）
+type transferConfirmation struct {
+	ID            uint   `json:"id"`
+	Entity        string `json:"entity"`
+	Chain         string `json:"chain"`
+	TxID          string `json:"txid"`
+	BlockNumber   uint64 `json:"block_number"`
+	Confirmations uint64 `json:"confirmations"`
+	Required      uint64 `json:"required_confirmations"`
+	Status        string `json:"status"`
+}
+
+type wsConfirmationEnvelope struct {
+	Type string                 `json:"type"`
+	Data []transferConfirmation `json:"data"`
+}
+
+// confirmationTracker 各链高度、确认数阈值与待确认转账
+type confirmationTracker struct {
+	mu       sync.Mutex
+	required map[string]uint64
+	tips     map[string]uint64
+	pending  map[string][]transferConfirmation // chain -> 未达到阈值的转账（按广播顺序）
+}
+
+func newConfirmationTracker(required map[string]uint64) *confirmationTracker {
+	t := &confirmationTracker{tips: map[string]uint64{}, pending: map[string][]transferConfirmation{}}
+	t.setRequired(required)
+	return t
+}
+
+var transferConfirms = newConfirmationTracker(nil)
+
+// SetTransferConfirmations 按链配置确认数阈值（链名不区分大小写，0 表示使用默认值）
+func SetTransferConfirmations(required map[string]uint64) {
+	transferConfirms.setRequired(required)
+}
+
+func (t *confirmationTracker) setRequired(required map[string]uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.required = map[string]uint64{}
+	for ch, n := range required {
+		if n > 0 {
+			t.required[strings.ToLower(strings.TrimSpace(ch))] = n
+		}
+	}
+}
+
+func (t *confirmationTracker) requiredFor(chain string) uint64 {
+	if n, ok := t.required[chain]; ok {
+		return n
+	}
+	if n, ok := builtinTransferConfirmations[chain]; ok {
+		return n
+	}
+	return defaultTransferConfirmations
+}
+
+// status 按当前高度计算确认数与状态；高度未知或区块在高度之后时确认数为 0
+func (t *confirmationTracker) status(c *transferConfirmation) {
+	c.Required = t.requiredFor(c.Chain)
+	c.Confirmations = 0
+	if tip := t.tips[c.Chain]; tip >= c.BlockNumber {
+		c.Confirmations = tip - c.BlockNumber + 1
+	}
+	c.Status = TransferUnconfirmed
+	if c.Confirmations >= c.Required {
+		c.Status = TransferConfirmed
+	}
+}
+
+// Track 广播前为新转账计算确认状态；未达到阈值的记入待确认列表。没有区块高度的返回 nil
+func (t *confirmationTracker) Track(r pdb.TransferEvent) *transferConfirmation {
+	if r.BlockNum == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := transferConfirmation{
+		ID: r.ID, Entity: r.Entity, Chain: strings.ToLower(strings.TrimSpace(r.Chain)),
+		TxID: r.TxID, BlockNumber: r.BlockNum,
+	}
+	t.status(&c)
+	if c.Status != TransferConfirmed {
+		p := append(t.pending[c.Chain], c)
+		if len(p) > maxPendingConfirmations {
+			p = p[len(p)-maxPendingConfirmations:]
+		}
+		t.pending[c.Chain] = p
+	}
+	return &c
+}
+
+// AdvanceTip 链高度推进（只增不减），返回本次达到阈值的转账
+func (t *confirmationTracker) AdvanceTip(chain string, tip uint64) []transferConfirmation {
+	chain = strings.ToLower(strings.TrimSpace(chain))
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if chain == "" || tip <= t.tips[chain] {
+		return nil
+	}
+	t.tips[chain] = tip
+	var done []transferConfirmation
+	keep := t.pending[chain][:0]
+	for _, c := range t.pending[chain] {
+		t.status(&c)
+		if c.Status == TransferConfirmed {
+			done = append(done, c)
+		} else {
+			keep = append(keep, c)
+		}
+	}
+	t.pending[chain] = keep
+	return done
+}
+
+// noteChainTip 记录链高度并把达到阈值的转账按实体推送给订阅者
+func noteChainTip(chain string, tip uint64) {
+	done := transferConfirms.AdvanceTip(chain, tip)
+	if hub == nil || len(done) == 0 {
+		return
+	}
+	byEntity := map[string][]transferConfirmation{}
+	for _, c := range done {
+		byEntity[c.Entity] = append(byEntity[c.Entity], c)
+	}
+	for entity, list := range byEntity {
+		payload, err := json.Marshal(wsConfirmationEnvelope{Type: "confirmations", Data: list})
+		if err != nil {
+			log.Printf("[ERROR] Failed to marshal WebSocket confirmations: %v", err)
+			continue
+		}
+		hub.broadcast <- wsMessage{entity: entity, data: payload}
+	}
+}
+
+// noteIngestTips 入库事件的最大区块高度也视为链高度（事件所在区块已出块）
+func noteIngestTips(rows []pdb.TransferEvent) {
+	tips := map[string]uint64{}
+	for _, r := range rows {
+		ch := strings.ToLower(strings.TrimSpace(r.Chain))
+		if r.BlockNum > tips[ch] {
+			tips[ch] = r.BlockNum
+		}
+	}
+	for ch, tip := range tips {
+		noteChainTip(ch, tip)
+	}
+}
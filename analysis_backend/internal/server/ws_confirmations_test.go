@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pdb "analysis/internal/db"
+	"analysis/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestWSTransfersConfirmationStatus 推送的转账带确认数；游标推进到阈值后推送 confirmed 更新
+func TestWSTransfersConfirmationStatus(t *testing.T) {
+	gdb, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	if err := gdb.AutoMigrate(&pdb.TransferEvent{}, &pdb.TransferCursor{}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	oldHub, oldConfirms := hub, transferConfirms
+	defer func() { hub, transferConfirms = oldHub, oldConfirms }()
+	hub = &wsHub{broadcast: make(chan wsMessage, 16)} // 不启动 run，直接读取广播
+	transferConfirms = newConfirmationTracker(map[string]uint64{"Ethereum": 3})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ingest/events", IngestEvents(gdb))
+	r.POST("/sync/cursor", SetCursor(gdb))
+	post := func(url string, body any) {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, url, bytes.NewReader(b)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST %s 失败: %d %s", url, w.Code, w.Body.String())
+		}
+	}
+	setCursor := func(block uint64) {
+		post("/sync/cursor?entity=binance&chain=ethereum", map[string]uint64{"block": block})
+	}
+	next := func() map[string]any {
+		t.Helper()
+		select {
+		case m := <-hub.broadcast:
+			var out map[string]any
+			if err := json.Unmarshal(m.data, &out); err != nil {
+				t.Fatal(err)
+			}
+			return out
+		default:
+			return nil
+		}
+	}
+
+	setCursor(100) // 高度 99
+	now := time.Now().UTC()
+	post("/ingest/events?entity=binance", []models.Event{
+		{Chain: "ethereum", Coin: "ETH", Direction: "in", Amount: "500", TxID: "0xa", Address: "0xhot", LogIndex: -1, TS: now, BlockNumber: 99},
+		{Chain: "bitcoin", Coin: "BTC", Direction: "in", Amount: "10", TxID: "b1", Address: "bc1", LogIndex: 0, TS: now},
+	})
+	msg := next()
+	if msg == nil || msg["type"] != "transfers" {
+		t.Fatalf("期望 transfers 推送，实际 %v", msg)
+	}
+	data := msg["data"].([]any)
+	eth, btc := data[0].(map[string]any), data[1].(map[string]any)
+	if eth["status"] != TransferUnconfirmed || eth["confirmations"] != 1.0 || eth["required_confirmations"] != 3.0 || eth["block_number"] != 99.0 {
+		t.Errorf("ETH 转账确认信息不符: %v", eth)
+	}
+	if _, ok := btc["status"]; ok {
+		t.Errorf("没有区块高度的转账不应带确认状态: %v", btc)
+	}
+
+	setCursor(101) // 2 个确认，未达到阈值
+	if m := next(); m != nil {
+		t.Errorf("未达到阈值不应推送: %v", m)
+	}
+	setCursor(102) // 3 个确认
+	msg = next()
+	if msg == nil || msg["type"] != "confirmations" {
+		t.Fatalf("达到阈值应推送 confirmations，实际 %v", msg)
+	}
+	upd := msg["data"].([]any)[0].(map[string]any)
+	if upd["status"] != TransferConfirmed || upd["confirmations"] != 3.0 || upd["txid"] != "0xa" {
+		t.Errorf("确认更新不符: %v", upd)
+	}
+	setCursor(110)
+	if m := next(); m != nil {
+		t.Errorf("已确认的转账不应重复推送: %v", m)
+	}
+}
+
+// TestConfirmationTrackerThresholds 配置覆盖优先，其次内置默认值；高度只增不减
+func TestConfirmationTrackerThresholds(t *testing.T) {
+	ct := newConfirmationTracker(map[string]uint64{"bsc": 5, "polygon": 0})
+	for ch, want := range map[string]uint64{"bsc": 5, "polygon": 128, "tron": 19, "linea": defaultTransferConfirmations} {
+		if got := ct.requiredFor(ch); got != want {
+			t.Errorf("%s 阈值期望 %d，实际 %d", ch, want, got)
+		}
+	}
+
+	ct.AdvanceTip("bsc", 100)
+	c := ct.Track(pdb.TransferEvent{ID: 1, Chain: "BSC", BlockNum: 96})
+	if c == nil || c.Confirmations != 5 || c.Status != TransferConfirmed {
+		t.Fatalf("已达到阈值应直接为 confirmed: %+v", c)
+	}
+	c = ct.Track(pdb.TransferEvent{ID: 2, Chain: "bsc", BlockNum: 101})
+	if c.Confirmations != 0 || c.Status != TransferUnconfirmed {
+		t.Errorf("区块在已知高度之后确认数为 0: %+v", c)
+	}
+	if done := ct.AdvanceTip("bsc", 90); done != nil {
+		t.Errorf("高度回退应忽略: %+v", done)
+	}
+	if done := ct.AdvanceTip("bsc", 105); len(done) != 1 || done[0].ID != 2 {
+		t.Errorf("高度 105 时转账 2 应达到 5 个确认: %+v", done)
+	}
+	for i := 0; i < maxPendingConfirmations+10; i++ {
+		ct.Track(pdb.TransferEvent{ID: uint(i), Chain: "linea", TxID: fmt.Sprint(i), BlockNum: 1000})
+	}
+	if n := len(ct.pending["linea"]); n != maxPendingConfirmations {
+		t.Errorf("待确认列表应限制在 %d，实际 %d", maxPendingConfirmations, n)
+	}
+}
@@ -113,6 +113,12 @@ type transferDTO struct {
 	To         string    `json:"to"`
 	OccurredAt time.Time `json:"occurred_at"` // 交易发生时间
 	CreatedAt  time.Time `json:"created_at"`  // 同步到系统的时间
+
+	// WS 推送时按链高度计算的确认信息（见 ws_confirmations.go）；没有区块高度的链不返回
+	BlockNumber           uint64 `json:"block_number,omitempty"`
+	Confirmations         uint64 `json:"confirmations,omitempty"`
+	RequiredConfirmations uint64 `json:"required_confirmations,omitempty"`
+	Status                string `json:"status,omitempty"` // unconfirmed / confirmed
 }
 type wsEnvelope struct {
 	Type string        `json:"type"`
@@ -201,6 +207,7 @@ func WSTransfers(c *gin.Context) {
 /*** ===== 广播：由 /ingest/events 调用 ===== ***/
 
 func BroadcastTransfers(entity string, rows []pdb.TransferEvent) {
+	noteIngestTips(rows)
 	if hub == nil || len(rows) == 0 {
 		return
 	}
@@ -224,7 +231,7 @@ func BroadcastTransfers(entity string, rows []pdb.TransferEvent) {
 
 	out := make([]transferDTO, 0, len(filtered))
 	for _, r := range filtered {
-		out = append(out, transferDTO{
+		dto := transferDTO{
 			ID:         r.ID,
 			Entity:     r.Entity,
 			Chain:      r.Chain,
@@ -237,7 +244,11 @@ func BroadcastTransfers(entity string, rows []pdb.TransferEvent) {
 			To:         r.To,
 			OccurredAt: r.OccurredAt,
 			CreatedAt:  r.CreatedAt,
-		})
+		}
+		if c := transferConfirms.Track(r); c != nil {
+			dto.BlockNumber, dto.Confirmations, dto.RequiredConfirmations, dto.Status = c.BlockNumber, c.Confirmations, c.Required, c.Status
+		}
+		out = append(out, dto)
 	}
 	// 优化：添加错误处理
 	payload, err := json.Marshal(wsEnvelope{Type: "transfers", Data: out})
@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"analysis/internal/models"
+	"context"
+	"fmt"
+)
+
+// DefaultBatchSize 单次下发的默认最大事件数
+const DefaultBatchSize = 1000
+
+// ChunkedSink 把大批事件按 batchSize 拆块顺序下发，避免单个请求过大或超时；
+// 任一块失败立即返回错误，后续块不再发送，调用方据此不推进游标
+type ChunkedSink struct {
+	inner     EventSink
+	batchSize int
+}
+
+// NewChunkedSink batchSize<=0 时使用 DefaultBatchSize
+func NewChunkedSink(inner EventSink, batchSize int) *ChunkedSink {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &ChunkedSink{inner: inner, batchSize: batchSize}
+}
+
+func (s *ChunkedSink) Name() string { return s.inner.Name() }
+
+func (s *ChunkedSink) Publish(ctx context.Context, entity string, events []models.Event) error {
+	chunks := (len(events) + s.batchSize - 1) / s.batchSize
+	for i := 0; i < chunks; i++ {
+		lo := i * s.batchSize
+		hi := min(lo+s.batchSize, len(events))
+		if err := s.inner.Publish(ctx, entity, events[lo:hi]); err != nil {
+			return fmt.Errorf("chunk %d/%d (events %d-%d): %w", i+1, chunks, lo, hi-1, err)
+		}
+	}
+	return nil
+}
+
+func (s *ChunkedSink) Close() error { return s.inner.Close() }
@@ -0,0 +1,41 @@
+package sink
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/netutil"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// HTTPSink 通过 POST /ingest/events 上报（原有行为）
+type HTTPSink struct {
+	apiBase string
+}
+
+func NewHTTPSink(apiBase string) *HTTPSink {
+	return &HTTPSink{apiBase: strings.TrimRight(apiBase, "/")}
+}
+
+func (s *HTTPSink) Name() string { return "http" }
+
+func (s *HTTPSink) Publish(ctx context.Context, entity string, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	u := fmt.Sprintf("%s/ingest/events?entity=%s", s.apiBase, url.QueryEscape(entity))
+	var resp struct {
+		OK    bool   `json:"ok"`
+		Saved int    `json:"saved"`
+		RunID string `json:"run_id"`
+	}
+	if err := netutil.PostJSON(ctx, u, events, &resp); err != nil {
+		return err
+	}
+	log.Printf("ingest ok: entity=%s saved=%d run_id=%s", entity, resp.Saved, resp.RunID)
+	return nil
+}
+
+func (s *HTTPSink) Close() error { return nil }
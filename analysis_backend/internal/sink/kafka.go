@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"analysis/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaWriter 便于测试时替换 kafka.Writer
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// KafkaSink 每个 entity/chain 批次写一条消息，key 为 entity/chain（Hash 分区）
+type KafkaSink struct {
+	topic string
+	w     kafkaWriter
+}
+
+func NewKafkaSink(brokers []string, topic string) (*KafkaSink, error) {
+	var bs []string
+	for _, b := range brokers {
+		if b = strings.TrimSpace(b); b != "" {
+			bs = append(bs, b)
+		}
+	}
+	if len(bs) == 0 || strings.TrimSpace(topic) == "" {
+		return nil, fmt.Errorf("kafka sink requires event_sink.kafka.brokers and topic")
+	}
+	w := &kafka.Writer{
+		Addr:         kafka.TCP(bs...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		BatchTimeout: 50 * time.Millisecond,
+	}
+	return &KafkaSink{topic: topic, w: w}, nil
+}
+
+func (s *KafkaSink) Name() string { return "kafka" }
+
+func (s *KafkaSink) Publish(ctx context.Context, entity string, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	order, groups := groupByKey(entity, events)
+	msgs := make([]kafka.Message, 0, len(order))
+	for _, k := range order {
+		bs, err := json.Marshal(groups[k])
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, kafka.Message{Key: []byte(k), Value: bs})
+	}
+	if err := s.w.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("kafka write %s: %w", s.topic, err)
+	}
+	return nil
+}
+
+func (s *KafkaSink) Close() error { return s.w.Close() }
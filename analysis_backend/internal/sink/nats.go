@@ -0,0 +1,61 @@
+package sink
+
+import (
+	"analysis/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsConn 便于测试时替换 *nats.Conn
+type natsConn interface {
+	PublishMsg(m *nats.Msg) error
+	Drain() error
+}
+
+// NATSSink subject = <prefix>.<entity>.<chain>，同时在 header 中带上分区键
+type NATSSink struct {
+	prefix string
+	nc     natsConn
+}
+
+func NewNATSSink(url, subject string) (*NATSSink, error) {
+	if strings.TrimSpace(url) == "" || strings.TrimSpace(subject) == "" {
+		return nil, fmt.Errorf("nats sink requires event_sink.nats.url and subject")
+	}
+	nc, err := nats.Connect(url, nats.Name("analysis-scanner"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("nats connect %s: %w", url, err)
+	}
+	return &NATSSink{prefix: strings.TrimRight(subject, "."), nc: nc}, nil
+}
+
+func (s *NATSSink) Name() string { return "nats" }
+
+func (s *NATSSink) Publish(ctx context.Context, entity string, events []models.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	order, groups := groupByKey(entity, events)
+	for _, k := range order {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		bs, err := json.Marshal(groups[k])
+		if err != nil {
+			return err
+		}
+		subj := s.prefix + "." + strings.ReplaceAll(k, "/", ".")
+		msg := &nats.Msg{Subject: subj, Data: bs, Header: nats.Header{}}
+		msg.Header.Set("key", k)
+		if err := s.nc.PublishMsg(msg); err != nil {
+			return fmt.Errorf("nats publish %s: %w", subj, err)
+		}
+	}
+	return nil
+}
+
+func (s *NATSSink) Close() error { return s.nc.Drain() }
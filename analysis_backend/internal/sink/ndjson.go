@@ -0,0 +1,151 @@
+package sink
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// NDJSONSink 把事件逐行写成 JSON（scanner -dry-run）：不经过 API，便于调试配置时反复重扫同一窗口
+type NDJSONSink struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	closer io.Closer // 写 stdout 时为 nil
+	path   string    // 写文件时的路径，轮转用
+	size   int64     // 当前文件已写入的字节数（追加打开时从现有大小起算）
+	rot    NDJSONRotation
+	now    func() time.Time
+}
+
+// NDJSONRotation 文件输出的轮转：当前文件达到 MaxBytes 时归档为 path.<UTC 时间戳> 并重新打开，MaxBytes 为 0 不轮转；
+// 归档按 Retention 在打开时和每次轮转后清理（不轮转时只清理以前留下的归档）
+type NDJSONRotation struct {
+	MaxBytes  int64
+	Retention util.RetentionPolicy
+}
+
+// NewNDJSONSink path 为空或 "-" 时写 stdout，否则覆盖写入文件
+func NewNDJSONSink(path string) (*NDJSONSink, error) {
+	if path == "" || path == "-" {
+		return NewNDJSONWriter(os.Stdout), nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ndjson %s: %w", path, err)
+	}
+	s := NewNDJSONWriter(f)
+	s.closer = f
+	s.path = path
+	return s, nil
+}
+
+// NewRotatingNDJSONSink path 为空或 "-" 时写 stdout（rot 不生效），否则追加写入文件（重启不截断）并按 rot 轮转；
+// 打开时现有文件已达到上限的先归档，并按保留策略清理一次旧归档
+func NewRotatingNDJSONSink(path string, rot NDJSONRotation) (*NDJSONSink, error) {
+	if path == "" || path == "-" {
+		return NewNDJSONWriter(os.Stdout), nil
+	}
+	s := NewNDJSONWriter(nil)
+	s.path, s.rot = path, rot
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	if err := s.rotate(); err != nil {
+		s.closer.Close()
+		return nil, err
+	}
+	s.prune(s.now())
+	return s, nil
+}
+
+// open 追加打开当前文件，已写入字节数从现有大小起算
+func (s *NDJSONSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open ndjson %s: %w", s.path, err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat ndjson %s: %w", s.path, err)
+	}
+	s.w.Reset(f)
+	s.closer = f
+	s.size = st.Size()
+	return nil
+}
+
+// NewNDJSONWriter 写入任意 io.Writer（测试用）
+func NewNDJSONWriter(w io.Writer) *NDJSONSink {
+	return &NDJSONSink{w: bufio.NewWriter(w), now: time.Now}
+}
+
+func (s *NDJSONSink) Name() string { return "ndjson" }
+
+// Publish 每个事件一行；每批写完即 flush，中途退出也不会丢已下发的批次
+func (s *NDJSONSink) Publish(_ context.Context, entity string, events []models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(s.w)
+	for _, e := range events {
+		if e.Entity == "" {
+			e.Entity = entity
+		}
+		if err := enc.Encode(e); err != nil {
+			return fmt.Errorf("ndjson encode: %w", err)
+		}
+	}
+	s.size += int64(s.w.Buffered())
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	return s.rotate()
+}
+
+// rotate 当前文件达到上限时归档并重新打开，再按保留策略清理旧归档；清理失败只记日志
+func (s *NDJSONSink) rotate() error {
+	if s.path == "" || s.rot.MaxBytes <= 0 || s.size < s.rot.MaxBytes {
+		return nil
+	}
+	now := s.now()
+	dst, err := util.RotateFile(s.path, s.rot.MaxBytes, now)
+	if err != nil || dst == "" {
+		return err
+	}
+	if err := s.closer.Close(); err != nil {
+		return fmt.Errorf("close ndjson %s: %w", dst, err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+	s.prune(now)
+	return nil
+}
+
+// prune 按保留策略清理当前文件的归档（path.*）；失败只记日志
+func (s *NDJSONSink) prune(now time.Time) {
+	if _, err := util.PruneFiles(filepath.Dir(s.path), filepath.Base(s.path)+".*", s.rot.Retention, now); err != nil {
+		log.Printf("[ndjson] prune archives of %s: %v", s.path, err)
+	}
+}
+
+func (s *NDJSONSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.w.Flush()
+	if s.closer != nil {
+		if cerr := s.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,171 @@
+package sink
+
+import (
+	"analysis/internal/config"
+	"analysis/internal/models"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EventSink 扫描器事件的下发目标（HTTP ingest / Kafka / NATS）
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, entity string, events []models.Event) error
+	Close() error
+}
+
+// New 按配置构建事件下发目标；targets 为空时默认仅走 HTTP ingest。
+// 返回的目标按 event_sink.batch_size 分块顺序下发
+func New(cfg *config.Config, apiBase string) (EventSink, error) {
+	targets := cfg.EventSink.Targets
+	if len(targets) == 0 {
+		targets = []string{"http"}
+	}
+	var sinks []EventSink
+	seen := map[string]bool{}
+	for _, t := range targets {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		var s EventSink
+		var err error
+		switch t {
+		case "http":
+			s = NewHTTPSink(apiBase)
+		case "kafka":
+			s, err = NewKafkaSink(cfg.EventSink.Kafka.Brokers, cfg.EventSink.Kafka.Topic)
+		case "nats":
+			s, err = NewNATSSink(cfg.EventSink.NATS.URL, cfg.EventSink.NATS.Subject)
+		default:
+			err = fmt.Errorf("unknown event sink %q", t)
+		}
+		if err != nil {
+			for _, x := range sinks {
+				_ = x.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if len(sinks) == 1 {
+		return NewChunkedSink(sinks[0], cfg.EventSink.BatchSize), nil
+	}
+	return NewChunkedSink(newMultiSink(sinks), cfg.EventSink.BatchSize), nil
+}
+
+// multiSinkMaxRecords 记录下发结果的批次数上限，超出时淘汰最早的记录
+const multiSinkMaxRecords = 4096
+
+// multiSink 同时下发到多个目标：单个目标失败时其它目标照常下发，但整批返回错误（窗口不提交、下轮重发）。
+// 按批次内容记录各目标是否已成功，重发同一批次时只发给之前失败的目标，已成功的 Kafka/NATS 不会重复收到；
+// 记录被淘汰或重扫得到的批次内容不同时退化为至少一次（HTTP ingest 按唯一键去重，消息队列的消费方需自行去重）
+type multiSink struct {
+	sinks []EventSink
+
+	mu        sync.Mutex
+	delivered map[string][]bool // 批次指纹 -> 各目标是否已成功
+	order     []string          // 记录的写入顺序，用于淘汰
+}
+
+func newMultiSink(sinks []EventSink) *multiSink {
+	return &multiSink{sinks: sinks, delivered: map[string][]bool{}}
+}
+
+func (m *multiSink) Name() string {
+	names := make([]string, 0, len(m.sinks))
+	for _, s := range m.sinks {
+		names = append(names, s.Name())
+	}
+	return strings.Join(names, "+")
+}
+
+func (m *multiSink) Publish(ctx context.Context, entity string, events []models.Event) error {
+	fp := batchFingerprint(entity, events)
+	m.mu.Lock()
+	done := append([]bool(nil), m.delivered[fp]...)
+	m.mu.Unlock()
+	if len(done) != len(m.sinks) {
+		done = make([]bool, len(m.sinks))
+	}
+
+	var errs []error
+	for i, s := range m.sinks {
+		if done[i] {
+			continue
+		}
+		if err := s.Publish(ctx, entity, events); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+			continue
+		}
+		done[i] = true
+	}
+	m.record(fp, done)
+	return errors.Join(errs...)
+}
+
+// record 保存批次的下发结果，超出上限时淘汰最早的记录
+func (m *multiSink) record(fp string, done []bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.delivered == nil {
+		m.delivered = map[string][]bool{}
+	}
+	if _, ok := m.delivered[fp]; !ok {
+		m.order = append(m.order, fp)
+	}
+	m.delivered[fp] = done
+	for len(m.order) > multiSinkMaxRecords {
+		delete(m.delivered, m.order[0])
+		m.order = m.order[1:]
+	}
+}
+
+// batchFingerprint 批次内容的指纹（实体 + 全部事件），内容相同的重发批次指纹相同
+func batchFingerprint(entity string, events []models.Event) string {
+	h := sha256.New()
+	h.Write([]byte(entity))
+	h.Write([]byte{0})
+	_ = json.NewEncoder(h).Encode(events)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m *multiSink) Close() error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PartitionKey 分区键：entity/chain，保证同一实体同一链的事件有序
+func PartitionKey(entity, chain string) string {
+	return strings.ToLower(entity) + "/" + strings.ToLower(chain)
+}
+
+// groupByKey 按 entity/chain 拆分批次，保持首次出现的顺序
+func groupByKey(entity string, events []models.Event) ([]string, map[string][]models.Event) {
+	var order []string
+	groups := map[string][]models.Event{}
+	for _, e := range events {
+		ent := e.Entity
+		if ent == "" {
+			ent = entity
+		}
+		k := PartitionKey(ent, e.Chain)
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], e)
+	}
+	return order, groups
+}
@@ -0,0 +1,324 @@
+package sink
+
+import (
+	"analysis/internal/models"
+	"analysis/internal/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+type mockKafkaWriter struct {
+	msgs []kafka.Message
+}
+
+func (m *mockKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	m.msgs = append(m.msgs, msgs...)
+	return nil
+}
+func (m *mockKafkaWriter) Close() error { return nil }
+
+type mockNATSConn struct {
+	msgs []*nats.Msg
+}
+
+func (m *mockNATSConn) PublishMsg(msg *nats.Msg) error {
+	m.msgs = append(m.msgs, msg)
+	return nil
+}
+func (m *mockNATSConn) Drain() error { return nil }
+
+func testEvents() []models.Event {
+	ts := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	return []models.Event{
+		{Entity: "binance", Chain: "ethereum", Coin: "USDT", Direction: "in", Amount: "10", TS: ts, TxID: "0x1", LogIndex: 1},
+		{Entity: "binance", Chain: "bitcoin", Coin: "BTC", Direction: "out", Amount: "1", TS: ts, TxID: "b1", LogIndex: -1},
+		{Entity: "binance", Chain: "ethereum", Coin: "ETH", Direction: "out", Amount: "2", TS: ts, TxID: "0x2", LogIndex: -1},
+	}
+}
+
+// TestKafkaSinkKeys 按 entity/chain 分批，key 正确
+func TestKafkaSinkKeys(t *testing.T) {
+	w := &mockKafkaWriter{}
+	s := &KafkaSink{topic: "events", w: w}
+	if err := s.Publish(context.Background(), "binance", testEvents()); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(w.msgs) != 2 {
+		t.Fatalf("期望 2 条消息，实际 %d", len(w.msgs))
+	}
+	if string(w.msgs[0].Key) != "binance/ethereum" || string(w.msgs[1].Key) != "binance/bitcoin" {
+		t.Fatalf("key 不符: %s, %s", w.msgs[0].Key, w.msgs[1].Key)
+	}
+	var evs []models.Event
+	if err := json.Unmarshal(w.msgs[0].Value, &evs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(evs) != 2 || evs[0].TxID != "0x1" || evs[1].TxID != "0x2" {
+		t.Fatalf("ethereum 批次内容不符: %+v", evs)
+	}
+}
+
+// TestNATSSinkSubjects subject = prefix.entity.chain，header 带 key
+func TestNATSSinkSubjects(t *testing.T) {
+	nc := &mockNATSConn{}
+	s := &NATSSink{prefix: "analysis.events", nc: nc}
+	evs := testEvents()
+	evs[1].Entity = "" // 缺省时回落到参数 entity
+	if err := s.Publish(context.Background(), "binance", evs); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(nc.msgs) != 2 {
+		t.Fatalf("期望 2 条消息，实际 %d", len(nc.msgs))
+	}
+	if nc.msgs[0].Subject != "analysis.events.binance.ethereum" || nc.msgs[1].Subject != "analysis.events.binance.bitcoin" {
+		t.Fatalf("subject 不符: %s, %s", nc.msgs[0].Subject, nc.msgs[1].Subject)
+	}
+	if got := nc.msgs[1].Header.Get("key"); got != "binance/bitcoin" {
+		t.Fatalf("header key 不符: %s", got)
+	}
+}
+
+// TestMultiSinkFanout 多目标时每个目标都收到
+func TestMultiSinkFanout(t *testing.T) {
+	w := &mockKafkaWriter{}
+	nc := &mockNATSConn{}
+	m := newMultiSink([]EventSink{&KafkaSink{topic: "t", w: w}, &NATSSink{prefix: "p", nc: nc}})
+	if err := m.Publish(context.Background(), "okx", testEvents()[:1]); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(w.msgs) != 1 || len(nc.msgs) != 1 {
+		t.Fatalf("fanout 失败: kafka=%d nats=%d", len(w.msgs), len(nc.msgs))
+	}
+	if m.Name() != "kafka+nats" {
+		t.Fatalf("name 不符: %s", m.Name())
+	}
+}
+
+// TestMultiSinkRetriesOnlyFailedTargets 部分目标失败时整批报错；重发同一批次只发给失败的目标，已成功的不重复；
+// 内容不同的批次照常发给全部目标
+func TestMultiSinkRetriesOnlyFailedTargets(t *testing.T) {
+	ok, flaky := &recordSink{}, &recordSink{failN: 1}
+	m := newMultiSink([]EventSink{ok, flaky})
+	evs := manyEvents(3)
+	if err := m.Publish(context.Background(), "binance", evs); err == nil {
+		t.Fatal("部分目标失败时应返回错误，窗口不提交")
+	}
+	if err := m.Publish(context.Background(), "binance", evs); err != nil {
+		t.Fatalf("重发应成功: %v", err)
+	}
+	if len(ok.batches) != 1 || len(flaky.batches) != 1 {
+		t.Fatalf("已成功的目标不应重复收到: ok=%d flaky=%d", len(ok.batches), len(flaky.batches))
+	}
+	// 游标提交失败后整窗重发：全部目标都已成功，不再重复下发
+	if err := m.Publish(context.Background(), "binance", evs); err != nil || len(ok.batches) != 1 || ok.calls != 1 {
+		t.Fatalf("已全部下发的批次不应重发: err=%v ok=%d", err, len(ok.batches))
+	}
+	if err := m.Publish(context.Background(), "binance", manyEvents(4)); err != nil || len(ok.batches) != 2 || len(flaky.batches) != 2 {
+		t.Fatalf("新批次应发给全部目标: err=%v ok=%d flaky=%d", err, len(ok.batches), len(flaky.batches))
+	}
+}
+
+type recordSink struct {
+	batches [][]models.Event
+	failAt  int // 第几次调用失败（从 1 开始），0 表示不失败
+	failN   int // 前 failN 次调用失败（之后恢复）
+	calls   int
+}
+
+func (r *recordSink) Name() string { return "record" }
+func (r *recordSink) Publish(_ context.Context, _ string, events []models.Event) error {
+	r.calls++
+	if r.calls <= r.failN {
+		return errors.New("broker unavailable")
+	}
+	if r.failAt > 0 && len(r.batches)+1 == r.failAt {
+		return errors.New("request entity too large")
+	}
+	r.batches = append(r.batches, append([]models.Event(nil), events...))
+	return nil
+}
+func (r *recordSink) Close() error { return nil }
+
+func manyEvents(n int) []models.Event {
+	evs := make([]models.Event, n)
+	for i := range evs {
+		evs[i] = models.Event{Entity: "binance", Chain: "ethereum", TxID: fmt.Sprintf("0x%d", i)}
+	}
+	return evs
+}
+
+// TestChunkedSinkSplitsBatches 大批事件按 batch_size 顺序拆块，最后一块为余数
+func TestChunkedSinkSplitsBatches(t *testing.T) {
+	inner := &recordSink{}
+	s := NewChunkedSink(inner, 1000)
+	if err := s.Publish(context.Background(), "binance", manyEvents(25_300)); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.batches) != 26 {
+		t.Fatalf("期望 26 块，实际 %d", len(inner.batches))
+	}
+	next := 0
+	for i, b := range inner.batches {
+		want := 1000
+		if i == 25 {
+			want = 300
+		}
+		if len(b) != want {
+			t.Errorf("第%d块期望 %d 条，实际 %d", i, want, len(b))
+		}
+		for _, e := range b {
+			if e.TxID != fmt.Sprintf("0x%d", next) {
+				t.Fatalf("事件顺序不符: 期望 0x%d，实际 %s", next, e.TxID)
+			}
+			next++
+		}
+	}
+}
+
+// TestChunkedSinkStopsOnError 某一块失败时返回错误，后续块不再发送
+func TestChunkedSinkStopsOnError(t *testing.T) {
+	inner := &recordSink{failAt: 3}
+	err := NewChunkedSink(inner, 100).Publish(context.Background(), "binance", manyEvents(550))
+	if err == nil || !strings.Contains(err.Error(), "chunk 3/6") {
+		t.Fatalf("期望第 3 块报错，实际 %v", err)
+	}
+	if len(inner.batches) != 2 {
+		t.Errorf("失败后不应继续发送，已发送 %d 块", len(inner.batches))
+	}
+}
+
+// TestNDJSONSinkLines 每个事件一行 JSON，缺省 entity 用批次的实体补齐；多批次追加
+func TestNDJSONSinkLines(t *testing.T) {
+	var b strings.Builder
+	s := NewNDJSONWriter(&b)
+	evs := testEvents()
+	evs[0].Entity = ""
+	if err := s.Publish(context.Background(), "binance", evs[:1]); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := s.Publish(context.Background(), "binance", evs[1:]); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != len(evs) {
+		t.Fatalf("期望 %d 行，实际 %d: %q", len(evs), len(lines), b.String())
+	}
+	var first models.Event
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if first.Entity != "binance" || first.TxID != "0x1" {
+		t.Fatalf("首行内容不符: %+v", first)
+	}
+}
+
+// TestRotatingNDJSONSink 文件达到上限即归档并重新打开，归档按总大小只保留最新的两个
+func TestRotatingNDJSONSink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.ndjson")
+	s, err := NewRotatingNDJSONSink(path, NDJSONRotation{MaxBytes: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { now = now.Add(time.Second); return now }
+	ev := testEvents()[:1]
+
+	if err := s.Publish(context.Background(), "binance", ev); err != nil {
+		t.Fatal(err)
+	}
+	archives, _ := filepath.Glob(path + ".*")
+	if len(archives) != 1 {
+		t.Fatalf("超过上限应归档一次，实际 %v", archives)
+	}
+	st, err := os.Stat(archives[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rot.Retention = util.RetentionPolicy{MaxBytes: 2 * st.Size()}
+	for i := 0; i < 3; i++ {
+		if err := s.Publish(context.Background(), "binance", ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archives, _ = filepath.Glob(path + ".*")
+	sort.Strings(archives)
+	if len(archives) != 2 || !strings.HasSuffix(archives[1], now.Format("20060102T150405.000000000Z")) {
+		t.Errorf("应只保留最新的两个归档，实际 %v", archives)
+	}
+	if st, err := os.Stat(path); err != nil || st.Size() != 0 {
+		t.Errorf("轮转后应重新打开空的当前文件: %v %v", st, err)
+	}
+}
+
+// TestRotatingNDJSONSinkAppendsAndPrunesOnOpen 重新打开时追加而不截断，已写入大小从现有文件起算；
+// 不轮转时保留策略也在打开时清理以前留下的归档
+func TestRotatingNDJSONSinkAppendsAndPrunesOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.ndjson")
+	old := time.Now().Add(-48 * time.Hour)
+	for _, name := range []string{"events.ndjson.20250101T000000.000000000Z", "other.ndjson.20250101T000000.000000000Z"} {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ev := testEvents()[:1]
+	open := func(rot NDJSONRotation) *NDJSONSink {
+		t.Helper()
+		s, err := NewRotatingNDJSONSink(path, rot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := open(NDJSONRotation{Retention: util.RetentionPolicy{MaxAge: 24 * time.Hour}})
+	if err := s.Publish(context.Background(), "binance", ev); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if archives, _ := filepath.Glob(path + ".*"); len(archives) != 0 {
+		t.Errorf("过期归档应在打开时清理: %v", archives)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.ndjson.20250101T000000.000000000Z")); err != nil {
+		t.Errorf("其它文件的归档不应被清理: %v", err)
+	}
+	st, _ := os.Stat(path)
+	line := st.Size()
+
+	s = open(NDJSONRotation{MaxBytes: 3 * line})
+	if s.size != line {
+		t.Fatalf("已写入大小应从现有文件起算: %d != %d", s.size, line)
+	}
+	if err := s.Publish(context.Background(), "binance", ev); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if st, _ := os.Stat(path); st.Size() != 2*line {
+		t.Fatalf("重启后应追加写入，期望 %d 字节，实际 %d", 2*line, st.Size())
+	}
+
+	// 现有文件已达到上限：打开时先归档
+	s = open(NDJSONRotation{MaxBytes: 2 * line})
+	defer s.Close()
+	if archives, _ := filepath.Glob(path + ".*"); len(archives) != 1 || s.size != 0 {
+		t.Errorf("超限的现有文件应在打开时归档: %v size=%d", archives, s.size)
+	}
+}
@@ -0,0 +1,80 @@
+package util
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff 指数退避：第 n 次 Next 返回 Base * Factor^(n-1)，不超过 Max，并按 Jitter 随机抖动
+//
+//	b := util.Backoff{Base: 200 * time.Millisecond, Max: 5 * time.Second, Jitter: 0.2, MaxAttempts: 5}
+//	for {
+//		if err := call(); err == nil {
+//			b.Reset()
+//			break
+//		}
+//		d, ok := b.Next()
+//		if !ok {
+//			break // 次数用尽
+//		}
+//		time.Sleep(d)
+//	}
+type Backoff struct {
+	Base        time.Duration // 首次延迟
+	Factor      float64       // 增长倍数，<=1 时按 2 处理
+	Max         time.Duration // 延迟上限（含抖动），0 表示不封顶
+	Jitter      float64       // 抖动比例 [0,1]：延迟落在 [d*(1-Jitter), d*(1+Jitter)]
+	MaxAttempts int           // 最多退避次数，0 表示不限
+
+	attempt int
+	cur     time.Duration
+	rand    func() float64 // 测试注入，默认 rand.Float64
+}
+
+// Next 返回下一次等待时长；超过 MaxAttempts 时返回 false
+func (b *Backoff) Next() (time.Duration, bool) {
+	if b.MaxAttempts > 0 && b.attempt >= b.MaxAttempts {
+		return 0, false
+	}
+	b.attempt++
+
+	if b.attempt == 1 || b.cur <= 0 {
+		b.cur = b.Base
+	} else if b.Max <= 0 || b.cur < b.Max {
+		factor := b.Factor
+		if factor <= 1 {
+			factor = 2
+		}
+		b.cur = time.Duration(float64(b.cur) * factor)
+	}
+	if b.Max > 0 && b.cur > b.Max {
+		b.cur = b.Max
+	}
+
+	d := b.cur
+	if j := b.Jitter; j > 0 {
+		if j > 1 {
+			j = 1
+		}
+		r := rand.Float64
+		if b.rand != nil {
+			r = b.rand
+		}
+		d = time.Duration(float64(d) * (1 - j + 2*j*r()))
+	}
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	return d, true
+}
+
+// Reset 成功后调用，下一次 Next 重新从 Base 开始
+func (b *Backoff) Reset() {
+	b.attempt = 0
+	b.cur = 0
+}
+
+// Attempt 已退避次数
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
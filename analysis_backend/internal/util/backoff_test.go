@@ -0,0 +1,69 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBackoffGrowthAndCap 按倍数增长并封顶
+func TestBackoffGrowthAndCap(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond, Factor: 3, Max: time.Second}
+	want := []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second}
+	for i, w := range want {
+		d, ok := b.Next()
+		if !ok || d != w {
+			t.Fatalf("第%d次期望 %v，实际 %v (ok=%v)", i+1, w, d, ok)
+		}
+	}
+
+	// Factor 未设置时按 2 增长
+	b2 := Backoff{Base: time.Second}
+	for i, w := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if d, _ := b2.Next(); d != w {
+			t.Fatalf("默认倍数第%d次期望 %v，实际 %v", i+1, w, d)
+		}
+	}
+}
+
+// TestBackoffMaxAttempts 超过最大次数后返回 false
+func TestBackoffMaxAttempts(t *testing.T) {
+	b := Backoff{Base: time.Millisecond, MaxAttempts: 2}
+	b.Next()
+	b.Next()
+	if _, ok := b.Next(); ok {
+		t.Fatal("第 3 次应返回 false")
+	}
+	if b.Attempt() != 2 {
+		t.Errorf("Attempt 期望 2，实际 %d", b.Attempt())
+	}
+}
+
+// TestBackoffJitterBounds 抖动落在 [d*(1-j), d*(1+j)] 内且不超过 Max
+func TestBackoffJitterBounds(t *testing.T) {
+	for _, r := range []float64{0, 0.5, 0.999} {
+		b := Backoff{Base: time.Second, Jitter: 0.25, rand: func() float64 { return r }}
+		d, _ := b.Next()
+		if d < 750*time.Millisecond || d > 1250*time.Millisecond {
+			t.Errorf("rand=%v 抖动越界: %v", r, d)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		b := Backoff{Base: time.Second, Max: time.Second, Jitter: 0.5}
+		if d, _ := b.Next(); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("抖动后应在 [0.5s, 1s]，实际 %v", d)
+		}
+	}
+}
+
+// TestBackoffReset Reset 后从 Base 重新开始，次数清零
+func TestBackoffReset(t *testing.T) {
+	b := Backoff{Base: 10 * time.Millisecond, MaxAttempts: 3}
+	b.Next()
+	b.Next()
+	b.Next()
+	b.Reset()
+	d, ok := b.Next()
+	if !ok || d != 10*time.Millisecond {
+		t.Fatalf("Reset 后期望 10ms，实际 %v (ok=%v)", d, ok)
+	}
+}
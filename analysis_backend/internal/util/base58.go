@@ -0,0 +1,57 @@
+package util
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"strings"
+)
+
+// Bitcoin 字母表的 base58 编解码：Tron/Bitcoin 地址（base58check）、Solana 公钥/mint、BIP32 扩展公钥共用
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// Base58Decode 解码 base58 字符串，前导 '1' 还原为前导 0 字节；含非法字符返回 false
+func Base58Decode(s string) ([]byte, bool) {
+	n := new(big.Int)
+	for _, c := range s {
+		i := strings.IndexRune(base58Alphabet, c)
+		if i < 0 {
+			return nil, false
+		}
+		n.Mul(n, big.NewInt(58))
+		n.Add(n, big.NewInt(int64(i)))
+	}
+	raw := n.Bytes()
+	for i := 0; i < len(s) && s[i] == '1'; i++ {
+		raw = append([]byte{0}, raw...)
+	}
+	return raw, true
+}
+
+// Base58Encode 编码为 base58，前导 0 字节编码为 '1'
+func Base58Encode(raw []byte) string {
+	n := new(big.Int).SetBytes(raw)
+	var out []byte
+	mod := new(big.Int)
+	for n.Sign() > 0 {
+		n.DivMod(n, big.NewInt(58), mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range raw {
+		if b != 0 {
+			break
+		}
+		out = append(out, '1')
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// Base58Checksum 两次 sha256 的前 4 字节（base58check 校验和）
+func Base58Checksum(payload []byte) []byte {
+	a := sha256.Sum256(payload)
+	b := sha256.Sum256(a[:])
+	return b[:4]
+}
@@ -0,0 +1,109 @@
+package util
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 时间来源抽象：生产代码用 SystemClock，测试用 FakeClock 手动推进时间，
+// 冷却到期、定时触发等逻辑因此可以确定性地测试而不必真的等待
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// RealClock 系统时钟
+type RealClock struct{}
+
+func (RealClock) Now() time.Time                         { return time.Now() }
+func (RealClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (RealClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock 默认时钟
+var SystemClock Clock = RealClock{}
+
+// FakeClock 测试时钟：时间只在 Advance/Set/Sleep 时前进。
+// Sleep 不阻塞，直接把时间推进 d；After 返回的通道在时间推进到期时触发（d<=0 立即触发）
+//
+//	clk := util.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	ch := clk.After(time.Minute)
+//	clk.Advance(time.Minute) // ch 触发
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Sleep 推进时间 d，不阻塞
+func (c *FakeClock) Sleep(d time.Duration) {
+	if d > 0 {
+		c.Advance(d)
+	}
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	at := c.now.Add(d)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: at, ch: ch})
+	return ch
+}
+
+// Advance 时间前进 d，触发所有到期的 After
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set 把时间设为 t（早于当前时间时忽略，时钟不回退）
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.setLocked(t)
+	}
+}
+
+// Waiters 尚未触发的 After 数量；测试用它确认被测协程已经进入等待
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+func (c *FakeClock) setLocked(t time.Time) {
+	c.now = t
+	sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+	n := 0
+	for _, w := range c.waiters {
+		if w.at.After(t) {
+			break
+		}
+		w.ch <- w.at
+		n++
+	}
+	c.waiters = c.waiters[n:]
+}
@@ -0,0 +1,71 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFakeClockAfter After 只在时间推进到期后触发，按到期顺序触发
+func TestFakeClockAfter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	a := c.After(2 * time.Second)
+	b := c.After(time.Second)
+	if c.Waiters() != 2 {
+		t.Fatalf("期望 2 个等待者，实际 %d", c.Waiters())
+	}
+
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-a:
+		t.Fatal("a 不应提前触发")
+	case <-b:
+		t.Fatal("b 不应提前触发")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	select {
+	case got := <-b:
+		if !got.Equal(start.Add(time.Second)) {
+			t.Fatalf("b 触发时间 %v", got)
+		}
+	default:
+		t.Fatal("b 应已触发")
+	}
+	if c.Waiters() != 1 {
+		t.Fatalf("期望剩 1 个等待者，实际 %d", c.Waiters())
+	}
+
+	c.Sleep(time.Second)
+	select {
+	case <-a:
+	default:
+		t.Fatal("Sleep 推进时间后 a 应已触发")
+	}
+	if !c.Now().Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("当前时间 %v", c.Now())
+	}
+}
+
+// TestFakeClockSetAndImmediate Set 不回退；d<=0 的 After 立即触发
+func TestFakeClockSetAndImmediate(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	c.Set(start.Add(-time.Hour))
+	if !c.Now().Equal(start) {
+		t.Fatalf("Set 不应回退时钟: %v", c.Now())
+	}
+	select {
+	case <-c.After(0):
+	default:
+		t.Fatal("After(0) 应立即触发")
+	}
+	ch := c.After(time.Hour)
+	c.Set(start.Add(2 * time.Hour))
+	select {
+	case <-ch:
+	default:
+		t.Fatal("Set 越过到期时间后应触发")
+	}
+}
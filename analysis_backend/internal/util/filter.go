@@ -5,6 +5,9 @@ import "strings"
 var (
 	allowed  = map[string]bool{}
 	allowAll = false
+
+	// 实体级覆盖：实体名（小写）-> 允许的币种；nil 表示该实体全部允许
+	entityAllowed = map[string]map[string]bool{}
 )
 
 // SetAllowed 接受逗号分隔的币种，比如 "BTC,ETH,USDT"
@@ -21,6 +24,32 @@ func SetAllowed(list string) {
 	}
 
 	allowAll = false
+	allowed = parseAllowed(list)
+}
+
+// SetEntityAllowed 为单个实体设置币种范围，覆盖 SetAllowed 的全局设置（不取交集）；
+// 格式同 SetAllowed，"*"/"all" 表示该实体全部允许，空串表示取消覆盖、回退到全局设置
+func SetEntityAllowed(entity, list string) {
+	key := strings.ToLower(strings.TrimSpace(entity))
+	list = strings.TrimSpace(list)
+	switch {
+	case key == "":
+		return
+	case list == "":
+		delete(entityAllowed, key)
+	case list == "*" || strings.EqualFold(list, "all"):
+		entityAllowed[key] = nil
+	default:
+		entityAllowed[key] = parseAllowed(list)
+	}
+}
+
+// ResetEntityAllowed 清空全部实体级覆盖
+func ResetEntityAllowed() {
+	entityAllowed = map[string]map[string]bool{}
+}
+
+func parseAllowed(list string) map[string]bool {
 	m := make(map[string]bool)
 	for _, s := range strings.Split(list, ",") {
 		s = strings.ToUpper(strings.TrimSpace(s))
@@ -28,7 +57,7 @@ func SetAllowed(list string) {
 			m[s] = true
 		}
 	}
-	allowed = m
+	return m
 }
 
 func IsAllowed(sym string) bool {
@@ -37,3 +66,12 @@ func IsAllowed(sym string) bool {
 	}
 	return allowed[strings.ToUpper(sym)]
 }
+
+// IsAllowedFor 实体配置了币种范围时按实体范围判断，否则回退到全局 IsAllowed
+func IsAllowedFor(entity, sym string) bool {
+	m, ok := entityAllowed[strings.ToLower(strings.TrimSpace(entity))]
+	if !ok {
+		return IsAllowed(sym)
+	}
+	return m == nil || m[strings.ToUpper(sym)]
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"math/big"
+	"testing"
+
+	"analysis/internal/models"
+)
+
+// TestIsAllowedForEntityScope 实体级范围独立于全局设置：可收窄也可放宽，未配置的实体回退到全局
+func TestIsAllowedForEntityScope(t *testing.T) {
+	SetAllowed("BTC,ETH,USDT")
+	t.Cleanup(func() {
+		SetAllowed("")
+		ResetEntityAllowed()
+	})
+	SetEntityAllowed("Binance", "btc, eth")
+	SetEntityAllowed("okx", "SOL")
+	SetEntityAllowed("bybit", "all")
+
+	cases := []struct {
+		entity, sym string
+		want        bool
+	}{
+		{"binance", "BTC", true},
+		{"BINANCE", "eth", true},
+		{"binance", "USDT", false}, // 全局允许，但实体范围不含
+		{"okx", "SOL", true},       // 全局不允许，实体范围允许
+		{"okx", "BTC", false},
+		{"bybit", "DOGE", true},
+		{"kraken", "USDT", true}, // 未配置：回退全局
+		{"kraken", "SOL", false},
+		{"", "BTC", true},
+	}
+	for _, c := range cases {
+		if got := IsAllowedFor(c.entity, c.sym); got != c.want {
+			t.Errorf("IsAllowedFor(%q, %q) = %v，期望 %v", c.entity, c.sym, got, c.want)
+		}
+	}
+
+	// 全局设置变化不影响已配置范围的实体
+	SetAllowed("*")
+	if IsAllowedFor("binance", "USDT") {
+		t.Error("全局改为全部允许后，binance 仍应只允许 BTC/ETH")
+	}
+	if !IsAllowedFor("kraken", "DOGE") {
+		t.Error("未配置实体应跟随全局的全部允许")
+	}
+
+	// 空串取消覆盖
+	SetEntityAllowed("okx", "")
+	if !IsAllowedFor("okx", "BTC") {
+		t.Error("取消覆盖后 okx 应回退全局")
+	}
+}
+
+// TestAddEntityHoldingFiltersByEntity 持仓累加按实体范围过滤
+func TestAddEntityHoldingFiltersByEntity(t *testing.T) {
+	SetAllowed("BTC,ETH")
+	t.Cleanup(func() {
+		SetAllowed("")
+		ResetEntityAllowed()
+	})
+	SetEntityAllowed("acme", "BTC")
+
+	sum := map[string]models.Holding{}
+	AddEntityHolding(sum, "acme", "bitcoin", "BTC", 8, big.NewInt(50000000), map[string]float64{"BTC": 60000})
+	AddEntityHolding(sum, "acme", "ethereum", "ETH", 18, big.NewInt(1e18), nil)
+	AddEntityHolding(sum, "other", "ethereum", "ETH", 18, big.NewInt(1e18), nil)
+	if len(sum) != 2 {
+		t.Fatalf("期望 2 条持仓，实际 %+v", sum)
+	}
+	if h := sum["bitcoin:BTC"]; h.Amount != "0.50000000" || h.ValueUSD != 30000 {
+		t.Errorf("BTC 持仓不符: %+v", h)
+	}
+	if h := sum["ethereum:ETH"]; h.Amount != "1.00000000" {
+		t.Errorf("ETH 持仓应只来自 other 实体: %+v", h)
+	}
+}
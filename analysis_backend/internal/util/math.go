@@ -22,10 +22,22 @@ func Pow10(n int) *big.Float {
 }
 
 func AddHolding(sum map[string]models.Holding, chain, symbol string, dec int, amt *big.Int, px map[string]float64) {
-	symU := stringsToUpper(symbol)
-	if !IsAllowed(symU) {
+	if !IsAllowed(symbol) {
+		return
+	}
+	addHolding(sum, chain, symbol, dec, amt, px)
+}
+
+// AddEntityHolding 同 AddHolding，按实体的币种范围过滤（见 IsAllowedFor）
+func AddEntityHolding(sum map[string]models.Holding, entity, chain, symbol string, dec int, amt *big.Int, px map[string]float64) {
+	if !IsAllowedFor(entity, symbol) {
 		return
 	}
+	addHolding(sum, chain, symbol, dec, amt, px)
+}
+
+func addHolding(sum map[string]models.Holding, chain, symbol string, dec int, amt *big.Int, px map[string]float64) {
+	symU := stringsToUpper(symbol)
 	key := fmt.Sprintf("%s:%s", chain, symU)
 	q := new(big.Float).Quo(new(big.Float).SetInt(amt), Pow10(dec))
 	val := 0.0
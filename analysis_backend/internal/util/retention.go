@@ -0,0 +1,98 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy 磁盘产物的保留策略：按最长保留时间 / 总大小上限从最旧的文件开始删除；零值表示该项不限。
+// 供 scanner -dry-run 的 NDJSON 归档、recommendation_scanner 的结果目录这类会持续累积的文件使用
+type RetentionPolicy struct {
+	MaxAge   time.Duration // 修改时间早于 now-MaxAge 的文件删除
+	MaxBytes int64         // 匹配文件总大小上限，超出时从最旧的开始删除
+}
+
+// Enabled 是否设置了任一限制
+func (p RetentionPolicy) Enabled() bool { return p.MaxAge > 0 || p.MaxBytes > 0 }
+
+// RotateFile path 大小达到 maxBytes 时改名为 path.<UTC 时间戳>，返回归档路径；未达到或文件不存在时返回空串
+func RotateFile(path string, maxBytes int64, now time.Time) (string, error) {
+	if maxBytes <= 0 {
+		return "", nil
+	}
+	st, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if st.Size() < maxBytes {
+		return "", nil
+	}
+	dst := path + "." + now.UTC().Format("20060102T150405.000000000Z")
+	if err := os.Rename(path, dst); err != nil {
+		return "", fmt.Errorf("rotate %s: %w", path, err)
+	}
+	return dst, nil
+}
+
+// PruneFiles 按保留策略清理 dir 下匹配 pattern（filepath.Match 语法）的普通文件，最旧的先删；返回删除的路径
+func PruneFiles(dir, pattern string, p RetentionPolicy, now time.Time) ([]string, error) {
+	if !p.Enabled() {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type fileInfo struct {
+		path string
+		mod  time.Time
+		size int64
+	}
+	files := make([]fileInfo, 0, len(entries))
+	var total int64
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		if ok, err := filepath.Match(pattern, e.Name()); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // 遍历途中被删除
+		}
+		files = append(files, fileInfo{path: filepath.Join(dir, e.Name()), mod: info.ModTime(), size: info.Size()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].mod.Equal(files[j].mod) {
+			return files[i].mod.Before(files[j].mod)
+		}
+		return files[i].path < files[j].path
+	})
+
+	var removed []string
+	for _, f := range files {
+		expired := p.MaxAge > 0 && now.Sub(f.mod) > p.MaxAge
+		oversize := p.MaxBytes > 0 && total > p.MaxBytes
+		if !expired && !oversize {
+			break // 其余文件更新，且总大小已在上限内
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, fmt.Errorf("prune %s: %w", f.path, err)
+		}
+		total -= f.size
+		removed = append(removed, f.path)
+	}
+	return removed, nil
+}
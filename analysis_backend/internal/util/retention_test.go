@@ -0,0 +1,127 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeAged 写入 size 字节的文件并把修改时间设为 now-age
+func writeAged(t *testing.T, dir, name string, size int, age time.Duration, now time.Time) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(strings.Repeat("x", size)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, now.Add(-age), now.Add(-age)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func remaining(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPruneFilesMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	// 30 个报告文件，第 i 个是 i 小时前写的；另有一个不匹配的文件
+	for i := 0; i < 30; i++ {
+		writeAged(t, dir, fmt.Sprintf("report-%02d.json", i), 10, time.Duration(i)*time.Hour, now)
+	}
+	writeAged(t, dir, "cursor.json", 10, 100*time.Hour, now)
+
+	removed, err := PruneFiles(dir, "report-*.json", RetentionPolicy{MaxAge: 24 * time.Hour}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 5 {
+		t.Fatalf("want 5 files older than 24h removed, got %d: %v", len(removed), removed)
+	}
+	if filepath.Base(removed[0]) != "report-29.json" {
+		t.Errorf("oldest should be removed first, got %v", removed)
+	}
+	left := remaining(t, dir)
+	if len(left) != 26 || left[0] != "cursor.json" || left[len(left)-1] != "report-24.json" {
+		t.Errorf("unexpected remaining files: %v", left)
+	}
+}
+
+func TestPruneFilesMaxBytesDeletesOldestFirst(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 50; i++ {
+		writeAged(t, dir, fmt.Sprintf("dlq-%02d.ndjson", i), 100, time.Duration(50-i)*time.Minute, now)
+	}
+
+	removed, err := PruneFiles(dir, "dlq-*.ndjson", RetentionPolicy{MaxBytes: 1050}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 40 {
+		t.Fatalf("want 40 removed to fit 1050 bytes, got %d", len(removed))
+	}
+	left := remaining(t, dir)
+	if len(left) != 10 || left[0] != "dlq-40.ndjson" || left[9] != "dlq-49.ndjson" {
+		t.Errorf("newest 10 files should remain, got %v", left)
+	}
+
+	// 未超限时不删除；零值策略不做任何事
+	if removed, _ := PruneFiles(dir, "dlq-*.ndjson", RetentionPolicy{MaxBytes: 1050, MaxAge: time.Hour}, now); len(removed) != 0 {
+		t.Errorf("nothing should be removed within policy, got %v", removed)
+	}
+	if removed, _ := PruneFiles(dir, "dlq-*.ndjson", RetentionPolicy{}, now); len(removed) != 0 {
+		t.Errorf("zero policy should not remove files, got %v", removed)
+	}
+}
+
+func TestRotateFileThenPrune(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dlq.ndjson")
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if dst, err := RotateFile(path, 100, now); err != nil || dst != "" {
+		t.Fatalf("missing file should not rotate: %q %v", dst, err)
+	}
+	for i := 0; i < 5; i++ {
+		ts := now.Add(time.Duration(i) * time.Hour)
+		writeAged(t, dir, "dlq.ndjson", 50, 0, ts)
+		if dst, _ := RotateFile(path, 100, ts); dst != "" {
+			t.Fatalf("file under limit should not rotate: %s", dst)
+		}
+		writeAged(t, dir, "dlq.ndjson", 120, 0, ts)
+		dst, err := RotateFile(path, 100, ts)
+		if err != nil || dst == "" {
+			t.Fatalf("file over limit should rotate: %q %v", dst, err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("current file should be moved away")
+		}
+	}
+
+	removed, err := PruneFiles(dir, "dlq.ndjson.*", RetentionPolicy{MaxBytes: 250}, now.Add(5*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("want 3 oldest archives removed, got %v", removed)
+	}
+	left := remaining(t, dir)
+	if len(left) != 2 || !strings.HasSuffix(left[1], now.Add(4*time.Hour).Format("20060102T150405.000000000Z")) {
+		t.Errorf("newest two archives should remain, got %v", left)
+	}
+}
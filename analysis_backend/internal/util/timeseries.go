@@ -0,0 +1,100 @@
+package util
+
+import (
+	"sort"
+	"time"
+)
+
+// TimePoint 一个带时间戳的数值
+type TimePoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// TimeBucket 一个时间桶内的聚合结果，区间为 [Start, Start+bucket)
+type TimeBucket struct {
+	Start time.Time
+	Open  float64
+	High  float64
+	Low   float64
+	Close float64
+	Sum   float64
+	Mean  float64
+	Count int // 0 表示空桶
+}
+
+// BucketStart 返回 t 所在桶的起点（按 Unix 纪元对齐，与时区无关）
+func BucketStart(t time.Time, bucket time.Duration) time.Time {
+	return t.Truncate(bucket)
+}
+
+// sortedPoints 按时间升序复制一份，时间相同的保持原顺序
+func sortedPoints(points []TimePoint) []TimePoint {
+	out := append([]TimePoint(nil), points...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+// Downsample 把数据点按固定时长分桶，计算每个桶的 OHLC / 求和 / 均值。
+// 只返回有数据的桶（按时间升序）；输入无需有序，bucket<=0 时返回 nil。
+func Downsample(points []TimePoint, bucket time.Duration) []TimeBucket {
+	if bucket <= 0 || len(points) == 0 {
+		return nil
+	}
+	var out []TimeBucket
+	for _, p := range sortedPoints(points) {
+		start := BucketStart(p.Time, bucket)
+		if n := len(out); n > 0 && out[n-1].Start.Equal(start) {
+			b := &out[n-1]
+			b.High = max(b.High, p.Value)
+			b.Low = min(b.Low, p.Value)
+			b.Close = p.Value
+			b.Sum += p.Value
+			b.Count++
+			continue
+		}
+		out = append(out, TimeBucket{Start: start, Open: p.Value, High: p.Value, Low: p.Value, Close: p.Value, Sum: p.Value, Count: 1})
+	}
+	for i := range out {
+		out[i].Mean = out[i].Sum / float64(out[i].Count)
+	}
+	return out
+}
+
+// DownsampleRange 返回 [from, to) 内以 from 为起点对齐的每一个桶（最后一个桶可能越过 to），范围外的点被忽略。
+// 空桶 Count=0、Sum/Mean=0，OHLC 沿用上一个桶的收盘价（之前没有数据时为 0）。
+func DownsampleRange(points []TimePoint, from, to time.Time, bucket time.Duration) []TimeBucket {
+	if bucket <= 0 || !from.Before(to) {
+		return nil
+	}
+	n := int((to.Sub(from) + bucket - 1) / bucket)
+	out := make([]TimeBucket, n)
+	for i := range out {
+		out[i].Start = from.Add(time.Duration(i) * bucket)
+	}
+	for _, p := range sortedPoints(points) {
+		if p.Time.Before(from) || !p.Time.Before(to) {
+			continue
+		}
+		b := &out[int(p.Time.Sub(from)/bucket)]
+		if b.Count == 0 {
+			b.Open, b.High, b.Low = p.Value, p.Value, p.Value
+		}
+		b.High = max(b.High, p.Value)
+		b.Low = min(b.Low, p.Value)
+		b.Close = p.Value
+		b.Sum += p.Value
+		b.Count++
+	}
+	last := 0.0
+	for i := range out {
+		b := &out[i]
+		if b.Count == 0 {
+			b.Open, b.High, b.Low, b.Close = last, last, last, last
+			continue
+		}
+		b.Mean = b.Sum / float64(b.Count)
+		last = b.Close
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+var tsBase = time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+func at(minutes int, v float64) TimePoint {
+	return TimePoint{Time: tsBase.Add(time.Duration(minutes) * time.Minute), Value: v}
+}
+
+// TestDownsampleOHLC 乱序输入按桶聚合出 OHLC / 求和 / 均值
+func TestDownsampleOHLC(t *testing.T) {
+	points := []TimePoint{at(10, 3), at(0, 1), at(30, 5), at(59, 2), at(75, 7)}
+	got := Downsample(points, time.Hour)
+	if len(got) != 2 {
+		t.Fatalf("期望 2 个桶，实际 %d: %+v", len(got), got)
+	}
+	b := got[0]
+	if !b.Start.Equal(tsBase) || b.Open != 1 || b.High != 5 || b.Low != 1 || b.Close != 2 || b.Sum != 11 || b.Count != 4 || b.Mean != 2.75 {
+		t.Errorf("第一个桶不符: %+v", b)
+	}
+	b = got[1]
+	if !b.Start.Equal(tsBase.Add(time.Hour)) || b.Open != 7 || b.Close != 7 || b.Count != 1 || b.Mean != 7 {
+		t.Errorf("第二个桶不符: %+v", b)
+	}
+}
+
+// TestDownsampleBoundary 桶为左闭右开：恰好落在边界上的点属于下一个桶
+func TestDownsampleBoundary(t *testing.T) {
+	got := Downsample([]TimePoint{at(0, 1), at(60, 2), at(119, 3), at(120, 4)}, time.Hour)
+	if len(got) != 3 {
+		t.Fatalf("期望 3 个桶，实际 %+v", got)
+	}
+	if got[0].Count != 1 || got[1].Count != 2 || got[1].Open != 2 || got[1].Close != 3 || got[2].Open != 4 {
+		t.Errorf("边界归属不符: %+v", got)
+	}
+	if Downsample(nil, time.Hour) != nil || Downsample([]TimePoint{at(0, 1)}, 0) != nil {
+		t.Error("空输入或非法桶长应返回 nil")
+	}
+}
+
+// TestDownsampleRangeEmptyBuckets 区间内的空桶保留，OHLC 沿用上一个收盘价，区间外的点忽略
+func TestDownsampleRangeEmptyBuckets(t *testing.T) {
+	from := tsBase.Add(15 * time.Minute)
+	to := from.Add(4 * time.Hour)
+	points := []TimePoint{at(0, 99), at(20, 1), at(30, 4), at(150, 6), at(15+240, 100)}
+	got := DownsampleRange(points, from, to, time.Hour)
+	if len(got) != 4 {
+		t.Fatalf("期望 4 个桶，实际 %d", len(got))
+	}
+	for i, b := range got {
+		if !b.Start.Equal(from.Add(time.Duration(i) * time.Hour)) {
+			t.Errorf("第 %d 个桶起点应以 from 对齐: %v", i, b.Start)
+		}
+	}
+	if got[0].Count != 2 || got[0].Open != 1 || got[0].Close != 4 || got[0].Mean != 2.5 {
+		t.Errorf("第一个桶不符: %+v", got[0])
+	}
+	if got[1].Count != 0 || got[1].Open != 4 || got[1].Close != 4 || got[1].Sum != 0 || got[1].Mean != 0 {
+		t.Errorf("空桶应沿用上一个收盘价: %+v", got[1])
+	}
+	if got[2].Count != 1 || got[2].Close != 6 || got[3].Count != 0 || got[3].Close != 6 {
+		t.Errorf("后续桶不符: %+v %+v", got[2], got[3])
+	}
+
+	empty := DownsampleRange(nil, from, to, time.Hour)
+	if len(empty) != 4 || empty[0].Count != 0 || empty[0].Close != 0 {
+		t.Errorf("无数据时应返回全空桶: %+v", empty)
+	}
+	if DownsampleRange(points, to, from, time.Hour) != nil {
+		t.Error("from >= to 应返回 nil")
+	}
+}
@@ -0,0 +1,15 @@
+-- 公告跨源合并：同一事件（币种+类型+时间相近）合并为一条规范记录
+-- +migrate Up
+
+ALTER TABLE announcements
+    ADD COLUMN merge_key VARCHAR(64) DEFAULT NULL COMMENT '合并键：币种|类型',
+    ADD COLUMN sources JSON DEFAULT NULL COMMENT '合并后引用的所有来源';
+
+CREATE INDEX idx_announcements_merge_key ON announcements (merge_key, release_time);
+
+-- +migrate Down
+
+DROP INDEX idx_announcements_merge_key ON announcements;
+ALTER TABLE announcements
+    DROP COLUMN merge_key,
+    DROP COLUMN sources;
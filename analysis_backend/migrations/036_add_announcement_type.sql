@@ -0,0 +1,13 @@
+-- 公告统一类型：按 announcements.category_map 将各数据源类目映射为 new-listing / delisting / maintenance 等
+-- +migrate Up
+
+ALTER TABLE announcements
+    ADD COLUMN type VARCHAR(32) DEFAULT NULL COMMENT '跨源统一类型';
+
+CREATE INDEX idx_announcements_type ON announcements (type, release_time);
+
+-- +migrate Down
+
+DROP INDEX idx_announcements_type ON announcements;
+ALTER TABLE announcements
+    DROP COLUMN type;
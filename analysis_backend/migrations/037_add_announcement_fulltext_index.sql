@@ -0,0 +1,9 @@
+-- 公告全文搜索：标题/摘要 FULLTEXT 索引（ngram 解析器以支持中文）
+-- +migrate Up
+
+ALTER TABLE announcements
+    ADD FULLTEXT INDEX ft_announcements_title_summary (title, summary) WITH PARSER ngram;
+
+-- +migrate Down
+
+DROP INDEX ft_announcements_title_summary ON announcements;
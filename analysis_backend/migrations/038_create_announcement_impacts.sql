@@ -0,0 +1,29 @@
+-- 公告价格影响：上币公告发布后标的在各统计窗口内的涨跌幅
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS announcement_impacts (
+    id               BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    announcement_id  BIGINT UNSIGNED NOT NULL,
+    window_minutes   INT             NOT NULL,
+    symbol           VARCHAR(32)     DEFAULT NULL,
+    kind             VARCHAR(16)     DEFAULT NULL,
+    type             VARCHAR(32)     DEFAULT NULL,
+    release_time     DATETIME(3)     DEFAULT NULL,
+    base_price       DOUBLE          DEFAULT NULL COMMENT '公告发布后首根K线开盘价',
+    end_price        DOUBLE          DEFAULT NULL COMMENT '窗口内最后一根K线收盘价',
+    change_percent   DOUBLE          DEFAULT NULL,
+    max_up_percent   DOUBLE          DEFAULT NULL,
+    max_down_percent DOUBLE          DEFAULT NULL,
+    kline_count      BIGINT          DEFAULT NULL,
+    created_at       DATETIME(3)     DEFAULT NULL,
+    updated_at       DATETIME(3)     DEFAULT NULL,
+    UNIQUE KEY uk_announcement_impact_window (announcement_id, window_minutes),
+    KEY idx_announcement_impacts_symbol (symbol),
+    KEY idx_announcement_impacts_type (type),
+    KEY idx_announcement_impacts_release_time (release_time)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS announcement_impacts;
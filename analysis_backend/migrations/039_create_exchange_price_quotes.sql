@@ -0,0 +1,22 @@
+-- 多交易所报价：PriceSyncer 聚合 VWAP 时保留的各交易所原始报价
+-- +migrate Up
+
+CREATE TABLE IF NOT EXISTS exchange_price_quotes (
+    id           BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    symbol       VARCHAR(32)  DEFAULT NULL,
+    kind         VARCHAR(16)  DEFAULT NULL,
+    exchange     VARCHAR(16)  DEFAULT NULL,
+    price        DOUBLE       DEFAULT NULL,
+    quote_volume DOUBLE       DEFAULT NULL COMMENT '24h 成交额，VWAP 权重',
+    outlier      TINYINT(1)   DEFAULT NULL COMMENT '本轮因偏离过大被剔除',
+    last_updated DATETIME(3)  DEFAULT NULL,
+    created_at   DATETIME(3)  DEFAULT NULL,
+    updated_at   DATETIME(3)  DEFAULT NULL,
+    UNIQUE KEY uk_exchange_quote (symbol, kind, exchange),
+    KEY idx_exchange_price_quotes_last_updated (last_updated)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4;
+
+-- +migrate Down
+
+DROP TABLE IF EXISTS exchange_price_quotes;